@@ -0,0 +1,123 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"crypto/ecdsa"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/project-oak/transparent-release/pkg/gate"
+	"github.com/project-oak/transparent-release/pkg/sign"
+)
+
+// admissionPath is the path at which admission reviews are accepted.
+const admissionPath = "/validate"
+
+func main() {
+	address := flag.String("address", ":8443",
+		"Address on which to serve admission reviews at "+admissionPath+".")
+	tlsCertPath := flag.String("tls_cert", "",
+		"Path to the PEM-encoded TLS certificate of the webhook. Kubernetes requires webhooks to be served over HTTPS.")
+	tlsKeyPath := flag.String("tls_key", "",
+		"Path to the PEM-encoded private key of --tls_cert.")
+	endorserPublicKeyPath := flag.String("endorser_public_key", "",
+		"Path to the PEM-encoded public key of the product team that signs the endorsements.")
+	endorsementsDir := flag.String("endorsements_dir", "",
+		"Directory with endorsements named after the image digest, i.e., <hex>.dsse.json, and optionally <hex>.rekor.json.")
+	bundlesDir := flag.String("bundles_dir", "",
+		"Directory with release bundles named after the image digest, i.e., <hex>.bundle.json. Requires --rekor_public_key.")
+	oci := flag.Bool("oci", false,
+		"Look up endorsements attached to images in their registry, using the OCI referrers API.")
+	rekorPublicKeyPath := flag.String("rekor_public_key", "",
+		"Path to the PEM-encoded public key of a Rekor instance. If set, endorsements must be included in its log.")
+	rekorURL := flag.String("rekor_url", "",
+		"URL of the Rekor instance of --rekor_public_key, for looking up log entries that are not stored with the endorsements.")
+	flag.Parse()
+
+	if *endorserPublicKeyPath == "" {
+		log.Fatalf("--endorser_public_key not set")
+	}
+	if *endorsementsDir == "" && *bundlesDir == "" && !*oci {
+		log.Fatalf("at least one of --endorsements_dir, --bundles_dir and --oci must be set")
+	}
+	if *bundlesDir != "" && *rekorPublicKeyPath == "" {
+		log.Fatalf("--bundles_dir requires --rekor_public_key")
+	}
+	if *rekorURL != "" && *rekorPublicKeyPath == "" {
+		log.Fatalf("--rekor_url requires --rekor_public_key")
+	}
+
+	endorser, err := sign.LoadPublicKeyVerifier(*endorserPublicKeyPath)
+	if err != nil {
+		log.Fatalf("Failed loading the endorser public key: %v", err)
+	}
+	var options []func(c *gate.Config)
+	if *endorsementsDir != "" {
+		options = append(options, gate.WithSource(gate.NewDirectorySource(*endorsementsDir)))
+	}
+	if *bundlesDir != "" {
+		options = append(options, gate.WithSource(gate.NewBundleSource(*bundlesDir)))
+	}
+	if *oci {
+		options = append(options, gate.WithSource(gate.NewOCISource()))
+	}
+	if *rekorPublicKeyPath != "" {
+		rekorPublicKey, err := loadECDSAPublicKey(*rekorPublicKeyPath)
+		if err != nil {
+			log.Fatalf("Failed loading the Rekor public key: %v", err)
+		}
+		options = append(options, gate.WithRekor(rekorPublicKey, *rekorURL))
+	}
+	deploymentGate, err := gate.New(endorser, options...)
+	if err != nil {
+		log.Fatalf("Failed creating the gate: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(admissionPath, gate.AdmissionHandler(deploymentGate))
+	server := &http.Server{
+		Addr:              *address,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	log.Printf("Serving admission reviews on %s%s", *address, admissionPath)
+	if *tlsCertPath != "" {
+		err = server.ListenAndServeTLS(*tlsCertPath, *tlsKeyPath)
+	} else {
+		err = server.ListenAndServe()
+	}
+	log.Fatalf("Failed serving admission reviews: %v", err)
+}
+
+func loadECDSAPublicKey(path string) (*ecdsa.PublicKey, error) {
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	publicKey, err := sign.ParsePublicKeyPEM(bytes)
+	if err != nil {
+		return nil, err
+	}
+	ecdsaKey, ok := publicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("got a %T public key, want an ECDSA key", publicKey)
+	}
+	return ecdsaKey, nil
+}
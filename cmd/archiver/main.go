@@ -0,0 +1,133 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/project-oak/transparent-release/internal/archive"
+)
+
+type pathsFlag []string
+
+func (f *pathsFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *pathsFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+//nolint:gochecknoglobals
+var (
+	provenancePaths  pathsFlag
+	endorsementPaths pathsFlag
+	evidencePaths    pathsFlag
+)
+
+func main() {
+	flag.Var(&provenancePaths, "provenance", "Path to a provenance. Can be repeated.")
+	flag.Var(&endorsementPaths, "endorsement",
+		"Path to a signed endorsement as a DSSE envelope, followed by a comma and the path to its Rekor log entry. Can be repeated.")
+	flag.Var(&evidencePaths, "evidence", "Path to any other evidence, e.g., a verification report. Can be repeated.")
+	rekorPublicKeyPath := flag.String("rekor_public_key", "",
+		"Path to the PEM-encoded public key of the Rekor instance.")
+	endorserPublicKeyPath := flag.String("endorser_public_key", "",
+		"Path to the PEM-encoded public key of the product team that signed the endorsements.")
+	outputPath := flag.String("output_path", "",
+		"Full path to store the archive. The outer digest is stored next to it, with a `.sha256` suffix.")
+	flag.Parse()
+
+	if *outputPath == "" {
+		log.Fatalf("--output_path not set")
+	}
+	if *rekorPublicKeyPath == "" || *endorserPublicKeyPath == "" {
+		log.Fatalf("--rekor_public_key and --endorser_public_key are required")
+	}
+
+	files, err := collectFiles(*rekorPublicKeyPath, *endorserPublicKeyPath)
+	if err != nil {
+		log.Fatalf("Failed collecting files: %v", err)
+	}
+
+	var buf bytes.Buffer
+	digest, err := archive.Write(&buf, files)
+	if err != nil {
+		log.Fatalf("Failed creating the archive: %v", err)
+	}
+	if err := os.WriteFile(*outputPath, buf.Bytes(), 0600); err != nil {
+		log.Fatalf("Failed writing the archive: %v", err)
+	}
+	if err := os.WriteFile(*outputPath+".sha256", []byte(digest+"\n"), 0600); err != nil {
+		log.Fatalf("Failed writing the outer digest: %v", err)
+	}
+	log.Printf("Archive with outer digest sha256:%s stored in %s", digest, *outputPath)
+}
+
+// collectFiles reads all files given in the flags. Every file is stored in
+// the archive under its base name, prefixed with a directory for its role.
+func collectFiles(rekorPublicKeyPath, endorserPublicKeyPath string) ([]archive.File, error) {
+	var files []archive.File
+	add := func(path, dir, role, subject string) (string, error) {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("reading %q: %v", path, err)
+		}
+		name := filepath.Base(path)
+		if dir != "" {
+			name = dir + "/" + name
+		}
+		files = append(files, archive.File{Path: name, Role: role, Subject: subject, Content: content})
+		return name, nil
+	}
+
+	for _, path := range provenancePaths {
+		if _, err := add(path, "provenances", archive.ProvenanceRole, ""); err != nil {
+			return nil, err
+		}
+	}
+	for _, paths := range endorsementPaths {
+		envelopePath, logEntryPath, ok := strings.Cut(paths, ",")
+		if !ok {
+			return nil, fmt.Errorf("--endorsement %q does not contain the path to a Rekor log entry", paths)
+		}
+		name, err := add(envelopePath, "endorsements", archive.EndorsementRole, "")
+		if err != nil {
+			return nil, err
+		}
+		if _, err := add(logEntryPath, "endorsements", archive.LogEntryRole, name); err != nil {
+			return nil, err
+		}
+	}
+	for _, path := range evidencePaths {
+		if _, err := add(path, "evidence", archive.EvidenceRole, ""); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := add(rekorPublicKeyPath, "trust_roots", archive.RekorPublicKeyRole, ""); err != nil {
+		return nil, err
+	}
+	if _, err := add(endorserPublicKeyPath, "trust_roots", archive.EndorserPublicKeyRole, ""); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
@@ -0,0 +1,188 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/project-oak/transparent-release/internal/builder"
+	"github.com/project-oak/transparent-release/internal/rebuild"
+	"github.com/project-oak/transparent-release/pkg/claims"
+	"github.com/project-oak/transparent-release/pkg/intoto"
+	slsav1 "github.com/project-oak/transparent-release/pkg/intoto/slsa_provenance/v1"
+	"github.com/project-oak/transparent-release/pkg/toolchain"
+)
+
+// buildCommand is the name of the subcommand that runs a containerized build,
+// and generates its unsigned provenance.
+const buildCommand = "build"
+
+// runBuild runs the build subcommand with the given arguments: it runs the
+// build described in a predicate, either generated by generate-predicate, or
+// generated from the build config flags, and writes the unsigned provenance.
+// A build config with several outputs yields several builds, which run one
+// after the other in the same checkout.
+func runBuild(args []string) {
+	flags := flag.NewFlagSet(buildCommand, flag.ExitOnError)
+	predicatePath := flags.String("predicate_path", "",
+		"Path to a predicate written by generate-predicate. Cannot be combined with --config_path.")
+	configPath := flags.String("config_path", "",
+		"Path of the build config, as for generate-predicate, relative to the root of the repository, which must be the current directory. Requires --docker_image and --image_digest.")
+	dockerImage := flags.String("docker_image", "",
+		"The builder image, as for generate-predicate.")
+	imageDigest := flags.String("image_digest", "",
+		"The SHA256 digest of the builder image, as for generate-predicate.")
+	builderID := flags.String("builder_id", "",
+		"Optional builder ID, as for generate-predicate.")
+	sourceDir := flags.String("source_dir", ".",
+		"Path of the checkout of the source, which is mounted into the builder image.")
+	toolchains := flags.String("toolchains", "",
+		toolchainsUsage+strings.Join(toolchain.SupportedToolchains(), ", ")+". Only used with --config_path.")
+	recordTree := flags.Bool("record_tree", false,
+		"Whether to record the Git tree of the commit, from the checkout in --source_dir, next to the commit in the predicates generated from --config_path.")
+	outputPath := flags.String("output_path", "",
+		"Full path to store the unsigned provenance, as an in-toto statement, if there is a single provenance.")
+	outputDir := flags.String("output_dir", "",
+		"Directory to store the unsigned provenances, as `<name>.provenance.json`, if there are several, e.g., for a config with several outputs, or with --output_mode=per_artifact.")
+	outputMode := flags.String("output_mode", multiSubjectMode,
+		"Either `multi_subject` for a provenance per build, with all artifacts matching the artifact path as subjects, or `per_artifact` for a provenance per artifact.")
+	containerRuntime := flags.String("container_runtime", rebuild.DockerRuntime,
+		"The container runtime that runs the build: `docker`, `podman`, or `nerdctl`. Podman and nerdctl do not need a Docker daemon, and can run rootless.")
+	logsDir := flags.String("logs_dir", "",
+		"Optional directory to store the output of the build, and its summary. If set, the build log is recorded as a byproduct in the provenance.")
+	timeout := flags.Duration("timeout", 0,
+		"Optional timeout, e.g., 1h, after which the builds are aborted.")
+	// ExitOnError makes Parse exit on errors.
+	_ = flags.Parse(args)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+
+	if *outputPath == "" && *outputDir == "" {
+		log.Fatalf("--output_path or --output_dir must be set")
+	}
+	if *outputMode != multiSubjectMode && *outputMode != perArtifactMode {
+		log.Fatalf("invalid --output_mode %q, want %s or %s", *outputMode, multiSubjectMode, perArtifactMode)
+	}
+	if (*predicatePath == "") == (*configPath == "") {
+		log.Fatalf("exactly one of --predicate_path and --config_path must be set")
+	}
+
+	var predicates []*slsav1.ProvenancePredicate
+	if *predicatePath != "" {
+		predicate, err := builder.ParsePredicateFile(*predicatePath)
+		if err != nil {
+			log.Fatalf("couldn't get the predicate: %v", err)
+		}
+		predicates = append(predicates, predicate)
+	} else {
+		treeDir := ""
+		if *recordTree {
+			treeDir = *sourceDir
+		}
+		var err error
+		predicates, err = generatePredicates(*configPath, *dockerImage, *imageDigest, *builderID, treeDir, parseToolchains(*toolchains), *containerRuntime)
+		if err != nil {
+			log.Fatalf("couldn't get the predicates: %v", err)
+		}
+	}
+
+	runner, err := rebuild.NewContainerRunner(*containerRuntime)
+	if err != nil {
+		log.Fatalf("invalid --container_runtime: %v", err)
+	}
+	options := []func(c *rebuild.RunConfig){rebuild.WithRunner(runner)}
+	if *logsDir != "" {
+		options = append(options, rebuild.WithLogsDir(*logsDir))
+	}
+	provenances, err := builder.BuildAll(ctx, predicates, *sourceDir, claims.SystemClock(), options...)
+	if err != nil {
+		log.Fatalf("couldn't run the build: %v", err)
+	}
+	if *outputMode == perArtifactMode {
+		var split []*intoto.Statement
+		for _, provenance := range provenances {
+			split = append(split, builder.SplitProvenance(provenance)...)
+		}
+		provenances = split
+	}
+
+	if len(provenances) == 1 && *outputDir == "" {
+		if err := intoto.WriteStatement(*outputPath, provenances[0]); err != nil {
+			log.Fatalf("couldn't write the provenance to %s: %v", *outputPath, err)
+		}
+		log.Printf("The provenance of %s is stored in %s", subjectNames(provenances[0]), *outputPath)
+		return
+	}
+	if *outputDir == "" {
+		log.Fatalf("--output_dir must be set for %d provenances", len(provenances))
+	}
+	if err := writeProvenances(*outputDir, provenances); err != nil {
+		log.Fatalf("couldn't write the provenances: %v", err)
+	}
+}
+
+// Output modes of the build subcommand.
+const (
+	multiSubjectMode = "multi_subject"
+	perArtifactMode  = "per_artifact"
+)
+
+// writeProvenances writes the given provenances to the given directory, each
+// named by its subject if it has a single subject, and by its index
+// otherwise.
+func writeProvenances(dir string, provenances []*intoto.Statement) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("couldn't create %s: %v", dir, err)
+	}
+	paths := make(map[string]bool, len(provenances))
+	for i, provenance := range provenances {
+		name := fmt.Sprintf("output-%d", i)
+		if len(provenance.Subject) == 1 {
+			name = provenance.Subject[0].Name
+		}
+		path := filepath.Join(dir, name+".provenance.json")
+		if paths[path] {
+			return fmt.Errorf("several provenances would be stored in %s", path)
+		}
+		paths[path] = true
+		if err := intoto.WriteStatement(path, provenance); err != nil {
+			return fmt.Errorf("couldn't write the provenance to %s: %v", path, err)
+		}
+		log.Printf("The provenance of %s is stored in %s", subjectNames(provenance), path)
+	}
+	return nil
+}
+
+func subjectNames(provenance *intoto.Statement) string {
+	names := make([]string, 0, len(provenance.Subject))
+	for _, subject := range provenance.Subject {
+		names = append(names, subject.Name)
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,67 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"flag"
+	"log"
+	"strings"
+
+	"github.com/project-oak/transparent-release/internal/rebuild"
+	"github.com/project-oak/transparent-release/pkg/toolchain"
+)
+
+// generatePredicateCommand is the name of the subcommand that generates an
+// unsigned SLSA v1 predicate from a build config.
+const generatePredicateCommand = "generate-predicate"
+
+// runGeneratePredicate runs the generate-predicate subcommand with the given
+// arguments: it resolves the build config, and writes an unsigned SLSA v1
+// predicate for building it, without running the build.
+func runGeneratePredicate(args []string) {
+	flags := flag.NewFlagSet(generatePredicateCommand, flag.ExitOnError)
+	configPath := flags.String("config_path", "",
+		"Path of the build config, as JSON if it has a .json extension, or as TOML otherwise, relative to the root of the repository, which must be the current directory.")
+	dockerImage := flags.String("docker_image", "",
+		"The builder image, e.g., europe-west2-docker.pkg.dev/oak-ci/oak-development. Any tag or digest is kept, and the image is pinned by --image_digest.")
+	imageDigest := flags.String("image_digest", "",
+		"The SHA256 digest of the builder image, hex-encoded, optionally prefixed with `sha256:`.")
+	builderID := flags.String("builder_id", "",
+		"Optional builder ID of the predicate. Defaults to the URI of the workflow that runs the build, from GITHUB_WORKFLOW_REF.")
+	toolchains := flags.String("toolchains", "",
+		toolchainsUsage+strings.Join(toolchain.SupportedToolchains(), ", ")+". They are queried with docker run.")
+	recordTree := flags.Bool("record_tree", false,
+		"Whether to record the Git tree of the commit, from the Git repository in the current directory, next to the commit in the predicate.")
+	outputPath := flags.String("output_path", "",
+		"Full path to store the predicate as JSON.")
+	// ExitOnError makes Parse exit on errors.
+	_ = flags.Parse(args)
+
+	if *outputPath == "" {
+		log.Fatalf("--output_path not set")
+	}
+	treeDir := ""
+	if *recordTree {
+		treeDir = "."
+	}
+	predicate, err := generatePredicate(*configPath, *dockerImage, *imageDigest, *builderID, treeDir, parseToolchains(*toolchains), rebuild.DockerRuntime)
+	if err != nil {
+		log.Fatalf("couldn't generate the predicate: %v", err)
+	}
+	if err := writeJSON(*outputPath, predicate); err != nil {
+		log.Fatalf("couldn't write the predicate to %s: %v", *outputPath, err)
+	}
+	log.Printf("The predicate is stored in %s", *outputPath)
+}
@@ -0,0 +1,147 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/project-oak/transparent-release/internal/builder"
+	"github.com/project-oak/transparent-release/internal/rebuild"
+	slsav1 "github.com/project-oak/transparent-release/pkg/intoto/slsa_provenance/v1"
+	"github.com/project-oak/transparent-release/pkg/toolchain"
+)
+
+// toolchainsUsage is the usage of the --toolchains flag of the subcommands
+// that generate predicates.
+const toolchainsUsage = "Optional comma-separated names of toolchains, e.g., `rustc,cargo`, whose versions are queried in the builder image and recorded as resolved dependencies of the predicates. Supported toolchains: "
+
+func main() {
+	// The subcommands have their own flags; see generate_predicate.go and
+	// build.go.
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case generatePredicateCommand:
+			runGeneratePredicate(os.Args[2:])
+			return
+		case buildCommand:
+			runBuild(os.Args[2:])
+			return
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Usage: %s %s|%s [flags]\n", os.Args[0], generatePredicateCommand, buildCommand)
+	os.Exit(2)
+}
+
+// generatePredicate loads the build config at the given path, relative to the
+// root of the repository, which must be the current directory, and returns an
+// unsigned predicate for building it in the given builder image, in the
+// context of the current GitHub Actions workflow run. The versions of the
+// given toolchains are queried in the builder image, with the given container
+// runtime.
+func generatePredicate(configPath, dockerImage, imageDigest, builderID, treeDir string, toolchains []string, containerRuntime string) (*slsav1.ProvenancePredicate, error) {
+	if configPath == "" {
+		return nil, fmt.Errorf("--config_path not set")
+	}
+	config, err := builder.LoadBuildConfig(configPath)
+	if err != nil {
+		return nil, err
+	}
+	predicates, err := generatePredicatesFor(configPath, []*slsav1.BuildConfig{config}, dockerImage, imageDigest, builderID, treeDir, toolchains, containerRuntime)
+	if err != nil {
+		return nil, err
+	}
+	return predicates[0], nil
+}
+
+// generatePredicates is like generatePredicate, but returns a predicate for
+// each output of the build config.
+func generatePredicates(configPath, dockerImage, imageDigest, builderID, treeDir string, toolchains []string, containerRuntime string) ([]*slsav1.ProvenancePredicate, error) {
+	if configPath == "" {
+		return nil, fmt.Errorf("--config_path not set")
+	}
+	configs, err := builder.LoadBuildConfigs(configPath)
+	if err != nil {
+		return nil, err
+	}
+	return generatePredicatesFor(configPath, configs, dockerImage, imageDigest, builderID, treeDir, toolchains, containerRuntime)
+}
+
+// generatePredicatesFor returns a predicate for each of the given build
+// configs. If treeDir is not empty, the predicates record the Git tree of the
+// commit in the Git repository in treeDir. If toolchains is not empty, the
+// predicates record the versions of the toolchains in the builder image.
+func generatePredicatesFor(configPath string, configs []*slsav1.BuildConfig, dockerImage, imageDigest, builderID, treeDir string, toolchains []string, containerRuntime string) ([]*slsav1.ProvenancePredicate, error) {
+	if dockerImage == "" || imageDigest == "" {
+		return nil, fmt.Errorf("--docker_image and --image_digest are required")
+	}
+	github, err := builder.GitHubContextFromEnv()
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get the GitHub context: %v", err)
+	}
+	var options []func(c *builder.PredicateConfig)
+	if builderID != "" {
+		options = append(options, builder.WithBuilderID(builderID))
+	}
+	if treeDir != "" {
+		tree, err := rebuild.GitCLI{}.Tree(context.Background(), treeDir, github.SHA)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't get the Git tree of the source: %v", err)
+		}
+		options = append(options, builder.WithTree(tree))
+	}
+	if len(toolchains) > 0 {
+		// Query the image that the build runs in, pinned by its digest.
+		image := strings.SplitN(dockerImage, "@", 2)[0] + "@sha256:" + strings.TrimPrefix(imageDigest, "sha256:")
+		versions, err := toolchain.QueryVersions(context.Background(), image, toolchains, toolchain.WithRunner(toolchain.DockerRunner{Runtime: containerRuntime}))
+		if err != nil {
+			return nil, fmt.Errorf("couldn't get the toolchain versions: %v", err)
+		}
+		options = append(options, builder.WithToolchainVersions(versions))
+	}
+	predicates := make([]*slsav1.ProvenancePredicate, 0, len(configs))
+	for _, config := range configs {
+		predicate, err := builder.GeneratePredicate(filepath.ToSlash(filepath.Clean(configPath)), config, dockerImage, imageDigest, github, options...)
+		if err != nil {
+			return nil, err
+		}
+		predicates = append(predicates, predicate)
+	}
+	return predicates, nil
+}
+
+// parseToolchains returns the names of the toolchains in the given value of
+// the --toolchains flag.
+func parseToolchains(value string) []string {
+	var toolchains []string
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			toolchains = append(toolchains, name)
+		}
+	}
+	return toolchains
+}
+
+func writeJSON(path string, object interface{}) error {
+	bytes, err := json.MarshalIndent(object, "", "    ")
+	if err != nil {
+		return fmt.Errorf("marshalling to JSON: %v", err)
+	}
+	return os.WriteFile(path, bytes, 0600)
+}
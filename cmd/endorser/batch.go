@@ -0,0 +1,137 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/project-oak/transparent-release/internal/endorser"
+	"github.com/project-oak/transparent-release/internal/verifier"
+	"github.com/project-oak/transparent-release/pkg/claims"
+	"github.com/project-oak/transparent-release/pkg/intoto"
+)
+
+// summaryFileName is the name of the summary report in the output directory
+// of a batch.
+const summaryFileName = "summary.json"
+
+// batchConfig holds the settings shared by all binaries in a batch.
+type batchConfig struct {
+	validity                  *claims.ClaimValidity
+	endorsementOptions        []func(c *claims.EndorsementConfig)
+	loadOptions               []func(c *endorser.LoadConfig)
+	referenceValuesFromSource bool
+	signing                   *signingConfig
+}
+
+// batchResult is the outcome of endorsing a single binary in a batch.
+type batchResult struct {
+	Name       string `json:"name"`
+	Endorsed   bool   `json:"endorsed"`
+	OutputPath string `json:"outputPath,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// endorseBatch endorses all binaries in the manifest at manifestPath, and
+// stores the endorsements, named after the binaries, and a summary report in
+// outputDir. Failing to endorse a binary does not stop the batch, but results
+// in an error once all binaries have been processed.
+func endorseBatch(ctx context.Context, manifestPath, outputDir string, config *batchConfig) error {
+	manifest, err := endorser.LoadManifest(manifestPath)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(outputDir, 0o700); err != nil {
+		return fmt.Errorf("creating the output directory: %v", err)
+	}
+
+	results := make([]batchResult, 0, len(manifest.Binaries))
+	failures := 0
+	for _, entry := range manifest.Binaries {
+		outputPath := filepath.Join(outputDir, entry.Name+".json")
+		result := batchResult{Name: entry.Name}
+		if err := endorseEntry(ctx, entry, outputPath, config); err != nil {
+			log.Printf("Failed to endorse %s: %v", entry.Name, err)
+			result.Error = err.Error()
+			failures++
+		} else {
+			log.Printf("Endorsed %s in %s", entry.Name, outputPath)
+			result.Endorsed = true
+			result.OutputPath = outputPath
+		}
+		results = append(results, result)
+	}
+
+	if err := writeJSON(filepath.Join(outputDir, summaryFileName), results); err != nil {
+		return fmt.Errorf("writing the summary report: %v", err)
+	}
+	if failures > 0 {
+		return fmt.Errorf("failed to endorse %d of %d binaries, see %s", failures, len(results), summaryFileName)
+	}
+	return nil
+}
+
+// endorseEntry endorses the binary described in the given manifest entry, and
+// stores the endorsement at outputPath.
+func endorseEntry(ctx context.Context, entry endorser.ManifestEntry, outputPath string, config *batchConfig) error {
+	verOpts, err := verifier.ParseVerificationOptions(entry.VerificationOptions)
+	if err != nil {
+		return err
+	}
+
+	var digests *intoto.DigestSet
+	if entry.BinaryPath != "" {
+		digests, err = computeBinaryDigests(entry.BinaryPath)
+		if err != nil {
+			return err
+		}
+	} else {
+		digests = &intoto.DigestSet{"sha2-256": entry.Digest}
+	}
+
+	loadOptions := append([]func(c *endorser.LoadConfig){endorser.WithSubjectDigests(*digests)}, config.loadOptions...)
+	if entry.SubjectName != "" {
+		loadOptions = append(loadOptions, endorser.WithSubjectName(entry.SubjectName))
+	}
+	provenances, err := endorser.LoadProvenances(ctx, entry.ProvenanceURIs, loadOptions...)
+	if err != nil {
+		return fmt.Errorf("loading provenances: %v", err)
+	}
+
+	endorsementOptions := config.endorsementOptions
+	if config.referenceValuesFromSource {
+		if entry.ReferenceValuesDigest == "" {
+			return fmt.Errorf("--reference_values_from_source requires a reference_values_digest")
+		}
+		evidence, err := verifyReferenceValues(ctx, provenances, entry.ReferenceValuesDigest)
+		if err != nil {
+			return fmt.Errorf("verifying the provenances against the reference values: %v", err)
+		}
+		endorsementOptions = append(append([]func(c *claims.EndorsementConfig){}, endorsementOptions...), claims.WithEvidence(*evidence))
+	}
+
+	endorsement, err := endorser.GenerateEndorsement(entry.Name, *digests, verOpts, *config.validity, provenances, endorsementOptions...)
+	if err != nil {
+		return err
+	}
+	if err := config.signing.writeEndorsement(endorsement, outputPath); err != nil {
+		return fmt.Errorf("writing the endorsement statement to file: %v", err)
+	}
+	return config.signing.signAndPublish(ctx, endorsement, outputPath)
+}
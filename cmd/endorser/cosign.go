@@ -0,0 +1,67 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/project-oak/transparent-release/internal/endorser"
+)
+
+// cosignCommand is the name of the subcommand that prepares an endorsement of
+// a container image for attaching it with `cosign attest`.
+const cosignCommand = "cosign"
+
+// runCosign runs the cosign subcommand with the given arguments: it writes
+// the predicate of an endorsement of a container image to a file, and prints
+// the `cosign attest` command that attaches it to the image.
+func runCosign(args []string) {
+	flags := flag.NewFlagSet(cosignCommand, flag.ExitOnError)
+	endorsementPath := flags.String("endorsement_path", "",
+		"Path to the endorsement of the image, as a bare in-toto statement or a DSSE envelope, e.g., as generated with --image_ref or --image_digest.")
+	image := flags.String("image", "",
+		"The repository of the image, e.g., `ghcr.io/project-oak/oak`, for printing the cosign command.")
+	imageDigest := flags.String("image_digest", "",
+		"The digest of the image manifest, `sha256:<hex digest>`, which must be the subject of the endorsement.")
+	predicatePath := flags.String("predicate_path", "",
+		"Where the predicate of the endorsement goes, for passing it to `cosign attest --predicate`.")
+	// ExitOnError makes Parse exit on errors.
+	_ = flags.Parse(args)
+
+	if *endorsementPath == "" || *imageDigest == "" || *predicatePath == "" {
+		log.Fatalf("--endorsement_path, --image_digest, and --predicate_path are required")
+	}
+	endorsementBytes, err := os.ReadFile(*endorsementPath)
+	if err != nil {
+		log.Fatalf("couldn't read the endorsement: %v", err)
+	}
+	predicate, err := endorser.NewCosignPredicate(endorsementBytes, *imageDigest)
+	if err != nil {
+		log.Fatalf("couldn't prepare the endorsement for cosign: %v", err)
+	}
+	if err := os.WriteFile(*predicatePath, predicate.Predicate, 0o600); err != nil {
+		log.Fatalf("couldn't write the predicate: %v", err)
+	}
+
+	log.Printf("Stored the predicate of type %s in %s", predicate.Type, *predicatePath)
+	repository := *image
+	if repository == "" {
+		repository = "<image>"
+	}
+	fmt.Printf("cosign attest --type %s --predicate %s %s@%s\n", predicate.Type, *predicatePath, repository, *imageDigest)
+}
@@ -15,25 +15,52 @@
 package main
 
 import (
+	"context"
 	"crypto/sha256"
 	"crypto/sha512"
+	"crypto/x509"
 	"encoding/hex"
 	"encoding/json"
+	"encoding/pem"
 	"flag"
 	"fmt"
 	"log"
+	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
 	"time"
 
+	"github.com/project-oak/transparent-release/internal/cache"
+	"github.com/project-oak/transparent-release/internal/cmdutil"
 	"github.com/project-oak/transparent-release/internal/endorser"
+	"github.com/project-oak/transparent-release/internal/ent"
+	"github.com/project-oak/transparent-release/internal/fetch"
+	"github.com/project-oak/transparent-release/internal/model"
+	"github.com/project-oak/transparent-release/internal/oci"
+	"github.com/project-oak/transparent-release/internal/oidc"
+	"github.com/project-oak/transparent-release/internal/rekor"
+	"github.com/project-oak/transparent-release/internal/sigstore"
 	"github.com/project-oak/transparent-release/internal/verifier"
 	"github.com/project-oak/transparent-release/pkg/claims"
 	"github.com/project-oak/transparent-release/pkg/intoto"
+	pb "github.com/project-oak/transparent-release/pkg/proto/oak/release"
+	"github.com/project-oak/transparent-release/pkg/sign"
+	"github.com/secure-systems-lab/go-securesystemslib/dsse"
 )
 
 // ISO 8601 layout for representing input dates.
 const dateLayout = "2006-01-02"
 
+// Supported values of --output_format.
+const (
+	statementFormat = "statement"
+	dsseFormat      = "dsse"
+)
+
 type provenanceURIsFlag []string
 
 func (f *provenanceURIsFlag) String() string {
@@ -48,82 +75,804 @@ func (f *provenanceURIsFlag) Set(value string) error {
 //nolint:gochecknoglobals
 var provenanceURIs provenanceURIsFlag
 
+type evidenceFlag []string
+
+func (f *evidenceFlag) String() string {
+	return "Auxiliary evidence"
+}
+
+func (f *evidenceFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+//nolint:gochecknoglobals
+var auxiliaryEvidence evidenceFlag
+
+type binaryDigestsFlag []string
+
+func (f *binaryDigestsFlag) String() string {
+	return "Binary digest"
+}
+
+func (f *binaryDigestsFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+//nolint:gochecknoglobals
+var binaryDigests binaryDigestsFlag
+
 //nolint:cyclop
 func main() {
+	// The subcommands have their own flags; see cosign.go.
+	if len(os.Args) > 1 && os.Args[1] == cosignCommand {
+		runCosign(os.Args[2:])
+		return
+	}
+
+	serveAddress := flag.String("serve_address", "",
+		"Address, e.g., :8080, on which to serve endorsement requests over HTTP at "+endorser.EndorsementsPath+", instead of endorsing a single binary. Requires --kms_key_uri and --caller_audience.")
+	callerAudience := flag.String("caller_audience", "",
+		"The audience of the GitHub Actions OIDC tokens that callers must present with --serve_address, whose repository must match the provenances. Required with --serve_address.")
+	manifestPath := flag.String("manifest", "",
+		"Path to a TOML manifest, or a YAML manifest with a .yaml or .yml extension, describing several binaries to endorse. If set, --output_path is a directory, in which an endorsement per binary and a summary are stored.")
 	binaryName := flag.String("binary_name", "",
 		"Name of the binary to endorse. Must match the binary names in all provenances.")
 	binaryPath := flag.String("binary_path", "",
 		"Location of the binary in the local file system. Required only for computing digests.")
+	flag.Var(&binaryDigests, "binary_digest",
+		"Digest of the binary, of the form `<algorithm>:<hex>`, e.g., sha2-256:<hex digest>, as an alternative to --binary_path for binaries that are not available locally. Can be repeated for several algorithms, of which at least one must be SHA-2 or SHA-3.")
+	imageRef := flag.String("image_ref", "",
+		"Reference of a container image in an OCI registry, e.g., ghcr.io/project-oak/oak:latest, to endorse instead of a binary. The digest of the image is resolved, and the attestations attached to it, as referrers or with the cosign `.att` tag, are loaded as provenances, in addition to --provenance_uris. The subject name defaults to the image name, unless --binary_name is set.")
+	imageDigest := flag.String("image_digest", "",
+		"Digest of the manifest of a container image, of the form sha256:<hex digest>, to endorse instead of a binary, without hashing a local file. Requires --binary_name, set to the image name, e.g., ghcr.io/project-oak/oak.")
+	imageMediaType := flag.String("image_media_type", oci.ImageManifestMediaType,
+		"Media type of the manifest at --image_digest, recorded in the subject of the endorsement.")
+	configPath := flag.String("config_path", "",
+		"Path to a configuration artifact, such as a policy, to endorse instead of a binary. The subject of the endorsement is the digest of the file, which must parse as --config_kind. The subject name defaults to the file name, unless --binary_name is set.")
+	configKind := flag.String("config_kind", endorser.PolicyConfigKind,
+		"Kind of the configuration artifact at --config_path: `"+endorser.PolicyConfigKind+"` or `"+endorser.ReferenceValuesConfigKind+"`.")
 	flag.Var(&provenanceURIs, "provenance_uris",
 		"Comma-separated URIs of zero or more provenances.")
+	flag.Var(&auxiliaryEvidence, "evidence",
+		"Auxiliary evidence to attach to the endorsement, e.g., test logs or scanner reports, as `uri=<URI>,role=<role>,digest=<algorithm>:<hex>`. The digest may be given several times. Can be repeated.")
+	subjectName := flag.String("subject_name", "",
+		"Name of the subject to select from provenances with several subjects. By default, the subject is selected by the digest of --binary_path or --binary_digest.")
+	requireEnvelope := flag.Bool("require_envelope", false,
+		"Reject provenances that are bare in-toto statements, and only accept provenances in DSSE envelopes.")
+	fulcioRootsPath := flag.String("fulcio_roots", "",
+		"Optional path to PEM-encoded Fulcio root certificates. If set, provenances in Sigstore bundles are verified against them and --bundle_rekor_public_key, so that their certificate identity can be checked with the all_with_certificate_identity verification option.")
+	bundleRekorPublicKeyPath := flag.String("bundle_rekor_public_key", "",
+		"Path to the PEM-encoded public key of the Rekor instance that logged the provenances in Sigstore bundles. Required with --fulcio_roots.")
 	verOptsTextproto := flag.String("verification_options", "",
 		"An instance of VerificationOptions as inline textproto.")
+	policyPath := flag.String("policy", "",
+		"Path to a file with VerificationOptions, as YAML if it has a .yaml or .yml extension, or as textproto otherwise. Cannot be combined with --verification_options.")
+	registryPath := flag.String("registry", "",
+		"Optional path to a TOML registry of VerificationOptions keyed by binary name, from which the options for --binary_name are used. Cannot be combined with --policy or --verification_options.")
 	skipVerification := flag.Bool("skip_verification", false,
-		"Confirms that empty --verification_options is intended.")
+		"Confirms that empty --verification_options and --policy are intended.")
+	referenceValuesFromSource := flag.Bool("reference_values_from_source", false,
+		"Additionally verify the provenances against the reference values in "+endorser.ReferenceValuesPath+" in the source repository, at the commit of the provenances.")
+	referenceValuesDigest := flag.String("reference_values_digest", "",
+		"The expected hex-encoded SHA256 digest of the reference values fetched with --reference_values_from_source. Required with --reference_values_from_source, unless --manifest is set, which has a reference_values_digest per binary instead.")
 	notBefore := flag.String("not_before", "",
 		"The date from which the endorsement is effective, formatted as YYYY-MM-DD. Defaults to 1 day after the issuance date.")
 	notAfter := flag.String("not_after", "",
 		"The expiry date of the endorsement, formatted as YYYY-MM-DD. Defaults to 90 day after the issuance date.")
 	outputPath := flag.String("output_path", "",
 		"Full path to store the generated endorsement statement as JSON.")
+	pinEvidence := flag.Bool("pin_evidence", false,
+		"Fetch every evidence of the endorsement, store it content-addressed in the `evidence` directory next to --output_path, and reference it by its bundle-relative `bundle:evidence/<sha256>` URI, so that the endorsement remains verifiable if the original URIs disappear.")
+	outputFormat := flag.String("output_format", statementFormat,
+		"Format of the endorsement at --output_path: `statement` for a bare in-toto statement, or `dsse` for a DSSE envelope with payload type "+intoto.PayloadType+". The envelope is unsigned, unless --kms_key_uri is set.")
+	statementType := flag.String("statement_type", intoto.StatementInTotoV01,
+		"The statement type URI of the generated endorsement statement, either "+intoto.StatementInTotoV01+" or "+intoto.StatementInTotoV1+".")
+	predicateType := flag.String("predicate_type", claims.ClaimV1,
+		"The predicate type URI of the generated endorsement statement. Endorsements with a predicate type other than "+claims.ClaimV1+" cannot be verified or extended by the tools in this repository yet.")
+	claimType := flag.String("claim_type", claims.EndorsementV2,
+		"The claim type URI of the generated endorsement statement. Endorsements with a claim type other than "+claims.EndorsementV2+" cannot be verified or extended by the tools in this repository yet.")
+	sign := flag.Bool("sign", false,
+		"Sign the endorsement using Sigstore keyless signing, and store the signed Sigstore bundle in --bundle_path.")
+	bundlePath := flag.String("bundle_path", "",
+		"Full path to store the signed endorsement as a Sigstore bundle. Defaults to --output_path with a `.sigstore.json` suffix.")
+	fulcioURL := flag.String("fulcio_url", sigstore.DefaultFulcioURL,
+		"URL of the Fulcio instance for issuing signing certificates.")
+	identityToken := flag.String("identity_token", "",
+		"OIDC identity token for keyless signing. Defaults to the ambient token, e.g., from GitHub Actions.")
+	kmsKeyURI := flag.String("kms_key_uri", "",
+		"URI of a Google Cloud KMS key version for signing the endorsement, of the form gcpkms://projects/P/locations/L/keyRings/R/cryptoKeys/K/cryptoKeyVersions/V.")
+	envelopePath := flag.String("envelope_path", "",
+		"Full path to store the KMS-signed endorsement as a DSSE envelope. Defaults to --output_path with a `.dsse.json` suffix.")
+	rekorURL := flag.String("rekor_url", "",
+		"URL of a Rekor instance, e.g., https://rekor.sigstore.dev. If set, the signed endorsement is uploaded to it.")
+	logEntryPath := flag.String("log_entry_path", "",
+		"Full path to store the Rekor log entry of the signed endorsement. Defaults to --output_path with a `.rekor.json` suffix.")
+	releaseBundlePath := flag.String("release_bundle_path", "",
+		"Optional path to store a release bundle, combining the KMS-signed endorsement, its Rekor log entry, the provenances it references and their Rekor log entries, and the public keys of Rekor and of --kms_key_uri, for verifying the release offline. Requires --kms_key_uri, --rekor_url, and --rekor_public_key.")
+	rekorPublicKeyPath := flag.String("rekor_public_key", "",
+		"Path to the PEM-encoded public key of the Rekor instance at --rekor_url, included in --release_bundle_path.")
+	entURL := flag.String("ent_url", ent.DefaultURL,
+		"URL of the Ent server for --ent_api_key.")
+	entAPIKey := flag.String("ent_api_key", "",
+		"API key of the Ent server at --ent_url. If set, the provenances are copied to Ent, and referenced by their `ent:` URIs in the endorsement.")
+	emitReportPath := flag.String("emit_verification_report", "",
+		"Phase 1 of two-phase issuance: verify the provenances, and store a verification report signed with --kms_key_uri at the given path, instead of generating an endorsement.")
+	reportURI := flag.String("verification_report", "",
+		"Phase 2 of two-phase issuance: URI of a signed verification report, from which the endorsement is issued without loading and verifying provenances.")
+	reportPublicKeyPath := flag.String("verification_report_public_key", "",
+		"Path to the PEM-encoded public key for verifying the signature on --verification_report.")
+	extendURI := flag.String("extend", "",
+		"URI of an existing endorsement, as a DSSE envelope signed with --kms_key_uri, to renew. Its evidence is re-verified, its provenances are verified against the verification options, and a fresh endorsement of the same subject is issued with the validity of --not_before and --not_after, referencing the existing endorsement as evidence.")
+	reportMaxAge := flag.Duration("verification_report_max_age", 24*time.Hour,
+		"Maximum age of --verification_report at the time of issuance.")
+	now := flag.String("now", "",
+		"Overrides the current time, as an RFC3339 timestamp.")
+	revoke := flag.Bool("revoke", false,
+		"Revoke all claims about the binary at --binary_path, instead of endorsing it, and store the revocation as a DSSE envelope signed with --revocation_kms_key_uri at --output_path.")
+	revokeEndorsementPath := flag.String("revoke_endorsement", "",
+		"Path to a signed endorsement, as a DSSE envelope. If set, only this endorsement is revoked, by its statement hash, instead of endorsing a binary, and the revocation is stored as a DSSE envelope signed with --revocation_kms_key_uri at --output_path.")
+	revocationReason := flag.String("revocation_reason", "",
+		"Optional human-readable reason for --revoke or --revoke_endorsement, e.g., a CVE.")
+	revocationKMSKeyURI := flag.String("revocation_kms_key_uri", "",
+		"URI of the Google Cloud KMS key version of the revocation authority, for signing revocations. Must be different from --kms_key_uri.")
+	cacheDir := flag.String("cache_dir", "",
+		"Optional directory for caching fetched provenances across runs, e.g., in CI. Only provenances referenced by their SHA2-256 digest are cached, by digest, and expire after --cache_ttl.")
+	cacheTTL := flag.Duration("cache_ttl", cache.DefaultTTL,
+		"Time after which the provenances cached in --cache_dir expire.")
+	cacheMaxBytes := flag.Int64("cache_max_bytes", cache.DefaultMaxBytes,
+		"Maximum total size of the provenances cached in --cache_dir. The oldest provenances are evicted first.")
+	timeout := flag.Duration("timeout", 0,
+		"Optional timeout, e.g., 10m, after which fetching, verifying, signing, and publishing are aborted. With --serve_address, the timeout applies to each request.")
+	listSupportedFormats := flag.Bool("list_supported_formats", false,
+		"Print the predicate types and build types of provenances that can be verified, and exit.")
+	// The --now flag is only meant for testing and for reproducing previously
+	// generated endorsements.
+	flag.Usage = cmdutil.Usage("now")
 	flag.Parse()
 
+	if *listSupportedFormats {
+		cmdutil.PrintSupportedFormats(os.Stdout)
+		return
+	}
+
+	// Interrupting the endorser cancels pending requests to external services.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if *serveAddress != "" {
+		if err := serve(ctx, *serveAddress, *kmsKeyURI, *rekorURL, *callerAudience, *timeout); err != nil {
+			log.Fatalf("Failed serving endorsement requests: %v", err)
+		}
+		return
+	}
+
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+
+	if *revoke && *revokeEndorsementPath != "" {
+		log.Fatalf("--revoke and --revoke_endorsement are mutually exclusive")
+	}
+	if *revoke || *revokeEndorsementPath != "" {
+		revocation := &revocationConfig{
+			binaryName:           *binaryName,
+			binaryPath:           *binaryPath,
+			binaryDigests:        binaryDigests,
+			endorsementPath:      *revokeEndorsementPath,
+			reason:               *revocationReason,
+			revocationKMSKeyURI:  *revocationKMSKeyURI,
+			endorsementKMSKeyURI: *kmsKeyURI,
+			outputPath:           *outputPath,
+			now:                  *now,
+		}
+		if *revoke {
+			if err := revocation.revoke(ctx); err != nil {
+				log.Fatalf("Failed to revoke the binary: %v", err)
+			}
+		} else if err := revocation.revokeEndorsement(ctx); err != nil {
+			log.Fatalf("Failed to revoke the endorsement: %v", err)
+		}
+		return
+	}
+
 	// Make sure required flags are set.
-	if len(*binaryName) == 0 {
+	if *outputFormat != statementFormat && *outputFormat != dsseFormat {
+		log.Fatalf("--output_format must be either %q or %q", statementFormat, dsseFormat)
+	}
+	if *manifestPath != "" && (*reportURI != "" || *emitReportPath != "") {
+		log.Fatalf("--manifest cannot be used with two-phase issuance")
+	}
+	if *policyPath != "" && *verOptsTextproto != "" {
+		log.Fatalf("--policy and --verification_options are mutually exclusive")
+	}
+	if *registryPath != "" && (*policyPath != "" || *verOptsTextproto != "") {
+		log.Fatalf("--registry cannot be combined with --policy or --verification_options")
+	}
+	if *manifestPath != "" && *subjectName != "" {
+		log.Fatalf("--manifest cannot be used with --subject_name, set subject_name per binary in the manifest instead")
+	}
+	if *manifestPath != "" && *referenceValuesDigest != "" {
+		log.Fatalf("--manifest cannot be used with --reference_values_digest, set reference_values_digest per binary in the manifest instead")
+	}
+	if *manifestPath == "" && *referenceValuesFromSource && *referenceValuesDigest == "" {
+		log.Fatalf("--reference_values_from_source requires --reference_values_digest")
+	}
+	if *manifestPath != "" && (*bundlePath != "" || *envelopePath != "" || *logEntryPath != "") {
+		log.Fatalf("--manifest cannot be used with --bundle_path, --envelope_path, or --log_entry_path")
+	}
+	if *configPath != "" && (*manifestPath != "" || *reportURI != "" || *emitReportPath != "" || len(provenanceURIs) != 0) {
+		log.Fatalf("--config_path cannot be used with --manifest, two-phase issuance, or --provenance_uris")
+	}
+	if *binaryPath != "" && len(binaryDigests) != 0 {
+		log.Fatalf("--binary_path and --binary_digest are mutually exclusive")
+	}
+	hasBinary := *binaryPath != "" || len(binaryDigests) != 0
+	if len(binaryDigests) != 0 && (*manifestPath != "" || *reportURI != "" || *configPath != "") {
+		log.Fatalf("--binary_digest cannot be used with --manifest, --verification_report, or --config_path")
+	}
+	if *imageRef != "" && (*manifestPath != "" || *reportURI != "" || *configPath != "" || hasBinary) {
+		log.Fatalf("--image_ref cannot be used with --manifest, --verification_report, --config_path, --binary_path, or --binary_digest")
+	}
+	if *imageDigest != "" && (*manifestPath != "" || *reportURI != "" || *configPath != "" || hasBinary || *imageRef != "") {
+		log.Fatalf("--image_digest cannot be used with --manifest, --verification_report, --config_path, --binary_path, --binary_digest, or --image_ref")
+	}
+	if *extendURI != "" && (*manifestPath != "" || *reportURI != "" || *emitReportPath != "" || *configPath != "" ||
+		*imageRef != "" || *imageDigest != "" || hasBinary || len(provenanceURIs) != 0 || len(auxiliaryEvidence) != 0) {
+		log.Fatalf("--extend cannot be used with --manifest, two-phase issuance, --config_path, --image_ref, --image_digest, --binary_path, --binary_digest, --provenance_uris, or --evidence")
+	}
+	if *extendURI != "" && *kmsKeyURI == "" {
+		log.Fatalf("--extend requires --kms_key_uri, whose key must have signed the endorsement to extend")
+	}
+	if *manifestPath == "" && *reportURI == "" && *configPath == "" && *imageRef == "" && *extendURI == "" && len(*binaryName) == 0 {
 		log.Fatalf("--binary_name not set")
 	}
-	if len(*binaryPath) == 0 {
-		log.Fatalf("--binary_path not set")
+	if *manifestPath == "" && *reportURI == "" && *configPath == "" && *imageRef == "" && *imageDigest == "" && *extendURI == "" && !hasBinary {
+		log.Fatalf("--binary_path or --binary_digest not set")
 	}
-	if len(*outputPath) == 0 {
+	if *emitReportPath == "" && len(*outputPath) == 0 {
 		log.Fatalf("--output_path not set")
 	}
-	if *verOptsTextproto == "" && !*skipVerification {
-		log.Fatalf("--verification_options empty, use --skip_verification to overrule")
+	if *emitReportPath != "" && *kmsKeyURI == "" {
+		log.Fatalf("--emit_verification_report requires --kms_key_uri for signing the report")
 	}
-	verOpts, err := verifier.ParseVerificationOptions(*verOptsTextproto)
-	if err != nil {
-		log.Fatalf("Couldn't map parse verification options: %v", err)
+	if *reportURI != "" && *reportPublicKeyPath == "" {
+		log.Fatalf("--verification_report requires --verification_report_public_key")
 	}
+	if *sign && *kmsKeyURI != "" {
+		log.Fatalf("--sign and --kms_key_uri are mutually exclusive")
+	}
+	if *rekorURL != "" && !*sign && *kmsKeyURI == "" {
+		log.Fatalf("--rekor_url requires either --sign or --kms_key_uri")
+	}
+	if *releaseBundlePath != "" && (*kmsKeyURI == "" || *rekorURL == "" || *rekorPublicKeyPath == "") {
+		log.Fatalf("--release_bundle_path requires --kms_key_uri, --rekor_url, and --rekor_public_key")
+	}
+	if *releaseBundlePath != "" && *manifestPath != "" {
+		log.Fatalf("--release_bundle_path cannot be used with --manifest")
+	}
+	signing := &signingConfig{
+		outputFormat:  *outputFormat,
+		sign:          *sign,
+		fulcioURL:     *fulcioURL,
+		identityToken: *identityToken,
+		kmsKeyURI:     *kmsKeyURI,
+		rekorURL:      *rekorURL,
+		bundlePath:    *bundlePath,
+		envelopePath:  *envelopePath,
+		logEntryPath:  *logEntryPath,
 
-	digests, err := computeBinaryDigests(*binaryPath)
+		releaseBundlePath:  *releaseBundlePath,
+		rekorPublicKeyPath: *rekorPublicKeyPath,
+	}
+	clock, err := claims.ParseClock(*now)
 	if err != nil {
-		log.Fatalf("Failed parsing binaryDigest: %v", err)
+		log.Fatalf("Failed parsing --now: %v", err)
 	}
 
-	validity, err := getClaimValidity(*notBefore, *notAfter)
+	validity, err := getClaimValidity(clock, *notBefore, *notAfter)
 	if err != nil {
 		log.Fatalf("Failed creating claimValidity: %v", err)
 	}
+	if *predicateType != claims.ClaimV1 || *claimType != claims.EndorsementV2 {
+		log.Printf("The endorsement has predicate type %s and claim type %s, and cannot be verified by the tools in this repository yet", *predicateType, *claimType)
+	}
+	endorsementOptions := []func(c *claims.EndorsementConfig){
+		claims.WithStatementType(*statementType), claims.WithPredicateType(*predicateType), claims.WithClaimType(*claimType), claims.WithClock(clock),
+	}
+	if len(auxiliaryEvidence) != 0 {
+		evidence, err := endorser.LoadAuxiliaryEvidence(ctx, auxiliaryEvidence)
+		if err != nil {
+			log.Fatalf("Failed loading the auxiliary evidence: %v", err)
+		}
+		endorsementOptions = append(endorsementOptions, claims.WithEvidence(evidence...))
+	}
+
+	var loadOptions []func(c *endorser.LoadConfig)
+	if *requireEnvelope {
+		loadOptions = append(loadOptions, endorser.WithRequireEnvelope())
+	}
+	if *subjectName != "" {
+		loadOptions = append(loadOptions, endorser.WithSubjectName(*subjectName))
+	}
+	if *fulcioRootsPath != "" {
+		if *bundleRekorPublicKeyPath == "" {
+			log.Fatalf("--bundle_rekor_public_key is required with --fulcio_roots")
+		}
+		trustedRoot, err := sigstore.LoadTrustedRoot(*fulcioRootsPath, *bundleRekorPublicKeyPath)
+		if err != nil {
+			log.Fatalf("Failed loading the trusted root for Sigstore bundles: %v", err)
+		}
+		loadOptions = append(loadOptions, endorser.WithTrustedRoot(trustedRoot))
+	}
+	if *cacheDir != "" {
+		provenanceCache, err := cache.New(*cacheDir, cache.WithTTL(*cacheTTL), cache.WithMaxBytes(*cacheMaxBytes))
+		if err != nil {
+			log.Fatalf("Failed creating the cache: %v", err)
+		}
+		loadOptions = append(loadOptions, endorser.WithProvenanceCache(provenanceCache))
+	}
+
+	if *manifestPath != "" {
+		batch := &batchConfig{
+			validity:                  validity,
+			endorsementOptions:        endorsementOptions,
+			loadOptions:               loadOptions,
+			referenceValuesFromSource: *referenceValuesFromSource,
+			signing:                   signing,
+		}
+		if err := endorseBatch(ctx, *manifestPath, *outputPath, batch); err != nil {
+			log.Fatalf("Failed to endorse the binaries in the manifest: %v", err)
+		}
+		return
+	}
+
+	var endorsement *intoto.Statement
+	if *configPath != "" {
+		endorsement, err = endorseConfig(*configPath, *configKind, *binaryName, *validity, endorsementOptions)
+		if err != nil {
+			log.Fatalf("Failed to endorse the configuration artifact: %v", err)
+		}
+	} else if *extendURI != "" {
+		if *verOptsTextproto == "" && *policyPath == "" && *registryPath == "" && !*skipVerification {
+			log.Fatalf("--verification_options, --policy, and --registry empty, use --skip_verification to overrule")
+		}
+		verOpts, err := loadVerificationOptions(*registryPath, *policyPath, *verOptsTextproto, *binaryName)
+		if err != nil {
+			log.Fatalf("Couldn't map parse verification options: %v", err)
+		}
+		endorsement, err = extendEndorsement(ctx, *extendURI, *kmsKeyURI, verOpts, validity, clock)
+		if err != nil {
+			log.Fatalf("Failed to extend the endorsement: %v", err)
+		}
+	} else if *reportURI != "" {
+		endorsement, err = issueFromReport(ctx, *reportURI, *reportPublicKeyPath, *reportMaxAge, validity, endorsementOptions)
+		if err != nil {
+			log.Fatalf("Failed to issue endorsement from the verification report: %v", err)
+		}
+	} else {
+		if *verOptsTextproto == "" && *policyPath == "" && *registryPath == "" && !*skipVerification {
+			log.Fatalf("--verification_options, --policy, and --registry empty, use --skip_verification to overrule")
+		}
+
+		var digests *intoto.DigestSet
+		var image *oci.Descriptor
+		if *imageRef != "" {
+			image, err = discoverImageAttestations(ctx, *imageRef, binaryName)
+			if err != nil {
+				log.Fatalf("Failed discovering the attestations of %s: %v", *imageRef, err)
+			}
+		} else if *imageDigest != "" {
+			if err := oci.ValidateDigest(*imageDigest); err != nil {
+				log.Fatalf("Invalid --image_digest: %v", err)
+			}
+			image = &oci.Descriptor{MediaType: *imageMediaType, Digest: *imageDigest}
+		}
+		if image != nil {
+			// Images are endorsed by the digest of their manifest, and their
+			// endorsements record its media type.
+			digests = imageDigests(image)
+			endorsementOptions = append(endorsementOptions, claims.WithSubjectMediaType(image.MediaType))
+		} else {
+			digests, err = binaryDigestSet(*binaryPath, binaryDigests)
+			if err != nil {
+				log.Fatalf("Failed parsing binaryDigest: %v", err)
+			}
+		}
+
+		// The registry is keyed by binary name, which is only known once the
+		// image, if any, has been discovered.
+		verOpts, err := loadVerificationOptions(*registryPath, *policyPath, *verOptsTextproto, *binaryName)
+		if err != nil {
+			log.Fatalf("Couldn't map parse verification options: %v", err)
+		}
+
+		// Provenances with several subjects are narrowed to the binary.
+		loadOptions = append(loadOptions, endorser.WithSubjectDigests(*digests))
+		provenances, err := endorser.LoadProvenances(ctx, provenanceURIs, loadOptions...)
+		if err != nil {
+			log.Fatalf("Failed loading provenances: %v", err)
+		}
+
+		if *entAPIKey != "" {
+			if err := endorser.CopyProvenancesToEnt(ctx, ent.NewClient(*entURL, *entAPIKey), provenances); err != nil {
+				log.Fatalf("Failed copying the provenances to Ent: %v", err)
+			}
+		}
+
+		if *referenceValuesFromSource {
+			evidence, err := verifyReferenceValues(ctx, provenances, *referenceValuesDigest)
+			if err != nil {
+				log.Fatalf("Failed verifying the provenances against the reference values: %v", err)
+			}
+			endorsementOptions = append(endorsementOptions, claims.WithEvidence(*evidence))
+		}
+
+		if *emitReportPath != "" {
+			if err := emitVerificationReport(ctx, *emitReportPath, *kmsKeyURI, *binaryName, *digests, verOpts, provenances, clock); err != nil {
+				log.Fatalf("Failed to emit the verification report: %v", err)
+			}
+			return
+		}
+
+		endorsement, err = endorser.GenerateEndorsement(*binaryName, *digests, verOpts, *validity, provenances, endorsementOptions...)
+		if err != nil {
+			log.Fatalf("Failed to generate endorsement: %v", err)
+		}
+	}
 
-	provenances, err := endorser.LoadProvenances(provenanceURIs)
+	if *pinEvidence {
+		endorsement, err = endorser.PinEvidence(ctx, endorsement, filepath.Dir(*outputPath))
+		if err != nil {
+			log.Fatalf("Failed pinning the evidence: %v", err)
+		}
+	}
+
+	if err := signing.writeEndorsement(endorsement, *outputPath); err != nil {
+		log.Fatalf("Failed writing the endorsement statement to file: %v", err)
+	}
+	hash, err := claims.StatementHash(endorsement)
 	if err != nil {
-		log.Fatalf("Failed loading provenances: %v", err)
+		log.Fatalf("Failed to hash the endorsement: %v", err)
+	}
+	log.Printf("The endorsement with statement hash %s is stored in %s", hash, *outputPath)
+
+	if err := signing.signAndPublish(ctx, endorsement, *outputPath); err != nil {
+		log.Fatalf("Failed signing the endorsement: %v", err)
 	}
+}
+
+// signingConfig holds the flags for writing and signing endorsements, and
+// uploading them to Rekor.
+type signingConfig struct {
+	outputFormat  string
+	sign          bool
+	fulcioURL     string
+	identityToken string
+	kmsKeyURI     string
+	rekorURL      string
+	bundlePath    string
+	envelopePath  string
+	logEntryPath  string
+
+	releaseBundlePath  string
+	rekorPublicKeyPath string
+}
 
-	endorsement, err := endorser.GenerateEndorsement(*binaryName, *digests, verOpts, *validity, provenances)
+// writeEndorsement writes the given endorsement to outputPath, either as a
+// bare statement or wrapped in an unsigned DSSE envelope, depending on the
+// output format.
+func (c *signingConfig) writeEndorsement(endorsement *intoto.Statement, outputPath string) error {
+	if c.outputFormat != dsseFormat {
+		return intoto.WriteStatement(outputPath, endorsement)
+	}
+	envelope, err := endorser.WrapStatement(endorsement)
 	if err != nil {
-		log.Fatalf("Failed to generate endorsement: %v", err)
+		return err
 	}
+	return writeJSON(outputPath, envelope)
+}
 
-	bytes, err := json.MarshalIndent(endorsement, "", "    ")
+// signAndPublish signs the given endorsement, stored at outputPath, using
+// either Sigstore keyless signing or KMS, uploads it to Rekor, and stores the
+// results next to outputPath, unless their paths are set explicitly.
+func (c *signingConfig) signAndPublish(ctx context.Context, endorsement *intoto.Statement, outputPath string) error {
+	basePath := strings.TrimSuffix(outputPath, ".json")
+	logEntryPath := c.logEntryPath
+	if logEntryPath == "" {
+		logEntryPath = basePath + ".rekor.json"
+	}
+
+	if c.sign {
+		bundle, err := signEndorsement(ctx, endorsement, c.fulcioURL, c.identityToken)
+		if err != nil {
+			return err
+		}
+		if c.rekorURL != "" {
+			certPEM := pem.EncodeToMemory(&pem.Block{
+				Type:  "CERTIFICATE",
+				Bytes: bundle.VerificationMaterial.X509CertificateChain.Certificates[0].RawBytes,
+			})
+			entry, err := uploadToRekor(ctx, c.rekorURL, bundle.DSSEEnvelope, certPEM, logEntryPath)
+			if err != nil {
+				return fmt.Errorf("uploading the signed endorsement to Rekor: %v", err)
+			}
+			if err := bundle.AddLogEntry(entry); err != nil {
+				return fmt.Errorf("adding the Rekor log entry to the bundle: %v", err)
+			}
+		}
+		bundlePath := c.bundlePath
+		if bundlePath == "" {
+			bundlePath = basePath + ".sigstore.json"
+		}
+		if err := writeJSON(bundlePath, bundle); err != nil {
+			return fmt.Errorf("writing the signed endorsement to file: %v", err)
+		}
+	}
+
+	if c.kmsKeyURI != "" {
+		envelope, publicKeyPEM, err := signEndorsementWithKMS(ctx, endorsement, c.kmsKeyURI)
+		if err != nil {
+			return fmt.Errorf("signing the endorsement with KMS: %v", err)
+		}
+		var entry *rekor.LogEntry
+		if c.rekorURL != "" {
+			entry, err = uploadToRekor(ctx, c.rekorURL, envelope, publicKeyPEM, logEntryPath)
+			if err != nil {
+				return fmt.Errorf("uploading the signed endorsement to Rekor: %v", err)
+			}
+		}
+		envelopePath := c.envelopePath
+		if envelopePath == "" && c.outputFormat == dsseFormat {
+			// Replace the unsigned envelope with the signed one.
+			envelopePath = outputPath
+		} else if envelopePath == "" {
+			envelopePath = basePath + ".dsse.json"
+		}
+		if err := writeJSON(envelopePath, envelope); err != nil {
+			return fmt.Errorf("writing the signed endorsement to file: %v", err)
+		}
+		if c.releaseBundlePath != "" {
+			if err := c.writeReleaseBundle(ctx, endorsement, envelope, entry, publicKeyPEM, outputPath); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// serve serves endorsement requests at the given address, signing the
+// endorsements with the given KMS key, and publishing them to Rekor if
+// rekorURL is set, until the given context is done. Each request is aborted
+// after the given timeout, if positive.
+func serve(ctx context.Context, address, kmsKeyURI, rekorURL, callerAudience string, timeout time.Duration) error {
+	if kmsKeyURI == "" {
+		return fmt.Errorf("--serve_address requires --kms_key_uri")
+	}
+	// Every request is signed with the key of the server, so callers must
+	// always be authenticated.
+	if callerAudience == "" {
+		return fmt.Errorf("--serve_address requires --caller_audience")
+	}
+	signer, err := sign.NewKMSSigner(ctx, kmsKeyURI)
 	if err != nil {
-		log.Fatalf("Failed marshalling the endorsement: %v", err)
+		return fmt.Errorf("creating KMS signer: %v", err)
+	}
+
+	var options []func(c *endorser.ServerConfig)
+	if rekorURL != "" {
+		publicKey, err := x509.MarshalPKIXPublicKey(signer.Public())
+		if err != nil {
+			return fmt.Errorf("marshalling the public key: %v", err)
+		}
+		publicKeyPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKey})
+		options = append(options, endorser.WithRekor(rekor.NewClient(rekorURL), publicKeyPEM))
+	}
+	options = append(options, endorser.WithCallerVerifier(oidc.NewGitHubVerifier(callerAudience)))
+	if timeout > 0 {
+		options = append(options, endorser.WithRequestTimeout(timeout))
+	}
+
+	server := &http.Server{
+		Addr:              address,
+		Handler:           endorser.NewServer(signer, options...),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	// Pending requests are completed before shutting down.
+	go func() {
+		<-ctx.Done()
+		if err := server.Shutdown(context.Background()); err != nil {
+			log.Printf("Failed shutting down the server: %v", err)
+		}
+	}()
+	log.Printf("Serving endorsement requests on %s%s", address, endorser.EndorsementsPath)
+	if err := server.ListenAndServe(); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// verifyReferenceValues verifies the given provenances against the reference
+// values in their source repository, and returns evidence referencing the
+// reference values.
+func verifyReferenceValues(ctx context.Context, provenances []endorser.ParsedProvenance, expectedDigest string) (*claims.ClaimEvidence, error) {
+	provenanceIRs := make([]model.ProvenanceIR, 0, len(provenances))
+	for _, p := range provenances {
+		provenanceIRs = append(provenanceIRs, p.Provenance)
+	}
+	referenceValues, err := endorser.LoadReferenceValues(ctx, provenanceIRs, endorser.WithExpectedDigest(expectedDigest))
+	if err != nil {
+		return nil, err
+	}
+	if err := referenceValues.Verify(provenanceIRs); err != nil {
+		return nil, err
+	}
+	evidence := referenceValues.Evidence()
+	return &evidence, nil
+}
+
+// loadVerificationOptions returns the VerificationOptions of the binary with
+// the given name from the registry at registryPath, if set, or else from the
+// policy at policyPath, if set, or else the given textproto.
+func loadVerificationOptions(registryPath, policyPath, textproto, binaryName string) (*pb.VerificationOptions, error) {
+	switch {
+	case registryPath != "":
+		return registryVerificationOptions(registryPath, binaryName)
+	case policyPath != "":
+		return verifier.LoadVerificationOptions(policyPath)
+	default:
+		return verifier.ParseVerificationOptions(textproto)
+	}
+}
+
+// registryVerificationOptions loads the registry at registryPath, and returns
+// the VerificationOptions of the binary with the given name.
+func registryVerificationOptions(registryPath, binaryName string) (*pb.VerificationOptions, error) {
+	registry, err := verifier.LoadRegistry(registryPath)
+	if err != nil {
+		return nil, err
+	}
+	return registry.VerificationOptions(binaryName)
+}
+
+// emitVerificationReport verifies the given provenances, and writes a
+// verification report, signed with the given KMS key, to the given path.
+func emitVerificationReport(ctx context.Context, path, kmsKeyURI, binaryName string, digests intoto.DigestSet, verOpts *pb.VerificationOptions, provenances []endorser.ParsedProvenance, clock claims.Clock) error {
+	report, err := endorser.GenerateVerificationReport(binaryName, digests, verOpts, provenances, clock)
+	if err != nil {
+		return fmt.Errorf("verifying the provenances: %v", err)
+	}
+	envelope, _, err := signEndorsementWithKMS(ctx, report, kmsKeyURI)
+	if err != nil {
+		return fmt.Errorf("signing the verification report with KMS: %v", err)
+	}
+	return writeJSON(path, envelope)
+}
+
+// issueFromReport issues an endorsement from the signed verification report
+// at the given URI.
+func issueFromReport(ctx context.Context, reportURI, publicKeyPath string, maxAge time.Duration, validity *claims.ClaimValidity, options []func(c *claims.EndorsementConfig)) (*intoto.Statement, error) {
+	reportBytes, err := endorser.GetProvenanceBytes(ctx, reportURI)
+	if err != nil {
+		return nil, fmt.Errorf("loading the verification report: %v", err)
+	}
+	reportVerifier, err := sign.LoadPublicKeyVerifier(publicKeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("loading the public key: %v", err)
+	}
+	return endorser.IssueEndorsementFromReport(ctx, reportURI, reportBytes, reportVerifier, maxAge, *validity, options...)
+}
+
+// extendEndorsement loads the endorsement at the given URI, checks that it is
+// signed with the given KMS key, re-verifies its evidence against the given
+// VerificationOptions, and issues a fresh endorsement with the given
+// validity. Evidence pinned next to a local endorsement is read from the
+// bundle directory of the endorsement.
+func extendEndorsement(ctx context.Context, predecessorURI, kmsKeyURI string, verOpts *pb.VerificationOptions, validity *claims.ClaimValidity, clock claims.Clock) (*intoto.Statement, error) {
+	predecessorBytes, err := fetch.Fetch(ctx, predecessorURI)
+	if err != nil {
+		return nil, fmt.Errorf("loading the endorsement: %v", err)
+	}
+	endorserKey, err := sign.NewKMSSigner(ctx, kmsKeyURI)
+	if err != nil {
+		return nil, fmt.Errorf("creating KMS signer: %v", err)
+	}
+	var evidenceOptions []func(c *endorser.EvidenceConfig)
+	if parsed, err := url.Parse(predecessorURI); err == nil && parsed.Scheme == "file" {
+		evidenceOptions = append(evidenceOptions, endorser.WithBundleDir(filepath.Dir(parsed.Path)))
+	}
+	return endorser.ExtendEndorsement(ctx, predecessorURI, predecessorBytes, endorserKey, verOpts, *validity, evidenceOptions, claims.WithClock(clock))
+}
+
+// signEndorsementWithKMS signs the given endorsement with the Google Cloud KMS
+// key identified by keyURI, and returns the resulting DSSE envelope, and the
+// PEM-encoded public key for verifying it.
+func signEndorsementWithKMS(ctx context.Context, endorsement *intoto.Statement, keyURI string) (*dsse.Envelope, []byte, error) {
+	signer, err := sign.NewKMSSigner(ctx, keyURI)
+	if err != nil {
+		return nil, nil, fmt.Errorf("creating KMS signer: %v", err)
+	}
+
+	envelope, err := endorser.SignStatement(ctx, endorsement, signer)
+	if err != nil {
+		return nil, nil, fmt.Errorf("signing the endorsement: %v", err)
+	}
+
+	publicKey, err := x509.MarshalPKIXPublicKey(signer.Public())
+	if err != nil {
+		return nil, nil, fmt.Errorf("marshalling the public key: %v", err)
+	}
+	return envelope, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKey}), nil
+}
+
+// uploadToRekor uploads the given envelope to the Rekor instance at rekorURL,
+// and writes the returned log entry to logEntryPath. verifierPEM is either a
+// PEM-encoded certificate or public key.
+func uploadToRekor(ctx context.Context, rekorURL string, envelope *dsse.Envelope, verifierPEM []byte, logEntryPath string) (*rekor.LogEntry, error) {
+	publicKey, err := sign.ParsePublicKeyPEM(verifierPEM)
+	if err != nil {
+		return nil, fmt.Errorf("parsing the verifier: %v", err)
+	}
+	verifier, err := sign.NewPublicKeyVerifier(publicKey)
+	if err != nil {
+		return nil, fmt.Errorf("creating the verifier: %v", err)
+	}
+
+	entry, err := endorser.PublishEndorsement(ctx, rekor.NewClient(rekorURL), envelope, verifier, verifierPEM)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeJSON(logEntryPath, entry); err != nil {
+		return nil, fmt.Errorf("writing the log entry to file: %v", err)
+	}
+	return entry, nil
+}
+
+// signEndorsement signs the given endorsement using Sigstore keyless signing,
+// and returns the result as a Sigstore bundle.
+func signEndorsement(ctx context.Context, endorsement *intoto.Statement, fulcioURL, idToken string) (*sigstore.Bundle, error) {
+	if idToken == "" {
+		token, err := sigstore.GetAmbientIDToken(ctx, sigstore.SigstoreAudience)
+		if err != nil {
+			return nil, fmt.Errorf("getting identity token: %v", err)
+		}
+		idToken = token
+	}
+
+	signer, err := sigstore.NewKeylessSigner(ctx, sigstore.NewFulcioClient(fulcioURL), idToken)
+	if err != nil {
+		return nil, fmt.Errorf("creating keyless signer: %v", err)
+	}
+
+	envelope, err := endorser.SignStatement(ctx, endorsement, signer)
+	if err != nil {
+		return nil, fmt.Errorf("signing the endorsement: %v", err)
+	}
+
+	return sigstore.NewBundle(envelope, signer.CertificateChain()), nil
+}
+
+// writeJSON marshals the given object as indented JSON, and writes it to the given path.
+func writeJSON(path string, object interface{}) error {
+	bytes, err := json.MarshalIndent(object, "", "    ")
+	if err != nil {
+		return fmt.Errorf("marshalling to JSON: %v", err)
 	}
 
 	// Add a newline at the end of the file.
 	newline := byte('\n')
 	bytes = append(bytes, newline)
-	if err := os.WriteFile(*outputPath, bytes, 0600); err != nil {
-		log.Fatalf("Failed writing the endorsement statement to file: %v", err)
-	}
+	return os.WriteFile(path, bytes, 0600)
 }
 
-func getClaimValidity(notBefore string, notAfter string) (*claims.ClaimValidity, error) {
+func getClaimValidity(clock claims.Clock, notBefore string, notAfter string) (*claims.ClaimValidity, error) {
 	// We only care about the date, but we want to store it as an
 	// RFC3339-encoded timestamp. So we need a Time object, but with only the
 	// date part.
-	currentTime := time.Now().UTC().Truncate(24 * time.Hour)
+	currentTime := clock.Now().UTC().Truncate(24 * time.Hour)
 
 	notBeforeDate, err := parseDateOrDefault(notBefore, currentTime.AddDate(0, 0, 1))
 	if err != nil {
@@ -141,6 +890,47 @@ func getClaimValidity(notBefore string, notAfter string) (*claims.ClaimValidity,
 	}, nil
 }
 
+// discoverImageAttestations resolves the manifest of the container image with
+// the given reference, and adds the URIs of the attestations attached to it to
+// the provenance URIs. Sets the binary name to the image name, unless it is
+// set. Returns the descriptor of the manifest.
+func discoverImageAttestations(ctx context.Context, imageRef string, binaryName *string) (*oci.Descriptor, error) {
+	ref, err := oci.ParseReference(imageRef)
+	if err != nil {
+		return nil, err
+	}
+	image, uris, err := oci.NewClient().Attestations(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Found %d attestations of %s@%s", len(uris), ref.Name(), image.Digest)
+	provenanceURIs = append(provenanceURIs, uris...)
+	if *binaryName == "" {
+		*binaryName = ref.Name()
+	}
+	return image, nil
+}
+
+// imageDigests returns the digests of the image with the given descriptor,
+// whose digest must be valid.
+func imageDigests(image *oci.Descriptor) *intoto.DigestSet {
+	_, value, _ := strings.Cut(image.Digest, ":")
+	return &intoto.DigestSet{"sha2-256": strings.ToLower(value)}
+}
+
+// endorseConfig generates an endorsement of the configuration artifact of the
+// given kind at the given path, named by its file name unless name is set.
+func endorseConfig(path, kind, name string, validity claims.ClaimValidity, options []func(c *claims.EndorsementConfig)) (*intoto.Statement, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %v", path, err)
+	}
+	if name == "" {
+		name = filepath.Base(path)
+	}
+	return endorser.GenerateConfigEndorsement(name, kind, content, validity, options...)
+}
+
 func parseDateOrDefault(date string, value time.Time) (time.Time, error) {
 	if date == "" {
 		return value, nil
@@ -148,6 +938,19 @@ func parseDateOrDefault(date string, value time.Time) (time.Time, error) {
 	return time.Parse(dateLayout, date)
 }
 
+// binaryDigestSet returns the given digests of the binary, if any, and
+// otherwise computes the digests of the binary at the given path.
+func binaryDigestSet(path string, digests []string) (*intoto.DigestSet, error) {
+	if len(digests) == 0 {
+		return computeBinaryDigests(path)
+	}
+	digestSet, err := endorser.ParseBinaryDigests(digests)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --binary_digest: %v", err)
+	}
+	return &digestSet, nil
+}
+
 func computeBinaryDigests(path string) (*intoto.DigestSet, error) {
 	bytes, err := os.ReadFile(path)
 	if err != nil {
@@ -0,0 +1,109 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/project-oak/transparent-release/internal/endorser"
+	"github.com/project-oak/transparent-release/internal/rekor"
+	"github.com/project-oak/transparent-release/pkg/bundle"
+	"github.com/project-oak/transparent-release/pkg/claims"
+	"github.com/project-oak/transparent-release/pkg/intoto"
+	"github.com/secure-systems-lab/go-securesystemslib/dsse"
+)
+
+// writeReleaseBundle writes a release bundle with the given signed
+// endorsement, its log entry, and the public keys, to the release bundle
+// path. The provenances referenced by the endorsement are loaded, and their
+// log entries are looked up in Rekor.
+func (c *signingConfig) writeReleaseBundle(ctx context.Context, endorsement *intoto.Statement, envelope *dsse.Envelope, entry *rekor.LogEntry, endorserPublicKeyPEM []byte, outputPath string) error {
+	rekorPublicKeyPEM, err := os.ReadFile(c.rekorPublicKeyPath)
+	if err != nil {
+		return fmt.Errorf("reading the Rekor public key: %v", err)
+	}
+	releaseBundle := &bundle.Bundle{
+		Type:                bundle.ReleaseBundleV1,
+		Endorsement:         envelope,
+		EndorsementLogEntry: entry,
+		Provenances:         []bundle.Provenance{},
+		RekorPublicKey:      string(rekorPublicKeyPEM),
+		EndorserPublicKey:   string(endorserPublicKeyPEM),
+	}
+
+	// Pinned provenances are stored next to the endorsement.
+	bundleDir := endorser.WithBundleDir(filepath.Dir(outputPath))
+	client := rekor.NewClient(c.rekorURL)
+	for _, evidence := range endorsement.Predicate.(claims.ClaimPredicate).Evidence {
+		if evidence.Role != claims.ProvenanceRole {
+			continue
+		}
+		content, err := endorser.LoadEvidence(ctx, evidence, bundleDir)
+		if err != nil {
+			return fmt.Errorf("loading the provenance %s: %v", evidence.URI, err)
+		}
+		logEntry, err := findProvenanceLogEntry(ctx, client, content)
+		if err != nil {
+			return fmt.Errorf("looking up the log entry of the provenance %s: %v", evidence.URI, err)
+		}
+		releaseBundle.Provenances = append(releaseBundle.Provenances, bundle.Provenance{
+			URI:      evidence.URI,
+			Content:  content,
+			LogEntry: logEntry,
+		})
+	}
+
+	if err := writeJSON(c.releaseBundlePath, releaseBundle); err != nil {
+		return fmt.Errorf("writing the release bundle to file: %v", err)
+	}
+	log.Printf("The release bundle is stored in %s", c.releaseBundlePath)
+	return nil
+}
+
+// findProvenanceLogEntry returns the Rekor log entry recording the payload of
+// the given provenance, or nil if the provenance is not a DSSE envelope, or
+// has not been uploaded to Rekor.
+func findProvenanceLogEntry(ctx context.Context, client *rekor.Client, provenance []byte) (*rekor.LogEntry, error) {
+	var envelope dsse.Envelope
+	if err := json.Unmarshal(provenance, &envelope); err != nil || envelope.PayloadType != intoto.PayloadType {
+		return nil, nil
+	}
+	payload, err := envelope.DecodeB64Payload()
+	if err != nil {
+		return nil, fmt.Errorf("decoding the provenance: %v", err)
+	}
+	sum256 := sha256.Sum256(payload)
+	uuids, err := client.SearchByHash(ctx, hex.EncodeToString(sum256[:]))
+	if err != nil {
+		return nil, err
+	}
+	for _, uuid := range uuids {
+		entry, err := client.GetLogEntry(ctx, uuid)
+		if err != nil {
+			return nil, err
+		}
+		if rekor.VerifyPayloadLogEntry(payload, entry) == nil {
+			return entry, nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,135 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/project-oak/transparent-release/pkg/claims"
+	"github.com/project-oak/transparent-release/pkg/intoto"
+	"github.com/secure-systems-lab/go-securesystemslib/dsse"
+)
+
+// revocationConfig holds the settings for revoking a binary with --revoke, or
+// an endorsement with --revoke_endorsement.
+type revocationConfig struct {
+	binaryName           string
+	binaryPath           string
+	binaryDigests        []string
+	endorsementPath      string
+	reason               string
+	revocationKMSKeyURI  string
+	endorsementKMSKeyURI string
+	outputPath           string
+	now                  string
+}
+
+// revoke generates a revocation of all claims about the binary, signs it with
+// the KMS key of the revocation authority, and writes the DSSE envelope to the
+// output path. The endorsement key is never used for revocations, so that a
+// compromised endorsement key cannot revoke binaries.
+func (c *revocationConfig) revoke(ctx context.Context) error {
+	if c.binaryName == "" {
+		return fmt.Errorf("--binary_name not set")
+	}
+	if (c.binaryPath == "") == (len(c.binaryDigests) == 0) {
+		return fmt.Errorf("exactly one of --binary_path and --binary_digest must be set")
+	}
+	clock, err := c.checkSigning()
+	if err != nil {
+		return err
+	}
+
+	digests, err := binaryDigestSet(c.binaryPath, c.binaryDigests)
+	if err != nil {
+		return fmt.Errorf("getting the digests of the binary: %v", err)
+	}
+	subject := intoto.Subject{Name: c.binaryName, Digest: *digests}
+	revocation := claims.GenerateRevocationStatement(subject, c.reason, clock)
+
+	if err := c.signAndWrite(ctx, revocation); err != nil {
+		return err
+	}
+	log.Printf("The revocation of %s is stored in %s", c.binaryName, c.outputPath)
+	return nil
+}
+
+// revokeEndorsement generates a revocation of the single endorsement at the
+// endorsement path, referenced by its statement hash, signs it with the KMS
+// key of the revocation authority, and writes the DSSE envelope to the output
+// path. Other claims about the endorsed binary are not revoked.
+func (c *revocationConfig) revokeEndorsement(ctx context.Context) error {
+	clock, err := c.checkSigning()
+	if err != nil {
+		return err
+	}
+	envelopeBytes, err := os.ReadFile(c.endorsementPath)
+	if err != nil {
+		return fmt.Errorf("reading the endorsement: %v", err)
+	}
+	var envelope dsse.Envelope
+	if err := json.Unmarshal(envelopeBytes, &envelope); err != nil {
+		return fmt.Errorf("could not unmarshal the endorsement envelope: %v", err)
+	}
+	payload, err := envelope.DecodeB64Payload()
+	if err != nil {
+		return fmt.Errorf("decoding the endorsement: %v", err)
+	}
+	revocation, err := claims.GenerateEndorsementRevocationStatement(payload, c.reason, clock)
+	if err != nil {
+		return fmt.Errorf("generating the endorsement revocation: %v", err)
+	}
+
+	if err := c.signAndWrite(ctx, revocation); err != nil {
+		return err
+	}
+	spec := revocation.Predicate.(claims.ClaimPredicate).ClaimSpec.(claims.EndorsementRevocationSpec)
+	log.Printf("The revocation of the endorsement with statement hash %s is stored in %s", spec.StatementHash, c.outputPath)
+	return nil
+}
+
+// checkSigning checks the settings shared by all kinds of revocations, and
+// returns the clock of the revocation.
+func (c *revocationConfig) checkSigning() (claims.Clock, error) {
+	if c.outputPath == "" {
+		return nil, fmt.Errorf("--output_path not set")
+	}
+	if c.revocationKMSKeyURI == "" {
+		return nil, fmt.Errorf("revocations require --revocation_kms_key_uri")
+	}
+	if c.revocationKMSKeyURI == c.endorsementKMSKeyURI {
+		return nil, fmt.Errorf("--revocation_kms_key_uri must be different from --kms_key_uri")
+	}
+	clock, err := claims.ParseClock(c.now)
+	if err != nil {
+		return nil, fmt.Errorf("parsing --now: %v", err)
+	}
+	return clock, nil
+}
+
+func (c *revocationConfig) signAndWrite(ctx context.Context, revocation *intoto.Statement) error {
+	envelope, _, err := signEndorsementWithKMS(ctx, revocation, c.revocationKMSKeyURI)
+	if err != nil {
+		return fmt.Errorf("signing the revocation with KMS: %v", err)
+	}
+	if err := writeJSON(c.outputPath, envelope); err != nil {
+		return fmt.Errorf("writing the revocation to file: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/secure-systems-lab/go-securesystemslib/dsse"
+
+	"github.com/project-oak/transparent-release/internal/rekor"
+)
+
+type pathsFlag []string
+
+func (f *pathsFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *pathsFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+//nolint:gochecknoglobals
+var endorsementPaths pathsFlag
+
+func main() {
+	flag.Var(&endorsementPaths, "endorsement",
+		"Path to a signed endorsement as a DSSE envelope, followed by a comma and the path to its Rekor log entry. Can be repeated.")
+	rekorURL := flag.String("rekor_url", rekor.DefaultURL,
+		"URL of the Rekor instance containing the log entries.")
+	outputPath := flag.String("output_path", "",
+		"Full path to store the transparency list.")
+	flag.Parse()
+
+	if *outputPath == "" {
+		log.Fatalf("--output_path not set")
+	}
+	if len(endorsementPaths) == 0 {
+		log.Fatalf("--endorsement not set")
+	}
+
+	statements := make([]rekor.SignedStatement, 0, len(endorsementPaths))
+	for _, paths := range endorsementPaths {
+		statement, err := readSignedStatement(paths)
+		if err != nil {
+			log.Fatalf("Failed reading the endorsement: %v", err)
+		}
+		statements = append(statements, *statement)
+	}
+
+	list, err := rekor.NewTransparencyList(*rekorURL, statements)
+	if err != nil {
+		log.Fatalf("Failed creating the transparency list: %v", err)
+	}
+	bytes, err := json.MarshalIndent(list, "", "    ")
+	if err != nil {
+		log.Fatalf("Failed marshalling the transparency list: %v", err)
+	}
+	if err := os.WriteFile(*outputPath, append(bytes, '\n'), 0600); err != nil {
+		log.Fatalf("Failed writing the transparency list: %v", err)
+	}
+	log.Printf("Transparency list with %d artifacts stored in %s", len(list.Artifacts), *outputPath)
+}
+
+// readSignedStatement reads the envelope and log entry at the
+// comma-separated paths.
+func readSignedStatement(paths string) (*rekor.SignedStatement, error) {
+	envelopePath, logEntryPath, ok := strings.Cut(paths, ",")
+	if !ok {
+		return nil, fmt.Errorf("--endorsement %q does not contain the path to a Rekor log entry", paths)
+	}
+	var envelope dsse.Envelope
+	if err := readJSON(envelopePath, &envelope); err != nil {
+		return nil, fmt.Errorf("reading %q: %v", envelopePath, err)
+	}
+	var entry rekor.LogEntry
+	if err := readJSON(logEntryPath, &entry); err != nil {
+		return nil, fmt.Errorf("reading %q: %v", logEntryPath, err)
+	}
+	return &rekor.SignedStatement{Envelope: &envelope, LogEntry: &entry}, nil
+}
+
+func readJSON(path string, object interface{}) error {
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(bytes, object)
+}
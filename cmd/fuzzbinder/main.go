@@ -20,43 +20,96 @@ import (
 	"context"
 	"encoding/json"
 	"flag"
+	"fmt"
 	"log"
 	"os"
 	"path/filepath"
-	"time"
 
+	"github.com/project-oak/transparent-release/internal/blobstore"
+	"github.com/project-oak/transparent-release/internal/cmdutil"
+	"github.com/project-oak/transparent-release/internal/ent"
 	"github.com/project-oak/transparent-release/internal/fuzzbinder"
 	"github.com/project-oak/transparent-release/internal/gcsutil"
+	"github.com/project-oak/transparent-release/pkg/claims"
+	"github.com/project-oak/transparent-release/pkg/intoto"
 )
 
 func main() {
-	// Current time in UTC time zone since it is used by OSS-Fuzz.
-	currentTime := time.Now().UTC()
-	defaultNotBefore := currentTime.AddDate(0, 0, 1).Format(fuzzbinder.Layout)
-	defaultNotAfter := currentTime.AddDate(0, 0, 90).Format(fuzzbinder.Layout)
-
 	fuzzParameters := &fuzzbinder.FuzzParameters{}
 	flag.StringVar(&fuzzParameters.ProjectName, "project_name", "",
 		"Required - Project name as defined in OSS-Fuzz projects.")
 	flag.StringVar(&fuzzParameters.ProjectGitRepo, "git_repo", "",
 		"Required - GitHub repository of the project.")
-	// TODO(#175): Remove fuzzEngine and sanitizer from FuzzBinder inputs.
-	flag.StringVar(&fuzzParameters.FuzzEngine, "fuzzengine", "libFuzzer",
-		"Required - Fuzzing engine used for the project. Examples: libFuzzer, afl, honggfuzz, centipede.")
-	flag.StringVar(&fuzzParameters.Sanitizer, "sanitizer", "asan",
-		"Required - Fuzzing sanitizer used for the project. Examples: asan, ubsan, msan.")
 	flag.StringVar(&fuzzParameters.Date, "date", "",
 		"Required - Fuzzing date. The expected date format is YYYYMMDD.")
 	fuzzClaimPath := flag.String("fuzzclaim_path", "fuzzclaim.json",
 		"Optional - Output file name for storing the generated fuzzing claim.")
-	notBefore := flag.String("not_before", defaultNotBefore,
-		"Optional -  The date from which the fuzzing claim is effective. The expected date format is YYYYMMDD.")
-	notAfter := flag.String("not_after", defaultNotAfter,
-		"Required - The date of when the fuzzing claim is no longer endorsed for use. The expected date format is YYYYMMDD.")
+	fuzzTargetClaimsDir := flag.String("fuzz_target_claims_dir", "",
+		"Optional - Directory for storing one fuzzing claim per fuzz-target, named after the fuzz-target, in addition to the aggregate fuzzing claim.")
+	notBefore := flag.String("not_before", "",
+		"Optional -  The date from which the fuzzing claim is effective. The expected date format is YYYYMMDD. Defaults to 1 day after the issuance date.")
+	notAfter := flag.String("not_after", "",
+		"Required - The date of when the fuzzing claim is no longer endorsed for use. The expected date format is YYYYMMDD. Defaults to 90 days after the issuance date.")
+	entURL := flag.String("ent_url", ent.DefaultURL,
+		"Optional - URL of the Ent server for --ent_api_key.")
+	entAPIKey := flag.String("ent_api_key", "",
+		"Optional - API key of the Ent server at --ent_url. If set, the evidence files are copied from GCS, where they expire, to Ent, and referenced by their `ent:` URIs.")
+	strict := flag.Bool("strict", true,
+		"Optional - Fail if the statistics of any fuzz-target cannot be fetched. If false, such fuzz-targets are recorded as unknown, with the reason, and the statistics of the project cover the other fuzz-targets.")
+	blobStore := flag.String("blob_store", "gs",
+		"Optional - Blob store from which the OSS-Fuzz and ClusterFuzz buckets are read: gs for Google Cloud Storage, s3 for AWS S3, configured with the standard AWS environment variables, or file:///path/to/dir for a local directory with a subdirectory per bucket.")
+	logWorkers := flag.Int("log_workers", gcsutil.DefaultBlobWorkers,
+		"Optional - Maximum number of fuzzer logs that are downloaded and scanned concurrently.")
+	verifyFuzzClaimPath := flag.String("verify_fuzzclaim_path", "",
+		"Optional - Path of an existing fuzzing claim to check against the thresholds, instead of generating a fuzzing claim.")
+	policy := &fuzzbinder.FuzzPolicy{}
+	flag.Float64Var(&policy.MinLineCoverage, "min_line_coverage", 0,
+		"Optional - Minimum line coverage, in percent, that the fuzzing claim must meet.")
+	flag.Float64Var(&policy.MinBranchCoverage, "min_branch_coverage", 0,
+		"Optional - Minimum branch coverage, in percent, that the fuzzing claim must meet.")
+	flag.Float64Var(&policy.MinFuzzTimeSeconds, "min_fuzz_time_seconds", 0,
+		"Optional - Minimum fuzzing time, in seconds, that the fuzzing claim must meet.")
+	flag.IntVar(&policy.MinNumberFuzzTests, "min_number_fuzz_tests", 0,
+		"Optional - Minimum number of executed fuzzing tests that the fuzzing claim must meet.")
+	flag.BoolVar(&policy.NoCrashes, "no_crashes", false,
+		"Optional - Require that the fuzzing claim reports no detected crashes.")
+	flag.BoolVar(&policy.PerTarget, "per_target_thresholds", false,
+		"Optional - Apply the thresholds to every fuzz-target, in addition to the project, and require the statistics of all fuzz-targets to be known.")
+	policyReportPath := flag.String("policy_report_path", "",
+		"Optional - Path for storing a JSON report of the checks against the thresholds, which is written even if the checks fail.")
+	now := flag.String("now", "",
+		"Overrides the current time, as an RFC3339 timestamp.")
+	// The --now flag is only meant for testing and for reproducing previously
+	// generated claims.
+	flag.Usage = cmdutil.Usage("now")
 	flag.Parse()
 
-	err := fuzzbinder.ValidateFuzzingDate(fuzzParameters.Date, currentTime)
+	if *verifyFuzzClaimPath != "" {
+		statement, err := fuzzbinder.ParseFuzzClaimFile(*verifyFuzzClaimPath)
+		if err != nil {
+			log.Fatalf("could not parse the fuzzing claim: %v", err)
+		}
+		if err := checkPolicy(statement, policy, *policyReportPath); err != nil {
+			log.Fatalf("the fuzzing claim does not meet the thresholds: %v", err)
+		}
+		log.Printf("The fuzzing claim in %s meets the thresholds", *verifyFuzzClaimPath)
+		return
+	}
+
+	clock, err := claims.ParseClock(*now)
 	if err != nil {
+		log.Fatalf("could not parse --now: %v", err)
+	}
+	// Current time in UTC time zone since it is used by OSS-Fuzz.
+	currentTime := clock.Now().UTC()
+	if *notBefore == "" {
+		*notBefore = currentTime.AddDate(0, 0, 1).Format(fuzzbinder.Layout)
+	}
+	if *notAfter == "" {
+		*notAfter = currentTime.AddDate(0, 0, 90).Format(fuzzbinder.Layout)
+	}
+
+	if err := fuzzbinder.ValidateFuzzingDate(fuzzParameters.Date, currentTime); err != nil {
 		log.Fatalf("could not validate the fuzzing date: %v", err)
 	}
 
@@ -73,26 +126,104 @@ func main() {
 	}
 
 	// Create new GCS client
-	client, err := gcsutil.NewClientWithContext(context.Background())
+	clientOptions := []func(c *gcsutil.ClientConfig){gcsutil.WithBlobWorkers(*logWorkers)}
+	if *blobStore != "gs" {
+		store, err := blobstore.Open(*blobStore)
+		if err != nil {
+			log.Fatalf("could not open the blob store: %v", err)
+		}
+		clientOptions = append(clientOptions, gcsutil.WithStore(store))
+	}
+	client, err := gcsutil.NewClientWithContext(context.Background(), clientOptions...)
 	if err != nil {
 		log.Fatalf("could not create GCS client for FuzzBinder: %v", err)
 	}
 
 	// Generate the fuzzing claim.
-	statement, err := fuzzbinder.GenerateFuzzClaim(client, fuzzParameters, *validValidity)
-	if err != nil {
-		log.Fatalf("could not generate the fuzzing claim: %v", err)
+	var options []func(c *fuzzbinder.GenerateConfig)
+	if *entAPIKey != "" {
+		options = append(options, fuzzbinder.WithEnt(ent.NewClient(*entURL, *entAPIKey)))
 	}
-
-	// Write the fuzzing claim to file and apply indent to it.
-	bytes, err := json.MarshalIndent(statement, "", "    ")
+	if !*strict {
+		options = append(options, fuzzbinder.WithUnknownTargetStats())
+	}
+	statement, err := fuzzbinder.GenerateFuzzClaim(client, fuzzParameters, *validValidity, clock, options...)
 	if err != nil {
-		log.Fatalf("could not marshal the fuzzing claim: %v", err)
+		log.Fatalf("could not generate the fuzzing claim: %v", err)
 	}
 
 	// Store the fuzzing claim.
 	log.Printf("Storing the fuzzing claim in %s", absFuzzClaimPath)
-	if err := os.WriteFile(absFuzzClaimPath, bytes, 0600); err != nil {
+	if err := writeClaim(absFuzzClaimPath, statement); err != nil {
 		log.Fatalf("could not write the fuzzing claim file: %v", err)
 	}
+
+	if *fuzzTargetClaimsDir != "" {
+		if err := writeFuzzTargetClaims(*fuzzTargetClaimsDir, statement); err != nil {
+			log.Fatalf("could not write the per-target fuzzing claims: %v", err)
+		}
+	}
+
+	// Gate on the thresholds, if any, once the claim has been stored.
+	if *policy != (fuzzbinder.FuzzPolicy{}) {
+		if err := checkPolicy(statement, policy, *policyReportPath); err != nil {
+			log.Fatalf("the fuzzing claim does not meet the thresholds: %v", err)
+		}
+	}
+}
+
+// checkPolicy checks the given fuzzing claim against the thresholds of the
+// given policy, logs the outcome of every check, and stores the report at the
+// given path, if not empty.
+func checkPolicy(statement *intoto.Statement, policy *fuzzbinder.FuzzPolicy, reportPath string) error {
+	report, err := fuzzbinder.VerifyFuzzClaim(statement, policy)
+	if err != nil {
+		return err
+	}
+	for _, check := range report.Checks {
+		log.Printf("%s (%s): passed=%t", check.Name, check.Threshold, check.Passed)
+		for _, checkErr := range check.Errors {
+			log.Printf("  %s", checkErr)
+		}
+	}
+	if reportPath != "" {
+		bytes, err := json.MarshalIndent(report, "", "    ")
+		if err != nil {
+			return fmt.Errorf("could not marshal the report: %v", err)
+		}
+		if err := os.WriteFile(reportPath, bytes, 0600); err != nil {
+			return fmt.Errorf("could not write the report: %v", err)
+		}
+	}
+	return report.Err()
+}
+
+// writeFuzzTargetClaims generates one fuzzing claim per fuzz-target from the
+// given aggregate fuzzing claim, and stores them in the given directory.
+func writeFuzzTargetClaims(dir string, aggregate *intoto.Statement) error {
+	statements, err := fuzzbinder.GenerateFuzzTargetClaims(aggregate)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("could not create %s: %v", dir, err)
+	}
+	for _, statement := range statements {
+		name := statement.Predicate.(*claims.ClaimPredicate).ClaimSpec.(fuzzbinder.FuzzSpecPerTarget).Name
+		path := filepath.Join(dir, name+".json")
+		log.Printf("Storing the fuzzing claim of %s in %s", name, path)
+		if err := writeClaim(path, statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeClaim writes the given claim as canonical JSON to the given path.
+func writeClaim(path string, statement *intoto.Statement) error {
+	bytes, err := intoto.MarshalCanonical(statement)
+	if err != nil {
+		return fmt.Errorf("could not marshal the fuzzing claim: %v", err)
+	}
+	return os.WriteFile(path, bytes, 0600)
 }
@@ -0,0 +1,88 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package main contains a command-line tool for checking that a fuzzing claim
+// is faithful to the fuzzing reports of OSS-Fuzz, by re-deriving it.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+
+	"go.uber.org/multierr"
+
+	"github.com/project-oak/transparent-release/internal/blobstore"
+	"github.com/project-oak/transparent-release/internal/fuzzbinder"
+	"github.com/project-oak/transparent-release/internal/gcsutil"
+)
+
+func main() {
+	fuzzClaimPath := flag.String("fuzzclaim_path", "",
+		"Required - Path of the fuzzing claim to verify.")
+	projectName := flag.String("project_name", "",
+		"Optional - Project name as defined in OSS-Fuzz projects. Inferred from the srcmap evidence of the fuzzing claim by default.")
+	date := flag.String("date", "",
+		"Optional - Fuzzing date. The expected date format is YYYYMMDD. Inferred from the srcmap evidence of the fuzzing claim by default.")
+	blobStore := flag.String("blob_store", "gs",
+		"Optional - Blob store from which the OSS-Fuzz and ClusterFuzz buckets are read: gs for Google Cloud Storage, s3 for AWS S3, configured with the standard AWS environment variables, or file:///path/to/dir for a local directory with a subdirectory per bucket.")
+	logWorkers := flag.Int("log_workers", gcsutil.DefaultBlobWorkers,
+		"Optional - Maximum number of fuzzer logs that are downloaded and scanned concurrently.")
+	flag.Parse()
+
+	if *fuzzClaimPath == "" {
+		log.Fatal("--fuzzclaim_path must be provided")
+	}
+	statement, err := fuzzbinder.ParseFuzzClaimFile(*fuzzClaimPath)
+	if err != nil {
+		log.Fatalf("could not parse the fuzzing claim: %v", err)
+	}
+
+	fuzzParameters := &fuzzbinder.FuzzParameters{ProjectName: *projectName, Date: *date}
+	if *projectName == "" || *date == "" {
+		inferred, err := fuzzbinder.FuzzParametersFromClaim(statement)
+		if err != nil {
+			log.Fatalf("could not infer the fuzzing parameters, set --project_name and --date: %v", err)
+		}
+		if fuzzParameters.ProjectName == "" {
+			fuzzParameters.ProjectName = inferred.ProjectName
+		}
+		if fuzzParameters.Date == "" {
+			fuzzParameters.Date = inferred.Date
+		}
+	}
+
+	// Create new GCS client
+	clientOptions := []func(c *gcsutil.ClientConfig){gcsutil.WithBlobWorkers(*logWorkers)}
+	if *blobStore != "gs" {
+		store, err := blobstore.Open(*blobStore)
+		if err != nil {
+			log.Fatalf("could not open the blob store: %v", err)
+		}
+		clientOptions = append(clientOptions, gcsutil.WithStore(store))
+	}
+	client, err := gcsutil.NewClientWithContext(context.Background(), clientOptions...)
+	if err != nil {
+		log.Fatalf("could not create GCS client for the fuzzing claim verifier: %v", err)
+	}
+
+	log.Printf("Re-deriving the fuzzing claim in %s for project %s on %s", *fuzzClaimPath, fuzzParameters.ProjectName, fuzzParameters.Date)
+	if err := fuzzbinder.RederiveFuzzClaim(client, statement, fuzzParameters); err != nil {
+		for _, discrepancy := range multierr.Errors(err) {
+			log.Printf("%v", discrepancy)
+		}
+		log.Fatalf("the fuzzing claim is not faithful to the evidence")
+	}
+	log.Printf("The fuzzing claim in %s is faithful to the evidence", *fuzzClaimPath)
+}
@@ -0,0 +1,188 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/secure-systems-lab/go-securesystemslib/dsse"
+
+	"github.com/project-oak/transparent-release/internal/endorser"
+	"github.com/project-oak/transparent-release/internal/verifier"
+	"github.com/project-oak/transparent-release/pkg/claims"
+	"github.com/project-oak/transparent-release/pkg/intoto"
+	"github.com/project-oak/transparent-release/pkg/sign"
+)
+
+func main() {
+	claimPath := flag.String("claim_path", "",
+		"Path to an endorsement statement, or to a directory of endorsement statements with a `.json` suffix.")
+	revokedSubjectsPath := flag.String("revoked_subjects", "",
+		"Optional path to a file listing the hex-encoded SHA2-256 digests of revoked subjects, one per line.")
+	revocationsPath := flag.String("revocations", "",
+		"Optional path to a signed revocation, as written by the endorser with --revoke, or to a directory of signed revocations with a `.json` suffix. Requires --revocation_public_key.")
+	revocationPublicKeyPath := flag.String("revocation_public_key", "",
+		"Path to the PEM-encoded public key of the revocation authority, which must have signed all --revocations.")
+	endorserPublicKeyPath := flag.String("endorser_public_key", "",
+		"Optional path to the PEM-encoded public key that endorsements are signed with. If set, revocations signed by this key are rejected, as are revocation keys equal to it.")
+	expiringSoonWindow := flag.Duration("expiring_soon_window", claims.DefaultExpiringSoonWindow,
+		"Window before the end of the validity of a claim, in which the claim is reported as expiring soon.")
+	now := flag.String("now", "",
+		"Overrides the current time, as an RFC3339 timestamp.")
+	roughtimeTokenPath := flag.String("roughtime_token", "",
+		"Optional path to a Roughtime token, as written by the verifier with --fetch_roughtime_token. If set, the status is computed at the time authenticated by the token. Cannot be combined with --now.")
+	roughtimePublicKey := flag.String("roughtime_public_key", "",
+		"The base64-encoded Ed25519 public key of the Roughtime server that signed --roughtime_token.")
+	flag.Parse()
+
+	if *claimPath == "" {
+		log.Fatalf("--claim_path not set")
+	}
+	if *now != "" && *roughtimeTokenPath != "" {
+		log.Fatalf("--now and --roughtime_token are mutually exclusive")
+	}
+	clock, err := claims.ParseClock(*now)
+	if err != nil {
+		log.Fatalf("Failed parsing --now: %v", err)
+	}
+	if *roughtimeTokenPath != "" {
+		timeSource, err := verifier.LoadRoughtimeToken(*roughtimeTokenPath, *roughtimePublicKey)
+		if err != nil {
+			log.Fatalf("Failed loading the Roughtime token: %v", err)
+		}
+		log.Printf("Computing the status at %v, as authenticated by %s", timeSource.Time, timeSource.Server)
+		clock = timeSource
+	}
+	options := []func(c *claims.StatusConfig){
+		claims.WithStatusClock(clock), claims.WithExpiringSoonWindow(*expiringSoonWindow),
+	}
+	if *revokedSubjectsPath != "" {
+		revoked, err := readRevokedSubjects(*revokedSubjectsPath)
+		if err != nil {
+			log.Fatalf("Failed reading the revoked subjects: %v", err)
+		}
+		options = append(options, claims.WithRevokedSubjects(revoked...))
+	}
+	if *revocationsPath != "" {
+		revocations, err := readRevocations(*revocationsPath, *revocationPublicKeyPath, *endorserPublicKeyPath)
+		if err != nil {
+			log.Fatalf("Failed reading the revocations: %v", err)
+		}
+		options = append(options, claims.WithRevocations(revocations...))
+	}
+
+	paths, err := claimPaths(*claimPath)
+	if err != nil {
+		log.Fatalf("Failed listing the claims: %v", err)
+	}
+	statements := make([]*intoto.Statement, 0, len(paths))
+	for _, path := range paths {
+		statement, err := claims.ParseEndorsementV2File(path)
+		if err != nil {
+			log.Fatalf("Failed parsing %s: %v", path, err)
+		}
+		statements = append(statements, statement)
+	}
+
+	statuses, err := claims.ClaimStatuses(statements, options...)
+	if err != nil {
+		log.Fatalf("Failed computing the status of the claims: %v", err)
+	}
+	for i, path := range paths {
+		fmt.Printf("%s\t%s\t%s\n", path, statements[i].Subject[0].Name, statuses[i])
+	}
+}
+
+// claimPaths returns the given path if it is a file, or the paths of all
+// `.json` files in it if it is a directory.
+func claimPaths(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+	paths, err := filepath.Glob(filepath.Join(path, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+func readRevokedSubjects(path string) ([]string, error) {
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var digests []string
+	for _, line := range strings.Split(string(bytes), "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			digests = append(digests, line)
+		}
+	}
+	return digests, nil
+}
+
+// readRevocations reads the signed revocations at the given path, which is
+// either a file or a directory, and checks that they are signed by the
+// revocation authority, and not by the endorser.
+func readRevocations(path, revocationPublicKeyPath, endorserPublicKeyPath string) ([]*intoto.Statement, error) {
+	if revocationPublicKeyPath == "" {
+		return nil, fmt.Errorf("--revocations requires --revocation_public_key")
+	}
+	revocationKey, err := sign.LoadPublicKeyVerifier(revocationPublicKeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("loading the revocation public key: %v", err)
+	}
+	var options []func(c *endorser.RevocationConfig)
+	if endorserPublicKeyPath != "" {
+		endorserKey, err := sign.LoadPublicKeyVerifier(endorserPublicKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("loading the endorser public key: %v", err)
+		}
+		options = append(options, endorser.WithEndorsementKey(endorserKey))
+	}
+
+	paths, err := claimPaths(path)
+	if err != nil {
+		return nil, err
+	}
+	revocations := make([]*intoto.Statement, 0, len(paths))
+	for _, path := range paths {
+		revocation, err := readRevocation(path, revocationKey, options)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		revocations = append(revocations, revocation)
+	}
+	return revocations, nil
+}
+
+func readRevocation(path string, revocationKey dsse.Verifier, options []func(c *endorser.RevocationConfig)) (*intoto.Statement, error) {
+	envelopeBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return endorser.VerifyRevocation(context.Background(), envelopeBytes, revocationKey, options...)
+}
@@ -0,0 +1,110 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"flag"
+	"log"
+
+	"github.com/project-oak/transparent-release/internal/endorser"
+	"github.com/project-oak/transparent-release/internal/sigstore"
+	"github.com/project-oak/transparent-release/internal/verifier"
+)
+
+// chainCommand is the name of the subcommand that verifies an endorsement
+// together with the provenances it references.
+const chainCommand = "chain"
+
+// runChain runs the chain subcommand with the given arguments: it verifies the
+// signed endorsement, fetches the provenances referenced as evidence, checks
+// their digests, verifies them against the verification options, and checks
+// that they are for the endorsed binary, and that the endorsement is valid.
+func runChain(args []string) {
+	flags := flag.NewFlagSet(chainCommand, flag.ExitOnError)
+	endorsementPath := flags.String("endorsement_path", "",
+		"Path to a signed endorsement, as a DSSE envelope.")
+	rekorLogEntryPath := flags.String("rekor_log_entry", "",
+		"Path to the Rekor log entry of the signed endorsement, as written by the endorser.")
+	rekorPublicKeyPath := flags.String("rekor_public_key", "",
+		"Path to the PEM-encoded public key of the Rekor instance.")
+	endorserPublicKeyPath := flags.String("endorser_public_key", "",
+		"Path to the PEM-encoded public key of the product team that signed the endorsement.")
+	fulcioRootsPath := flags.String("fulcio_roots", "",
+		"Optional path to PEM-encoded Fulcio root certificates. If set, provenances given as Sigstore bundles are verified against them and --rekor_public_key.")
+	verOptsTextproto := flags.String("verification_options", "",
+		"An instance of VerificationOptions as inline textproto, for verifying the provenances.")
+	policyPath := flags.String("policy", "",
+		"Path to a file with VerificationOptions, as YAML if it has a .yaml or .yml extension, or as textproto otherwise. Cannot be combined with --verification_options.")
+	roughtimeTokenPath := flags.String("roughtime_token", "",
+		"Optional path to a Roughtime token. If set, the validity of the endorsement is checked at the time authenticated by the token instead of the local clock. Requires --roughtime_public_key.")
+	roughtimePublicKey := flags.String("roughtime_public_key", "",
+		"The base64-encoded Ed25519 public key of the Roughtime server.")
+	reportPath := flags.String("report_path", "",
+		"Optional path for storing a JSON report listing every check performed on the chain and its outcome.")
+	timeout := flags.Duration("timeout", 0,
+		"Optional timeout, e.g., 10m, after which fetching and verifying are aborted.")
+	// ExitOnError makes Parse exit on errors.
+	_ = flags.Parse(args)
+
+	ctx, cancel := commandContext(*timeout)
+	defer cancel()
+
+	if *endorsementPath == "" {
+		log.Fatalf("--endorsement_path is required")
+	}
+	if *policyPath != "" && *verOptsTextproto != "" {
+		log.Fatalf("--policy and --verification_options are mutually exclusive")
+	}
+	verOpts, err := loadVerificationOptions(*policyPath, *verOptsTextproto)
+	if err != nil {
+		log.Fatalf("couldn't parse the verification options: %v", err)
+	}
+
+	timeSource := verifier.SystemTime()
+	if *roughtimeTokenPath != "" {
+		timeSource, err = verifier.LoadRoughtimeToken(*roughtimeTokenPath, *roughtimePublicKey)
+		if err != nil {
+			log.Fatalf("couldn't load the Roughtime token: %v", err)
+		}
+	}
+
+	var loadOptions []func(c *endorser.LoadConfig)
+	if *fulcioRootsPath != "" {
+		trustedRoot, err := sigstore.LoadTrustedRoot(*fulcioRootsPath, *rekorPublicKeyPath)
+		if err != nil {
+			log.Fatalf("couldn't load the trusted root: %v", err)
+		}
+		loadOptions = append(loadOptions, endorser.WithTrustedRoot(trustedRoot))
+	}
+
+	endorsement, err := verifyEndorsement(ctx, *endorsementPath, *rekorLogEntryPath, *rekorPublicKeyPath, *endorserPublicKeyPath)
+	if err != nil {
+		log.Fatalf("error when verifying the endorsement: %v", err)
+	}
+	report, err := endorser.VerifyEndorsementChain(ctx, endorsement, verOpts, timeSource, loadOptions...)
+	if err != nil {
+		log.Fatalf("error when verifying the endorsement chain: %v", err)
+	}
+
+	if *reportPath != "" {
+		if err := writeJSON(*reportPath, report); err != nil {
+			log.Fatalf("couldn't write the report to %s: %v", *reportPath, err)
+		}
+	}
+	if err := report.Err(); err != nil {
+		log.Fatalf("error when verifying the endorsement chain: %v", err)
+	}
+	log.Printf("Verified the endorsement and %d provenances.", report.ProvenanceCount)
+}
@@ -0,0 +1,152 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/project-oak/transparent-release/internal/rekor"
+)
+
+// consistencyCommand is the name of the subcommand that verifies that the
+// log entry of an endorsement remains included in a later state of the log.
+const consistencyCommand = "consistency"
+
+// runConsistency runs the consistency subcommand with the given arguments: it
+// verifies the inclusion proof stored with the log entry of an endorsement,
+// and that a later checkpoint of the log is consistent with the checkpoint of
+// the inclusion proof, so that the entry remains included. Comparing
+// checkpoints obtained by different parties this way detects a log that
+// presents different views to different verifiers.
+func runConsistency(args []string) {
+	flags := flag.NewFlagSet(consistencyCommand, flag.ExitOnError)
+	rekorLogEntryPath := flags.String("rekor_log_entry", "",
+		"Path to the Rekor log entry of the signed endorsement, as written by the endorser, including its inclusion proof.")
+	rekorPublicKeyPath := flags.String("rekor_public_key", "",
+		"Path to the PEM-encoded public key of the Rekor instance.")
+	oldCheckpointPath := flags.String("old_checkpoint", "",
+		"Optional path to the checkpoint that the inclusion proof of the log entry refers to. Defaults to the checkpoint stored in the log entry.")
+	newCheckpointPath := flags.String("new_checkpoint", "",
+		"Path to a later checkpoint of the log, e.g., the signedTreeHead returned by `/api/v1/log`, or one obtained from a witness.")
+	consistencyProofPath := flags.String("consistency_proof", "",
+		"Optional path to the consistency proof between the checkpoints, as returned by `/api/v1/log/proof`. If not set, the proof is fetched from --rekor_url.")
+	rekorURL := flags.String("rekor_url", rekor.DefaultURL,
+		"The URL of the Rekor instance for fetching the consistency proof.")
+	witnessKeysPath := flags.String("witness_keys", "",
+		"Optional path to the note verifier keys of trusted witnesses, one per line. If set, --new_checkpoint must be cosigned by at least --witness_threshold of them, so that the verification does not rely on the log alone.")
+	witnessThreshold := flags.Int("witness_threshold", 1,
+		"The minimum number of trusted witnesses from --witness_keys that must cosign --new_checkpoint.")
+	endorsementPath := flags.String("endorsement_path", "",
+		"Optional path to the signed endorsement, as a DSSE envelope. If set, the log entry must record it. Requires --endorser_public_key.")
+	endorserPublicKeyPath := flags.String("endorser_public_key", "",
+		"Path to the PEM-encoded public key of the product team that signed the endorsement.")
+	timeout := flags.Duration("timeout", 0,
+		"Optional timeout, e.g., 10m, after which fetching and verifying are aborted.")
+	// ExitOnError makes Parse exit on errors.
+	_ = flags.Parse(args)
+
+	ctx, cancel := commandContext(*timeout)
+	defer cancel()
+
+	if *rekorLogEntryPath == "" || *rekorPublicKeyPath == "" || *newCheckpointPath == "" {
+		log.Fatalf("--rekor_log_entry, --rekor_public_key, and --new_checkpoint are required")
+	}
+	var witnessPolicy *rekor.WitnessPolicy
+	if *witnessKeysPath != "" {
+		var err error
+		witnessPolicy, err = rekor.LoadWitnessPolicy(*witnessKeysPath, *witnessThreshold)
+		if err != nil {
+			log.Fatalf("couldn't load the witness keys: %v", err)
+		}
+	}
+	if *endorsementPath != "" {
+		if _, err := verifyEndorsement(ctx, *endorsementPath, *rekorLogEntryPath, *rekorPublicKeyPath, *endorserPublicKeyPath); err != nil {
+			log.Fatalf("error when verifying the endorsement: %v", err)
+		}
+	}
+	if err := verifyConsistency(ctx, *rekorLogEntryPath, *rekorPublicKeyPath, *oldCheckpointPath, *newCheckpointPath, *consistencyProofPath, *rekorURL, witnessPolicy); err != nil {
+		log.Fatalf("error when verifying the consistency of the log: %v", err)
+	}
+	log.Print("Verification was successful.")
+}
+
+// verifyConsistency verifies that the log entry at the given path remains
+// included in the tree committed to by the new checkpoint. If
+// consistencyProofPath is empty, the consistency proof is fetched from the
+// Rekor instance at the given URL. If witnessPolicy is not nil, the new
+// checkpoint must be cosigned by the witnesses it requires.
+func verifyConsistency(ctx context.Context, logEntryPath, rekorPublicKeyPath, oldCheckpointPath, newCheckpointPath, consistencyProofPath, rekorURL string, witnessPolicy *rekor.WitnessPolicy) error {
+	var entry rekor.LogEntry
+	if err := readJSON(logEntryPath, &entry); err != nil {
+		return fmt.Errorf("reading the log entry: %v", err)
+	}
+	if entry.Verification == nil || entry.Verification.InclusionProof == nil {
+		return fmt.Errorf("the log entry does not contain an inclusion proof")
+	}
+	rekorPublicKey, err := loadECDSAPublicKey(rekorPublicKeyPath)
+	if err != nil {
+		return fmt.Errorf("loading the Rekor public key: %v", err)
+	}
+	oldNote := entry.Verification.InclusionProof.Checkpoint
+	if oldCheckpointPath != "" {
+		bytes, err := os.ReadFile(oldCheckpointPath)
+		if err != nil {
+			return fmt.Errorf("reading the old checkpoint: %v", err)
+		}
+		oldNote = string(bytes)
+	}
+	newBytes, err := os.ReadFile(newCheckpointPath)
+	if err != nil {
+		return fmt.Errorf("reading the new checkpoint: %v", err)
+	}
+	newNote := string(newBytes)
+	if witnessPolicy != nil {
+		if _, err := rekor.VerifyWitnessedCheckpoint(newNote, rekorPublicKey, witnessPolicy); err != nil {
+			return fmt.Errorf("verifying the witness cosignatures of the new checkpoint: %v", err)
+		}
+	}
+
+	var proof *rekor.ConsistencyProof
+	if consistencyProofPath != "" {
+		proof = &rekor.ConsistencyProof{}
+		if err := readJSON(consistencyProofPath, proof); err != nil {
+			return fmt.Errorf("reading the consistency proof: %v", err)
+		}
+	} else {
+		// The tree sizes for the query are only trusted once the signatures on
+		// the checkpoints have been verified.
+		oldCheckpoint, err := rekor.VerifyCheckpoint(oldNote, rekorPublicKey)
+		if err != nil {
+			return fmt.Errorf("verifying the old checkpoint: %v", err)
+		}
+		newCheckpoint, err := rekor.VerifyCheckpoint(newNote, rekorPublicKey)
+		if err != nil {
+			return fmt.Errorf("verifying the new checkpoint: %v", err)
+		}
+		// Checkpoints for the same tree size need no proof; they must be equal.
+		proof = &rekor.ConsistencyProof{}
+		if oldCheckpoint.TreeSize != newCheckpoint.TreeSize {
+			proof, err = rekor.NewClient(rekorURL).GetConsistencyProof(ctx, oldCheckpoint.TreeSize, newCheckpoint.TreeSize)
+			if err != nil {
+				return fmt.Errorf("fetching the consistency proof: %v", err)
+			}
+		}
+	}
+	return rekor.VerifyEntryConsistency(&entry, oldNote, newNote, proof, rekorPublicKey)
+}
@@ -15,42 +15,667 @@
 package main
 
 import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
 	"flag"
+	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
 
+	"github.com/project-oak/transparent-release/internal/archive"
+	"github.com/project-oak/transparent-release/internal/cmdutil"
+	"github.com/project-oak/transparent-release/internal/endorser"
+	"github.com/project-oak/transparent-release/internal/fetch"
 	"github.com/project-oak/transparent-release/internal/model"
+	"github.com/project-oak/transparent-release/internal/rebuild"
+	"github.com/project-oak/transparent-release/internal/rekor"
+	"github.com/project-oak/transparent-release/internal/roughtime"
+	"github.com/project-oak/transparent-release/internal/sigstore"
 	"github.com/project-oak/transparent-release/internal/verifier"
+	"github.com/project-oak/transparent-release/pkg/bundle"
+	"github.com/project-oak/transparent-release/pkg/claims"
+	"github.com/project-oak/transparent-release/pkg/intoto"
+	pb "github.com/project-oak/transparent-release/pkg/proto/oak/release"
+	"github.com/project-oak/transparent-release/pkg/sign"
+	"github.com/secure-systems-lab/go-securesystemslib/dsse"
+	"google.golang.org/protobuf/encoding/prototext"
 )
 
 func main() {
-	provenancePath := flag.String("provenance_path", "", "Path to a single SLSA provenance file.")
+	// The subcommands have their own flags; see chain.go and consistency.go.
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case chainCommand:
+			runChain(os.Args[2:])
+			return
+		case consistencyCommand:
+			runConsistency(os.Args[2:])
+			return
+		}
+	}
+
+	provenancePath := flag.String("provenance_path", "", "Path or URI (file, http(s), or gs) of a single SLSA provenance file.")
+	fulcioRootsPath := flag.String("fulcio_roots", "",
+		"Optional path to PEM-encoded Fulcio root certificates. If set, --provenance_path must be a Sigstore bundle, which is verified against them and --rekor_public_key, so that its certificate identity can be checked with the all_with_certificate_identity verification option.")
+	subjectName := flag.String("subject_name", "",
+		"Name of the subject to select from a provenance with several subjects.")
+	subjectDigest := flag.String("subject_digest", "",
+		"Hex-encoded SHA256 digest of the subject to select from a provenance with several subjects, optionally prefixed with `sha256:`. Can be combined with --subject_name.")
+	provenanceLogEntryPath := flag.String("provenance_log_entry", "",
+		"Optional path to the Rekor log entry of --provenance_path. If set, the build finish time in the provenance is checked against the time the entry was integrated into the log. Requires --rekor_public_key.")
+	maxClockSkew := flag.Duration("max_clock_skew", time.Hour,
+		"Maximum difference between the build finish time in the provenance and the integrated time of --provenance_log_entry.")
 	verOptsTextproto := flag.String("verification_options", "",
 		"An instance of VerificationOptions as inline textproto.")
+	policyPath := flag.String("policy", "",
+		"Path to a file with VerificationOptions, as YAML if it has a .yaml or .yml extension, or as textproto otherwise. Cannot be combined with --verification_options.")
+	registryPath := flag.String("registry", "",
+		"Optional path to a TOML registry of VerificationOptions keyed by binary name, from which the options for --binary_name are used. Cannot be combined with --policy or --verification_options.")
+	binaryName := flag.String("binary_name", "",
+		"The name of the binary whose options are used from --registry. The binary name in --provenance_path must be equal to it. Required with --registry.")
+	regoPolicyPath := flag.String("rego_policy", "",
+		"Optional path to a verification policy in Rego, which must define a `deny` set of messages, and is evaluated against the fields of the provenance. Can be combined with --policy or --verification_options.")
+	policyEndorsementPath := flag.String("policy_endorsement", "",
+		"Optional path to a signed endorsement of --policy, as a DSSE envelope, generated by the endorser with --config_path. If set, the verification fails unless the policy is endorsed. Requires --policy_endorsement_log_entry, --rekor_public_key, and --endorser_public_key.")
+	policyLogEntryPath := flag.String("policy_endorsement_log_entry", "",
+		"Path to the Rekor log entry of --policy_endorsement.")
+	printPolicySchema := flag.Bool("print_policy_schema", false,
+		"Print the JSON schema of --policy files in YAML, and exit.")
+	referenceValuesFromSource := flag.Bool("reference_values_from_source", false,
+		"Additionally verify the provenance against the reference values in "+endorser.ReferenceValuesPath+" in the source repository, at the commit of the provenance.")
+	referenceValuesDigest := flag.String("reference_values_digest", "",
+		"The expected hex-encoded SHA256 digest of the reference values fetched with --reference_values_from_source. Required with --reference_values_from_source.")
+	bootstrapPolicy := flag.Bool("bootstrap_policy", false,
+		"Print starter VerificationOptions as textproto, pinning the build type, builder, repository, and builder image digest of the known-good provenance at --provenance_path, instead of verifying it.")
+	rebuildProvenance := flag.Bool("rebuild", false,
+		"Additionally re-execute the build described in the container-based SLSA v1 provenance, with git and docker, and check that the rebuilt artifact matches the subject of the provenance.")
+	rebuildDir := flag.String("rebuild_dir", "",
+		"Optional path of a new or empty directory into which the source is checked out for --rebuild, and which is kept after the build. Defaults to a temporary directory.")
+	gitTimeout := flag.Duration("git_timeout", 0,
+		"Optional timeout of each git command for --rebuild, e.g., `5m`. By default, git commands are only bounded by --timeout.")
+	containerRuntime := flag.String("container_runtime", rebuild.DockerRuntime,
+		"The container runtime for --rebuild: `docker`, `podman`, or `nerdctl`.")
+	endorsementPath := flag.String("endorsement_path", "",
+		"Path to a signed endorsement, as a DSSE envelope. If set, the endorsement is verified instead of a provenance.")
+	rekorLogEntryPath := flag.String("rekor_log_entry", "",
+		"Path to the Rekor log entry of the signed endorsement, as written by the endorser.")
+	rekorPublicKeyPath := flag.String("rekor_public_key", "",
+		"Path to the PEM-encoded public key of the Rekor instance.")
+	endorserPublicKeyPath := flag.String("endorser_public_key", "",
+		"Path to the PEM-encoded public key of the product team that signed the endorsement.")
+	verifyEvidence := flag.Bool("verify_evidence", true,
+		"Fetch the evidence of the endorsement verified with --endorsement_path, and check it against the digests in the endorsement.")
+	evidenceDir := flag.String("evidence_dir", "",
+		"Optional path to a directory with pre-fetched evidence of the endorsement verified with --endorsement_path, named by their hex-encoded SHA256 digests or by the last element of their URIs. If set, the evidence is read from the directory instead of being fetched from its URIs.")
+	endorsementRevocationsPath := flag.String("endorsement_revocations", "",
+		"Optional path to a signed endorsement revocation, as written by the endorser with --revoke_endorsement, or to a directory of them with a `.json` suffix. If set, the endorsement verified with --endorsement_path is rejected if any of them revokes it. Requires --revocation_public_key.")
+	revocationPublicKeyPath := flag.String("revocation_public_key", "",
+		"Path to the PEM-encoded public key of the revocation authority, which must have signed all --endorsement_revocations.")
+	archivePath := flag.String("archive_path", "",
+		"Path to a release archive, as written by the archiver. If set, the archive is verified offline instead of a provenance.")
+	archiveDigest := flag.String("archive_digest", "",
+		"The expected hex-encoded SHA256 digest of --archive_path.")
+	releaseBundlePath := flag.String("release_bundle_path", "",
+		"Path to a release bundle, as written by the endorser with --release_bundle_path. If set, the bundle is verified offline instead of a provenance. Requires --rekor_public_key and --endorser_public_key, which the keys in the bundle must match.")
+	checkValidity := flag.Bool("check_validity", false,
+		"Additionally check that the endorsements verified with --endorsement_path, --archive_path, or --release_bundle_path are valid at the current time, according to the local clock, or to --roughtime_token if set.")
+	roughtimeTokenPath := flag.String("roughtime_token", "",
+		"Optional path to a Roughtime token, as written with --fetch_roughtime_token. If set, validity windows are checked at the time authenticated by the token instead of the local clock. Requires --roughtime_public_key.")
+	roughtimePublicKey := flag.String("roughtime_public_key", "",
+		"The base64-encoded Ed25519 public key of the Roughtime server.")
+	fetchRoughtimeTokenPath := flag.String("fetch_roughtime_token", "",
+		"Query the Roughtime server at --roughtime_server, verify the response with --roughtime_public_key, store it as a token at the given path, and exit.")
+	roughtimeServer := flag.String("roughtime_server", "",
+		"The UDP address of the Roughtime server for --fetch_roughtime_token, e.g., roughtime.sandbox.google.com:2002.")
+	reportPath := flag.String("report_path", "",
+		"Optional path for storing a JSON report listing every check performed on the provenance and its outcome.")
+	vsaPath := flag.String("vsa_path", "",
+		"Optional path for storing a SLSA Verification Summary Attestation (VSA) of the verification of --provenance_path, recording the policy, the verifier, the result, and the verified SLSA Build level. The VSA is a bare in-toto statement, unless --vsa_kms_key_uri is set.")
+	vsaVerifierID := flag.String("vsa_verifier_id", verifier.DefaultVerifierID,
+		"The ID of the verifier recorded in --vsa_path, e.g., the URI of the service running the verifier.")
+	vsaResourceURI := flag.String("vsa_resource_uri", "",
+		"The URI of the verified artifact recorded in --vsa_path, e.g., its download URL. Defaults to the name of the subject of the provenance.")
+	vsaKMSKeyURI := flag.String("vsa_kms_key_uri", "",
+		"Optional URI of a Google Cloud KMS key version for signing the VSA, which is then stored at --vsa_path as a DSSE envelope.")
+	timeout := flag.Duration("timeout", 0,
+		"Optional timeout, e.g., 10m, after which fetching and verifying are aborted.")
+	listSupportedFormats := flag.Bool("list_supported_formats", false,
+		"Print the predicate types and build types of provenances that can be verified, and exit.")
 	flag.Parse()
 
-	provenanceBytes, err := os.ReadFile(*provenancePath)
+	ctx, cancel := commandContext(*timeout)
+	defer cancel()
+
+	if *listSupportedFormats {
+		cmdutil.PrintSupportedFormats(os.Stdout)
+		return
+	}
+
+	if *printPolicySchema {
+		schema, err := verifier.VerificationOptionsJSONSchema()
+		if err != nil {
+			log.Fatalf("couldn't generate the policy schema: %v", err)
+		}
+		fmt.Println(string(schema))
+		return
+	}
+
+	if *policyPath != "" && *verOptsTextproto != "" {
+		log.Fatalf("--policy and --verification_options are mutually exclusive")
+	}
+	if *registryPath != "" && (*policyPath != "" || *verOptsTextproto != "") {
+		log.Fatalf("--registry cannot be combined with --policy or --verification_options")
+	}
+	if (*registryPath != "") != (*binaryName != "") {
+		log.Fatalf("--registry and --binary_name must be set together")
+	}
+	if *policyEndorsementPath != "" && *policyPath == "" {
+		log.Fatalf("--policy_endorsement requires --policy")
+	}
+	if *referenceValuesFromSource && *referenceValuesDigest == "" {
+		log.Fatalf("--reference_values_from_source requires --reference_values_digest")
+	}
+
+	if *fetchRoughtimeTokenPath != "" {
+		if err := fetchRoughtimeToken(ctx, *fetchRoughtimeTokenPath, *roughtimeServer, *roughtimePublicKey); err != nil {
+			log.Fatalf("couldn't fetch the Roughtime token: %v", err)
+		}
+		return
+	}
+
+	var timeSource *verifier.TimeSource
+	if *roughtimeTokenPath != "" {
+		var err error
+		timeSource, err = verifier.LoadRoughtimeToken(*roughtimeTokenPath, *roughtimePublicKey)
+		if err != nil {
+			log.Fatalf("couldn't load the Roughtime token: %v", err)
+		}
+	} else if *checkValidity {
+		timeSource = verifier.SystemTime()
+	}
+
+	if *archivePath != "" {
+		if err := verifyArchive(ctx, *archivePath, *archiveDigest, timeSource); err != nil {
+			log.Fatalf("error when verifying the archive: %v", err)
+		}
+		log.Print("Verification was successful.")
+		return
+	}
+
+	if *releaseBundlePath != "" {
+		if err := verifyReleaseBundle(ctx, *releaseBundlePath, *rekorPublicKeyPath, *endorserPublicKeyPath, timeSource); err != nil {
+			log.Fatalf("error when verifying the release bundle: %v", err)
+		}
+		log.Print("Verification was successful.")
+		return
+	}
+
+	if *endorsementPath != "" {
+		endorsement, err := verifyEndorsement(ctx, *endorsementPath, *rekorLogEntryPath, *rekorPublicKeyPath, *endorserPublicKeyPath)
+		if err != nil {
+			log.Fatalf("error when verifying the endorsement: %v", err)
+		}
+		report := &verifier.Report{Passed: true, Checks: []verifier.CheckResult{}}
+		if timeSource != nil {
+			report.CheckClaimValidity(endorsement, timeSource)
+		}
+		if *verifyEvidence {
+			// Evidence pinned by the endorser is stored next to the endorsement.
+			evidenceOptions := []func(c *endorser.EvidenceConfig){endorser.WithBundleDir(filepath.Dir(*endorsementPath))}
+			if *evidenceDir != "" {
+				evidenceOptions = append(evidenceOptions, endorser.WithEvidenceDir(*evidenceDir))
+			}
+			report.AddCheck("evidence_digests", nil, endorser.VerifyEvidence(ctx, endorsement, evidenceOptions...))
+		}
+		if *endorsementRevocationsPath != "" {
+			report.AddCheck("endorsement_not_revoked", nil, checkEndorsementNotRevoked(ctx,
+				*endorsementPath, *endorsementRevocationsPath, *revocationPublicKeyPath, *endorserPublicKeyPath))
+		}
+		if *reportPath != "" {
+			if err := writeJSON(*reportPath, report); err != nil {
+				log.Fatalf("couldn't write the report to %s: %v", *reportPath, err)
+			}
+		}
+		if err := report.Err(); err != nil {
+			log.Fatalf("error when verifying the endorsement: %v", err)
+		}
+		log.Print("Verification was successful.")
+		return
+	}
+
+	provenanceBytes, err := fetch.FetchPathOrURI(ctx, *provenancePath)
 	if err != nil {
 		log.Fatalf("couldn't load the provenance bytes from %s: %v", *provenancePath, err)
 	}
+	// The VSA references the provenance as loaded, e.g., the Sigstore bundle.
+	vsaOptions := []func(c *verifier.VSAConfig){
+		verifier.WithVerifierID(*vsaVerifierID, nil),
+		verifier.WithResourceURI(*vsaResourceURI),
+		verifier.WithInputAttestation(*provenancePath, provenanceBytes),
+	}
+	var identity *model.CertificateIdentity
+	if *fulcioRootsPath != "" {
+		provenanceBytes, identity, err = verifyBundle(ctx, provenanceBytes, *fulcioRootsPath, *rekorPublicKeyPath)
+		if err != nil {
+			log.Fatalf("error when verifying the Sigstore bundle %s: %v", *provenancePath, err)
+		}
+	}
 	// Parse into a validated provenance to get the predicate/build type of the provenance.
 	validatedProvenance, err := model.ParseStatementData(provenanceBytes)
 	if err != nil {
 		log.Fatalf("couldn't parse bytes from %s into a validated provenance: %v", *provenancePath, err)
 	}
+	if *subjectName != "" || *subjectDigest != "" {
+		var subjectDigests intoto.DigestSet
+		if *subjectDigest != "" {
+			subjectDigests = intoto.DigestSet{"sha2-256": strings.ToLower(strings.TrimPrefix(*subjectDigest, "sha256:"))}
+		}
+		validatedProvenance, err = validatedProvenance.SelectSubject(*subjectName, subjectDigests)
+		if err != nil {
+			log.Fatalf("couldn't select the subject from %s: %v", *provenancePath, err)
+		}
+	}
 	// Map to internal provenance representation based on the predicate/build type.
 	provenanceIR, err := model.FromValidatedProvenance(validatedProvenance)
 	if err != nil {
 		log.Fatalf("couldn't map from %s to internal representation: %v", validatedProvenance, err)
 	}
-	verOpts, err := verifier.ParseVerificationOptions(*verOptsTextproto)
+	if identity != nil {
+		model.WithCertificateIdentity(*identity)(provenanceIR)
+	}
+	if *bootstrapPolicy {
+		verOpts := verifier.BootstrapVerificationOptions(provenanceIR)
+		fmt.Print(prototext.MarshalOptions{Multiline: true}.Format(verOpts))
+		return
+	}
+	var verOpts *pb.VerificationOptions
+	if *registryPath != "" {
+		verOpts, err = registryVerificationOptions(*registryPath, *binaryName, provenanceIR)
+	} else {
+		verOpts, err = loadVerificationOptions(*policyPath, *verOptsTextproto)
+	}
 	if err != nil {
 		log.Fatalf("couldn't map parse verification options: %v", err)
 	}
 	// We only process a single provenance, even though the verifier works on many.
-	if err := verifier.Verify([]model.ProvenanceIR{*provenanceIR}, verOpts); err != nil {
+	report := verifier.VerifyWithReport([]model.ProvenanceIR{*provenanceIR}, verOpts)
+
+	if *regoPolicyPath != "" {
+		regoPolicy, err := verifier.LoadRegoPolicy(ctx, *regoPolicyPath)
+		if err != nil {
+			log.Fatalf("couldn't load the Rego policy: %v", err)
+		}
+		report.CheckRegoPolicy(ctx, regoPolicy, []model.ProvenanceIR{*provenanceIR})
+	}
+
+	if *policyEndorsementPath != "" {
+		report.AddCheck("policy_endorsement", nil, verifyPolicyEndorsement(ctx, *policyPath, *policyEndorsementPath, *policyLogEntryPath, *rekorPublicKeyPath, *endorserPublicKeyPath, timeSource))
+	}
+
+	if *provenanceLogEntryPath != "" {
+		integratedTime, err := verifyProvenanceLogEntry(provenanceBytes, *provenanceLogEntryPath, *rekorPublicKeyPath)
+		if err != nil {
+			log.Fatalf("error when verifying the log entry of the provenance: %v", err)
+		}
+		report.CheckIntegratedTime(*provenanceIR, integratedTime, *maxClockSkew)
+	}
+
+	if *rebuildProvenance {
+		runner, err := rebuild.NewContainerRunner(*containerRuntime)
+		if err != nil {
+			log.Fatalf("invalid --container_runtime: %v", err)
+		}
+		vcs := rebuild.GitCLI{Timeout: *gitTimeout}
+		runner.VCS = vcs
+		rebuildOptions := []func(c *rebuild.RunConfig){rebuild.WithRunner(runner), rebuild.WithVCS(vcs)}
+		if *rebuildDir != "" {
+			rebuildOptions = append(rebuildOptions, rebuild.WithWorkDir(*rebuildDir))
+		}
+		report.AddCheck("rebuild", nil, rebuild.Verify(ctx, validatedProvenance, rebuildOptions...))
+	}
+
+	if *referenceValuesFromSource && report.Passed {
+		referenceValues, err := endorser.LoadReferenceValues(ctx, []model.ProvenanceIR{*provenanceIR}, endorser.WithExpectedDigest(*referenceValuesDigest))
+		if err != nil {
+			log.Fatalf("couldn't load the reference values: %v", err)
+		}
+		report.AddCheck("reference_values_from_source", referenceValues.Options, referenceValues.Verify([]model.ProvenanceIR{*provenanceIR}))
+		if report.Passed {
+			log.Printf("Verified against reference values from %s with digest sha256:%s", referenceValues.URI, referenceValues.SHA256Digest)
+		}
+	}
+
+	if *reportPath != "" {
+		if err := writeJSON(*reportPath, report); err != nil {
+			log.Fatalf("couldn't write the report to %s: %v", *reportPath, err)
+		}
+	}
+	if *vsaPath != "" {
+		if *policyPath != "" {
+			policyBytes, err := os.ReadFile(*policyPath)
+			if err != nil {
+				log.Fatalf("couldn't read the policy: %v", err)
+			}
+			vsaOptions = append(vsaOptions, verifier.WithPolicy(*policyPath, policyBytes))
+		}
+		if err := writeVerificationSummary(ctx, *vsaPath, *vsaKMSKeyURI, report, provenanceIR, verOpts, vsaOptions); err != nil {
+			log.Fatalf("couldn't write the VSA to %s: %v", *vsaPath, err)
+		}
+	}
+	if err := report.Err(); err != nil {
 		log.Fatalf("error when verifying the provenance: %v", err)
 	}
 
 	log.Print("Verification was successful.")
 }
+
+// commandContext returns a context that is canceled when the verifier is
+// interrupted, or after the given timeout, if positive.
+func commandContext(timeout time.Duration) (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	if timeout <= 0 {
+		return ctx, stop
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	return ctx, func() {
+		cancel()
+		stop()
+	}
+}
+
+// loadVerificationOptions loads VerificationOptions from the file at
+// policyPath if set, and parses them from the given textproto otherwise.
+func loadVerificationOptions(policyPath, textproto string) (*pb.VerificationOptions, error) {
+	if policyPath != "" {
+		return verifier.LoadVerificationOptions(policyPath)
+	}
+	return verifier.ParseVerificationOptions(textproto)
+}
+
+// registryVerificationOptions loads the registry at registryPath, and returns
+// the VerificationOptions of the binary with the given name. The options are
+// not selected by the binary name in the provenance, which is untrusted, but
+// it must be equal to the given name.
+func registryVerificationOptions(registryPath, binaryName string, provenance *model.ProvenanceIR) (*pb.VerificationOptions, error) {
+	if got := provenance.BinaryName(); got != binaryName {
+		return nil, fmt.Errorf("the binary name in the provenance is %q, want %q", got, binaryName)
+	}
+	registry, err := verifier.LoadRegistry(registryPath)
+	if err != nil {
+		return nil, err
+	}
+	return registry.VerificationOptions(binaryName)
+}
+
+// verifyPolicyEndorsement verifies that the policy at the given path is
+// endorsed by the signed endorsement at endorsementPath. If timeSource is not
+// nil, the endorsement must be valid at its time.
+func verifyPolicyEndorsement(ctx context.Context, policyPath, endorsementPath, logEntryPath, rekorPublicKeyPath, endorserPublicKeyPath string, timeSource *verifier.TimeSource) error {
+	policyBytes, err := os.ReadFile(policyPath)
+	if err != nil {
+		return fmt.Errorf("reading the policy: %v", err)
+	}
+	if logEntryPath == "" || rekorPublicKeyPath == "" || endorserPublicKeyPath == "" {
+		return fmt.Errorf("--policy_endorsement_log_entry, --rekor_public_key, and --endorser_public_key are required with --policy_endorsement")
+	}
+	endorsement, err := verifyEndorsement(ctx, endorsementPath, logEntryPath, rekorPublicKeyPath, endorserPublicKeyPath)
+	if err != nil {
+		return fmt.Errorf("verifying the policy endorsement: %v", err)
+	}
+	if err := endorser.VerifyConfigEndorsement(endorsement, endorser.PolicyConfigKind, policyBytes); err != nil {
+		return err
+	}
+	if timeSource != nil {
+		status, err := claims.ClaimStatus(endorsement, claims.WithStatusClock(timeSource))
+		if err != nil {
+			return err
+		}
+		if status != claims.StatusActive && status != claims.StatusExpiringSoon {
+			return fmt.Errorf("the policy endorsement is %s at %v (%s time)", status, timeSource.Time, timeSource.Kind)
+		}
+	}
+	return nil
+}
+
+// verifyEndorsement verifies that the endorsement in the given DSSE envelope
+// is signed by the product team, and that it has been included in Rekor.
+// Returns the endorsement.
+func verifyEndorsement(ctx context.Context, endorsementPath, logEntryPath, rekorPublicKeyPath, endorserPublicKeyPath string) (*intoto.Statement, error) {
+	if logEntryPath == "" || rekorPublicKeyPath == "" || endorserPublicKeyPath == "" {
+		return nil, fmt.Errorf("--rekor_log_entry, --rekor_public_key, and --endorser_public_key are required with --endorsement_path")
+	}
+
+	var envelope dsse.Envelope
+	if err := readJSON(endorsementPath, &envelope); err != nil {
+		return nil, fmt.Errorf("reading the endorsement: %v", err)
+	}
+	var entry rekor.LogEntry
+	if err := readJSON(logEntryPath, &entry); err != nil {
+		return nil, fmt.Errorf("reading the log entry: %v", err)
+	}
+	rekorPublicKey, err := loadECDSAPublicKey(rekorPublicKeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("loading the Rekor public key: %v", err)
+	}
+	endorserVerifier, err := sign.LoadPublicKeyVerifier(endorserPublicKeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("loading the endorser public key: %v", err)
+	}
+
+	if err := rekor.VerifyLogEntry(&entry, rekorPublicKey); err != nil {
+		return nil, fmt.Errorf("verifying the inclusion of the log entry: %v", err)
+	}
+	if err := rekor.VerifyEnvelopeLogEntry(ctx, &envelope, &entry, endorserVerifier); err != nil {
+		return nil, fmt.Errorf("verifying the signed endorsement: %v", err)
+	}
+
+	payload, err := envelope.DecodeB64Payload()
+	if err != nil {
+		return nil, fmt.Errorf("decoding the endorsement: %v", err)
+	}
+	endorsement, err := claims.ParseEndorsementV2Bytes(payload)
+	if err != nil {
+		return nil, fmt.Errorf("parsing the endorsement: %v", err)
+	}
+	hash, err := claims.StatementHashFromBytes(payload)
+	if err != nil {
+		return nil, fmt.Errorf("hashing the endorsement: %v", err)
+	}
+	log.Printf("Verified the endorsement with statement hash %s", hash)
+	return endorsement, nil
+}
+
+// verifyBundle verifies the Sigstore bundle in the given bytes against the
+// given Fulcio roots and Rekor public key. It returns the statement in the
+// bundle, and the identity of the signing certificate.
+func verifyBundle(ctx context.Context, bundleBytes []byte, fulcioRootsPath, rekorPublicKeyPath string) ([]byte, *model.CertificateIdentity, error) {
+	if rekorPublicKeyPath == "" {
+		return nil, nil, fmt.Errorf("--rekor_public_key is required with --fulcio_roots")
+	}
+	trustedRoot, err := sigstore.LoadTrustedRoot(fulcioRootsPath, rekorPublicKeyPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	bundle, err := sigstore.ParseBundle(bundleBytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parsing the bundle: %v", err)
+	}
+	identity, err := bundle.Verify(ctx, trustedRoot)
+	if err != nil {
+		return nil, nil, err
+	}
+	statement, err := bundle.DSSEEnvelope.DecodeB64Payload()
+	if err != nil {
+		return nil, nil, fmt.Errorf("decoding the envelope payload: %v", err)
+	}
+	return statement, identity, nil
+}
+
+// verifyProvenanceLogEntry verifies that the log entry at the given path
+// records the given provenance, and has been included in the Rekor log with
+// the given public key. It returns the time at which the entry was integrated
+// into the log.
+func verifyProvenanceLogEntry(provenanceBytes []byte, logEntryPath, rekorPublicKeyPath string) (time.Time, error) {
+	if rekorPublicKeyPath == "" {
+		return time.Time{}, fmt.Errorf("--rekor_public_key is required with --provenance_log_entry")
+	}
+	var entry rekor.LogEntry
+	if err := readJSON(logEntryPath, &entry); err != nil {
+		return time.Time{}, fmt.Errorf("reading the log entry: %v", err)
+	}
+	rekorPublicKey, err := loadECDSAPublicKey(rekorPublicKeyPath)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("loading the Rekor public key: %v", err)
+	}
+	if err := rekor.VerifyPayloadLogEntry(provenanceBytes, &entry); err != nil {
+		return time.Time{}, err
+	}
+	if err := rekor.VerifyLogEntry(&entry, rekorPublicKey); err != nil {
+		return time.Time{}, fmt.Errorf("verifying the inclusion of the log entry: %v", err)
+	}
+	return entry.IntegratedAt(), nil
+}
+
+// verifyArchive verifies the integrity of the release archive at the given
+// path, and the signed endorsements in it, without network access. If
+// timeSource is not nil, the endorsements must be valid at its time.
+func verifyArchive(ctx context.Context, archivePath, archiveDigest string, timeSource *verifier.TimeSource) error {
+	if archiveDigest == "" {
+		return fmt.Errorf("--archive_digest is required with --archive_path")
+	}
+	archiveBytes, err := os.ReadFile(archivePath)
+	if err != nil {
+		return fmt.Errorf("reading the archive: %v", err)
+	}
+	releaseArchive, err := archive.Read(archiveBytes, strings.TrimPrefix(archiveDigest, "sha256:"))
+	if err != nil {
+		return fmt.Errorf("verifying the integrity of the archive: %v", err)
+	}
+	var options []func(c *archive.VerifyConfig)
+	if timeSource != nil {
+		log.Printf("Checking the validity of the endorsements at %v (%s time)", timeSource.Time, timeSource.Kind)
+		options = append(options, archive.WithClock(timeSource))
+	}
+	return releaseArchive.VerifyEndorsements(ctx, options...)
+}
+
+// writeVerificationSummary generates a VSA of the given verification, and
+// writes it to the given path, signed with the given KMS key, if set.
+func writeVerificationSummary(ctx context.Context, path, kmsKeyURI string, report *verifier.Report, provenance *model.ProvenanceIR, verOpts *pb.VerificationOptions, options []func(c *verifier.VSAConfig)) error {
+	vsa, err := verifier.GenerateVerificationSummary(report, provenance, verOpts, options...)
+	if err != nil {
+		return err
+	}
+	if kmsKeyURI == "" {
+		return intoto.WriteStatement(path, vsa)
+	}
+	signer, err := sign.NewKMSSigner(ctx, kmsKeyURI)
+	if err != nil {
+		return fmt.Errorf("creating KMS signer: %v", err)
+	}
+	envelope, err := endorser.SignStatement(ctx, vsa, signer)
+	if err != nil {
+		return fmt.Errorf("signing the VSA: %v", err)
+	}
+	return writeJSON(path, envelope)
+}
+
+// verifyReleaseBundle verifies the release bundle at the given path offline,
+// pinning the public keys at the given paths, which are required.
+func verifyReleaseBundle(ctx context.Context, bundlePath, rekorPublicKeyPath, endorserPublicKeyPath string, timeSource *verifier.TimeSource) error {
+	if rekorPublicKeyPath == "" || endorserPublicKeyPath == "" {
+		return fmt.Errorf("--release_bundle_path requires --rekor_public_key and --endorser_public_key")
+	}
+	bundleBytes, err := os.ReadFile(bundlePath)
+	if err != nil {
+		return fmt.Errorf("reading the release bundle: %v", err)
+	}
+	releaseBundle, err := bundle.Parse(bundleBytes)
+	if err != nil {
+		return err
+	}
+	rekorPublicKey, err := loadECDSAPublicKey(rekorPublicKeyPath)
+	if err != nil {
+		return fmt.Errorf("loading the Rekor public key: %v", err)
+	}
+	endorserPublicKeyPEM, err := os.ReadFile(endorserPublicKeyPath)
+	if err != nil {
+		return fmt.Errorf("reading the endorser public key: %v", err)
+	}
+	endorserPublicKey, err := sign.ParsePublicKeyPEM(endorserPublicKeyPEM)
+	if err != nil {
+		return fmt.Errorf("parsing the endorser public key: %v", err)
+	}
+	var options []func(c *bundle.VerifyConfig)
+	if timeSource != nil {
+		log.Printf("Checking the validity of the endorsement at %v (%s time)", timeSource.Time, timeSource.Kind)
+		options = append(options, bundle.WithClock(timeSource))
+	}
+	endorsement, err := releaseBundle.Verify(ctx, rekorPublicKey, endorserPublicKey, options...)
+	if err != nil {
+		return err
+	}
+	log.Printf("Verified the endorsement of %s in the release bundle", endorsement.Subject[0].Name)
+	return nil
+}
+
+// fetchRoughtimeToken queries the given Roughtime server, and stores the
+// verified response as a token at the given path.
+func fetchRoughtimeToken(ctx context.Context, path, server, publicKey string) error {
+	if server == "" || publicKey == "" {
+		return fmt.Errorf("--roughtime_server and --roughtime_public_key are required with --fetch_roughtime_token")
+	}
+	key, err := roughtime.ParsePublicKey(publicKey)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	token, t, err := roughtime.Query(ctx, server, key)
+	if err != nil {
+		return err
+	}
+	if err := writeJSON(path, token); err != nil {
+		return fmt.Errorf("writing the token to %s: %v", path, err)
+	}
+	log.Printf("Stored the time %v (radius %v) from %s in %s", t.Midpoint, t.Radius, server, path)
+	return nil
+}
+
+func readJSON(path string, object interface{}) error {
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(bytes, object)
+}
+
+func writeJSON(path string, object interface{}) error {
+	bytes, err := json.MarshalIndent(object, "", "    ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(bytes, '\n'), 0o600)
+}
+
+func loadECDSAPublicKey(path string) (*ecdsa.PublicKey, error) {
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	publicKey, err := sign.ParsePublicKeyPEM(bytes)
+	if err != nil {
+		return nil, err
+	}
+	ecdsaKey, ok := publicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("got a %T public key, want an ECDSA key", publicKey)
+	}
+	return ecdsaKey, nil
+}
@@ -0,0 +1,96 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/project-oak/transparent-release/internal/endorser"
+	"github.com/project-oak/transparent-release/pkg/claims"
+	"github.com/project-oak/transparent-release/pkg/intoto"
+	"github.com/project-oak/transparent-release/pkg/sign"
+	"github.com/secure-systems-lab/go-securesystemslib/dsse"
+)
+
+// checkEndorsementNotRevoked returns an error if the endorsement at the given
+// path is revoked by any of the endorsement revocations at the given path,
+// which is either a file or a directory of files with a `.json` suffix. The
+// revocations must be signed by the revocation authority, and not by the
+// endorser.
+func checkEndorsementNotRevoked(ctx context.Context, endorsementPath, revocationsPath, revocationPublicKeyPath, endorserPublicKeyPath string) error {
+	if revocationPublicKeyPath == "" {
+		return fmt.Errorf("--endorsement_revocations requires --revocation_public_key")
+	}
+	revocations, err := readEndorsementRevocations(ctx, revocationsPath, revocationPublicKeyPath, endorserPublicKeyPath)
+	if err != nil {
+		return fmt.Errorf("reading the endorsement revocations: %v", err)
+	}
+
+	var envelope dsse.Envelope
+	if err := readJSON(endorsementPath, &envelope); err != nil {
+		return fmt.Errorf("reading the endorsement: %v", err)
+	}
+	payload, err := envelope.DecodeB64Payload()
+	if err != nil {
+		return fmt.Errorf("decoding the endorsement: %v", err)
+	}
+	hash, err := claims.StatementHashFromBytes(payload)
+	if err != nil {
+		return fmt.Errorf("hashing the endorsement: %v", err)
+	}
+	return claims.CheckEndorsementNotRevoked(hash, revocations...)
+}
+
+func readEndorsementRevocations(ctx context.Context, path, revocationPublicKeyPath, endorserPublicKeyPath string) ([]*intoto.Statement, error) {
+	revocationKey, err := sign.LoadPublicKeyVerifier(revocationPublicKeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("loading the revocation public key: %v", err)
+	}
+	var options []func(c *endorser.RevocationConfig)
+	if endorserPublicKeyPath != "" {
+		endorserKey, err := sign.LoadPublicKeyVerifier(endorserPublicKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("loading the endorser public key: %v", err)
+		}
+		options = append(options, endorser.WithEndorsementKey(endorserKey))
+	}
+
+	paths := []string{path}
+	if info, err := os.Stat(path); err != nil {
+		return nil, err
+	} else if info.IsDir() {
+		if paths, err = filepath.Glob(filepath.Join(path, "*.json")); err != nil {
+			return nil, err
+		}
+		sort.Strings(paths)
+	}
+	revocations := make([]*intoto.Statement, 0, len(paths))
+	for _, path := range paths {
+		envelopeBytes, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		revocation, err := endorser.VerifyEndorsementRevocation(ctx, envelopeBytes, revocationKey, options...)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		revocations = append(revocations, revocation)
+	}
+	return revocations, nil
+}
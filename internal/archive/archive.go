@@ -0,0 +1,455 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package archive provides a long-term archival format for the full set of
+// attestations of a release. An archive is a gzip-compressed tarball,
+// containing a manifest with the role and digest of every other file in the
+// archive. The digest of the archive itself serves as the outer digest, and is
+// expected to be recorded separately, e.g., next to the archive.
+//
+// Archives can be verified without network access: the trust roots needed for
+// verifying the signed endorsements and their Rekor log entries are part of
+// the archive.
+package archive
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/secure-systems-lab/go-securesystemslib/dsse"
+	"go.uber.org/multierr"
+
+	"github.com/project-oak/transparent-release/internal/rekor"
+	"github.com/project-oak/transparent-release/pkg/claims"
+	"github.com/project-oak/transparent-release/pkg/intoto"
+	"github.com/project-oak/transparent-release/pkg/sign"
+)
+
+// ManifestV1 is the type of archive manifests.
+const ManifestV1 = "https://github.com/project-oak/transparent-release/archive_manifest/v1"
+
+// ManifestPath is the path of the manifest in an archive.
+const ManifestPath = "manifest.json"
+
+// Roles of the files in an archive.
+const (
+	// ProvenanceRole is the role of provenances, either as bare in-toto
+	// statements, or in DSSE envelopes.
+	ProvenanceRole = "Provenance"
+	// EndorsementRole is the role of signed endorsements, as DSSE envelopes.
+	EndorsementRole = "Endorsement"
+	// EvidenceRole is the role of any other evidence referenced by the
+	// endorsements, e.g., verification reports.
+	EvidenceRole = "Evidence"
+	// LogEntryRole is the role of Rekor log entries of signed endorsements.
+	LogEntryRole = "Rekor log entry"
+	// RekorPublicKeyRole is the role of the PEM-encoded public key of the
+	// Rekor instance.
+	RekorPublicKeyRole = "Rekor public key"
+	// EndorserPublicKeyRole is the role of the PEM-encoded public key that
+	// signed the endorsements.
+	EndorserPublicKeyRole = "Endorser public key"
+)
+
+// DefaultMaxEntryBytes is the default maximum size of a single file in an
+// archive, after decompression.
+const DefaultMaxEntryBytes = 64 << 20
+
+// SHA256DigestKey is the key of SHA256 digests in the digest sets of an
+// archive.
+const SHA256DigestKey = "sha2-256"
+
+// File is a file to add to, or read from, an archive.
+type File struct {
+	// Path of the file in the archive. Must be a clean relative path.
+	Path string
+	// Role of the file in the release.
+	Role string
+	// Subject is the path of the file that this file refers to, if any. For
+	// instance, a Rekor log entry refers to the endorsement it records.
+	Subject string
+	// Content of the file.
+	Content []byte
+}
+
+// ManifestEntry describes a single file in an archive.
+type ManifestEntry struct {
+	Path    string           `json:"path"`
+	Role    string           `json:"role"`
+	Subject string           `json:"subject,omitempty"`
+	Digest  intoto.DigestSet `json:"digest"`
+}
+
+// Manifest lists all files in an archive, other than the manifest itself.
+type Manifest struct {
+	Type  string          `json:"type"`
+	Files []ManifestEntry `json:"files"`
+}
+
+// Archive is a verified archive.
+type Archive struct {
+	Manifest Manifest
+	// Files contains the files in the archive, in the same order as in the manifest.
+	Files []File
+}
+
+// Write packs the given files into an archive and writes it to w. Returns the
+// outer digest, i.e., the hex-encoded SHA256 digest of the written bytes. The
+// output is deterministic: equal inputs result in equal archives.
+func Write(w io.Writer, files []File) (string, error) {
+	sorted := append([]File{}, files...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Path < sorted[j].Path })
+
+	manifest := Manifest{Type: ManifestV1, Files: make([]ManifestEntry, 0, len(sorted))}
+	seen := make(map[string]bool)
+	for _, f := range sorted {
+		if err := validatePath(f.Path); err != nil {
+			return "", err
+		}
+		if seen[f.Path] {
+			return "", fmt.Errorf("duplicate path %q", f.Path)
+		}
+		seen[f.Path] = true
+		manifest.Files = append(manifest.Files, ManifestEntry{
+			Path:    f.Path,
+			Role:    f.Role,
+			Subject: f.Subject,
+			Digest:  intoto.DigestSet{SHA256DigestKey: sha256Hex(f.Content)},
+		})
+	}
+	for _, entry := range manifest.Files {
+		if entry.Subject != "" && !seen[entry.Subject] {
+			return "", fmt.Errorf("subject %q of %q is not in the archive", entry.Subject, entry.Path)
+		}
+	}
+	manifestBytes, err := json.MarshalIndent(manifest, "", "    ")
+	if err != nil {
+		return "", fmt.Errorf("marshalling the manifest: %v", err)
+	}
+
+	outerHash := sha256.New()
+	gzipWriter := gzip.NewWriter(io.MultiWriter(w, outerHash))
+	tarWriter := tar.NewWriter(gzipWriter)
+	if err := writeTarFile(tarWriter, ManifestPath, manifestBytes); err != nil {
+		return "", err
+	}
+	for _, f := range sorted {
+		if err := writeTarFile(tarWriter, f.Path, f.Content); err != nil {
+			return "", err
+		}
+	}
+	if err := tarWriter.Close(); err != nil {
+		return "", fmt.Errorf("closing the tarball: %v", err)
+	}
+	if err := gzipWriter.Close(); err != nil {
+		return "", fmt.Errorf("closing the gzip stream: %v", err)
+	}
+	return hex.EncodeToString(outerHash.Sum(nil)), nil
+}
+
+// writeTarFile writes a regular file with a fixed mode and modification time,
+// to keep the archive deterministic.
+func writeTarFile(w *tar.Writer, name string, content []byte) error {
+	header := &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Mode:     0o644,
+		Size:     int64(len(content)),
+		Format:   tar.FormatPAX,
+	}
+	if err := w.WriteHeader(header); err != nil {
+		return fmt.Errorf("writing the header of %q: %v", name, err)
+	}
+	if _, err := w.Write(content); err != nil {
+		return fmt.Errorf("writing %q: %v", name, err)
+	}
+	return nil
+}
+
+// ReadConfig holds optional settings for reading an archive.
+type ReadConfig struct {
+	maxEntryBytes int64
+}
+
+// WithMaxEntryBytes overrides DefaultMaxEntryBytes.
+func WithMaxEntryBytes(maxEntryBytes int64) func(c *ReadConfig) {
+	return func(c *ReadConfig) {
+		c.maxEntryBytes = maxEntryBytes
+	}
+}
+
+// Read verifies that the given archive bytes have the given outer digest, and
+// that the archive contains exactly the files listed in its manifest, with
+// the listed digests, each at most once. Returns the verified archive. Fails
+// for files larger than the maximum entry size, so that a small compressed
+// archive cannot exhaust the memory.
+func Read(archiveBytes []byte, outerDigest string, options ...func(c *ReadConfig)) (*Archive, error) {
+	config := &ReadConfig{maxEntryBytes: DefaultMaxEntryBytes}
+	for _, addOption := range options {
+		addOption(config)
+	}
+	if got := sha256Hex(archiveBytes); got != outerDigest {
+		return nil, fmt.Errorf("unexpected outer digest: got %s, want %s", got, outerDigest)
+	}
+
+	gzipReader, err := gzip.NewReader(bytes.NewReader(archiveBytes))
+	if err != nil {
+		return nil, fmt.Errorf("opening the gzip stream: %v", err)
+	}
+	tarReader := tar.NewReader(gzipReader)
+	contents := make(map[string][]byte)
+	var manifestBytes []byte
+	for {
+		header, err := tarReader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading the tarball: %v", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			return nil, fmt.Errorf("%q is not a regular file", header.Name)
+		}
+		if header.Size > config.maxEntryBytes {
+			return nil, fmt.Errorf("%q exceeds the maximum size of %d bytes", header.Name, config.maxEntryBytes)
+		}
+		// Read one byte more than the limit to detect oversized content.
+		content, err := io.ReadAll(io.LimitReader(tarReader, config.maxEntryBytes+1))
+		if err != nil {
+			return nil, fmt.Errorf("reading %q: %v", header.Name, err)
+		}
+		if int64(len(content)) > config.maxEntryBytes {
+			return nil, fmt.Errorf("%q exceeds the maximum size of %d bytes", header.Name, config.maxEntryBytes)
+		}
+		if header.Name == ManifestPath {
+			if manifestBytes != nil {
+				return nil, fmt.Errorf("duplicate file %q", header.Name)
+			}
+			manifestBytes = content
+			continue
+		}
+		if _, ok := contents[header.Name]; ok {
+			return nil, fmt.Errorf("duplicate file %q", header.Name)
+		}
+		contents[header.Name] = content
+	}
+	if manifestBytes == nil {
+		return nil, fmt.Errorf("the archive does not contain %s", ManifestPath)
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(manifestBytes, &manifest); err != nil {
+		return nil, fmt.Errorf("parsing the manifest: %v", err)
+	}
+	if manifest.Type != ManifestV1 {
+		return nil, fmt.Errorf("unsupported manifest type: got %q, want %q", manifest.Type, ManifestV1)
+	}
+
+	archive := &Archive{Manifest: manifest, Files: make([]File, 0, len(manifest.Files))}
+	var errs error
+	listed := make(map[string]bool, len(manifest.Files))
+	for _, entry := range manifest.Files {
+		if listed[entry.Path] {
+			errs = multierr.Append(errs, fmt.Errorf("%q is listed more than once in the manifest", entry.Path))
+			continue
+		}
+		listed[entry.Path] = true
+		content, ok := contents[entry.Path]
+		if !ok {
+			errs = multierr.Append(errs, fmt.Errorf("%q is missing from the archive", entry.Path))
+			continue
+		}
+		delete(contents, entry.Path)
+		if got, want := sha256Hex(content), entry.Digest[SHA256DigestKey]; got != want {
+			errs = multierr.Append(errs, fmt.Errorf("unexpected digest of %q: got %s, want %s", entry.Path, got, want))
+			continue
+		}
+		archive.Files = append(archive.Files, File{Path: entry.Path, Role: entry.Role, Subject: entry.Subject, Content: content})
+	}
+	for name := range contents {
+		errs = multierr.Append(errs, fmt.Errorf("%q is not listed in the manifest", name))
+	}
+	if errs != nil {
+		return nil, errs
+	}
+	return archive, nil
+}
+
+// VerifyConfig holds optional settings for verifying the endorsements in an
+// archive.
+type VerifyConfig struct {
+	clock claims.Clock
+}
+
+// WithClock additionally checks that every endorsement is valid, i.e., active
+// or expiring soon, at the time of the given clock. On machines whose local
+// clock is not trusted, this can be a time authenticated by a Roughtime
+// server.
+func WithClock(clock claims.Clock) func(c *VerifyConfig) {
+	return func(c *VerifyConfig) {
+		c.clock = clock
+	}
+}
+
+// VerifyEndorsements verifies, without network access, that every signed
+// endorsement in the archive is signed by the endorser public key in the
+// archive, and that it is recorded in a Rekor log entry in the archive whose
+// inclusion can be verified with the Rekor public key in the archive.
+func (a *Archive) VerifyEndorsements(ctx context.Context, options ...func(c *VerifyConfig)) error {
+	config := &VerifyConfig{}
+	for _, addOption := range options {
+		addOption(config)
+	}
+
+	endorsements := a.filesWithRole(EndorsementRole)
+	if len(endorsements) == 0 {
+		return fmt.Errorf("the archive does not contain any endorsements")
+	}
+
+	rekorPublicKey, err := a.rekorPublicKey()
+	if err != nil {
+		return err
+	}
+	endorserVerifier, err := a.endorserVerifier()
+	if err != nil {
+		return err
+	}
+
+	logEntries := make(map[string]File)
+	for _, f := range a.filesWithRole(LogEntryRole) {
+		logEntries[f.Subject] = f
+	}
+
+	var errs error
+	for _, f := range endorsements {
+		logEntryFile, ok := logEntries[f.Path]
+		if !ok {
+			errs = multierr.Append(errs, fmt.Errorf("no Rekor log entry for %q", f.Path))
+			continue
+		}
+		if err := verifyEndorsement(ctx, f, logEntryFile, rekorPublicKey, endorserVerifier, config.clock); err != nil {
+			errs = multierr.Append(errs, fmt.Errorf("verifying %q: %v", f.Path, err))
+		}
+	}
+	return errs
+}
+
+func verifyEndorsement(ctx context.Context, endorsementFile, logEntryFile File, rekorPublicKey *ecdsa.PublicKey, endorserVerifier dsse.Verifier, clock claims.Clock) error {
+	var envelope dsse.Envelope
+	if err := json.Unmarshal(endorsementFile.Content, &envelope); err != nil {
+		return fmt.Errorf("parsing the DSSE envelope: %v", err)
+	}
+	var entry rekor.LogEntry
+	if err := json.Unmarshal(logEntryFile.Content, &entry); err != nil {
+		return fmt.Errorf("parsing the log entry %q: %v", logEntryFile.Path, err)
+	}
+	if err := rekor.VerifyLogEntry(&entry, rekorPublicKey); err != nil {
+		return fmt.Errorf("verifying the inclusion of the log entry: %v", err)
+	}
+	if err := rekor.VerifyEnvelopeLogEntry(ctx, &envelope, &entry, endorserVerifier); err != nil {
+		return err
+	}
+	if clock == nil {
+		return nil
+	}
+
+	payload, err := envelope.DecodeB64Payload()
+	if err != nil {
+		return fmt.Errorf("decoding the endorsement: %v", err)
+	}
+	statement, err := claims.ParseEndorsementV2Bytes(payload)
+	if err != nil {
+		return fmt.Errorf("parsing the endorsement: %v", err)
+	}
+	status, err := claims.ClaimStatus(statement, claims.WithStatusClock(clock))
+	if err != nil {
+		return err
+	}
+	if status != claims.StatusActive && status != claims.StatusExpiringSoon {
+		return fmt.Errorf("the endorsement is %s at %v", status, clock.Now())
+	}
+	return nil
+}
+
+func (a *Archive) filesWithRole(role string) []File {
+	var files []File
+	for _, f := range a.Files {
+		if f.Role == role {
+			files = append(files, f)
+		}
+	}
+	return files
+}
+
+// singleFileWithRole returns the only file with the given role, or an error
+// if there is not exactly one such file.
+func (a *Archive) singleFileWithRole(role string) (*File, error) {
+	files := a.filesWithRole(role)
+	if len(files) != 1 {
+		return nil, fmt.Errorf("got %d files with role %q, want exactly 1", len(files), role)
+	}
+	return &files[0], nil
+}
+
+func (a *Archive) rekorPublicKey() (*ecdsa.PublicKey, error) {
+	f, err := a.singleFileWithRole(RekorPublicKeyRole)
+	if err != nil {
+		return nil, err
+	}
+	publicKey, err := sign.ParsePublicKeyPEM(f.Content)
+	if err != nil {
+		return nil, fmt.Errorf("parsing the Rekor public key: %v", err)
+	}
+	ecdsaKey, ok := publicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("got a %T Rekor public key, want an ECDSA key", publicKey)
+	}
+	return ecdsaKey, nil
+}
+
+func (a *Archive) endorserVerifier() (dsse.Verifier, error) {
+	f, err := a.singleFileWithRole(EndorserPublicKeyRole)
+	if err != nil {
+		return nil, err
+	}
+	publicKey, err := sign.ParsePublicKeyPEM(f.Content)
+	if err != nil {
+		return nil, fmt.Errorf("parsing the endorser public key: %v", err)
+	}
+	return sign.NewPublicKeyVerifier(publicKey)
+}
+
+func validatePath(name string) error {
+	if name == "" || name == ManifestPath || path.IsAbs(name) || path.Clean(name) != name || name == ".." || strings.HasPrefix(name, "../") {
+		return fmt.Errorf("invalid path %q", name)
+	}
+	return nil
+}
+
+func sha256Hex(content []byte) string {
+	sum256 := sha256.Sum256(content)
+	return hex.EncodeToString(sum256[:])
+}
@@ -0,0 +1,313 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package archive
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/project-oak/transparent-release/pkg/claims"
+)
+
+const fixturesPath = "../../testdata/rekor"
+
+func readFixture(t *testing.T, name string) []byte {
+	bytes, err := os.ReadFile(filepath.Join(fixturesPath, name))
+	if err != nil {
+		t.Fatalf("could not read %s: %v", name, err)
+	}
+	return bytes
+}
+
+func releaseFiles(t *testing.T) []File {
+	return []File{
+		{Path: "endorsement.dsse.json", Role: EndorsementRole, Content: readFixture(t, "endorsement.dsse.json")},
+		{Path: "endorsement.rekor.json", Role: LogEntryRole, Subject: "endorsement.dsse.json", Content: readFixture(t, "endorsement.rekor.json")},
+		{Path: "rekor.pub", Role: RekorPublicKeyRole, Content: readFixture(t, "rekor.pub")},
+		{Path: "endorser.pub", Role: EndorserPublicKeyRole, Content: readFixture(t, "endorser.pub")},
+		{Path: "provenances/slsa_v1_provenance.json", Role: ProvenanceRole, Content: []byte("{}")},
+	}
+}
+
+func writeArchive(t *testing.T, files []File) ([]byte, string) {
+	var buf bytes.Buffer
+	digest, err := Write(&buf, files)
+	if err != nil {
+		t.Fatalf("could not write the archive: %v", err)
+	}
+	return buf.Bytes(), digest
+}
+
+func TestWriteRead_RoundTrip(t *testing.T) {
+	files := releaseFiles(t)
+	archiveBytes, digest := writeArchive(t, files)
+
+	got, err := Read(archiveBytes, digest)
+	if err != nil {
+		t.Fatalf("could not read the archive: %v", err)
+	}
+	if len(got.Files) != len(files) {
+		t.Fatalf("got %d files, want %d", len(got.Files), len(files))
+	}
+	for _, want := range files {
+		found := false
+		for _, f := range got.Files {
+			if f.Path == want.Path {
+				found = true
+				if diff := cmp.Diff(want, f); diff != "" {
+					t.Errorf("unexpected file %q: %s", want.Path, diff)
+				}
+			}
+		}
+		if !found {
+			t.Errorf("%q is missing from the archive", want.Path)
+		}
+	}
+
+	if err := got.VerifyEndorsements(context.Background()); err != nil {
+		t.Errorf("could not verify the endorsements: %v", err)
+	}
+}
+
+func TestWrite_Deterministic(t *testing.T) {
+	files := releaseFiles(t)
+	_, digest := writeArchive(t, files)
+
+	reversed := make([]File, 0, len(files))
+	for i := len(files) - 1; i >= 0; i-- {
+		reversed = append(reversed, files[i])
+	}
+	_, otherDigest := writeArchive(t, reversed)
+	if digest != otherDigest {
+		t.Errorf("got different outer digests for equal inputs: %s and %s", digest, otherDigest)
+	}
+}
+
+func TestWrite_InvalidInput(t *testing.T) {
+	tests := map[string][]File{
+		"absolute path":   {{Path: "/etc/passwd", Role: EvidenceRole}},
+		"parent path":     {{Path: "../evidence.json", Role: EvidenceRole}},
+		"manifest path":   {{Path: ManifestPath, Role: EvidenceRole}},
+		"duplicate path":  {{Path: "evidence.json", Role: EvidenceRole}, {Path: "evidence.json", Role: EvidenceRole}},
+		"missing subject": {{Path: "entry.json", Role: LogEntryRole, Subject: "endorsement.json"}},
+	}
+	for name, files := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Write(&bytes.Buffer{}, files); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
+
+func TestRead_WrongOuterDigest(t *testing.T) {
+	archiveBytes, _ := writeArchive(t, releaseFiles(t))
+	_, otherDigest := writeArchive(t, []File{{Path: "evidence.json", Role: EvidenceRole}})
+
+	_, err := Read(archiveBytes, otherDigest)
+	if err == nil || !strings.Contains(err.Error(), "outer digest") {
+		t.Errorf("got %v, want an error about the outer digest", err)
+	}
+}
+
+// rewrite rebuilds the given archive, replacing the content of the given file,
+// or adding it if it is not in the archive. Returns the new archive and its
+// outer digest.
+func rewrite(t *testing.T, archiveBytes []byte, name string, content []byte) ([]byte, string) {
+	replaced := false
+	rewritten, digest := rebuildArchive(t, archiveBytes, func(tarWriter *tar.Writer, fileName string, fileContent []byte) {
+		if fileName == name {
+			fileContent = content
+			replaced = true
+		}
+		if err := writeTarFile(tarWriter, fileName, fileContent); err != nil {
+			t.Fatal(err)
+		}
+	}, func(tarWriter *tar.Writer) {
+		if replaced {
+			return
+		}
+		if err := writeTarFile(tarWriter, name, content); err != nil {
+			t.Fatal(err)
+		}
+	})
+	return rewritten, digest
+}
+
+// duplicate rebuilds the given archive, with the given file twice. Returns the
+// new archive and its outer digest.
+func duplicate(t *testing.T, archiveBytes []byte, name string) ([]byte, string) {
+	return rebuildArchive(t, archiveBytes, func(tarWriter *tar.Writer, fileName string, fileContent []byte) {
+		copies := 1
+		if fileName == name {
+			copies = 2
+		}
+		for i := 0; i < copies; i++ {
+			if err := writeTarFile(tarWriter, fileName, fileContent); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}, func(*tar.Writer) {})
+}
+
+// rebuildArchive rebuilds the given archive, calling write for every file in
+// it, and then finish. Returns the new archive and its outer digest.
+func rebuildArchive(t *testing.T, archiveBytes []byte, write func(tarWriter *tar.Writer, name string, content []byte), finish func(tarWriter *tar.Writer)) ([]byte, string) {
+	gzipReader, err := gzip.NewReader(bytes.NewReader(archiveBytes))
+	if err != nil {
+		t.Fatalf("could not open the archive: %v", err)
+	}
+	tarReader := tar.NewReader(gzipReader)
+
+	var buf bytes.Buffer
+	gzipWriter := gzip.NewWriter(&buf)
+	tarWriter := tar.NewWriter(gzipWriter)
+	for {
+		header, err := tarReader.Next()
+		if err != nil {
+			break
+		}
+		fileContent := new(bytes.Buffer)
+		if _, err := fileContent.ReadFrom(tarReader); err != nil {
+			t.Fatalf("could not read %q: %v", header.Name, err)
+		}
+		write(tarWriter, header.Name, fileContent.Bytes())
+	}
+	finish(tarWriter)
+	if err := tarWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gzipWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes(), sha256Hex(buf.Bytes())
+}
+
+func TestRead_TamperedFile(t *testing.T) {
+	archiveBytes, _ := writeArchive(t, releaseFiles(t))
+	tampered, digest := rewrite(t, archiveBytes, "provenances/slsa_v1_provenance.json", []byte(`{"tampered": true}`))
+
+	_, err := Read(tampered, digest)
+	if err == nil || !strings.Contains(err.Error(), "unexpected digest") {
+		t.Errorf("got %v, want an error about the digest of the tampered file", err)
+	}
+}
+
+func TestRead_UnlistedFile(t *testing.T) {
+	archiveBytes, _ := writeArchive(t, releaseFiles(t))
+	tampered, digest := rewrite(t, archiveBytes, "unlisted.json", []byte("{}"))
+
+	_, err := Read(tampered, digest)
+	if err == nil || !strings.Contains(err.Error(), "not listed in the manifest") {
+		t.Errorf("got %v, want an error about the unlisted file", err)
+	}
+}
+
+func TestRead_DuplicateManifest(t *testing.T) {
+	archiveBytes, _ := writeArchive(t, releaseFiles(t))
+	tampered, digest := duplicate(t, archiveBytes, ManifestPath)
+
+	_, err := Read(tampered, digest)
+	if err == nil || !strings.Contains(err.Error(), "duplicate file") {
+		t.Errorf("got %v, want an error about the duplicate manifest", err)
+	}
+}
+
+func TestRead_DuplicateFile(t *testing.T) {
+	archiveBytes, _ := writeArchive(t, releaseFiles(t))
+	tampered, digest := duplicate(t, archiveBytes, "provenances/slsa_v1_provenance.json")
+
+	_, err := Read(tampered, digest)
+	if err == nil || !strings.Contains(err.Error(), "duplicate file") {
+		t.Errorf("got %v, want an error about the duplicate file", err)
+	}
+}
+
+func TestRead_MaxEntryBytes(t *testing.T) {
+	archiveBytes, digest := writeArchive(t, []File{{Path: "evidence.json", Role: EvidenceRole, Content: bytes.Repeat([]byte("0"), 2048)}})
+
+	if _, err := Read(archiveBytes, digest, WithMaxEntryBytes(2048)); err != nil {
+		t.Fatalf("could not read the archive: %v", err)
+	}
+	_, err := Read(archiveBytes, digest, WithMaxEntryBytes(2047))
+	if err == nil || !strings.Contains(err.Error(), `"evidence.json" exceeds the maximum size`) {
+		t.Errorf("got %v, want an error about the size of the file", err)
+	}
+}
+
+func TestVerifyEndorsements_MissingLogEntry(t *testing.T) {
+	var files []File
+	for _, f := range releaseFiles(t) {
+		if f.Role != LogEntryRole {
+			files = append(files, f)
+		}
+	}
+	archiveBytes, digest := writeArchive(t, files)
+	archive, err := Read(archiveBytes, digest)
+	if err != nil {
+		t.Fatalf("could not read the archive: %v", err)
+	}
+
+	if err := archive.VerifyEndorsements(context.Background()); err == nil {
+		t.Errorf("expected verification without a log entry to fail")
+	}
+}
+
+func TestVerifyEndorsements_WrongTrustRoot(t *testing.T) {
+	files := releaseFiles(t)
+	for i := range files {
+		// Use the endorser key as the Rekor key.
+		if files[i].Role == RekorPublicKeyRole {
+			files[i].Content = readFixture(t, "endorser.pub")
+		}
+	}
+	archiveBytes, digest := writeArchive(t, files)
+	archive, err := Read(archiveBytes, digest)
+	if err != nil {
+		t.Fatalf("could not read the archive: %v", err)
+	}
+
+	if err := archive.VerifyEndorsements(context.Background()); err == nil {
+		t.Errorf("expected verification with the wrong Rekor key to fail")
+	}
+}
+
+func TestVerifyEndorsements_WithClock(t *testing.T) {
+	archiveBytes, digest := writeArchive(t, releaseFiles(t))
+	archive, err := Read(archiveBytes, digest)
+	if err != nil {
+		t.Fatalf("could not read the archive: %v", err)
+	}
+
+	// The endorsement is valid from 2022-07-08 to 2022-08-08.
+	valid := claims.FixedClock(time.Date(2022, 7, 20, 0, 0, 0, 0, time.UTC))
+	if err := archive.VerifyEndorsements(context.Background(), WithClock(valid)); err != nil {
+		t.Errorf("could not verify the endorsements: %v", err)
+	}
+	expired := claims.FixedClock(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC))
+	err = archive.VerifyEndorsements(context.Background(), WithClock(expired))
+	if err == nil || !strings.Contains(err.Error(), "expired") {
+		t.Errorf("got %v, want an error about the expired endorsement", err)
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package blobstore abstracts the blob storage from which fuzzing reports and
+// evidence are read, so that they can be stored in Google Cloud Storage (see
+// gcsutil), in AWS S3 or an S3-compatible service, or in a local directory.
+package blobstore
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Query selects the entries listed by BlobStore.ListPage.
+type Query struct {
+	// Prefix restricts the entries to the blobs whose paths start with it.
+	Prefix string
+	// Delimiter, e.g., `/`, lists the blobs whose paths contain it after
+	// Prefix as a single prefix entry, up to and including the delimiter,
+	// like the subdirectories of a directory.
+	Delimiter string
+}
+
+// Entry is an entry of a listing of a bucket. Exactly one of Name and Prefix
+// is set.
+type Entry struct {
+	// Name is the path of a blob.
+	Name string
+	// Prefix is a prefix of the paths of blobs, if Query.Delimiter is set.
+	Prefix string
+}
+
+// key returns the name or the prefix of the entry, by which listings are
+// sorted.
+func (e Entry) key() string {
+	if e.Prefix != "" {
+		return e.Prefix
+	}
+	return e.Name
+}
+
+// BlobStore provides read access to blobs, organized in buckets.
+type BlobStore interface {
+	// ListPage lists a page of at most pageSize entries, or of a size chosen
+	// by the store if pageSize is not positive, in the given bucket that
+	// match the given query, in lexicographic order, starting at the given
+	// page token, or at the first entry if the token is empty. Returns the
+	// entries, and the token of the next page, which is empty after the last
+	// page.
+	ListPage(ctx context.Context, bucketName string, query Query, pageSize int, pageToken string) ([]Entry, string, error)
+	// NewReader returns a reader for the blob at the given path in the given
+	// bucket. The caller closes the reader.
+	NewReader(ctx context.Context, bucketName string, blobPath string) (io.ReadCloser, error)
+	// URI returns the URI of the blob at the given path in the given bucket,
+	// e.g., `gs://bucket/path`, by which it is referenced as evidence.
+	URI(bucketName string, blobPath string) string
+}
+
+// Open returns the BlobStore with the given specification: `s3` for AWS S3,
+// configured from the environment, see NewS3Store, or a `file` URI of a local
+// directory, see NewDirStore. The Google Cloud Storage store is provided by
+// gcsutil.
+func Open(spec string) (BlobStore, error) {
+	if spec == "s3" {
+		return NewS3Store(), nil
+	}
+	uri, err := url.Parse(spec)
+	if err != nil || uri.Scheme != "file" || uri.Host != "" || uri.Path == "" {
+		return nil, fmt.Errorf("invalid blob store %q, want s3 or file:///path/to/dir", spec)
+	}
+	return NewDirStore(uri.Path), nil
+}
+
+// listEntries returns the entries of a listing of the given sorted blob
+// paths that match the given query, as specified by BlobStore.ListPage, with
+// page tokens that are the keys of the last entries of the pages. It is used
+// by stores that list all blobs at once.
+func listEntries(blobPaths []string, query Query, pageSize int, pageToken string) ([]Entry, string) {
+	var entries []Entry
+	seenPrefixes := map[string]bool{}
+	for _, blobPath := range blobPaths {
+		if !strings.HasPrefix(blobPath, query.Prefix) {
+			continue
+		}
+		rest := strings.TrimPrefix(blobPath, query.Prefix)
+		if i := strings.Index(rest, query.Delimiter); query.Delimiter != "" && i >= 0 {
+			prefix := query.Prefix + rest[:i+len(query.Delimiter)]
+			if !seenPrefixes[prefix] {
+				seenPrefixes[prefix] = true
+				entries = append(entries, Entry{Prefix: prefix})
+			}
+			continue
+		}
+		entries = append(entries, Entry{Name: blobPath})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].key() < entries[j].key() })
+
+	start := sort.Search(len(entries), func(i int) bool { return entries[i].key() > pageToken })
+	entries = entries[start:]
+	if pageSize <= 0 || len(entries) <= pageSize {
+		return entries, ""
+	}
+	return entries[:pageSize], entries[pageSize-1].key()
+}
@@ -0,0 +1,106 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package blobstore
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DirStore is a BlobStore in a local directory, with a subdirectory per
+// bucket, e.g., a mirror of Google Cloud Storage buckets made with
+// `gsutil -m rsync -r gs://bucket <dir>/bucket`.
+type DirStore struct {
+	root string
+}
+
+// NewDirStore creates a DirStore in the given directory.
+func NewDirStore(root string) *DirStore {
+	return &DirStore{root: root}
+}
+
+// ListPage implements BlobStore.
+func (s *DirStore) ListPage(ctx context.Context, bucketName string, query Query, pageSize int, pageToken string) ([]Entry, string, error) {
+	bucketDir, err := s.path(bucketName, "")
+	if err != nil {
+		return nil, "", err
+	}
+	var blobPaths []string
+	err = filepath.WalkDir(bucketDir, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		relativePath, err := filepath.Rel(bucketDir, filePath)
+		if err != nil {
+			return err
+		}
+		blobPaths = append(blobPaths, filepath.ToSlash(relativePath))
+		return nil
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("could not list the blobs in %q: %v", bucketDir, err)
+	}
+	sort.Strings(blobPaths)
+	entries, nextPageToken := listEntries(blobPaths, query, pageSize, pageToken)
+	return entries, nextPageToken, nil
+}
+
+// NewReader implements BlobStore.
+func (s *DirStore) NewReader(_ context.Context, bucketName string, blobPath string) (io.ReadCloser, error) {
+	filePath, err := s.path(bucketName, blobPath)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("could not open blob %q: %v", blobPath, err)
+	}
+	return file, nil
+}
+
+// URI implements BlobStore.
+func (s *DirStore) URI(bucketName string, blobPath string) string {
+	filePath, err := filepath.Abs(filepath.Join(s.root, bucketName, filepath.FromSlash(blobPath)))
+	if err != nil {
+		filePath = filepath.Join(s.root, bucketName, filepath.FromSlash(blobPath))
+	}
+	return "file://" + filepath.ToSlash(filePath)
+}
+
+// path returns the path of the file of the blob at the given path in the
+// given bucket, or of the directory of the bucket if the blob path is empty.
+// Paths that escape the directory of the bucket are rejected.
+func (s *DirStore) path(bucketName string, blobPath string) (string, error) {
+	if bucketName == "" || strings.ContainsAny(bucketName, `/\`) || bucketName == "." || bucketName == ".." {
+		return "", fmt.Errorf("invalid bucket name %q", bucketName)
+	}
+	if blobPath != "" && (path.IsAbs(blobPath) || path.Clean(blobPath) != blobPath || blobPath == ".." || strings.HasPrefix(blobPath, "../")) {
+		return "", fmt.Errorf("invalid blob path %q", blobPath)
+	}
+	return filepath.Join(s.root, bucketName, filepath.FromSlash(blobPath)), nil
+}
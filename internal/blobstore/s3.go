@@ -0,0 +1,330 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package blobstore
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultS3Region is the AWS region used if none is configured.
+const DefaultS3Region = "us-east-1"
+
+// emptyPayloadHash is the hex-encoded SHA256 digest of an empty request body.
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// S3Config holds optional settings for an S3Store.
+type S3Config struct {
+	endpoint        string
+	region          string
+	accessKeyID     string
+	secretAccessKey string
+	sessionToken    string
+	httpClient      *http.Client
+	clock           func() time.Time
+}
+
+// WithS3Endpoint overrides the endpoint of the S3 API, e.g., for an
+// S3-compatible service. Defaults to the AWS endpoint of the region.
+func WithS3Endpoint(endpoint string) func(c *S3Config) {
+	return func(c *S3Config) {
+		c.endpoint = strings.TrimSuffix(endpoint, "/")
+	}
+}
+
+// WithS3Region overrides the AWS region.
+func WithS3Region(region string) func(c *S3Config) {
+	return func(c *S3Config) {
+		c.region = region
+	}
+}
+
+// WithS3Credentials overrides the AWS credentials with which requests are
+// signed. The session token may be empty. Requests are not signed if the
+// access key ID is empty, so that public buckets can be read.
+func WithS3Credentials(accessKeyID, secretAccessKey, sessionToken string) func(c *S3Config) {
+	return func(c *S3Config) {
+		c.accessKeyID = accessKeyID
+		c.secretAccessKey = secretAccessKey
+		c.sessionToken = sessionToken
+	}
+}
+
+// WithS3HTTPClient overrides http.DefaultClient.
+func WithS3HTTPClient(httpClient *http.Client) func(c *S3Config) {
+	return func(c *S3Config) {
+		c.httpClient = httpClient
+	}
+}
+
+// S3Store is a BlobStore in AWS S3, or an S3-compatible service, accessed with
+// path-style requests signed with AWS Signature Version 4.
+type S3Store struct {
+	config *S3Config
+}
+
+// NewS3Store creates an S3Store. The region, the endpoint, and the credentials
+// are read from the standard AWS environment variables (AWS_REGION or
+// AWS_DEFAULT_REGION, AWS_ENDPOINT_URL_S3 or AWS_ENDPOINT_URL,
+// AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY, and AWS_SESSION_TOKEN), and can
+// be overridden by the given options.
+func NewS3Store(options ...func(c *S3Config)) *S3Store {
+	config := &S3Config{
+		region:          firstNonEmpty(os.Getenv("AWS_REGION"), os.Getenv("AWS_DEFAULT_REGION"), DefaultS3Region),
+		endpoint:        strings.TrimSuffix(firstNonEmpty(os.Getenv("AWS_ENDPOINT_URL_S3"), os.Getenv("AWS_ENDPOINT_URL")), "/"),
+		accessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		secretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		sessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		httpClient:      http.DefaultClient,
+		clock:           time.Now,
+	}
+	for _, addOption := range options {
+		addOption(config)
+	}
+	if config.endpoint == "" {
+		config.endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", config.region)
+	}
+	return &S3Store{config: config}
+}
+
+// listBucketResult is the response of the ListObjectsV2 API.
+type listBucketResult struct {
+	Contents []struct {
+		Key string `xml:"Key"`
+	} `xml:"Contents"`
+	CommonPrefixes []struct {
+		Prefix string `xml:"Prefix"`
+	} `xml:"CommonPrefixes"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// ListPage implements BlobStore, with the ListObjectsV2 API.
+func (s *S3Store) ListPage(ctx context.Context, bucketName string, query Query, pageSize int, pageToken string) ([]Entry, string, error) {
+	params := url.Values{"list-type": {"2"}}
+	if query.Prefix != "" {
+		params.Set("prefix", query.Prefix)
+	}
+	if query.Delimiter != "" {
+		params.Set("delimiter", query.Delimiter)
+	}
+	if pageSize > 0 {
+		params.Set("max-keys", strconv.Itoa(pageSize))
+	}
+	if pageToken != "" {
+		params.Set("continuation-token", pageToken)
+	}
+	body, err := s.get(ctx, bucketName, "", params)
+	if err != nil {
+		return nil, "", fmt.Errorf("could not list the objects in %q: %v", bucketName, err)
+	}
+	defer body.Close()
+	var result listBucketResult
+	if err := xml.NewDecoder(body).Decode(&result); err != nil {
+		return nil, "", fmt.Errorf("could not parse the listing of %q: %v", bucketName, err)
+	}
+	entries := make([]Entry, 0, len(result.Contents)+len(result.CommonPrefixes))
+	for _, object := range result.Contents {
+		entries = append(entries, Entry{Name: object.Key})
+	}
+	for _, prefix := range result.CommonPrefixes {
+		entries = append(entries, Entry{Prefix: prefix.Prefix})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].key() < entries[j].key() })
+	if !result.IsTruncated {
+		return entries, "", nil
+	}
+	return entries, result.NextContinuationToken, nil
+}
+
+// NewReader implements BlobStore, with the GetObject API.
+func (s *S3Store) NewReader(ctx context.Context, bucketName string, blobPath string) (io.ReadCloser, error) {
+	body, err := s.get(ctx, bucketName, blobPath, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not get object %q: %v", blobPath, err)
+	}
+	return body, nil
+}
+
+// URI implements BlobStore.
+func (s *S3Store) URI(bucketName string, blobPath string) string {
+	return fmt.Sprintf("s3://%s/%s", bucketName, blobPath)
+}
+
+// Fetch returns a reader for the object at the given `s3://bucket/key` URI.
+// It implements fetch.Fetcher.
+func (s *S3Store) Fetch(ctx context.Context, uri *url.URL) (io.ReadCloser, error) {
+	key := strings.TrimPrefix(uri.Path, "/")
+	if uri.Scheme != "s3" || uri.Host == "" || key == "" {
+		return nil, fmt.Errorf("invalid S3 URI %q, want s3://bucket/key", uri)
+	}
+	return s.NewReader(ctx, uri.Host, key)
+}
+
+// get sends a signed GET request for the given key, or for the bucket if the
+// key is empty, with the given query parameters, and returns the body of a
+// successful response.
+func (s *S3Store) get(ctx context.Context, bucketName, key string, params url.Values) (io.ReadCloser, error) {
+	endpoint, err := url.Parse(s.config.endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid S3 endpoint %q: %v", s.config.endpoint, err)
+	}
+	requestPath := "/" + bucketName + "/" + key
+	if key == "" {
+		requestPath = "/" + bucketName
+	}
+	requestURL := &url.URL{
+		Scheme:   endpoint.Scheme,
+		Host:     endpoint.Host,
+		Path:     endpoint.Path + requestPath,
+		RawPath:  uriEncode(endpoint.Path+requestPath, false),
+		RawQuery: canonicalQuery(params),
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not create the request: %v", err)
+	}
+	if s.config.accessKeyID != "" {
+		s.sign(req, s.config.clock())
+	}
+	resp, err := s.config.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("could not receive response from server: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("the request failed with status %s: %s", resp.Status, message)
+	}
+	return resp.Body, nil
+}
+
+// sign signs the given request, without a body, with AWS Signature Version 4,
+// at the given time. All headers of the request are signed, in addition to
+// the host and the added x-amz-* headers.
+//
+// See https://docs.aws.amazon.com/AmazonS3/latest/API/sig-v4-header-based-auth.html.
+func (s *S3Store) sign(req *http.Request, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", emptyPayloadHash)
+	if s.config.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.config.sessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		emptyPayloadHash,
+	}, "\n")
+	scope := fmt.Sprintf("%s/%s/s3/aws4_request", date, s.config.region)
+	requestDigest := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, hex.EncodeToString(requestDigest[:])}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.config.secretAccessKey), date)
+	key = hmacSHA256(key, s.config.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.config.accessKeyID, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// canonicalQuery returns the query string of the given parameters, sorted by
+// name, and encoded as required by AWS Signature Version 4.
+func canonicalQuery(params url.Values) string {
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var pairs []string
+	for _, name := range names {
+		values := append([]string(nil), params[name]...)
+		sort.Strings(values)
+		for _, value := range values {
+			pairs = append(pairs, uriEncode(name, true)+"="+uriEncode(value, true))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// uriEncode percent-encodes all characters of the given string except the
+// unreserved characters, and `/` unless encodeSlash is set, as required by
+// AWS Signature Version 4.
+func uriEncode(s string, encodeSlash bool) string {
+	var encoded strings.Builder
+	for _, b := range []byte(s) {
+		switch {
+		case 'A' <= b && b <= 'Z', 'a' <= b && b <= 'z', '0' <= b && b <= '9',
+			b == '-', b == '_', b == '.', b == '~':
+			encoded.WriteByte(b)
+		case b == '/' && !encodeSlash:
+			encoded.WriteByte(b)
+		default:
+			fmt.Fprintf(&encoded, "%%%02X", b)
+		}
+	}
+	return encoded.String()
+}
+
+// firstNonEmpty returns the first of the given strings that is not empty.
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,360 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package builder runs container-based builds, and generates unsigned SLSA v1
+// provenances for them, following the design of the Docker-based builder of
+// the SLSA GitHub generator. A build is described by a TOML or JSON config
+// file in the source repository; see config.go. Generating the predicate and
+// running the build are separate steps, so that the predicate can be
+// generated, and reviewed, before the build runs.
+package builder
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.uber.org/multierr"
+
+	"github.com/project-oak/transparent-release/internal/rebuild"
+	"github.com/project-oak/transparent-release/pkg/claims"
+	"github.com/project-oak/transparent-release/pkg/intoto"
+	slsav1 "github.com/project-oak/transparent-release/pkg/intoto/slsa_provenance/v1"
+	"github.com/project-oak/transparent-release/pkg/toolchain"
+)
+
+// GitHubContext is the context of the GitHub Actions workflow run that runs
+// the build.
+type GitHubContext struct {
+	// ServerURL is the URL of the GitHub server, e.g., `https://github.com`.
+	ServerURL string
+	// Repository is the owner and name of the repository, e.g.,
+	// `project-oak/oak`.
+	Repository string
+	// SHA is the hex-encoded SHA1 digest of the commit that is built.
+	SHA string
+	// Ref is the Git ref that the workflow run was triggered on, e.g.,
+	// `refs/heads/main`. Optional.
+	Ref string
+	// RunID and RunAttempt identify the workflow run. Optional.
+	RunID      string
+	RunAttempt string
+	// WorkflowRef is the ref of the workflow, e.g.,
+	// `project-oak/oak/.github/workflows/build.yml@refs/heads/main`. Optional.
+	WorkflowRef string
+	// EventName is the name of the event that triggered the workflow run,
+	// e.g., `push`. Optional.
+	EventName string
+	// RunnerOS and RunnerArch describe the runner of the job, e.g., `Linux`
+	// and `X64`. Optional.
+	RunnerOS   string
+	RunnerArch string
+}
+
+// GitHubContextFromEnv returns the context of the current workflow run, from
+// the default environment variables of GitHub Actions.
+func GitHubContextFromEnv() (*GitHubContext, error) {
+	github := &GitHubContext{
+		ServerURL:   os.Getenv("GITHUB_SERVER_URL"),
+		Repository:  os.Getenv("GITHUB_REPOSITORY"),
+		SHA:         os.Getenv("GITHUB_SHA"),
+		Ref:         os.Getenv("GITHUB_REF"),
+		RunID:       os.Getenv("GITHUB_RUN_ID"),
+		RunAttempt:  os.Getenv("GITHUB_RUN_ATTEMPT"),
+		WorkflowRef: os.Getenv("GITHUB_WORKFLOW_REF"),
+		EventName:   os.Getenv("GITHUB_EVENT_NAME"),
+		RunnerOS:    os.Getenv("RUNNER_OS"),
+		RunnerArch:  os.Getenv("RUNNER_ARCH"),
+	}
+	if github.ServerURL == "" {
+		github.ServerURL = "https://github.com"
+	}
+	if github.Repository == "" {
+		return nil, fmt.Errorf("GITHUB_REPOSITORY is not set")
+	}
+	if github.SHA == "" {
+		return nil, fmt.Errorf("GITHUB_SHA is not set")
+	}
+	return github, nil
+}
+
+// PredicateConfig holds optional settings for generating predicates.
+type PredicateConfig struct {
+	builderID         string
+	tree              string
+	toolchainVersions map[string]string
+}
+
+// WithBuilderID sets the builder ID of the generated predicate, instead of
+// the URI of the workflow that runs the build.
+func WithBuilderID(builderID string) func(c *PredicateConfig) {
+	return func(c *PredicateConfig) {
+		c.builderID = builderID
+	}
+}
+
+// WithTree records the given hex-encoded digest of the Git tree of the source
+// commit, e.g., as returned by rebuild.GitCLI.Tree, in the digests of the
+// source of the generated predicate, with the key slsav1.GitTreeDigestKey.
+func WithTree(tree string) func(c *PredicateConfig) {
+	return func(c *PredicateConfig) {
+		c.tree = tree
+	}
+}
+
+// WithToolchainVersions records the given versions of the toolchains in the
+// builder image, keyed by the names of the toolchains, e.g., as returned by
+// toolchain.QueryVersions, as resolved dependencies of the generated
+// predicate.
+func WithToolchainVersions(versions map[string]string) func(c *PredicateConfig) {
+	return func(c *PredicateConfig) {
+		c.toolchainVersions = versions
+	}
+}
+
+// GeneratePredicate returns an unsigned SLSA v1 predicate for building the
+// given config, loaded from configPath relative to the root of the
+// repository, in the given builder image, pinned by the given digest, either
+// hex-encoded or prefixed with `sha256:`. The source and the config path
+// identify the config, as the config source of SLSA v0.2 did. The environment
+// of the runner is recorded in the internal parameters. The predicate has no
+// build times; these are set by Build.
+func GeneratePredicate(configPath string, config *slsav1.BuildConfig, image, imageDigest string, github *GitHubContext, options ...func(c *PredicateConfig)) (*slsav1.ProvenancePredicate, error) {
+	predicateConfig := &PredicateConfig{}
+	for _, option := range options {
+		option(predicateConfig)
+	}
+	imageDigest = strings.TrimPrefix(imageDigest, "sha256:")
+	if digest, err := hex.DecodeString(imageDigest); err != nil || len(digest) != 32 {
+		return nil, fmt.Errorf("the image digest %q is not a hex-encoded SHA256 digest", imageDigest)
+	}
+	// The image is always pinned by its digest.
+	if i := strings.Index(image, "@"); i >= 0 {
+		image = image[:i]
+	}
+	if image == "" {
+		return nil, fmt.Errorf("no builder image")
+	}
+
+	sourceDigests := intoto.DigestSet{"sha1": github.SHA}
+	if predicateConfig.tree != "" {
+		if digest, err := hex.DecodeString(predicateConfig.tree); err != nil || (len(digest) != 20 && len(digest) != 32) {
+			return nil, fmt.Errorf("the tree %q is not a hex-encoded SHA1 or SHA256 digest", predicateConfig.tree)
+		}
+		sourceDigests[slsav1.GitTreeDigestKey] = predicateConfig.tree
+	}
+
+	sourceURI := "git+" + github.ServerURL + "/" + github.Repository
+	if github.Ref != "" {
+		sourceURI += "@" + github.Ref
+	}
+	predicate := &slsav1.ProvenancePredicate{
+		BuildDefinition: slsav1.ProvenanceBuildDefinition{
+			BuildType: slsav1.DockerBasedBuildType,
+			ExternalParameters: slsav1.DockerBasedExternalParameters{
+				Source: slsav1.ResourceDescriptor{
+					URI:    sourceURI,
+					Digest: sourceDigests,
+				},
+				BuilderImage: slsav1.ResourceDescriptor{
+					URI:    image + "@sha256:" + imageDigest,
+					Digest: intoto.DigestSet{"sha256": imageDigest},
+				},
+				ConfigPath: configPath,
+				Config:     *config,
+			},
+		},
+	}
+	if len(predicateConfig.toolchainVersions) > 0 {
+		predicate.BuildDefinition.ResolvedDependencies = toolchain.ResolvedDependencies(predicateConfig.toolchainVersions)
+	}
+	if environment := github.environment(); len(environment) > 0 {
+		predicate.BuildDefinition.InternalParameters = map[string]interface{}{"environment": environment}
+	}
+	switch {
+	case predicateConfig.builderID != "":
+		predicate.RunDetails.Builder.ID = predicateConfig.builderID
+	case github.WorkflowRef != "":
+		predicate.RunDetails.Builder.ID = github.ServerURL + "/" + github.WorkflowRef
+	}
+	if github.RunID != "" {
+		attempt := github.RunAttempt
+		if attempt == "" {
+			attempt = "1"
+		}
+		predicate.RunDetails.BuildMetadata.InvocationID = fmt.Sprintf("%s/%s/actions/runs/%s/attempts/%s", github.ServerURL, github.Repository, github.RunID, attempt)
+	}
+	return predicate, nil
+}
+
+// environment returns the set variables that describe the environment of the
+// workflow run, keyed by their names in GitHub Actions.
+func (github *GitHubContext) environment() map[string]interface{} {
+	environment := make(map[string]interface{})
+	for name, value := range map[string]string{
+		"GITHUB_EVENT_NAME": github.EventName,
+		"RUNNER_OS":         github.RunnerOS,
+		"RUNNER_ARCH":       github.RunnerArch,
+	} {
+		if value != "" {
+			environment[name] = value
+		}
+	}
+	return environment
+}
+
+// ParsePredicateFile parses the JSON predicate at the given path, as written
+// by GeneratePredicate.
+func ParsePredicateFile(path string) (*slsav1.ProvenancePredicate, error) {
+	predicateBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read the predicate: %v", err)
+	}
+	var predicate interface{}
+	if err := json.Unmarshal(predicateBytes, &predicate); err != nil {
+		return nil, fmt.Errorf("could not unmarshal the predicate: %v", err)
+	}
+	return slsav1.ParseContainerBasedSLSAv1Provenance(predicate)
+}
+
+// Build runs the build described in the given predicate in the given checkout
+// of the source, and returns an unsigned SLSA v1 provenance, with the built
+// artifacts as the subjects, and the start and finish times of the build
+// according to the given clock. There are several subjects if the artifact
+// path is a pattern that matches several artifacts. If the build log is
+// stored, with rebuild.WithLogsDir, it is recorded as a byproduct of the
+// build. The predicate is not modified.
+func Build(ctx context.Context, predicate *slsav1.ProvenancePredicate, sourceDir string, clock claims.Clock, options ...func(c *rebuild.RunConfig)) (*intoto.Statement, error) {
+	build, err := rebuild.FromPredicate(predicate)
+	if err != nil {
+		return nil, err
+	}
+
+	startedOn := clock.Now()
+	artifacts, err := build.RunInSourceAll(ctx, sourceDir, options...)
+	if err != nil {
+		return nil, err
+	}
+	finishedOn := clock.Now()
+	subjects, err := artifactSubjects(artifacts)
+	if err != nil {
+		return nil, err
+	}
+
+	provenance := *predicate
+	provenance.RunDetails.BuildMetadata.StartedOn = &startedOn
+	provenance.RunDetails.BuildMetadata.FinishedOn = &finishedOn
+
+	config := &rebuild.RunConfig{}
+	for _, option := range options {
+		option(config)
+	}
+	if logsDir := config.LogsDir(); logsDir != "" {
+		buildLog, err := rebuild.ReadBuildLog(logsDir)
+		if err != nil {
+			return nil, err
+		}
+		// Copy the byproducts, so that the predicate is not modified.
+		byproducts := make([]slsav1.ResourceDescriptor, 0, len(predicate.RunDetails.Byproducts)+1)
+		byproducts = append(byproducts, predicate.RunDetails.Byproducts...)
+		provenance.RunDetails.Byproducts = append(byproducts, slsav1.ResourceDescriptor{
+			Name:      rebuild.BuildLogName,
+			Digest:    intoto.DigestSet{"sha256": buildLog.SHA256Digest},
+			MediaType: "text/plain",
+		})
+	}
+	return &intoto.Statement{
+		StatementHeader: intoto.StatementHeader{
+			Type:          intoto.StatementInTotoV01,
+			PredicateType: slsav1.PredicateSLSAProvenance,
+			Subject:       subjects,
+		},
+		Predicate: provenance,
+	}, nil
+}
+
+// artifactSubjects returns the subjects for the given artifacts, keyed by
+// their paths, sorted by their paths, and named by their file names, which
+// must be distinct.
+func artifactSubjects(artifacts map[string]string) ([]intoto.Subject, error) {
+	paths := make([]string, 0, len(artifacts))
+	for artifactPath := range artifacts {
+		paths = append(paths, artifactPath)
+	}
+	sort.Strings(paths)
+	subjects := make([]intoto.Subject, 0, len(paths))
+	names := make(map[string]string, len(paths))
+	for _, artifactPath := range paths {
+		name := path.Base(artifactPath)
+		if other, ok := names[name]; ok {
+			return nil, fmt.Errorf("the artifacts %s and %s have the same name", other, artifactPath)
+		}
+		names[name] = artifactPath
+		subjects = append(subjects, intoto.Subject{
+			Name:   name,
+			Digest: intoto.DigestSet{"sha256": artifacts[artifactPath]},
+		})
+	}
+	return subjects, nil
+}
+
+// BuildAll runs the builds described in the given predicates in the given
+// checkout of the source, as Build, one after the other, since builds write to
+// the checkout, and returns their provenances, in the order of the predicates.
+// If the build logs are stored, the log of the i-th build is stored in the
+// subdirectory `i` of the logs directory. Returns the errors of all failed
+// builds.
+func BuildAll(ctx context.Context, predicates []*slsav1.ProvenancePredicate, sourceDir string, clock claims.Clock, options ...func(c *rebuild.RunConfig)) ([]*intoto.Statement, error) {
+	config := &rebuild.RunConfig{}
+	for _, option := range options {
+		option(config)
+	}
+
+	statements := make([]*intoto.Statement, len(predicates))
+	var err error
+	for i, predicate := range predicates {
+		buildOptions := options
+		if logsDir := config.LogsDir(); logsDir != "" && len(predicates) > 1 {
+			buildOptions = append(options[:len(options):len(options)], rebuild.WithLogsDir(filepath.Join(logsDir, strconv.Itoa(i))))
+		}
+		var buildErr error
+		statements[i], buildErr = Build(ctx, predicate, sourceDir, clock, buildOptions...)
+		if buildErr != nil {
+			err = multierr.Append(err, fmt.Errorf("build #%d: %v", i, buildErr))
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return statements, nil
+}
+
+// SplitProvenance returns a provenance for each subject of the given
+// provenance, with the same predicate.
+func SplitProvenance(statement *intoto.Statement) []*intoto.Statement {
+	statements := make([]*intoto.Statement, 0, len(statement.Subject))
+	for _, subject := range statement.Subject {
+		split := *statement
+		split.Subject = []intoto.Subject{subject}
+		statements = append(statements, &split)
+	}
+	return statements
+}
@@ -0,0 +1,401 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package builder
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/project-oak/transparent-release/internal/model"
+	"github.com/project-oak/transparent-release/internal/rebuild"
+	"github.com/project-oak/transparent-release/internal/testutil"
+	"github.com/project-oak/transparent-release/pkg/claims"
+	"github.com/project-oak/transparent-release/pkg/intoto"
+	slsav1 "github.com/project-oak/transparent-release/pkg/intoto/slsa_provenance/v1"
+)
+
+const (
+	imageDigest = "51532c757d1008bbff696d053a1d05226f6387cf232aa80b6f9c13b0759ccea0"
+	commit      = "6bac02b6b0442ed944f57b7cba9a5f1119863ca4"
+	buildConfig = `command = ["cargo", "build", "--release"]
+artifact_path = "target/release/hello"
+`
+)
+
+// fakeRunner writes the given artifact to the artifact path of the test
+// build config, instead of building it.
+type fakeRunner struct {
+	artifact string
+	images   []string
+}
+
+func (r *fakeRunner) Checkout(_ context.Context, _, _, _ string) (*rebuild.CheckoutMetrics, error) {
+	return nil, nil
+}
+
+func (r *fakeRunner) Run(_ context.Context, build *rebuild.Build, dir string, output io.Writer) error {
+	r.images = append(r.images, build.Image)
+	fmt.Fprintf(output, "Building in %s\n", build.Image)
+	path := filepath.Join(dir, "target/release/hello")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(r.artifact), 0o600)
+}
+
+func testGitHubContext() *GitHubContext {
+	return &GitHubContext{
+		ServerURL:   "https://github.com",
+		Repository:  "project-oak/oak",
+		SHA:         commit,
+		Ref:         "refs/heads/main",
+		RunID:       "4755980100",
+		WorkflowRef: "project-oak/oak/.github/workflows/build.yml@refs/heads/main",
+		EventName:   "push",
+		RunnerOS:    "Linux",
+	}
+}
+
+func generateTestPredicate(t *testing.T) *slsav1.ProvenancePredicate {
+	configPath := filepath.Join(t.TempDir(), "hello.toml")
+	if err := os.WriteFile(configPath, []byte(buildConfig), 0o600); err != nil {
+		t.Fatalf("couldn't write the build config: %v", err)
+	}
+	config, err := LoadBuildConfig(configPath)
+	if err != nil {
+		t.Fatalf("couldn't load the build config: %v", err)
+	}
+	predicate, err := GeneratePredicate("buildconfigs/hello.toml", config, "europe-west2-docker.pkg.dev/oak-ci/oak-development:latest", "sha256:"+imageDigest, testGitHubContext())
+	if err != nil {
+		t.Fatalf("couldn't generate the predicate: %v", err)
+	}
+	return predicate
+}
+
+func TestGeneratePredicate(t *testing.T) {
+	predicate := generateTestPredicate(t)
+
+	parameters := predicate.BuildDefinition.ExternalParameters.(slsav1.DockerBasedExternalParameters)
+	testutil.AssertEq(t, "source URI", parameters.Source.URI, "git+https://github.com/project-oak/oak@refs/heads/main")
+	testutil.AssertEq(t, "commit", parameters.Source.Digest["sha1"], commit)
+	testutil.AssertEq(t, "image URI", parameters.BuilderImage.URI, "europe-west2-docker.pkg.dev/oak-ci/oak-development:latest@sha256:"+imageDigest)
+	testutil.AssertEq(t, "config path", parameters.ConfigPath, "buildconfigs/hello.toml")
+	testutil.AssertEq(t, "builder ID", predicate.BuilderID(), "https://github.com/project-oak/oak/.github/workflows/build.yml@refs/heads/main")
+	testutil.AssertEq(t, "invocation ID", predicate.RunDetails.BuildMetadata.InvocationID, "https://github.com/project-oak/oak/actions/runs/4755980100/attempts/1")
+	wantInternalParameters := map[string]interface{}{
+		"environment": map[string]interface{}{"GITHUB_EVENT_NAME": "push", "RUNNER_OS": "Linux"},
+	}
+	if diff := cmp.Diff(wantInternalParameters, predicate.BuildDefinition.InternalParameters); diff != "" {
+		t.Errorf("unexpected internal parameters (-want +got):\n%s", diff)
+	}
+}
+
+func TestGeneratePredicate_WithBuilderID(t *testing.T) {
+	config := &slsav1.BuildConfig{Command: []string{"true"}, ArtifactPath: "out"}
+	github := &GitHubContext{ServerURL: "https://github.com", Repository: "project-oak/oak", SHA: commit}
+	predicate, err := GeneratePredicate("", config, "image", imageDigest, github, WithBuilderID("https://example.com/builder"))
+	if err != nil {
+		t.Fatalf("couldn't generate the predicate: %v", err)
+	}
+	testutil.AssertEq(t, "builder ID", predicate.BuilderID(), "https://example.com/builder")
+	if predicate.BuildDefinition.InternalParameters != nil {
+		t.Errorf("unexpected internal parameters without a runner environment: %v", predicate.BuildDefinition.InternalParameters)
+	}
+}
+
+func TestGeneratePredicate_WithTree(t *testing.T) {
+	config := &slsav1.BuildConfig{Command: []string{"true"}, ArtifactPath: "out"}
+	tree := "4b825dc642cb6eb9a060e54bf8d69288fbee4904"
+	predicate, err := GeneratePredicate("", config, "image", imageDigest, testGitHubContext(), WithTree(tree))
+	if err != nil {
+		t.Fatalf("couldn't generate the predicate: %v", err)
+	}
+	if diff := cmp.Diff(predicate.TreeDigests(), intoto.DigestSet{"sha1": tree}); diff != "" {
+		t.Errorf("unexpected tree digests: %s", diff)
+	}
+	if diff := cmp.Diff(predicate.CommitDigests(), intoto.DigestSet{"sha1": commit}); diff != "" {
+		t.Errorf("unexpected commit digests: %s", diff)
+	}
+
+	if _, err := GeneratePredicate("", config, "image", imageDigest, testGitHubContext(), WithTree("1234")); err == nil {
+		t.Fatalf("expected an error for an invalid tree")
+	}
+}
+
+func TestGeneratePredicate_WithToolchainVersions(t *testing.T) {
+	config := &slsav1.BuildConfig{Command: []string{"true"}, ArtifactPath: "out"}
+	versions := map[string]string{"cargo": "1.70.0", "rustc": "1.70.0"}
+	predicate, err := GeneratePredicate("", config, "image", imageDigest, testGitHubContext(), WithToolchainVersions(versions))
+	if err != nil {
+		t.Fatalf("couldn't generate the predicate: %v", err)
+	}
+
+	// The versions are kept when the predicate is written and parsed again.
+	predicatePath := filepath.Join(t.TempDir(), "predicate.json")
+	predicateBytes, err := json.Marshal(predicate)
+	if err != nil {
+		t.Fatalf("couldn't marshal the predicate: %v", err)
+	}
+	if err := os.WriteFile(predicatePath, predicateBytes, 0o600); err != nil {
+		t.Fatalf("couldn't write the predicate: %v", err)
+	}
+	parsed, err := ParsePredicateFile(predicatePath)
+	if err != nil {
+		t.Fatalf("couldn't parse the predicate: %v", err)
+	}
+	if diff := cmp.Diff(versions, parsed.ToolchainVersions()); diff != "" {
+		t.Errorf("unexpected toolchain versions (-want +got):\n%s", diff)
+	}
+}
+
+func TestGeneratePredicate_InvalidImageDigestFails(t *testing.T) {
+	config := &slsav1.BuildConfig{Command: []string{"true"}, ArtifactPath: "out"}
+	if _, err := GeneratePredicate("", config, "image", "sha256:1234", testGitHubContext()); err == nil {
+		t.Fatalf("expected an error for an invalid image digest")
+	}
+}
+
+func TestBuild(t *testing.T) {
+	predicatePath := filepath.Join(t.TempDir(), "predicate.json")
+	predicateBytes, err := json.Marshal(generateTestPredicate(t))
+	if err != nil {
+		t.Fatalf("couldn't marshal the predicate: %v", err)
+	}
+	if err := os.WriteFile(predicatePath, predicateBytes, 0o600); err != nil {
+		t.Fatalf("couldn't write the predicate: %v", err)
+	}
+	predicate, err := ParsePredicateFile(predicatePath)
+	if err != nil {
+		t.Fatalf("couldn't parse the predicate: %v", err)
+	}
+	runner := &fakeRunner{artifact: "hello"}
+	clock := claims.FixedClock(time.Date(2023, 7, 1, 12, 0, 0, 0, time.UTC))
+
+	statement, err := Build(context.Background(), predicate, t.TempDir(), clock, rebuild.WithRunner(runner))
+	if err != nil {
+		t.Fatalf("couldn't run the build: %v", err)
+	}
+
+	sum256 := sha256.Sum256([]byte("hello"))
+	testutil.AssertEq(t, "subject name", statement.Subject[0].Name, "hello")
+	testutil.AssertEq(t, "subject digest", statement.Subject[0].Digest["sha256"], hex.EncodeToString(sum256[:]))
+	testutil.AssertEq(t, "builder image", runner.images[0], "europe-west2-docker.pkg.dev/oak-ci/oak-development:latest@sha256:"+imageDigest)
+
+	// The provenance can be verified, and rebuilt.
+	statementBytes, err := json.Marshal(statement)
+	if err != nil {
+		t.Fatalf("couldn't marshal the provenance: %v", err)
+	}
+	validatedProvenance, err := model.ParseStatementData(statementBytes)
+	if err != nil {
+		t.Fatalf("couldn't parse the provenance: %v", err)
+	}
+	provenance, err := model.FromValidatedProvenance(validatedProvenance)
+	if err != nil {
+		t.Fatalf("couldn't map the provenance: %v", err)
+	}
+	finishedOn, err := provenance.BuildFinishedOn()
+	if err != nil {
+		t.Fatalf("the provenance has no build finish time: %v", err)
+	}
+	testutil.AssertEq(t, "finished on", finishedOn, clock.Now())
+	if err := rebuild.Verify(context.Background(), validatedProvenance, rebuild.WithRunner(runner)); err != nil {
+		t.Errorf("couldn't rebuild the provenance: %v", err)
+	}
+}
+
+func TestBuild_WithLogsDir(t *testing.T) {
+	predicate := generateTestPredicate(t)
+	runner := &fakeRunner{artifact: "hello"}
+	clock := claims.FixedClock(time.Date(2023, 7, 1, 12, 0, 0, 0, time.UTC))
+	logsDir := t.TempDir()
+
+	statement, err := Build(context.Background(), predicate, t.TempDir(), clock, rebuild.WithRunner(runner), rebuild.WithLogsDir(logsDir))
+	if err != nil {
+		t.Fatalf("couldn't run the build: %v", err)
+	}
+
+	logBytes, err := os.ReadFile(filepath.Join(logsDir, rebuild.BuildLogName))
+	if err != nil {
+		t.Fatalf("couldn't read the build log: %v", err)
+	}
+	sum256 := sha256.Sum256(logBytes)
+	want := []slsav1.ResourceDescriptor{{
+		Name:      "build.log",
+		Digest:    map[string]string{"sha256": hex.EncodeToString(sum256[:])},
+		MediaType: "text/plain",
+	}}
+	if diff := cmp.Diff(want, statement.Predicate.(slsav1.ProvenancePredicate).RunDetails.Byproducts); diff != "" {
+		t.Errorf("unexpected byproducts (-want +got):\n%s", diff)
+	}
+	if len(predicate.RunDetails.Byproducts) != 0 {
+		t.Errorf("the predicate has been modified: %v", predicate.RunDetails.Byproducts)
+	}
+}
+
+// filesRunner writes the files given for the command of each build, and
+// records the maximum number of concurrent builds.
+type filesRunner struct {
+	files map[string]map[string]string
+
+	mu         sync.Mutex
+	running    int
+	maxRunning int
+}
+
+func (r *filesRunner) Checkout(_ context.Context, _, _, _ string) (*rebuild.CheckoutMetrics, error) {
+	return nil, nil
+}
+
+func (r *filesRunner) Run(_ context.Context, build *rebuild.Build, dir string, _ io.Writer) error {
+	r.mu.Lock()
+	r.running++
+	if r.running > r.maxRunning {
+		r.maxRunning = r.running
+	}
+	r.mu.Unlock()
+	defer func() {
+		r.mu.Lock()
+		r.running--
+		r.mu.Unlock()
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	for name, content := range r.files[strings.Join(build.Command, " ")] {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func generatePredicates(t *testing.T, config string) []*slsav1.ProvenancePredicate {
+	configPath := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(configPath, []byte(config), 0o600); err != nil {
+		t.Fatalf("couldn't write the build config: %v", err)
+	}
+	configs, err := LoadBuildConfigs(configPath)
+	if err != nil {
+		t.Fatalf("couldn't load the build configs: %v", err)
+	}
+	var predicates []*slsav1.ProvenancePredicate
+	for _, config := range configs {
+		predicate, err := GeneratePredicate("buildconfigs/config.toml", config, "europe-west2-docker.pkg.dev/oak-ci/oak-development:latest", "sha256:"+imageDigest, testGitHubContext())
+		if err != nil {
+			t.Fatalf("couldn't generate the predicate: %v", err)
+		}
+		predicates = append(predicates, predicate)
+	}
+	return predicates
+}
+
+func subjectNames(statement *intoto.Statement) []string {
+	var names []string
+	for _, subject := range statement.Subject {
+		names = append(names, subject.Name)
+	}
+	return names
+}
+
+func TestBuild_ArtifactPattern(t *testing.T) {
+	predicates := generatePredicates(t, "command = [\"make\"]\nartifact_path = \"out/*.so\"\n")
+	runner := &filesRunner{files: map[string]map[string]string{
+		"make": {"out/b.so": "b", "out/a.so": "a", "out/c.a": "c", "out/d.so/e": "e"},
+	}}
+	clock := claims.FixedClock(time.Date(2023, 7, 1, 12, 0, 0, 0, time.UTC))
+
+	statement, err := Build(context.Background(), predicates[0], t.TempDir(), clock, rebuild.WithRunner(runner))
+	if err != nil {
+		t.Fatalf("couldn't run the build: %v", err)
+	}
+	if diff := cmp.Diff([]string{"a.so", "b.so"}, subjectNames(statement)); diff != "" {
+		t.Errorf("unexpected subjects (-want +got):\n%s", diff)
+	}
+
+	split := SplitProvenance(statement)
+	if len(split) != 2 {
+		t.Fatalf("expected a provenance per subject, got %d", len(split))
+	}
+	for i, provenance := range split {
+		if diff := cmp.Diff([]intoto.Subject{statement.Subject[i]}, provenance.Subject); diff != "" {
+			t.Errorf("unexpected subject of provenance #%d (-want +got):\n%s", i, diff)
+		}
+		if diff := cmp.Diff(statement.Predicate, provenance.Predicate); diff != "" {
+			t.Errorf("unexpected predicate of provenance #%d (-want +got):\n%s", i, diff)
+		}
+	}
+}
+
+func TestBuild_ArtifactPatternDuplicateNamesFails(t *testing.T) {
+	predicates := generatePredicates(t, "command = [\"make\"]\nartifact_path = \"out/*/hello\"\n")
+	runner := &filesRunner{files: map[string]map[string]string{
+		"make": {"out/a/hello": "a", "out/b/hello": "b"},
+	}}
+	if _, err := Build(context.Background(), predicates[0], t.TempDir(), claims.SystemClock(), rebuild.WithRunner(runner)); err == nil || !strings.Contains(err.Error(), "have the same name") {
+		t.Errorf("expected artifacts with the same name, got %v", err)
+	}
+}
+
+func TestBuildAll(t *testing.T) {
+	config := "[[outputs]]\ncommand = [\"make\", \"a\"]\nartifact_path = \"out/a\"\n" +
+		"[[outputs]]\ncommand = [\"make\", \"b\"]\nartifact_path = \"out/b\"\n" +
+		"[[outputs]]\ncommand = [\"make\", \"c\"]\nartifact_path = \"out/c\"\n"
+	predicates := generatePredicates(t, config)
+	runner := &filesRunner{files: map[string]map[string]string{
+		"make a": {"out/a": "a"},
+		"make b": {"out/b": "b"},
+		"make c": {"out/c": "c"},
+	}}
+	logsDir := t.TempDir()
+
+	statements, err := BuildAll(context.Background(), predicates, t.TempDir(), claims.SystemClock(), rebuild.WithRunner(runner), rebuild.WithLogsDir(logsDir))
+	if err != nil {
+		t.Fatalf("couldn't run the builds: %v", err)
+	}
+	for i, want := range []string{"a", "b", "c"} {
+		if diff := cmp.Diff([]string{want}, subjectNames(statements[i])); diff != "" {
+			t.Errorf("unexpected subjects of build #%d (-want +got):\n%s", i, diff)
+		}
+		if _, err := rebuild.ReadBuildLog(filepath.Join(logsDir, fmt.Sprint(i))); err != nil {
+			t.Errorf("no build log of build #%d: %v", i, err)
+		}
+	}
+	// The builds share the checkout, so they must not run concurrently.
+	testutil.AssertEq(t, "concurrent builds", runner.maxRunning, 1)
+
+	// The errors of all failed builds are returned.
+	delete(runner.files, "make a")
+	delete(runner.files, "make c")
+	_, err = BuildAll(context.Background(), predicates, t.TempDir(), claims.SystemClock(), rebuild.WithRunner(runner))
+	if err == nil || !strings.Contains(err.Error(), "build #0") || !strings.Contains(err.Error(), "build #2") {
+		t.Errorf("expected builds #0 and #2 to fail, got %v", err)
+	}
+}
@@ -0,0 +1,298 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package builder
+
+// This file parses build configs, in TOML or JSON, with the same field names
+// in both formats:
+//
+//	command = ["cargo", "build", "--release"]
+//	artifact_path = "target/release/hello"
+//
+//	[env]
+//	CARGO_INCREMENTAL = "0"
+//
+//	[options]
+//	offline = true
+//	platform = "linux/amd64"
+//
+// The artifact path may be a pattern, e.g., `target/release/*.so`, for builds
+// with several artifacts. Instead of a command and an artifact path, a config
+// may list several outputs, built from the same source with the same
+// environment and options, but with their own commands, e.g., to build several
+// binaries concurrently:
+//
+//	[[outputs]]
+//	command = ["cargo", "build", "--release", "--package=hello"]
+//	artifact_path = "target/release/hello"
+//
+//	[[outputs]]
+//	command = ["cargo", "build", "--release", "--package=world"]
+//	artifact_path = "target/release/world"
+//
+// Syntax and type errors point at the offending line, and validation errors
+// at the offending field.
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+
+	slsav1 "github.com/project-oak/transparent-release/pkg/intoto/slsa_provenance/v1"
+)
+
+//nolint:gochecknoglobals
+var (
+	envNamePattern   = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	platformPattern  = regexp.MustCompile(`^[a-z0-9]+/[a-z0-9_]+(/[a-z0-9]+)?$`)
+	tomlErrorPattern = regexp.MustCompile(`^toml: line (\d+) \(last key "([^"]*)"\): (.*)$`)
+)
+
+// configFile is the format of build config files.
+type configFile struct {
+	Command      []string          `toml:"command" json:"command"`
+	ArtifactPath string            `toml:"artifact_path" json:"artifact_path"`
+	Env          map[string]string `toml:"env" json:"env"`
+	Options      *optionsFile      `toml:"options" json:"options"`
+	Outputs      []outputFile      `toml:"outputs" json:"outputs"`
+}
+
+type outputFile struct {
+	Command      []string `toml:"command" json:"command"`
+	ArtifactPath string   `toml:"artifact_path" json:"artifact_path"`
+}
+
+type optionsFile struct {
+	Offline  bool   `toml:"offline" json:"offline"`
+	Platform string `toml:"platform" json:"platform"`
+}
+
+// LoadBuildConfig loads the build config at the given path, as JSON if it has
+// a `.json` extension, or as TOML otherwise. Fails if the config lists several
+// outputs; see LoadBuildConfigs.
+func LoadBuildConfig(path string) (*slsav1.BuildConfig, error) {
+	file, err := loadConfigFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config, err := file.validate()
+	if err != nil {
+		return nil, fmt.Errorf("invalid build config %s: %v", path, err)
+	}
+	return config, nil
+}
+
+// LoadBuildConfigs loads the build config at the given path, as
+// LoadBuildConfig, and returns a build config for each of its outputs, or the
+// build config itself if it does not list outputs.
+func LoadBuildConfigs(path string) ([]*slsav1.BuildConfig, error) {
+	file, err := loadConfigFile(path)
+	if err != nil {
+		return nil, err
+	}
+	configs, err := file.validateOutputs()
+	if err != nil {
+		return nil, fmt.Errorf("invalid build config %s: %v", path, err)
+	}
+	return configs, nil
+}
+
+func loadConfigFile(path string) (*configFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read the build config: %v", err)
+	}
+	var file *configFile
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		file, err = parseConfigFileJSON(data)
+	} else {
+		file, err = parseConfigFileTOML(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid build config %s: %v", path, err)
+	}
+	return file, nil
+}
+
+// ParseBuildConfigTOML parses and validates a build config in TOML.
+func ParseBuildConfigTOML(data []byte) (*slsav1.BuildConfig, error) {
+	file, err := parseConfigFileTOML(data)
+	if err != nil {
+		return nil, err
+	}
+	return file.validate()
+}
+
+// ParseBuildConfigJSON parses and validates a build config in JSON.
+func ParseBuildConfigJSON(data []byte) (*slsav1.BuildConfig, error) {
+	file, err := parseConfigFileJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	return file.validate()
+}
+
+func parseConfigFileTOML(data []byte) (*configFile, error) {
+	var file configFile
+	metadata, err := toml.Decode(string(data), &file)
+	if err != nil {
+		var parseErr toml.ParseError
+		if errors.As(err, &parseErr) {
+			return nil, fmt.Errorf("line %d: %s", parseErr.Position.Line, parseErrMessage(parseErr))
+		}
+		// Type errors are reported as `toml: line N (last key "K"): message`.
+		if match := tomlErrorPattern.FindStringSubmatch(err.Error()); match != nil {
+			return nil, fmt.Errorf("line %s: %s: %s", match[1], match[2], match[3])
+		}
+		return nil, err
+	}
+	if undecoded := metadata.Undecoded(); len(undecoded) != 0 {
+		return nil, fmt.Errorf("%s: unknown field", undecoded[0])
+	}
+	return &file, nil
+}
+
+func parseConfigFileJSON(data []byte) (*configFile, error) {
+	var file configFile
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&file); err != nil {
+		var syntaxErr *json.SyntaxError
+		var typeErr *json.UnmarshalTypeError
+		switch {
+		case errors.As(err, &syntaxErr):
+			line, column := position(data, syntaxErr.Offset)
+			return nil, fmt.Errorf("line %d, column %d: %v", line, column, err)
+		case errors.As(err, &typeErr):
+			line, column := position(data, typeErr.Offset)
+			return nil, fmt.Errorf("line %d, column %d: %s: got %s, want %s", line, column, typeErr.Field, typeErr.Value, typeErr.Type)
+		}
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, fmt.Errorf("unexpected data after the build config")
+	}
+	return &file, nil
+}
+
+// validateOutputs returns a build config for each output in the given file,
+// or the build config in the file if it has no outputs, or an error naming the
+// first invalid field.
+func (f *configFile) validateOutputs() ([]*slsav1.BuildConfig, error) {
+	if len(f.Outputs) == 0 {
+		config, err := f.validate()
+		if err != nil {
+			return nil, err
+		}
+		return []*slsav1.BuildConfig{config}, nil
+	}
+	if len(f.Command) != 0 || f.ArtifactPath != "" {
+		return nil, fmt.Errorf("outputs: must not be combined with command and artifact_path")
+	}
+	configs := make([]*slsav1.BuildConfig, 0, len(f.Outputs))
+	for i, output := range f.Outputs {
+		outputFile := configFile{Command: output.Command, ArtifactPath: output.ArtifactPath, Env: f.Env, Options: f.Options}
+		config, err := outputFile.validate()
+		if err != nil {
+			return nil, fmt.Errorf("outputs[%d].%v", i, err)
+		}
+		configs = append(configs, config)
+	}
+	return configs, nil
+}
+
+// validate returns the build config in the given file, or an error naming the
+// first invalid field.
+func (f *configFile) validate() (*slsav1.BuildConfig, error) {
+	if len(f.Outputs) != 0 {
+		return nil, fmt.Errorf("outputs: the config lists %d outputs, but a single build was expected", len(f.Outputs))
+	}
+	if len(f.Command) == 0 {
+		return nil, fmt.Errorf("command: must not be empty")
+	}
+	if f.Command[0] == "" {
+		return nil, fmt.Errorf("command[0]: the executable must not be empty")
+	}
+	if f.ArtifactPath == "" {
+		return nil, fmt.Errorf("artifact_path: must not be empty")
+	}
+	if p := path.Clean(f.ArtifactPath); path.IsAbs(p) || p == "." || p == ".." || strings.HasPrefix(p, "../") {
+		return nil, fmt.Errorf("artifact_path: %q is not a path within the repository", f.ArtifactPath)
+	}
+	if _, err := path.Match(f.ArtifactPath, ""); err != nil {
+		return nil, fmt.Errorf("artifact_path: %q is not a valid pattern", f.ArtifactPath)
+	}
+	for name := range f.Env {
+		if !envNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("env.%s: invalid environment variable name", name)
+		}
+	}
+
+	config := &slsav1.BuildConfig{
+		ArtifactPath: f.ArtifactPath,
+		Command:      f.Command,
+	}
+	if len(f.Env) != 0 {
+		config.Env = f.Env
+	}
+	if f.Options != nil {
+		if f.Options.Platform != "" && !platformPattern.MatchString(f.Options.Platform) {
+			return nil, fmt.Errorf("options.platform: %q is not of the form os/arch[/variant]", f.Options.Platform)
+		}
+		if *f.Options != (optionsFile{}) {
+			config.Options = &slsav1.BuildOptions{Offline: f.Options.Offline, Platform: f.Options.Platform}
+		}
+	}
+	return config, nil
+}
+
+// parseErrMessage returns the message of the given TOML error, without its
+// position, which is reported separately.
+func parseErrMessage(err toml.ParseError) string {
+	message := err.Message
+	if message == "" {
+		message = err.Error()
+		if i := strings.Index(message, "): "); i >= 0 {
+			message = message[i+3:]
+		}
+	}
+	if err.LastKey != "" {
+		message = err.LastKey + ": " + message
+	}
+	return message
+}
+
+// position returns the 1-based line and column of the byte before the given
+// offset in the given data, i.e., of the last byte read by the JSON decoder
+// when it failed.
+func position(data []byte, offset int64) (int, int) {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	if offset > 0 {
+		offset--
+	}
+	before := data[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	column := len(before) - bytes.LastIndexByte(before, '\n')
+	return line, column
+}
@@ -0,0 +1,175 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package builder
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	slsav1 "github.com/project-oak/transparent-release/pkg/intoto/slsa_provenance/v1"
+)
+
+const (
+	fullConfigTOML = `command = ["cargo", "build", "--release"]
+artifact_path = "target/release/hello"
+
+[env]
+CARGO_INCREMENTAL = "0"
+
+[options]
+offline = true
+platform = "linux/amd64"
+`
+	fullConfigJSON = `{
+  "command": ["cargo", "build", "--release"],
+  "artifact_path": "target/release/hello",
+  "env": {"CARGO_INCREMENTAL": "0"},
+  "options": {"offline": true, "platform": "linux/amd64"}
+}`
+)
+
+//nolint:gochecknoglobals
+var fullConfig = &slsav1.BuildConfig{
+	Command:      []string{"cargo", "build", "--release"},
+	ArtifactPath: "target/release/hello",
+	Env:          map[string]string{"CARGO_INCREMENTAL": "0"},
+	Options:      &slsav1.BuildOptions{Offline: true, Platform: "linux/amd64"},
+}
+
+func TestLoadBuildConfig(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{"hello.toml": fullConfigTOML, "hello.json": fullConfigJSON} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("couldn't write the build config: %v", err)
+		}
+		got, err := LoadBuildConfig(path)
+		if err != nil {
+			t.Fatalf("couldn't load %s: %v", name, err)
+		}
+		if diff := cmp.Diff(got, fullConfig); diff != "" {
+			t.Errorf("unexpected build config in %s: %s", name, diff)
+		}
+	}
+}
+
+const outputsConfigTOML = `[env]
+CARGO_INCREMENTAL = "0"
+
+[[outputs]]
+command = ["cargo", "build", "--release", "--package=hello"]
+artifact_path = "target/release/hello"
+
+[[outputs]]
+command = ["cargo", "build", "--release", "--package=world"]
+artifact_path = "target/release/world"
+`
+
+func TestLoadBuildConfigs(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{"outputs.toml": outputsConfigTOML, "hello.toml": fullConfigTOML} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatalf("couldn't write the build config: %v", err)
+		}
+	}
+
+	got, err := LoadBuildConfigs(filepath.Join(dir, "outputs.toml"))
+	if err != nil {
+		t.Fatalf("couldn't load the build configs: %v", err)
+	}
+	env := map[string]string{"CARGO_INCREMENTAL": "0"}
+	want := []*slsav1.BuildConfig{
+		{Command: []string{"cargo", "build", "--release", "--package=hello"}, ArtifactPath: "target/release/hello", Env: env},
+		{Command: []string{"cargo", "build", "--release", "--package=world"}, ArtifactPath: "target/release/world", Env: env},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected build configs (-want +got):\n%s", diff)
+	}
+
+	// A config without outputs is a single build config.
+	got, err = LoadBuildConfigs(filepath.Join(dir, "hello.toml"))
+	if err != nil {
+		t.Fatalf("couldn't load the build configs: %v", err)
+	}
+	if diff := cmp.Diff([]*slsav1.BuildConfig{fullConfig}, got); diff != "" {
+		t.Errorf("unexpected build configs (-want +got):\n%s", diff)
+	}
+}
+
+func TestLoadBuildConfigs_Errors(t *testing.T) {
+	tests := map[string]string{
+		"command = [\"make\"]\n[[outputs]]\ncommand = [\"make\"]\nartifact_path = \"out\"":              "outputs: must not be combined with command and artifact_path",
+		"[[outputs]]\ncommand = [\"make\"]\nartifact_path = \"out\"\n[[outputs]]\ncommand = [\"make\"]": "outputs[1].artifact_path: must not be empty",
+		"[[outputs]]\ncommand = [\"make\"]\nartifact_path = \"out\"\nenv = 1":                           "outputs.env: unknown field",
+	}
+	for config, want := range tests {
+		path := filepath.Join(t.TempDir(), "config.toml")
+		if err := os.WriteFile(path, []byte(config), 0o600); err != nil {
+			t.Fatalf("couldn't write the build config: %v", err)
+		}
+		if _, err := LoadBuildConfigs(path); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected an error containing %q for %q, got %v", want, config, err)
+		}
+	}
+}
+
+func TestParseBuildConfigTOML_MinimalConfig(t *testing.T) {
+	got, err := ParseBuildConfigTOML([]byte(buildConfig))
+	if err != nil {
+		t.Fatalf("couldn't parse the build config: %v", err)
+	}
+	want := &slsav1.BuildConfig{Command: []string{"cargo", "build", "--release"}, ArtifactPath: "target/release/hello"}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("unexpected build config: %s", diff)
+	}
+}
+
+func TestParseBuildConfigTOML_Errors(t *testing.T) {
+	tests := map[string]string{
+		"command = [\n  \"make\",\n":                                                     "line 2: command",
+		"command = \"make\"\nartifact_path = \"out\"":                                    "line 1: command: incompatible types",
+		"command = [\"make\"]\nartifact_path = \"out\"\ncmd = 1":                         "cmd: unknown field",
+		"artifact_path = \"out\"":                                                        "command: must not be empty",
+		"command = [\"make\"]":                                                           "artifact_path: must not be empty",
+		"command = [\"make\"]\nartifact_path = \"../out\"":                               "artifact_path: \"../out\" is not a path within the repository",
+		"command = [\"make\"]\nartifact_path = \"out\"\n[env]\n\"A-B\" = \"1\"":          "env.A-B: invalid environment variable name",
+		"command = [\"make\"]\nartifact_path = \"out\"\n[options]\nplatform = \"amd64\"": "options.platform",
+		"command = [\"make\"]\nartifact_path = \"out/[a\"":                               "artifact_path: \"out/[a\" is not a valid pattern",
+		outputsConfigTOML: "outputs: the config lists 2 outputs, but a single build was expected",
+	}
+	for config, want := range tests {
+		if _, err := ParseBuildConfigTOML([]byte(config)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected an error containing %q for %q, got %v", want, config, err)
+		}
+	}
+}
+
+func TestParseBuildConfigJSON_Errors(t *testing.T) {
+	tests := map[string]string{
+		"{\n  \"command\": [\"make\"],\n  \"artifact_path\": 1\n}":          "line 3, column 20: artifact_path: got number, want string",
+		"{\n  \"command\": [\"make\"],\n}":                                  "line 3, column 1",
+		"{\"command\": [\"make\"], \"artifact_path\": \"out\", \"cmd\": 1}": "unknown field \"cmd\"",
+		"{\"command\": [\"\"], \"artifact_path\": \"out\"}":                 "command[0]: the executable must not be empty",
+	}
+	for config, want := range tests {
+		if _, err := ParseBuildConfigJSON([]byte(config)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected an error containing %q for %q, got %v", want, config, err)
+		}
+	}
+}
@@ -0,0 +1,254 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package builder
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"testing"
+	"testing/quick"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/project-oak/transparent-release/internal/model"
+	"github.com/project-oak/transparent-release/internal/rebuild"
+	"github.com/project-oak/transparent-release/pkg/claims"
+	slsav1 "github.com/project-oak/transparent-release/pkg/intoto/slsa_provenance/v1"
+)
+
+// TestPredicateRoundTrip checks that generated predicates are accepted by the
+// parser of SLSA v1 predicates, survive the round trip unchanged, and
+// describe the same build before and after the round trip.
+func TestPredicateRoundTrip(t *testing.T) {
+	property := func(seed int64) bool {
+		r := rand.New(rand.NewSource(seed)) //nolint:gosec
+		predicate, err := randomPredicate(r)
+		if err != nil {
+			t.Logf("Failed to generate the predicate: %v", err)
+			return false
+		}
+		parsed, err := roundTripPredicate(predicate)
+		if err != nil {
+			t.Logf("%v", err)
+			return false
+		}
+		if diff := cmp.Diff(predicate, parsed, cmpopts.EquateEmpty()); diff != "" {
+			t.Logf("The parsed predicate differs from the generated one (-want +got):\n%s", diff)
+			return false
+		}
+
+		want, err := rebuild.FromPredicate(predicate)
+		if err != nil {
+			t.Logf("Failed to get the build of the predicate: %v", err)
+			return false
+		}
+		got, err := rebuild.FromPredicate(parsed)
+		if err != nil {
+			t.Logf("Failed to get the build of the parsed predicate: %v", err)
+			return false
+		}
+		if diff := cmp.Diff(want, got, cmpopts.EquateEmpty()); diff != "" {
+			t.Logf("The builds differ (-want +got):\n%s", diff)
+			return false
+		}
+		return true
+	}
+	if err := quick.Check(property, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestProvenanceRoundTrip checks that the provenances generated by Build are
+// accepted by the parser of provenances, and that the parsed provenance
+// records the build described in the predicate.
+func TestProvenanceRoundTrip(t *testing.T) {
+	property := func(seed int64, artifact string) bool {
+		r := rand.New(rand.NewSource(seed)) //nolint:gosec
+		predicate, err := randomPredicate(r)
+		if err != nil {
+			t.Logf("Failed to generate the predicate: %v", err)
+			return false
+		}
+		startedOn := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(r.Int63n(int64(365 * 24 * time.Hour))))
+		statement, err := Build(context.Background(), predicate, t.TempDir(), claims.FixedClock(startedOn), rebuild.WithRunner(&fakeRunner{artifact: artifact}))
+		if err != nil {
+			t.Logf("Failed to run the build: %v", err)
+			return false
+		}
+		statementBytes, err := json.Marshal(statement)
+		if err != nil {
+			t.Logf("Failed to marshal the provenance: %v", err)
+			return false
+		}
+		validatedProvenance, err := model.ParseStatementData(statementBytes)
+		if err != nil {
+			t.Logf("Failed to parse the generated provenance %s: %v", statementBytes, err)
+			return false
+		}
+		provenance, err := model.FromValidatedProvenance(validatedProvenance)
+		if err != nil {
+			t.Logf("Failed to map the generated provenance: %v", err)
+			return false
+		}
+
+		got := provenanceFields(provenance)
+		sum256 := sha256.Sum256([]byte(artifact))
+		repoURI, commit := predicate.RepoURIAndDigest()
+		imageDigest, _ := predicate.BuilderImageDigest()
+		want := provenanceSummary{
+			BinaryName:         "hello",
+			BinarySHA256Digest: hex.EncodeToString(sum256[:]),
+			BuildType:          slsav1.DockerBasedBuildType,
+			BuildCmd:           predicate.BuildCmd(),
+			RepoURI:            *repoURI,
+			CommitSHA1Digest:   *commit,
+			SourceRef:          predicate.SourceRef(),
+			BuilderImageDigest: imageDigest,
+			TrustedBuilder:     predicate.BuilderID(),
+			InvocationID:       predicate.RunDetails.BuildMetadata.InvocationID,
+			BuildStartedOn:     startedOn,
+			BuildFinishedOn:    startedOn,
+		}
+		if diff := cmp.Diff(want, got, cmpopts.EquateEmpty()); diff != "" {
+			t.Logf("The parsed provenance differs from the build (-want +got):\n%s", diff)
+			return false
+		}
+		return true
+	}
+	if err := quick.Check(property, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+// provenanceSummary holds the fields of a parsed provenance that are
+// determined by the predicate and the build.
+type provenanceSummary struct {
+	BinaryName         string
+	BinarySHA256Digest string
+	BuildType          string
+	BuildCmd           []string
+	RepoURI            string
+	CommitSHA1Digest   string
+	SourceRef          string
+	BuilderImageDigest string
+	TrustedBuilder     string
+	InvocationID       string
+	BuildStartedOn     time.Time
+	BuildFinishedOn    time.Time
+}
+
+func provenanceFields(provenance *model.ProvenanceIR) provenanceSummary {
+	summary := provenanceSummary{
+		BinaryName:         provenance.BinaryName(),
+		BinarySHA256Digest: provenance.BinarySHA256Digest(),
+		BuildType:          provenance.BuildType(),
+		RepoURI:            provenance.RepoURI(),
+		CommitSHA1Digest:   provenance.CommitSHA1Digest(),
+	}
+	summary.BuildCmd, _ = provenance.BuildCmd()
+	summary.SourceRef, _ = provenance.SourceRef()
+	summary.BuilderImageDigest, _ = provenance.BuilderImageSHA256Digest()
+	summary.TrustedBuilder, _ = provenance.TrustedBuilder()
+	if metadata, err := provenance.BuildMetadata(); err == nil {
+		summary.InvocationID = metadata.InvocationID
+	}
+	summary.BuildStartedOn, _ = provenance.BuildStartedOn()
+	summary.BuildFinishedOn, _ = provenance.BuildFinishedOn()
+	return summary
+}
+
+// roundTripPredicate marshals the given predicate, and parses it as the
+// builder does with ParsePredicateFile.
+func roundTripPredicate(predicate *slsav1.ProvenancePredicate) (*slsav1.ProvenancePredicate, error) {
+	predicateBytes, err := json.Marshal(predicate)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't marshal the predicate: %v", err)
+	}
+	var object interface{}
+	if err := json.Unmarshal(predicateBytes, &object); err != nil {
+		return nil, fmt.Errorf("couldn't unmarshal the predicate: %v", err)
+	}
+	parsed, err := slsav1.ParseContainerBasedSLSAv1Provenance(object)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't parse the generated predicate %s: %v", predicateBytes, err)
+	}
+	return parsed, nil
+}
+
+// randomPredicate returns a predicate for a random build config, and a
+// random context of the workflow run, with optional fields possibly unset.
+// The artifact path is that of fakeRunner.
+func randomPredicate(r *rand.Rand) (*slsav1.ProvenancePredicate, error) {
+	config := &slsav1.BuildConfig{ArtifactPath: "target/release/hello"}
+	for i := r.Intn(5); i >= 0; i-- {
+		config.Command = append(config.Command, randomWord(r))
+	}
+	if r.Intn(2) == 0 {
+		config.Env = map[string]string{}
+		for i := r.Intn(3); i >= 0; i-- {
+			config.Env[randomWord(r)] = randomWord(r)
+		}
+	}
+	if r.Intn(2) == 0 {
+		config.Options = &slsav1.BuildOptions{Offline: r.Intn(2) == 0}
+		if r.Intn(2) == 0 {
+			config.Options.Platform = "linux/amd64"
+		}
+	}
+
+	github := &GitHubContext{
+		ServerURL:  "https://github.com",
+		Repository: randomWord(r) + "/" + randomWord(r),
+		SHA:        randomHex(r, 20),
+	}
+	if r.Intn(2) == 0 {
+		github.Ref = "refs/heads/" + randomWord(r)
+	}
+	if r.Intn(2) == 0 {
+		github.RunID = fmt.Sprint(r.Int63())
+		github.RunAttempt = fmt.Sprint(1 + r.Intn(3))
+		github.WorkflowRef = github.Repository + "/.github/workflows/build.yml@refs/heads/main"
+	}
+	if r.Intn(2) == 0 {
+		github.EventName = randomWord(r)
+		github.RunnerOS = randomWord(r)
+	}
+	var options []func(c *PredicateConfig)
+	if r.Intn(2) == 0 {
+		options = append(options, WithBuilderID("https://example.com/"+randomWord(r)))
+	}
+	return GeneratePredicate("buildconfigs/"+randomWord(r)+".toml", config, "europe-west2-docker.pkg.dev/oak-ci/"+randomWord(r)+":latest", randomHex(r, 32), github, options...)
+}
+
+func randomWord(r *rand.Rand) string {
+	const letters = "abcdefghijklmnopqrstuvwxyz0123456789_-"
+	word := make([]byte, 1+r.Intn(10))
+	for i := range word {
+		word[i] = letters[r.Intn(len(letters))]
+	}
+	return string(word)
+}
+
+func randomHex(r *rand.Rand, length int) string {
+	bytes := make([]byte, length)
+	_, _ = r.Read(bytes)
+	return hex.EncodeToString(bytes)
+}
@@ -0,0 +1,219 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package cache provides an on-disk cache for fetched provenances and
+// evidence, so that repeated runs, e.g., in CI, do not download identical
+// content again. Entries are addressed by the SHA2-256 digest of their
+// content, which is checked again when they are read, so only content with a
+// known expected digest can be cached. Entries expire after a TTL.
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultTTL is the default time after which cache entries expire.
+const DefaultTTL = 24 * time.Hour
+
+// DefaultMaxBytes is the default maximum total size of the cache entries.
+const DefaultMaxBytes = 1 << 30
+
+// entrySuffix is the suffix of the files of cache entries.
+const entrySuffix = ".entry"
+
+// Config holds optional settings for a Cache.
+type Config struct {
+	ttl      time.Duration
+	maxBytes int64
+	clock    func() time.Time
+}
+
+// WithTTL overrides DefaultTTL.
+func WithTTL(ttl time.Duration) func(c *Config) {
+	return func(c *Config) {
+		c.ttl = ttl
+	}
+}
+
+// WithMaxBytes overrides DefaultMaxBytes. The oldest entries are evicted
+// when adding an entry exceeds the limit.
+func WithMaxBytes(maxBytes int64) func(c *Config) {
+	return func(c *Config) {
+		c.maxBytes = maxBytes
+	}
+}
+
+// Cache stores content in files in a directory, which may be shared by
+// several processes.
+type Cache struct {
+	mu     sync.Mutex
+	dir    string
+	config *Config
+}
+
+// New creates a Cache in the given directory, creating the directory if
+// needed.
+func New(dir string, options ...func(c *Config)) (*Cache, error) {
+	config := &Config{ttl: DefaultTTL, maxBytes: DefaultMaxBytes, clock: time.Now}
+	for _, addOption := range options {
+		addOption(config)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("could not create the cache directory %q: %v", dir, err)
+	}
+	return &Cache{dir: dir, config: config}, nil
+}
+
+// Get returns the content with the given hex-encoded SHA2-256 digest, and
+// whether there is an entry for the digest that has not expired. Entries whose
+// content does not have the digest, e.g., because the file was modified, are
+// removed.
+func (c *Cache) Get(sha256Digest string) ([]byte, bool) {
+	sha256Digest = strings.ToLower(sha256Digest)
+	if !isSHA256Digest(sha256Digest) {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	path := c.path(sha256Digest)
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, false
+	}
+	if c.config.clock().Sub(info.ModTime()) > c.config.ttl {
+		_ = os.Remove(path)
+		return nil, false
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	if digestOf(content) != sha256Digest {
+		_ = os.Remove(path)
+		return nil, false
+	}
+	return content, true
+}
+
+// Put stores the given content, which must have the given hex-encoded
+// SHA2-256 digest, replacing the entry for the digest, if any, and evicts the
+// oldest entries if the cache exceeds its maximum size. Content larger than
+// the maximum size is not stored.
+func (c *Cache) Put(sha256Digest string, content []byte) error {
+	sha256Digest = strings.ToLower(sha256Digest)
+	if !isSHA256Digest(sha256Digest) {
+		return fmt.Errorf("invalid SHA2-256 digest %q", sha256Digest)
+	}
+	if got := digestOf(content); got != sha256Digest {
+		return fmt.Errorf("unexpected SHA2-256 digest of the content: got %s, want %s", got, sha256Digest)
+	}
+	if int64(len(content)) > c.config.maxBytes {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Write to a temporary file first, so that other processes never read
+	// partial entries.
+	file, err := os.CreateTemp(c.dir, "tmp-*")
+	if err != nil {
+		return fmt.Errorf("could not create a cache entry: %v", err)
+	}
+	if _, err := file.Write(content); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return fmt.Errorf("could not write the cache entry: %v", err)
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(file.Name())
+		return fmt.Errorf("could not write the cache entry: %v", err)
+	}
+	now := c.config.clock()
+	if err := os.Chtimes(file.Name(), now, now); err != nil {
+		os.Remove(file.Name())
+		return fmt.Errorf("could not set the time of the cache entry: %v", err)
+	}
+	if err := os.Rename(file.Name(), c.path(sha256Digest)); err != nil {
+		os.Remove(file.Name())
+		return fmt.Errorf("could not store the cache entry: %v", err)
+	}
+	return c.evict()
+}
+
+// evict removes expired entries, and the oldest entries until the total size
+// of the entries is within the maximum size.
+func (c *Cache) evict() error {
+	paths, err := filepath.Glob(filepath.Join(c.dir, "*"+entrySuffix))
+	if err != nil {
+		return fmt.Errorf("could not list the cache entries: %v", err)
+	}
+	type entry struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+	entries := make([]entry, 0, len(paths))
+	var total int64
+	now := c.config.clock()
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			// Removed concurrently.
+			continue
+		}
+		if now.Sub(info.ModTime()) > c.config.ttl {
+			_ = os.Remove(path)
+			continue
+		}
+		entries = append(entries, entry{path: path, size: info.Size(), modTime: info.ModTime()})
+		total += info.Size()
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].modTime.Before(entries[j].modTime) })
+	for _, e := range entries {
+		if total <= c.config.maxBytes {
+			break
+		}
+		if err := os.Remove(e.path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("could not evict the cache entry %q: %v", e.path, err)
+		}
+		total -= e.size
+	}
+	return nil
+}
+
+// path returns the path of the file of the entry with the given digest.
+func (c *Cache) path(sha256Digest string) string {
+	return filepath.Join(c.dir, sha256Digest+entrySuffix)
+}
+
+// digestOf returns the hex-encoded SHA2-256 digest of the given content.
+func digestOf(content []byte) string {
+	sum256 := sha256.Sum256(content)
+	return hex.EncodeToString(sum256[:])
+}
+
+// isSHA256Digest returns whether the given string is a hex-encoded SHA2-256
+// digest, so that it can safely be used as a file name.
+func isSHA256Digest(digest string) bool {
+	decoded, err := hex.DecodeString(digest)
+	return err == nil && len(decoded) == sha256.Size
+}
@@ -0,0 +1,128 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/project-oak/transparent-release/internal/testutil"
+)
+
+// newTestCache creates a cache in a temporary directory, with a clock that is
+// returned for advancing it.
+func newTestCache(t *testing.T, options ...func(c *Config)) (*Cache, *time.Time) {
+	now := time.Date(2023, 6, 1, 10, 0, 0, 0, time.UTC)
+	c, err := New(t.TempDir(), options...)
+	if err != nil {
+		t.Fatalf("Could not create the cache: %v", err)
+	}
+	c.config.clock = func() time.Time { return now }
+	return c, &now
+}
+
+func TestCache_GetPut(t *testing.T) {
+	c, _ := newTestCache(t)
+	digest := digestOf([]byte("content"))
+
+	if _, ok := c.Get(digest); ok {
+		t.Fatalf("Unexpected entry in an empty cache")
+	}
+	if err := c.Put(digest, []byte("content")); err != nil {
+		t.Fatalf("Could not store the entry: %v", err)
+	}
+	content, ok := c.Get(digest)
+	if !ok {
+		t.Fatalf("Missing entry for %s", digest)
+	}
+	testutil.AssertEq(t, "content", string(content), "content")
+
+	// Content that does not have the digest is not stored.
+	if err := c.Put(digestOf([]byte("other")), []byte("content")); err == nil {
+		t.Errorf("Expected an error for content with a different digest")
+	}
+	if err := c.Put("../content", []byte("content")); err == nil {
+		t.Errorf("Expected an error for an invalid digest")
+	}
+}
+
+func TestCache_GetModified(t *testing.T) {
+	c, _ := newTestCache(t)
+	digest := digestOf([]byte("content"))
+	if err := c.Put(digest, []byte("content")); err != nil {
+		t.Fatalf("Could not store the entry: %v", err)
+	}
+	if err := os.WriteFile(c.path(digest), []byte("tampered"), 0o600); err != nil {
+		t.Fatalf("Could not modify the entry: %v", err)
+	}
+
+	// The digest is checked again when reading, and the entry is removed.
+	if _, ok := c.Get(digest); ok {
+		t.Errorf("Unexpected entry with modified content")
+	}
+	if _, err := os.Stat(c.path(digest)); !os.IsNotExist(err) {
+		t.Errorf("Expected the modified entry to be removed, got %v", err)
+	}
+}
+
+func TestCache_TTL(t *testing.T) {
+	c, now := newTestCache(t, WithTTL(time.Hour))
+	key := digestOf([]byte("content"))
+	if err := c.Put(key, []byte("content")); err != nil {
+		t.Fatalf("Could not store the entry: %v", err)
+	}
+
+	*now = now.Add(59 * time.Minute)
+	if _, ok := c.Get(key); !ok {
+		t.Errorf("Missing entry before the TTL")
+	}
+	*now = now.Add(2 * time.Minute)
+	if _, ok := c.Get(key); ok {
+		t.Errorf("Unexpected expired entry")
+	}
+}
+
+func TestCache_MaxBytes(t *testing.T) {
+	c, now := newTestCache(t, WithMaxBytes(10))
+	contents := []string{"aaaaa", "bbbbb", "ccccc"}
+	keys := make([]string, len(contents))
+	for i, content := range contents {
+		keys[i] = digestOf([]byte(content))
+		if err := c.Put(keys[i], []byte(content)); err != nil {
+			t.Fatalf("Could not store the entry: %v", err)
+		}
+		*now = now.Add(time.Second)
+	}
+
+	// The oldest entry is evicted.
+	if _, ok := c.Get(keys[0]); ok {
+		t.Errorf("Unexpected entry that should have been evicted")
+	}
+	for _, key := range keys[1:] {
+		if _, ok := c.Get(key); !ok {
+			t.Errorf("Missing entry for %s", key)
+		}
+	}
+
+	// Content larger than the cache is not stored.
+	large := []byte("12345678901")
+	if err := c.Put(digestOf(large), large); err != nil {
+		t.Fatalf("Could not store the entry: %v", err)
+	}
+	if _, ok := c.Get(digestOf(large)); ok {
+		t.Errorf("Unexpected entry larger than the cache")
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package cmdutil contains helpers shared by the commands in cmd.
+package cmdutil
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/project-oak/transparent-release/internal/model"
+)
+
+// Usage returns a function for flag.Usage that prints the usage message of
+// the command-line flags, omitting the flags with the given names, e.g.,
+// flags that are only meant for testing.
+func Usage(hidden ...string) func() {
+	hiddenFlags := make(map[string]bool, len(hidden))
+	for _, name := range hidden {
+		hiddenFlags[name] = true
+	}
+	return func() {
+		output := flag.CommandLine.Output()
+		fmt.Fprintf(output, "Usage of %s:\n", os.Args[0])
+		flag.VisitAll(func(f *flag.Flag) {
+			if hiddenFlags[f.Name] {
+				return
+			}
+			fmt.Fprintf(output, "  -%s\n    \t%s (default %q)\n", f.Name, f.Usage, f.DefValue)
+		})
+	}
+}
+
+// PrintSupportedFormats prints the supported combinations of predicate types
+// and build types of provenances to the given writer, one per line.
+func PrintSupportedFormats(w io.Writer) {
+	for _, format := range model.SupportedFormats() {
+		fmt.Fprintf(w, "%s\t%s\n", format.PredicateType, format.BuildType)
+	}
+}
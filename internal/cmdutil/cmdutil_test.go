@@ -0,0 +1,50 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmdutil
+
+import (
+	"bytes"
+	"flag"
+	"strings"
+	"testing"
+
+	"github.com/project-oak/transparent-release/internal/model"
+	"github.com/project-oak/transparent-release/internal/testutil"
+)
+
+func TestUsage_OmitsHiddenFlags(t *testing.T) {
+	commandLine := flag.CommandLine
+	t.Cleanup(func() { flag.CommandLine = commandLine })
+	flag.CommandLine = flag.NewFlagSet("test", flag.ContinueOnError)
+	var output bytes.Buffer
+	flag.CommandLine.SetOutput(&output)
+	flag.String("visible", "", "A visible flag.")
+	flag.String("now", "", "A hidden flag.")
+
+	Usage("now")()
+	if !strings.Contains(output.String(), "-visible") {
+		t.Errorf("expected the visible flag in the usage message:\n%s", output.String())
+	}
+	if strings.Contains(output.String(), "-now") {
+		t.Errorf("expected no hidden flag in the usage message:\n%s", output.String())
+	}
+}
+
+func TestPrintSupportedFormats(t *testing.T) {
+	var output bytes.Buffer
+	PrintSupportedFormats(&output)
+	lines := strings.Split(strings.TrimSuffix(output.String(), "\n"), "\n")
+	testutil.AssertEq(t, "number of formats", len(lines), len(model.SupportedFormats()))
+}
@@ -0,0 +1,21 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package e2e contains end-to-end tests that exercise the full release
+// pipeline across package boundaries: a small fixture binary is built with
+// the builder, which generates its SLSA v1 provenance, an endorsement is
+// generated and signed for it, and the signed endorsement and the provenance
+// are verified offline, without access to a transparency log. The build
+// command runs directly on the host instead of in a container.
+package e2e
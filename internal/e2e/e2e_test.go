@@ -0,0 +1,284 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package e2e
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/project-oak/transparent-release/internal/builder"
+	"github.com/project-oak/transparent-release/internal/endorser"
+	"github.com/project-oak/transparent-release/internal/model"
+	"github.com/project-oak/transparent-release/internal/rebuild"
+	"github.com/project-oak/transparent-release/internal/testutil"
+	"github.com/project-oak/transparent-release/internal/verifier"
+	"github.com/project-oak/transparent-release/pkg/claims"
+	"github.com/project-oak/transparent-release/pkg/intoto"
+	pb "github.com/project-oak/transparent-release/pkg/proto/oak/release"
+	"github.com/secure-systems-lab/go-securesystemslib/dsse"
+)
+
+const (
+	fixtureBinaryName   = "hello_transparent_release"
+	fixtureRepoURI      = "git+https://github.com/project-oak/hello-transparent-release"
+	fixtureCommitDigest = "ef0b9d6e9a3b4a7b8c7e1a5f0d2c3b4a5e6f7a8b"
+	fixtureBuilderID    = "https://github.com/slsa-framework/slsa-github-generator/.github/workflows/builder_docker-based_slsa3.yml@refs/tags/v1.7.0"
+	fixtureImageDigest  = "51532c757d1008bbff696d053a1d05226f6387cf232aa80b6f9c13b0759ccea0"
+)
+
+// ecdsaSigner is a dsse.SignerVerifier backed by a local ECDSA key, standing
+// in for the signing key of a product team.
+type ecdsaSigner struct {
+	key *ecdsa.PrivateKey
+}
+
+func (s *ecdsaSigner) Sign(_ context.Context, data []byte) ([]byte, error) {
+	digest := sha256.Sum256(data)
+	return s.key.Sign(rand.Reader, digest[:], crypto.SHA256)
+}
+
+func (s *ecdsaSigner) Verify(_ context.Context, data, sig []byte) error {
+	digest := sha256.Sum256(data)
+	if !ecdsa.VerifyASN1(&s.key.PublicKey, digest[:], sig) {
+		return fmt.Errorf("invalid signature")
+	}
+	return nil
+}
+
+func (s *ecdsaSigner) KeyID() (string, error) {
+	return dsse.SHA256KeyID(s.key.Public())
+}
+
+func (s *ecdsaSigner) Public() crypto.PublicKey {
+	return s.key.Public()
+}
+
+// fixtureBuildConfig builds the fixture binary with a shell command, so that
+// the build does not need a toolchain.
+const fixtureBuildConfig = `command = ["sh", "-c", "mkdir -p out && printf 'Hello, Transparent Release!\\n' > out/hello_transparent_release"]
+artifact_path = "out/hello_transparent_release"
+`
+
+// localRunner runs the commands of builds directly in the source directory,
+// standing in for the container runtime, which is not available in tests.
+type localRunner struct{}
+
+func (localRunner) Checkout(_ context.Context, _, _, _ string) (*rebuild.CheckoutMetrics, error) {
+	return nil, fmt.Errorf("checking out sources is not supported")
+}
+
+func (localRunner) Run(ctx context.Context, build *rebuild.Build, dir string, output io.Writer) error {
+	cmd := exec.CommandContext(ctx, build.Command[0], build.Command[1:]...)
+	cmd.Dir = dir
+	cmd.Stdout = output
+	cmd.Stderr = output
+	return cmd.Run()
+}
+
+// buildFixture builds a fixture binary in a new source directory with the
+// builder, writes its SLSA v1 container-based provenance to the given
+// directory, and returns the SHA2-256 digest of the binary and the URI of the
+// provenance.
+func buildFixture(t *testing.T, dir string) (string, string) {
+	sourceDir := t.TempDir()
+	configPath := filepath.Join(sourceDir, "buildconfigs", "hello.toml")
+	if err := os.MkdirAll(filepath.Dir(configPath), 0o755); err != nil {
+		t.Fatalf("could not create the build config directory: %v", err)
+	}
+	if err := os.WriteFile(configPath, []byte(fixtureBuildConfig), 0o600); err != nil {
+		t.Fatalf("could not write the build config: %v", err)
+	}
+	config, err := builder.LoadBuildConfig(configPath)
+	if err != nil {
+		t.Fatalf("could not load the build config: %v", err)
+	}
+
+	github := &builder.GitHubContext{
+		ServerURL:  "https://github.com",
+		Repository: strings.TrimPrefix(fixtureRepoURI, "git+https://github.com/"),
+		SHA:        fixtureCommitDigest,
+	}
+	predicate, err := builder.GeneratePredicate("buildconfigs/hello.toml", config,
+		"europe-west2-docker.pkg.dev/oak-ci/oak-development/oak-development", "sha256:"+fixtureImageDigest, github,
+		builder.WithBuilderID(fixtureBuilderID))
+	if err != nil {
+		t.Fatalf("could not generate the predicate: %v", err)
+	}
+	statement, err := builder.Build(context.Background(), predicate, sourceDir, claims.SystemClock(), rebuild.WithRunner(localRunner{}))
+	if err != nil {
+		t.Fatalf("could not build the fixture binary: %v", err)
+	}
+	testutil.AssertEq(t, "subject name", statement.Subject[0].Name, fixtureBinaryName)
+
+	bytes, err := json.Marshal(statement)
+	if err != nil {
+		t.Fatalf("could not marshal the provenance: %v", err)
+	}
+	provenancePath := filepath.Join(dir, "provenance.json")
+	if err := os.WriteFile(provenancePath, bytes, 0600); err != nil {
+		t.Fatalf("could not write the provenance: %v", err)
+	}
+
+	return statement.Subject[0].Digest["sha256"], "file://" + provenancePath
+}
+
+func referenceValues(binaryDigest string) *pb.VerificationOptions {
+	return &pb.VerificationOptions{
+		ProvenanceCountAtLeast: &pb.VerifyProvenanceCountAtLeast{Count: 1},
+		AllWithBuildCommand:    &pb.VerifyAllWithBuildCommand{},
+		AllWithBinaryDigests: &pb.VerifyAllWithBinaryDigests{
+			Digests: []*pb.Digest{{Hexadecimal: map[int32]string{int32(pb.Digest_SHA2_256): binaryDigest}}},
+		},
+		AllWithBuilderNames: &pb.VerifyAllWithBuilderNames{BuilderNames: []string{fixtureBuilderID}},
+		AllWithBuilderDigests: &pb.VerifyAllWithBuilderDigests{
+			Digests: []*pb.Digest{{Hexadecimal: map[int32]string{int32(pb.Digest_SHA2_256): fixtureImageDigest}}},
+		},
+		AllWithRepository: &pb.VerifyAllWithRepository{RepositoryUri: fixtureRepoURI},
+	}
+}
+
+func validity() claims.ClaimValidity {
+	notBefore := time.Now().AddDate(0, 0, 1)
+	notAfter := time.Now().AddDate(0, 0, 90)
+	return claims.ClaimValidity{NotBefore: &notBefore, NotAfter: &notAfter}
+}
+
+func TestEndToEnd_EndorseAndVerifyOffline(t *testing.T) {
+	ctx := context.Background()
+	binaryDigest, provenanceURI := buildFixture(t, t.TempDir())
+
+	// Endorse: load the provenance, verify it, and generate the endorsement.
+	provenances, err := endorser.LoadProvenances(context.Background(), []string{provenanceURI})
+	if err != nil {
+		t.Fatalf("could not load provenances: %v", err)
+	}
+	digests := intoto.DigestSet{"sha2-256": binaryDigest}
+	endorsement, err := endorser.GenerateEndorsement(fixtureBinaryName, digests, referenceValues(binaryDigest), validity(), provenances)
+	if err != nil {
+		t.Fatalf("could not generate the endorsement: %v", err)
+	}
+
+	// Sign: wrap the endorsement in a DSSE envelope signed by the product team.
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("could not generate a signing key: %v", err)
+	}
+	signer := &ecdsaSigner{key: key}
+	envelope, err := endorser.SignStatement(ctx, endorsement, signer)
+	if err != nil {
+		t.Fatalf("could not sign the endorsement: %v", err)
+	}
+
+	// Verify offline: check the envelope signature with the team's public key,
+	// parse and validate the endorsement, and re-verify the evidence.
+	envelopeVerifier, err := dsse.NewEnvelopeVerifier(&ecdsaSigner{key: key})
+	if err != nil {
+		t.Fatalf("could not create the envelope verifier: %v", err)
+	}
+	if _, err := envelopeVerifier.Verify(ctx, envelope); err != nil {
+		t.Fatalf("could not verify the endorsement signature: %v", err)
+	}
+	payload, err := envelope.DecodeB64Payload()
+	if err != nil {
+		t.Fatalf("could not decode the envelope payload: %v", err)
+	}
+	parsed, err := claims.ParseEndorsementV2Bytes(payload)
+	if err != nil {
+		t.Fatalf("could not parse the endorsement: %v", err)
+	}
+	testutil.AssertEq(t, "subject name", parsed.Subject[0].Name, fixtureBinaryName)
+	testutil.AssertEq(t, "subject digest", parsed.Subject[0].Digest["sha2-256"], binaryDigest)
+
+	predicate := parsed.Predicate.(claims.ClaimPredicate)
+	testutil.AssertEq(t, "evidence length", len(predicate.Evidence), 1)
+	evidence := predicate.Evidence[0]
+	reloaded, err := endorser.LoadProvenance(context.Background(), evidence.URI)
+	if err != nil {
+		t.Fatalf("could not reload the provenance evidence: %v", err)
+	}
+	testutil.AssertEq(t, "evidence digest", reloaded.SourceMetadata.SHA256Digest, evidence.Digest["sha256"])
+	if err := verifier.Verify([]model.ProvenanceIR{reloaded.Provenance}, referenceValues(binaryDigest)); err != nil {
+		t.Fatalf("could not verify the provenance evidence: %v", err)
+	}
+}
+
+func TestEndToEnd_TamperedEnvelopeFails(t *testing.T) {
+	ctx := context.Background()
+	binaryDigest, provenanceURI := buildFixture(t, t.TempDir())
+
+	provenances, err := endorser.LoadProvenances(context.Background(), []string{provenanceURI})
+	if err != nil {
+		t.Fatalf("could not load provenances: %v", err)
+	}
+	endorsement, err := endorser.GenerateEndorsement(fixtureBinaryName, intoto.DigestSet{"sha2-256": binaryDigest},
+		referenceValues(binaryDigest), validity(), provenances)
+	if err != nil {
+		t.Fatalf("could not generate the endorsement: %v", err)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("could not generate a signing key: %v", err)
+	}
+	envelope, err := endorser.SignStatement(ctx, endorsement, &ecdsaSigner{key: key})
+	if err != nil {
+		t.Fatalf("could not sign the endorsement: %v", err)
+	}
+
+	// Endorse a different binary under the same signature.
+	endorsement.Subject[0].Digest["sha2-256"] = fixtureImageDigest
+	tamperedPayload, err := json.Marshal(endorsement)
+	if err != nil {
+		t.Fatalf("could not marshal the tampered endorsement: %v", err)
+	}
+	envelope.Payload = base64.StdEncoding.EncodeToString(tamperedPayload)
+
+	envelopeVerifier, err := dsse.NewEnvelopeVerifier(&ecdsaSigner{key: key})
+	if err != nil {
+		t.Fatalf("could not create the envelope verifier: %v", err)
+	}
+	if _, err := envelopeVerifier.Verify(ctx, envelope); err == nil {
+		t.Fatalf("expected the tampered envelope to fail verification")
+	}
+}
+
+func TestEndToEnd_WrongReferenceValuesFail(t *testing.T) {
+	binaryDigest, provenanceURI := buildFixture(t, t.TempDir())
+
+	provenances, err := endorser.LoadProvenances(context.Background(), []string{provenanceURI})
+	if err != nil {
+		t.Fatalf("could not load provenances: %v", err)
+	}
+	verOpts := referenceValues(binaryDigest)
+	verOpts.AllWithRepository.RepositoryUri = "git+https://github.com/project-oak/oak"
+	if _, err := endorser.GenerateEndorsement(fixtureBinaryName, intoto.DigestSet{"sha2-256": binaryDigest},
+		verOpts, validity(), provenances); err == nil {
+		t.Fatalf("expected endorsement generation to fail for a provenance from an unexpected repository")
+	}
+}
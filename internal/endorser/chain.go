@@ -0,0 +1,104 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package endorser
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/multierr"
+
+	"github.com/project-oak/transparent-release/internal/model"
+	"github.com/project-oak/transparent-release/internal/verifier"
+	"github.com/project-oak/transparent-release/pkg/claims"
+	"github.com/project-oak/transparent-release/pkg/intoto"
+	pb "github.com/project-oak/transparent-release/pkg/proto/oak/release"
+)
+
+// VerifyEndorsementChain verifies an endorsement together with the provenances
+// it references as evidence, and returns a report listing every check and its
+// outcome. The signature of the endorsement must be verified separately.
+//
+// The provenances are fetched from the URIs in the evidence, and must match
+// the digests in the evidence. They are verified against the given
+// VerificationOptions, and must be for the binary in the subject of the
+// endorsement. The endorsement must be valid at the time of the given source.
+// The LoadConfig options are used for loading the provenances.
+func VerifyEndorsementChain(ctx context.Context, endorsement *intoto.Statement, verOpts *pb.VerificationOptions, timeSource *verifier.TimeSource, options ...func(c *LoadConfig)) (*verifier.Report, error) {
+	predicate, err := claims.ValidateClaim(*endorsement)
+	if err != nil {
+		return nil, fmt.Errorf("invalid endorsement: %v", err)
+	}
+	if len(endorsement.Subject) != 1 {
+		return nil, fmt.Errorf("the endorsement has %d subjects, want 1", len(endorsement.Subject))
+	}
+	subject := endorsement.Subject[0]
+	subjectDigests, err := model.NormalizeDigestSet(subject.Digest)
+	if err != nil {
+		return nil, fmt.Errorf("invalid digests of the endorsed binary: %v", err)
+	}
+
+	var evidenceErrs error
+	provenances := make([]model.ProvenanceIR, 0, len(predicate.Evidence))
+	for _, evidence := range predicate.Evidence {
+		if evidence.Role != claims.ProvenanceRole {
+			continue
+		}
+		provenance, err := loadProvenanceEvidence(ctx, evidence, subject.Name, subjectDigests, options...)
+		if err != nil {
+			evidenceErrs = multierr.Append(evidenceErrs, err)
+			continue
+		}
+		provenances = append(provenances, provenance.Provenance)
+	}
+	if len(provenances) == 0 && evidenceErrs == nil {
+		evidenceErrs = fmt.Errorf("the endorsement does not reference any provenances")
+	}
+
+	report := verifier.VerifyWithReport(provenances, verOpts)
+	report.CheckClaimValidity(endorsement, timeSource)
+	report.AddCheck("provenance_evidence", nil, evidenceErrs)
+	// The provenances must be for the endorsed binary, which may be given with
+	// any of the digests of the subject.
+	report.AddCheck("provenances_for_endorsed_binary", nil, verifier.Verify(provenances, &pb.VerificationOptions{
+		AllWithBinaryName: &pb.VerifyAllWithBinaryName{BinaryName: subject.Name},
+		AllWithBinaryDigests: &pb.VerifyAllWithBinaryDigests{
+			Digests: []*pb.Digest{verifier.DigestFromDigestSet(subjectDigests)},
+		},
+	}))
+	return report, nil
+}
+
+// loadProvenanceEvidence loads the provenance referenced by the given
+// evidence, selecting the subject with the given name and digests, and checks
+// that its SHA256 digest matches the one in the evidence.
+func loadProvenanceEvidence(ctx context.Context, evidence claims.ClaimEvidence, subjectName string, subjectDigests intoto.DigestSet, options ...func(c *LoadConfig)) (*ParsedProvenance, error) {
+	digests, err := model.NormalizeDigestSet(evidence.Digest)
+	if err != nil {
+		return nil, fmt.Errorf("invalid digests of %s: %v", evidence.URI, err)
+	}
+	want, ok := digests["sha2-256"]
+	if !ok {
+		return nil, fmt.Errorf("no SHA256 digest of %s in the evidence", evidence.URI)
+	}
+	provenance, err := LoadProvenance(ctx, evidence.URI, append(options, WithSubjectName(subjectName), WithSubjectDigests(subjectDigests))...)
+	if err != nil {
+		return nil, err
+	}
+	if got := provenance.SourceMetadata.SHA256Digest; got != want {
+		return nil, fmt.Errorf("digest mismatch for %s: got sha256:%s, want sha256:%s", evidence.URI, got, want)
+	}
+	return provenance, nil
+}
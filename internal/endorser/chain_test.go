@@ -0,0 +1,148 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package endorser
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/project-oak/transparent-release/internal/verifier"
+	"github.com/project-oak/transparent-release/pkg/claims"
+	"github.com/project-oak/transparent-release/pkg/intoto"
+	pb "github.com/project-oak/transparent-release/pkg/proto/oak/release"
+)
+
+// newChainEndorsement endorses the provenance at provenancePath, valid from a
+// day before to a week after the given time.
+func newChainEndorsement(t *testing.T, now time.Time) (*intoto.Statement, []ParsedProvenance) {
+	provenances := createProvenanceList(t, []string{provenancePath})
+	notBefore, notAfter := now.AddDate(0, 0, -1), now.AddDate(0, 0, 7)
+	validity := claims.ClaimValidity{NotBefore: &notBefore, NotAfter: &notAfter}
+	statement, err := GenerateEndorsement(binaryName, intoto.DigestSet{"sha2-256": binaryDigest}, &pb.VerificationOptions{}, validity, provenances,
+		claims.WithClock(claims.FixedClock(notBefore)))
+	if err != nil {
+		t.Fatalf("could not generate the endorsement: %v", err)
+	}
+	return statement, provenances
+}
+
+// failedChecks returns the names of the failed checks in the given report.
+func failedChecks(report *verifier.Report) []string {
+	var names []string
+	for _, check := range report.Checks {
+		if !check.Passed {
+			names = append(names, check.Name)
+		}
+	}
+	return names
+}
+
+func TestVerifyEndorsementChain(t *testing.T) {
+	now := time.Now()
+	endorsement, _ := newChainEndorsement(t, now)
+	verOpts := &pb.VerificationOptions{ProvenanceCountAtLeast: &pb.VerifyProvenanceCountAtLeast{Count: 1}}
+
+	report, err := VerifyEndorsementChain(context.Background(), endorsement, verOpts, &verifier.TimeSource{Kind: verifier.SystemTimeSource, Time: now})
+	if err != nil {
+		t.Fatalf("could not verify the chain: %v", err)
+	}
+	if !report.Passed {
+		t.Fatalf("unexpected failure: %v", report.Err())
+	}
+	var names []string
+	for _, check := range report.Checks {
+		names = append(names, check.Name)
+	}
+	want := "provenance_count_at_least claim_validity provenance_evidence provenances_for_endorsed_binary"
+	if got := strings.Join(names, " "); got != want {
+		t.Errorf("unexpected checks: got %q, want %q", got, want)
+	}
+}
+
+func TestVerifyEndorsementChain_Failures(t *testing.T) {
+	now := time.Now()
+	for name, tc := range map[string]struct {
+		// modify changes the endorsement, the provenances, or the time.
+		modify  func(t *testing.T, endorsement *intoto.Statement, provenances []ParsedProvenance) (*pb.VerificationOptions, time.Time)
+		wantErr string
+	}{
+		"expired endorsement": {
+			modify: func(*testing.T, *intoto.Statement, []ParsedProvenance) (*pb.VerificationOptions, time.Time) {
+				return &pb.VerificationOptions{}, now.AddDate(0, 1, 0)
+			},
+			wantErr: "claim_validity",
+		},
+		"provenance changed after endorsement": {
+			modify: func(t *testing.T, _ *intoto.Statement, provenances []ParsedProvenance) (*pb.VerificationOptions, time.Time) {
+				differentBytes, err := os.ReadFile(differentProvenancePath)
+				if err != nil {
+					t.Fatalf("could not read the provenance: %v", err)
+				}
+				path := strings.TrimPrefix(provenances[0].SourceMetadata.URI, "file://")
+				if err := os.WriteFile(path, differentBytes, 0o600); err != nil {
+					t.Fatalf("could not overwrite the provenance: %v", err)
+				}
+				return &pb.VerificationOptions{}, now
+			},
+			wantErr: "provenance_evidence",
+		},
+		"provenance for another binary": {
+			modify: func(_ *testing.T, endorsement *intoto.Statement, _ []ParsedProvenance) (*pb.VerificationOptions, time.Time) {
+				endorsement.Subject[0].Digest = intoto.DigestSet{"sha256": strings.Repeat("0", 64)}
+				return &pb.VerificationOptions{}, now
+			},
+			wantErr: "provenances_for_endorsed_binary",
+		},
+		"reference values not met": {
+			modify: func(*testing.T, *intoto.Statement, []ParsedProvenance) (*pb.VerificationOptions, time.Time) {
+				return &pb.VerificationOptions{AllWithRepository: &pb.VerifyAllWithRepository{RepositoryUri: "https://github.com/attacker/oak"}}, now
+			},
+			wantErr: "all_with_repository",
+		},
+	} {
+		endorsement, provenances := newChainEndorsement(t, now)
+		verOpts, at := tc.modify(t, endorsement, provenances)
+
+		report, err := VerifyEndorsementChain(context.Background(), endorsement, verOpts, &verifier.TimeSource{Kind: verifier.SystemTimeSource, Time: at})
+		if err != nil {
+			t.Fatalf("%s: could not verify the chain: %v", name, err)
+		}
+		if got := strings.Join(failedChecks(report), " "); got != tc.wantErr {
+			t.Errorf("%s: unexpected failed checks: got %q, want %q", name, got, tc.wantErr)
+		}
+	}
+}
+
+func TestVerifyEndorsementChain_NoProvenances(t *testing.T) {
+	now := time.Now()
+	notBefore, notAfter := now.AddDate(0, 0, -1), now.AddDate(0, 0, 7)
+	validity := claims.ClaimValidity{NotBefore: &notBefore, NotAfter: &notAfter}
+	endorsement, err := GenerateEndorsement(binaryName, intoto.DigestSet{"sha2-256": binaryDigest}, &pb.VerificationOptions{}, validity, []ParsedProvenance{},
+		claims.WithClock(claims.FixedClock(notBefore)))
+	if err != nil {
+		t.Fatalf("could not generate the endorsement: %v", err)
+	}
+
+	report, err := VerifyEndorsementChain(context.Background(), endorsement, &pb.VerificationOptions{}, &verifier.TimeSource{Kind: verifier.SystemTimeSource, Time: now})
+	if err != nil {
+		t.Fatalf("could not verify the chain: %v", err)
+	}
+	if got := strings.Join(failedChecks(report), " "); got != "provenance_evidence" {
+		t.Errorf("unexpected failed checks: got %q, want %q", got, "provenance_evidence")
+	}
+}
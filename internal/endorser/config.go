@@ -0,0 +1,148 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package endorser
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/project-oak/transparent-release/internal/model"
+	"github.com/project-oak/transparent-release/internal/verifier"
+	"github.com/project-oak/transparent-release/pkg/claims"
+	"github.com/project-oak/transparent-release/pkg/intoto"
+)
+
+// Kinds of configuration artifacts that can be endorsed, as recorded in the
+// ConfigSpec of their endorsements.
+const (
+	// PolicyConfigKind is the kind of VerificationOptions files passed to the
+	// verifier and the endorser with --policy.
+	PolicyConfigKind = "policy"
+	// ReferenceValuesConfigKind is the kind of reference values files, stored
+	// at ReferenceValuesPath in source repositories.
+	ReferenceValuesConfigKind = "reference_values"
+)
+
+// ConfigSpec is the claim spec of endorsements of configuration artifacts. It
+// distinguishes them from endorsements of binaries, so that, e.g., a binary
+// that happens to parse as a policy cannot be passed off as an endorsed
+// policy.
+type ConfigSpec struct {
+	// ConfigKind is the kind of the endorsed configuration artifact, e.g.,
+	// PolicyConfigKind.
+	ConfigKind string `json:"configKind"`
+}
+
+// GenerateConfigEndorsement generates an endorsement statement for the
+// configuration artifact with the given name, kind, and content. The subject
+// of the endorsement is the digest of the content, which must parse as the
+// given kind of artifact. Policies are parsed as YAML if the name has a
+// `.yaml` or `.yml` extension, and as textproto otherwise. There are no
+// provenances for configuration artifacts; additional evidence can be passed
+// in the options.
+func GenerateConfigEndorsement(name, kind string, content []byte, validity claims.ClaimValidity, options ...func(c *claims.EndorsementConfig)) (*intoto.Statement, error) {
+	if err := validateConfig(name, kind, content); err != nil {
+		return nil, err
+	}
+	options = append(options, claims.WithClaimSpec(ConfigSpec{ConfigKind: kind}))
+	config, err := claims.NewEndorsementConfig(options...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid endorsement config: %v", err)
+	}
+	sum256 := sha256.Sum256(content)
+	subject := claims.VerifiedProvenanceSet{
+		BinaryName: name,
+		Digests:    intoto.DigestSet{"sha2-256": hex.EncodeToString(sum256[:])},
+	}
+	return claims.GenerateEndorsementStatementWithConfig(config, validity, subject)
+}
+
+// VerifyConfigEndorsement checks that the given endorsement endorses the given
+// content as a configuration artifact of the given kind. The signature and
+// validity of the endorsement are not checked here.
+func VerifyConfigEndorsement(endorsement *intoto.Statement, kind string, content []byte) error {
+	predicate, err := claims.ValidateClaim(*endorsement)
+	if err != nil {
+		return fmt.Errorf("invalid endorsement: %v", err)
+	}
+	spec, err := parseConfigSpec(predicate.ClaimSpec)
+	if err != nil {
+		return err
+	}
+	if spec.ConfigKind != kind {
+		return fmt.Errorf("the endorsement is for a configuration artifact of kind %q, want %q", spec.ConfigKind, kind)
+	}
+
+	if len(endorsement.Subject) != 1 {
+		return fmt.Errorf("the endorsement has %d subjects, want 1", len(endorsement.Subject))
+	}
+	digests, err := model.NormalizeDigestSet(endorsement.Subject[0].Digest)
+	if err != nil {
+		return fmt.Errorf("invalid digests of the subject: %v", err)
+	}
+	want, ok := digests["sha2-256"]
+	if !ok {
+		return fmt.Errorf("no SHA256 digest of the subject in the endorsement")
+	}
+	sum256 := sha256.Sum256(content)
+	if got := hex.EncodeToString(sum256[:]); got != want {
+		return fmt.Errorf("the %s is not endorsed: got SHA256 digest %s, the endorsement is for %s", kind, got, want)
+	}
+	return nil
+}
+
+// parseConfigSpec converts the claim spec of a parsed endorsement, which is a
+// generic JSON object, into a ConfigSpec.
+func parseConfigSpec(claimSpec interface{}) (*ConfigSpec, error) {
+	if claimSpec == nil {
+		return nil, fmt.Errorf("the endorsement is not for a configuration artifact")
+	}
+	bytes, err := json.Marshal(claimSpec)
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal the claim spec: %v", err)
+	}
+	var spec ConfigSpec
+	if err := json.Unmarshal(bytes, &spec); err != nil || spec.ConfigKind == "" {
+		return nil, fmt.Errorf("the endorsement is not for a configuration artifact")
+	}
+	return &spec, nil
+}
+
+// validateConfig checks that the given content parses as the given kind of
+// configuration artifact.
+func validateConfig(name, kind string, content []byte) error {
+	var err error
+	switch kind {
+	case PolicyConfigKind:
+		switch strings.ToLower(filepath.Ext(name)) {
+		case ".yaml", ".yml":
+			_, err = verifier.ParseVerificationOptionsYAML(content)
+		default:
+			_, err = verifier.ParseVerificationOptions(string(content))
+		}
+	case ReferenceValuesConfigKind:
+		_, err = verifier.ParseVerificationOptions(string(content))
+	default:
+		return fmt.Errorf("unsupported kind of configuration artifact %q, want %q or %q", kind, PolicyConfigKind, ReferenceValuesConfigKind)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid %s %s: %v", kind, name, err)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package endorser
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/project-oak/transparent-release/internal/testutil"
+	"github.com/project-oak/transparent-release/pkg/claims"
+	"github.com/project-oak/transparent-release/pkg/intoto"
+	pb "github.com/project-oak/transparent-release/pkg/proto/oak/release"
+)
+
+const policyTextproto = `all_with_binary_name { binary_name: "oak_functions_freestanding_bin" }`
+
+// roundTrip serializes the given endorsement and parses it back, as the
+// verifier does.
+func roundTrip(t *testing.T, endorsement *intoto.Statement) *intoto.Statement {
+	bytes, err := json.Marshal(endorsement)
+	if err != nil {
+		t.Fatalf("Could not marshal the endorsement: %v", err)
+	}
+	parsed, err := claims.ParseEndorsementV2Bytes(bytes)
+	if err != nil {
+		t.Fatalf("Could not parse the endorsement: %v", err)
+	}
+	return parsed
+}
+
+func TestGenerateConfigEndorsement_Policy(t *testing.T) {
+	endorsement, err := GenerateConfigEndorsement("policy.textproto", PolicyConfigKind, []byte(policyTextproto), createClaimValidity(7))
+	if err != nil {
+		t.Fatalf("Could not generate the endorsement: %v", err)
+	}
+	testutil.AssertEq(t, "subject name", endorsement.Subject[0].Name, "policy.textproto")
+
+	parsed := roundTrip(t, endorsement)
+	if err := VerifyConfigEndorsement(parsed, PolicyConfigKind, []byte(policyTextproto)); err != nil {
+		t.Errorf("Could not verify the endorsement: %v", err)
+	}
+	if err := VerifyConfigEndorsement(parsed, PolicyConfigKind, []byte(policyTextproto+" ")); err == nil {
+		t.Errorf("Expected an error for a different policy")
+	}
+	if err := VerifyConfigEndorsement(parsed, ReferenceValuesConfigKind, []byte(policyTextproto)); err == nil {
+		t.Errorf("Expected an error for a different kind of configuration artifact")
+	}
+}
+
+func TestGenerateConfigEndorsement_YAMLPolicy(t *testing.T) {
+	policyYAML := "all_with_binary_name:\n  binary_name: oak_functions_freestanding_bin\n"
+	if _, err := GenerateConfigEndorsement("policy.yaml", PolicyConfigKind, []byte(policyYAML), createClaimValidity(7)); err != nil {
+		t.Errorf("Could not generate the endorsement: %v", err)
+	}
+}
+
+func TestGenerateConfigEndorsement_Invalid(t *testing.T) {
+	if _, err := GenerateConfigEndorsement("policy.textproto", PolicyConfigKind, []byte("unknown_field: 1"), createClaimValidity(7)); err == nil {
+		t.Errorf("Expected an error for an invalid policy")
+	}
+	if _, err := GenerateConfigEndorsement("config.json", "unknown", []byte("{}"), createClaimValidity(7)); err == nil {
+		t.Errorf("Expected an error for an unknown kind")
+	}
+}
+
+func TestVerifyConfigEndorsement_BinaryEndorsement(t *testing.T) {
+	// An endorsement of a binary must not pass as an endorsement of a policy,
+	// even if the binary has the same content.
+	provenances := createProvenanceList(t, []string{provenancePath})
+	endorsement, err := GenerateEndorsement(binaryName, intoto.DigestSet{"sha2-256": binaryDigest}, &pb.VerificationOptions{}, createClaimValidity(7), provenances)
+	if err != nil {
+		t.Fatalf("Could not generate the endorsement: %v", err)
+	}
+	if err := VerifyConfigEndorsement(roundTrip(t, endorsement), PolicyConfigKind, []byte(policyTextproto)); err == nil {
+		t.Errorf("Expected an error for an endorsement of a binary")
+	}
+}
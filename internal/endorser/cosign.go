@@ -0,0 +1,70 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package endorser
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/project-oak/transparent-release/internal/model"
+	"github.com/project-oak/transparent-release/internal/oci"
+	"github.com/project-oak/transparent-release/pkg/claims"
+)
+
+// CosignPredicate is an endorsement in the form expected by
+// `cosign attest --type <Type> --predicate <file with Predicate>`, which wraps
+// the predicate in a fresh in-toto statement about the image, signs it, and
+// attaches it to the image.
+type CosignPredicate struct {
+	// Type is the predicate type of the endorsement, e.g., claims.ClaimV1.
+	Type string
+	// Predicate is the JSON-encoded predicate of the endorsement.
+	Predicate []byte
+}
+
+// NewCosignPredicate returns the predicate of the given endorsement, either a
+// bare statement or a DSSE envelope, whose signature is not checked, for
+// attaching it with cosign to the container image with the given manifest
+// digest, e.g., `sha256:<hex digest>`. Fails unless the image is the subject
+// of the endorsement, since cosign replaces the subject with the image.
+func NewCosignPredicate(endorsementBytes []byte, imageDigest string) (*CosignPredicate, error) {
+	if err := oci.ValidateDigest(imageDigest); err != nil {
+		return nil, err
+	}
+	payload, err := endorsementPayload(endorsementBytes)
+	if err != nil {
+		return nil, err
+	}
+	endorsement, err := claims.ParseEndorsementV2Bytes(payload)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse the endorsement: %v", err)
+	}
+
+	digests, err := model.NormalizeDigestSet(endorsement.Subject[0].Digest)
+	if err != nil {
+		return nil, fmt.Errorf("invalid digests of the subject of the endorsement: %v", err)
+	}
+	_, want, _ := strings.Cut(imageDigest, ":")
+	if got := digests["sha2-256"]; !strings.EqualFold(got, want) {
+		return nil, fmt.Errorf("the image %s is not the subject of the endorsement, whose SHA2-256 digest is %q", imageDigest, got)
+	}
+
+	predicate, err := json.MarshalIndent(endorsement.Predicate, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal the predicate: %v", err)
+	}
+	return &CosignPredicate{Type: endorsement.PredicateType, Predicate: predicate}, nil
+}
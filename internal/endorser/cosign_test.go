@@ -0,0 +1,73 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package endorser
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/project-oak/transparent-release/internal/testutil"
+	"github.com/project-oak/transparent-release/pkg/claims"
+)
+
+func TestNewCosignPredicate(t *testing.T) {
+	endorsement := newEvidenceEndorsement()
+	statementBytes, err := json.Marshal(endorsement)
+	if err != nil {
+		t.Fatalf("could not marshal the endorsement: %v", err)
+	}
+	envelope, err := SignStatement(context.Background(), endorsement, newTestSigner(t))
+	if err != nil {
+		t.Fatalf("could not sign the endorsement: %v", err)
+	}
+	envelopeBytes, err := json.Marshal(envelope)
+	if err != nil {
+		t.Fatalf("could not marshal the envelope: %v", err)
+	}
+
+	for name, endorsementBytes := range map[string][]byte{"statement": statementBytes, "envelope": envelopeBytes} {
+		predicate, err := NewCosignPredicate(endorsementBytes, "sha256:"+binaryDigest)
+		if err != nil {
+			t.Fatalf("%s: could not generate the predicate: %v", name, err)
+		}
+		testutil.AssertEq(t, name+": type", predicate.Type, claims.ClaimV1)
+		var got claims.ClaimPredicate
+		if err := json.Unmarshal(predicate.Predicate, &got); err != nil {
+			t.Fatalf("%s: could not unmarshal the predicate: %v", name, err)
+		}
+		testutil.AssertEq(t, name+": claim type", got.ClaimType, claims.EndorsementV2)
+	}
+}
+
+func TestNewCosignPredicate_Invalid(t *testing.T) {
+	statementBytes, err := json.Marshal(newEvidenceEndorsement())
+	if err != nil {
+		t.Fatalf("could not marshal the endorsement: %v", err)
+	}
+	tests := map[string]struct {
+		endorsementBytes []byte
+		imageDigest      string
+	}{
+		"other image":        {statementBytes, "sha256:" + evidenceDigests()["sha256"]},
+		"invalid digest":     {statementBytes, binaryDigest},
+		"not an endorsement": {[]byte(`{"foo": "bar"}`), "sha256:" + binaryDigest},
+	}
+	for name, test := range tests {
+		if _, err := NewCosignPredicate(test.endorsementBytes, test.imageDigest); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
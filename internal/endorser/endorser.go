@@ -17,17 +17,24 @@ package endorser
 import (
 	"context"
 	"crypto/sha256"
+	"encoding/base64"
 	"encoding/hex"
-	"errors"
 	"fmt"
-	"io"
-	"net/http"
 	"net/url"
-	"os"
+	"strings"
+	"time"
 
+	"github.com/secure-systems-lab/go-securesystemslib/dsse"
 	"go.uber.org/multierr"
 
+	"github.com/project-oak/transparent-release/internal/cache"
+	"github.com/project-oak/transparent-release/internal/ent"
+	"github.com/project-oak/transparent-release/internal/fetch"
 	"github.com/project-oak/transparent-release/internal/model"
+	"github.com/project-oak/transparent-release/internal/oidc"
+	"github.com/project-oak/transparent-release/internal/parallel"
+	"github.com/project-oak/transparent-release/internal/rekor"
+	"github.com/project-oak/transparent-release/internal/sigstore"
 	"github.com/project-oak/transparent-release/internal/verifier"
 	"github.com/project-oak/transparent-release/pkg/claims"
 	"github.com/project-oak/transparent-release/pkg/intoto"
@@ -45,8 +52,26 @@ type ParsedProvenance struct {
 
 // GenerateEndorsement generates an endorsement statement for the given binary
 // and validity duration, using the given provenances as evidence and
-// user-specified VerificationOptions to verify them.
-func GenerateEndorsement(binaryName string, digests intoto.DigestSet, verOpts *pb.VerificationOptions, validityDuration claims.ClaimValidity, provenances []ParsedProvenance) (*intoto.Statement, error) {
+// user-specified VerificationOptions to verify them. The optional
+// EndorsementConfig options select the format of the generated statement.
+func GenerateEndorsement(binaryName string, digests intoto.DigestSet, verOpts *pb.VerificationOptions, validityDuration claims.ClaimValidity, provenances []ParsedProvenance, options ...func(c *claims.EndorsementConfig)) (*intoto.Statement, error) {
+	config, err := claims.NewEndorsementConfig(options...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid endorsement config: %v", err)
+	}
+
+	verifiedProvenances, err := VerifyProvenances(binaryName, digests, verOpts, provenances)
+	if err != nil {
+		return nil, err
+	}
+
+	return claims.GenerateEndorsementStatementWithConfig(config, validityDuration, *verifiedProvenances)
+}
+
+// VerifyProvenances verifies that all given provenances are for the given
+// binary, and satisfy the given VerificationOptions. Returns the metadata of
+// the verified provenances.
+func VerifyProvenances(binaryName string, digests intoto.DigestSet, verOpts *pb.VerificationOptions, provenances []ParsedProvenance) (*claims.VerifiedProvenanceSet, error) {
 	provenanceIRs := make([]model.ProvenanceIR, 0, len(provenances))
 	provenancesData := make([]claims.ProvenanceData, 0, len(provenances))
 	for _, p := range provenances {
@@ -54,13 +79,12 @@ func GenerateEndorsement(binaryName string, digests intoto.DigestSet, verOpts *p
 		provenancesData = append(provenancesData, p.SourceMetadata)
 	}
 
-	// First verify the non-negiotiable: binary name and digest.
+	// First verify the non-negiotiable: binary name and digest. Provenances
+	// may match on any of the algorithms in the given digests.
 	err := verifier.Verify(provenanceIRs, &pb.VerificationOptions{
 		AllWithBinaryName: &pb.VerifyAllWithBinaryName{BinaryName: binaryName},
 		AllWithBinaryDigests: &pb.VerifyAllWithBinaryDigests{
-			Digests: []*pb.Digest{
-				{Hexadecimal: map[int32]string{int32(pb.Digest_SHA2_256): digests["sha2-256"]}},
-			},
+			Digests: []*pb.Digest{verifier.DigestFromDigestSet(digests)},
 		},
 	})
 	if err != nil {
@@ -73,25 +97,240 @@ func GenerateEndorsement(binaryName string, digests intoto.DigestSet, verOpts *p
 		return nil, fmt.Errorf("failed to verify provenances: %v", err)
 	}
 
-	verifiedProvenances := claims.VerifiedProvenanceSet{
+	return &claims.VerifiedProvenanceSet{
 		Digests:     digests,
 		BinaryName:  binaryName,
 		Provenances: provenancesData,
+	}, nil
+}
+
+// CallerIdentityRole is the role of the evidence identifying the CI workflow
+// on whose behalf an endorsement was issued.
+const CallerIdentityRole = "Caller identity"
+
+// VerifyCaller checks that the repository in the claims of the caller's OIDC
+// token matches the repository of every given provenance, i.e., that the
+// caller owns the binary being endorsed. Returns an error otherwise.
+func VerifyCaller(caller *oidc.GitHubClaims, provenances []ParsedProvenance) error {
+	var errs error
+	for i, p := range provenances {
+		if !p.Provenance.HasRepoURI() {
+			errs = multierr.Append(errs, fmt.Errorf("no repository URI in provenance #%d", i))
+			continue
+		}
+		if !caller.MatchesRepository(p.Provenance.RepoURI()) {
+			errs = multierr.Append(errs, fmt.Errorf("caller repository %q does not match repository %q in provenance #%d",
+				caller.Repository, p.Provenance.RepoURI(), i))
+		}
 	}
+	return errs
+}
 
-	return claims.GenerateEndorsementStatement(validityDuration, verifiedProvenances), nil
+// CallerEvidence returns evidence binding the caller's identity into an
+// endorsement. The URI is the URI of the caller's workflow run, which
+// identifies the repository and the run, with the workflow, the workflow ref
+// and the job workflow ref of the caller as query parameters. Caller identity
+// evidence cannot be fetched: its content is its URI, of which the SHA256
+// digest is recorded, so that VerifyEvidence can check it offline.
+func CallerEvidence(caller *oidc.GitHubClaims) claims.ClaimEvidence {
+	query := url.Values{}
+	for name, value := range map[string]string{
+		"workflow":         caller.Workflow,
+		"workflow_ref":     caller.WorkflowRef,
+		"job_workflow_ref": caller.JobWorkflowRef,
+	} {
+		if value != "" {
+			query.Set(name, value)
+		}
+	}
+	uri := caller.RunURI()
+	if len(query) > 0 {
+		// Encode sorts the parameters by name.
+		uri += "?" + query.Encode()
+	}
+	sum256 := sha256.Sum256([]byte(uri))
+	return claims.ClaimEvidence{
+		Role:   CallerIdentityRole,
+		URI:    uri,
+		Digest: intoto.DigestSet{"sha256": hex.EncodeToString(sum256[:])},
+	}
 }
 
-// LoadProvenances loads a number of provenance from the give URIs. Returns an
-// array of ParsedProvenance instances, or an error if loading or parsing any
-// of the provenances fails. See LoadProvenance for more details.
-func LoadProvenances(provenanceURIs []string) ([]ParsedProvenance, error) {
-	provenances := make([]ParsedProvenance, 0, len(provenanceURIs))
-	for _, uri := range provenanceURIs {
-		parsedProvenance, err := LoadProvenance(uri)
-		if err != nil {
-			return nil, fmt.Errorf("couldn't load the provenance from %s: %v", uri, err)
+// WrapStatement wraps the given statement in an unsigned DSSE envelope, with
+// `application/vnd.in-toto+json` as the payload type, for tooling that
+// expects attestations as DSSE envelopes. The payload is the canonical JSON
+// encoding of the statement.
+func WrapStatement(statement *intoto.Statement) (*dsse.Envelope, error) {
+	payload, err := intoto.MarshalCanonical(statement)
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal the statement: %v", err)
+	}
+	return &dsse.Envelope{
+		PayloadType: intoto.PayloadType,
+		Payload:     base64.StdEncoding.EncodeToString(payload),
+		Signatures:  []dsse.Signature{},
+	}, nil
+}
+
+// SignStatement wraps the given statement in a DSSE envelope, with
+// `application/vnd.in-toto+json` as the payload type, and signs it using the
+// given signer. The payload is the canonical JSON encoding of the statement.
+func SignStatement(ctx context.Context, statement *intoto.Statement, signer dsse.SignerVerifier) (*dsse.Envelope, error) {
+	payload, err := intoto.MarshalCanonical(statement)
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal the statement: %v", err)
+	}
+
+	envelopeSigner, err := dsse.NewEnvelopeSigner(signer)
+	if err != nil {
+		return nil, fmt.Errorf("could not create an envelope signer: %v", err)
+	}
+
+	envelope, err := envelopeSigner.SignPayload(ctx, intoto.PayloadType, payload)
+	if err != nil {
+		// The signer is usually a remote service, e.g., a KMS.
+		return nil, &transientError{err: fmt.Errorf("could not sign the statement: %v", err)}
+	}
+	return envelope, nil
+}
+
+// PublishEndorsement uploads the given signed endorsement to the Rekor log
+// behind the given client, and checks that the returned log entry records the
+// endorsement and its signature by the given signer. verifierPEM is the
+// PEM-encoded public key or certificate of the signer.
+func PublishEndorsement(ctx context.Context, client *rekor.Client, envelope *dsse.Envelope, signer dsse.Verifier, verifierPEM []byte) (*rekor.LogEntry, error) {
+	entry, err := client.UploadDSSE(ctx, envelope, verifierPEM)
+	if err != nil {
+		return nil, fmt.Errorf("could not upload the endorsement to Rekor: %w", err)
+	}
+	if err := rekor.VerifyEnvelopeLogEntry(ctx, envelope, entry, signer); err != nil {
+		return nil, fmt.Errorf("the Rekor log entry does not match the endorsement: %v", err)
+	}
+	return entry, nil
+}
+
+// DefaultLoadParallelism is the default maximum number of provenances that
+// LoadProvenances loads concurrently.
+const DefaultLoadParallelism = 8
+
+// LoadConfig holds optional settings for loading provenances.
+type LoadConfig struct {
+	parallelism     int
+	requireEnvelope bool
+	subjectName     string
+	subjectDigests  intoto.DigestSet
+	trustedRoot     *sigstore.TrustedRoot
+	fetchOptions    []func(c *fetch.FetchConfig)
+}
+
+// WithRequireEnvelope makes loading fail for provenances given as bare in-toto
+// statements, so that only provenances wrapped in a DSSE envelope are
+// accepted. Note that this does not verify the signatures on the envelopes.
+func WithRequireEnvelope() func(c *LoadConfig) {
+	return func(c *LoadConfig) {
+		c.requireEnvelope = true
+	}
+}
+
+// WithSubjectName selects the subject with the given name from provenances
+// with several subjects. Provenances with a single subject must still match
+// the given name.
+func WithSubjectName(subjectName string) func(c *LoadConfig) {
+	return func(c *LoadConfig) {
+		c.subjectName = subjectName
+	}
+}
+
+// WithSubjectDigests selects the subject with the given digests, e.g., of the
+// binary to endorse, from provenances with several subjects, such as those
+// listing all assets of a release. Provenances with a single subject are not
+// filtered, so that a mismatching digest is reported by the verification.
+func WithSubjectDigests(digests intoto.DigestSet) func(c *LoadConfig) {
+	return func(c *LoadConfig) {
+		c.subjectDigests = digests
+	}
+}
+
+// WithTrustedRoot verifies provenances given as Sigstore bundles against the
+// given Fulcio roots and Rekor public key, and records the identity of the
+// signing certificate in the provenance, for checking with the
+// `all_with_certificate_identity` verification option. Loading fails if the
+// verification of a bundle fails.
+func WithTrustedRoot(root *sigstore.TrustedRoot) func(c *LoadConfig) {
+	return func(c *LoadConfig) {
+		c.trustedRoot = root
+	}
+}
+
+// WithFetchRetries retries fetching a provenance up to the given number of
+// attempts, if it fails with a transient error, e.g., a failed connection or
+// a 5xx response, waiting for the given backoff before the first retry, and
+// for twice as long before every further retry. Client errors, e.g., a 404
+// response, are not retried.
+func WithFetchRetries(attempts int, backoff time.Duration) func(c *LoadConfig) {
+	return func(c *LoadConfig) {
+		c.fetchOptions = append(c.fetchOptions, fetch.WithRetries(attempts, backoff))
+	}
+}
+
+// WithMaxProvenanceBytes makes loading fail for provenances larger than the
+// given number of bytes, instead of fetch.DefaultMaxBytes.
+func WithMaxProvenanceBytes(maxBytes int64) func(c *LoadConfig) {
+	return func(c *LoadConfig) {
+		c.fetchOptions = append(c.fetchOptions, fetch.WithSizeLimit(maxBytes))
+	}
+}
+
+// WithLoadParallelism sets the maximum number of provenances that
+// LoadProvenances loads concurrently, instead of DefaultLoadParallelism.
+func WithLoadParallelism(parallelism int) func(c *LoadConfig) {
+	return func(c *LoadConfig) {
+		c.parallelism = parallelism
+	}
+}
+
+// WithProvenanceCache looks up provenances in the given cache before fetching
+// them, and stores fetched provenances in it, so that repeated runs do not
+// download the same provenances again. Only provenances with a known SHA2-256
+// digest are cached.
+func WithProvenanceCache(c *cache.Cache) func(c *LoadConfig) {
+	return func(config *LoadConfig) {
+		config.fetchOptions = append(config.fetchOptions, fetch.WithCache(c))
+	}
+}
+
+// LoadProvenances loads a number of provenance from the give URIs, loading at
+// most the configured number of provenances concurrently. Returns an array of
+// ParsedProvenance instances, in the order of the URIs, or the errors of all
+// provenances for which loading or parsing fails. See LoadProvenance for more
+// details.
+func LoadProvenances(ctx context.Context, provenanceURIs []string, options ...func(c *LoadConfig)) ([]ParsedProvenance, error) {
+	config := &LoadConfig{parallelism: DefaultLoadParallelism}
+	for _, addOption := range options {
+		addOption(config)
+	}
+	if config.parallelism < 1 {
+		return nil, fmt.Errorf("the parallelism must be positive, got %d", config.parallelism)
+	}
+
+	parsedProvenances := make([]*ParsedProvenance, len(provenanceURIs))
+	errs := parallel.Do(len(provenanceURIs), config.parallelism, func(i int) error {
+		var err error
+		parsedProvenances[i], err = LoadProvenance(ctx, provenanceURIs[i], options...)
+		return err
+	})
+
+	var err error
+	for i, loadErr := range errs {
+		if loadErr != nil {
+			err = multierr.Append(err, fmt.Errorf("couldn't load the provenance from %s: %v", provenanceURIs[i], loadErr))
 		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	provenances := make([]ParsedProvenance, 0, len(parsedProvenances))
+	for _, parsedProvenance := range parsedProvenances {
 		provenances = append(provenances, *parsedProvenance)
 	}
 	return provenances, nil
@@ -100,15 +339,28 @@ func LoadProvenances(provenanceURIs []string) ([]ParsedProvenance, error) {
 // LoadProvenance loads a provenance from the give URI (either a local file or
 // a remote file on an HTTP/HTTPS server). Returns an instance of
 // ParsedProvenance if loading and parsing is successful, or an error Otherwise.
-func LoadProvenance(provenanceURI string) (*ParsedProvenance, error) {
-	provenanceBytes, err := GetProvenanceBytes(provenanceURI)
+func LoadProvenance(ctx context.Context, provenanceURI string, options ...func(c *LoadConfig)) (*ParsedProvenance, error) {
+	config := &LoadConfig{}
+	for _, addOption := range options {
+		addOption(config)
+	}
+
+	provenanceBytes, err := GetProvenanceBytes(ctx, provenanceURI, config.fetchOptions...)
 	if err != nil {
 		return nil, fmt.Errorf("couldn't load the provenance bytes from %s: %v", provenanceURI, err)
 	}
+	return parseProvenance(ctx, provenanceURI, provenanceBytes, config)
+}
 
+// parseProvenance parses the given bytes, loaded from the given URI, into a
+// ParsedProvenance, as LoadProvenance.
+func parseProvenance(ctx context.Context, provenanceURI string, provenanceBytes []byte, config *LoadConfig) (*ParsedProvenance, error) {
 	// Parse into a validated provenance to get the predicate/build type of the provenance.
 	var errs error
 	validatedProvenance, err := model.ParseStatementData(provenanceBytes)
+	if err == nil && config.requireEnvelope {
+		return nil, fmt.Errorf("%s contains an unsigned in-toto statement, but a DSSE envelope is required", provenanceURI)
+	}
 	if err != nil {
 		errs = multierr.Append(errs, fmt.Errorf("parsing bytes as an in-toto statement: %v", err))
 		validatedProvenance, err = model.ParseEnvelope(provenanceBytes)
@@ -118,11 +370,33 @@ func LoadProvenance(provenanceURI string) (*ParsedProvenance, error) {
 		}
 	}
 
+	var subjectDigests intoto.DigestSet
+	if validatedProvenance.SubjectCount() > 1 {
+		subjectDigests = config.subjectDigests
+	}
+	if config.subjectName != "" || len(subjectDigests) != 0 {
+		validatedProvenance, err = validatedProvenance.SelectSubject(config.subjectName, subjectDigests)
+		if err != nil {
+			return nil, fmt.Errorf("selecting the subject of %s: %v", provenanceURI, err)
+		}
+	}
+
 	// Map to internal provenance representation based on the predicate/build type.
 	provenanceIR, err := model.FromValidatedProvenance(validatedProvenance)
 	if err != nil {
 		return nil, fmt.Errorf("couldn't map from %s to internal representation: %v", validatedProvenance, err)
 	}
+
+	if config.trustedRoot != nil {
+		if bundle, err := sigstore.ParseBundle(provenanceBytes); err == nil {
+			identity, err := bundle.Verify(ctx, config.trustedRoot)
+			if err != nil {
+				return nil, fmt.Errorf("couldn't verify the Sigstore bundle %s: %v", provenanceURI, err)
+			}
+			model.WithCertificateIdentity(*identity)(provenanceIR)
+		}
+	}
+
 	sum256 := sha256.Sum256(provenanceBytes)
 	return &ParsedProvenance{
 		Provenance: *provenanceIR,
@@ -133,48 +407,90 @@ func LoadProvenance(provenanceURI string) (*ParsedProvenance, error) {
 	}, nil
 }
 
-// GetProvenanceBytes fetches provenance bytes from the give URI. Supported URI
-// schemes are "http", "https", and "file". Only local files are supported.
-func GetProvenanceBytes(provenanceURI string) ([]byte, error) {
-	uri, err := url.Parse(provenanceURI)
-	if err != nil {
-		return nil, fmt.Errorf("could not parse the URI (%q): %v", provenanceURI, err)
+// CopyProvenancesToEnt uploads the given provenances to the Ent server of the
+// given client, and updates their URIs to Ent URIs, so that they remain
+// resolvable as evidence of the generated endorsement. Provenances that
+// already have Ent URIs are not uploaded again.
+func CopyProvenancesToEnt(ctx context.Context, client *ent.Client, provenances []ParsedProvenance) error {
+	for i := range provenances {
+		metadata := &provenances[i].SourceMetadata
+		if _, err := ent.ParseURI(metadata.URI); err == nil {
+			continue
+		}
+		provenanceBytes, err := fetch.Fetch(ctx, metadata.URI, fetch.WithExpectedSHA256Digest(metadata.SHA256Digest))
+		if err != nil {
+			return fmt.Errorf("couldn't load the provenance bytes from %s: %v", metadata.URI, err)
+		}
+		uri, err := client.Put(ctx, provenanceBytes)
+		if err != nil {
+			return fmt.Errorf("couldn't copy the provenance from %s to Ent: %v", metadata.URI, err)
+		}
+		metadata.URI = uri
 	}
+	return nil
+}
 
-	if uri.Scheme == "http" || uri.Scheme == "https" {
-		return getJSONOverHTTP(provenanceURI)
-	} else if uri.Scheme == "file" {
-		return getLocalJSONFile(uri)
-	}
+// GetProvenanceBytes fetches provenance bytes from the given URI, using the
+// default fetch registry. Supported URI schemes are "http", "https", "gs",
+// "file", "ent", for blobs in the default Ent server, "oci", for attestations
+// in OCI registries, and any schemes registered with fetch.Default().
+func GetProvenanceBytes(ctx context.Context, provenanceURI string, options ...func(c *fetch.FetchConfig)) ([]byte, error) {
+	return fetch.Fetch(ctx, provenanceURI, options...)
+}
 
-	return nil, fmt.Errorf("unsupported URI scheme (%q)", uri.Scheme)
+// digestSizes contains the sizes, in bytes, of the digests with the
+// algorithms supported by ParseBinaryDigests, keyed by their canonical names.
+//
+//nolint:gochecknoglobals
+var digestSizes = map[string]int{
+	"sha1":     20,
+	"sha2-256": 32,
+	"sha2-384": 48,
+	"sha2-512": 64,
+	"sha3-224": 28,
+	"sha3-256": 32,
+	"sha3-384": 48,
+	"sha3-512": 64,
 }
 
-func getJSONOverHTTP(uri string) ([]byte, error) {
-	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, uri, nil)
-	if err != nil {
-		return nil, fmt.Errorf("could not create HTTP request: %v", err)
+// ParseBinaryDigests parses the given digests of a binary, each of the form
+// `<algorithm>:<hex>`, for endorsing a binary that is not available locally.
+// Algorithms may be named as in in-toto (e.g., `sha256`) or canonically
+// (e.g., `sha2-256`). At least one digest must be a SHA-2 or SHA-3 digest,
+// since a SHA1 digest alone does not pin the binary. The digests of the result
+// are keyed canonically.
+func ParseBinaryDigests(specs []string) (intoto.DigestSet, error) {
+	if len(specs) == 0 {
+		return nil, fmt.Errorf("no digests given")
 	}
-
-	req.Header.Set("Accept", "application/json")
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	digests := make(intoto.DigestSet)
+	for _, spec := range specs {
+		algorithm, digest, ok := strings.Cut(spec, ":")
+		if !ok || algorithm == "" || digest == "" {
+			return nil, fmt.Errorf("invalid digest %q, want algorithm:hex", spec)
+		}
+		if _, ok := digests[algorithm]; ok {
+			return nil, fmt.Errorf("duplicate %s digest", algorithm)
+		}
+		digests[algorithm] = strings.ToLower(digest)
+	}
+	normalized, err := intoto.ConvertDigestSet(digests, intoto.CanonicalDigestKeys)
 	if err != nil {
-		return nil, fmt.Errorf("could not receive response from server: %v", err)
+		return nil, err
 	}
-
-	defer resp.Body.Close()
-
-	return io.ReadAll(resp.Body)
-}
-
-func getLocalJSONFile(uri *url.URL) ([]byte, error) {
-	if uri.Host != "" {
-		return nil, fmt.Errorf("invalid scheme (%q) and host (%q) combination", uri.Scheme, uri.Host)
+	pinned := false
+	for algorithm, digest := range normalized {
+		size, ok := digestSizes[algorithm]
+		if !ok {
+			return nil, fmt.Errorf("unsupported digest algorithm %q", algorithm)
+		}
+		if bytes, err := hex.DecodeString(digest); err != nil || len(bytes) != size {
+			return nil, fmt.Errorf("invalid %s digest %q, want %d hex-encoded bytes", algorithm, digest, size)
+		}
+		pinned = pinned || algorithm != "sha1"
 	}
-	if _, err := os.Stat(uri.Path); errors.Is(err, os.ErrNotExist) {
-		return nil, fmt.Errorf("%q does not exist", uri.Path)
+	if !pinned {
+		return nil, fmt.Errorf("at least one SHA-2 or SHA-3 digest is required")
 	}
-	return os.ReadFile(uri.Path)
+	return normalized, nil
 }
@@ -15,15 +15,35 @@
 package endorser
 
 import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
 	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/project-oak/transparent-release/internal/ent"
+	"github.com/project-oak/transparent-release/internal/oidc"
+	"github.com/project-oak/transparent-release/internal/rekor"
 	"github.com/project-oak/transparent-release/internal/testutil"
 	"github.com/project-oak/transparent-release/pkg/claims"
+	"github.com/project-oak/transparent-release/pkg/intoto"
 	pb "github.com/project-oak/transparent-release/pkg/proto/oak/release"
+	"github.com/secure-systems-lab/go-securesystemslib/dsse"
 )
 
 const (
@@ -51,13 +71,40 @@ func createProvenanceList(t *testing.T, paths []string) []ParsedProvenance {
 		}
 		tempURIs = append(tempURIs, "file://"+tempPath)
 	}
-	provenances, err := LoadProvenances(tempURIs)
+	provenances, err := LoadProvenances(context.Background(), tempURIs)
 	if err != nil {
 		t.Fatalf("Could not load provenances: %v", err)
 	}
 	return provenances
 }
 
+func TestParseBinaryDigests(t *testing.T) {
+	sha512 := strings.Repeat("ab", 64)
+	got, err := ParseBinaryDigests([]string{"sha256:" + strings.ToUpper(binaryDigest), "sha2-512:" + sha512})
+	if err != nil {
+		t.Fatalf("could not parse the digests: %v", err)
+	}
+	testutil.AssertEq(t, "sha2-256 digest", got["sha2-256"], binaryDigest)
+	testutil.AssertEq(t, "sha2-512 digest", got["sha2-512"], sha512)
+	testutil.AssertEq(t, "digest count", len(got), 2)
+
+	for name, specs := range map[string][]string{
+		"none":         nil,
+		"no algorithm": {binaryDigest},
+		"duplicate":    {"sha256:" + binaryDigest, "sha256:" + binaryDigest},
+		"conflicting":  {"sha256:" + binaryDigest, "sha2-256:" + strings.Repeat("00", 32)},
+		"unsupported":  {"md5:" + strings.Repeat("00", 16)},
+		"not hex":      {"sha256:" + strings.Repeat("zz", 32)},
+		"invalid size": {"sha256:" + binaryDigest[:62]},
+		"empty digest": {"sha256:"},
+		"only sha1":    {"sha1:" + strings.Repeat("00", 20)},
+	} {
+		if _, err := ParseBinaryDigests(specs); err == nil {
+			t.Errorf("%s: expected failure", name)
+		}
+	}
+}
+
 func TestGenerateEndorsement_NoProvenanceSuccess(t *testing.T) {
 	verOpts := pb.VerificationOptions{}
 	digests := map[string]string{"sha2-256": binaryDigest}
@@ -86,6 +133,21 @@ func TestGenerateEndorsement_SingleProvenanceSucess(t *testing.T) {
 	testutil.AssertEq(t, "evidence length", len(predicate.Evidence), 1)
 }
 
+func TestGenerateEndorsement_CustomClaimType(t *testing.T) {
+	verOpts := pb.VerificationOptions{}
+	digests := map[string]string{"sha2-256": binaryDigest}
+	claimType := "https://github.com/project-oak/transparent-release/endorsement/v3"
+	statement, err := GenerateEndorsement(binaryName, digests, &verOpts, createClaimValidity(7), []ParsedProvenance{},
+		claims.WithClaimType(claimType))
+	if err != nil {
+		t.Fatalf("Failed to generate endorsement: %v", err)
+	}
+
+	testutil.AssertEq(t, "predicate type", statement.PredicateType, claims.ClaimV1)
+	predicate := statement.Predicate.(claims.ClaimPredicate)
+	testutil.AssertEq(t, "claim type", predicate.ClaimType, claimType)
+}
+
 func TestGenerateEndorsement_BinaryNameMismatchFailure(t *testing.T) {
 	verOpts := pb.VerificationOptions{}
 	provenances := createProvenanceList(t, []string{provenancePath})
@@ -149,13 +211,379 @@ func TestGenerateEndorsement_SingleValidProvenanceSuccess(t *testing.T) {
 }
 
 func TestLoadProvenances_FailingSingleRemoteProvenanceEndorsement(t *testing.T) {
-	_, err := LoadProvenances([]string{"https://github.com/project-oak/transparent-release/blob/main/testdata/missing_provenance.json"})
+	_, err := LoadProvenances(context.Background(), []string{"https://github.com/project-oak/transparent-release/blob/main/testdata/missing_provenance.json"})
 	want := "couldn't load the provenance"
 	if err == nil || !strings.Contains(err.Error(), want) {
 		t.Fatalf("got %q, want error message containing %q,", err, want)
 	}
 }
 
+func TestLoadProvenances_Concurrent(t *testing.T) {
+	var mu sync.Mutex
+	active, maxActive := 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		active++
+		if active > maxActive {
+			maxActive = active
+		}
+		mu.Unlock()
+		defer func() {
+			mu.Lock()
+			active--
+			mu.Unlock()
+		}()
+		time.Sleep(10 * time.Millisecond)
+		if strings.HasPrefix(r.URL.Path, "/missing") {
+			http.NotFound(w, r)
+			return
+		}
+		http.ServeFile(w, r, provenancePath)
+	}))
+	defer server.Close()
+
+	uris := make([]string, 6)
+	for i := range uris {
+		uris[i] = fmt.Sprintf("%s/provenance-%d.json", server.URL, i)
+	}
+	provenances, err := LoadProvenances(context.Background(), uris, WithLoadParallelism(2))
+	if err != nil {
+		t.Fatalf("could not load the provenances: %v", err)
+	}
+	testutil.AssertEq(t, "provenances", len(provenances), len(uris))
+	for i, provenance := range provenances {
+		testutil.AssertEq(t, "uri", provenance.SourceMetadata.URI, uris[i])
+	}
+	if maxActive > 2 {
+		t.Errorf("too many concurrent requests: got %d, want at most 2", maxActive)
+	}
+
+	// The errors of all failing provenances are reported.
+	_, err = LoadProvenances(context.Background(), []string{server.URL + "/missing-1.json", uris[0], server.URL + "/missing-2.json"})
+	if err == nil || !strings.Contains(err.Error(), "missing-1.json") || !strings.Contains(err.Error(), "missing-2.json") {
+		t.Errorf("expected errors for both missing provenances, got: %v", err)
+	}
+}
+
+func TestVerifyCaller(t *testing.T) {
+	provenances := createProvenanceList(t, []string{provenancePath})
+
+	owner := oidc.GitHubClaims{Repository: "project-oak/oak"}
+	if err := VerifyCaller(&owner, provenances); err != nil {
+		t.Fatalf("Failed to verify caller: %v", err)
+	}
+
+	other := oidc.GitHubClaims{Repository: "project-oak/transparent-release"}
+	if err := VerifyCaller(&other, provenances); err == nil {
+		t.Fatalf("Expected failure for a caller from a different repository")
+	}
+}
+
+func TestGenerateEndorsement_WithCallerEvidence(t *testing.T) {
+	provenances := createProvenanceList(t, []string{provenancePath})
+	caller := oidc.GitHubClaims{
+		Repository:     "project-oak/oak",
+		Workflow:       "Release",
+		JobWorkflowRef: "project-oak/oak/.github/workflows/release.yml@refs/heads/main",
+		RunID:          "1234",
+		RunAttempt:     "1",
+	}
+	verOpts := pb.VerificationOptions{}
+	digests := map[string]string{"sha2-256": binaryDigest}
+
+	statement, err := GenerateEndorsement(binaryName, digests, &verOpts, createClaimValidity(7), provenances,
+		claims.WithEvidence(CallerEvidence(&caller)))
+	if err != nil {
+		t.Fatalf("Failed to generate endorsement: %v", err)
+	}
+
+	predicate := statement.Predicate.(claims.ClaimPredicate)
+	testutil.AssertEq(t, "evidence length", len(predicate.Evidence), 2)
+	testutil.AssertEq(t, "caller role", predicate.Evidence[1].Role, CallerIdentityRole)
+	testutil.AssertEq(t, "caller URI", predicate.Evidence[1].URI, "https://github.com/project-oak/oak/actions/runs/1234/attempts/1"+
+		"?job_workflow_ref=project-oak%2Foak%2F.github%2Fworkflows%2Frelease.yml%40refs%2Fheads%2Fmain&workflow=Release")
+}
+
+// ed25519Signer is a dsse.SignerVerifier for tests.
+type ed25519Signer struct {
+	privateKey ed25519.PrivateKey
+}
+
+func (s *ed25519Signer) Sign(_ context.Context, data []byte) ([]byte, error) {
+	return ed25519.Sign(s.privateKey, data), nil
+}
+
+func (s *ed25519Signer) Verify(_ context.Context, data, sig []byte) error {
+	if !ed25519.Verify(s.privateKey.Public().(ed25519.PublicKey), data, sig) {
+		return fmt.Errorf("invalid signature")
+	}
+	return nil
+}
+
+func (s *ed25519Signer) KeyID() (string, error) {
+	return "test-key", nil
+}
+
+func (s *ed25519Signer) Public() crypto.PublicKey {
+	return s.privateKey.Public()
+}
+
+func TestSignStatement(t *testing.T) {
+	verOpts := pb.VerificationOptions{}
+	digests := map[string]string{"sha2-256": binaryDigest}
+	statement, err := GenerateEndorsement(binaryName, digests, &verOpts, createClaimValidity(7), []ParsedProvenance{})
+	if err != nil {
+		t.Fatalf("Failed to generate endorsement: %v", err)
+	}
+
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	signer := &ed25519Signer{privateKey: privateKey}
+
+	ctx := context.Background()
+	envelope, err := SignStatement(ctx, statement, signer)
+	if err != nil {
+		t.Fatalf("Failed to sign endorsement: %v", err)
+	}
+	testutil.AssertEq(t, "payload type", envelope.PayloadType, intoto.PayloadType)
+
+	verifier, err := dsse.NewEnvelopeVerifier(signer)
+	if err != nil {
+		t.Fatalf("Failed to create verifier: %v", err)
+	}
+	if _, err := verifier.Verify(ctx, envelope); err != nil {
+		t.Fatalf("Failed to verify the envelope: %v", err)
+	}
+
+	payload, err := envelope.DecodeB64Payload()
+	if err != nil {
+		t.Fatalf("Failed to decode payload: %v", err)
+	}
+	endorsement, err := claims.ParseEndorsementV2Bytes(payload)
+	if err != nil {
+		t.Fatalf("Failed to parse the signed endorsement: %v", err)
+	}
+	testutil.AssertEq(t, "binary name", endorsement.Subject[0].Name, binaryName)
+}
+
+func TestWrapStatement(t *testing.T) {
+	verOpts := pb.VerificationOptions{}
+	digests := map[string]string{"sha2-256": binaryDigest}
+	statement, err := GenerateEndorsement(binaryName, digests, &verOpts, createClaimValidity(7), []ParsedProvenance{})
+	if err != nil {
+		t.Fatalf("Failed to generate endorsement: %v", err)
+	}
+
+	envelope, err := WrapStatement(statement)
+	if err != nil {
+		t.Fatalf("Failed to wrap endorsement: %v", err)
+	}
+	testutil.AssertEq(t, "payload type", envelope.PayloadType, intoto.PayloadType)
+	testutil.AssertEq(t, "number of signatures", len(envelope.Signatures), 0)
+
+	envelopeBytes, err := json.Marshal(envelope)
+	if err != nil {
+		t.Fatalf("Failed to marshal envelope: %v", err)
+	}
+	if !strings.Contains(string(envelopeBytes), `"signatures":[]`) {
+		t.Errorf("Expected an empty list of signatures, got: %s", envelopeBytes)
+	}
+
+	payload, err := envelope.DecodeB64Payload()
+	if err != nil {
+		t.Fatalf("Failed to decode payload: %v", err)
+	}
+	endorsement, err := claims.ParseEndorsementV2Bytes(payload)
+	if err != nil {
+		t.Fatalf("Failed to parse the wrapped endorsement: %v", err)
+	}
+	testutil.AssertEq(t, "binary name", endorsement.Subject[0].Name, binaryName)
+}
+
+func TestLoadProvenance_RequireEnvelope(t *testing.T) {
+	statementPath, err := copyToTemp(provenancePath)
+	if err != nil {
+		t.Fatalf("Could not copy provenance: %v", err)
+	}
+	if _, err := LoadProvenance(context.Background(), "file://"+statementPath, WithRequireEnvelope()); err == nil {
+		t.Fatalf("Expected an error for a bare statement when an envelope is required")
+	}
+
+	// Wrap the same provenance in a DSSE envelope.
+	statementBytes, err := os.ReadFile(provenancePath)
+	if err != nil {
+		t.Fatalf("Could not read provenance: %v", err)
+	}
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	envelopeSigner, err := dsse.NewEnvelopeSigner(&ed25519Signer{privateKey: privateKey})
+	if err != nil {
+		t.Fatalf("Failed to create envelope signer: %v", err)
+	}
+	envelope, err := envelopeSigner.SignPayload(context.Background(), intoto.PayloadType, statementBytes)
+	if err != nil {
+		t.Fatalf("Failed to sign provenance: %v", err)
+	}
+	envelopeBytes, err := json.Marshal(envelope)
+	if err != nil {
+		t.Fatalf("Failed to marshal envelope: %v", err)
+	}
+	envelopePath := filepath.Join(t.TempDir(), "provenance.dsse.json")
+	if err := os.WriteFile(envelopePath, envelopeBytes, 0600); err != nil {
+		t.Fatalf("Failed to write envelope: %v", err)
+	}
+
+	provenance, err := LoadProvenance(context.Background(), "file://"+envelopePath, WithRequireEnvelope())
+	if err != nil {
+		t.Fatalf("Failed to load enveloped provenance: %v", err)
+	}
+	testutil.AssertEq(t, "binary name", provenance.Provenance.BinaryName(), binaryName)
+}
+
+func TestLoadProvenance_WithSubjectName(t *testing.T) {
+	// Add a second subject to the provenance.
+	statementBytes, err := os.ReadFile(provenancePath)
+	if err != nil {
+		t.Fatalf("Could not read provenance: %v", err)
+	}
+	var statement intoto.Statement
+	if err := json.Unmarshal(statementBytes, &statement); err != nil {
+		t.Fatalf("Could not unmarshal provenance: %v", err)
+	}
+	statement.Subject = append(statement.Subject, intoto.Subject{
+		Name:   "other_bin",
+		Digest: intoto.DigestSet{"sha256": strings.Repeat("0", 64)},
+	})
+	multiSubjectBytes, err := json.Marshal(statement)
+	if err != nil {
+		t.Fatalf("Could not marshal provenance: %v", err)
+	}
+	multiSubjectPath := filepath.Join(t.TempDir(), "provenance.json")
+	if err := os.WriteFile(multiSubjectPath, multiSubjectBytes, 0600); err != nil {
+		t.Fatalf("Could not write provenance: %v", err)
+	}
+
+	if _, err := LoadProvenance(context.Background(), "file://"+multiSubjectPath); err == nil {
+		t.Fatalf("Expected an error for a provenance with several subjects without a selector")
+	}
+
+	provenance, err := LoadProvenance(context.Background(), "file://"+multiSubjectPath, WithSubjectName(binaryName))
+	if err != nil {
+		t.Fatalf("Failed to load provenance: %v", err)
+	}
+	testutil.AssertEq(t, "binary name", provenance.Provenance.BinaryName(), binaryName)
+	testutil.AssertEq(t, "binary digest", provenance.Provenance.BinarySHA256Digest(), binaryDigest)
+
+	provenance, err = LoadProvenance(context.Background(), "file://"+multiSubjectPath, WithSubjectDigests(intoto.DigestSet{"sha2-256": binaryDigest}))
+	if err != nil {
+		t.Fatalf("Failed to load provenance: %v", err)
+	}
+	testutil.AssertEq(t, "binary name", provenance.Provenance.BinaryName(), binaryName)
+
+	// A single subject is not filtered by digest, so that verification reports
+	// the mismatch.
+	singleSubjectPath, err := copyToTemp(provenancePath)
+	if err != nil {
+		t.Fatalf("Could not copy provenance: %v", err)
+	}
+	provenance, err = LoadProvenance(context.Background(), "file://"+singleSubjectPath, WithSubjectDigests(intoto.DigestSet{"sha2-256": strings.Repeat("0", 64)}))
+	if err != nil {
+		t.Fatalf("Failed to load provenance: %v", err)
+	}
+	testutil.AssertEq(t, "binary digest", provenance.Provenance.BinarySHA256Digest(), binaryDigest)
+}
+
+// newFakeRekor starts a test server that records the uploaded DSSE envelopes
+// as log entries of kind `dsse`. If tamper is set, the recorded payload hash
+// does not match the uploaded envelope.
+func newFakeRekor(t *testing.T, tamper bool) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var proposed struct {
+			Spec struct {
+				ProposedContent struct {
+					Envelope  string   `json:"envelope"`
+					Verifiers [][]byte `json:"verifiers"`
+				} `json:"proposedContent"`
+			} `json:"spec"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&proposed); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var envelope dsse.Envelope
+		if err := json.Unmarshal([]byte(proposed.Spec.ProposedContent.Envelope), &envelope); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		payload, err := envelope.DecodeB64Payload()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if tamper {
+			payload = append(payload, ' ')
+		}
+		payloadHash := sha256.Sum256(payload)
+		var verifier []byte
+		if len(proposed.Spec.ProposedContent.Verifiers) > 0 {
+			verifier = proposed.Spec.ProposedContent.Verifiers[0]
+		}
+		body := fmt.Sprintf(`{"kind":"dsse","apiVersion":"0.0.1","spec":{"payloadHash":{"algorithm":"sha256","value":%q},"signatures":[{"signature":%q,"verifier":%q}]}}`,
+			hex.EncodeToString(payloadHash[:]), envelope.Signatures[0].Sig, base64.StdEncoding.EncodeToString(verifier))
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(map[string]rekor.LogEntry{"some-uuid": {
+			Body:     base64.StdEncoding.EncodeToString([]byte(body)),
+			LogIndex: 1,
+		}})
+	}))
+}
+
+func TestPublishEndorsement(t *testing.T) {
+	for _, tamper := range []bool{false, true} {
+		server := newFakeRekor(t, tamper)
+		defer server.Close()
+
+		verOpts := pb.VerificationOptions{}
+		digests := map[string]string{"sha2-256": binaryDigest}
+		statement, err := GenerateEndorsement(binaryName, digests, &verOpts, createClaimValidity(7), []ParsedProvenance{})
+		if err != nil {
+			t.Fatalf("Failed to generate endorsement: %v", err)
+		}
+		_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatalf("Failed to generate key: %v", err)
+		}
+		signer := &ed25519Signer{privateKey: privateKey}
+		ctx := context.Background()
+		envelope, err := SignStatement(ctx, statement, signer)
+		if err != nil {
+			t.Fatalf("Failed to sign endorsement: %v", err)
+		}
+
+		publicKeyDER, err := x509.MarshalPKIXPublicKey(privateKey.Public())
+		if err != nil {
+			t.Fatalf("Failed to marshal the public key: %v", err)
+		}
+		publicKeyPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKeyDER})
+
+		entry, err := PublishEndorsement(ctx, rekor.NewClient(server.URL), envelope, signer, publicKeyPEM)
+		if tamper {
+			if err == nil {
+				t.Errorf("Expected an error for a log entry not matching the endorsement")
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Failed to publish endorsement: %v", err)
+		}
+		testutil.AssertEq(t, "uuid", entry.UUID, "some-uuid")
+	}
+}
+
 // copyToTemp creates a copy of the given file in `/tmp`.
 // This is used for creating URLs with `file` as the scheme.
 func copyToTemp(path string) (string, error) {
@@ -176,3 +604,71 @@ func copyToTemp(path string) (string, error) {
 
 	return tmpfile.Name(), nil
 }
+
+func TestCopyProvenancesToEnt(t *testing.T) {
+	var uploads [][]byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut || r.URL.Path != "/raw" || r.Header.Get("x-api-key") != "key" {
+			http.NotFound(w, r)
+			return
+		}
+		content, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		uploads = append(uploads, content)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	provenances := createProvenanceList(t, []string{provenancePath})
+	want, err := os.ReadFile(provenancePath)
+	if err != nil {
+		t.Fatalf("could not read the provenance: %v", err)
+	}
+	client := ent.NewClient(server.URL, "key")
+	if err := CopyProvenancesToEnt(context.Background(), client, provenances); err != nil {
+		t.Fatalf("could not copy the provenances: %v", err)
+	}
+	testutil.AssertEq(t, "uri", provenances[0].SourceMetadata.URI, ent.URI(provenances[0].SourceMetadata.SHA256Digest))
+	if len(uploads) != 1 || string(uploads[0]) != string(want) {
+		t.Fatalf("unexpected uploads: got %d", len(uploads))
+	}
+
+	// Provenances with Ent URIs are not uploaded again.
+	if err := CopyProvenancesToEnt(context.Background(), client, provenances); err != nil {
+		t.Fatalf("could not copy the provenances again: %v", err)
+	}
+	testutil.AssertEq(t, "number of uploads", len(uploads), 1)
+}
+
+func TestLoadProvenances_FetchRetries(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			http.Error(w, "unavailable", http.StatusBadGateway)
+			return
+		}
+		http.ServeFile(w, r, provenancePath)
+	}))
+	defer server.Close()
+	uri := server.URL + "/provenance.json"
+
+	if _, err := LoadProvenances(context.Background(), []string{uri}); err == nil {
+		t.Fatalf("expected an error without retries")
+	}
+	requests = 0
+	provenances, err := LoadProvenances(context.Background(), []string{uri}, WithFetchRetries(2, time.Millisecond))
+	if err != nil {
+		t.Fatalf("could not load the provenance with retries: %v", err)
+	}
+	testutil.AssertEq(t, "provenances", len(provenances), 1)
+	testutil.AssertEq(t, "requests", requests, 2)
+
+	_, err = LoadProvenances(context.Background(), []string{uri}, WithMaxProvenanceBytes(16))
+	if err == nil || !strings.Contains(err.Error(), "exceeds the maximum size") {
+		t.Errorf("expected a size limit error, got: %v", err)
+	}
+}
@@ -0,0 +1,296 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package endorser
+
+import (
+	"context"
+	"crypto/sha1" //nolint:gosec
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"go.uber.org/multierr"
+
+	"github.com/project-oak/transparent-release/internal/fetch"
+	"github.com/project-oak/transparent-release/internal/model"
+	"github.com/project-oak/transparent-release/pkg/claims"
+	"github.com/project-oak/transparent-release/pkg/intoto"
+)
+
+// evidenceHashes maps the canonical names of the supported digest algorithms
+// to constructors of their hashes.
+//
+//nolint:gochecknoglobals
+var evidenceHashes = map[string]func() hash.Hash{
+	"sha1":     sha1.New,
+	"sha2-256": sha256.New,
+	"sha2-384": sha512.New384,
+	"sha2-512": sha512.New,
+}
+
+// reservedEvidenceRoles are the roles of the evidence that the endorser adds
+// itself, which auxiliary evidence must not take.
+//
+//nolint:gochecknoglobals
+var reservedEvidenceRoles = map[string]bool{
+	claims.ProvenanceRole:  true,
+	ReferenceValuesRole:    true,
+	VerificationReportRole: true,
+	CallerIdentityRole:     true,
+	PredecessorRole:        true,
+}
+
+// EvidenceConfig holds optional settings for VerifyEvidence and
+// LoadAuxiliaryEvidence.
+type EvidenceConfig struct {
+	registry    *fetch.Registry
+	evidenceDir string
+	bundleDir   string
+}
+
+// WithFetchRegistry sets the registry for fetching the evidence. Defaults to
+// fetch.Default().
+func WithFetchRegistry(registry *fetch.Registry) func(c *EvidenceConfig) {
+	return func(c *EvidenceConfig) {
+		c.registry = registry
+	}
+}
+
+// WithEvidenceDir verifies the evidence offline, reading it from the given
+// directory of pre-fetched files instead of fetching it from its URI. Each
+// evidence is read from the file named by its hex-encoded SHA2-256 digest if
+// there is one, and from the file named by the last element of its URI
+// otherwise.
+func WithEvidenceDir(dir string) func(c *EvidenceConfig) {
+	return func(c *EvidenceConfig) {
+		c.evidenceDir = dir
+	}
+}
+
+// WithBundleDir reads the evidence with bundle-relative URIs, as written by
+// PinEvidence, from the endorsement bundle in the given directory. Other
+// evidence is loaded as before.
+func WithBundleDir(dir string) func(c *EvidenceConfig) {
+	return func(c *EvidenceConfig) {
+		c.bundleDir = dir
+	}
+}
+
+// VerifyEvidence resolves the URI of every evidence in the given endorsement,
+// and checks that the content matches all the digests recorded for it with
+// supported algorithms. The content of caller identity evidence is its URI;
+// see CallerEvidence. Every evidence must have at least one such digest.
+// Returns the errors of all evidence that could not be verified.
+func VerifyEvidence(ctx context.Context, endorsement *intoto.Statement, options ...func(c *EvidenceConfig)) error {
+	config := &EvidenceConfig{registry: fetch.Default()}
+	for _, addOption := range options {
+		addOption(config)
+	}
+
+	predicate, err := claims.ValidateClaim(*endorsement)
+	if err != nil {
+		return fmt.Errorf("invalid endorsement: %v", err)
+	}
+	var errs error
+	for _, evidence := range predicate.Evidence {
+		if err := verifyEvidence(ctx, evidence, config); err != nil {
+			errs = multierr.Append(errs, err)
+		}
+	}
+	return errs
+}
+
+// LoadEvidence loads the content of the given evidence, as in VerifyEvidence,
+// and checks that it matches all the digests recorded for it with supported
+// algorithms.
+func LoadEvidence(ctx context.Context, evidence claims.ClaimEvidence, options ...func(c *EvidenceConfig)) ([]byte, error) {
+	config := &EvidenceConfig{registry: fetch.Default()}
+	for _, addOption := range options {
+		addOption(config)
+	}
+	return loadEvidence(ctx, evidence, config)
+}
+
+func verifyEvidence(ctx context.Context, evidence claims.ClaimEvidence, config *EvidenceConfig) error {
+	_, err := loadEvidence(ctx, evidence, config)
+	return err
+}
+
+// loadEvidence loads the content of the given evidence, and checks that it
+// matches all the digests recorded for it with supported algorithms.
+func loadEvidence(ctx context.Context, evidence claims.ClaimEvidence, config *EvidenceConfig) ([]byte, error) {
+	digests, err := verifiableDigests(evidence)
+	if err != nil {
+		return nil, err
+	}
+
+	var content []byte
+	parsed, parseErr := url.Parse(evidence.URI)
+	switch {
+	case parseErr == nil && parsed.Scheme == BundleURIScheme:
+		content, err = readBundledEvidence(config.bundleDir, parsed)
+	case evidence.Role == CallerIdentityRole:
+		// Caller identity evidence cannot be fetched; its content is its URI.
+		// See CallerEvidence.
+		content = []byte(evidence.URI)
+	case config.evidenceDir != "":
+		content, err = readPrefetchedEvidence(config.evidenceDir, evidence.URI, digests)
+	default:
+		content, err = config.registry.Fetch(ctx, evidence.URI)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("couldn't load the evidence %s: %v", evidence.URI, err)
+	}
+
+	for algorithm, want := range digests {
+		h := evidenceHashes[algorithm]()
+		h.Write(content)
+		if got := hex.EncodeToString(h.Sum(nil)); got != want {
+			return nil, fmt.Errorf("digest mismatch for %s: got %s:%s, want %s:%s", evidence.URI, algorithm, got, algorithm, want)
+		}
+	}
+	return content, nil
+}
+
+// verifiableDigests returns the digests of the given evidence with algorithms
+// in evidenceHashes, keyed by their canonical names, or an error if there are
+// none.
+func verifiableDigests(evidence claims.ClaimEvidence) (intoto.DigestSet, error) {
+	normalized, err := model.NormalizeDigestSet(evidence.Digest)
+	if err != nil {
+		return nil, fmt.Errorf("invalid digests of %s: %v", evidence.URI, err)
+	}
+	digests := make(intoto.DigestSet)
+	for algorithm, digest := range normalized {
+		if _, ok := evidenceHashes[algorithm]; ok {
+			digests[algorithm] = digest
+		}
+	}
+	if len(digests) == 0 {
+		return nil, fmt.Errorf("no sha1, sha256, sha384, or sha512 digest of %s in the evidence", evidence.URI)
+	}
+	return digests, nil
+}
+
+// readPrefetchedEvidence reads the evidence with the given URI and digests
+// from the given directory; see WithEvidenceDir.
+func readPrefetchedEvidence(dir, uri string, digests intoto.DigestSet) ([]byte, error) {
+	if digest, ok := digests["sha2-256"]; ok {
+		content, err := os.ReadFile(filepath.Join(dir, digest))
+		if err == nil {
+			return content, nil
+		}
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse the URI: %v", err)
+	}
+	name := path.Base(parsed.Path)
+	if name == "." || name == ".." || name == "/" {
+		return nil, fmt.Errorf("no file name in the URI")
+	}
+	return os.ReadFile(filepath.Join(dir, name))
+}
+
+// ParseEvidenceSpec parses the specification of auxiliary evidence, of the
+// form `uri=<URI>,role=<role>,digest=<algorithm>:<hex>`. The digest may be
+// repeated for several algorithms, named as in in-toto (e.g., `sha256`) or
+// canonically (e.g., `sha2-256`), and must be verifiable by the endorser, i.e.,
+// SHA1, SHA256, SHA384, or SHA512 digests. The role is required, and must not
+// be one of the roles of the evidence that the endorser adds itself, e.g.,
+// provenances. The digests of the result are keyed as in in-toto.
+func ParseEvidenceSpec(spec string) (*claims.ClaimEvidence, error) {
+	evidence := &claims.ClaimEvidence{Digest: make(intoto.DigestSet)}
+	for _, field := range strings.Split(spec, ",") {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid field %q in evidence %q, want key=value", field, spec)
+		}
+		switch key {
+		case "uri":
+			evidence.URI = value
+		case "role":
+			evidence.Role = value
+		case "digest":
+			algorithm, digest, ok := strings.Cut(value, ":")
+			if !ok || algorithm == "" || digest == "" {
+				return nil, fmt.Errorf("invalid digest %q in evidence %q, want algorithm:hex", value, spec)
+			}
+			if _, ok := evidence.Digest[algorithm]; ok {
+				return nil, fmt.Errorf("duplicate %s digest in evidence %q", algorithm, spec)
+			}
+			evidence.Digest[algorithm] = strings.ToLower(digest)
+		default:
+			return nil, fmt.Errorf("unknown field %q in evidence %q", key, spec)
+		}
+	}
+	if evidence.URI == "" || evidence.Role == "" || len(evidence.Digest) == 0 {
+		return nil, fmt.Errorf("evidence %q must have a uri, a role, and at least one digest", spec)
+	}
+	if reservedEvidenceRoles[evidence.Role] {
+		return nil, fmt.Errorf("role %q of evidence %q is reserved for evidence added by the endorser", evidence.Role, spec)
+	}
+	canonical, err := intoto.ConvertDigestSet(evidence.Digest, intoto.CanonicalDigestKeys)
+	if err != nil {
+		return nil, fmt.Errorf("invalid digests in evidence %q: %v", spec, err)
+	}
+	for algorithm := range canonical {
+		if _, ok := evidenceHashes[algorithm]; !ok {
+			return nil, fmt.Errorf("unsupported digest algorithm %q in evidence %q, want sha1, sha256, sha384, or sha512", algorithm, spec)
+		}
+	}
+	digests, err := intoto.ConvertDigestSet(evidence.Digest, intoto.InTotoDigestKeys)
+	if err != nil {
+		return nil, fmt.Errorf("invalid digests in evidence %q: %v", spec, err)
+	}
+	evidence.Digest = digests
+	return evidence, nil
+}
+
+// LoadAuxiliaryEvidence parses the given specifications of auxiliary
+// evidence, e.g., test logs, review approvals, or scanner reports, as in
+// ParseEvidenceSpec, and checks that the content of each evidence matches its
+// digests. The endorser cannot interpret auxiliary evidence, but attaches it
+// to the endorsement, so that it is covered by the signature.
+func LoadAuxiliaryEvidence(ctx context.Context, specs []string, options ...func(c *EvidenceConfig)) ([]claims.ClaimEvidence, error) {
+	config := &EvidenceConfig{registry: fetch.Default()}
+	for _, addOption := range options {
+		addOption(config)
+	}
+
+	evidence := make([]claims.ClaimEvidence, 0, len(specs))
+	for _, spec := range specs {
+		parsed, err := ParseEvidenceSpec(spec)
+		if err != nil {
+			return nil, err
+		}
+		if err := verifyEvidence(ctx, *parsed, config); err != nil {
+			return nil, err
+		}
+		evidence = append(evidence, *parsed)
+	}
+	return evidence, nil
+}
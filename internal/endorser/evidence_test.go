@@ -0,0 +1,182 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package endorser
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/project-oak/transparent-release/pkg/claims"
+	"github.com/project-oak/transparent-release/pkg/intoto"
+)
+
+const evidenceContent = "evidence"
+
+// newEvidenceEndorsement returns an endorsement with the given evidence.
+func newEvidenceEndorsement(evidence ...claims.ClaimEvidence) *intoto.Statement {
+	notBefore := time.Now().AddDate(0, 0, 1)
+	notAfter := notBefore.AddDate(0, 0, 7)
+	statement := claims.GenerateEndorsementStatement(claims.ClaimValidity{NotBefore: &notBefore, NotAfter: &notAfter}, claims.VerifiedProvenanceSet{
+		BinaryName: binaryName,
+		Digests:    intoto.DigestSet{"sha2-256": binaryDigest},
+	})
+	predicate := statement.Predicate.(claims.ClaimPredicate)
+	predicate.Evidence = evidence
+	statement.Predicate = predicate
+	return statement
+}
+
+func evidenceDigests() intoto.DigestSet {
+	sum256 := sha256.Sum256([]byte(evidenceContent))
+	sum512 := sha512.Sum512([]byte(evidenceContent))
+	return intoto.DigestSet{"sha256": hex.EncodeToString(sum256[:]), "sha2-512": hex.EncodeToString(sum512[:])}
+}
+
+func TestVerifyEvidence(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(evidenceContent))
+	}))
+	defer server.Close()
+	path := filepath.Join(t.TempDir(), "evidence.json")
+	if err := os.WriteFile(path, []byte(evidenceContent), 0o600); err != nil {
+		t.Fatalf("could not write the evidence: %v", err)
+	}
+
+	endorsement := newEvidenceEndorsement(
+		claims.ClaimEvidence{Role: "Log", URI: server.URL + "/evidence.json", Digest: evidenceDigests()},
+		claims.ClaimEvidence{Role: claims.ProvenanceRole, URI: "file://" + path, Digest: evidenceDigests()},
+	)
+	if err := VerifyEvidence(context.Background(), endorsement); err != nil {
+		t.Fatalf("could not verify the evidence: %v", err)
+	}
+}
+
+func TestVerifyEvidence_Failures(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "evidence.json")
+	if err := os.WriteFile(path, []byte("other evidence"), 0o600); err != nil {
+		t.Fatalf("could not write the evidence: %v", err)
+	}
+
+	for name, evidence := range map[string]claims.ClaimEvidence{
+		"digest mismatch":    {URI: "file://" + path, Digest: evidenceDigests()},
+		"no digest":          {URI: "file://" + path, Digest: intoto.DigestSet{"md5": "abc"}},
+		"missing evidence":   {URI: "file:///does/not/exist.json", Digest: evidenceDigests()},
+		"unsupported scheme": {URI: "ftp://example.com/evidence.json", Digest: evidenceDigests()},
+	} {
+		if err := VerifyEvidence(context.Background(), newEvidenceEndorsement(evidence)); err == nil {
+			t.Errorf("%s: expected failure", name)
+		}
+	}
+}
+
+func TestVerifyEvidence_UnverifiableAlgorithm(t *testing.T) {
+	// SHA3 digests are supported in provenances, but not for evidence.
+	evidence := claims.ClaimEvidence{URI: "https://example.invalid/log.json", Digest: intoto.DigestSet{"sha3_256": strings.Repeat("ab", 32)}}
+	if err := VerifyEvidence(context.Background(), newEvidenceEndorsement(evidence)); err == nil {
+		t.Errorf("expected failure for evidence without a verifiable digest")
+	}
+}
+
+func TestParseEvidenceSpec(t *testing.T) {
+	digests := evidenceDigests()
+	got, err := ParseEvidenceSpec("uri=https://example.com/test.log,role=Test log,digest=sha2-256:" + digests["sha256"] + ",digest=sha512:" + digests["sha2-512"])
+	if err != nil {
+		t.Fatalf("could not parse the evidence: %v", err)
+	}
+	want := &claims.ClaimEvidence{
+		Role:   "Test log",
+		URI:    "https://example.com/test.log",
+		Digest: intoto.DigestSet{"sha256": digests["sha256"], "sha512": digests["sha2-512"]},
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("unexpected evidence: %s", diff)
+	}
+
+	for name, spec := range map[string]string{
+		"no role":           "uri=https://example.com/test.log,digest=sha256:" + digests["sha256"],
+		"no digest":         "uri=https://example.com/test.log,role=Test log",
+		"reserved role":     "uri=https://example.com/test.log,role=Provenance,digest=sha256:" + digests["sha256"],
+		"predecessor role":  "uri=https://example.com/test.log,role=Predecessor endorsement,digest=sha256:" + digests["sha256"],
+		"unknown field":     "uri=https://example.com/test.log,role=Test log,digest=sha256:" + digests["sha256"] + ",name=log",
+		"invalid digest":    "uri=https://example.com/test.log,role=Test log,digest=" + digests["sha256"],
+		"duplicate digest":  "uri=https://example.com/test.log,role=Test log,digest=sha256:" + digests["sha256"] + ",digest=sha256:" + digests["sha256"],
+		"unverifiable":      "uri=https://example.com/test.log,role=Test log,digest=md5:abc",
+		"some unverifiable": "uri=https://example.com/test.log,role=Test log,digest=sha256:" + digests["sha256"] + ",digest=sha3-256:" + digests["sha256"],
+	} {
+		if _, err := ParseEvidenceSpec(spec); err == nil {
+			t.Errorf("%s: expected failure", name)
+		}
+	}
+}
+
+func TestLoadAuxiliaryEvidence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.log")
+	if err := os.WriteFile(path, []byte(evidenceContent), 0o600); err != nil {
+		t.Fatalf("could not write the evidence: %v", err)
+	}
+	spec := "uri=file://" + path + ",role=Test log,digest=sha256:" + evidenceDigests()["sha256"]
+
+	evidence, err := LoadAuxiliaryEvidence(context.Background(), []string{spec})
+	if err != nil {
+		t.Fatalf("could not load the evidence: %v", err)
+	}
+	if len(evidence) != 1 || evidence[0].Role != "Test log" {
+		t.Errorf("unexpected evidence: %v", evidence)
+	}
+
+	if err := os.WriteFile(path, []byte("other evidence"), 0o600); err != nil {
+		t.Fatalf("could not write the evidence: %v", err)
+	}
+	if _, err := LoadAuxiliaryEvidence(context.Background(), []string{spec}); err == nil {
+		t.Errorf("expected failure for evidence with a different digest")
+	}
+}
+
+func TestVerifyEvidence_Offline(t *testing.T) {
+	dir := t.TempDir()
+	digests := evidenceDigests()
+	// One evidence is stored under its digest, the other under its file name.
+	if err := os.WriteFile(filepath.Join(dir, digests["sha256"]), []byte(evidenceContent), 0o600); err != nil {
+		t.Fatalf("could not write the evidence: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "log.json"), []byte(evidenceContent), 0o600); err != nil {
+		t.Fatalf("could not write the evidence: %v", err)
+	}
+
+	endorsement := newEvidenceEndorsement(
+		// The URIs cannot be fetched.
+		claims.ClaimEvidence{URI: "https://example.invalid/provenance.json", Digest: digests},
+		claims.ClaimEvidence{URI: "gs://bucket/path/log.json", Digest: intoto.DigestSet{"sha2-512": digests["sha2-512"]}},
+	)
+	if err := VerifyEvidence(context.Background(), endorsement, WithEvidenceDir(dir)); err != nil {
+		t.Fatalf("could not verify the evidence offline: %v", err)
+	}
+
+	missing := newEvidenceEndorsement(claims.ClaimEvidence{URI: "https://example.invalid/other.json", Digest: intoto.DigestSet{"sha2-512": digests["sha2-512"]}})
+	if err := VerifyEvidence(context.Background(), missing, WithEvidenceDir(dir)); err == nil {
+		t.Errorf("expected failure for evidence missing from the directory")
+	}
+}
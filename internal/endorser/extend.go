@@ -0,0 +1,174 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package endorser
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/secure-systems-lab/go-securesystemslib/dsse"
+	"go.uber.org/multierr"
+
+	"github.com/project-oak/transparent-release/internal/fetch"
+	"github.com/project-oak/transparent-release/internal/model"
+	"github.com/project-oak/transparent-release/pkg/claims"
+	"github.com/project-oak/transparent-release/pkg/intoto"
+	pb "github.com/project-oak/transparent-release/pkg/proto/oak/release"
+)
+
+// PredecessorRole is the role of the evidence referencing the endorsement
+// that an extended endorsement renews.
+const PredecessorRole = "Predecessor endorsement"
+
+// ExtendEndorsement re-verifies the given predecessor endorsement, and
+// generates a fresh endorsement of the same subject, with the given validity,
+// which must end after the validity of the predecessor. predecessorURI and
+// predecessorBytes are the location and content of the predecessor, which
+// must be a DSSE envelope signed with the given endorser key, and reference
+// at least one provenance as evidence. All evidence of the predecessor must
+// match its digests, and the provenances must be for the subject of the
+// predecessor, and satisfy the given VerificationOptions. The fresh
+// endorsement keeps the predicate type, claim type, claim spec, and evidence
+// of the predecessor, and references the predecessor as evidence, replacing
+// the reference to its own predecessor, if any, so that rolling renewals form
+// a chain. The evidence options are used for re-verifying the evidence, and
+// the EndorsementConfig options for setting, e.g., the clock of the fresh
+// endorsement.
+func ExtendEndorsement(ctx context.Context, predecessorURI string, predecessorBytes []byte, endorserKey dsse.Verifier, verOpts *pb.VerificationOptions, validity claims.ClaimValidity, evidenceOptions []func(c *EvidenceConfig), options ...func(c *claims.EndorsementConfig)) (*intoto.Statement, error) {
+	var envelope dsse.Envelope
+	if err := json.Unmarshal(predecessorBytes, &envelope); err != nil || envelope.PayloadType == "" {
+		return nil, fmt.Errorf("the predecessor endorsement is not a DSSE envelope")
+	}
+	if err := verifyEnvelope(ctx, &envelope, endorserKey); err != nil {
+		return nil, fmt.Errorf("could not verify the signature of the predecessor endorsement: %v", err)
+	}
+	payload, err := endorsementPayload(predecessorBytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid predecessor: %v", err)
+	}
+	predecessor, err := claims.ParseEndorsementV2Bytes(payload)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse the predecessor endorsement: %v", err)
+	}
+	predicate := predecessor.Predicate.(claims.ClaimPredicate)
+	if predicate.Validity == nil || predicate.Validity.NotAfter == nil || validity.NotAfter == nil ||
+		!validity.NotAfter.After(*predicate.Validity.NotAfter) {
+		return nil, fmt.Errorf("the validity of the extended endorsement must end after the validity of the predecessor")
+	}
+	if err := verifyPredecessorEvidence(ctx, predecessor, verOpts, evidenceOptions); err != nil {
+		return nil, fmt.Errorf("could not re-verify the evidence of the predecessor endorsement: %v", err)
+	}
+
+	sum256 := sha256.Sum256(predecessorBytes)
+	evidence := make([]claims.ClaimEvidence, 0, len(predicate.Evidence)+1)
+	for _, e := range predicate.Evidence {
+		if e.Role != PredecessorRole {
+			evidence = append(evidence, e)
+		}
+	}
+	evidence = append(evidence, claims.ClaimEvidence{
+		Role:   PredecessorRole,
+		URI:    predecessorURI,
+		Digest: intoto.DigestSet{"sha256": hex.EncodeToString(sum256[:])},
+	})
+
+	// The format and content of the predecessor take precedence over the
+	// given options.
+	subject := predecessor.Subject[0]
+	options = append(options,
+		claims.WithStatementType(predecessor.Type),
+		claims.WithPredicateType(predecessor.PredicateType),
+		claims.WithClaimType(predicate.ClaimType),
+		claims.WithClaimSpec(predicate.ClaimSpec),
+		claims.WithSubjectMediaType(subject.MediaType),
+		claims.WithEvidence(evidence...))
+	config, err := claims.NewEndorsementConfig(options...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid endorsement config: %v", err)
+	}
+	verifiedProvenances := claims.VerifiedProvenanceSet{
+		BinaryName: subject.Name,
+		Digests:    subject.Digest,
+	}
+	return claims.GenerateEndorsementStatementWithConfig(config, validity, verifiedProvenances)
+}
+
+// verifyPredecessorEvidence checks that all evidence of the given
+// endorsement matches its digests, and verifies the provenances among the
+// evidence, of which there must be at least one, against the subject of the
+// endorsement and the given VerificationOptions.
+func verifyPredecessorEvidence(ctx context.Context, endorsement *intoto.Statement, verOpts *pb.VerificationOptions, evidenceOptions []func(c *EvidenceConfig)) error {
+	config := &EvidenceConfig{registry: fetch.Default()}
+	for _, addOption := range evidenceOptions {
+		addOption(config)
+	}
+	subject := endorsement.Subject[0]
+	subjectDigests, err := model.NormalizeDigestSet(subject.Digest)
+	if err != nil {
+		return fmt.Errorf("invalid digests of the endorsed binary: %v", err)
+	}
+	loadConfig := &LoadConfig{subjectName: subject.Name, subjectDigests: subjectDigests}
+
+	var errs error
+	var provenances []ParsedProvenance
+	for _, evidence := range endorsement.Predicate.(claims.ClaimPredicate).Evidence {
+		content, err := loadEvidence(ctx, evidence, config)
+		if err != nil {
+			errs = multierr.Append(errs, err)
+			continue
+		}
+		if evidence.Role != claims.ProvenanceRole {
+			continue
+		}
+		provenance, err := parseProvenance(ctx, evidence.URI, content, loadConfig)
+		if err != nil {
+			errs = multierr.Append(errs, err)
+			continue
+		}
+		provenances = append(provenances, *provenance)
+	}
+	if errs != nil {
+		return errs
+	}
+	if len(provenances) == 0 {
+		return fmt.Errorf("the endorsement does not reference any provenances")
+	}
+	_, err = VerifyProvenances(subject.Name, subjectDigests, verOpts, provenances)
+	return err
+}
+
+// endorsementPayload returns the statement in the given bytes, which are
+// either the statement itself, or a DSSE envelope with the statement as its
+// payload.
+func endorsementPayload(endorsementBytes []byte) ([]byte, error) {
+	var envelope dsse.Envelope
+	if err := json.Unmarshal(endorsementBytes, &envelope); err != nil {
+		return nil, fmt.Errorf("could not unmarshal the endorsement: %v", err)
+	}
+	if envelope.PayloadType == "" {
+		return endorsementBytes, nil
+	}
+	if envelope.PayloadType != intoto.PayloadType {
+		return nil, fmt.Errorf("unexpected payload type of the endorsement: %q", envelope.PayloadType)
+	}
+	payload, err := envelope.DecodeB64Payload()
+	if err != nil {
+		return nil, fmt.Errorf("could not decode the endorsement: %v", err)
+	}
+	return payload, nil
+}
@@ -0,0 +1,129 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package endorser
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/project-oak/transparent-release/internal/testutil"
+	"github.com/project-oak/transparent-release/pkg/claims"
+	"github.com/project-oak/transparent-release/pkg/intoto"
+	pb "github.com/project-oak/transparent-release/pkg/proto/oak/release"
+)
+
+// signEndorsement signs the given endorsement with the given signer, and
+// returns the DSSE envelope as JSON.
+func signEndorsement(t *testing.T, endorsement *intoto.Statement, signer *ed25519Signer) []byte {
+	envelope, err := SignStatement(context.Background(), endorsement, signer)
+	if err != nil {
+		t.Fatalf("could not sign the endorsement: %v", err)
+	}
+	envelopeBytes, err := json.Marshal(envelope)
+	if err != nil {
+		t.Fatalf("could not marshal the envelope: %v", err)
+	}
+	return envelopeBytes
+}
+
+func TestExtendEndorsement(t *testing.T) {
+	signer := newTestSigner(t)
+	predecessor, provenances := newChainEndorsement(t, time.Now())
+	predecessorBytes := signEndorsement(t, predecessor, signer)
+	predecessorPath := filepath.Join(t.TempDir(), "endorsement.dsse.json")
+	if err := os.WriteFile(predecessorPath, predecessorBytes, 0o600); err != nil {
+		t.Fatalf("could not write the predecessor: %v", err)
+	}
+	verOpts := &pb.VerificationOptions{ProvenanceCountAtLeast: &pb.VerifyProvenanceCountAtLeast{Count: 1}}
+
+	issuedOn := time.Now().AddDate(0, 0, 6)
+	notAfter := issuedOn.AddDate(0, 0, 30)
+	validity := claims.ClaimValidity{NotBefore: &issuedOn, NotAfter: &notAfter}
+	extended, err := ExtendEndorsement(context.Background(), "file://"+predecessorPath, predecessorBytes, signer, verOpts, validity, nil,
+		claims.WithClock(claims.FixedClock(issuedOn)))
+	if err != nil {
+		t.Fatalf("could not extend the endorsement: %v", err)
+	}
+
+	testutil.AssertEq(t, "subject digest", extended.Subject[0].Digest["sha2-256"], binaryDigest)
+	predicate := extended.Predicate.(claims.ClaimPredicate)
+	testutil.AssertEq(t, "claim type", predicate.ClaimType, claims.EndorsementV2)
+	testutil.AssertEq(t, "not after", *predicate.Validity.NotAfter, notAfter)
+	testutil.AssertEq(t, "evidence count", len(predicate.Evidence), 2)
+	testutil.AssertEq(t, "provenance URI", predicate.Evidence[0].URI, provenances[0].SourceMetadata.URI)
+	sum256 := sha256.Sum256(predecessorBytes)
+	testutil.AssertEq(t, "predecessor role", predicate.Evidence[1].Role, PredecessorRole)
+	testutil.AssertEq(t, "predecessor digest", predicate.Evidence[1].Digest["sha256"], hex.EncodeToString(sum256[:]))
+
+	// Extending the extended endorsement also re-verifies its predecessor,
+	// and only references its own predecessor.
+	laterNotAfter := notAfter.AddDate(0, 0, 30)
+	renewed, err := ExtendEndorsement(context.Background(), "file:///endorsements/new.json", signEndorsement(t, extended, signer), signer, verOpts,
+		claims.ClaimValidity{NotBefore: &notAfter, NotAfter: &laterNotAfter}, nil)
+	if err != nil {
+		t.Fatalf("could not extend the extended endorsement: %v", err)
+	}
+	evidence := renewed.Predicate.(claims.ClaimPredicate).Evidence
+	testutil.AssertEq(t, "evidence count", len(evidence), 2)
+	testutil.AssertEq(t, "predecessor URI", evidence[1].URI, "file:///endorsements/new.json")
+}
+
+func TestExtendEndorsement_Failures(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "evidence.json")
+	if err := os.WriteFile(path, []byte("other evidence"), 0o600); err != nil {
+		t.Fatalf("could not write the evidence: %v", err)
+	}
+	signer := newTestSigner(t)
+	longer := time.Now().AddDate(0, 0, 60)
+	shorter := time.Now().AddDate(0, 0, 2)
+	endorsement, _ := newChainEndorsement(t, time.Now())
+	unsigned, err := json.Marshal(endorsement)
+	if err != nil {
+		t.Fatalf("could not marshal the endorsement: %v", err)
+	}
+	// An endorsement whose only evidence is not a provenance.
+	log := filepath.Join(t.TempDir(), "log.txt")
+	if err := os.WriteFile(log, []byte(evidenceContent), 0o600); err != nil {
+		t.Fatalf("could not write the evidence: %v", err)
+	}
+	withoutProvenance := newEvidenceEndorsement(claims.ClaimEvidence{Role: "Log", URI: "file://" + log, Digest: evidenceDigests()})
+
+	tests := map[string]struct {
+		predecessor []byte
+		verOpts     *pb.VerificationOptions
+		notAfter    time.Time
+	}{
+		"unsigned":            {unsigned, &pb.VerificationOptions{}, longer},
+		"other key":           {signEndorsement(t, endorsement, newTestSigner(t)), &pb.VerificationOptions{}, longer},
+		"tampered evidence":   {signEndorsement(t, newEvidenceEndorsement(claims.ClaimEvidence{Role: claims.ProvenanceRole, URI: "file://" + path, Digest: evidenceDigests()}), signer), &pb.VerificationOptions{}, longer},
+		"no provenance":       {signEndorsement(t, withoutProvenance, signer), &pb.VerificationOptions{}, longer},
+		"no evidence":         {signEndorsement(t, newEvidenceEndorsement(), signer), &pb.VerificationOptions{}, longer},
+		"failed verification": {signEndorsement(t, endorsement, signer), &pb.VerificationOptions{ProvenanceCountAtLeast: &pb.VerifyProvenanceCountAtLeast{Count: 2}}, longer},
+		"shorter validity":    {signEndorsement(t, endorsement, signer), &pb.VerificationOptions{}, shorter},
+	}
+	for name, test := range tests {
+		notBefore := time.Now()
+		validity := claims.ClaimValidity{NotBefore: &notBefore, NotAfter: &test.notAfter}
+		if _, err := ExtendEndorsement(context.Background(), "file:///old.json", test.predecessor, signer, test.verOpts, validity, nil); err == nil {
+			t.Errorf("%s: expected failure", name)
+		}
+	}
+}
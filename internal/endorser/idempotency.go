@@ -0,0 +1,204 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package endorser
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/project-oak/transparent-release/internal/fetch"
+)
+
+// DefaultIdempotencyTTL is how long a Server remembers the response to a
+// request with a request ID.
+const DefaultIdempotencyTTL = 24 * time.Hour
+
+// DefaultIdempotencyMaxEntries is the default maximum number of responses to
+// requests with a request ID that a Server remembers.
+const DefaultIdempotencyMaxEntries = 10000
+
+// idempotencyCache remembers the responses to requests with a request ID, so
+// that a retried request is answered with the endorsement issued for the
+// original request, instead of a new endorsement with a different timestamp.
+// When the cache is full, the oldest completed response is forgotten.
+type idempotencyCache struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	entries    map[string]*idempotencyEntry
+}
+
+// idempotencyEntry is the response to a request, which is pending until done
+// is closed.
+type idempotencyEntry struct {
+	fingerprint string
+	created     time.Time
+	done        chan struct{}
+	response    *EndorseResponse
+	err         error
+}
+
+func newIdempotencyCache(ttl time.Duration, maxEntries int) *idempotencyCache {
+	if maxEntries < 1 {
+		maxEntries = 1
+	}
+	return &idempotencyCache{ttl: ttl, maxEntries: maxEntries, entries: make(map[string]*idempotencyEntry)}
+}
+
+// requestFingerprint returns the hex-encoded SHA256 digest of the JSON
+// encoding of the given request, so that requests with the same ID but
+// different inputs can be told apart.
+func requestFingerprint(request *EndorseRequest) (string, error) {
+	bytes, err := json.Marshal(request)
+	if err != nil {
+		return "", fmt.Errorf("could not marshal the request: %v", err)
+	}
+	sum256 := sha256.Sum256(bytes)
+	return hex.EncodeToString(sum256[:]), nil
+}
+
+// do returns the response to the request with the given key and fingerprint.
+// If there is no such response yet, it calls issue, and remembers its
+// response, unless issue fails. Concurrent requests with the same key wait
+// for the first one. Returns an httpError with status 409 if the key has been
+// used for a request with a different fingerprint, and with status 503 if the
+// cache is full of pending requests.
+func (c *idempotencyCache) do(ctx context.Context, key, fingerprint string, now time.Time, issue func() (*EndorseResponse, error)) (*EndorseResponse, error) {
+	for {
+		c.mu.Lock()
+		c.removeExpired(now)
+		entry, ok := c.entries[key]
+		if !ok {
+			if len(c.entries) >= c.maxEntries && !c.removeOldest() {
+				c.mu.Unlock()
+				return nil, newHTTPError(http.StatusServiceUnavailable, "too many pending requests with a request ID")
+			}
+			entry = &idempotencyEntry{fingerprint: fingerprint, created: now, done: make(chan struct{})}
+			c.entries[key] = entry
+			c.mu.Unlock()
+			return c.issue(key, entry, issue)
+		}
+		c.mu.Unlock()
+
+		if entry.fingerprint != fingerprint {
+			return nil, newHTTPError(http.StatusConflict, "the request ID has already been used for a request with different inputs")
+		}
+		select {
+		case <-entry.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if entry.err == nil {
+			return entry.response, nil
+		}
+		// The original request failed, and has been removed, so that this
+		// request can be retried.
+	}
+}
+
+// issue calls issue for the given pending entry, and completes the entry.
+func (c *idempotencyCache) issue(key string, entry *idempotencyEntry, issue func() (*EndorseResponse, error)) (*EndorseResponse, error) {
+	response, err := issue()
+	c.mu.Lock()
+	entry.response, entry.err = response, err
+	if err != nil {
+		delete(c.entries, key)
+	}
+	c.mu.Unlock()
+	close(entry.done)
+	return response, err
+}
+
+// removeExpired removes the completed entries created more than the TTL
+// before now. Must be called with c.mu held.
+func (c *idempotencyCache) removeExpired(now time.Time) {
+	for key, entry := range c.entries {
+		select {
+		case <-entry.done:
+			if now.Sub(entry.created) > c.ttl {
+				delete(c.entries, key)
+			}
+		default:
+		}
+	}
+}
+
+// removeOldest removes the completed entry created first, and returns
+// whether there was such an entry. Must be called with c.mu held.
+func (c *idempotencyCache) removeOldest() bool {
+	var oldestKey string
+	var oldest *idempotencyEntry
+	for key, entry := range c.entries {
+		select {
+		case <-entry.done:
+			if oldest == nil || entry.created.Before(oldest.created) {
+				oldestKey, oldest = key, entry
+			}
+		default:
+		}
+	}
+	if oldest == nil {
+		return false
+	}
+	delete(c.entries, oldestKey)
+	return true
+}
+
+// transientError marks the failure of an external service, e.g., of the
+// signer, after which the operation may succeed when retried.
+type transientError struct {
+	err error
+}
+
+func (e *transientError) Error() string {
+	return e.err.Error()
+}
+
+func (e *transientError) Unwrap() error {
+	return e.err
+}
+
+// isTransient returns whether the given error is a transientError, or a
+// transient error of fetching, as reported by fetch.IsTransient.
+func isTransient(err error) bool {
+	var transientErr *transientError
+	return errors.As(err, &transientErr) || fetch.IsTransient(err)
+}
+
+// retry calls f up to the given number of attempts, until it succeeds or
+// fails with an error that is not transient, as reported by isTransient,
+// waiting for the given backoff before the first retry, and for twice as long
+// before every further retry. Returns the error of the last attempt.
+func retry(ctx context.Context, attempts int, backoff time.Duration, f func() error) error {
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = f(); err == nil || attempt >= attempts || !isTransient(err) {
+			return err
+		}
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return fmt.Errorf("%v (retrying: %v)", err, ctx.Err())
+		}
+		backoff *= 2
+	}
+}
@@ -0,0 +1,168 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package endorser
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/project-oak/transparent-release/internal/fetch"
+	"github.com/project-oak/transparent-release/internal/testutil"
+)
+
+func TestIdempotencyCache_ConcurrentRequests(t *testing.T) {
+	cache := newIdempotencyCache(time.Hour, DefaultIdempotencyMaxEntries)
+	now := time.Date(2023, 6, 1, 10, 0, 0, 0, time.UTC)
+	release := make(chan struct{})
+	var mu sync.Mutex
+	issued := 0
+	issue := func() (*EndorseResponse, error) {
+		<-release
+		mu.Lock()
+		defer mu.Unlock()
+		issued++
+		return &EndorseResponse{}, nil
+	}
+
+	var wg sync.WaitGroup
+	responses := make([]*EndorseResponse, 4)
+	for i := range responses {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			response, err := cache.do(context.Background(), "key", "fingerprint", now, issue)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			responses[i] = response
+		}(i)
+	}
+	close(release)
+	wg.Wait()
+
+	testutil.AssertEq(t, "issued endorsements", issued, 1)
+	for i, response := range responses {
+		if response != responses[0] {
+			t.Errorf("response #%d differs from the first response", i)
+		}
+	}
+}
+
+func TestIdempotencyCache_Expiry(t *testing.T) {
+	cache := newIdempotencyCache(time.Hour, DefaultIdempotencyMaxEntries)
+	now := time.Date(2023, 6, 1, 10, 0, 0, 0, time.UTC)
+	issued := 0
+	issue := func() (*EndorseResponse, error) {
+		issued++
+		return &EndorseResponse{}, nil
+	}
+
+	for _, at := range []time.Time{now, now.Add(time.Minute), now.Add(2 * time.Hour)} {
+		if _, err := cache.do(context.Background(), "key", "fingerprint", at, issue); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	testutil.AssertEq(t, "issued endorsements", issued, 2)
+
+	// A different request with the same key conflicts, until the key expires.
+	if _, err := cache.do(context.Background(), "key", "other", now.Add(2*time.Hour), issue); err == nil {
+		t.Errorf("expected a conflict")
+	}
+	if _, err := cache.do(context.Background(), "key", "other", now.Add(4*time.Hour), issue); err != nil {
+		t.Errorf("unexpected error after expiry: %v", err)
+	}
+}
+
+func TestIdempotencyCache_MaxEntries(t *testing.T) {
+	cache := newIdempotencyCache(time.Hour, 2)
+	now := time.Date(2023, 6, 1, 10, 0, 0, 0, time.UTC)
+	issued := 0
+	issue := func() (*EndorseResponse, error) {
+		issued++
+		return &EndorseResponse{}, nil
+	}
+
+	for i, key := range []string{"a", "b", "c", "b"} {
+		if _, err := cache.do(context.Background(), key, "fingerprint", now.Add(time.Duration(i)*time.Minute), issue); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	testutil.AssertEq(t, "issued endorsements", issued, 3)
+	testutil.AssertEq(t, "cached entries", len(cache.entries), 2)
+	if _, ok := cache.entries["a"]; ok {
+		t.Errorf("expected the oldest entry to be evicted")
+	}
+
+	// Pending entries are not evicted.
+	release := make(chan struct{})
+	full := newIdempotencyCache(time.Hour, 1)
+	started := make(chan struct{})
+	go func() {
+		_, _ = full.do(context.Background(), "pending", "fingerprint", now, func() (*EndorseResponse, error) {
+			close(started)
+			<-release
+			return &EndorseResponse{}, nil
+		})
+	}()
+	<-started
+	_, err := full.do(context.Background(), "other", "fingerprint", now, issue)
+	close(release)
+	if httpErr, ok := err.(*httpError); !ok || httpErr.status != http.StatusServiceUnavailable {
+		t.Errorf("expected status %d, got: %v", http.StatusServiceUnavailable, err)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	attempts := 0
+	err := retry(context.Background(), 3, time.Millisecond, func() error {
+		attempts++
+		return &transientError{err: fmt.Errorf("failure #%d", attempts)}
+	})
+	if err == nil || err.Error() != "failure #3" {
+		t.Errorf("expected the error of the last attempt, got: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	attempts = 0
+	if err := retry(ctx, 3, time.Hour, func() error {
+		attempts++
+		return &transientError{err: fmt.Errorf("failure")}
+	}); err == nil {
+		t.Errorf("expected an error")
+	}
+	testutil.AssertEq(t, "attempts with canceled context", attempts, 1)
+
+	// Errors that are not transient are not retried.
+	attempts = 0
+	if err := retry(context.Background(), 3, time.Millisecond, func() error {
+		attempts++
+		return &fetch.StatusError{URI: "https://example.com", StatusCode: http.StatusNotFound, Status: "404 Not Found"}
+	}); err == nil {
+		t.Errorf("expected an error")
+	}
+	testutil.AssertEq(t, "attempts with a permanent error", attempts, 1)
+
+	attempts = 0
+	_ = retry(context.Background(), 3, time.Millisecond, func() error {
+		attempts++
+		return fmt.Errorf("creating the log entry failed: %w", &fetch.StatusError{StatusCode: http.StatusServiceUnavailable})
+	})
+	testutil.AssertEq(t, "attempts with a wrapped transient error", attempts, 3)
+}
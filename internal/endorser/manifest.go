@@ -0,0 +1,146 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package endorser
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"go.uber.org/multierr"
+	"gopkg.in/yaml.v3"
+)
+
+// sha256HexPattern matches hex-encoded SHA2-256 digests.
+//
+//nolint:gochecknoglobals
+var sha256HexPattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// Manifest describes a batch of binaries to endorse in one invocation of the
+// endorser. Manifests are written in TOML, with one `[[binary]]` table per
+// binary, or in YAML, with a list of binaries under `binary`.
+type Manifest struct {
+	Binaries []ManifestEntry `toml:"binary" yaml:"binary"`
+}
+
+// ManifestEntry describes a single binary to endorse.
+type ManifestEntry struct {
+	// Name of the binary. Must match the binary names in all provenances, and
+	// be unique within the manifest. The endorsement is stored in a file named
+	// after the binary, so the name must not contain path separators or `..`.
+	Name string `toml:"name" yaml:"name"`
+	// Hex-encoded SHA2-256 digest of the binary. Exactly one of Digest and
+	// BinaryPath must be set.
+	Digest string `toml:"digest" yaml:"digest"`
+	// Location of the binary in the local file system, for computing its
+	// digests.
+	BinaryPath string `toml:"binary_path" yaml:"binary_path"`
+	// Optional name of the subject to select from provenances with several
+	// subjects. By default, the subject is selected by the digest.
+	SubjectName string `toml:"subject_name" yaml:"subject_name"`
+	// URIs of zero or more provenances of the binary.
+	ProvenanceURIs []string `toml:"provenance_uris" yaml:"provenance_uris"`
+	// VerificationOptions as textproto. Must be set, unless SkipVerification is set.
+	VerificationOptions string `toml:"verification_options" yaml:"verification_options"`
+	// Confirms that empty VerificationOptions are intended.
+	SkipVerification bool `toml:"skip_verification" yaml:"skip_verification"`
+	// Hex-encoded SHA2-256 digest of the reference values in the source
+	// repository of the provenances. Required for verifying the provenances
+	// against these reference values.
+	ReferenceValuesDigest string `toml:"reference_values_digest" yaml:"reference_values_digest"`
+}
+
+// LoadManifest reads and validates the manifest at the given path, parsed as
+// YAML if it has a `.yaml` or `.yml` extension, and as TOML otherwise.
+func LoadManifest(path string) (*Manifest, error) {
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading the manifest: %v", err)
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return ParseManifestYAML(bytes)
+	default:
+		return ParseManifest(bytes)
+	}
+}
+
+// ParseManifest parses and validates the given TOML manifest.
+func ParseManifest(bytes []byte) (*Manifest, error) {
+	var manifest Manifest
+	metadata, err := toml.Decode(string(bytes), &manifest)
+	if err != nil {
+		return nil, fmt.Errorf("parsing the manifest: %v", err)
+	}
+	if undecoded := metadata.Undecoded(); len(undecoded) != 0 {
+		return nil, fmt.Errorf("unknown keys in the manifest: %v", undecoded)
+	}
+	if err := manifest.validate(); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %v", err)
+	}
+	return &manifest, nil
+}
+
+// ParseManifestYAML parses and validates the given YAML manifest.
+func ParseManifestYAML(data []byte) (*Manifest, error) {
+	var manifest Manifest
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	// An empty document leaves the manifest empty, which fails validation.
+	if err := decoder.Decode(&manifest); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("parsing the manifest: %v", err)
+	}
+	if err := manifest.validate(); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %v", err)
+	}
+	return &manifest, nil
+}
+
+func (m *Manifest) validate() error {
+	if len(m.Binaries) == 0 {
+		return fmt.Errorf("no binaries in the manifest")
+	}
+	var errs error
+	names := make(map[string]bool)
+	for i, entry := range m.Binaries {
+		if entry.Name == "" {
+			errs = multierr.Append(errs, fmt.Errorf("binary #%d has no name", i))
+		} else if names[entry.Name] {
+			errs = multierr.Append(errs, fmt.Errorf("binary #%d has a duplicate name %q", i, entry.Name))
+		} else if strings.ContainsAny(entry.Name, `/\`) || strings.Contains(entry.Name, "..") {
+			errs = multierr.Append(errs, fmt.Errorf("binary #%d has a name %q with a path separator or `..`", i, entry.Name))
+		}
+		names[entry.Name] = true
+		if (entry.Digest == "") == (entry.BinaryPath == "") {
+			errs = multierr.Append(errs, fmt.Errorf("binary %q must have exactly one of digest and binary_path", entry.Name))
+		}
+		if entry.Digest != "" && !sha256HexPattern.MatchString(entry.Digest) {
+			errs = multierr.Append(errs, fmt.Errorf("binary %q has an invalid SHA2-256 digest %q", entry.Name, entry.Digest))
+		}
+		if entry.ReferenceValuesDigest != "" && !sha256HexPattern.MatchString(entry.ReferenceValuesDigest) {
+			errs = multierr.Append(errs, fmt.Errorf("binary %q has an invalid SHA2-256 reference_values_digest %q", entry.Name, entry.ReferenceValuesDigest))
+		}
+		if entry.VerificationOptions == "" && !entry.SkipVerification {
+			errs = multierr.Append(errs, fmt.Errorf("binary %q has empty verification_options, set skip_verification to overrule", entry.Name))
+		}
+	}
+	return errs
+}
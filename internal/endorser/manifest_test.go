@@ -0,0 +1,145 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package endorser
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseManifest(t *testing.T) {
+	manifest := `
+[[binary]]
+name = "stage0_bin"
+digest = "d059c38cea82047ad316a1c6c6fbd13ecf7a0abdcc375463920bd25bf5c142cc"
+provenance_uris = ["file:///tmp/provenance.json"]
+verification_options = "provenance_count_at_least { count: 1 }"
+reference_values_digest = "fd6ebf2bbd5cfc5ed4ffe6e56b11ec3b2d43d3e2e1fcf2cc9d45bf3b8b0b9bd2"
+
+[[binary]]
+name = "kernel_bin"
+binary_path = "/tmp/kernel_bin"
+skip_verification = true
+`
+	got, err := ParseManifest([]byte(manifest))
+	if err != nil {
+		t.Fatalf("Failed to parse the manifest: %v", err)
+	}
+	want := &Manifest{Binaries: []ManifestEntry{
+		{
+			Name:                  "stage0_bin",
+			Digest:                "d059c38cea82047ad316a1c6c6fbd13ecf7a0abdcc375463920bd25bf5c142cc",
+			ProvenanceURIs:        []string{"file:///tmp/provenance.json"},
+			VerificationOptions:   "provenance_count_at_least { count: 1 }",
+			ReferenceValuesDigest: "fd6ebf2bbd5cfc5ed4ffe6e56b11ec3b2d43d3e2e1fcf2cc9d45bf3b8b0b9bd2",
+		},
+		{
+			Name:             "kernel_bin",
+			BinaryPath:       "/tmp/kernel_bin",
+			SkipVerification: true,
+		},
+	}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Unexpected manifest (-want +got):\n%s", diff)
+	}
+}
+
+func TestParseManifest_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"no binaries": ``,
+		"unknown key": `
+[[binary]]
+name = "a"
+digest = "d059c38cea82047ad316a1c6c6fbd13ecf7a0abdcc375463920bd25bf5c142cc"
+skip_verification = true
+provenances = []`,
+		"duplicate name": `
+[[binary]]
+name = "a"
+digest = "d059c38cea82047ad316a1c6c6fbd13ecf7a0abdcc375463920bd25bf5c142cc"
+skip_verification = true
+[[binary]]
+name = "a"
+binary_path = "/tmp/a"
+skip_verification = true`,
+		"digest and binary_path": `
+[[binary]]
+name = "a"
+digest = "d059c38cea82047ad316a1c6c6fbd13ecf7a0abdcc375463920bd25bf5c142cc"
+binary_path = "/tmp/a"
+skip_verification = true`,
+		"invalid digest": `
+[[binary]]
+name = "a"
+digest = "d059c38c"
+skip_verification = true`,
+		"invalid reference values digest": `
+[[binary]]
+name = "a"
+digest = "d059c38cea82047ad316a1c6c6fbd13ecf7a0abdcc375463920bd25bf5c142cc"
+skip_verification = true
+reference_values_digest = "main"`,
+		"path separator in name": `
+[[binary]]
+name = "../../x"
+digest = "d059c38cea82047ad316a1c6c6fbd13ecf7a0abdcc375463920bd25bf5c142cc"
+skip_verification = true`,
+		"missing verification options": `
+[[binary]]
+name = "a"
+binary_path = "/tmp/a"`,
+	}
+	for name, manifest := range tests {
+		if _, err := ParseManifest([]byte(manifest)); err == nil {
+			t.Errorf("Expected an error for a manifest with %s", name)
+		}
+	}
+}
+
+func TestParseManifestYAML(t *testing.T) {
+	manifest := `
+binary:
+  - name: stage0_bin
+    digest: d059c38cea82047ad316a1c6c6fbd13ecf7a0abdcc375463920bd25bf5c142cc
+    subject_name: stage0_bin_x86
+    provenance_uris: ["file:///tmp/provenance.json"]
+    verification_options: "provenance_count_at_least { count: 1 }"
+`
+	got, err := ParseManifestYAML([]byte(manifest))
+	if err != nil {
+		t.Fatalf("Failed to parse the manifest: %v", err)
+	}
+	want := &Manifest{Binaries: []ManifestEntry{{
+		Name:                "stage0_bin",
+		Digest:              "d059c38cea82047ad316a1c6c6fbd13ecf7a0abdcc375463920bd25bf5c142cc",
+		SubjectName:         "stage0_bin_x86",
+		ProvenanceURIs:      []string{"file:///tmp/provenance.json"},
+		VerificationOptions: "provenance_count_at_least { count: 1 }",
+	}}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Unexpected manifest (-want +got):\n%s", diff)
+	}
+
+	for name, invalid := range map[string]string{
+		"no binaries": ``,
+		"unknown key": "binary:\n  - name: a\n    binary_path: /tmp/a\n    skip_verification: true\n    provenances: []\n",
+		"traversal":   "binary:\n  - name: ..\\x\n    binary_path: /tmp/a\n    skip_verification: true\n",
+	} {
+		if _, err := ParseManifestYAML([]byte(invalid)); err == nil {
+			t.Errorf("Expected an error for a YAML manifest with %s", name)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package endorser
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+
+	"github.com/project-oak/transparent-release/internal/fetch"
+	"github.com/project-oak/transparent-release/pkg/claims"
+	"github.com/project-oak/transparent-release/pkg/intoto"
+)
+
+const (
+	// BundleURIScheme is the scheme of the URIs of evidence pinned in an
+	// endorsement bundle, e.g., `bundle:evidence/<sha256>`.
+	BundleURIScheme = "bundle"
+	// PinnedEvidenceDir is the directory of the pinned evidence, relative to
+	// the root of an endorsement bundle.
+	PinnedEvidenceDir = "evidence"
+)
+
+// bundleURIPattern matches the opaque part of the URIs of pinned evidence.
+//
+//nolint:gochecknoglobals
+var bundleURIPattern = regexp.MustCompile(`^` + PinnedEvidenceDir + `/[0-9a-f]{64}$`)
+
+// PinEvidence fetches every evidence referenced by the given endorsement,
+// checks it against its digests, and stores it in the given bundle directory,
+// in PinnedEvidenceDir, in a file named by the hex-encoded SHA2-256 digest of
+// its content. Returns a copy of the endorsement, in which the URI of every
+// evidence is replaced by its bundle-relative URI, and which records the
+// SHA2-256 digest of every evidence. The endorsement remains verifiable with
+// WithBundleDir, even if the original URIs disappear.
+func PinEvidence(ctx context.Context, endorsement *intoto.Statement, bundleDir string, options ...func(c *EvidenceConfig)) (*intoto.Statement, error) {
+	config := &EvidenceConfig{registry: fetch.Default()}
+	for _, addOption := range options {
+		addOption(config)
+	}
+
+	predicate, err := claims.ValidateClaim(*endorsement)
+	if err != nil {
+		return nil, fmt.Errorf("invalid endorsement: %v", err)
+	}
+	evidenceDir := filepath.Join(bundleDir, PinnedEvidenceDir)
+	if err := os.MkdirAll(evidenceDir, 0o755); err != nil {
+		return nil, fmt.Errorf("could not create the evidence directory: %v", err)
+	}
+
+	pinned := make([]claims.ClaimEvidence, 0, len(predicate.Evidence))
+	for _, evidence := range predicate.Evidence {
+		content, err := loadEvidence(ctx, evidence, config)
+		if err != nil {
+			return nil, err
+		}
+		sum256 := sha256.Sum256(content)
+		digest := hex.EncodeToString(sum256[:])
+		if err := writePinnedEvidence(filepath.Join(evidenceDir, digest), content); err != nil {
+			return nil, fmt.Errorf("could not pin the evidence %s: %v", evidence.URI, err)
+		}
+
+		digests := make(intoto.DigestSet, len(evidence.Digest)+1)
+		for algorithm, value := range evidence.Digest {
+			digests[algorithm] = value
+		}
+		_, hasSHA256 := digests["sha256"]
+		_, hasSHA2256 := digests["sha2-256"]
+		if !hasSHA256 && !hasSHA2256 {
+			digests["sha256"] = digest
+		}
+		pinned = append(pinned, claims.ClaimEvidence{
+			Role:   evidence.Role,
+			URI:    BundleURIScheme + ":" + path.Join(PinnedEvidenceDir, digest),
+			Digest: digests,
+		})
+	}
+
+	pinnedPredicate := *predicate
+	pinnedPredicate.Evidence = pinned
+	pinnedEndorsement := *endorsement
+	pinnedEndorsement.Predicate = pinnedPredicate
+	return &pinnedEndorsement, nil
+}
+
+// writePinnedEvidence writes the given content to the given content-addressed
+// path, unless the file exists with the same content, e.g., if the same
+// evidence is referenced twice.
+func writePinnedEvidence(filePath string, content []byte) error {
+	existing, err := os.ReadFile(filePath)
+	if err == nil && bytes.Equal(existing, content) {
+		return nil
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return os.WriteFile(filePath, content, 0o600)
+}
+
+// readBundledEvidence reads the evidence with the given bundle-relative URI
+// from the given bundle directory.
+func readBundledEvidence(bundleDir string, uri *url.URL) ([]byte, error) {
+	if bundleDir == "" {
+		return nil, fmt.Errorf("no bundle directory for the bundle-relative URI")
+	}
+	if !bundleURIPattern.MatchString(uri.Opaque) {
+		return nil, fmt.Errorf("invalid bundle-relative URI, want %s:%s/<sha256>", BundleURIScheme, PinnedEvidenceDir)
+	}
+	return os.ReadFile(filepath.Join(bundleDir, filepath.FromSlash(uri.Opaque)))
+}
@@ -0,0 +1,97 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package endorser
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/project-oak/transparent-release/pkg/claims"
+	"github.com/project-oak/transparent-release/pkg/intoto"
+)
+
+func TestPinEvidence(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(evidenceContent))
+	}))
+	digests := evidenceDigests()
+	endorsement := newEvidenceEndorsement(
+		claims.ClaimEvidence{Role: "Log", URI: server.URL + "/log.json", Digest: intoto.DigestSet{"sha2-512": digests["sha2-512"]}},
+		claims.ClaimEvidence{Role: claims.ProvenanceRole, URI: server.URL + "/provenance.json", Digest: digests},
+	)
+	bundleDir := t.TempDir()
+
+	pinned, err := PinEvidence(context.Background(), endorsement, bundleDir)
+	if err != nil {
+		t.Fatalf("could not pin the evidence: %v", err)
+	}
+	// The original URIs disappear.
+	server.Close()
+
+	wantURI := "bundle:evidence/" + digests["sha256"]
+	for _, evidence := range pinned.Predicate.(claims.ClaimPredicate).Evidence {
+		if evidence.URI != wantURI {
+			t.Errorf("unexpected URI of pinned evidence: got %s, want %s", evidence.URI, wantURI)
+		}
+		if evidence.Digest["sha256"] != digests["sha256"] {
+			t.Errorf("the pinned evidence %s has no SHA256 digest: %v", evidence.Role, evidence.Digest)
+		}
+	}
+	if endorsement.Predicate.(claims.ClaimPredicate).Evidence[0].URI != server.URL+"/log.json" {
+		t.Errorf("the endorsement has been modified")
+	}
+	content, err := os.ReadFile(filepath.Join(bundleDir, "evidence", digests["sha256"]))
+	if err != nil || string(content) != evidenceContent {
+		t.Errorf("unexpected pinned content %q: %v", content, err)
+	}
+
+	if err := VerifyEvidence(context.Background(), pinned, WithBundleDir(bundleDir)); err != nil {
+		t.Errorf("could not verify the pinned evidence: %v", err)
+	}
+	if err := VerifyEvidence(context.Background(), pinned); err == nil || !strings.Contains(err.Error(), "no bundle directory") {
+		t.Errorf("expected failure without a bundle directory, got %v", err)
+	}
+}
+
+func TestPinEvidence_DigestMismatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "evidence.json")
+	if err := os.WriteFile(path, []byte("other evidence"), 0o600); err != nil {
+		t.Fatalf("could not write the evidence: %v", err)
+	}
+	endorsement := newEvidenceEndorsement(claims.ClaimEvidence{URI: "file://" + path, Digest: evidenceDigests()})
+	bundleDir := t.TempDir()
+
+	if _, err := PinEvidence(context.Background(), endorsement, bundleDir); err == nil || !strings.Contains(err.Error(), "digest mismatch") {
+		t.Errorf("expected a digest mismatch, got %v", err)
+	}
+	if entries, _ := os.ReadDir(filepath.Join(bundleDir, "evidence")); len(entries) != 0 {
+		t.Errorf("mismatching evidence has been pinned: %v", entries)
+	}
+}
+
+func TestVerifyEvidence_InvalidBundleURI(t *testing.T) {
+	bundleDir := t.TempDir()
+	for _, uri := range []string{"bundle:../evidence.json", "bundle:evidence/" + strings.Repeat("AB", 32)} {
+		endorsement := newEvidenceEndorsement(claims.ClaimEvidence{URI: uri, Digest: evidenceDigests()})
+		if err := VerifyEvidence(context.Background(), endorsement, WithBundleDir(bundleDir)); err == nil || !strings.Contains(err.Error(), "invalid bundle-relative URI") {
+			t.Errorf("%s: expected an invalid bundle-relative URI, got %v", uri, err)
+		}
+	}
+}
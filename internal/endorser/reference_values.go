@@ -0,0 +1,191 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package endorser
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/project-oak/transparent-release/internal/fetch"
+	"github.com/project-oak/transparent-release/internal/model"
+	"github.com/project-oak/transparent-release/internal/verifier"
+	"github.com/project-oak/transparent-release/pkg/claims"
+	"github.com/project-oak/transparent-release/pkg/intoto"
+	pb "github.com/project-oak/transparent-release/pkg/proto/oak/release"
+)
+
+// ReferenceValuesPath is the path of the reference values file in the source
+// repository. The file contains VerificationOptions as textproto.
+const ReferenceValuesPath = ".transparent-release/reference_values.textproto"
+
+// ReferenceValuesRole is the role of the evidence referencing the reference
+// values that the provenances were verified against.
+const ReferenceValuesRole = "Reference values"
+
+// DefaultRawContentURL is the base URL for fetching files from GitHub
+// repositories at a given commit.
+const DefaultRawContentURL = "https://raw.githubusercontent.com"
+
+// ReferenceValues are VerificationOptions fetched from the source repository
+// of the provenances, pinned to the SHA256 digest of the fetched file.
+type ReferenceValues struct {
+	URI          string
+	SHA256Digest string
+	Options      *pb.VerificationOptions
+}
+
+// ReferenceValuesConfig holds optional settings for loading reference values.
+type ReferenceValuesConfig struct {
+	rawContentURL  string
+	expectedDigest string
+}
+
+// WithRawContentURL overrides DefaultRawContentURL.
+func WithRawContentURL(rawContentURL string) func(c *ReferenceValuesConfig) {
+	return func(c *ReferenceValuesConfig) {
+		c.rawContentURL = rawContentURL
+	}
+}
+
+// WithExpectedDigest makes loading the reference values fail, unless the
+// fetched file has the given hex-encoded SHA256 digest. The digest is
+// required, since the reference values are fetched from a mutable location.
+func WithExpectedDigest(expectedDigest string) func(c *ReferenceValuesConfig) {
+	return func(c *ReferenceValuesConfig) {
+		c.expectedDigest = expectedDigest
+	}
+}
+
+// LoadReferenceValues fetches the reference values from ReferenceValuesPath
+// in the source repository of the given provenances, at the commit they were
+// built from. All provenances must agree on the repository and commit. Only
+// GitHub repositories are supported. The expected digest of the reference
+// values must be set with WithExpectedDigest.
+func LoadReferenceValues(ctx context.Context, provenances []model.ProvenanceIR, options ...func(c *ReferenceValuesConfig)) (*ReferenceValues, error) {
+	config := &ReferenceValuesConfig{rawContentURL: DefaultRawContentURL}
+	for _, addOption := range options {
+		addOption(config)
+	}
+	if config.expectedDigest == "" {
+		return nil, fmt.Errorf("the expected SHA256 digest of the reference values is required")
+	}
+	if digest, err := hex.DecodeString(config.expectedDigest); err != nil || len(digest) != sha256.Size {
+		return nil, fmt.Errorf("the expected digest of the reference values %q is not a hex-encoded SHA256 digest", config.expectedDigest)
+	}
+
+	repoURI, commitDigest, err := sourceOf(provenances)
+	if err != nil {
+		return nil, err
+	}
+	uri, err := referenceValuesURI(config.rawContentURL, repoURI, commitDigest)
+	if err != nil {
+		return nil, err
+	}
+
+	bytes, err := fetch.Fetch(ctx, uri, fetch.WithExpectedSHA256Digest(config.expectedDigest))
+	if err != nil {
+		return nil, fmt.Errorf("fetching the reference values from %s: %v", uri, err)
+	}
+	sum256 := sha256.Sum256(bytes)
+	digest := hex.EncodeToString(sum256[:])
+
+	verOpts, err := verifier.ParseVerificationOptions(string(bytes))
+	if err != nil {
+		return nil, fmt.Errorf("parsing the reference values from %s: %v", uri, err)
+	}
+	return &ReferenceValues{URI: uri, SHA256Digest: digest, Options: verOpts}, nil
+}
+
+// Verify verifies the given provenances against the reference values.
+func (r *ReferenceValues) Verify(provenances []model.ProvenanceIR) error {
+	if err := verifier.Verify(provenances, r.Options); err != nil {
+		return fmt.Errorf("failed to verify provenances against the reference values from %s: %v", r.URI, err)
+	}
+	return nil
+}
+
+// Evidence returns evidence referencing the reference values, for inclusion
+// in an endorsement.
+func (r *ReferenceValues) Evidence() claims.ClaimEvidence {
+	return claims.ClaimEvidence{
+		Role:   ReferenceValuesRole,
+		URI:    r.URI,
+		Digest: intoto.DigestSet{"sha256": r.SHA256Digest},
+	}
+}
+
+// sourceOf returns the repository URI and commit digest that all given
+// provenances agree on, or an error if they don't.
+func sourceOf(provenances []model.ProvenanceIR) (string, string, error) {
+	if len(provenances) == 0 {
+		return "", "", fmt.Errorf("no provenances to get the source repository from")
+	}
+	var repoURI, commitDigest string
+	for i, p := range provenances {
+		commit := commitOf(&provenances[i])
+		if !p.HasRepoURI() || commit == "" {
+			return "", "", fmt.Errorf("no repository URI or commit digest in provenance #%d", i)
+		}
+		if i == 0 {
+			repoURI, commitDigest = p.RepoURI(), commit
+			continue
+		}
+		if p.RepoURI() != repoURI || commit != commitDigest {
+			return "", "", fmt.Errorf("provenance #%d is built from %s@%s, but provenance #0 from %s@%s",
+				i, p.RepoURI(), commit, repoURI, commitDigest)
+		}
+	}
+	return repoURI, commitDigest, nil
+}
+
+// commitOf returns the commit hash that the given provenance is built from,
+// i.e., its SHA1 commit digest, or its SHA256 one for repos in the SHA-256 Git
+// object format. Returns an empty string if there is neither.
+func commitOf(p *model.ProvenanceIR) string {
+	digests := p.CommitDigests()
+	if digest := digests["sha1"]; digest != "" {
+		return digest
+	}
+	return digests["sha2-256"]
+}
+
+// referenceValuesURI returns the URI of the reference values file in the given
+// GitHub repository at the given commit, e.g., for repository
+// `git+https://github.com/project-oak/oak@refs/heads/main`. The commit must be
+// a hex-encoded SHA1 commit hash, since GitHub does not support SHA-256
+// repositories, so that it cannot be a branch name, or change the path.
+func referenceValuesURI(rawContentURL, repoURI, commitDigest string) (string, error) {
+	if commit, err := hex.DecodeString(commitDigest); err != nil || len(commit) != 20 {
+		return "", fmt.Errorf("the commit %q is not a hex-encoded SHA1 commit hash", commitDigest)
+	}
+	commitDigest = strings.ToLower(commitDigest)
+	uri, err := url.Parse(strings.TrimPrefix(repoURI, "git+"))
+	if err != nil {
+		return "", fmt.Errorf("could not parse the repository URI (%q): %v", repoURI, err)
+	}
+	if uri.Host != "github.com" {
+		return "", fmt.Errorf("unsupported repository host (%q), only github.com is supported", uri.Host)
+	}
+	repoPath, _, _ := strings.Cut(uri.Path, "@")
+	repoPath = strings.TrimSuffix(strings.Trim(repoPath, "/"), ".git")
+	if strings.Count(repoPath, "/") != 1 {
+		return "", fmt.Errorf("could not get the owner and name of the repository from %q", repoURI)
+	}
+	return fmt.Sprintf("%s/%s/%s/%s", strings.TrimSuffix(rawContentURL, "/"), repoPath, commitDigest, ReferenceValuesPath), nil
+}
@@ -0,0 +1,158 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package endorser
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/project-oak/transparent-release/internal/model"
+	"github.com/project-oak/transparent-release/internal/testutil"
+)
+
+const (
+	referenceValuesRequestPath = "/project-oak/oak/1b128fb2556e4bdcc4f92552654bfbca9d2fb8c6/" + ReferenceValuesPath
+	trustedBuilder             = "https://github.com/slsa-framework/slsa-github-generator/.github/workflows/generator_generic_slsa3.yml@refs/tags/v1.2.0"
+)
+
+// newFakeRawContent starts a test server that serves the given reference
+// values for the commit of the provenance in provenancePath.
+func newFakeRawContent(t *testing.T, referenceValues string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != referenceValuesRequestPath {
+			http.NotFound(w, r)
+			return
+		}
+		if _, err := w.Write([]byte(referenceValues)); err != nil {
+			t.Errorf("could not write the response: %v", err)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// digestOf returns the hex-encoded SHA256 digest of the given reference values.
+func digestOf(referenceValues string) string {
+	sum256 := sha256.Sum256([]byte(referenceValues))
+	return hex.EncodeToString(sum256[:])
+}
+
+func provenanceIRs(provenances []ParsedProvenance) []model.ProvenanceIR {
+	irs := make([]model.ProvenanceIR, 0, len(provenances))
+	for _, p := range provenances {
+		irs = append(irs, p.Provenance)
+	}
+	return irs
+}
+
+func TestLoadReferenceValues(t *testing.T) {
+	referenceValues := "all_with_builder_names { builder_names: '" + trustedBuilder + "' }"
+	server := newFakeRawContent(t, referenceValues)
+	provenances := provenanceIRs(createProvenanceList(t, []string{provenancePath}))
+
+	got, err := LoadReferenceValues(context.Background(), provenances, WithRawContentURL(server.URL), WithExpectedDigest(digestOf(referenceValues)))
+	if err != nil {
+		t.Fatalf("Failed to load reference values: %v", err)
+	}
+	testutil.AssertEq(t, "URI", got.URI, server.URL+referenceValuesRequestPath)
+	testutil.AssertEq(t, "digest", got.SHA256Digest, digestOf(referenceValues))
+	testutil.AssertEq(t, "evidence role", got.Evidence().Role, ReferenceValuesRole)
+	if err := got.Verify(provenances); err != nil {
+		t.Errorf("Failed to verify provenances against reference values: %v", err)
+	}
+
+	// The digest must be pinned.
+	if _, err := LoadReferenceValues(context.Background(), provenances, WithRawContentURL(server.URL)); err == nil {
+		t.Errorf("Expected an error without the expected digest of the reference values")
+	}
+	if _, err := LoadReferenceValues(context.Background(), provenances, WithRawContentURL(server.URL), WithExpectedDigest("abc")); err == nil {
+		t.Errorf("Expected an error for an invalid expected digest of the reference values")
+	}
+	if _, err := LoadReferenceValues(context.Background(), provenances, WithRawContentURL(server.URL), WithExpectedDigest(binaryDigest)); err == nil {
+		t.Errorf("Expected an error for an unexpected digest of the reference values")
+	}
+}
+
+func TestLoadReferenceValues_VerificationFailure(t *testing.T) {
+	referenceValues := "all_with_builder_names { builder_names: 'https://example.com/other-builder' }"
+	server := newFakeRawContent(t, referenceValues)
+	provenances := provenanceIRs(createProvenanceList(t, []string{provenancePath}))
+
+	got, err := LoadReferenceValues(context.Background(), provenances, WithRawContentURL(server.URL), WithExpectedDigest(digestOf(referenceValues)))
+	if err != nil {
+		t.Fatalf("Failed to load reference values: %v", err)
+	}
+	if err := got.Verify(provenances); err == nil {
+		t.Errorf("Expected verification against the reference values to fail")
+	}
+}
+
+func TestLoadReferenceValues_CommitDigest(t *testing.T) {
+	referenceValues := "all_with_commit_digests { digests { hexadecimal { key: 17 value: '1b128fb2556e4bdcc4f92552654bfbca9d2fb8c6' } } }"
+	server := newFakeRawContent(t, referenceValues)
+	provenances := provenanceIRs(createProvenanceList(t, []string{provenancePath}))
+
+	got, err := LoadReferenceValues(context.Background(), provenances, WithRawContentURL(server.URL), WithExpectedDigest(digestOf(referenceValues)))
+	if err != nil {
+		t.Fatalf("Failed to load reference values: %v", err)
+	}
+	if err := got.Verify(provenances); err != nil {
+		t.Errorf("Failed to verify provenances against reference values: %v", err)
+	}
+
+	// Provenances built from another commit do not pass.
+	other := provenanceIRs(createProvenanceList(t, []string{differentProvenancePath}))
+	if err := got.Verify(other); err == nil {
+		t.Errorf("Expected verification of a provenance from another commit to fail")
+	}
+}
+
+func TestLoadReferenceValues_DifferentSources(t *testing.T) {
+	server := newFakeRawContent(t, "")
+	provenances := provenanceIRs(createProvenanceList(t, []string{provenancePath, differentProvenancePath}))
+
+	if _, err := LoadReferenceValues(context.Background(), provenances, WithRawContentURL(server.URL), WithExpectedDigest(digestOf(""))); err == nil {
+		t.Errorf("Expected an error for provenances from different commits")
+	}
+}
+
+func TestReferenceValuesURI(t *testing.T) {
+	commit := "1b128fb2556e4bdcc4f92552654bfbca9d2fb8c6"
+	for _, repoURI := range []string{
+		"git+https://github.com/project-oak/oak@refs/heads/main",
+		"git+https://github.com/project-oak/oak",
+		"https://github.com/project-oak/oak.git",
+	} {
+		got, err := referenceValuesURI(DefaultRawContentURL, repoURI, commit)
+		if err != nil {
+			t.Errorf("referenceValuesURI(%q) failed: %v", repoURI, err)
+			continue
+		}
+		testutil.AssertEq(t, repoURI, got, "https://raw.githubusercontent.com/project-oak/oak/"+commit+"/"+ReferenceValuesPath)
+	}
+
+	if _, err := referenceValuesURI(DefaultRawContentURL, "git+https://gitlab.com/project-oak/oak", commit); err == nil {
+		t.Errorf("Expected an error for a repository not on GitHub")
+	}
+	for _, invalid := range []string{"abc", "main", "../../" + commit[6:], commit + "00"} {
+		if _, err := referenceValuesURI(DefaultRawContentURL, "git+https://github.com/project-oak/oak", invalid); err == nil {
+			t.Errorf("Expected an error for the commit %q", invalid)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package endorser
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/secure-systems-lab/go-securesystemslib/dsse"
+	"google.golang.org/protobuf/encoding/prototext"
+
+	"github.com/project-oak/transparent-release/pkg/claims"
+	"github.com/project-oak/transparent-release/pkg/intoto"
+	pb "github.com/project-oak/transparent-release/pkg/proto/oak/release"
+)
+
+// VerificationReportV1 is the predicate type of verification reports.
+const VerificationReportV1 = "https://github.com/project-oak/transparent-release/verification_report/v1"
+
+// VerificationReportRole is the role of the evidence referencing the
+// verification report from which an endorsement was issued.
+const VerificationReportRole = "Verification report"
+
+// VerificationReport is the predicate of a verification report. A signed
+// verification report records that the provenances of the binary in its
+// subject have been verified, so that an endorsement can be issued from it on
+// a different host, without repeating the verification.
+type VerificationReport struct {
+	// VerifiedOn is the time at which the provenances were verified.
+	VerifiedOn *time.Time `json:"verifiedOn"`
+	// VerificationOptions are the options used for verifying the
+	// provenances, as textproto.
+	VerificationOptions string `json:"verificationOptions"`
+	// Provenances are the verified provenances.
+	Provenances []claims.ClaimEvidence `json:"provenances"`
+}
+
+// GenerateVerificationReport verifies the given provenances as in
+// GenerateEndorsement, and returns a verification report for the given
+// binary, using the given clock for the verification time. The report can be
+// signed using SignStatement.
+func GenerateVerificationReport(binaryName string, digests intoto.DigestSet, verOpts *pb.VerificationOptions, provenances []ParsedProvenance, clock claims.Clock) (*intoto.Statement, error) {
+	verifiedProvenances, err := VerifyProvenances(binaryName, digests, verOpts, provenances)
+	if err != nil {
+		return nil, err
+	}
+	verOptsTextproto, err := prototext.Marshal(verOpts)
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal the verification options: %v", err)
+	}
+
+	evidence := make([]claims.ClaimEvidence, 0, len(verifiedProvenances.Provenances))
+	for _, provenance := range verifiedProvenances.Provenances {
+		evidence = append(evidence, claims.ClaimEvidence{
+			Role:   claims.ProvenanceRole,
+			URI:    provenance.URI,
+			Digest: intoto.DigestSet{"sha256": provenance.SHA256Digest},
+		})
+	}
+	verifiedOn := clock.Now().UTC()
+
+	return &intoto.Statement{
+		StatementHeader: intoto.StatementHeader{
+			Type:          intoto.StatementInTotoV01,
+			PredicateType: VerificationReportV1,
+			Subject:       []intoto.Subject{{Name: binaryName, Digest: digests}},
+		},
+		Predicate: VerificationReport{
+			VerifiedOn:          &verifiedOn,
+			VerificationOptions: string(verOptsTextproto),
+			Provenances:         evidence,
+		},
+	}, nil
+}
+
+// IssueEndorsementFromReport generates an endorsement statement from the
+// given signed verification report, after checking that the report is signed
+// by the given verifier, and that it is not older than maxAge according to the
+// clock in the EndorsementConfig options. reportURI and reportBytes are the
+// location and content of the DSSE envelope containing the report; the
+// endorsement references it as evidence, alongside the verified provenances.
+func IssueEndorsementFromReport(ctx context.Context, reportURI string, reportBytes []byte, reportVerifier dsse.Verifier, maxAge time.Duration, validity claims.ClaimValidity, options ...func(c *claims.EndorsementConfig)) (*intoto.Statement, error) {
+	var envelope dsse.Envelope
+	if err := json.Unmarshal(reportBytes, &envelope); err != nil {
+		return nil, fmt.Errorf("could not unmarshal the verification report envelope: %v", err)
+	}
+	envelopeVerifier, err := dsse.NewEnvelopeVerifier(reportVerifier)
+	if err != nil {
+		return nil, fmt.Errorf("could not create an envelope verifier: %v", err)
+	}
+	if _, err := envelopeVerifier.Verify(ctx, &envelope); err != nil {
+		return nil, fmt.Errorf("could not verify the signature of the verification report: %v", err)
+	}
+
+	subject, report, err := parseVerificationReport(&envelope)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := claims.NewEndorsementConfig(options...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid endorsement config: %v", err)
+	}
+	now := config.Clock().Now()
+	if report.VerifiedOn.After(now) {
+		return nil, fmt.Errorf("the verification report is from the future (%v)", report.VerifiedOn)
+	}
+	if now.Sub(*report.VerifiedOn) > maxAge {
+		return nil, fmt.Errorf("the verification report from %v is older than %v", report.VerifiedOn, maxAge)
+	}
+
+	sum256 := sha256.Sum256(reportBytes)
+	evidence := append(report.Provenances, claims.ClaimEvidence{
+		Role:   VerificationReportRole,
+		URI:    reportURI,
+		Digest: intoto.DigestSet{"sha256": hex.EncodeToString(sum256[:])},
+	})
+	claims.WithEvidence(evidence...)(config)
+
+	// The provenances are referenced through the evidence of the report.
+	verifiedProvenances := claims.VerifiedProvenanceSet{
+		BinaryName: subject.Name,
+		Digests:    subject.Digest,
+	}
+	return claims.GenerateEndorsementStatementWithConfig(config, validity, verifiedProvenances)
+}
+
+// parseVerificationReport parses the payload of the given envelope as a
+// verification report, and returns its subject and predicate.
+func parseVerificationReport(envelope *dsse.Envelope) (*intoto.Subject, *VerificationReport, error) {
+	if envelope.PayloadType != intoto.PayloadType {
+		return nil, nil, fmt.Errorf("unexpected payload type of the verification report: %q", envelope.PayloadType)
+	}
+	payload, err := envelope.DecodeB64Payload()
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not decode the verification report: %v", err)
+	}
+
+	var statement struct {
+		intoto.StatementHeader
+		Predicate VerificationReport `json:"predicate"`
+	}
+	if err := json.Unmarshal(payload, &statement); err != nil {
+		return nil, nil, fmt.Errorf("could not unmarshal the verification report: %v", err)
+	}
+	if err := intoto.NormalizeStatementHeader(&statement.StatementHeader); err != nil {
+		return nil, nil, fmt.Errorf("invalid verification report: %v", err)
+	}
+	if statement.PredicateType != VerificationReportV1 {
+		return nil, nil, fmt.Errorf("unexpected predicate type of the verification report: %q", statement.PredicateType)
+	}
+	if len(statement.Subject) != 1 || statement.Subject[0].Digest["sha2-256"] == "" {
+		return nil, nil, fmt.Errorf("the verification report must have exactly one subject with a sha2-256 digest")
+	}
+	if statement.Predicate.VerifiedOn == nil {
+		return nil, nil, fmt.Errorf("the verification report does not have a verification time")
+	}
+	return &statement.Subject[0], &statement.Predicate, nil
+}
@@ -0,0 +1,119 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package endorser
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/project-oak/transparent-release/internal/testutil"
+	"github.com/project-oak/transparent-release/pkg/claims"
+	"github.com/project-oak/transparent-release/pkg/intoto"
+	pb "github.com/project-oak/transparent-release/pkg/proto/oak/release"
+)
+
+const reportURI = "https://example.com/reports/oak_functions_freestanding_bin.dsse.json"
+
+// createSignedReport runs phase 1: verifies the test provenance at the given
+// time, and returns the signed verification report, and the signer.
+func createSignedReport(t *testing.T, verifiedOn time.Time) ([]byte, *ed25519Signer) {
+	provenances := createProvenanceList(t, []string{provenancePath})
+	verOpts := &pb.VerificationOptions{
+		ProvenanceCountAtLeast: &pb.VerifyProvenanceCountAtLeast{Count: 1},
+	}
+	report, err := GenerateVerificationReport(binaryName, intoto.DigestSet{"sha2-256": binaryDigest}, verOpts,
+		provenances, claims.FixedClock(verifiedOn))
+	if err != nil {
+		t.Fatalf("Failed to generate verification report: %v", err)
+	}
+
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	signer := &ed25519Signer{privateKey: privateKey}
+	envelope, err := SignStatement(context.Background(), report, signer)
+	if err != nil {
+		t.Fatalf("Failed to sign verification report: %v", err)
+	}
+	reportBytes, err := json.Marshal(envelope)
+	if err != nil {
+		t.Fatalf("Failed to marshal verification report: %v", err)
+	}
+	return reportBytes, signer
+}
+
+func TestIssueEndorsementFromReport(t *testing.T) {
+	verifiedOn := time.Date(2023, 7, 1, 12, 0, 0, 0, time.UTC)
+	reportBytes, signer := createSignedReport(t, verifiedOn)
+
+	// Phase 2 runs half an hour later.
+	clock := claims.FixedClock(verifiedOn.Add(30 * time.Minute))
+	endorsement, err := IssueEndorsementFromReport(context.Background(), reportURI, reportBytes, signer, time.Hour,
+		createClaimValidity(7), claims.WithClock(clock))
+	if err != nil {
+		t.Fatalf("Failed to issue endorsement from report: %v", err)
+	}
+
+	testutil.AssertEq(t, "binary name", endorsement.Subject[0].Name, binaryName)
+	testutil.AssertEq(t, "binary digest", endorsement.Subject[0].Digest["sha2-256"], binaryDigest)
+	predicate := endorsement.Predicate.(claims.ClaimPredicate)
+	testutil.AssertEq(t, "evidence length", len(predicate.Evidence), 2)
+	testutil.AssertEq(t, "provenance role", predicate.Evidence[0].Role, "Provenance")
+	testutil.AssertEq(t, "report role", predicate.Evidence[1].Role, VerificationReportRole)
+	testutil.AssertEq(t, "report URI", predicate.Evidence[1].URI, reportURI)
+	testutil.AssertEq(t, "issued on", *predicate.IssuedOn, clock.Now())
+}
+
+func TestIssueEndorsementFromReport_StaleReportFails(t *testing.T) {
+	verifiedOn := time.Date(2023, 7, 1, 12, 0, 0, 0, time.UTC)
+	reportBytes, signer := createSignedReport(t, verifiedOn)
+
+	clock := claims.FixedClock(verifiedOn.Add(2 * time.Hour))
+	_, err := IssueEndorsementFromReport(context.Background(), reportURI, reportBytes, signer, time.Hour,
+		createClaimValidity(7), claims.WithClock(clock))
+	if err == nil {
+		t.Fatalf("Expected an error for a stale verification report")
+	}
+}
+
+func TestIssueEndorsementFromReport_WrongSignerFails(t *testing.T) {
+	verifiedOn := time.Date(2023, 7, 1, 12, 0, 0, 0, time.UTC)
+	reportBytes, _ := createSignedReport(t, verifiedOn)
+
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	clock := claims.FixedClock(verifiedOn.Add(time.Minute))
+	_, err = IssueEndorsementFromReport(context.Background(), reportURI, reportBytes, &ed25519Signer{privateKey: privateKey},
+		time.Hour, createClaimValidity(7), claims.WithClock(clock))
+	if err == nil {
+		t.Fatalf("Expected an error for a report signed by another key")
+	}
+}
+
+func TestGenerateVerificationReport_FailedVerification(t *testing.T) {
+	provenances := createProvenanceList(t, []string{provenancePath})
+	_, err := GenerateVerificationReport("other_binary", intoto.DigestSet{"sha2-256": binaryDigest},
+		&pb.VerificationOptions{}, provenances, claims.SystemClock())
+	if err == nil {
+		t.Fatalf("Expected an error for a binary name mismatch")
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package endorser
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"fmt"
+
+	"github.com/secure-systems-lab/go-securesystemslib/dsse"
+
+	"github.com/project-oak/transparent-release/pkg/claims"
+	"github.com/project-oak/transparent-release/pkg/intoto"
+)
+
+// RevocationConfig holds optional settings for verifying revocations.
+type RevocationConfig struct {
+	endorsementKey dsse.Verifier
+}
+
+// WithEndorsementKey sets the key that endorsements are signed with, to
+// enforce that revocations are signed by a different key. Revocations signed
+// by the endorsement key are rejected, so that a compromised endorsement key
+// cannot be used to revoke (or un-revoke) binaries.
+func WithEndorsementKey(endorsementKey dsse.Verifier) func(c *RevocationConfig) {
+	return func(c *RevocationConfig) {
+		c.endorsementKey = endorsementKey
+	}
+}
+
+// VerifyRevocation checks that the given DSSE envelope is signed by the given
+// revocation key, and returns the revocation statement in it.
+func VerifyRevocation(ctx context.Context, envelopeBytes []byte, revocationKey dsse.Verifier, options ...func(c *RevocationConfig)) (*intoto.Statement, error) {
+	payload, err := verifyRevocationEnvelope(ctx, envelopeBytes, revocationKey, options...)
+	if err != nil {
+		return nil, err
+	}
+	return claims.ParseRevocationBytes(payload)
+}
+
+// VerifyEndorsementRevocation checks that the given DSSE envelope is signed
+// by the given revocation key, and returns the endorsement revocation
+// statement in it.
+func VerifyEndorsementRevocation(ctx context.Context, envelopeBytes []byte, revocationKey dsse.Verifier, options ...func(c *RevocationConfig)) (*intoto.Statement, error) {
+	payload, err := verifyRevocationEnvelope(ctx, envelopeBytes, revocationKey, options...)
+	if err != nil {
+		return nil, err
+	}
+	return claims.ParseEndorsementRevocationBytes(payload)
+}
+
+// verifyRevocationEnvelope checks that the given DSSE envelope is signed by
+// the given revocation key, and not by the endorsement key, and returns its
+// decoded payload.
+func verifyRevocationEnvelope(ctx context.Context, envelopeBytes []byte, revocationKey dsse.Verifier, options ...func(c *RevocationConfig)) ([]byte, error) {
+	config := &RevocationConfig{}
+	for _, option := range options {
+		option(config)
+	}
+
+	var envelope dsse.Envelope
+	if err := json.Unmarshal(envelopeBytes, &envelope); err != nil {
+		return nil, fmt.Errorf("could not unmarshal the revocation envelope: %v", err)
+	}
+	if envelope.PayloadType != intoto.PayloadType {
+		return nil, fmt.Errorf("unexpected payload type of the revocation: %q", envelope.PayloadType)
+	}
+
+	if config.endorsementKey != nil {
+		if samePublicKey(revocationKey.Public(), config.endorsementKey.Public()) {
+			return nil, fmt.Errorf("the revocation key must be different from the endorsement key")
+		}
+		if err := verifyEnvelope(ctx, &envelope, config.endorsementKey); err == nil {
+			return nil, fmt.Errorf("the revocation is signed by the endorsement key")
+		}
+	}
+	if err := verifyEnvelope(ctx, &envelope, revocationKey); err != nil {
+		return nil, fmt.Errorf("could not verify the signature of the revocation: %v", err)
+	}
+
+	payload, err := envelope.DecodeB64Payload()
+	if err != nil {
+		return nil, fmt.Errorf("could not decode the revocation: %v", err)
+	}
+	return payload, nil
+}
+
+func verifyEnvelope(ctx context.Context, envelope *dsse.Envelope, verifier dsse.Verifier) error {
+	envelopeVerifier, err := dsse.NewEnvelopeVerifier(verifier)
+	if err != nil {
+		return fmt.Errorf("could not create an envelope verifier: %v", err)
+	}
+	_, err = envelopeVerifier.Verify(ctx, envelope)
+	return err
+}
+
+// samePublicKey returns true if both keys are equal. All public key types of
+// the standard library implement Equal.
+func samePublicKey(a, b crypto.PublicKey) bool {
+	key, ok := a.(interface{ Equal(crypto.PublicKey) bool })
+	return ok && key.Equal(b)
+}
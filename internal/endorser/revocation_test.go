@@ -0,0 +1,167 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package endorser
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/project-oak/transparent-release/internal/testutil"
+	"github.com/project-oak/transparent-release/pkg/claims"
+	"github.com/project-oak/transparent-release/pkg/intoto"
+	pb "github.com/project-oak/transparent-release/pkg/proto/oak/release"
+)
+
+func newTestSigner(t *testing.T) *ed25519Signer {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	return &ed25519Signer{privateKey: privateKey}
+}
+
+// createSignedRevocation returns a revocation of the test binary, signed by
+// the given signer.
+func createSignedRevocation(t *testing.T, signer *ed25519Signer) []byte {
+	subject := intoto.Subject{Name: binaryName, Digest: intoto.DigestSet{"sha2-256": binaryDigest}}
+	clock := claims.FixedClock(time.Date(2023, 7, 1, 12, 0, 0, 0, time.UTC))
+	revocation := claims.GenerateRevocationStatement(subject, "CVE-2023-0001", clock)
+	envelope, err := SignStatement(context.Background(), revocation, signer)
+	if err != nil {
+		t.Fatalf("Failed to sign revocation: %v", err)
+	}
+	envelopeBytes, err := json.Marshal(envelope)
+	if err != nil {
+		t.Fatalf("Failed to marshal revocation: %v", err)
+	}
+	return envelopeBytes
+}
+
+func TestVerifyRevocation(t *testing.T) {
+	revocationKey := newTestSigner(t)
+	envelopeBytes := createSignedRevocation(t, revocationKey)
+
+	revocation, err := VerifyRevocation(context.Background(), envelopeBytes, revocationKey,
+		WithEndorsementKey(newTestSigner(t)))
+	if err != nil {
+		t.Fatalf("Failed to verify revocation: %v", err)
+	}
+	testutil.AssertEq(t, "binary digest", revocation.Subject[0].Digest["sha2-256"], binaryDigest)
+	predicate := revocation.Predicate.(claims.ClaimPredicate)
+	testutil.AssertEq(t, "claim type", predicate.ClaimType, claims.RevocationV1)
+}
+
+func TestVerifyRevocation_WrongSignerFails(t *testing.T) {
+	envelopeBytes := createSignedRevocation(t, newTestSigner(t))
+
+	_, err := VerifyRevocation(context.Background(), envelopeBytes, newTestSigner(t))
+	if err == nil || !strings.Contains(err.Error(), "could not verify the signature") {
+		t.Fatalf("Expected an error for a revocation signed by another key, got %v", err)
+	}
+}
+
+func TestVerifyRevocation_SignedByEndorsementKeyFails(t *testing.T) {
+	endorsementKey := newTestSigner(t)
+	envelopeBytes := createSignedRevocation(t, endorsementKey)
+
+	_, err := VerifyRevocation(context.Background(), envelopeBytes, newTestSigner(t), WithEndorsementKey(endorsementKey))
+	if err == nil || !strings.Contains(err.Error(), "signed by the endorsement key") {
+		t.Fatalf("Expected an error for a revocation signed by the endorsement key, got %v", err)
+	}
+}
+
+func TestVerifyRevocation_SharedKeyFails(t *testing.T) {
+	key := newTestSigner(t)
+	envelopeBytes := createSignedRevocation(t, key)
+
+	_, err := VerifyRevocation(context.Background(), envelopeBytes, key, WithEndorsementKey(&ed25519Signer{privateKey: key.privateKey}))
+	if err == nil || !strings.Contains(err.Error(), "must be different from the endorsement key") {
+		t.Fatalf("Expected an error for a shared key, got %v", err)
+	}
+}
+
+func TestVerifyRevocation_EndorsementRejected(t *testing.T) {
+	key := newTestSigner(t)
+	digests := intoto.DigestSet{"sha2-256": binaryDigest}
+	endorsement, err := GenerateEndorsement(binaryName, digests, &pb.VerificationOptions{}, createClaimValidity(7), []ParsedProvenance{})
+	if err != nil {
+		t.Fatalf("Failed to generate endorsement: %v", err)
+	}
+	envelope, err := SignStatement(context.Background(), endorsement, key)
+	if err != nil {
+		t.Fatalf("Failed to sign endorsement: %v", err)
+	}
+	envelopeBytes, err := json.Marshal(envelope)
+	if err != nil {
+		t.Fatalf("Failed to marshal endorsement: %v", err)
+	}
+
+	if _, err := VerifyRevocation(context.Background(), envelopeBytes, key); err == nil || !strings.Contains(err.Error(), "claim type") {
+		t.Fatalf("Expected an error for an endorsement, got %v", err)
+	}
+}
+
+func TestVerifyEndorsementRevocation(t *testing.T) {
+	endorsementKey := newTestSigner(t)
+	revocationKey := newTestSigner(t)
+	digests := intoto.DigestSet{"sha2-256": binaryDigest}
+	endorsement, err := GenerateEndorsement(binaryName, digests, &pb.VerificationOptions{}, createClaimValidity(7), []ParsedProvenance{})
+	if err != nil {
+		t.Fatalf("Failed to generate endorsement: %v", err)
+	}
+	envelope, err := SignStatement(context.Background(), endorsement, endorsementKey)
+	if err != nil {
+		t.Fatalf("Failed to sign endorsement: %v", err)
+	}
+	payload, err := envelope.DecodeB64Payload()
+	if err != nil {
+		t.Fatalf("Failed to decode endorsement: %v", err)
+	}
+	clock := claims.FixedClock(time.Date(2023, 7, 1, 12, 0, 0, 0, time.UTC))
+	revocation, err := claims.GenerateEndorsementRevocationStatement(payload, "wrong validity", clock)
+	if err != nil {
+		t.Fatalf("Failed to generate endorsement revocation: %v", err)
+	}
+	revocationEnvelope, err := SignStatement(context.Background(), revocation, revocationKey)
+	if err != nil {
+		t.Fatalf("Failed to sign endorsement revocation: %v", err)
+	}
+	envelopeBytes, err := json.Marshal(revocationEnvelope)
+	if err != nil {
+		t.Fatalf("Failed to marshal endorsement revocation: %v", err)
+	}
+
+	verified, err := VerifyEndorsementRevocation(context.Background(), envelopeBytes, revocationKey, WithEndorsementKey(endorsementKey))
+	if err != nil {
+		t.Fatalf("Failed to verify endorsement revocation: %v", err)
+	}
+	hash, err := claims.StatementHashFromBytes(payload)
+	if err != nil {
+		t.Fatalf("Failed to hash endorsement: %v", err)
+	}
+	if err := claims.CheckEndorsementNotRevoked(hash, verified); err == nil {
+		t.Errorf("Expected the endorsement to be revoked")
+	}
+
+	// A revocation of all claims about the binary is not an endorsement revocation.
+	if _, err := VerifyEndorsementRevocation(context.Background(), createSignedRevocation(t, revocationKey), revocationKey); err == nil || !strings.Contains(err.Error(), "claim type") {
+		t.Fatalf("Expected an error for a revocation of the binary, got %v", err)
+	}
+}
@@ -0,0 +1,385 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package endorser
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/secure-systems-lab/go-securesystemslib/dsse"
+
+	"github.com/project-oak/transparent-release/internal/model"
+	"github.com/project-oak/transparent-release/internal/oidc"
+	"github.com/project-oak/transparent-release/internal/rekor"
+	"github.com/project-oak/transparent-release/internal/verifier"
+	"github.com/project-oak/transparent-release/pkg/claims"
+	"github.com/project-oak/transparent-release/pkg/intoto"
+)
+
+// EndorsementsPath is the path at which Server accepts endorsement requests.
+const EndorsementsPath = "/v1/endorsements"
+
+// maxRequestBytes limits the size of endorsement requests.
+const maxRequestBytes = 1 << 20
+
+// Default settings for retrying the transient failures of fetching
+// provenances, and of signing and publishing endorsements.
+const (
+	DefaultRetryAttempts = 3
+	DefaultRetryBackoff  = 500 * time.Millisecond
+)
+
+// EndorseRequest is the JSON body of a request for an endorsement.
+type EndorseRequest struct {
+	// RequestID optionally makes the request idempotent. The server responds
+	// to a request with the same ID and the same inputs as an earlier one,
+	// e.g., when the caller retries a request that timed out, with the
+	// endorsement issued for the earlier request, and rejects a request with
+	// the same ID but different inputs. IDs are scoped to the caller, if the
+	// server verifies callers, and remembered for DefaultIdempotencyTTL.
+	RequestID string `json:"requestID,omitempty"`
+	// BinaryName is the name of the binary to endorse. Must match the binary
+	// names in all provenances.
+	BinaryName string `json:"binaryName"`
+	// Digests of the binary. Must contain a SHA2-256 digest.
+	Digests intoto.DigestSet `json:"digests"`
+	// ProvenanceURIs are HTTP(S) URIs of one or more provenances. Requests
+	// without provenances are rejected, even with SkipVerification.
+	ProvenanceURIs []string `json:"provenanceURIs"`
+	// VerificationOptions as textproto. Must be set, unless SkipVerification
+	// is set.
+	VerificationOptions string `json:"verificationOptions"`
+	// SkipVerification confirms that empty VerificationOptions are intended.
+	SkipVerification bool `json:"skipVerification"`
+	// NotBefore is the optional start of the validity of the endorsement.
+	// Defaults to the start of the next day.
+	NotBefore *time.Time `json:"notBefore,omitempty"`
+	// NotAfter is the optional end of the validity of the endorsement.
+	// Defaults to 90 days after the start of the current day.
+	NotAfter *time.Time `json:"notAfter,omitempty"`
+}
+
+// EndorseResponse is the JSON body of the response to an EndorseRequest.
+type EndorseResponse struct {
+	// Endorsement is the signed endorsement.
+	Endorsement *dsse.Envelope `json:"endorsement"`
+	// LogEntry is the Rekor log entry of the signed endorsement, if the
+	// server publishes endorsements.
+	LogEntry *rekor.LogEntry `json:"logEntry,omitempty"`
+}
+
+// ServerConfig holds optional settings for a Server.
+type ServerConfig struct {
+	clock                 claims.Clock
+	callerVerifier        *oidc.Verifier
+	rekorClient           *rekor.Client
+	verifierPEM           []byte
+	retryAttempts         int
+	retryBackoff          time.Duration
+	idempotencyTTL        time.Duration
+	requestTimeout        time.Duration
+	idempotencyMaxEntries int
+}
+
+// WithServerClock sets the clock for the issuance time and default validity
+// of endorsements. Defaults to the system clock.
+func WithServerClock(clock claims.Clock) func(c *ServerConfig) {
+	return func(c *ServerConfig) {
+		c.clock = clock
+	}
+}
+
+// WithCallerVerifier requires every request to carry an OIDC token of the
+// caller as a bearer token, verified by the given verifier. The repository
+// of the caller must match the repository of all provenances, and the
+// identity of the caller is included as evidence in the endorsement.
+func WithCallerVerifier(verifier *oidc.Verifier) func(c *ServerConfig) {
+	return func(c *ServerConfig) {
+		c.callerVerifier = verifier
+	}
+}
+
+// WithRekor publishes every signed endorsement to the Rekor log behind the
+// given client. verifierPEM is the PEM-encoded public key or certificate of
+// the signer of the server.
+func WithRekor(client *rekor.Client, verifierPEM []byte) func(c *ServerConfig) {
+	return func(c *ServerConfig) {
+		c.rekorClient = client
+		c.verifierPEM = verifierPEM
+	}
+}
+
+// WithRetries sets how many times fetching provenances, and signing and
+// publishing an endorsement, is attempted, and how long to wait before the
+// first retry. The wait doubles for every further retry. Defaults to
+// DefaultRetryAttempts and DefaultRetryBackoff.
+func WithRetries(attempts int, backoff time.Duration) func(c *ServerConfig) {
+	return func(c *ServerConfig) {
+		c.retryAttempts = attempts
+		c.retryBackoff = backoff
+	}
+}
+
+// WithIdempotencyTTL sets how long the responses to requests with a request
+// ID are remembered. Defaults to DefaultIdempotencyTTL.
+func WithIdempotencyTTL(ttl time.Duration) func(c *ServerConfig) {
+	return func(c *ServerConfig) {
+		c.idempotencyTTL = ttl
+	}
+}
+
+// WithIdempotencyMaxEntries sets how many responses to requests with a
+// request ID are remembered at most. When the limit is reached, the oldest
+// response is forgotten. Defaults to DefaultIdempotencyMaxEntries.
+func WithIdempotencyMaxEntries(maxEntries int) func(c *ServerConfig) {
+	return func(c *ServerConfig) {
+		c.idempotencyMaxEntries = maxEntries
+	}
+}
+
+// WithRequestTimeout bounds the time for handling a request, including
+// loading the provenances, and signing and publishing the endorsement.
+// Requests that time out are answered with 504 Gateway Timeout. By default,
+// requests are only canceled when the client disconnects.
+func WithRequestTimeout(timeout time.Duration) func(c *ServerConfig) {
+	return func(c *ServerConfig) {
+		c.requestTimeout = timeout
+	}
+}
+
+// Server is an HTTP handler that verifies provenances and issues signed
+// endorsements, for release pipelines that cannot run the endorser directly.
+// It accepts POST requests at EndorsementsPath, with an EndorseRequest as
+// JSON body, and responds with an EndorseResponse.
+type Server struct {
+	signer    dsse.SignerVerifier
+	config    *ServerConfig
+	responses *idempotencyCache
+}
+
+// NewServer creates a Server that signs endorsements with the given signer.
+func NewServer(signer dsse.SignerVerifier, options ...func(c *ServerConfig)) *Server {
+	config := &ServerConfig{
+		clock:                 claims.SystemClock(),
+		retryAttempts:         DefaultRetryAttempts,
+		retryBackoff:          DefaultRetryBackoff,
+		idempotencyTTL:        DefaultIdempotencyTTL,
+		idempotencyMaxEntries: DefaultIdempotencyMaxEntries,
+	}
+	for _, addOption := range options {
+		addOption(config)
+	}
+	return &Server{signer: signer, config: config, responses: newIdempotencyCache(config.idempotencyTTL, config.idempotencyMaxEntries)}
+}
+
+// httpError is an error with the HTTP status code of the response.
+type httpError struct {
+	status int
+	err    error
+}
+
+func (e *httpError) Error() string {
+	return e.err.Error()
+}
+
+func newHTTPError(status int, format string, a ...interface{}) *httpError {
+	return &httpError{status: status, err: fmt.Errorf(format, a...)}
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != EndorsementsPath {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "only POST is supported", http.StatusMethodNotAllowed)
+		return
+	}
+
+	response, err := s.endorse(r)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if httpErr, ok := err.(*httpError); ok {
+			status = httpErr.status
+		}
+		log.Printf("Failed to handle the endorsement request: %v", err)
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("Failed to write the endorsement response: %v", err)
+	}
+}
+
+func (s *Server) endorse(r *http.Request) (*EndorseResponse, error) {
+	ctx := r.Context()
+	if s.config.requestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.config.requestTimeout)
+		defer cancel()
+	}
+	response, err := s.endorseWithContext(ctx, r)
+	if err != nil && ctx.Err() == context.DeadlineExceeded {
+		return nil, newHTTPError(http.StatusGatewayTimeout, "the request timed out: %v", err)
+	}
+	return response, err
+}
+
+func (s *Server) endorseWithContext(ctx context.Context, r *http.Request) (*EndorseResponse, error) {
+	var options []func(c *claims.EndorsementConfig)
+	var caller *oidc.GitHubClaims
+	if s.config.callerVerifier != nil {
+		authorization := r.Header.Get("Authorization")
+		if !strings.HasPrefix(authorization, "Bearer ") {
+			return nil, newHTTPError(http.StatusUnauthorized, "missing bearer token")
+		}
+		token := strings.TrimPrefix(authorization, "Bearer ")
+		var err error
+		caller, err = s.config.callerVerifier.Verify(ctx, token)
+		if err != nil {
+			return nil, newHTTPError(http.StatusUnauthorized, "invalid bearer token: %v", err)
+		}
+		options = append(options, claims.WithEvidence(CallerEvidence(caller)))
+	}
+
+	request, err := parseEndorseRequest(r.Body)
+	if err != nil {
+		return nil, newHTTPError(http.StatusBadRequest, "%v", err)
+	}
+	if request.RequestID == "" {
+		return s.issue(ctx, request, caller, options)
+	}
+
+	fingerprint, err := requestFingerprint(request)
+	if err != nil {
+		return nil, err
+	}
+	key := request.RequestID
+	if caller != nil {
+		key = caller.Subject + "\x00" + key
+	}
+	return s.responses.do(ctx, key, fingerprint, s.config.clock.Now(), func() (*EndorseResponse, error) {
+		return s.issue(ctx, request, caller, options)
+	})
+}
+
+// issue verifies the provenances of the given request, and issues, signs, and
+// publishes an endorsement.
+func (s *Server) issue(ctx context.Context, request *EndorseRequest, caller *oidc.GitHubClaims, options []func(c *claims.EndorsementConfig)) (*EndorseResponse, error) {
+	verOpts, err := verifier.ParseVerificationOptions(request.VerificationOptions)
+	if err != nil {
+		return nil, newHTTPError(http.StatusBadRequest, "%v", err)
+	}
+	provenances, err := LoadProvenances(ctx, request.ProvenanceURIs, WithSubjectDigests(request.Digests),
+		WithFetchRetries(s.config.retryAttempts, s.config.retryBackoff))
+	if err != nil {
+		return nil, newHTTPError(http.StatusBadRequest, "loading provenances: %v", err)
+	}
+	if caller != nil {
+		if err := VerifyCaller(caller, provenances); err != nil {
+			return nil, newHTTPError(http.StatusForbidden, "%v", err)
+		}
+	}
+
+	validity := s.validity(request)
+	options = append(options, claims.WithClock(s.config.clock))
+	endorsement, err := GenerateEndorsement(request.BinaryName, request.Digests, verOpts, validity, provenances, options...)
+	if err != nil {
+		return nil, newHTTPError(http.StatusUnprocessableEntity, "%v", err)
+	}
+
+	// Signing and publishing depend on external services, so their failures
+	// may be transient.
+	response := &EndorseResponse{}
+	err = retry(ctx, s.config.retryAttempts, s.config.retryBackoff, func() error {
+		response.Endorsement, err = SignStatement(ctx, endorsement, s.signer)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if s.config.rekorClient != nil {
+		err = retry(ctx, s.config.retryAttempts, s.config.retryBackoff, func() error {
+			response.LogEntry, err = PublishEndorsement(ctx, s.config.rekorClient, response.Endorsement, s.signer, s.config.verifierPEM)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return response, nil
+}
+
+// validity returns the validity of the endorsement for the given request.
+func (s *Server) validity(request *EndorseRequest) claims.ClaimValidity {
+	today := s.config.clock.Now().UTC().Truncate(24 * time.Hour)
+	notBefore := today.AddDate(0, 0, 1)
+	if request.NotBefore != nil {
+		notBefore = *request.NotBefore
+	}
+	notAfter := today.AddDate(0, 0, 90)
+	if request.NotAfter != nil {
+		notAfter = *request.NotAfter
+	}
+	return claims.ClaimValidity{NotBefore: &notBefore, NotAfter: &notAfter}
+}
+
+// parseEndorseRequest parses and validates an EndorseRequest.
+func parseEndorseRequest(body io.Reader) (*EndorseRequest, error) {
+	var request EndorseRequest
+	decoder := json.NewDecoder(io.LimitReader(body, maxRequestBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&request); err != nil {
+		return nil, fmt.Errorf("parsing the request: %v", err)
+	}
+
+	if request.BinaryName == "" {
+		return nil, fmt.Errorf("binaryName not set")
+	}
+	digests, err := model.NormalizeDigestSet(request.Digests)
+	if err != nil {
+		return nil, fmt.Errorf("invalid digests: %v", err)
+	}
+	if digests["sha2-256"] == "" {
+		return nil, fmt.Errorf("digests must contain a SHA2-256 digest")
+	}
+	request.Digests = digests
+	if request.VerificationOptions == "" && !request.SkipVerification {
+		return nil, fmt.Errorf("verificationOptions empty, set skipVerification to overrule")
+	}
+	if len(request.ProvenanceURIs) == 0 {
+		return nil, fmt.Errorf("provenanceURIs empty, at least one provenance is required")
+	}
+	// Provenances are fetched by the server, so local files must not be
+	// accessible to callers.
+	for _, uri := range request.ProvenanceURIs {
+		parsed, err := url.Parse(uri)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+			return nil, fmt.Errorf("unsupported provenance URI %q, want an HTTP(S) URI", uri)
+		}
+	}
+	return &request, nil
+}
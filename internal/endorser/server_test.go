@@ -0,0 +1,410 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package endorser
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/project-oak/transparent-release/internal/oidc"
+	"github.com/project-oak/transparent-release/internal/testutil"
+	"github.com/project-oak/transparent-release/pkg/claims"
+	"github.com/secure-systems-lab/go-securesystemslib/dsse"
+)
+
+// newTestServer starts an endorsement server, and a server hosting the
+// provenance in provenancePath at /provenance.json.
+func newTestServer(t *testing.T) (*httptest.Server, string, *ed25519Signer) {
+	provenances := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, provenancePath)
+	}))
+	t.Cleanup(provenances.Close)
+
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	signer := &ed25519Signer{privateKey: privateKey}
+	clock := claims.FixedClock(time.Date(2023, 6, 1, 10, 0, 0, 0, time.UTC))
+	server := httptest.NewServer(NewServer(signer, WithServerClock(clock)))
+	t.Cleanup(server.Close)
+	return server, provenances.URL + "/provenance.json", signer
+}
+
+func postEndorseRequest(t *testing.T, url string, request interface{}) *http.Response {
+	body, err := json.Marshal(request)
+	if err != nil {
+		t.Fatalf("Failed to marshal the request: %v", err)
+	}
+	resp, err := http.Post(url+EndorsementsPath, "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("Failed to send the request: %v", err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestServer_Endorse(t *testing.T) {
+	server, provenanceURI, signer := newTestServer(t)
+
+	resp := postEndorseRequest(t, server.URL, EndorseRequest{
+		BinaryName:          binaryName,
+		Digests:             map[string]string{"sha256": binaryDigest},
+		ProvenanceURIs:      []string{provenanceURI},
+		VerificationOptions: "provenance_count_at_least { count: 1 }",
+	})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Unexpected status: %s", resp.Status)
+	}
+	var response EndorseResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode the response: %v", err)
+	}
+
+	envelopeVerifier, err := dsse.NewEnvelopeVerifier(signer)
+	if err != nil {
+		t.Fatalf("Failed to create an envelope verifier: %v", err)
+	}
+	if _, err := envelopeVerifier.Verify(context.Background(), response.Endorsement); err != nil {
+		t.Fatalf("Failed to verify the signed endorsement: %v", err)
+	}
+	payload, err := response.Endorsement.DecodeB64Payload()
+	if err != nil {
+		t.Fatalf("Failed to decode the payload: %v", err)
+	}
+	statement, err := claims.ParseEndorsementV2Bytes(payload)
+	if err != nil {
+		t.Fatalf("Failed to parse the endorsement: %v", err)
+	}
+	predicate := statement.Predicate.(claims.ClaimPredicate)
+	if want := time.Date(2023, 6, 2, 0, 0, 0, 0, time.UTC); !predicate.Validity.NotBefore.Equal(want) {
+		t.Errorf("Unexpected notBefore: got %v, want %v", predicate.Validity.NotBefore, want)
+	}
+	if resp.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("Unexpected content type: %q", resp.Header.Get("Content-Type"))
+	}
+}
+
+func TestServer_InvalidRequests(t *testing.T) {
+	server, provenanceURI, _ := newTestServer(t)
+	tests := map[string]struct {
+		request interface{}
+		status  int
+	}{
+		"unknown field": {
+			request: map[string]interface{}{"binaryName": binaryName, "unknown": true},
+			status:  http.StatusBadRequest,
+		},
+		"missing verification options": {
+			request: EndorseRequest{BinaryName: binaryName, Digests: map[string]string{"sha256": binaryDigest}},
+			status:  http.StatusBadRequest,
+		},
+		"no provenances": {
+			request: EndorseRequest{
+				BinaryName:       binaryName,
+				Digests:          map[string]string{"sha256": binaryDigest},
+				SkipVerification: true,
+			},
+			status: http.StatusBadRequest,
+		},
+		"local provenance": {
+			request: EndorseRequest{
+				BinaryName:       binaryName,
+				Digests:          map[string]string{"sha256": binaryDigest},
+				ProvenanceURIs:   []string{"file:///etc/passwd"},
+				SkipVerification: true,
+			},
+			status: http.StatusBadRequest,
+		},
+		"digest mismatch": {
+			request: EndorseRequest{
+				BinaryName:       binaryName,
+				Digests:          map[string]string{"sha256": "813841dda3818d616aa3e706e49d0286dc825c5dbad4a75cfb37b91ba412238b"},
+				ProvenanceURIs:   []string{provenanceURI},
+				SkipVerification: true,
+			},
+			status: http.StatusUnprocessableEntity,
+		},
+	}
+	for name, test := range tests {
+		resp := postEndorseRequest(t, server.URL, test.request)
+		if resp.StatusCode != test.status {
+			t.Errorf("Unexpected status for %s: got %d, want %d", name, resp.StatusCode, test.status)
+		}
+	}
+
+	resp, err := http.Get(server.URL + EndorsementsPath)
+	if err != nil {
+		t.Fatalf("Failed to send the request: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("Unexpected status for GET: got %d, want %d", resp.StatusCode, http.StatusMethodNotAllowed)
+	}
+}
+
+// newCallerToken starts a server publishing the key of a test OIDC issuer, and
+// returns a verifier for its tokens, and a token with the given claims.
+func newCallerToken(t *testing.T, audience string, tokenClaims map[string]interface{}) (*oidc.Verifier, string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	jwks := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys := map[string][]map[string]string{"keys": {{
+			"kty": "RSA",
+			"kid": "test-kid",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}}
+		if err := json.NewEncoder(w).Encode(keys); err != nil {
+			t.Errorf("Failed to encode keys: %v", err)
+		}
+	}))
+	t.Cleanup(jwks.Close)
+
+	encode := func(v interface{}) string {
+		bytes, err := json.Marshal(v)
+		if err != nil {
+			t.Fatalf("Failed to marshal: %v", err)
+		}
+		return base64.RawURLEncoding.EncodeToString(bytes)
+	}
+	signingInput := encode(map[string]string{"alg": "RS256", "kid": "test-kid"}) + "." + encode(tokenClaims)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatalf("Failed to sign the token: %v", err)
+	}
+	verifier := oidc.NewVerifier(oidc.GitHubActionsIssuer, jwks.URL, audience)
+	return verifier, signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestServer_EndorseWithCallerEvidence(t *testing.T) {
+	_, provenanceURI, signer := newTestServer(t)
+	verifier, token := newCallerToken(t, "transparent-release", map[string]interface{}{
+		"iss":              oidc.GitHubActionsIssuer,
+		"aud":              "transparent-release",
+		"exp":              time.Now().Add(5 * time.Minute).Unix(),
+		"repository":       "project-oak/oak",
+		"workflow":         "Release",
+		"job_workflow_ref": "project-oak/oak/.github/workflows/release.yml@refs/heads/main",
+		"run_id":           "1234",
+		"run_attempt":      "1",
+	})
+	clock := claims.FixedClock(time.Date(2023, 6, 1, 10, 0, 0, 0, time.UTC))
+	server := httptest.NewServer(NewServer(signer, WithServerClock(clock), WithCallerVerifier(verifier)))
+	t.Cleanup(server.Close)
+
+	body, err := json.Marshal(EndorseRequest{
+		BinaryName:          binaryName,
+		Digests:             map[string]string{"sha256": binaryDigest},
+		ProvenanceURIs:      []string{provenanceURI},
+		VerificationOptions: "provenance_count_at_least { count: 1 }",
+	})
+	if err != nil {
+		t.Fatalf("Failed to marshal the request: %v", err)
+	}
+	req, err := http.NewRequest(http.MethodPost, server.URL+EndorsementsPath, bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("Failed to create the request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to send the request: %v", err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	response := decodeEndorseResponse(t, resp)
+
+	payload, err := response.Endorsement.DecodeB64Payload()
+	if err != nil {
+		t.Fatalf("Failed to decode the payload: %v", err)
+	}
+	statement, err := claims.ParseEndorsementV2Bytes(payload)
+	if err != nil {
+		t.Fatalf("Failed to parse the endorsement: %v", err)
+	}
+	predicate := statement.Predicate.(claims.ClaimPredicate)
+	testutil.AssertEq(t, "evidence length", len(predicate.Evidence), 2)
+	testutil.AssertEq(t, "caller role", predicate.Evidence[1].Role, CallerIdentityRole)
+
+	// All evidence, including the caller identity, can be verified.
+	if err := VerifyEvidence(context.Background(), statement); err != nil {
+		t.Errorf("Failed to verify the evidence: %v", err)
+	}
+}
+
+// advancingClock reports a later time on every call.
+type advancingClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *advancingClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(time.Second)
+	return c.now
+}
+
+// flakySigner fails the given number of times before signing.
+type flakySigner struct {
+	*ed25519Signer
+	mu       sync.Mutex
+	failures int
+	calls    int
+}
+
+func (s *flakySigner) Sign(ctx context.Context, data []byte) ([]byte, error) {
+	s.mu.Lock()
+	s.calls++
+	fail := s.calls <= s.failures
+	s.mu.Unlock()
+	if fail {
+		return nil, fmt.Errorf("transient failure")
+	}
+	return s.ed25519Signer.Sign(ctx, data)
+}
+
+func newFlakyTestServer(t *testing.T, failures int, options ...func(c *ServerConfig)) (*httptest.Server, string, *flakySigner) {
+	_, provenanceURI, signer := newTestServer(t)
+	flaky := &flakySigner{ed25519Signer: signer, failures: failures}
+	options = append([]func(c *ServerConfig){
+		WithServerClock(&advancingClock{now: time.Date(2023, 6, 1, 10, 0, 0, 0, time.UTC)}),
+		WithRetries(3, time.Millisecond),
+	}, options...)
+	server := httptest.NewServer(NewServer(flaky, options...))
+	t.Cleanup(server.Close)
+	return server, provenanceURI, flaky
+}
+
+func decodeEndorseResponse(t *testing.T, resp *http.Response) *EndorseResponse {
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Unexpected status: %s", resp.Status)
+	}
+	var response EndorseResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode the response: %v", err)
+	}
+	return &response
+}
+
+func TestServer_IdempotentRequests(t *testing.T) {
+	server, provenanceURI, signer := newFlakyTestServer(t, 0)
+	request := EndorseRequest{
+		RequestID:           "ci-run-1",
+		BinaryName:          binaryName,
+		Digests:             map[string]string{"sha256": binaryDigest},
+		ProvenanceURIs:      []string{provenanceURI},
+		VerificationOptions: "provenance_count_at_least { count: 1 }",
+	}
+
+	first := decodeEndorseResponse(t, postEndorseRequest(t, server.URL, request))
+	retried := decodeEndorseResponse(t, postEndorseRequest(t, server.URL, request))
+	if first.Endorsement.Payload != retried.Endorsement.Payload {
+		t.Errorf("Expected the retried request to return the same endorsement")
+	}
+	if signer.calls != 1 {
+		t.Errorf("Unexpected number of signatures: got %d, want 1", signer.calls)
+	}
+
+	// Without a request ID, every request is issued a new endorsement, with a
+	// different issuance time.
+	request.RequestID = ""
+	other := decodeEndorseResponse(t, postEndorseRequest(t, server.URL, request))
+	if other.Endorsement.Payload == first.Endorsement.Payload {
+		t.Errorf("Expected a new endorsement for a request without ID")
+	}
+
+	// Reusing the request ID with different inputs is a conflict.
+	request.RequestID = "ci-run-1"
+	request.SkipVerification = true
+	if resp := postEndorseRequest(t, server.URL, request); resp.StatusCode != http.StatusConflict {
+		t.Errorf("Unexpected status for a conflicting request: got %d, want %d", resp.StatusCode, http.StatusConflict)
+	}
+}
+
+func TestServer_RetriesTransientFailures(t *testing.T) {
+	request := EndorseRequest{
+		RequestID:           "ci-run-1",
+		BinaryName:          binaryName,
+		Digests:             map[string]string{"sha256": binaryDigest},
+		VerificationOptions: "provenance_count_at_least { count: 1 }",
+	}
+
+	server, provenanceURI, signer := newFlakyTestServer(t, 2)
+	request.ProvenanceURIs = []string{provenanceURI}
+	decodeEndorseResponse(t, postEndorseRequest(t, server.URL, request))
+	if signer.calls != 3 {
+		t.Errorf("Unexpected number of signing attempts: got %d, want 3", signer.calls)
+	}
+
+	// A request that fails after all retries is not remembered, so that it
+	// can be retried by the caller.
+	server, provenanceURI, signer = newFlakyTestServer(t, 3)
+	request.ProvenanceURIs = []string{provenanceURI}
+	if resp := postEndorseRequest(t, server.URL, request); resp.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("Unexpected status: got %d, want %d", resp.StatusCode, http.StatusInternalServerError)
+	}
+	decodeEndorseResponse(t, postEndorseRequest(t, server.URL, request))
+	if signer.calls != 4 {
+		t.Errorf("Unexpected number of signing attempts: got %d, want 4", signer.calls)
+	}
+}
+
+func TestServer_RequestTimeout(t *testing.T) {
+	release := make(chan struct{})
+	provenances := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(provenances.Close)
+	t.Cleanup(func() { close(release) })
+
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	signer := &ed25519Signer{privateKey: privateKey}
+	server := httptest.NewServer(NewServer(signer, WithRequestTimeout(100*time.Millisecond)))
+	t.Cleanup(server.Close)
+
+	resp := postEndorseRequest(t, server.URL, EndorseRequest{
+		BinaryName:          binaryName,
+		Digests:             map[string]string{"sha256": binaryDigest},
+		ProvenanceURIs:      []string{provenances.URL + "/provenance.json"},
+		VerificationOptions: "provenance_count_at_least { count: 1 }",
+	})
+	if resp.StatusCode != http.StatusGatewayTimeout {
+		t.Errorf("Unexpected status: got %d, want %d", resp.StatusCode, http.StatusGatewayTimeout)
+	}
+}
@@ -0,0 +1,178 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ent provides a client for resolving and uploading blobs in an
+// [Ent](https://github.com/google/ent) server, a permanent content-addressable
+// store. Blobs are referenced by `ent:sha256:<hex digest>` URIs, which remain
+// valid, unlike the URIs of files in buckets that expire.
+package ent
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// DefaultURL is the URL of the Ent server used by Project Oak.
+const DefaultURL = "https://ent-server-62sa4xcfia-ew.a.run.app"
+
+// Scheme is the scheme of Ent URIs.
+const Scheme = "ent"
+
+// DefaultMaxBytes is the default maximum size of the blobs fetched by
+// Client.Get.
+const DefaultMaxBytes = 64 << 20
+
+// ClientConfig holds optional settings for a Client.
+type ClientConfig struct {
+	maxBytes int64
+}
+
+// WithMaxBytes sets the maximum size of the blobs fetched by Client.Get.
+// Defaults to DefaultMaxBytes.
+func WithMaxBytes(maxBytes int64) func(c *ClientConfig) {
+	return func(c *ClientConfig) {
+		c.maxBytes = maxBytes
+	}
+}
+
+// Client resolves and uploads blobs in an Ent server.
+type Client struct {
+	baseURL  string
+	apiKey   string
+	client   *http.Client
+	maxBytes int64
+}
+
+// NewClient creates a new Client for the Ent server at the given URL. The API
+// key is only needed for uploading blobs.
+func NewClient(baseURL, apiKey string, options ...func(c *ClientConfig)) *Client {
+	config := &ClientConfig{maxBytes: DefaultMaxBytes}
+	for _, addOption := range options {
+		addOption(config)
+	}
+	return &Client{
+		baseURL:  strings.TrimSuffix(baseURL, "/"),
+		apiKey:   apiKey,
+		client:   &http.Client{},
+		maxBytes: config.maxBytes,
+	}
+}
+
+// URI returns the Ent URI of the blob with the given hex-encoded SHA2-256
+// digest.
+func URI(sha256Digest string) string {
+	return fmt.Sprintf("%s:sha256:%s", Scheme, strings.ToLower(sha256Digest))
+}
+
+// ParseURI returns the hex-encoded SHA2-256 digest in the given Ent URI, of
+// the form `ent:sha256:<hex digest>` or `ent:sha2-256:<hex digest>`.
+func ParseURI(uri string) (string, error) {
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		return "", fmt.Errorf("could not parse the URI (%q): %v", uri, err)
+	}
+	if !strings.EqualFold(parsed.Scheme, Scheme) {
+		return "", fmt.Errorf("unexpected scheme of %q, want %q", uri, Scheme)
+	}
+	algorithm, digest, found := strings.Cut(parsed.Opaque, ":")
+	if !found || (algorithm != "sha256" && algorithm != "sha2-256") {
+		return "", fmt.Errorf("invalid Ent URI %q, want %s:sha256:<hex digest>", uri, Scheme)
+	}
+	digest = strings.ToLower(digest)
+	if decoded, err := hex.DecodeString(digest); err != nil || len(decoded) != sha256.Size {
+		return "", fmt.Errorf("invalid SHA2-256 digest in %q", uri)
+	}
+	return digest, nil
+}
+
+// Get fetches the blob with the given hex-encoded SHA2-256 digest, and checks
+// that its content matches the digest. Fails for blobs larger than the
+// maximum size of the client.
+func (c *Client) Get(ctx context.Context, sha256Digest string) ([]byte, error) {
+	sha256Digest = strings.ToLower(sha256Digest)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/raw/sha256:"+sha256Digest, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not create HTTP request: %v", err)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("could not receive response from Ent: %v", err)
+	}
+	defer resp.Body.Close()
+
+	// Read one more byte than allowed, to detect larger blobs.
+	content, err := io.ReadAll(io.LimitReader(resp.Body, c.maxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("could not read the Ent response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching the blob sha256:%s failed with status %d: %s", sha256Digest, resp.StatusCode, content)
+	}
+	if int64(len(content)) > c.maxBytes {
+		return nil, fmt.Errorf("the blob sha256:%s is larger than the maximum size of %d bytes", sha256Digest, c.maxBytes)
+	}
+	sum256 := sha256.Sum256(content)
+	if got := hex.EncodeToString(sum256[:]); got != sha256Digest {
+		return nil, fmt.Errorf("unexpected SHA2-256 digest of the blob: got %s, want %s", got, sha256Digest)
+	}
+	return content, nil
+}
+
+// Put uploads the given content, and returns its Ent URI.
+func (c *Client) Put(ctx context.Context, content []byte) (string, error) {
+	if c.apiKey == "" {
+		return "", fmt.Errorf("an API key is required for uploading to Ent")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, c.baseURL+"/raw", bytes.NewReader(content))
+	if err != nil {
+		return "", fmt.Errorf("could not create HTTP request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("x-api-key", c.apiKey)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("could not receive response from Ent: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		body, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("uploading the blob failed with status %d: %s", resp.StatusCode, body)
+	}
+	sum256 := sha256.Sum256(content)
+	return URI(hex.EncodeToString(sum256[:])), nil
+}
+
+// Fetch resolves the given Ent URI. It implements fetch.Fetcher, so that Ent
+// URIs can be used wherever provenances and other evidence are fetched.
+func (c *Client) Fetch(ctx context.Context, uri *url.URL) (io.ReadCloser, error) {
+	digest, err := ParseURI(uri.String())
+	if err != nil {
+		return nil, err
+	}
+	content, err := c.Get(ctx, digest)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(content)), nil
+}
@@ -0,0 +1,163 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ent
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/project-oak/transparent-release/internal/testutil"
+)
+
+const testAPIKey = "secret"
+
+// newFakeEnt starts a test server that stores blobs in memory. If tamper is
+// set, it serves different content than was uploaded.
+func newFakeEnt(t *testing.T, tamper bool) *httptest.Server {
+	blobs := make(map[string][]byte)
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPut && r.URL.Path == "/raw":
+			if r.Header.Get("x-api-key") != testAPIKey {
+				http.Error(w, "invalid API key", http.StatusForbidden)
+				return
+			}
+			content, err := io.ReadAll(r.Body)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			sum256 := sha256.Sum256(content)
+			blobs["sha256:"+hex.EncodeToString(sum256[:])] = content
+			w.WriteHeader(http.StatusCreated)
+		case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/raw/"):
+			content, ok := blobs[strings.TrimPrefix(r.URL.Path, "/raw/")]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			if tamper {
+				content = append(content, '!')
+			}
+			_, _ = w.Write(content)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func TestParseURI(t *testing.T) {
+	digest := strings.Repeat("ab", 32)
+	for _, uri := range []string{URI(digest), "ent:sha2-256:" + digest, "ent:sha256:" + strings.ToUpper(digest)} {
+		got, err := ParseURI(uri)
+		if err != nil {
+			t.Fatalf("could not parse %q: %v", uri, err)
+		}
+		testutil.AssertEq(t, uri, got, digest)
+	}
+	for _, uri := range []string{"gs://bucket/" + digest, "ent:md5:" + digest, "ent:sha256:abc", "ent:" + digest} {
+		if _, err := ParseURI(uri); err == nil {
+			t.Errorf("expected failure for %q", uri)
+		}
+	}
+}
+
+func TestClient(t *testing.T) {
+	server := newFakeEnt(t, false)
+	defer server.Close()
+	ctx := context.Background()
+	client := NewClient(server.URL, testAPIKey)
+
+	uri, err := client.Put(ctx, []byte("evidence"))
+	if err != nil {
+		t.Fatalf("could not upload the blob: %v", err)
+	}
+	sum256 := sha256.Sum256([]byte("evidence"))
+	testutil.AssertEq(t, "uri", uri, "ent:sha256:"+hex.EncodeToString(sum256[:]))
+
+	// The blob can be fetched without an API key.
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		t.Fatalf("could not parse %q: %v", uri, err)
+	}
+	reader, err := NewClient(server.URL, "").Fetch(ctx, parsed)
+	if err != nil {
+		t.Fatalf("could not fetch the blob: %v", err)
+	}
+	defer reader.Close()
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("could not read the blob: %v", err)
+	}
+	testutil.AssertEq(t, "content", string(content), "evidence")
+
+	if _, err := client.Get(ctx, strings.Repeat("0", 64)); err == nil {
+		t.Errorf("expected failure for a missing blob")
+	}
+}
+
+func TestClient_Failures(t *testing.T) {
+	server := newFakeEnt(t, true)
+	defer server.Close()
+	ctx := context.Background()
+
+	if _, err := NewClient(server.URL, "").Put(ctx, []byte("evidence")); err == nil {
+		t.Errorf("expected failure for an upload without an API key")
+	}
+	if _, err := NewClient(server.URL, "wrong").Put(ctx, []byte("evidence")); err == nil {
+		t.Errorf("expected failure for an upload with a wrong API key")
+	}
+
+	client := NewClient(server.URL, testAPIKey)
+	uri, err := client.Put(ctx, []byte("evidence"))
+	if err != nil {
+		t.Fatalf("could not upload the blob: %v", err)
+	}
+	digest, err := ParseURI(uri)
+	if err != nil {
+		t.Fatalf("could not parse %q: %v", uri, err)
+	}
+	if _, err := client.Get(ctx, digest); err == nil {
+		t.Errorf("expected failure for tampered content")
+	}
+}
+
+func TestClient_MaxBytes(t *testing.T) {
+	server := newFakeEnt(t, false)
+	defer server.Close()
+	ctx := context.Background()
+
+	uri, err := NewClient(server.URL, testAPIKey).Put(ctx, []byte("evidence"))
+	if err != nil {
+		t.Fatalf("could not upload the blob: %v", err)
+	}
+	digest, err := ParseURI(uri)
+	if err != nil {
+		t.Fatalf("could not parse %q: %v", uri, err)
+	}
+	if _, err := NewClient(server.URL, "", WithMaxBytes(int64(len("evidence")))).Get(ctx, digest); err != nil {
+		t.Errorf("unexpected error for a blob of the maximum size: %v", err)
+	}
+	if _, err := NewClient(server.URL, "", WithMaxBytes(int64(len("evidence"))-1)).Get(ctx, digest); err == nil || !strings.Contains(err.Error(), "maximum size") {
+		t.Errorf("expected failure for a blob larger than the maximum size, got: %v", err)
+	}
+}
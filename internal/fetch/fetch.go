@@ -0,0 +1,356 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package fetch provides a registry of fetchers for the URIs of provenances,
+// reports, and other evidence, dispatching on the URI scheme, with
+// consistent integrity checks, timeouts, and size limits.
+package fetch
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/project-oak/transparent-release/internal/blobstore"
+	"github.com/project-oak/transparent-release/internal/cache"
+	"github.com/project-oak/transparent-release/internal/ent"
+	"github.com/project-oak/transparent-release/internal/oci"
+)
+
+// DefaultTimeout is the default timeout for fetching a single URI.
+const DefaultTimeout = time.Minute
+
+// DefaultMaxBytes is the default maximum size of fetched content.
+const DefaultMaxBytes = 64 << 20
+
+// DefaultRetryBackoff is the default wait before the first retry of a fetch
+// that failed with a transient error.
+const DefaultRetryBackoff = time.Second
+
+// StatusError is returned for an unsuccessful HTTP response.
+type StatusError struct {
+	URI        string
+	StatusCode int
+	Status     string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected response from %q: %s", e.URI, e.Status)
+}
+
+// TransientError marks an error, e.g., a failed connection, after which
+// fetching may succeed when retried.
+type TransientError struct {
+	Err error
+}
+
+func (e *TransientError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *TransientError) Unwrap() error {
+	return e.Err
+}
+
+// IsTransient returns whether fetching failed with the given error, but may
+// succeed when retried, i.e., whether the error is a TransientError, or a
+// StatusError for a server error or for too many requests. Other errors,
+// e.g., for client errors, such as 404 Not Found, are permanent.
+func IsTransient(err error) bool {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode >= 500 || statusErr.StatusCode == 429
+	}
+	var transientErr *TransientError
+	return errors.As(err, &transientErr)
+}
+
+// Fetcher fetches the content of URIs with a given scheme.
+type Fetcher interface {
+	// Fetch returns a reader for the content at the given URI. The caller
+	// closes the reader.
+	Fetch(ctx context.Context, uri *url.URL) (io.ReadCloser, error)
+}
+
+// FetcherFunc adapts a function to a Fetcher.
+type FetcherFunc func(ctx context.Context, uri *url.URL) (io.ReadCloser, error)
+
+// Fetch calls f(ctx, uri).
+func (f FetcherFunc) Fetch(ctx context.Context, uri *url.URL) (io.ReadCloser, error) {
+	return f(ctx, uri)
+}
+
+// RegistryConfig holds optional settings for a Registry.
+type RegistryConfig struct {
+	timeout  time.Duration
+	maxBytes int64
+	fetchers map[string]Fetcher
+}
+
+// WithTimeout overrides DefaultTimeout.
+func WithTimeout(timeout time.Duration) func(c *RegistryConfig) {
+	return func(c *RegistryConfig) {
+		c.timeout = timeout
+	}
+}
+
+// WithMaxBytes overrides DefaultMaxBytes.
+func WithMaxBytes(maxBytes int64) func(c *RegistryConfig) {
+	return func(c *RegistryConfig) {
+		c.maxBytes = maxBytes
+	}
+}
+
+// WithFetcher registers the given fetcher for the given URI scheme, replacing
+// the default fetcher for the scheme, if any.
+func WithFetcher(scheme string, fetcher Fetcher) func(c *RegistryConfig) {
+	return func(c *RegistryConfig) {
+		c.fetchers[strings.ToLower(scheme)] = fetcher
+	}
+}
+
+// Registry dispatches fetching URIs to the fetcher registered for their
+// scheme. By default, the schemes "file", "http", "https", "gs", "ent", and
+// "oci", for blobs in OCI registries, are supported.
+type Registry struct {
+	mu     sync.RWMutex
+	config *RegistryConfig
+}
+
+// NewRegistry creates a Registry with the default fetchers, modified by the
+// given options.
+func NewRegistry(options ...func(c *RegistryConfig)) *Registry {
+	config := &RegistryConfig{
+		timeout:  DefaultTimeout,
+		maxBytes: DefaultMaxBytes,
+		fetchers: map[string]Fetcher{
+			"file":  FetcherFunc(fetchFile),
+			"http":  newHTTPFetcher(),
+			"https": newHTTPFetcher(),
+			"gs":    &gcsFetcher{},
+			"s3":    blobstore.NewS3Store(),
+			"ent":   ent.NewClient(ent.DefaultURL, ""),
+			"oci":   oci.NewClient(),
+		},
+	}
+	for _, addOption := range options {
+		addOption(config)
+	}
+	return &Registry{config: config}
+}
+
+// Register registers the given fetcher for the given URI scheme, replacing
+// the fetcher for the scheme, if any.
+func (r *Registry) Register(scheme string, fetcher Fetcher) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	WithFetcher(scheme, fetcher)(r.config)
+}
+
+// FetchConfig holds optional settings for fetching a single URI.
+type FetchConfig struct {
+	expectedSHA256Digest string
+	maxBytes             int64
+	retryAttempts        int
+	retryBackoff         time.Duration
+	cache                *cache.Cache
+}
+
+// WithExpectedSHA256Digest makes fetching fail unless the content has the
+// given hex-encoded SHA2-256 digest. An empty digest is ignored.
+func WithExpectedSHA256Digest(digest string) func(c *FetchConfig) {
+	return func(c *FetchConfig) {
+		c.expectedSHA256Digest = strings.ToLower(digest)
+	}
+}
+
+// WithSizeLimit overrides the maximum size of the content of the registry
+// for a single URI. A limit of zero is ignored.
+func WithSizeLimit(maxBytes int64) func(c *FetchConfig) {
+	return func(c *FetchConfig) {
+		c.maxBytes = maxBytes
+	}
+}
+
+// WithRetries sets how many times fetching is attempted, if it fails with a
+// transient error, as reported by IsTransient, and how long to wait before
+// the first retry. The wait doubles for every further retry. By default, a
+// URI is fetched once, and the backoff is DefaultRetryBackoff.
+func WithRetries(attempts int, backoff time.Duration) func(c *FetchConfig) {
+	return func(c *FetchConfig) {
+		c.retryAttempts = attempts
+		c.retryBackoff = backoff
+	}
+}
+
+// WithCache looks up the content in the given cache, by its expected digest,
+// before fetching it, and stores fetched content in the cache. Content is
+// only cached if its expected digest is known, either with
+// WithExpectedSHA256Digest, or from a content-addressed URI, i.e., an Ent URI
+// or an OCI blob URI. Local files are not cached.
+func WithCache(c *cache.Cache) func(c *FetchConfig) {
+	return func(config *FetchConfig) {
+		config.cache = c
+	}
+}
+
+// Fetch returns the content at the given URI, using the fetcher registered
+// for its scheme. Each attempt is subject to the timeout of the registry.
+func (r *Registry) Fetch(ctx context.Context, uri string, options ...func(c *FetchConfig)) ([]byte, error) {
+	config := &FetchConfig{retryAttempts: 1, retryBackoff: DefaultRetryBackoff}
+	for _, addOption := range options {
+		addOption(config)
+	}
+
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse the URI (%q): %v", uri, err)
+	}
+	r.mu.RLock()
+	fetcher, ok := r.config.fetchers[strings.ToLower(parsed.Scheme)]
+	timeout, maxBytes := r.config.timeout, r.config.maxBytes
+	r.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unsupported URI scheme (%q)", parsed.Scheme)
+	}
+	if config.maxBytes > 0 {
+		maxBytes = config.maxBytes
+	}
+	if config.expectedSHA256Digest == "" {
+		config.expectedSHA256Digest = addressedSHA256Digest(parsed)
+	}
+
+	cached := config.cache != nil && config.expectedSHA256Digest != "" && !strings.EqualFold(parsed.Scheme, "file")
+	if cached {
+		// The cache checks the digest of the content it returns.
+		if content, ok := config.cache.Get(config.expectedSHA256Digest); ok && int64(len(content)) <= maxBytes {
+			return content, nil
+		}
+	}
+
+	var content []byte
+	backoff := config.retryBackoff
+	for attempt := 1; ; attempt++ {
+		content, err = fetchOnce(ctx, fetcher, parsed, timeout, maxBytes)
+		if err == nil || attempt >= config.retryAttempts || !IsTransient(err) || ctx.Err() != nil {
+			break
+		}
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return nil, fmt.Errorf("%v (retrying: %v)", err, ctx.Err())
+		}
+		backoff *= 2
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !hasDigest(content, config.expectedSHA256Digest) {
+		sum256 := sha256.Sum256(content)
+		return nil, fmt.Errorf("unexpected SHA2-256 digest of %q: got %s, want %s", uri, hex.EncodeToString(sum256[:]), config.expectedSHA256Digest)
+	}
+	if cached {
+		// Failing to cache the content does not fail fetching it.
+		_ = config.cache.Put(config.expectedSHA256Digest, content)
+	}
+	return content, nil
+}
+
+// addressedSHA256Digest returns the hex-encoded SHA2-256 digest of the
+// content at the given URI, if the URI is content-addressed, i.e., an Ent URI
+// or an OCI blob URI, or an empty string otherwise.
+func addressedSHA256Digest(uri *url.URL) string {
+	switch strings.ToLower(uri.Scheme) {
+	case ent.Scheme:
+		if digest, err := ent.ParseURI(uri.String()); err == nil {
+			return digest
+		}
+	case oci.Scheme:
+		if ref, err := oci.ParseReference(uri.Host + uri.Path); err == nil && ref.Digest != "" {
+			return strings.TrimPrefix(ref.Digest, "sha256:")
+		}
+	}
+	return ""
+}
+
+// hasDigest returns whether the given content has the given hex-encoded
+// SHA2-256 digest, or the digest is empty.
+func hasDigest(content []byte, sha256Digest string) bool {
+	if sha256Digest == "" {
+		return true
+	}
+	sum256 := sha256.Sum256(content)
+	return hex.EncodeToString(sum256[:]) == sha256Digest
+}
+
+// fetchOnce fetches the content at the given URI with the given fetcher,
+// within the given timeout, failing if it exceeds maxBytes.
+func fetchOnce(ctx context.Context, fetcher Fetcher, uri *url.URL, timeout time.Duration, maxBytes int64) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	reader, err := fetcher.Fetch(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	// Read one byte more than the limit to detect oversized content.
+	content, err := io.ReadAll(io.LimitReader(reader, maxBytes+1))
+	if err != nil {
+		return nil, &TransientError{Err: fmt.Errorf("could not read the content of %q: %v", uri, err)}
+	}
+	if int64(len(content)) > maxBytes {
+		return nil, fmt.Errorf("the content of %q exceeds the maximum size of %d bytes", uri, maxBytes)
+	}
+	return content, nil
+}
+
+// defaultRegistry is the registry used by the package-level functions.
+//
+//nolint:gochecknoglobals
+var defaultRegistry = NewRegistry()
+
+// Default returns the default registry, e.g., for registering additional
+// fetchers.
+func Default() *Registry {
+	return defaultRegistry
+}
+
+// Fetch returns the content at the given URI, using the default registry.
+func Fetch(ctx context.Context, uri string, options ...func(c *FetchConfig)) ([]byte, error) {
+	return defaultRegistry.Fetch(ctx, uri, options...)
+}
+
+// FetchPathOrURI returns the content at the given URI, or of the local file
+// at the given path if it does not have a URI scheme, using the default
+// registry.
+func FetchPathOrURI(ctx context.Context, pathOrURI string, options ...func(c *FetchConfig)) ([]byte, error) {
+	if !strings.Contains(pathOrURI, "://") {
+		path, err := filepath.Abs(pathOrURI)
+		if err != nil {
+			return nil, err
+		}
+		pathOrURI = (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+	}
+	return Fetch(ctx, pathOrURI, options...)
+}
@@ -0,0 +1,253 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fetch
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/project-oak/transparent-release/internal/cache"
+	"github.com/project-oak/transparent-release/internal/testutil"
+)
+
+const content = `{"hello": "world"}`
+
+func sha256Hex(s string) string {
+	sum256 := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum256[:])
+}
+
+func TestFetchFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "content.json")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Could not write file: %v", err)
+	}
+
+	got, err := NewRegistry().Fetch(context.Background(), "file://"+path)
+	if err != nil {
+		t.Fatalf("Could not fetch file: %v", err)
+	}
+	testutil.AssertEq(t, "content", string(got), content)
+
+	got, err = FetchPathOrURI(context.Background(), path)
+	if err != nil {
+		t.Fatalf("Could not fetch path: %v", err)
+	}
+	testutil.AssertEq(t, "content", string(got), content)
+
+	if _, err := NewRegistry().Fetch(context.Background(), "file://"+path+".missing"); err == nil {
+		t.Errorf("Expected an error for a missing file")
+	}
+}
+
+func TestFetchHTTP(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/content.json" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, content)
+	}))
+	defer server.Close()
+
+	registry := NewRegistry()
+	got, err := registry.Fetch(context.Background(), server.URL+"/content.json")
+	if err != nil {
+		t.Fatalf("Could not fetch over HTTP: %v", err)
+	}
+	testutil.AssertEq(t, "content", string(got), content)
+
+	if _, err := registry.Fetch(context.Background(), server.URL+"/missing.json"); err == nil {
+		t.Errorf("Expected an error for a 404 response")
+	}
+}
+
+func TestFetchExpectedDigest(t *testing.T) {
+	registry := NewRegistry(WithFetcher("test", staticFetcher(content)))
+
+	if _, err := registry.Fetch(context.Background(), "test://content", WithExpectedSHA256Digest(strings.ToUpper(sha256Hex(content)))); err != nil {
+		t.Errorf("Failed to fetch content with the expected digest: %v", err)
+	}
+	_, err := registry.Fetch(context.Background(), "test://content", WithExpectedSHA256Digest(sha256Hex("other")))
+	if err == nil || !strings.Contains(err.Error(), "unexpected SHA2-256 digest") {
+		t.Errorf("Expected a digest mismatch error, got: %v", err)
+	}
+}
+
+func TestFetchMaxBytes(t *testing.T) {
+	registry := NewRegistry(WithFetcher("test", staticFetcher(content)), WithMaxBytes(int64(len(content))))
+	if _, err := registry.Fetch(context.Background(), "test://content"); err != nil {
+		t.Errorf("Failed to fetch content within the limit: %v", err)
+	}
+
+	registry = NewRegistry(WithFetcher("test", staticFetcher(content)), WithMaxBytes(int64(len(content)-1)))
+	_, err := registry.Fetch(context.Background(), "test://content")
+	if err == nil || !strings.Contains(err.Error(), "exceeds the maximum size") {
+		t.Errorf("Expected a size limit error, got: %v", err)
+	}
+}
+
+func TestFetchSizeLimit(t *testing.T) {
+	registry := NewRegistry(WithFetcher("test", staticFetcher(content)))
+	_, err := registry.Fetch(context.Background(), "test://content", WithSizeLimit(int64(len(content)-1)))
+	if err == nil || !strings.Contains(err.Error(), "exceeds the maximum size") {
+		t.Errorf("Expected a size limit error, got: %v", err)
+	}
+	if IsTransient(err) {
+		t.Errorf("Expected the size limit error to be permanent")
+	}
+}
+
+func TestFetchRetries(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch {
+		case r.URL.Path == "/missing.json":
+			http.NotFound(w, r)
+		case requests < 3:
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		default:
+			fmt.Fprint(w, content)
+		}
+	}))
+	defer server.Close()
+	registry := NewRegistry()
+
+	// Server errors are retried.
+	got, err := registry.Fetch(context.Background(), server.URL+"/content.json", WithRetries(3, time.Millisecond))
+	if err != nil {
+		t.Fatalf("Could not fetch with retries: %v", err)
+	}
+	testutil.AssertEq(t, "content", string(got), content)
+	testutil.AssertEq(t, "requests", requests, 3)
+
+	// Client errors are not.
+	requests = 0
+	_, err = registry.Fetch(context.Background(), server.URL+"/missing.json", WithRetries(3, time.Millisecond))
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected a 404 status error, got: %v", err)
+	}
+	testutil.AssertEq(t, "requests", requests, 1)
+
+	// Without retries, a server error fails the fetch.
+	requests = 0
+	_, err = registry.Fetch(context.Background(), server.URL+"/content.json")
+	if !IsTransient(err) {
+		t.Errorf("Expected a transient error, got: %v", err)
+	}
+	testutil.AssertEq(t, "requests", requests, 1)
+}
+
+func TestFetchWithCache(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fmt.Fprint(w, content)
+	}))
+	defer server.Close()
+	c, err := cache.New(t.TempDir())
+	if err != nil {
+		t.Fatalf("Could not create the cache: %v", err)
+	}
+	registry := NewRegistry()
+	uri := server.URL + "/content.json"
+
+	for i := 0; i < 2; i++ {
+		got, err := registry.Fetch(context.Background(), uri, WithCache(c), WithExpectedSHA256Digest(sha256Hex(content)))
+		if err != nil {
+			t.Fatalf("Could not fetch with a cache: %v", err)
+		}
+		testutil.AssertEq(t, "content", string(got), content)
+	}
+	testutil.AssertEq(t, "requests", requests, 1)
+
+	// Content without an expected digest is neither looked up nor cached.
+	for i := 0; i < 2; i++ {
+		if _, err := registry.Fetch(context.Background(), uri, WithCache(c)); err != nil {
+			t.Fatalf("Could not fetch with a cache: %v", err)
+		}
+	}
+	testutil.AssertEq(t, "requests", requests, 3)
+
+	// Content at content-addressed URIs is looked up by the digest in the URI,
+	// so the content cached above is not fetched again.
+	registry.Register("ent", FetcherFunc(func(ctx context.Context, _ *url.URL) (io.ReadCloser, error) {
+		requests++
+		return io.NopCloser(strings.NewReader(content)), nil
+	}))
+	for i := 0; i < 2; i++ {
+		got, err := registry.Fetch(context.Background(), "ent:sha256:"+sha256Hex(content), WithCache(c))
+		if err != nil {
+			t.Fatalf("Could not fetch with a cache: %v", err)
+		}
+		testutil.AssertEq(t, "content", string(got), content)
+	}
+	testutil.AssertEq(t, "requests", requests, 3)
+}
+
+func TestFetchTimeout(t *testing.T) {
+	blocking := FetcherFunc(func(ctx context.Context, _ *url.URL) (io.ReadCloser, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	registry := NewRegistry(WithFetcher("test", blocking), WithTimeout(time.Millisecond))
+	if _, err := registry.Fetch(context.Background(), "test://content"); err == nil {
+		t.Errorf("Expected a timeout error")
+	}
+}
+
+func TestFetchUnsupportedScheme(t *testing.T) {
+	registry := NewRegistry()
+	_, err := registry.Fetch(context.Background(), "ftp://host/object")
+	if err == nil || !strings.Contains(err.Error(), "unsupported URI scheme") {
+		t.Errorf("Expected an unsupported scheme error, got: %v", err)
+	}
+
+	registry.Register("FTP", staticFetcher(content))
+	got, err := registry.Fetch(context.Background(), "ftp://host/object")
+	if err != nil {
+		t.Fatalf("Could not fetch with a registered fetcher: %v", err)
+	}
+	testutil.AssertEq(t, "content", string(got), content)
+}
+
+func staticFetcher(s string) Fetcher {
+	return FetcherFunc(func(context.Context, *url.URL) (io.ReadCloser, error) {
+		return io.NopCloser(strings.NewReader(s)), nil
+	})
+}
+
+func TestFetchInvalidGCSURI(t *testing.T) {
+	for _, uri := range []string{"gs:///blob", "gs://bucket", "gs://bucket/"} {
+		_, err := NewRegistry().Fetch(context.Background(), uri)
+		if err == nil || !strings.Contains(err.Error(), "want gs://bucket/blob") {
+			t.Errorf("Expected an invalid URI error for %q, got: %v", uri, err)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fetch
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+
+	"github.com/project-oak/transparent-release/internal/gcsutil"
+)
+
+// fetchFile opens the local file in the given `file` URI.
+func fetchFile(_ context.Context, uri *url.URL) (io.ReadCloser, error) {
+	if uri.Host != "" {
+		return nil, fmt.Errorf("invalid scheme (%q) and host (%q) combination", uri.Scheme, uri.Host)
+	}
+	file, err := os.Open(uri.Path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open %q: %v", uri.Path, err)
+	}
+	return file, nil
+}
+
+// httpFetcher fetches `http` and `https` URIs.
+type httpFetcher struct {
+	client *http.Client
+}
+
+func newHTTPFetcher() *httpFetcher {
+	return &httpFetcher{client: &http.Client{}}
+}
+
+func (f *httpFetcher) Fetch(ctx context.Context, uri *url.URL) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not create HTTP request: %v", err)
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, &TransientError{Err: fmt.Errorf("could not receive response from server: %v", err)}
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, &StatusError{URI: uri.String(), StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+	return resp.Body, nil
+}
+
+// gcsFetcher fetches `gs://bucket/object` URIs, using a Google Cloud Storage
+// client that is created on first use.
+type gcsFetcher struct {
+	once   sync.Once
+	client *gcsutil.Client
+	err    error
+}
+
+func (f *gcsFetcher) Fetch(ctx context.Context, uri *url.URL) (io.ReadCloser, error) {
+	// Reject malformed URIs before creating a client, which requires
+	// credentials.
+	if _, _, err := gcsutil.ParseURI(uri); err != nil {
+		return nil, err
+	}
+	f.once.Do(func() {
+		f.client, f.err = gcsutil.NewClientWithContext(context.Background())
+	})
+	if f.err != nil {
+		return nil, f.err
+	}
+	return f.client.Fetch(ctx, uri)
+}
@@ -43,6 +43,27 @@ type FuzzClaimSpec struct {
 	PerTarget []FuzzSpecPerTarget `json:"perTarget"`
 	// `ClaimSpec` for all fuzz-targets.
 	PerProject *FuzzStats `json:"perProject"`
+	// CrashReports contains the details of the detected crashes, if any.
+	CrashReports []CrashReport `json:"crashReports,omitempty"`
+}
+
+// CrashReport contains the details of a crash detected by a fuzz-target.
+type CrashReport struct {
+	// FuzzTarget is the name of the fuzz-target that detected the crash.
+	FuzzTarget string `json:"fuzzTarget"`
+	// FuzzEngine is the fuzzing engine with which the crash was detected.
+	FuzzEngine string `json:"fuzzEngine,omitempty"`
+	// Sanitizer is the sanitizer with which the crash was detected.
+	Sanitizer string `json:"sanitizer,omitempty"`
+	// CrashType is the type of the crash, as reported by the sanitizer or
+	// the fuzzing engine, e.g., heap-buffer-overflow. Empty if unknown.
+	CrashType string `json:"crashType,omitempty"`
+	// TestcaseDigest is the digest of the testcase that triggers the crash,
+	// if known.
+	TestcaseDigest intoto.DigestSet `json:"testcaseDigest,omitempty"`
+	// IssueID is the ID of the OSS-Fuzz issue of the crash, if it could be
+	// resolved.
+	IssueID string `json:"issueId,omitempty"`
 }
 
 // FuzzSpecPerTarget contains the fuzzing claims specification per fuzz-target.
@@ -51,8 +72,55 @@ type FuzzSpecPerTarget struct {
 	Name string `json:"name"`
 	// Path of the fuzz-target, relative to the root of the Git repository.
 	Path string `json:"path"`
-	// Fuzzing statistics of the fuzz-target.
+	// SourceDigest is the digest of the source file of the fuzz-target at
+	// Path, in the revision of the subject. Empty if the Git repository is
+	// not hosted on GitHub, or in claims generated before source digests
+	// were recorded.
+	SourceDigest intoto.DigestSet `json:"sourceDigest,omitempty"`
+	// SourceDigestUnknownReason explains why SourceDigest is empty although
+	// the Git repository is hosted on GitHub, e.g., because the repository is
+	// private, or the source file is not at Path in the repository.
+	SourceDigestUnknownReason string `json:"sourceDigestUnknownReason,omitempty"`
+	// Fuzzing statistics of the fuzz-target. Nil if the statistics are
+	// unknown.
 	FuzzStats *FuzzStats `json:"fuzzStats"`
+	// UnknownReason explains why the fuzzing statistics of the fuzz-target
+	// are unknown, e.g., because its reports are missing for the fuzzing
+	// date. Set if and only if FuzzStats is nil.
+	UnknownReason string `json:"unknownReason,omitempty"`
+	// PerConfiguration contains the fuzzing efforts and crashes of the
+	// fuzz-target per combination of fuzzing engine and sanitizer, which add
+	// up to FuzzStats. Empty in claims generated before the statistics were
+	// split per combination.
+	PerConfiguration []FuzzStatsPerConfiguration `json:"perConfiguration,omitempty"`
+}
+
+// FuzzStatsPerConfiguration contains the fuzzing statistics of a fuzz-target
+// fuzzed with a combination of a fuzzing engine and a sanitizer.
+type FuzzStatsPerConfiguration struct {
+	// FuzzEngine is the fuzzing engine, e.g., libFuzzer.
+	FuzzEngine string `json:"fuzzEngine"`
+	// Sanitizer is the sanitizer, e.g., asan.
+	Sanitizer string `json:"sanitizer"`
+	// DetectedCrashes specifies if any bugs/crashes were detected with the
+	// combination.
+	DetectedCrashes bool `json:"detectedCrashes"`
+	// FuzzTimeSeconds specifies the fuzzing time in seconds.
+	FuzzTimeSeconds float64 `json:"fuzzTimeSeconds,omitempty"`
+	// NumberFuzzTests specifies the number of executed fuzzing tests.
+	NumberFuzzTests int `json:"numberFuzzTests,omitempty"`
+}
+
+// knownFuzzTargets returns the names of the fuzz-targets with known fuzzing
+// statistics.
+func (spec *FuzzClaimSpec) knownFuzzTargets() []string {
+	names := make([]string, 0, len(spec.PerTarget))
+	for _, target := range spec.PerTarget {
+		if target.FuzzStats != nil {
+			names = append(names, target.Name)
+		}
+	}
+	return names
 }
 
 // FuzzStats contains the fuzzing statistics of the revision
@@ -103,6 +171,17 @@ func validateFuzzClaimSpec(predicate claims.ClaimPredicate) (*claims.ClaimPredic
 	sumTargetsTimeSeconds := 0.0
 	sumTargetsNumberTests := 0
 	for _, spec := range predicate.ClaimSpec.(FuzzClaimSpec).PerTarget {
+		// Fuzz-targets with unknown statistics are not part of the
+		// statistics of the project.
+		if (spec.FuzzStats == nil) == (spec.UnknownReason == "") {
+			return nil, fmt.Errorf("fuzz-target %q must have either fuzzing statistics or the reason why they are unknown", spec.Name)
+		}
+		if spec.FuzzStats == nil {
+			continue
+		}
+		if err := validatePerConfiguration(spec); err != nil {
+			return nil, err
+		}
 		sumTargetsTimeSeconds += spec.FuzzStats.FuzzTimeSeconds
 		sumTargetsNumberTests += spec.FuzzStats.NumberFuzzTests
 	}
@@ -121,16 +200,83 @@ func validateFuzzClaimSpec(predicate claims.ClaimPredicate) (*claims.ClaimPredic
 	// the detectedCrashes for all fuzz-targets.
 	targetsDetectedCrashes := false
 	for _, spec := range predicate.ClaimSpec.(FuzzClaimSpec).PerTarget {
-		targetsDetectedCrashes = targetsDetectedCrashes || spec.FuzzStats.DetectedCrashes
+		targetsDetectedCrashes = targetsDetectedCrashes || (spec.FuzzStats != nil && spec.FuzzStats.DetectedCrashes)
 	}
 	if predicate.ClaimSpec.(FuzzClaimSpec).PerProject.DetectedCrashes != targetsDetectedCrashes {
 		return nil, fmt.Errorf("perProject.DetectedCrashes (%t) is not consistent with the detectedCrashes for all fuzz-targets (%t)",
 			predicate.ClaimSpec.(FuzzClaimSpec).PerProject.DetectedCrashes, targetsDetectedCrashes)
 	}
 
+	// validate that crash reports refer to fuzz-targets that detected crashes.
+	for i, report := range predicate.ClaimSpec.(FuzzClaimSpec).CrashReports {
+		if !detectedCrashes(predicate.ClaimSpec.(FuzzClaimSpec).PerTarget, report.FuzzTarget) {
+			return nil, fmt.Errorf("crashReports[%d] refers to fuzz-target %q, which did not detect crashes", i, report.FuzzTarget)
+		}
+	}
+
 	return &predicate, nil
 }
 
+// detectedCrashes returns whether the fuzz-target with the given name has
+// known statistics with detected crashes.
+func detectedCrashes(perTarget []FuzzSpecPerTarget, fuzzTarget string) bool {
+	for _, spec := range perTarget {
+		if spec.Name == fuzzTarget && spec.FuzzStats != nil && spec.FuzzStats.DetectedCrashes {
+			return true
+		}
+	}
+	return false
+}
+
+// validatePerConfiguration validates that the fuzzing statistics per
+// combination of fuzzing engine and sanitizer of the given fuzz-target, if
+// any, add up to its fuzzing statistics.
+func validatePerConfiguration(spec FuzzSpecPerTarget) error {
+	if len(spec.PerConfiguration) == 0 {
+		return nil
+	}
+	timeSeconds := 0.0
+	numberTests := 0
+	detectedCrashes := false
+	for _, stats := range spec.PerConfiguration {
+		timeSeconds += stats.FuzzTimeSeconds
+		numberTests += stats.NumberFuzzTests
+		detectedCrashes = detectedCrashes || stats.DetectedCrashes
+	}
+	if spec.FuzzStats.FuzzTimeSeconds != timeSeconds {
+		return fmt.Errorf("fuzzStats.fuzzTimeSeconds (%f) of fuzz-target %q is not equal to the sum of per-configuration fuzzTimeSeconds (%f)",
+			spec.FuzzStats.FuzzTimeSeconds, spec.Name, timeSeconds)
+	}
+	if spec.FuzzStats.NumberFuzzTests != numberTests {
+		return fmt.Errorf("fuzzStats.numberFuzzTests (%d) of fuzz-target %q is not equal to the sum of per-configuration numberFuzzTests (%d)",
+			spec.FuzzStats.NumberFuzzTests, spec.Name, numberTests)
+	}
+	if spec.FuzzStats.DetectedCrashes != detectedCrashes {
+		return fmt.Errorf("fuzzStats.detectedCrashes (%t) of fuzz-target %q is not consistent with the per-configuration detectedCrashes (%t)",
+			spec.FuzzStats.DetectedCrashes, spec.Name, detectedCrashes)
+	}
+	return nil
+}
+
+// fuzzClaimSpec returns the predicate and the ClaimSpec of the given fuzzing
+// claim, as returned by GenerateFuzzClaim or ParseFuzzClaimFile.
+func fuzzClaimSpec(statement *intoto.Statement) (*claims.ClaimPredicate, *FuzzClaimSpec, error) {
+	var predicate *claims.ClaimPredicate
+	switch p := statement.Predicate.(type) {
+	case *claims.ClaimPredicate:
+		predicate = p
+	case claims.ClaimPredicate:
+		predicate = &p
+	default:
+		return nil, nil, fmt.Errorf("the predicate of the fuzzing claim does not have the expected type; got: %T, want: ClaimPredicate", statement.Predicate)
+	}
+	spec, ok := predicate.ClaimSpec.(FuzzClaimSpec)
+	if !ok || predicate.ClaimType != FuzzClaimV1 {
+		return nil, nil, fmt.Errorf("the claim is not a fuzzing claim")
+	}
+	return predicate, &spec, nil
+}
+
 // ParseFuzzClaimFile reads a JSON file from a path, and parses it into an
 // instance of intoto.Statement, with ClaimV1 as the PredicateType and
 // FuzzClaimV1 as the ClaimType.
@@ -16,9 +16,11 @@ package fuzzbinder
 import (
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/project-oak/transparent-release/internal/testutil"
 	"github.com/project-oak/transparent-release/pkg/claims"
+	"github.com/project-oak/transparent-release/pkg/intoto"
 )
 
 const (
@@ -49,3 +51,70 @@ func TestParseFuzzClaimFile(t *testing.T) {
 	testutil.AssertNonEmpty(t, "evidence[0].uri", statement.Predicate.(*claims.ClaimPredicate).Evidence[0].URI)
 	testutil.AssertEq(t, "evidence[0].digest length", len(statement.Predicate.(*claims.ClaimPredicate).Evidence[0].Digest["sha256"]), wantSHA256HexDigitLength)
 }
+
+func TestValidateFuzzClaim_UnknownTargetStats(t *testing.T) {
+	stats := &FuzzStats{LineCoverage: "50.00% (1/2)", BranchCoverage: "0.00% (0/0)", FuzzTimeSeconds: 10, NumberFuzzTests: 5}
+	spec := &FuzzClaimSpec{
+		PerTarget: []FuzzSpecPerTarget{
+			{Name: "known", Path: "fuzz/known.rs", FuzzStats: stats},
+			{Name: "unknown", UnknownReason: "could not get unknown coverage"},
+		},
+		PerProject: &FuzzStats{LineCoverage: "50.00% (1/2)", BranchCoverage: "0.00% (0/0)", FuzzTimeSeconds: 10, NumberFuzzTests: 5},
+	}
+	notBefore := time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC)
+	notAfter := notBefore.AddDate(0, 0, 90)
+	validity := claims.ClaimValidity{NotBefore: &notBefore, NotAfter: &notAfter}
+	clock := claims.FixedClock(notBefore.AddDate(0, 0, -1))
+	revision := intoto.DigestSet{"sha1": "6bac02b6b0442ed944f57b7cba9a5f1119863ca4"}
+
+	statement, err := newFuzzClaim("https://github.com/project-oak/oak", revision, spec, nil, validity, clock)
+	if err != nil {
+		t.Fatalf("failed to generate a fuzzing claim with an unknown fuzz-target: %v", err)
+	}
+	targetClaims, err := GenerateFuzzTargetClaims(statement)
+	if err != nil {
+		t.Fatalf("failed to generate per-target fuzzing claims: %v", err)
+	}
+	testutil.AssertEq(t, "number of per-target claims", len(targetClaims), 1)
+
+	// The statistics per configuration must add up to the statistics of the
+	// fuzz-target.
+	spec.PerTarget[0].PerConfiguration = []FuzzStatsPerConfiguration{
+		{FuzzEngine: "libFuzzer", Sanitizer: "asan", FuzzTimeSeconds: 6, NumberFuzzTests: 3},
+		{FuzzEngine: "libFuzzer", Sanitizer: "ubsan", FuzzTimeSeconds: 4, NumberFuzzTests: 2},
+	}
+	if _, err := newFuzzClaim("https://github.com/project-oak/oak", revision, spec, nil, validity, clock); err != nil {
+		t.Errorf("failed to generate a fuzzing claim with statistics per configuration: %v", err)
+	}
+	spec.PerTarget[0].PerConfiguration[1].DetectedCrashes = true
+	if _, err := newFuzzClaim("https://github.com/project-oak/oak", revision, spec, nil, validity, clock); err == nil {
+		t.Errorf("expected an error for inconsistent detected crashes per configuration")
+	}
+	spec.PerTarget[0].PerConfiguration[1].DetectedCrashes = false
+	spec.PerTarget[0].PerConfiguration[1].NumberFuzzTests = 3
+	if _, err := newFuzzClaim("https://github.com/project-oak/oak", revision, spec, nil, validity, clock); err == nil {
+		t.Errorf("expected an error for numbers of tests per configuration that do not add up")
+	}
+	spec.PerTarget[0].PerConfiguration = nil
+
+	// Crash reports must refer to fuzz-targets that detected crashes.
+	spec.CrashReports = []CrashReport{{FuzzTarget: "known", CrashType: "heap-buffer-overflow"}}
+	if _, err := newFuzzClaim("https://github.com/project-oak/oak", revision, spec, nil, validity, clock); err == nil {
+		t.Errorf("expected an error for a crash report of a fuzz-target without crashes")
+	}
+	stats.DetectedCrashes = true
+	spec.PerProject.DetectedCrashes = true
+	if _, err := newFuzzClaim("https://github.com/project-oak/oak", revision, spec, nil, validity, clock); err != nil {
+		t.Errorf("failed to generate a fuzzing claim with a crash report: %v", err)
+	}
+	stats.DetectedCrashes = false
+	spec.PerProject.DetectedCrashes = false
+	spec.CrashReports = nil
+
+	// A fuzz-target needs either statistics, or the reason why they are
+	// unknown.
+	spec.PerTarget[1].UnknownReason = ""
+	if _, err := newFuzzClaim("https://github.com/project-oak/oak", revision, spec, nil, validity, clock); err == nil {
+		t.Errorf("expected an error for a fuzz-target without statistics")
+	}
+}
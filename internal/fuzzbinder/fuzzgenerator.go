@@ -20,8 +20,8 @@ package fuzzbinder
 
 import (
 	"fmt"
-	"time"
 
+	"github.com/project-oak/transparent-release/internal/ent"
 	"github.com/project-oak/transparent-release/internal/gcsutil"
 	"github.com/project-oak/transparent-release/pkg/claims"
 	"github.com/project-oak/transparent-release/pkg/intoto"
@@ -29,38 +29,30 @@ import (
 
 // TODO(#171): Split generateFuzzClaimSpec into smaller functions.
 // generateFuzzClaimSpec generates a fuzzing claim specification using the
-// fuzzing reports of OSS-Fuzz.
-func generateFuzzClaimSpec(client *gcsutil.Client, revisionDigest intoto.DigestSet, fuzzParameters *FuzzParameters, fuzzTargets []string) (*FuzzClaimSpec, error) {
+// fuzzing reports of OSS-Fuzz. If strict is false, fuzz-targets whose
+// statistics cannot be fetched are recorded as unknown, and left out of the
+// statistics of the project, instead of failing.
+func generateFuzzClaimSpec(client *gcsutil.Client, revisionDigest intoto.DigestSet, fuzzParameters *FuzzParameters, fuzzTargets []string, strict bool) (*FuzzClaimSpec, error) {
 	var projectCrashes Crash
 	var projectFuzzEffort FuzzEffort
-	fuzzersCrashes := make(map[string]*Crash)
-	fuzzersFuzzEffort := make(map[string]*FuzzEffort)
-	fuzzersCoverage := make(map[string]*Coverage)
+	perTarget := make([]FuzzSpecPerTarget, 0, len(fuzzTargets))
+	var crashReports []CrashReport
 	//Get fuzzing statistics.
 	for _, fuzzTarget := range fuzzTargets {
-		coverage, err := GetCoverage(client, fuzzParameters, fuzzTarget, "perTarget")
-		if err != nil {
-			return nil, fmt.Errorf(
-				"could not get %s coverage to generate the fuzzing ClaimSpec: %v", fuzzTarget, err)
-		}
-		fuzzEffort, err := GetFuzzEffort(client, revisionDigest, fuzzParameters, fuzzTarget)
-		if err != nil {
-			return nil, fmt.Errorf(
-				"could not get %s fuzzing efforts to generate the fuzzing ClaimSpec: %v", fuzzTarget, err)
-		}
-		crash, err := GetCrashes(client, revisionDigest, fuzzParameters, fuzzTarget)
+		targetSpec, targetCrashReports, err := getFuzzSpecPerTarget(client, revisionDigest, fuzzParameters, fuzzTarget)
 		if err != nil {
-			return nil, fmt.Errorf(
-				"could not get %s crashes to generate the fuzzing ClaimSpec: %v", fuzzTarget, err)
+			if strict {
+				return nil, err
+			}
+			perTarget = append(perTarget, FuzzSpecPerTarget{Name: fuzzTarget, UnknownReason: err.Error()})
+			continue
 		}
+		perTarget = append(perTarget, *targetSpec)
+		crashReports = append(crashReports, targetCrashReports...)
 
-		fuzzersCrashes[fuzzTarget] = crash
-		fuzzersFuzzEffort[fuzzTarget] = fuzzEffort
-		fuzzersCoverage[fuzzTarget] = coverage
-
-		projectCrashes.detected = projectCrashes.detected || crash.detected
-		projectFuzzEffort.fuzzTimeSeconds += fuzzEffort.fuzzTimeSeconds
-		projectFuzzEffort.numberFuzzTests += fuzzEffort.numberFuzzTests
+		projectCrashes.detected = projectCrashes.detected || targetSpec.FuzzStats.DetectedCrashes
+		projectFuzzEffort.fuzzTimeSeconds += targetSpec.FuzzStats.FuzzTimeSeconds
+		projectFuzzEffort.numberFuzzTests += targetSpec.FuzzStats.NumberFuzzTests
 	}
 	projectCoverage, err := GetCoverage(client, fuzzParameters, "", "perProject")
 	if err != nil {
@@ -75,39 +67,135 @@ func generateFuzzClaimSpec(client *gcsutil.Client, revisionDigest intoto.DigestS
 		FuzzTimeSeconds: projectFuzzEffort.fuzzTimeSeconds,
 		NumberFuzzTests: projectFuzzEffort.numberFuzzTests,
 	}
-	perTarget := make([]FuzzSpecPerTarget, 0, len(fuzzTargets))
-	for _, fuzzTarget := range fuzzTargets {
-		targetStats := FuzzStats{
-			BranchCoverage:  fuzzersCoverage[fuzzTarget].branchCoverage,
-			LineCoverage:    fuzzersCoverage[fuzzTarget].lineCoverage,
-			DetectedCrashes: fuzzersCrashes[fuzzTarget].detected,
-			FuzzTimeSeconds: fuzzersFuzzEffort[fuzzTarget].fuzzTimeSeconds,
-			NumberFuzzTests: fuzzersFuzzEffort[fuzzTarget].numberFuzzTests,
-		}
-		fuzzTargetPath, err := GetFuzzTargetsPath(client, *fuzzParameters, fuzzTarget)
+	fuzzClaimSpec := FuzzClaimSpec{
+		PerTarget:    perTarget,
+		PerProject:   perProject,
+		CrashReports: crashReports,
+	}
+	return &fuzzClaimSpec, nil
+}
+
+// getFuzzSpecPerTarget gets the fuzzing statistics and the path of the given
+// fuzz-target, aggregating the fuzzing efforts and crashes of all its
+// combinations of fuzzing engines and sanitizers, and the reports of its
+// detected crashes.
+func getFuzzSpecPerTarget(client *gcsutil.Client, revisionDigest intoto.DigestSet, fuzzParameters *FuzzParameters, fuzzTarget string) (*FuzzSpecPerTarget, []CrashReport, error) {
+	coverage, err := GetCoverage(client, fuzzParameters, fuzzTarget, "perTarget")
+	if err != nil {
+		return nil, nil, fmt.Errorf(
+			"could not get %s coverage to generate the fuzzing ClaimSpec: %v", fuzzTarget, err)
+	}
+	configurations, err := DetectFuzzConfigurations(client, fuzzParameters, fuzzTarget)
+	if err != nil {
+		return nil, nil, fmt.Errorf(
+			"could not get %s fuzzing engines and sanitizers to generate the fuzzing ClaimSpec: %v", fuzzTarget, err)
+	}
+	var fuzzEffort FuzzEffort
+	var crash Crash
+	var crashReports []CrashReport
+	perConfiguration := make([]FuzzStatsPerConfiguration, 0, len(configurations))
+	for _, configuration := range configurations {
+		configurationParameters := *fuzzParameters
+		configurationParameters.FuzzEngine = configuration.FuzzEngine
+		configurationParameters.Sanitizer = configuration.Sanitizer
+		configurationEffort, configurationCrash, err := GetFuzzEffortAndCrashes(client, revisionDigest, &configurationParameters, fuzzTarget)
 		if err != nil {
-			return nil, fmt.Errorf(
-				"could not get fuzz-target path in %q: %v", fuzzParameters.ProjectGitRepo, err)
+			return nil, nil, fmt.Errorf(
+				"could not get %s fuzzing efforts and crashes with %s and %s to generate the fuzzing ClaimSpec: %v", fuzzTarget, configuration.FuzzEngine, configuration.Sanitizer, err)
 		}
-		targetSpec := FuzzSpecPerTarget{
-			Name:      fuzzTarget,
-			Path:      *fuzzTargetPath,
-			FuzzStats: &targetStats,
+		perConfiguration = append(perConfiguration, FuzzStatsPerConfiguration{
+			FuzzEngine:      configuration.FuzzEngine,
+			Sanitizer:       configuration.Sanitizer,
+			DetectedCrashes: configurationCrash.detected,
+			FuzzTimeSeconds: configurationEffort.fuzzTimeSeconds,
+			NumberFuzzTests: configurationEffort.numberFuzzTests,
+		})
+		fuzzEffort.fuzzTimeSeconds += configurationEffort.fuzzTimeSeconds
+		fuzzEffort.numberFuzzTests += configurationEffort.numberFuzzTests
+		crash.detected = crash.detected || configurationCrash.detected
+		for _, report := range configurationCrash.reports {
+			report.FuzzTarget = fuzzTarget
+			report.FuzzEngine = configuration.FuzzEngine
+			report.Sanitizer = configuration.Sanitizer
+			crashReports = append(crashReports, report)
 		}
-		perTarget = append(perTarget, targetSpec)
 	}
-	fuzzClaimSpec := FuzzClaimSpec{
-		PerTarget:  perTarget,
-		PerProject: perProject,
+	fuzzTargetPath, err := GetFuzzTargetsPath(client, *fuzzParameters, fuzzTarget)
+	if err != nil {
+		return nil, nil, fmt.Errorf(
+			"could not get fuzz-target path in %q: %v", fuzzParameters.ProjectGitRepo, err)
+	}
+	// The source digest is supplementary, so that failing to fetch the source
+	// file does not fail the claim.
+	var sourceDigestUnknownReason string
+	sourceDigest, err := GetFuzzTargetSourceDigest(*fuzzParameters, revisionDigest, *fuzzTargetPath)
+	if err != nil {
+		sourceDigestUnknownReason = fmt.Sprintf("could not get the digest of %s in %q: %v", *fuzzTargetPath, fuzzParameters.ProjectGitRepo, err)
+	}
+	return &FuzzSpecPerTarget{
+		Name:                      fuzzTarget,
+		Path:                      *fuzzTargetPath,
+		SourceDigest:              sourceDigest,
+		SourceDigestUnknownReason: sourceDigestUnknownReason,
+		FuzzStats: &FuzzStats{
+			BranchCoverage:  coverage.branchCoverage,
+			LineCoverage:    coverage.lineCoverage,
+			DetectedCrashes: crash.detected,
+			FuzzTimeSeconds: fuzzEffort.fuzzTimeSeconds,
+			NumberFuzzTests: fuzzEffort.numberFuzzTests,
+		},
+		PerConfiguration: perConfiguration,
+	}, crashReports, nil
+}
+
+// GenerateConfig holds optional settings for GenerateFuzzClaim.
+type GenerateConfig struct {
+	entClient          *ent.Client
+	unknownTargetStats bool
+	issueResolver      func(report CrashReport) string
+}
+
+// WithEnt copies the evidence files from GCS, where they expire, to the Ent
+// server of the given client, and references them by their `ent:` URIs in the
+// generated claim.
+func WithEnt(entClient *ent.Client) func(c *GenerateConfig) {
+	return func(c *GenerateConfig) {
+		c.entClient = entClient
+	}
+}
+
+// WithUnknownTargetStats records fuzz-targets whose statistics cannot be
+// fetched, e.g., because their reports are missing for the fuzzing date, as
+// unknown, with the reason, instead of failing. The statistics of the project
+// then only cover the other fuzz-targets.
+func WithUnknownTargetStats() func(c *GenerateConfig) {
+	return func(c *GenerateConfig) {
+		c.unknownTargetStats = true
+	}
+}
+
+// WithIssueResolver sets the OSS-Fuzz issue IDs of the detected crashes using
+// the given function, e.g., by looking up the digests of their testcases in
+// the issue tracker. The function returns an empty ID if the issue of a crash
+// cannot be resolved. ClusterFuzz logs do not reference issues, so without a
+// resolver, crash reports have no issue IDs.
+func WithIssueResolver(resolve func(report CrashReport) string) func(c *GenerateConfig) {
+	return func(c *GenerateConfig) {
+		c.issueResolver = resolve
 	}
-	return &fuzzClaimSpec, nil
 }
 
 // GenerateFuzzClaim generates a fuzzing claim (an instance of intoto.Statement,
 // with ClaimV1 as the PredicateType and FuzzClaimV1 as the ClaimType) using the
-// fuzzing reports of OSS-Fuzz and ClusterFuzz.
-
-func GenerateFuzzClaim(client *gcsutil.Client, fuzzParameters *FuzzParameters, validity claims.ClaimValidity) (*intoto.Statement, error) {
+// fuzzing reports of OSS-Fuzz and ClusterFuzz. The given clock provides the
+// issuance time of the claim. The statistics of each fuzz-target are
+// aggregated over all combinations of fuzzing engines and sanitizers found in
+// its logs, see DetectFuzzConfigurations.
+func GenerateFuzzClaim(client *gcsutil.Client, fuzzParameters *FuzzParameters, validity claims.ClaimValidity, clock claims.Clock, options ...func(c *GenerateConfig)) (*intoto.Statement, error) {
+	config := &GenerateConfig{}
+	for _, addOption := range options {
+		addOption(config)
+	}
 	revisionDigest, err := GetCoverageRevision(client, fuzzParameters)
 
 	if err != nil {
@@ -119,18 +207,31 @@ func GenerateFuzzClaim(client *gcsutil.Client, fuzzParameters *FuzzParameters, v
 		return nil, fmt.Errorf(
 			"could not get the fuzzing targets to generate the fuzzing claim: %v", err)
 	}
-	fuzzClaimSpec, err := generateFuzzClaimSpec(client, revisionDigest, fuzzParameters, fuzzTargets)
+	fuzzClaimSpec, err := generateFuzzClaimSpec(client, revisionDigest, fuzzParameters, fuzzTargets, !config.unknownTargetStats)
 	if err != nil {
 		return nil, fmt.Errorf(
 			"could not get the fuzzing ClaimSpec to generate the fuzzing claim: %v", err)
 	}
-	evidences, err := GetEvidences(client, fuzzParameters, fuzzTargets)
+	if config.issueResolver != nil {
+		for i := range fuzzClaimSpec.CrashReports {
+			fuzzClaimSpec.CrashReports[i].IssueID = config.issueResolver(fuzzClaimSpec.CrashReports[i])
+		}
+	}
+	// There is no evidence for fuzz-targets with unknown statistics.
+	evidences, err := GetEvidences(client, config.entClient, fuzzParameters, fuzzClaimSpec.knownFuzzTargets())
 	if err != nil {
 		return nil, fmt.Errorf(
 			"could not get evidences to generate the fuzzing claim: %v", err)
 	}
+	return newFuzzClaim(fuzzParameters.ProjectGitRepo, revisionDigest, fuzzClaimSpec, evidences, validity, clock)
+}
+
+// newFuzzClaim generates and validates a fuzzing claim about the given
+// revision of the given Git repository, with the given ClaimSpec and
+// evidence, issued at the time of the given clock.
+func newFuzzClaim(projectGitRepo string, revisionDigest intoto.DigestSet, fuzzClaimSpec *FuzzClaimSpec, evidences []claims.ClaimEvidence, validity claims.ClaimValidity, clock claims.Clock) (*intoto.Statement, error) {
 	// Current time in UTC time zone since it is used by OSS-Fuzz.
-	currentTime := time.Now().UTC()
+	currentTime := clock.Now().UTC()
 	// Generate claim predicate
 	predicate := claims.ClaimPredicate{
 		ClaimType: FuzzClaimV1,
@@ -141,7 +242,7 @@ func GenerateFuzzClaim(client *gcsutil.Client, fuzzParameters *FuzzParameters, v
 	}
 	// Generate intoto statement
 	subject := intoto.Subject{
-		Name:   fuzzParameters.ProjectGitRepo,
+		Name:   projectGitRepo,
 		Digest: revisionDigest,
 	}
 	statementHeader := intoto.StatementHeader{
@@ -47,14 +47,20 @@ package fuzzbinder
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
+	"path"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 
+	"github.com/project-oak/transparent-release/internal/ent"
+	"github.com/project-oak/transparent-release/internal/fetch"
 	"github.com/project-oak/transparent-release/internal/gcsutil"
 	"github.com/project-oak/transparent-release/pkg/claims"
 	"github.com/project-oak/transparent-release/pkg/intoto"
@@ -130,6 +136,9 @@ type FuzzEffort struct {
 // Crash indicates if a crash has been detected.
 type Crash struct {
 	detected bool
+	// reports contains the details of the detected crashes, one per log
+	// file with a crash.
+	reports []CrashReport
 }
 
 // FuzzParameters contains the fuzzing parameters
@@ -139,11 +148,13 @@ type FuzzParameters struct {
 	ProjectName string
 	// ProjectGitRepo specifies the GitHub repository of the project.
 	ProjectGitRepo string
-	// FuzzEngine specifies the fuzzing engine used for the project.
-	// Examples: libFuzzer, afl, honggfuzz, centipede.
+	// FuzzEngine optionally restricts the fuzzing engines whose statistics
+	// are used to one of SupportedFuzzEngines: libFuzzer, afl, honggfuzz,
+	// centipede. By default, all engines found in the logs are used.
 	FuzzEngine string
-	// Sanitizer specifies the fuzzing sanitizer used for the project.
-	// Examples: asan, ubsan, msan.
+	// Sanitizer optionally restricts the fuzzing sanitizers whose statistics
+	// are used, e.g., to asan, ubsan, or msan. By default, all sanitizers
+	// found in the logs are used.
 	Sanitizer string
 	// Date specifies the fuzzing date.
 	// The expected format is YYYYMMDD.
@@ -217,44 +228,175 @@ func getLogDirInfo(fuzzParameters *FuzzParameters, fuzzTarget string) (string, s
 	return logsBucket, relativePath
 }
 
+// executedUnitsPattern matches the number of executed tests in the fuzzer
+// logs of a fuzzing engine.
+type executedUnitsPattern struct {
+	// fuzzEngine is the name of the fuzzing engine in ClusterFuzz.
+	fuzzEngine string
+	// pattern matches a line with the number of tests in its first group.
+	pattern *regexp.Regexp
+}
+
+// executedUnitsPatterns lists the formats of the number of executed tests in
+// the fuzzer logs of the supported fuzzing engines:
+//
+//   - libFuzzer: 'stat::number_of_executed_units: {number_of_executed_units}'
+//     reference:
+//     https://github.com/google/clusterfuzz/blob/910f08b9316a729c4c6b05ed260f97d1d03c3d88/src/clusterfuzz/_internal/bot/fuzzers/libfuzzer.py#L1377
+//   - AFL++: 'execs_done : {number}', from the fuzzer_stats file of AFL++.
+//   - honggfuzz: 'Summary iterations:{number} time:{seconds} ...'
+//   - centipede: '[S{shard}.{number}] {event}: ...', with the number of
+//     runs so far in the prefix of the progress lines.
+//
+//nolint:gochecknoglobals
+var executedUnitsPatterns = []executedUnitsPattern{
+	{"libFuzzer", regexp.MustCompile(`^stat::number_of_executed_units:?\s+(\d+)`)},
+	{"afl", regexp.MustCompile(`^execs_done\s*:\s*(\d+)`)},
+	{"honggfuzz", regexp.MustCompile(`^Summary iterations:(\d+)`)},
+	{"centipede", regexp.MustCompile(`^\[S\d+\.(\d+)\]`)},
+}
+
+// executedUnitsPatternOf returns the pattern of the number of executed tests
+// in the fuzzer logs of the given fuzzing engine, or an error if the engine is
+// not supported.
+func executedUnitsPatternOf(fuzzEngine string) (*regexp.Regexp, error) {
+	for _, p := range executedUnitsPatterns {
+		if p.fuzzEngine == fuzzEngine {
+			return p.pattern, nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported fuzzing engine %q, want one of %v", fuzzEngine, SupportedFuzzEngines())
+}
+
+// SupportedFuzzEngines returns the names of the fuzzing engines, as used by
+// ClusterFuzz, whose logs FuzzBinder can parse.
+func SupportedFuzzEngines() []string {
+	engines := make([]string, 0, len(executedUnitsPatterns))
+	for _, p := range executedUnitsPatterns {
+		engines = append(engines, p.fuzzEngine)
+	}
+	return engines
+}
+
 // getFuzzStatsFromScanner gets the fuzzing effort (execution time and number of tests) from a
-// fuzzer log scanner of the good revision of the source code.
+// fuzzer log scanner of the good revision of the source code, with the given fuzzing engine.
 // A log file generated by ClusterFuzz contains:
 //
 //  1. The execution time in this format: 'Command: {command}\n' + 'Time ran: {time}\n'
 //     reference: https://github.com/google/clusterfuzz/blob/master/src/clusterfuzz/_internal/bot/fuzzers/engine_common.py#L70
 //
-//  2. The number of tests in this format (for LibFuzzer): 'stat::number_of_executed_units {number_of_executed_units}'
-//     reference:
-//     https://github.com/google/clusterfuzz/blob/910f08b9316a729c4c6b05ed260f97d1d03c3d88/src/clusterfuzz/_internal/metrics/fuzzer_stats_schema.py#L214
-//     and
-//     https://github.com/google/clusterfuzz/blob/910f08b9316a729c4c6b05ed260f97d1d03c3d88/src/clusterfuzz/_internal/bot/fuzzers/libfuzzer.py#L1377
-func getFuzzStatsFromScanner(lineScanner *bufio.Scanner) (*FuzzEffort, error) {
+//  2. The number of tests, in the format of the fuzzing engine, as listed in
+//     executedUnitsPatterns. Only the format of the given engine is matched,
+//     so that the output of the fuzz-target cannot be mistaken for the
+//     statistics of another engine, and the largest number is used, since
+//     some engines print running totals.
+func getFuzzStatsFromScanner(lineScanner *bufio.Scanner, fuzzEngine string) (*FuzzEffort, error) {
+	pattern, err := executedUnitsPatternOf(fuzzEngine)
+	if err != nil {
+		return nil, err
+	}
 	var fuzzEffort FuzzEffort
 	for lineScanner.Scan() {
-		// Get the fuzzing time in seconds.
-		if strings.Contains(lineScanner.Text(), "Time ran:") {
-			timeFuzzStr := strings.Split(lineScanner.Text(), " ")[2]
-			timeFuzzSecondsTemp, err := strconv.ParseFloat(timeFuzzStr, 32)
-			if err != nil {
-				return nil, fmt.Errorf("could not convert %q to float: %v", timeFuzzStr, err)
-			}
-			fuzzEffort.fuzzTimeSeconds = timeFuzzSecondsTemp
+		if err := addFuzzStatsFromLine(&fuzzEffort, lineScanner.Text(), pattern); err != nil {
+			return nil, err
 		}
-		// Get the number of fuzzing tests.
-		if strings.Contains(lineScanner.Text(), "stat::number_of_executed_units") {
-			numTestsStr := strings.Split(lineScanner.Text(), " ")[1]
-			numTestsTemp, err := strconv.Atoi(numTestsStr)
-			if err != nil {
-				return nil, fmt.Errorf("could not convert %q to int: %v", numTestsStr, err)
-			}
+	}
+	if err := lineScanner.Err(); err != nil {
+		return nil, fmt.Errorf("could not read the log file: %v", err)
+	}
+	return &fuzzEffort, nil
+}
+
+// addFuzzStatsFromLine updates the given fuzzing effort with the execution
+// time, or the number of tests matched by the given pattern, in the given line
+// of a fuzzer log, if any; see getFuzzStatsFromScanner.
+func addFuzzStatsFromLine(fuzzEffort *FuzzEffort, line string, executedUnitsPattern *regexp.Regexp) error {
+	// Get the fuzzing time in seconds.
+	if strings.HasPrefix(line, "Time ran:") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			return fmt.Errorf("could not find the fuzzing time in %q", line)
+		}
+		timeFuzzSecondsTemp, err := strconv.ParseFloat(fields[2], 32)
+		if err != nil {
+			return fmt.Errorf("could not convert %q to float: %v", fields[2], err)
+		}
+		fuzzEffort.fuzzTimeSeconds = timeFuzzSecondsTemp
+	}
+	// Get the number of fuzzing tests.
+	if match := executedUnitsPattern.FindStringSubmatch(line); match != nil {
+		numTestsTemp, err := strconv.Atoi(match[1])
+		if err != nil {
+			return fmt.Errorf("could not convert %q to int: %v", match[1], err)
+		}
+		if numTestsTemp > fuzzEffort.numberFuzzTests {
 			fuzzEffort.numberFuzzTests = numTestsTemp
 		}
-		if (fuzzEffort.fuzzTimeSeconds > 0) && (fuzzEffort.numberFuzzTests > 0) {
-			break
+	}
+	return nil
+}
+
+// FuzzConfiguration is a combination of a fuzzing engine and a sanitizer
+// with which ClusterFuzz fuzzes a fuzz-target.
+type FuzzConfiguration struct {
+	// FuzzEngine is one of SupportedFuzzEngines.
+	FuzzEngine string
+	// Sanitizer is the sanitizer, e.g., asan, ubsan, or msan.
+	Sanitizer string
+}
+
+// DetectFuzzConfigurations returns the combinations of fuzzing engines, among
+// SupportedFuzzEngines, and sanitizers for which ClusterFuzz stores logs of
+// the given fuzz-target, inferred from the layout of the logs bucket, as
+// described in getLogDirInfo. If the fuzzing engine or the sanitizer in
+// fuzzParameters is set, only the matching combinations are returned.
+func DetectFuzzConfigurations(client *gcsutil.Client, fuzzParameters *FuzzParameters, fuzzTarget string) ([]FuzzConfiguration, error) {
+	logsBucket, _ := getLogDirInfo(fuzzParameters, fuzzTarget)
+	var configurations []FuzzConfiguration
+	for _, engine := range SupportedFuzzEngines() {
+		if fuzzParameters.FuzzEngine != "" && fuzzParameters.FuzzEngine != engine {
+			continue
+		}
+		prefix := fmt.Sprintf("%s_%s_%s/", engine, fuzzParameters.ProjectName, fuzzTarget)
+		dirs, err := client.ListPrefixes(logsBucket, prefix)
+		if err != nil {
+			return nil, fmt.Errorf("could not list the logs of %s: %v", engine, err)
+		}
+		for _, dir := range dirs {
+			sanitizer, ok := sanitizerFromLogDir(strings.TrimPrefix(dir, prefix), engine, fuzzParameters.ProjectName)
+			if !ok {
+				continue
+			}
+			if fuzzParameters.Sanitizer != "" && fuzzParameters.Sanitizer != sanitizer {
+				continue
+			}
+			configurations = append(configurations, FuzzConfiguration{FuzzEngine: engine, Sanitizer: sanitizer})
 		}
 	}
-	return &fuzzEffort, nil
+	if len(configurations) == 0 {
+		return nil, fmt.Errorf("could not find logs of %q for any of the fuzzing engines %v", fuzzTarget, SupportedFuzzEngines())
+	}
+	sort.Slice(configurations, func(i, j int) bool {
+		if configurations[i].FuzzEngine != configurations[j].FuzzEngine {
+			return configurations[i].FuzzEngine < configurations[j].FuzzEngine
+		}
+		return configurations[i].Sanitizer < configurations[j].Sanitizer
+	})
+	return configurations, nil
+}
+
+// sanitizerFromLogDir returns the sanitizer in the name of a directory of
+// logs of the given fuzzing engine and project, of the form
+// {fuzzengine}_{sanitizer}_{projectName}/, and whether the name has that
+// form.
+func sanitizerFromLogDir(dir, fuzzEngine, projectName string) (string, bool) {
+	name := strings.TrimSuffix(dir, "/")
+	enginePrefix, projectSuffix := strings.ToLower(fuzzEngine)+"_", "_"+projectName
+	if !strings.HasPrefix(name, enginePrefix) || !strings.HasSuffix(name, projectSuffix) ||
+		len(name) <= len(enginePrefix)+len(projectSuffix) {
+		return "", false
+	}
+	return name[len(enginePrefix) : len(name)-len(projectSuffix)], true
 }
 
 // checkHash checks if a log file has a good revision hash.
@@ -267,52 +409,93 @@ func checkHash(fileBytes []byte, revisionDigest intoto.DigestSet) (*bool, error)
 	return &isGoodHash, nil
 }
 
-// getFuzzEffortFromFile gets the fuzzingEffort from a single fuzzer log file.
-func getFuzzEffortFromFile(revisionDigest intoto.DigestSet, fileBytes []byte) (*FuzzEffort, error) {
-	isGoodHash, err := checkHash(fileBytes, revisionDigest)
-	if err != nil {
-		return nil, fmt.Errorf(
-			"could not check revision hash for fuzzing effort: %v", err)
-	}
-	if *isGoodHash {
-		reader := bytes.NewReader(fileBytes)
-		lineScanner := bufio.NewScanner(reader)
-		if err := lineScanner.Err(); err != nil {
-			return nil, fmt.Errorf(
-				"could not get linescanner for log file: %v", err)
-		}
-		return getFuzzStatsFromScanner(lineScanner)
-	}
-	noFuzzEffort := FuzzEffort{0, 0}
-	return &noFuzzEffort, nil
-}
+// maxLogLineBytes is the maximum length of the lines of fuzzer logs.
+const maxLogLineBytes = 1 << 20
 
-// TODO(#195): Check that crash detection is generalizable for all types of crashes
-// crashDetectedInFile detects crashes in log files that are related to a
-// given revision.
+// scanLog scans a single fuzzer log file, line by line, and gets both the
+// fuzzing effort and the detected crashes in it. The log is only counted if it
+// is related to the given revision, i.e., if it contains the revision hash.
+// The number of tests is parsed in the format of the given fuzzing engine.
+//
 // When a crash is detected, we observe that: a test case is created and
 // 'fuzzer-testcases/crash-' is printed in the logs.
 //
 // Examples of crash data are available here:
 //
 //	https://github.com/google/clusterfuzz/tree/master/src/clusterfuzz/_internal/tests/core/crash_analysis/stack_parsing/stack_analyzer_data
-func crashDetectedInFile(fileBytes []byte, revisionDigest intoto.DigestSet) (*Crash, error) {
-	isGoodHash, err := checkHash(fileBytes, revisionDigest)
+func scanLog(reader io.Reader, revisionDigest intoto.DigestSet, fuzzEngine string) (*FuzzEffort, *Crash, error) {
+	pattern, err := executedUnitsPatternOf(fuzzEngine)
 	if err != nil {
-		return nil, fmt.Errorf(
-			"could not check revision hash for crash detection: %v", err)
+		return nil, nil, err
+	}
+	var fuzzEffort FuzzEffort
+	var crash Crash
+	var report CrashReport
+	isGoodHash := false
+	lineScanner := bufio.NewScanner(reader)
+	lineScanner.Buffer(nil, maxLogLineBytes)
+	for lineScanner.Scan() {
+		line := lineScanner.Text()
+		isGoodHash = isGoodHash || strings.Contains(line, revisionDigest["sha1"])
+		if err := addFuzzStatsFromLine(&fuzzEffort, line, pattern); err != nil {
+			return nil, nil, err
+		}
+		crash.detected = crash.detected || strings.Contains(line, "fuzzer-testcases/crash-")
+		// Use the first crash type and testcase in the log.
+		if match := crashSummaryPattern.FindStringSubmatch(line); match != nil && report.CrashType == "" {
+			report.CrashType = match[1]
+		}
+		if match := crashTestcasePattern.FindStringSubmatch(line); match != nil && report.TestcaseDigest == nil {
+			report.TestcaseDigest = intoto.DigestSet{"sha1": match[1]}
+		}
+	}
+	if err := lineScanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("could not read the log file: %v", err)
 	}
-	isDetected, err := regexp.Match("fuzzer-testcases/crash-", fileBytes)
+	if !isGoodHash {
+		return &FuzzEffort{0, 0}, &Crash{}, nil
+	}
+	if crash.detected {
+		crash.reports = []CrashReport{report}
+	}
+	return &fuzzEffort, &crash, nil
+}
+
+// getFuzzEffortFromFile gets the fuzzingEffort from a single fuzzer log file
+// of the given fuzzing engine.
+func getFuzzEffortFromFile(revisionDigest intoto.DigestSet, fileBytes []byte, fuzzEngine string) (*FuzzEffort, error) {
+	fuzzEffort, _, err := scanLog(bytes.NewReader(fileBytes), revisionDigest, fuzzEngine)
 	if err != nil {
-		return nil, fmt.Errorf(
-			"could not check if log file contains crashes: %v", err)
+		return nil, fmt.Errorf("could not get fuzzing effort from log file: %v", err)
 	}
-	crash := Crash{
-		detected: isDetected && *isGoodHash,
+	return fuzzEffort, nil
+}
+
+// TODO(#195): Check that crash detection is generalizable for all types of crashes
+// crashDetectedInFile detects crashes in log files that are related to a
+// given revision; see scanLog.
+func crashDetectedInFile(fileBytes []byte, revisionDigest intoto.DigestSet, fuzzEngine string) (*Crash, error) {
+	_, crash, err := scanLog(bytes.NewReader(fileBytes), revisionDigest, fuzzEngine)
+	if err != nil {
+		return nil, fmt.Errorf("could not analyze log file for crashes: %v", err)
 	}
-	return &crash, nil
+	return crash, nil
 }
 
+// crashSummaryPattern matches the summary of a crash printed by sanitizers
+// and libFuzzer, e.g., 'SUMMARY: AddressSanitizer: heap-buffer-overflow ...',
+// with the crash type in its first group.
+//
+//nolint:gochecknoglobals
+var crashSummaryPattern = regexp.MustCompile(`SUMMARY: [^:\s]+: (deadly signal|[^\s(]+)`)
+
+// crashTestcasePattern matches the path of the testcase of a crash, which
+// libFuzzer names after the SHA1 digest of the testcase, with the digest in
+// its first group.
+//
+//nolint:gochecknoglobals
+var crashTestcasePattern = regexp.MustCompile(`fuzzer-testcases/crash-([0-9a-f]{40})`)
+
 // getGCSFileDigest gets the digest of a file stored in GCS.
 func getGCSFileDigest(fileBytes []byte) *intoto.DigestSet {
 	sum256 := sha256.Sum256(fileBytes)
@@ -395,45 +578,60 @@ func GetFuzzTargets(client *gcsutil.Client, fuzzParameters *FuzzParameters) ([]s
 	return fuzzTargets, nil
 }
 
-// addClaimEvidence adds an evidence to the list of the evidence files used by the fuzzscraper.
-func addClaimEvidence(client *gcsutil.Client, evidences []claims.ClaimEvidence, blobName string, role string) ([]claims.ClaimEvidence, error) {
+// addClaimEvidence adds an evidence to the list of the evidence files used by
+// the fuzzscraper. If entClient is not nil, the evidence file is copied to Ent,
+// and referenced by its Ent URI instead of its GCS URI.
+func addClaimEvidence(client *gcsutil.Client, entClient *ent.Client, evidences []claims.ClaimEvidence, blobName string, role string) ([]claims.ClaimEvidence, error) {
+	uri := client.URI(CoverageBucket, blobName)
 	fileBytes, err := client.GetBlobData(CoverageBucket, blobName)
 	if err != nil {
 		return nil, fmt.Errorf("could not get data in evidence file: %v", err)
 	}
+	evidenceURI, err := copyToEnt(entClient, uri, fileBytes)
+	if err != nil {
+		return nil, fmt.Errorf("could not copy %s to Ent: %v", uri, err)
+	}
 	digest := getGCSFileDigest(fileBytes)
 	evidence := claims.ClaimEvidence{
 		Role:   role,
-		URI:    fmt.Sprintf("gs://%s/%s", CoverageBucket, blobName),
+		URI:    evidenceURI,
 		Digest: *digest,
 	}
 	evidences = append(evidences, evidence)
 	return evidences, nil
 }
 
+// copyToEnt uploads the given content of the evidence file with the given URI
+// to Ent, and returns its Ent URI. Returns the given URI if entClient is nil.
+func copyToEnt(entClient *ent.Client, uri string, fileBytes []byte) (string, error) {
+	if entClient == nil {
+		return uri, nil
+	}
+	return entClient.Put(context.Background(), fileBytes)
+}
+
 // GetEvidences gets the list of the evidence files used by the fuzzscraper.
-func GetEvidences(client *gcsutil.Client, fuzzParameters *FuzzParameters, fuzzTargets []string) ([]claims.ClaimEvidence, error) {
+// If entClient is not nil, the evidence files are copied from GCS, where they
+// expire, to Ent, and referenced by their Ent URIs.
+func GetEvidences(client *gcsutil.Client, entClient *ent.Client, fuzzParameters *FuzzParameters, fuzzTargets []string) ([]claims.ClaimEvidence, error) {
 	evidences := make([]claims.ClaimEvidence, 0, len(fuzzTargets)+2)
-	// TODO(#174): Replace GCS path by Ent path in evidences URI.
 	// The GCS absolute path of the file containing the revision hash of the source code used
 	// in the coverage build on a given day.
 	blobName := fmt.Sprintf("%s/srcmap/%s.json", fuzzParameters.ProjectName, fuzzParameters.Date)
-	evidences, err := addClaimEvidence(client, evidences, blobName, "srcmap")
+	evidences, err := addClaimEvidence(client, entClient, evidences, blobName, "srcmap")
 	if err != nil {
 		return nil, fmt.Errorf("could not add srcmap evidence: %v", err)
 	}
-	// TODO(#174): Replace GCS path by Ent path in evidences URI.
 	// The GCS absolute path of the file containing the coverage summary for the project on a given day.
 	blobName = fmt.Sprintf("%s/reports/%s/linux/summary.json", fuzzParameters.ProjectName, fuzzParameters.Date)
-	evidences, err = addClaimEvidence(client, evidences, blobName, "project coverage")
+	evidences, err = addClaimEvidence(client, entClient, evidences, blobName, "project coverage")
 	if err != nil {
 		return nil, fmt.Errorf("could not add project coverage evidence: %v", err)
 	}
 	for _, fuzzTarget := range fuzzTargets {
-		// TODO(#174): Replace GCS path by Ent path in evidences URI.
 		// The GCS absolute path of the file containing the coverage summary for a fuzz-target on a given day.
 		blobName = fmt.Sprintf("%s/fuzzer_stats/%s/%v.json", fuzzParameters.ProjectName, fuzzParameters.Date, fuzzTarget)
-		evidences, err = addClaimEvidence(client, evidences, blobName, "fuzzTarget coverage")
+		evidences, err = addClaimEvidence(client, entClient, evidences, blobName, "fuzzTarget coverage")
 		if err != nil {
 			return nil, fmt.Errorf("could not add fuzzTarget coverage evidence: %v", err)
 		}
@@ -441,74 +639,147 @@ func GetEvidences(client *gcsutil.Client, fuzzParameters *FuzzParameters, fuzzTa
 	return evidences, nil
 }
 
+// GetFuzzEffortAndCrashes gets the fuzzing efforts for a given revision of a
+// source code on a given day, and checks whether there are any detected
+// crashes, with the details of all detected crashes, in a single pass over
+// the fuzzer logs. The logs are listed page by page, read concurrently, and
+// scanned line by line. The fuzzing engine of the given parameters must be set,
+// since the logs are stored, and parsed, per fuzzing engine.
+// TODO(#172): Rename functions that take a lot of computation.
+func GetFuzzEffortAndCrashes(client *gcsutil.Client, revisionDigest intoto.DigestSet, fuzzParameters *FuzzParameters, fuzzTarget string) (*FuzzEffort, *Crash, error) {
+	bucketName, relativePath := getLogDirInfo(fuzzParameters, fuzzTarget)
+	var fuzzEffort FuzzEffort
+	var crash Crash
+	// Scan the logs page by page, so that only the statistics of the logs of
+	// a page are held at a time.
+	err := client.ForEachLogFilePage(bucketName, relativePath, gcsutil.DefaultListPageSize, func(logFilePaths []string) error {
+		fuzzEfforts := make([]*FuzzEffort, len(logFilePaths))
+		crashes := make([]*Crash, len(logFilePaths))
+		err := client.ScanBlobs(bucketName, logFilePaths, func(i int, reader io.Reader) error {
+			var err error
+			fuzzEfforts[i], crashes[i], err = scanLog(reader, revisionDigest, fuzzParameters.FuzzEngine)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		// Aggregate in the order of the logs, so that the crash reports are
+		// deterministic.
+		for i := range logFilePaths {
+			fuzzEffort.numberFuzzTests += fuzzEfforts[i].numberFuzzTests
+			fuzzEffort.fuzzTimeSeconds += fuzzEfforts[i].fuzzTimeSeconds
+			crash.detected = crash.detected || crashes[i].detected
+			crash.reports = append(crash.reports, crashes[i].reports...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not analyze log data: %v", err)
+	}
+	return &fuzzEffort, &crash, nil
+}
+
 // GetFuzzEffort gets the fuzzing efforts for a given revision
 // of a source code on a given day.
-// TODO(#172): Rename functions that take a lot of computation.
 func GetFuzzEffort(client *gcsutil.Client, revisionDigest intoto.DigestSet, fuzzParameters *FuzzParameters, fuzzTarget string) (*FuzzEffort, error) {
-	bucketName, relativePath := getLogDirInfo(fuzzParameters, fuzzTarget)
-	listFileBytes, err := client.GetLogsData(bucketName, relativePath)
+	fuzzEffort, _, err := GetFuzzEffortAndCrashes(client, revisionDigest, fuzzParameters, fuzzTarget)
 	if err != nil {
 		return nil, fmt.Errorf(
 			"could not get logs data to extract fuzzing efforts: %v", err)
 	}
-	var fuzzEffort FuzzEffort
-	for _, fileBytes := range listFileBytes {
-		fuzzEffortFile, err := getFuzzEffortFromFile(revisionDigest, fileBytes)
-		if err != nil {
-			return nil, fmt.Errorf(
-				"could not get fuzzing efforts from log data: %v", err)
-		}
-		fuzzEffort.numberFuzzTests += fuzzEffortFile.numberFuzzTests
-		fuzzEffort.fuzzTimeSeconds += fuzzEffortFile.fuzzTimeSeconds
-	}
-	return &fuzzEffort, nil
+	return fuzzEffort, nil
 }
 
 // GetCrashes checks whether there are any detected crashes for
-// a revision of a source code on a given day.
+// a revision of a source code on a given day, and returns the details of all
+// detected crashes.
 func GetCrashes(client *gcsutil.Client, revisionDigest intoto.DigestSet, fuzzParameters *FuzzParameters, fuzzTarget string) (*Crash, error) {
-	bucketName, relativePath := getLogDirInfo(fuzzParameters, fuzzTarget)
-	listFileBytes, err := client.GetLogsData(bucketName, relativePath)
+	_, crash, err := GetFuzzEffortAndCrashes(client, revisionDigest, fuzzParameters, fuzzTarget)
 	if err != nil {
 		return nil, fmt.Errorf(
 			"could not get logs data to detect crashes: %v", err)
 	}
-	for _, fileBytes := range listFileBytes {
-		crash, err := crashDetectedInFile(fileBytes, revisionDigest)
-		if err != nil {
-			return nil, fmt.Errorf(
-				"could not analyze log data for crashes: %v", err)
-		}
-		if crash.detected {
-			return crash, nil
-		}
-	}
-	noCrash := Crash{
-		detected: false,
-	}
-	return &noCrash, nil
+	return crash, nil
+}
+
+// fuzzTargetExtensions are the extensions of the source files of fuzz-targets
+// in the languages supported by OSS-Fuzz.
+//
+//nolint:gochecknoglobals
+var fuzzTargetExtensions = map[string]bool{
+	".c": true, ".cc": true, ".cpp": true, ".cxx": true, ".rs": true,
+	".go": true, ".java": true, ".py": true, ".swift": true, ".js": true,
 }
 
 // extractFuzzTargetPath gets the fuzz-target path from a coverage report summary file.
 // The paths to the source code files used for fuzzing are listed in the filenames in
 // the `CoverageSummary`, including the fuzz-target files from which the path to the
-// fuzz-target can be extracted.
+// fuzz-target can be extracted. Only the source files of the project, which OSS-Fuzz
+// checks out in /src/{projectName}, are considered. A source file named after the
+// fuzz-target, e.g., {fuzz-target}.cc, is preferred over a source file whose name
+// contains the name of the fuzz-target, e.g., {fuzz-target}_fuzzer.cc.
 func extractFuzzTargetPath(fileBytes []byte, fuzzParameters FuzzParameters, fuzzTarget string) (*string, error) {
 	var summary CoverageSummary
 	err := json.Unmarshal(fileBytes, &summary)
 	if err != nil {
 		return nil, fmt.Errorf("could not unmarshal fileBytes into a %T: %v", summary, err)
 	}
+	if len(summary.Data) == 0 {
+		return nil, fmt.Errorf("could not find coverage data in the coverage summary")
+	}
+	projectDir := fmt.Sprintf("/src/%s/", fuzzParameters.ProjectName)
+	var partialMatches []string
 	for _, fileSummary := range summary.Data[0].Files {
-		if strings.Contains(fileSummary.Filename, fuzzTarget) {
-			// Extract the path of the fuzz-target, if the filename contains that fuzz-target in it.
-			pathFuzzTarget := strings.Split(fileSummary.Filename, fuzzParameters.ProjectName+"/")[1]
-			return &pathFuzzTarget, nil
+		relativePath := strings.TrimPrefix(fileSummary.Filename, projectDir)
+		extension := path.Ext(relativePath)
+		if relativePath == fileSummary.Filename || !fuzzTargetExtensions[extension] {
+			continue
+		}
+		name := strings.TrimSuffix(path.Base(relativePath), extension)
+		if name == fuzzTarget {
+			return &relativePath, nil
 		}
+		if strings.Contains(name, fuzzTarget) {
+			partialMatches = append(partialMatches, relativePath)
+		}
+	}
+	if len(partialMatches) == 1 {
+		return &partialMatches[0], nil
+	}
+	if len(partialMatches) > 1 {
+		return nil, fmt.Errorf("could not choose the fuzz-target path among %v in the coverage summary", partialMatches)
 	}
 	return nil, fmt.Errorf("could not find fuzz-target path in the coverage summary")
 }
 
+// fuzzTargetSourceURI returns the URI of the raw content of the source file at
+// the given path in the given revision of the given Git repository, or an
+// empty string if the repository is not hosted on GitHub.
+func fuzzTargetSourceURI(gitRepo string, revisionDigest intoto.DigestSet, sourcePath string) string {
+	repo := strings.TrimSuffix(strings.TrimSuffix(gitRepo, "/"), ".git")
+	const githubPrefix = "https://github.com/"
+	if !strings.HasPrefix(repo, githubPrefix) || revisionDigest["sha1"] == "" {
+		return ""
+	}
+	return fmt.Sprintf("https://raw.githubusercontent.com/%s/%s/%s",
+		strings.TrimPrefix(repo, githubPrefix), revisionDigest["sha1"], sourcePath)
+}
+
+// GetFuzzTargetSourceDigest gets the digest of the source file of a fuzz-target
+// at the given path, in the given revision of the project's GitHub repository.
+// Returns nil if the repository is not hosted on GitHub.
+func GetFuzzTargetSourceDigest(fuzzParameters FuzzParameters, revisionDigest intoto.DigestSet, sourcePath string) (intoto.DigestSet, error) {
+	uri := fuzzTargetSourceURI(fuzzParameters.ProjectGitRepo, revisionDigest, sourcePath)
+	if uri == "" {
+		return nil, nil
+	}
+	fileBytes, err := fetch.Fetch(context.Background(), uri)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch the source file of the fuzz-target: %v", err)
+	}
+	return *getGCSFileDigest(fileBytes), nil
+}
+
 // GetFuzzTargetsPath gets the path of a fuzz-target in the project's GitHub repository.
 func GetFuzzTargetsPath(client *gcsutil.Client, fuzzParameters FuzzParameters, fuzzTarget string) (*string, error) {
 	fileName := fmt.Sprintf("%s/fuzzer_stats/%s/%s.json", fuzzParameters.ProjectName, fuzzParameters.Date, fuzzTarget)
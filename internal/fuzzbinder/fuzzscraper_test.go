@@ -14,8 +14,10 @@
 package fuzzbinder
 
 import (
+	"bufio"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/project-oak/transparent-release/internal/testutil"
@@ -81,6 +83,25 @@ func TestGetLogDirInfo(t *testing.T) {
 	}
 }
 
+func TestSanitizerFromLogDir(t *testing.T) {
+	tests := []struct {
+		dir, engine, wantSanitizer string
+		wantOK                     bool
+	}{
+		{"libfuzzer_asan_oak/", "libFuzzer", "asan", true},
+		{"honggfuzz_ubsan_oak/", "honggfuzz", "ubsan", true},
+		{"libfuzzer_asan_other/", "libFuzzer", "", false},
+		{"afl_asan_oak/", "libFuzzer", "", false},
+		{"libfuzzer__oak/", "libFuzzer", "", false},
+	}
+	for _, tc := range tests {
+		sanitizer, ok := sanitizerFromLogDir(tc.dir, tc.engine, projectName)
+		if sanitizer != tc.wantSanitizer || ok != tc.wantOK {
+			t.Errorf("sanitizerFromLogDir(%q, %q): got (%q, %t), want (%q, %t)", tc.dir, tc.engine, sanitizer, ok, tc.wantSanitizer, tc.wantOK)
+		}
+	}
+}
+
 func TestCheckHash(t *testing.T) {
 	revisionDigest := intoto.DigestSet{
 		"sha1": hash,
@@ -108,7 +129,7 @@ func TestGetFuzzEffortFromFile(t *testing.T) {
 	if err != nil {
 		t.Fatalf("%v", err)
 	}
-	fuzzEffort, err := getFuzzEffortFromFile(revisionDigest, fileBytes)
+	fuzzEffort, err := getFuzzEffortFromFile(revisionDigest, fileBytes, "libFuzzer")
 	if err != nil {
 		t.Fatalf("%v", err)
 	}
@@ -120,6 +141,62 @@ func TestGetFuzzEffortFromFile(t *testing.T) {
 	}
 }
 
+func TestGetFuzzStatsFromScanner_Engines(t *testing.T) {
+	tests := []struct {
+		engine string
+		log    string
+		want   int
+	}{
+		{"libFuzzer", "stat::number_of_executed_units: 4850288\n", 4850288},
+		{"afl", "execs_done        : 123456\nexecs_per_sec     : 100.00\n", 123456},
+		{"honggfuzz", "Summary iterations:98765 time:600 speed:164 crashes_count:0 timeout_count:0\n", 98765},
+		{"centipede", "[S0.1000] begin-fuzz: ft: 10 corp: 1/1\n[S0.25000] end-fuzz: ft: 20 corp: 2/2\n", 25000},
+	}
+	for _, tc := range tests {
+		t.Run(tc.engine, func(t *testing.T) {
+			log := "Command: fuzz\nTime ran: 600.5\n" + tc.log
+			fuzzEffort, err := getFuzzStatsFromScanner(bufio.NewScanner(strings.NewReader(log)), tc.engine)
+			if err != nil {
+				t.Fatalf("could not parse the log: %v", err)
+			}
+			testutil.AssertEq(t, "numberFuzzTests", fuzzEffort.numberFuzzTests, tc.want)
+			testutil.AssertEq(t, "fuzzTimeSeconds", fuzzEffort.fuzzTimeSeconds, 600.5)
+		})
+	}
+}
+
+func TestGetFuzzStatsFromScanner_OnlyConfiguredEngine(t *testing.T) {
+	// The output of the fuzz-target may contain the statistics of other
+	// engines, or the statistics of the engine after other text.
+	log := "execs_done : 999999\nSummary iterations:999999\n[S0.999999] end-fuzz\n" +
+		"INFO: stat::number_of_executed_units: 999999\nstat::number_of_executed_units: 1234\n"
+	fuzzEffort, err := getFuzzStatsFromScanner(bufio.NewScanner(strings.NewReader(log)), "libFuzzer")
+	if err != nil {
+		t.Fatalf("could not parse the log: %v", err)
+	}
+	testutil.AssertEq(t, "numberFuzzTests", fuzzEffort.numberFuzzTests, 1234)
+
+	if _, err := getFuzzStatsFromScanner(bufio.NewScanner(strings.NewReader(log)), "jazzer"); err == nil {
+		t.Errorf("expected an error for an unsupported fuzzing engine")
+	}
+}
+
+func TestGetFuzzStatsFromScanner_TimeRan(t *testing.T) {
+	// Lines mentioning the time elsewhere are ignored.
+	log := "Time ran: 600.5\nOutput: Time ran:\n"
+	fuzzEffort, err := getFuzzStatsFromScanner(bufio.NewScanner(strings.NewReader(log)), "libFuzzer")
+	if err != nil {
+		t.Fatalf("could not parse the log: %v", err)
+	}
+	testutil.AssertEq(t, "fuzzTimeSeconds", fuzzEffort.fuzzTimeSeconds, 600.5)
+
+	for _, line := range []string{"Time ran:", "Time ran: ", "Time ran: soon"} {
+		if _, err := getFuzzStatsFromScanner(bufio.NewScanner(strings.NewReader(line)), "libFuzzer"); err == nil {
+			t.Errorf("expected an error for %q", line)
+		}
+	}
+}
+
 func TestCrashDetected(t *testing.T) {
 	revisionDigest := intoto.DigestSet{
 		"sha1": hash,
@@ -129,7 +206,7 @@ func TestCrashDetected(t *testing.T) {
 	if err != nil {
 		t.Fatalf("%v", err)
 	}
-	got, err := crashDetectedInFile(fileBytes, revisionDigest)
+	got, err := crashDetectedInFile(fileBytes, revisionDigest, "libFuzzer")
 	if err != nil {
 		t.Fatalf("%v", err)
 	}
@@ -141,13 +218,47 @@ func TestCrashDetected(t *testing.T) {
 	if err != nil {
 		t.Fatalf("%v", err)
 	}
-	got, err = crashDetectedInFile(fileBytes, revisionDigest)
+	got, err = crashDetectedInFile(fileBytes, revisionDigest, "libFuzzer")
 	if err != nil {
 		t.Fatalf("%v", err)
 	}
 	if !got.detected {
 		t.Errorf("unexpected crash detection: got %v, want true", got.detected)
 	}
+	if len(got.reports) != 1 {
+		t.Fatalf("unexpected number of crash reports: got %d, want 1", len(got.reports))
+	}
+	testutil.AssertEq(t, "crashType", got.reports[0].CrashType, "ABRT")
+	testutil.AssertEq(t, "testcaseDigest", got.reports[0].TestcaseDigest["sha1"], "c02bd6b9fb02092ebdd7476679f788687667d10b")
+}
+
+func TestScanLog(t *testing.T) {
+	revisionDigest := intoto.DigestSet{
+		"sha1": hash,
+	}
+	fileBytes, err := os.ReadFile(filepath.Join(testdataPath, logFileWithCrashPath))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	// Lines longer than the default buffer of bufio.Scanner are scanned.
+	longLine := strings.Repeat("x", 100_000) + "\n"
+	fuzzEffort, crash, err := scanLog(strings.NewReader(longLine+string(fileBytes)), revisionDigest, "libFuzzer")
+	if err != nil {
+		t.Fatalf("could not scan the log: %v", err)
+	}
+	if fuzzEffort.fuzzTimeSeconds == 0 {
+		t.Errorf("unexpected fuzzTimeSeconds: got %v, want non-zero value", fuzzEffort.fuzzTimeSeconds)
+	}
+	testutil.AssertEq(t, "detected", crash.detected, true)
+
+	// Logs of other revisions are ignored.
+	fuzzEffort, crash, err = scanLog(strings.NewReader(string(fileBytes)), intoto.DigestSet{"sha1": "0000000000000000000000000000000000000000"}, "libFuzzer")
+	if err != nil {
+		t.Fatalf("could not scan the log: %v", err)
+	}
+	testutil.AssertEq(t, "fuzzEffort", *fuzzEffort, FuzzEffort{})
+	testutil.AssertEq(t, "detected", crash.detected, false)
+	testutil.AssertEq(t, "reports", len(crash.reports), 0)
 }
 
 func TestGetGCSFileDigest(t *testing.T) {
@@ -184,3 +295,50 @@ func TestExtractFuzzTargetPath(t *testing.T) {
 		t.Errorf("invalid fuzz-target path: got %q want %q", *got, want)
 	}
 }
+
+func TestExtractFuzzTargetPath_Languages(t *testing.T) {
+	fuzzParameters := FuzzParameters{ProjectName: "project"}
+	summary := []byte(`{"data": [{"files": [
+		{"filename": "/src/libfuzzer/FuzzerMain.cpp"},
+		{"filename": "/src/project/README.md"},
+		{"filename": "/src/project/fuzz/parse_fuzzer.cc"},
+		{"filename": "/src/project/fuzz/parse.cc"},
+		{"filename": "/src/project/fuzz/DecoderFuzzer.java"},
+		{"filename": "/src/project/pkg/fuzz_unmarshal_test.go"},
+		{"filename": "/src/project/fuzz/json_a_fuzzer.c"},
+		{"filename": "/src/project/fuzz/json_b_fuzzer.c"}
+	]}]}`)
+	tests := []struct {
+		fuzzTarget string
+		want       string
+	}{
+		// The exact match is preferred over the partial match.
+		{fuzzTarget: "parse", want: "fuzz/parse.cc"},
+		{fuzzTarget: "parse_fuzzer", want: "fuzz/parse_fuzzer.cc"},
+		{fuzzTarget: "DecoderFuzzer", want: "fuzz/DecoderFuzzer.java"},
+		{fuzzTarget: "fuzz_unmarshal", want: "pkg/fuzz_unmarshal_test.go"},
+	}
+	for _, tt := range tests {
+		got, err := extractFuzzTargetPath(summary, fuzzParameters, tt.fuzzTarget)
+		if err != nil {
+			t.Errorf("could not get the path of %s: %v", tt.fuzzTarget, err)
+			continue
+		}
+		testutil.AssertEq(t, tt.fuzzTarget, *got, tt.want)
+	}
+
+	// Ambiguous, outside of the project, or not a source file.
+	for _, fuzzTarget := range []string{"json", "FuzzerMain", "README"} {
+		if got, err := extractFuzzTargetPath(summary, fuzzParameters, fuzzTarget); err == nil {
+			t.Errorf("expected an error for %s; got path %q", fuzzTarget, *got)
+		}
+	}
+}
+
+func TestFuzzTargetSourceURI(t *testing.T) {
+	revisionDigest := intoto.DigestSet{"sha1": "e6a3b0bc4f6e9d3f1a4e3c5b8b0b1a0c2d3e4f50"}
+	got := fuzzTargetSourceURI("https://github.com/project-oak/oak.git", revisionDigest, "fuzz/fuzz_targets/apply_policy.rs")
+	testutil.AssertEq(t, "GitHub URI", got,
+		"https://raw.githubusercontent.com/project-oak/oak/e6a3b0bc4f6e9d3f1a4e3c5b8b0b1a0c2d3e4f50/fuzz/fuzz_targets/apply_policy.rs")
+	testutil.AssertEq(t, "non-GitHub URI", fuzzTargetSourceURI("https://gitlab.com/project/project", revisionDigest, "fuzz.c"), "")
+}
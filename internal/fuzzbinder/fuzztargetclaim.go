@@ -0,0 +1,119 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fuzzbinder
+
+// This file provides per-target fuzzing claims, derived from an aggregate
+// fuzzing claim. A per-target fuzzing claim has the same subject as the
+// aggregate claim, and the FuzzSpecPerTarget of a single fuzz-target as its
+// `ClaimSpec`, so that policies can be applied to individual fuzz-targets.
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/project-oak/transparent-release/pkg/claims"
+	"github.com/project-oak/transparent-release/pkg/intoto"
+)
+
+// FuzzTargetClaimV1 is the URI that should be used as the ClaimType in
+// ClaimV1 representing a V1 fuzzing claim for a single fuzz-target.
+const FuzzTargetClaimV1 = "https://github.com/project-oak/transparent-release/fuzz_target_claim/v1"
+
+// GenerateFuzzTargetClaims generates one fuzzing claim per fuzz-target in the
+// given aggregate fuzzing claim, as returned by GenerateFuzzClaim. Each claim
+// has the subject, issuance time, and validity of the aggregate claim, and
+// references the srcmap and the coverage report of its fuzz-target as
+// evidence. Fuzz-targets with unknown statistics have no claim.
+func GenerateFuzzTargetClaims(aggregate *intoto.Statement) ([]*intoto.Statement, error) {
+	predicate, ok := aggregate.Predicate.(*claims.ClaimPredicate)
+	if !ok {
+		return nil, fmt.Errorf("the predicate of the aggregate fuzzing claim does not have the expected type; got: %T, want: *ClaimPredicate", aggregate.Predicate)
+	}
+	spec, ok := predicate.ClaimSpec.(FuzzClaimSpec)
+	if !ok || predicate.ClaimType != FuzzClaimV1 {
+		return nil, fmt.Errorf("the aggregate claim is not a fuzzing claim")
+	}
+
+	statements := make([]*intoto.Statement, 0, len(spec.PerTarget))
+	for _, target := range spec.PerTarget {
+		// There is nothing to claim about fuzz-targets with unknown
+		// statistics.
+		if target.FuzzStats == nil {
+			continue
+		}
+		targetPredicate := claims.ClaimPredicate{
+			ClaimType: FuzzTargetClaimV1,
+			ClaimSpec: target,
+			IssuedOn:  predicate.IssuedOn,
+			Validity:  predicate.Validity,
+			Evidence:  fuzzTargetEvidence(predicate.Evidence, target.Name),
+		}
+		statement := intoto.Statement{
+			StatementHeader: aggregate.StatementHeader,
+			Predicate:       targetPredicate,
+		}
+		validPredicate, err := ValidateFuzzTargetClaim(statement)
+		if err != nil {
+			return nil, fmt.Errorf("could not validate the fuzzing claim of %s: %v", target.Name, err)
+		}
+		statement.Predicate = validPredicate
+		statements = append(statements, &statement)
+	}
+	return statements, nil
+}
+
+// ValidateFuzzTargetClaim validates that a Claim is a per-target fuzzing
+// claim with a valid ClaimType. If valid, the ClaimPredicate object is
+// returned. Otherwise an error is returned.
+func ValidateFuzzTargetClaim(statement intoto.Statement) (*claims.ClaimPredicate, error) {
+	predicate, err := claims.ValidateClaim(statement)
+	if err != nil {
+		return nil, fmt.Errorf("could not validate the fuzzing Claim: %v", err)
+	}
+	if predicate.ClaimType != FuzzTargetClaimV1 {
+		return nil, fmt.Errorf(
+			"the claimPredicate does not have the expected claim type; got: %s, want: %s",
+			predicate.ClaimType,
+			FuzzTargetClaimV1)
+	}
+	spec, ok := predicate.ClaimSpec.(FuzzSpecPerTarget)
+	if !ok {
+		return nil, fmt.Errorf(
+			"the claimSpec does not have the expected type; got: %T, want: FuzzSpecPerTarget",
+			predicate.ClaimSpec)
+	}
+	if spec.Name == "" || spec.FuzzStats == nil {
+		return nil, fmt.Errorf("the claimSpec must have a fuzz-target name and fuzzing statistics")
+	}
+	return predicate, nil
+}
+
+// fuzzTargetEvidence returns the evidence of the aggregate claim that
+// applies to the given fuzz-target: the srcmap and the coverage report of the
+// fuzz-target.
+func fuzzTargetEvidence(evidence []claims.ClaimEvidence, fuzzTarget string) []claims.ClaimEvidence {
+	var targetEvidence []claims.ClaimEvidence
+	for _, e := range evidence {
+		switch e.Role {
+		case "srcmap":
+			targetEvidence = append(targetEvidence, e)
+		case "fuzzTarget coverage":
+			if strings.HasSuffix(e.URI, "/"+fuzzTarget+".json") {
+				targetEvidence = append(targetEvidence, e)
+			}
+		}
+	}
+	return targetEvidence
+}
@@ -0,0 +1,46 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fuzzbinder
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/project-oak/transparent-release/internal/testutil"
+	"github.com/project-oak/transparent-release/pkg/claims"
+)
+
+func TestGenerateFuzzTargetClaims(t *testing.T) {
+	aggregate, err := ParseFuzzClaimFile(filepath.Join(testdataPath, fuzzclaimExamplePath))
+	if err != nil {
+		t.Fatalf("failed to parse fuzzing claim example: %v", err)
+	}
+
+	statements, err := GenerateFuzzTargetClaims(aggregate)
+	if err != nil {
+		t.Fatalf("failed to generate per-target fuzzing claims: %v", err)
+	}
+	testutil.AssertEq(t, "number of claims", len(statements), 2)
+
+	statement := statements[1]
+	predicate := statement.Predicate.(*claims.ClaimPredicate)
+	spec := predicate.ClaimSpec.(FuzzSpecPerTarget)
+	testutil.AssertEq(t, "subject[0].name", statement.Subject[0].Name, "https://github.com/project-oak/oak")
+	testutil.AssertEq(t, "claimType", predicate.ClaimType, FuzzTargetClaimV1)
+	testutil.AssertEq(t, "name", spec.Name, "failing")
+	testutil.AssertEq(t, "fuzzStats.detectedCrashes", spec.FuzzStats.DetectedCrashes, true)
+	testutil.AssertEq(t, "number of evidence", len(predicate.Evidence), 2)
+	testutil.AssertEq(t, "evidence[1].uri", predicate.Evidence[1].URI, "gs://oss-fuzz-coverage/oak/fuzzer_stats/20221205/failing.json")
+}
@@ -0,0 +1,171 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fuzzbinder
+
+// This file provides checks of fuzzing claims against reference thresholds,
+// so that releases can be gated on fuzzing criteria.
+
+import (
+	"fmt"
+
+	"go.uber.org/multierr"
+
+	"github.com/project-oak/transparent-release/pkg/intoto"
+)
+
+// FuzzPolicy contains the reference thresholds that a fuzzing claim must
+// meet. Zero thresholds are not checked.
+type FuzzPolicy struct {
+	// MinLineCoverage is the minimum line coverage, in percent.
+	MinLineCoverage float64 `json:"minLineCoverage,omitempty"`
+	// MinBranchCoverage is the minimum branch coverage, in percent.
+	MinBranchCoverage float64 `json:"minBranchCoverage,omitempty"`
+	// MinFuzzTimeSeconds is the minimum fuzzing time in seconds.
+	MinFuzzTimeSeconds float64 `json:"minFuzzTimeSeconds,omitempty"`
+	// MinNumberFuzzTests is the minimum number of executed fuzzing tests.
+	MinNumberFuzzTests int `json:"minNumberFuzzTests,omitempty"`
+	// NoCrashes requires that no crashes were detected.
+	NoCrashes bool `json:"noCrashes,omitempty"`
+	// PerTarget applies the thresholds to every fuzz-target, in addition to
+	// the project, and requires the statistics of all fuzz-targets to be
+	// known.
+	PerTarget bool `json:"perTarget,omitempty"`
+}
+
+// PolicyCheck is the outcome of checking a fuzzing claim against a threshold
+// of a FuzzPolicy.
+type PolicyCheck struct {
+	// Name of the check, e.g., min_line_coverage.
+	Name string `json:"name"`
+	// Threshold of the check, e.g., "60.00%".
+	Threshold string `json:"threshold"`
+	// Passed is true if the project, and every fuzz-target if checked per
+	// target, meet the threshold.
+	Passed bool `json:"passed"`
+	// Errors lists the project and the fuzz-targets that do not meet the
+	// threshold.
+	Errors []string `json:"errors,omitempty"`
+}
+
+// PolicyReport lists the outcomes of checking a fuzzing claim against a
+// FuzzPolicy.
+type PolicyReport struct {
+	// Passed is true if all checks passed.
+	Passed bool          `json:"passed"`
+	Checks []PolicyCheck `json:"checks"`
+	errs   error
+}
+
+// Err returns the errors of all failed checks, or nil if all checks passed.
+func (r *PolicyReport) Err() error {
+	return r.errs
+}
+
+func (r *PolicyReport) addCheck(name, threshold string, errs error) {
+	check := PolicyCheck{Name: name, Threshold: threshold, Passed: errs == nil}
+	for _, err := range multierr.Errors(errs) {
+		check.Errors = append(check.Errors, err.Error())
+	}
+	r.Checks = append(r.Checks, check)
+	r.errs = multierr.Append(r.errs, errs)
+	r.Passed = r.errs == nil
+}
+
+// statsCheck checks the fuzzing statistics of the project, or of the given
+// fuzz-target, and returns an error if they do not meet a threshold.
+type statsCheck func(stats *FuzzStats, subject string) error
+
+// VerifyFuzzClaim checks the given fuzzing claim, as returned by
+// GenerateFuzzClaim or ParseFuzzClaimFile, against the thresholds of the
+// given policy, and returns a report of all checks. The claim meets the
+// policy if report.Err() is nil.
+func VerifyFuzzClaim(statement *intoto.Statement, policy *FuzzPolicy) (*PolicyReport, error) {
+	_, spec, err := fuzzClaimSpec(statement)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &PolicyReport{Passed: true}
+	check := func(name, threshold string, checkStats statsCheck) {
+		errs := checkStats(spec.PerProject, "the project")
+		if policy.PerTarget {
+			for _, target := range spec.PerTarget {
+				if target.FuzzStats == nil {
+					errs = multierr.Append(errs, fmt.Errorf("the statistics of fuzz-target %q are unknown: %s", target.Name, target.UnknownReason))
+					continue
+				}
+				errs = multierr.Append(errs, checkStats(target.FuzzStats, fmt.Sprintf("fuzz-target %q", target.Name)))
+			}
+		}
+		report.addCheck(name, threshold, errs)
+	}
+
+	if policy.MinLineCoverage > 0 {
+		check("min_line_coverage", fmt.Sprintf("%.2f%%", policy.MinLineCoverage), func(stats *FuzzStats, subject string) error {
+			return checkCoverage(stats.LineCoverage, policy.MinLineCoverage, "line", subject)
+		})
+	}
+	if policy.MinBranchCoverage > 0 {
+		check("min_branch_coverage", fmt.Sprintf("%.2f%%", policy.MinBranchCoverage), func(stats *FuzzStats, subject string) error {
+			return checkCoverage(stats.BranchCoverage, policy.MinBranchCoverage, "branch", subject)
+		})
+	}
+	if policy.MinFuzzTimeSeconds > 0 {
+		check("min_fuzz_time_seconds", fmt.Sprintf("%.2f", policy.MinFuzzTimeSeconds), func(stats *FuzzStats, subject string) error {
+			if stats.FuzzTimeSeconds < policy.MinFuzzTimeSeconds {
+				return fmt.Errorf("the fuzzing time of %s is %.2f seconds, want at least %.2f", subject, stats.FuzzTimeSeconds, policy.MinFuzzTimeSeconds)
+			}
+			return nil
+		})
+	}
+	if policy.MinNumberFuzzTests > 0 {
+		check("min_number_fuzz_tests", fmt.Sprintf("%d", policy.MinNumberFuzzTests), func(stats *FuzzStats, subject string) error {
+			if stats.NumberFuzzTests < policy.MinNumberFuzzTests {
+				return fmt.Errorf("the number of fuzzing tests of %s is %d, want at least %d", subject, stats.NumberFuzzTests, policy.MinNumberFuzzTests)
+			}
+			return nil
+		})
+	}
+	if policy.NoCrashes {
+		var errs error
+		for _, target := range spec.PerTarget {
+			if target.FuzzStats != nil && target.FuzzStats.DetectedCrashes {
+				errs = multierr.Append(errs, fmt.Errorf("fuzz-target %q detected crashes", target.Name))
+			}
+		}
+		for _, crashReport := range spec.CrashReports {
+			errs = multierr.Append(errs, fmt.Errorf("crash of type %q in fuzz-target %q, with testcase %v, issue %q",
+				crashReport.CrashType, crashReport.FuzzTarget, crashReport.TestcaseDigest, crashReport.IssueID))
+		}
+		if errs == nil && spec.PerProject.DetectedCrashes {
+			errs = fmt.Errorf("the project detected crashes")
+		}
+		report.addCheck("no_crashes", "true", errs)
+	}
+	return report, nil
+}
+
+// checkCoverage checks that the given coverage, in the format of
+// formatCoverage, is at least the given percentage.
+func checkCoverage(coverage string, minPercent float64, kind, subject string) error {
+	var percent float64
+	if _, err := fmt.Sscanf(coverage, "%f%%", &percent); err != nil {
+		return fmt.Errorf("could not parse the %s coverage of %s (%q): %v", kind, subject, coverage, err)
+	}
+	if percent < minPercent {
+		return fmt.Errorf("the %s coverage of %s is %s, want at least %.2f%%", kind, subject, coverage, minPercent)
+	}
+	return nil
+}
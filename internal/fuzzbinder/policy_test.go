@@ -0,0 +1,68 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fuzzbinder
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/project-oak/transparent-release/internal/testutil"
+)
+
+func TestVerifyFuzzClaim(t *testing.T) {
+	statement, err := ParseFuzzClaimFile(filepath.Join(testdataPath, fuzzclaimExamplePath))
+	if err != nil {
+		t.Fatalf("could not parse the fuzzing claim: %v", err)
+	}
+
+	// The project meets the thresholds.
+	policy := &FuzzPolicy{MinLineCoverage: 9.5, MinFuzzTimeSeconds: 3600, MinNumberFuzzTests: 1000}
+	report, err := VerifyFuzzClaim(statement, policy)
+	if err != nil {
+		t.Fatalf("could not verify the fuzzing claim: %v", err)
+	}
+	if err := report.Err(); err != nil {
+		t.Errorf("unexpected failed checks: %v", err)
+	}
+	testutil.AssertEq(t, "number of checks", len(report.Checks), 3)
+
+	// The fuzz-targets do not, and crashes were detected.
+	policy.PerTarget = true
+	policy.NoCrashes = true
+	report, err = VerifyFuzzClaim(statement, policy)
+	if err != nil {
+		t.Fatalf("could not verify the fuzzing claim: %v", err)
+	}
+	if report.Passed {
+		t.Fatalf("expected failed checks")
+	}
+	failed := map[string]string{}
+	for _, check := range report.Checks {
+		if !check.Passed {
+			failed[check.Name] = strings.Join(check.Errors, "; ")
+		}
+	}
+	testutil.AssertEq(t, "number of failed checks", len(failed), 3)
+	if !strings.Contains(failed["min_line_coverage"], `fuzz-target "apply_policy"`) {
+		t.Errorf("unexpected errors of min_line_coverage: %s", failed["min_line_coverage"])
+	}
+	if !strings.Contains(failed["min_fuzz_time_seconds"], `fuzz-target "failing"`) {
+		t.Errorf("unexpected errors of min_fuzz_time_seconds: %s", failed["min_fuzz_time_seconds"])
+	}
+	if !strings.Contains(failed["no_crashes"], `fuzz-target "failing" detected crashes`) {
+		t.Errorf("unexpected errors of no_crashes: %s", failed["no_crashes"])
+	}
+}
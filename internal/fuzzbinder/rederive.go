@@ -0,0 +1,218 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fuzzbinder
+
+// This file provides the re-derivation of fuzzing claims from the fuzzing
+// reports of OSS-Fuzz, so that third parties can audit that a fuzzing claim
+// is faithful to its evidence.
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/google/go-cmp/cmp"
+	"go.uber.org/multierr"
+
+	"github.com/project-oak/transparent-release/internal/fetch"
+	"github.com/project-oak/transparent-release/internal/gcsutil"
+	"github.com/project-oak/transparent-release/pkg/intoto"
+)
+
+// srcmapURIPattern matches the GCS, S3, or local URI of the srcmap evidence of
+// a fuzzing claim, and captures the name of the project and the fuzzing date.
+//
+//nolint:gochecknoglobals
+var srcmapURIPattern = regexp.MustCompile(`^(?:gs://|s3://|file://.*/)` + CoverageBucket + `/([^/]+)/srcmap/(\d{8})\.json$`)
+
+// FuzzParametersFromClaim infers the fuzzing parameters with which the given
+// fuzzing claim was generated from its subject and its srcmap evidence. The
+// project name and the fuzzing date can only be inferred if the srcmap
+// evidence is referenced by its URI in a blob store, and not, e.g., by its
+// Ent URI.
+func FuzzParametersFromClaim(statement *intoto.Statement) (*FuzzParameters, error) {
+	predicate, _, err := fuzzClaimSpec(statement)
+	if err != nil {
+		return nil, err
+	}
+	if len(statement.Subject) != 1 {
+		return nil, fmt.Errorf("the fuzzing claim must have exactly one subject; got %d", len(statement.Subject))
+	}
+	for _, evidence := range predicate.Evidence {
+		if evidence.Role != "srcmap" {
+			continue
+		}
+		match := srcmapURIPattern.FindStringSubmatch(evidence.URI)
+		if match == nil {
+			return nil, fmt.Errorf("could not infer the project name and the fuzzing date from the srcmap evidence %q", evidence.URI)
+		}
+		return &FuzzParameters{
+			ProjectName:    match[1],
+			ProjectGitRepo: statement.Subject[0].Name,
+			Date:           match[2],
+		}, nil
+	}
+	return nil, fmt.Errorf("the fuzzing claim has no srcmap evidence")
+}
+
+// RederiveFuzzClaim re-fetches the fuzzing reports of OSS-Fuzz and
+// ClusterFuzz with the given fuzzing parameters, as returned by
+// FuzzParametersFromClaim, recomputes the revision, the statistics, the crash
+// reports, and the evidence digests of the given fuzzing claim, and checks
+// that they match the claim. The evidence in the claim is fetched from its
+// URIs, e.g., from Ent, and checked against its digests. The statistics of
+// every fuzz-target are re-derived, so fuzz-targets whose statistics are
+// unknown in the claim must fail re-derivation too. The issue IDs of crash
+// reports are not checked. Returns all discrepancies, or nil if the claim is
+// faithful to the evidence.
+func RederiveFuzzClaim(client *gcsutil.Client, statement *intoto.Statement, fuzzParameters *FuzzParameters) error {
+	predicate, spec, err := fuzzClaimSpec(statement)
+	if err != nil {
+		return err
+	}
+	if len(statement.Subject) != 1 {
+		return fmt.Errorf("the fuzzing claim must have exactly one subject; got %d", len(statement.Subject))
+	}
+	subject := statement.Subject[0]
+	// The revision is looked up in the srcmap by the repository of the subject.
+	parameters := *fuzzParameters
+	parameters.ProjectGitRepo = subject.Name
+
+	revisionDigest, err := GetCoverageRevision(client, &parameters)
+	if err != nil {
+		return fmt.Errorf("could not re-derive the revision: %v", err)
+	}
+	var errs error
+	if diff := cmp.Diff(subject.Digest, revisionDigest); diff != "" {
+		errs = multierr.Append(errs, fmt.Errorf("the digest of the subject does not match the srcmap (-claimed +rederived):\n%s", diff))
+	}
+
+	fuzzTargets, err := GetFuzzTargets(client, &parameters)
+	if err != nil {
+		return multierr.Append(errs, fmt.Errorf("could not re-derive the fuzz-targets: %v", err))
+	}
+	errs = multierr.Append(errs, compareFuzzTargets(spec, fuzzTargets))
+
+	// The statistics are re-derived for the subject of the claim, so that
+	// the logs of other revisions are ignored as when generating the claim.
+	// Fuzz-targets whose statistics cannot be re-derived are recorded as
+	// unknown, and checked against the claim.
+	rederived, err := generateFuzzClaimSpec(client, subject.Digest, &parameters, fuzzTargets, false)
+	if err != nil {
+		return multierr.Append(errs, fmt.Errorf("could not re-derive the fuzzing statistics: %v", err))
+	}
+	errs = multierr.Append(errs, compareFuzzClaimSpecs(spec, rederived))
+
+	// The evidence is re-derived without Ent, so it has GCS URIs.
+	wantEvidence, err := GetEvidences(client, nil, &parameters, rederived.knownFuzzTargets())
+	if err != nil {
+		return multierr.Append(errs, fmt.Errorf("could not re-derive the evidence: %v", err))
+	}
+	if len(predicate.Evidence) != len(wantEvidence) {
+		return multierr.Append(errs, fmt.Errorf("the fuzzing claim has %d evidence files, want %d", len(predicate.Evidence), len(wantEvidence)))
+	}
+	registry := fetch.NewRegistry(fetch.WithFetcher("gs", client))
+	for i, evidence := range predicate.Evidence {
+		want := wantEvidence[i]
+		if evidence.Role != want.Role || evidence.Digest["sha256"] != want.Digest["sha256"] {
+			errs = multierr.Append(errs, fmt.Errorf("the evidence %s (%s, sha256:%s) does not match %s (%s, sha256:%s)",
+				evidence.URI, evidence.Role, evidence.Digest["sha256"], want.URI, want.Role, want.Digest["sha256"]))
+			continue
+		}
+		if evidence.URI == want.URI {
+			continue
+		}
+		// The evidence was copied, e.g., to Ent; check the copy too.
+		if _, err := registry.Fetch(context.Background(), evidence.URI, fetch.WithExpectedSHA256Digest(want.Digest["sha256"])); err != nil {
+			errs = multierr.Append(errs, fmt.Errorf("could not verify the evidence %s: %v", evidence.URI, err))
+		}
+	}
+	return errs
+}
+
+// compareFuzzTargets checks that the given ClaimSpec lists exactly the given
+// fuzz-targets.
+func compareFuzzTargets(spec *FuzzClaimSpec, fuzzTargets []string) error {
+	claimed := make([]string, 0, len(spec.PerTarget))
+	for _, target := range spec.PerTarget {
+		claimed = append(claimed, target.Name)
+	}
+	rederived := append([]string(nil), fuzzTargets...)
+	sort.Strings(claimed)
+	sort.Strings(rederived)
+	if diff := cmp.Diff(claimed, rederived); diff != "" {
+		return fmt.Errorf("the fuzz-targets do not match the coverage reports (-claimed +rederived):\n%s", diff)
+	}
+	return nil
+}
+
+// compareFuzzClaimSpecs checks that the statistics and crash reports of the
+// claimed ClaimSpec match the re-derived ClaimSpec. Fuzz-targets must have
+// unknown statistics in both, or in neither; the reasons for unknown
+// statistics and source digests, and the issue IDs of crash reports, are
+// ignored.
+func compareFuzzClaimSpecs(claimed, rederived *FuzzClaimSpec) error {
+	var errs error
+	if diff := cmp.Diff(claimed.PerProject, rederived.PerProject); diff != "" {
+		errs = multierr.Append(errs, fmt.Errorf("the statistics of the project do not match the evidence (-claimed +rederived):\n%s", diff))
+	}
+
+	rederivedTargets := make(map[string]FuzzSpecPerTarget, len(rederived.PerTarget))
+	for _, target := range rederived.PerTarget {
+		rederivedTargets[target.Name] = target
+	}
+	for _, target := range claimed.PerTarget {
+		rederivedTarget, ok := rederivedTargets[target.Name]
+		if !ok {
+			errs = multierr.Append(errs, fmt.Errorf("could not re-derive the statistics of fuzz-target %q", target.Name))
+			continue
+		}
+		switch {
+		case target.FuzzStats == nil && rederivedTarget.FuzzStats == nil:
+			continue
+		case target.FuzzStats == nil:
+			errs = multierr.Append(errs, fmt.Errorf("the statistics of fuzz-target %q are claimed unknown, but could be re-derived", target.Name))
+			continue
+		case rederivedTarget.FuzzStats == nil:
+			errs = multierr.Append(errs, fmt.Errorf("could not re-derive the statistics of fuzz-target %q: %s", target.Name, rederivedTarget.UnknownReason))
+			continue
+		}
+		// Claims generated before the statistics were split per combination
+		// of fuzzing engine and sanitizer, or before source digests were
+		// recorded, have none.
+		if len(target.PerConfiguration) == 0 {
+			rederivedTarget.PerConfiguration = nil
+		}
+		if len(target.SourceDigest) == 0 {
+			rederivedTarget.SourceDigest = nil
+		}
+		rederivedTarget.SourceDigestUnknownReason = target.SourceDigestUnknownReason
+		if diff := cmp.Diff(target, rederivedTarget); diff != "" {
+			errs = multierr.Append(errs, fmt.Errorf("the statistics of fuzz-target %q do not match the evidence (-claimed +rederived):\n%s", target.Name, diff))
+		}
+	}
+
+	claimedReports := make([]CrashReport, 0, len(claimed.CrashReports))
+	for _, report := range claimed.CrashReports {
+		report.IssueID = ""
+		claimedReports = append(claimedReports, report)
+	}
+	rederivedReports := append([]CrashReport{}, rederived.CrashReports...)
+	if diff := cmp.Diff(claimedReports, rederivedReports); diff != "" {
+		errs = multierr.Append(errs, fmt.Errorf("the crash reports do not match the evidence (-claimed +rederived):\n%s", diff))
+	}
+	return errs
+}
@@ -0,0 +1,127 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fuzzbinder
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/project-oak/transparent-release/internal/testutil"
+	"github.com/project-oak/transparent-release/pkg/claims"
+)
+
+func TestFuzzParametersFromClaim(t *testing.T) {
+	statement, err := ParseFuzzClaimFile(filepath.Join(testdataPath, fuzzclaimExamplePath))
+	if err != nil {
+		t.Fatalf("could not parse the fuzzing claim: %v", err)
+	}
+	got, err := FuzzParametersFromClaim(statement)
+	if err != nil {
+		t.Fatalf("could not infer the fuzzing parameters: %v", err)
+	}
+	want := FuzzParameters{
+		ProjectName:    "oak",
+		ProjectGitRepo: "https://github.com/project-oak/oak",
+		Date:           "20221205",
+	}
+	testutil.AssertEq(t, "fuzzing parameters", *got, want)
+
+	// The evidence may be in a mirror of the buckets.
+	predicate := statement.Predicate.(*claims.ClaimPredicate)
+	predicate.Evidence[0].URI = "s3://oss-fuzz-coverage/oak/srcmap/20221205.json"
+	got, err = FuzzParametersFromClaim(statement)
+	if err != nil {
+		t.Fatalf("could not infer the fuzzing parameters from S3: %v", err)
+	}
+	testutil.AssertEq(t, "fuzzing parameters from S3", *got, want)
+
+	// The parameters cannot be inferred from evidence copied to Ent.
+	predicate.Evidence[0].URI = "ent:sha256:" + predicate.Evidence[0].Digest["sha256"]
+	if _, err := FuzzParametersFromClaim(statement); err == nil {
+		t.Errorf("expected an error for the srcmap evidence in Ent")
+	}
+}
+
+func TestCompareFuzzClaimSpecs(t *testing.T) {
+	statement, err := ParseFuzzClaimFile(filepath.Join(testdataPath, fuzzclaimExamplePath))
+	if err != nil {
+		t.Fatalf("could not parse the fuzzing claim: %v", err)
+	}
+	_, claimed, err := fuzzClaimSpec(statement)
+	if err != nil {
+		t.Fatalf("could not get the ClaimSpec: %v", err)
+	}
+	claimed.CrashReports = []CrashReport{{FuzzTarget: "failing", CrashType: "heap-buffer-overflow", IssueID: "1234"}}
+
+	// Deep-copy the statistics, so that changes to the copy do not change
+	// the claim.
+	rederived := &FuzzClaimSpec{PerProject: &FuzzStats{}}
+	*rederived.PerProject = *claimed.PerProject
+	for _, target := range claimed.PerTarget {
+		stats := *target.FuzzStats
+		target.FuzzStats = &stats
+		rederived.PerTarget = append(rederived.PerTarget, target)
+	}
+	rederived.CrashReports = []CrashReport{{FuzzTarget: "failing", CrashType: "heap-buffer-overflow"}}
+
+	// Issue IDs are not re-derived.
+	if err := compareFuzzClaimSpecs(claimed, rederived); err != nil {
+		t.Errorf("unexpected discrepancies: %v", err)
+	}
+
+	rederived.PerTarget[1].FuzzStats.NumberFuzzTests++
+	err = compareFuzzClaimSpecs(claimed, rederived)
+	if err == nil || !strings.Contains(err.Error(), `fuzz-target "failing"`) {
+		t.Errorf("expected a discrepancy of fuzz-target \"failing\"; got: %v", err)
+	}
+	rederived.PerTarget[1].FuzzStats.NumberFuzzTests--
+
+	// The reasons for unknown source digests are ignored.
+	claimed.PerTarget[1].SourceDigestUnknownReason = "404 Not Found"
+	rederived.PerTarget[1].SourceDigestUnknownReason = "connection refused"
+	if err := compareFuzzClaimSpecs(claimed, rederived); err != nil {
+		t.Errorf("unexpected discrepancies with unknown source digests: %v", err)
+	}
+
+	// Unknown statistics are only accepted if they cannot be re-derived.
+	claimedStats := claimed.PerTarget[0].FuzzStats
+	claimed.PerTarget[0].FuzzStats = nil
+	claimed.PerTarget[0].UnknownReason = "no logs"
+	err = compareFuzzClaimSpecs(claimed, rederived)
+	if err == nil || !strings.Contains(err.Error(), "claimed unknown") {
+		t.Errorf("expected a discrepancy about the unknown statistics; got: %v", err)
+	}
+	rederived.PerTarget[0].FuzzStats = nil
+	rederived.PerTarget[0].UnknownReason = "no coverage report"
+	if err := compareFuzzClaimSpecs(claimed, rederived); err != nil {
+		t.Errorf("unexpected discrepancies with unknown statistics: %v", err)
+	}
+	claimed.PerTarget[0].FuzzStats = claimedStats
+	err = compareFuzzClaimSpecs(claimed, rederived)
+	if err == nil || !strings.Contains(err.Error(), "no coverage report") {
+		t.Errorf("expected a discrepancy with the reason of the failed re-derivation; got: %v", err)
+	}
+}
+
+func TestCompareFuzzTargets(t *testing.T) {
+	spec := &FuzzClaimSpec{PerTarget: []FuzzSpecPerTarget{{Name: "b"}, {Name: "a"}}}
+	if err := compareFuzzTargets(spec, []string{"a", "b"}); err != nil {
+		t.Errorf("unexpected discrepancies: %v", err)
+	}
+	if err := compareFuzzTargets(spec, []string{"a", "b", "c"}); err == nil {
+		t.Errorf("expected a discrepancy for the missing fuzz-target")
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fuzzbinder
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"testing"
+	"testing/quick"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/project-oak/transparent-release/pkg/claims"
+	"github.com/project-oak/transparent-release/pkg/intoto"
+)
+
+// TestFuzzClaimRoundTrip checks that generated fuzzing claims are accepted by
+// parseFuzzClaimBytes, survive the round trip unchanged, and yield the same
+// per-target claims as before the round trip.
+func TestFuzzClaimRoundTrip(t *testing.T) {
+	property := func(seed int64) bool {
+		r := rand.New(rand.NewSource(seed)) //nolint:gosec
+		statement, err := randomFuzzClaim(r)
+		if err != nil {
+			t.Logf("Failed to generate the fuzzing claim: %v", err)
+			return false
+		}
+		statementBytes, err := json.Marshal(statement)
+		if err != nil {
+			t.Logf("Failed to marshal the fuzzing claim: %v", err)
+			return false
+		}
+		parsed, err := parseFuzzClaimBytes(statementBytes)
+		if err != nil {
+			t.Logf("Failed to parse the generated fuzzing claim %s: %v", statementBytes, err)
+			return false
+		}
+		if diff := cmp.Diff(statement, parsed, cmpopts.EquateEmpty()); diff != "" {
+			t.Logf("The parsed fuzzing claim differs from the generated one (-want +got):\n%s", diff)
+			return false
+		}
+
+		want, err := GenerateFuzzTargetClaims(statement)
+		if err != nil {
+			t.Logf("Failed to generate the per-target claims: %v", err)
+			return false
+		}
+		got, err := GenerateFuzzTargetClaims(parsed)
+		if err != nil {
+			t.Logf("Failed to generate the per-target claims of the parsed claim: %v", err)
+			return false
+		}
+		if diff := cmp.Diff(want, got, cmpopts.EquateEmpty()); diff != "" {
+			t.Logf("The per-target claims differ (-want +got):\n%s", diff)
+			return false
+		}
+		return true
+	}
+	if err := quick.Check(property, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+// randomFuzzClaim returns a fuzzing claim about a random revision, with
+// random, but consistent, per-target and per-project statistics.
+func randomFuzzClaim(r *rand.Rand) (*intoto.Statement, error) {
+	revision := make([]byte, 20)
+	_, _ = r.Read(revision)
+	revisionDigest := intoto.DigestSet{"sha1": hex.EncodeToString(revision)}
+
+	spec := &FuzzClaimSpec{PerProject: &FuzzStats{}}
+	evidence := []claims.ClaimEvidence{{
+		Role:   "srcmap",
+		URI:    "gs://oss-fuzz-coverage/oak/srcmap.json",
+		Digest: intoto.DigestSet{"sha256": randomDigest(r)},
+	}}
+	for i := r.Intn(5); i >= 0; i-- {
+		stats := &FuzzStats{
+			LineCoverage:    fmt.Sprintf("%.2f%% (%d/%d)", r.Float64()*100, r.Intn(1000), r.Intn(1000)),
+			BranchCoverage:  fmt.Sprintf("%.2f%% (%d/%d)", r.Float64()*100, r.Intn(1000), r.Intn(1000)),
+			DetectedCrashes: r.Intn(2) == 0,
+			FuzzTimeSeconds: r.Float64() * 1000,
+			NumberFuzzTests: r.Intn(1000),
+		}
+		name := fmt.Sprintf("target_%d", i)
+		spec.PerTarget = append(spec.PerTarget, FuzzSpecPerTarget{Name: name, Path: "fuzz/" + name + ".rs", FuzzStats: stats})
+		spec.PerProject.DetectedCrashes = spec.PerProject.DetectedCrashes || stats.DetectedCrashes
+		spec.PerProject.FuzzTimeSeconds += stats.FuzzTimeSeconds
+		spec.PerProject.NumberFuzzTests += stats.NumberFuzzTests
+		evidence = append(evidence, claims.ClaimEvidence{
+			Role:   "fuzzTarget coverage",
+			URI:    "gs://oss-fuzz-coverage/oak/fuzzer_stats/20221205/" + name + ".json",
+			Digest: intoto.DigestSet{"sha256": randomDigest(r)},
+		})
+	}
+
+	if r.Intn(2) == 0 {
+		spec.PerTarget = append(spec.PerTarget, FuzzSpecPerTarget{Name: "unknown", UnknownReason: "could not get unknown coverage"})
+	}
+
+	issuedOn := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(r.Int63n(int64(365 * 24 * time.Hour))))
+	notBefore := issuedOn.Add(time.Hour)
+	notAfter := notBefore.AddDate(0, 0, 1+r.Intn(90))
+	validity := claims.ClaimValidity{NotBefore: &notBefore, NotAfter: &notAfter}
+	return newFuzzClaim("https://github.com/project-oak/oak", revisionDigest, spec, evidence, validity, claims.FixedClock(issuedOn))
+}
+
+func randomDigest(r *rand.Rand) string {
+	digest := make([]byte, 32)
+	_, _ = r.Read(digest)
+	return hex.EncodeToString(digest)
+}
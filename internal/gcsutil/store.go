@@ -0,0 +1,64 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gcsutil
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/iterator"
+
+	"github.com/project-oak/transparent-release/internal/blobstore"
+)
+
+// defaultGCSPageSize is the number of entries per page listed by gcsStore if
+// none is given, which is the maximum of the Google Cloud Storage API.
+const defaultGCSPageSize = 1000
+
+// gcsStore is a blobstore.BlobStore in Google Cloud Storage.
+type gcsStore struct {
+	storageClient *storage.Client
+}
+
+// ListPage implements blobstore.BlobStore.
+func (s *gcsStore) ListPage(ctx context.Context, bucketName string, query blobstore.Query, pageSize int, pageToken string) ([]blobstore.Entry, string, error) {
+	if pageSize <= 0 {
+		pageSize = defaultGCSPageSize
+	}
+	storageQuery := &storage.Query{Prefix: query.Prefix, Delimiter: query.Delimiter}
+	objects := s.storageClient.Bucket(bucketName).Objects(ctx, storageQuery)
+	var page []*storage.ObjectAttrs
+	nextPageToken, err := iterator.NewPager(objects, pageSize, pageToken).NextPage(&page)
+	if err != nil {
+		return nil, "", err
+	}
+	entries := make([]blobstore.Entry, 0, len(page))
+	for _, attrs := range page {
+		entries = append(entries, blobstore.Entry{Name: attrs.Name, Prefix: attrs.Prefix})
+	}
+	return entries, nextPageToken, nil
+}
+
+// NewReader implements blobstore.BlobStore.
+func (s *gcsStore) NewReader(ctx context.Context, bucketName string, blobPath string) (io.ReadCloser, error) {
+	return s.storageClient.Bucket(bucketName).Object(blobPath).NewReader(ctx)
+}
+
+// URI implements blobstore.BlobStore.
+func (s *gcsStore) URI(bucketName string, blobPath string) string {
+	return fmt.Sprintf("gs://%s/%s", bucketName, blobPath)
+}
@@ -13,18 +13,28 @@
 // limitations under the License.
 
 // Package gcsutil contains utility functions for Google Cloud Storage data access.
+// The data can also be read from other blob stores, see WithStore.
 package gcsutil
 
 import (
 	"context"
 	"fmt"
 	"io"
+	"net/url"
 	"strings"
 
 	"cloud.google.com/go/storage"
+	"go.uber.org/multierr"
 	"google.golang.org/api/iterator"
+
+	"github.com/project-oak/transparent-release/internal/blobstore"
+	"github.com/project-oak/transparent-release/internal/parallel"
 )
 
+// DefaultBlobWorkers is the default maximum number of blobs that ScanBlobs
+// and GetLogsData read concurrently.
+const DefaultBlobWorkers = 8
+
 // ContextInStruct contains contexts that can be used in
 // structures when there is no risk of confusion. Using
 // context.Context directly can lead to linting errors.
@@ -41,62 +51,190 @@ type ContextInStruct context.Context
 // Note that, if the use of context is extended in the future, then it should be
 // passed explicitly to each function that needs it as an argument.
 //
-// Client contains a Google Cloud Storage client and a context.Context.
+// Client contains a blob store, Google Cloud Storage by default, and a
+// context.Context.
 type Client struct {
-	storageClient *storage.Client
-	context       ContextInStruct
+	store       blobstore.BlobStore
+	context     ContextInStruct
+	blobWorkers int
+}
+
+// ClientConfig holds optional settings for a Client.
+type ClientConfig struct {
+	store       blobstore.BlobStore
+	blobWorkers int
+}
+
+// WithStore makes the client read the blobs from the given store, e.g., an
+// S3Store or a DirStore, with the same bucket names and blob paths, instead of
+// from Google Cloud Storage.
+func WithStore(store blobstore.BlobStore) func(config *ClientConfig) {
+	return func(config *ClientConfig) {
+		config.store = store
+	}
+}
+
+// WithBlobWorkers overrides DefaultBlobWorkers.
+func WithBlobWorkers(blobWorkers int) func(config *ClientConfig) {
+	return func(config *ClientConfig) {
+		config.blobWorkers = blobWorkers
+	}
 }
 
 // NewClientWithContext creates and returns a new Client.
 // The given ctx is used for the lifetime of the Client!
-func NewClientWithContext(ctx context.Context) (*Client, error) {
-	storageClient, err := storage.NewClient(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("could not create a new Google Cloud Storage client: %v", err)
+func NewClientWithContext(ctx context.Context, options ...func(config *ClientConfig)) (*Client, error) {
+	config := &ClientConfig{blobWorkers: DefaultBlobWorkers}
+	for _, addOption := range options {
+		addOption(config)
+	}
+	if config.store == nil {
+		storageClient, err := storage.NewClient(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("could not create a new Google Cloud Storage client: %v", err)
+		}
+		defer storageClient.Close()
+		config.store = &gcsStore{storageClient: storageClient}
 	}
-	defer storageClient.Close()
 	client := Client{
-		storageClient: storageClient,
-		context:       ctx,
+		store:       config.store,
+		context:     ctx,
+		blobWorkers: config.blobWorkers,
 	}
 	return &client, nil
 }
 
+// Done is returned by BlobIterator.Next when there are no more entries.
+//
+//nolint:gochecknoglobals
+var Done = iterator.Done
+
+// ListQuery selects the entries listed by Objects and ListPage.
+type ListQuery = blobstore.Query
+
+// BlobEntry is an entry of a listing of a bucket. Exactly one of Name and
+// Prefix is set.
+type BlobEntry = blobstore.Entry
+
+// BlobIterator iterates over the entries of a listing of a bucket, fetching
+// them page by page, so that the listing need not fit in memory.
+type BlobIterator struct {
+	ctx        ContextInStruct
+	store      blobstore.BlobStore
+	bucketName string
+	query      ListQuery
+	page       []BlobEntry
+	pageToken  string
+	lastPage   bool
+}
+
+// Next returns the next entry, or Done if there are no more entries. The
+// iteration stops with the error of the context if it is canceled.
+func (it *BlobIterator) Next() (*BlobEntry, error) {
+	for len(it.page) == 0 {
+		if it.lastPage {
+			return nil, Done
+		}
+		if err := it.ctx.Err(); err != nil {
+			return nil, err
+		}
+		page, nextPageToken, err := it.store.ListPage(it.ctx, it.bucketName, it.query, 0, it.pageToken)
+		if err != nil {
+			return nil, err
+		}
+		it.page = page
+		it.pageToken = nextPageToken
+		it.lastPage = nextPageToken == ""
+	}
+	entry := it.page[0]
+	it.page = it.page[1:]
+	return &entry, nil
+}
+
+// Objects returns an iterator over the entries in a bucket that match the
+// given query.
+func (c *Client) Objects(ctx context.Context, bucketName string, query ListQuery) *BlobIterator {
+	return &BlobIterator{ctx: ctx, store: c.store, bucketName: bucketName, query: query}
+}
+
+// ListPage lists a page of at most pageSize entries in a bucket that match
+// the given query, starting at the given page token, or at the first entry if
+// the token is empty. Returns the entries, and the token of the next page,
+// which is empty after the last page, so that a listing can be resumed, e.g.,
+// after a failure.
+func (c *Client) ListPage(ctx context.Context, bucketName string, query ListQuery, pageSize int, pageToken string) ([]BlobEntry, string, error) {
+	entries, nextPageToken, err := c.store.ListPage(ctx, bucketName, query, pageSize, pageToken)
+	if err != nil {
+		return nil, "", fmt.Errorf("could not list a page of objects in %q: %v", bucketName, err)
+	}
+	return entries, nextPageToken, nil
+}
+
+// URI returns the URI of the blob at the given path in the given bucket in the
+// store of the client, e.g., `gs://bucket/path` for Google Cloud Storage.
+func (c *Client) URI(bucketName string, blobPath string) string {
+	return c.store.URI(bucketName, blobPath)
+}
+
 // ListBlobPaths returns all the objects paths in a Google Cloud Storage bucket
 // under a given relative path.
 func (c *Client) ListBlobPaths(bucketName string, relativePath string) ([]string, error) {
-	query := &storage.Query{Prefix: relativePath}
-	objects := c.storageClient.Bucket(bucketName).Objects(c.context, query)
+	objects := c.Objects(c.context, bucketName, ListQuery{Prefix: relativePath})
 	var blobPaths []string
 	for {
-		attrs, err := objects.Next()
-		if err == iterator.Done {
+		entry, err := objects.Next()
+		if err == Done {
 			break
 		}
 		if err != nil {
 			return nil, fmt.Errorf("could not fetch object from %q: %v", bucketName, err)
 		}
-		blobPaths = append(blobPaths, attrs.Name)
+		blobPaths = append(blobPaths, entry.Name)
 	}
 	return blobPaths, nil
 }
 
+// ListPrefixes returns the prefixes of the objects in a Google Cloud Storage
+// bucket under a given relative path, up to the next `/`, like the
+// subdirectories of a directory.
+func (c *Client) ListPrefixes(bucketName string, relativePath string) ([]string, error) {
+	objects := c.Objects(c.context, bucketName, ListQuery{Prefix: relativePath, Delimiter: "/"})
+	var prefixes []string
+	for {
+		entry, err := objects.Next()
+		if err == Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not fetch object from %q: %v", bucketName, err)
+		}
+		if entry.Prefix != "" {
+			prefixes = append(prefixes, entry.Prefix)
+		}
+	}
+	return prefixes, nil
+}
+
+// IsLogFile returns whether the object at the given path is a log-file.
+func IsLogFile(blobPath string) bool {
+	return strings.Contains(blobPath, ".log")
+}
+
 // ListLogFilePaths returns all the log-files paths in a Google Cloud Storage bucket
 // under a given relative path.
 func (c *Client) ListLogFilePaths(bucketName string, relativePath string) ([]string, error) {
-	query := &storage.Query{Prefix: relativePath}
-	objects := c.storageClient.Bucket(bucketName).Objects(c.context, query)
+	objects := c.Objects(c.context, bucketName, ListQuery{Prefix: relativePath})
 	var logFilePaths []string
 	for {
-		attrs, err := objects.Next()
-		if err == iterator.Done {
+		entry, err := objects.Next()
+		if err == Done {
 			break
 		}
 		if err != nil {
 			return nil, fmt.Errorf("could not fetch object from %q: %v", bucketName, err)
 		}
-		if strings.Contains(attrs.Name, ".log") {
-			logFilePaths = append(logFilePaths, attrs.Name)
+		if IsLogFile(entry.Name) {
+			logFilePaths = append(logFilePaths, entry.Name)
 		}
 	}
 	if len(logFilePaths) == 0 {
@@ -105,9 +243,49 @@ func (c *Client) ListLogFilePaths(bucketName string, relativePath string) ([]str
 	return logFilePaths, nil
 }
 
+// DefaultListPageSize is the default number of entries per page listed by
+// ForEachLogFilePage.
+const DefaultListPageSize = 1000
+
+// ForEachLogFilePage lists the log-files in a Google Cloud Storage bucket
+// under a given relative path page by page, with at most pageSize entries per
+// page, and calls visit with the log-files paths in each page, so that
+// directories with many log-files can be processed without listing them all
+// first. Returns an error if there are no log-files, or if visit fails.
+func (c *Client) ForEachLogFilePage(bucketName string, relativePath string, pageSize int, visit func(logFilePaths []string) error) error {
+	found := false
+	pageToken := ""
+	for {
+		entries, nextPageToken, err := c.ListPage(c.context, bucketName, ListQuery{Prefix: relativePath}, pageSize, pageToken)
+		if err != nil {
+			return err
+		}
+		logFilePaths := make([]string, 0, len(entries))
+		for _, entry := range entries {
+			if IsLogFile(entry.Name) {
+				logFilePaths = append(logFilePaths, entry.Name)
+			}
+		}
+		if len(logFilePaths) > 0 {
+			found = true
+			if err := visit(logFilePaths); err != nil {
+				return err
+			}
+		}
+		if nextPageToken == "" {
+			break
+		}
+		pageToken = nextPageToken
+	}
+	if !found {
+		return fmt.Errorf("could not find log files in %q under %q", bucketName, relativePath)
+	}
+	return nil
+}
+
 // GetBlobData gets the data in a blob in a Google Cloud Storage bucket.
 func (c *Client) GetBlobData(bucketName string, blobPath string) ([]byte, error) {
-	reader, err := c.storageClient.Bucket(bucketName).Object(blobPath).NewReader(c.context)
+	reader, err := c.store.NewReader(c.context, bucketName, blobPath)
 	if err != nil {
 		return nil, fmt.Errorf("could not create a new reader for blob %q: %v", blobPath, err)
 	}
@@ -120,19 +298,77 @@ func (c *Client) GetBlobData(bucketName string, blobPath string) ([]byte, error)
 	return fileBytes, nil
 }
 
+// ParseURI returns the bucket name and the blob path in the given
+// `gs://bucket/blob` URI.
+func ParseURI(uri *url.URL) (string, string, error) {
+	blobPath := strings.TrimPrefix(uri.Path, "/")
+	if uri.Scheme != "gs" || uri.Host == "" || blobPath == "" {
+		return "", "", fmt.Errorf("invalid Google Cloud Storage URI %q, want gs://bucket/blob", uri)
+	}
+	return uri.Host, blobPath, nil
+}
+
+// Fetch returns a reader for the blob at the given `gs://bucket/blob` URI,
+// read from the store of the client.
+// The caller closes the reader.
+func (c *Client) Fetch(ctx context.Context, uri *url.URL) (io.ReadCloser, error) {
+	bucketName, blobPath, err := ParseURI(uri)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := c.store.NewReader(ctx, bucketName, blobPath)
+	if err != nil {
+		return nil, fmt.Errorf("could not create a new reader for blob %q: %v", blobPath, err)
+	}
+	return reader, nil
+}
+
+// ReadBlob returns a reader for a blob in a Google Cloud Storage bucket, so
+// that large blobs can be processed without reading them into memory. The
+// caller closes the reader.
+func (c *Client) ReadBlob(bucketName string, blobPath string) (io.ReadCloser, error) {
+	reader, err := c.store.NewReader(c.context, bucketName, blobPath)
+	if err != nil {
+		return nil, fmt.Errorf("could not create a new reader for blob %q: %v", blobPath, err)
+	}
+	return reader, nil
+}
+
+// ScanBlobs reads the given blobs in a Google Cloud Storage bucket
+// concurrently, with at most the number of workers of the client, and calls
+// scan with the index of each blob in blobPaths and a reader for its content.
+// scan may be called concurrently. Returns the errors of all blobs that could
+// not be read or scanned.
+func (c *Client) ScanBlobs(bucketName string, blobPaths []string, scan func(index int, reader io.Reader) error) error {
+	errs := parallel.Do(len(blobPaths), c.blobWorkers, func(i int) error {
+		reader, err := c.ReadBlob(bucketName, blobPaths[i])
+		if err != nil {
+			return err
+		}
+		defer reader.Close()
+		if err := scan(i, reader); err != nil {
+			return fmt.Errorf("could not scan blob %q: %v", blobPaths[i], err)
+		}
+		return nil
+	})
+	return multierr.Combine(errs...)
+}
+
 // GetLogsData gets the data in log-files in a Google Cloud Storage bucket under a relative path.
+// The log-files are read concurrently, see ScanBlobs.
 func (c *Client) GetLogsData(bucketName string, relativePath string) ([][]byte, error) {
 	logFilesPaths, err := c.ListLogFilePaths(bucketName, relativePath)
 	if err != nil {
 		return nil, fmt.Errorf("could not get log files paths: %v", err)
 	}
-	logFilesBytes := make([][]byte, 0, len(logFilesPaths))
-	for _, logFilePath := range logFilesPaths {
-		fileBytes, err := c.GetBlobData(bucketName, logFilePath)
-		if err != nil {
-			return nil, fmt.Errorf("could not get data from log file: %v", err)
-		}
-		logFilesBytes = append(logFilesBytes, fileBytes)
+	logFilesBytes := make([][]byte, len(logFilesPaths))
+	err = c.ScanBlobs(bucketName, logFilesPaths, func(i int, reader io.Reader) error {
+		fileBytes, err := io.ReadAll(reader)
+		logFilesBytes[i] = fileBytes
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not get data from log files: %v", err)
 	}
 	return logFilesBytes, nil
 }
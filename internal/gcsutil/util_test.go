@@ -0,0 +1,209 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gcsutil
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/storage"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/api/option"
+
+	"github.com/project-oak/transparent-release/internal/blobstore"
+	"github.com/project-oak/transparent-release/internal/testutil"
+)
+
+// newFakeClient returns a Client for a fake Google Cloud Storage JSON API
+// serving the listing of a bucket with the given object paths. Page tokens
+// are the offsets of the pages in the listing.
+func newFakeClient(t *testing.T, ctx context.Context, blobPaths []string) *Client {
+	sort.Strings(blobPaths)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/storage/v1/b/bucket/o" {
+			http.NotFound(w, r)
+			return
+		}
+		query := r.URL.Query()
+		prefix, delimiter := query.Get("prefix"), query.Get("delimiter")
+		var items []map[string]string
+		prefixes := []string{}
+		seenPrefixes := map[string]bool{}
+		for _, blobPath := range blobPaths {
+			if !strings.HasPrefix(blobPath, prefix) {
+				continue
+			}
+			rest := strings.TrimPrefix(blobPath, prefix)
+			if i := strings.Index(rest, delimiter); delimiter != "" && i >= 0 {
+				p := prefix + rest[:i+len(delimiter)]
+				if !seenPrefixes[p] {
+					seenPrefixes[p] = true
+					prefixes = append(prefixes, p)
+				}
+				continue
+			}
+			items = append(items, map[string]string{"name": blobPath, "bucket": "bucket"})
+		}
+		offset, _ := strconv.Atoi(query.Get("pageToken"))
+		pageSize, err := strconv.Atoi(query.Get("maxResults"))
+		if err != nil || pageSize == 0 {
+			pageSize = len(items) + 1
+		}
+		response := map[string]interface{}{"kind": "storage#objects", "prefixes": prefixes}
+		end := offset + pageSize
+		if end < len(items) {
+			response["nextPageToken"] = fmt.Sprint(end)
+		} else {
+			end = len(items)
+		}
+		if offset < end {
+			response["items"] = items[offset:end]
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(response)
+	}))
+	t.Cleanup(server.Close)
+
+	storageClient, err := storage.NewClient(ctx, option.WithEndpoint(server.URL+"/storage/v1/"), option.WithoutAuthentication())
+	if err != nil {
+		t.Fatalf("could not create the storage client: %v", err)
+	}
+	return &Client{store: &gcsStore{storageClient: storageClient}, context: ctx, blobWorkers: DefaultBlobWorkers}
+}
+
+func TestListPage(t *testing.T) {
+	ctx := context.Background()
+	client := newFakeClient(t, ctx, []string{"logs/a.log", "logs/b.log", "logs/c.log", "other/d.log"})
+
+	var got []string
+	var pages int
+	pageToken := ""
+	for {
+		entries, nextPageToken, err := client.ListPage(ctx, "bucket", ListQuery{Prefix: "logs/"}, 2, pageToken)
+		if err != nil {
+			t.Fatalf("could not list the page: %v", err)
+		}
+		pages++
+		for _, entry := range entries {
+			got = append(got, entry.Name)
+		}
+		if nextPageToken == "" {
+			break
+		}
+		pageToken = nextPageToken
+	}
+	testutil.AssertEq(t, "pages", pages, 2)
+	if diff := cmp.Diff([]string{"logs/a.log", "logs/b.log", "logs/c.log"}, got); diff != "" {
+		t.Errorf("unexpected entries (-want +got):\n%s", diff)
+	}
+}
+
+func TestObjects_Delimiter(t *testing.T) {
+	ctx := context.Background()
+	client := newFakeClient(t, ctx, []string{"logs/x/a.log", "logs/y/b.log", "logs/c.log"})
+
+	objects := client.Objects(ctx, "bucket", ListQuery{Prefix: "logs/", Delimiter: "/"})
+	var names, prefixes []string
+	for {
+		entry, err := objects.Next()
+		if err == Done {
+			break
+		}
+		if err != nil {
+			t.Fatalf("could not list the objects: %v", err)
+		}
+		if entry.Prefix != "" {
+			prefixes = append(prefixes, entry.Prefix)
+		} else {
+			names = append(names, entry.Name)
+		}
+	}
+	if diff := cmp.Diff([]string{"logs/c.log"}, names); diff != "" {
+		t.Errorf("unexpected names (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"logs/x/", "logs/y/"}, prefixes); diff != "" {
+		t.Errorf("unexpected prefixes (-want +got):\n%s", diff)
+	}
+}
+
+func TestObjects_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	client := newFakeClient(t, ctx, []string{"logs/a.log"})
+	cancel()
+
+	if _, err := client.Objects(ctx, "bucket", ListQuery{Prefix: "logs/"}).Next(); err == nil || err == Done {
+		t.Errorf("expected the error of the canceled context; got: %v", err)
+	}
+}
+
+func TestForEachLogFilePage(t *testing.T) {
+	client := newFakeClient(t, context.Background(), []string{"logs/a.log", "logs/b.txt", "logs/c.log", "logs/d.log"})
+
+	var pages [][]string
+	err := client.ForEachLogFilePage("bucket", "logs/", 2, func(logFilePaths []string) error {
+		pages = append(pages, logFilePaths)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("could not list the log files: %v", err)
+	}
+	if diff := cmp.Diff([][]string{{"logs/a.log"}, {"logs/c.log", "logs/d.log"}}, pages); diff != "" {
+		t.Errorf("unexpected pages (-want +got):\n%s", diff)
+	}
+
+	if err := client.ForEachLogFilePage("bucket", "none/", 2, func([]string) error { return nil }); err == nil {
+		t.Errorf("expected an error for a directory without log files")
+	}
+}
+
+func TestClient_WithStore(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"logs/a.log", "logs/b.log", "summary.json"} {
+		filePath := filepath.Join(root, "bucket", filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filePath), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filePath, []byte(name), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// No Google Cloud Storage client, nor credentials, are needed.
+	client, err := NewClientWithContext(context.Background(), WithStore(blobstore.NewDirStore(root)))
+	if err != nil {
+		t.Fatalf("could not create the client: %v", err)
+	}
+
+	fileBytes, err := client.GetBlobData("bucket", "summary.json")
+	if err != nil {
+		t.Fatalf("could not read the blob: %v", err)
+	}
+	testutil.AssertEq(t, "content", string(fileBytes), "summary.json")
+
+	logsData, err := client.GetLogsData("bucket", "logs/")
+	if err != nil {
+		t.Fatalf("could not read the logs: %v", err)
+	}
+	testutil.AssertEq(t, "number of logs", len(logsData), 2)
+	testutil.AssertEq(t, "URI", client.URI("bucket", "summary.json"), "file://"+filepath.ToSlash(filepath.Join(root, "bucket", "summary.json")))
+}
@@ -22,6 +22,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"os"
+	"time"
 
 	slsav02 "github.com/project-oak/transparent-release/pkg/intoto/slsa_provenance/v0.2"
 	slsav1 "github.com/project-oak/transparent-release/pkg/intoto/slsa_provenance/v1"
@@ -32,19 +33,53 @@ import (
 // ProvenanceIR is an internal intermediate representation of data from provenances.
 // We want to map different provenances of different build types to ProvenanceIR, so
 // all fields except for `binarySHA256Digest`, `buildType`, and `binaryName` are optional.
+// `binarySHA256Digest` is empty if the subject of the provenance only has
+// digests with other algorithms, which are then available in `binaryDigests`.
 //
 // To add a new field X to `ProvenanceIR`
 // (i) implement GetX, HasX, WithX, and
-// (ii) check whether `WithX` needs to be added to existing mappings to `ProvenanceIR` from validated provenances.
+// (ii) check whether `WithX` needs to be added to existing mappings to `ProvenanceIR` from validated provenances, and
+// (iii) add X and HasX to `ProvenanceFields`, and set them in `Export`.
 type ProvenanceIR struct {
 	binarySHA256Digest       string
 	buildType                string
 	binaryName               string
+	binaryDigests            *intoto.DigestSet
 	buildCmd                 *[]string
 	builderImageSHA256Digest *string
+	builderImageDigests      *intoto.DigestSet
 	repoURI                  *string
 	commitSHA1Digest         *string
+	commitDigests            *intoto.DigestSet
+	treeDigests              *intoto.DigestSet
+	sourceRef                *string
 	trustedBuilder           *string
+	buildStartedOn           *time.Time
+	buildFinishedOn          *time.Time
+	certificateIdentity      *CertificateIdentity
+	toolchainVersions        *map[string]string
+	byproducts               *[]Byproduct
+	buildMetadata            *BuildMetadata
+}
+
+// CertificateIdentity is the identity that Fulcio bound to the certificate
+// signing a provenance, e.g., the GitHub Actions workflow that generated it.
+type CertificateIdentity struct {
+	// SubjectAlternativeName is the URI or email address in the certificate,
+	// e.g., the URI of the workflow, including its ref, for GitHub Actions.
+	SubjectAlternativeName string `json:"subjectAlternativeName"`
+	// Issuer is the OIDC issuer of the token for which the certificate has
+	// been issued.
+	Issuer string `json:"issuer"`
+	// BuildConfigURI is the URI of the top-level workflow, including its ref.
+	// It differs from SubjectAlternativeName for reusable workflows.
+	BuildConfigURI string `json:"buildConfigURI"`
+	// SourceRepositoryRef is the ref of the source repository at which the
+	// workflow ran, e.g., `refs/heads/main`.
+	SourceRepositoryRef string `json:"sourceRepositoryRef"`
+	// SourceRepositoryOwnerURI is the URI of the owner of the source
+	// repository, e.g., `https://github.com/project-oak`.
+	SourceRepositoryOwnerURI string `json:"sourceRepositoryOwnerURI"`
 }
 
 // NewProvenanceIR creates a new proveance with given optional fields.
@@ -72,6 +107,21 @@ func (p *ProvenanceIR) BuildType() string {
 	return p.buildType
 }
 
+// BinaryDigests returns all digests of the binary, keyed by the canonical
+// names returned by NormalizeDigestSet. If no digests have been set, returns a
+// digest set containing the binary sha256 digest, if available.
+func (p *ProvenanceIR) BinaryDigests() intoto.DigestSet {
+	digests := make(intoto.DigestSet)
+	if p.HasBinaryDigests() {
+		for key, value := range *p.binaryDigests {
+			digests[key] = value
+		}
+	} else if p.binarySHA256Digest != "" {
+		digests["sha2-256"] = p.binarySHA256Digest
+	}
+	return digests
+}
+
 // BuildCmd return the build cmd, or an error if the build cmd has not been set.
 func (p *ProvenanceIR) BuildCmd() ([]string, error) {
 	if !p.HasBuildCmd() {
@@ -90,6 +140,43 @@ func (p *ProvenanceIR) CommitSHA1Digest() string {
 	return *p.commitSHA1Digest
 }
 
+// CommitDigests returns all digests of the source commit, keyed by canonical
+// names, i.e., "sha1" for the SHA-1 Git object format, and "sha2-256" for the
+// SHA-256 one. If no digests have been set, returns a digest set containing
+// the SHA1 commit digest, if available.
+func (p *ProvenanceIR) CommitDigests() intoto.DigestSet {
+	digests := make(intoto.DigestSet)
+	if p.HasCommitDigests() {
+		for key, value := range *p.commitDigests {
+			digests[key] = value
+		}
+	} else if p.HasCommitSHA1Digest() && *p.commitSHA1Digest != "" {
+		digests["sha1"] = *p.commitSHA1Digest
+	}
+	return digests
+}
+
+// TreeDigests returns all digests of the Git tree of the source commit,
+// keyed by canonical names, or an empty digest set if none have been set.
+func (p *ProvenanceIR) TreeDigests() intoto.DigestSet {
+	digests := make(intoto.DigestSet)
+	if p.HasTreeDigests() {
+		for key, value := range *p.treeDigests {
+			digests[key] = value
+		}
+	}
+	return digests
+}
+
+// SourceRef returns the Git ref of the source that the build was invoked on,
+// e.g., `refs/tags/v1.2.3`, or an error if the source ref has not been set.
+func (p *ProvenanceIR) SourceRef() (string, error) {
+	if !p.HasSourceRef() {
+		return "", fmt.Errorf("provenance does not have a source ref")
+	}
+	return *p.sourceRef, nil
+}
+
 // BuilderImageSHA256Digest returns the builder image sha256 digest, or an
 // error if the builder image sha256 digest has not been set.
 func (p *ProvenanceIR) BuilderImageSHA256Digest() (string, error) {
@@ -99,6 +186,21 @@ func (p *ProvenanceIR) BuilderImageSHA256Digest() (string, error) {
 	return *p.builderImageSHA256Digest, nil
 }
 
+// BuilderImageDigests returns all digests of the builder image, keyed by
+// canonical names. If no digests have been set, returns a digest set
+// containing the builder image sha256 digest, if available.
+func (p *ProvenanceIR) BuilderImageDigests() intoto.DigestSet {
+	digests := make(intoto.DigestSet)
+	if p.HasBuilderImageDigests() {
+		for key, value := range *p.builderImageDigests {
+			digests[key] = value
+		}
+	} else if p.HasBuilderImageSHA256Digest() && *p.builderImageSHA256Digest != "" {
+		digests["sha2-256"] = *p.builderImageSHA256Digest
+	}
+	return digests
+}
+
 // TrustedBuilder returns the builder image sha256 digest, or an error if the
 // trusted builder has not been set.
 func (p *ProvenanceIR) TrustedBuilder() (string, error) {
@@ -108,6 +210,20 @@ func (p *ProvenanceIR) TrustedBuilder() (string, error) {
 	return *p.trustedBuilder, nil
 }
 
+// WithBinaryDigests sets all digests of the binary when creating a new
+// ProvenanceIR. The digests must be keyed by canonical names, as returned by
+// NormalizeDigestSet.
+func WithBinaryDigests(binaryDigests intoto.DigestSet) func(p *ProvenanceIR) {
+	return func(p *ProvenanceIR) {
+		p.binaryDigests = &binaryDigests
+	}
+}
+
+// HasBinaryDigests returns true if the binary digests have been set in the ProvenanceIR.
+func (p *ProvenanceIR) HasBinaryDigests() bool {
+	return p.binaryDigests != nil
+}
+
 // WithBuildCmd sets the build cmd when creating a new ProvenanceIR.
 func WithBuildCmd(buildCmd []string) func(p *ProvenanceIR) {
 	return func(p *ProvenanceIR) {
@@ -132,6 +248,20 @@ func (p *ProvenanceIR) HasBuilderImageSHA256Digest() bool {
 	return p.builderImageSHA256Digest != nil
 }
 
+// WithBuilderImageDigests sets all digests of the builder image when creating
+// a new ProvenanceIR. The digests must be keyed by canonical names, as
+// returned by NormalizeDigestSet.
+func WithBuilderImageDigests(builderImageDigests intoto.DigestSet) func(p *ProvenanceIR) {
+	return func(p *ProvenanceIR) {
+		p.builderImageDigests = &builderImageDigests
+	}
+}
+
+// HasBuilderImageDigests returns true if the builder image digests have been set in the ProvenanceIR.
+func (p *ProvenanceIR) HasBuilderImageDigests() bool {
+	return p.builderImageDigests != nil
+}
+
 // WithRepoURI sets repo URI referenced in the provenance when creating a new ProvenanceIR.
 func WithRepoURI(repoURI string) func(p *ProvenanceIR) {
 	return func(p *ProvenanceIR) {
@@ -156,7 +286,80 @@ func (p *ProvenanceIR) HasCommitSHA1Digest() bool {
 	return p.commitSHA1Digest != nil
 }
 
+// WithCommitDigests sets all digests of the source commit when creating a new
+// ProvenanceIR. The digests must be keyed by canonical names, as returned by
+// NormalizeDigestSet.
+func WithCommitDigests(commitDigests intoto.DigestSet) func(p *ProvenanceIR) {
+	return func(p *ProvenanceIR) {
+		p.commitDigests = &commitDigests
+	}
+}
+
+// HasCommitDigests returns true if the commit digests have been set in the ProvenanceIR.
+func (p *ProvenanceIR) HasCommitDigests() bool {
+	return p.commitDigests != nil
+}
+
+// WithTreeDigests sets all digests of the Git tree of the source commit when
+// creating a new ProvenanceIR. The digests must be keyed by canonical names,
+// as returned by NormalizeDigestSet.
+func WithTreeDigests(treeDigests intoto.DigestSet) func(p *ProvenanceIR) {
+	return func(p *ProvenanceIR) {
+		p.treeDigests = &treeDigests
+	}
+}
+
+// HasTreeDigests returns true if the tree digests have been set in the ProvenanceIR.
+func (p *ProvenanceIR) HasTreeDigests() bool {
+	return p.treeDigests != nil
+}
+
+// WithSourceRef sets the Git ref of the source when creating a new ProvenanceIR.
+func WithSourceRef(sourceRef string) func(p *ProvenanceIR) {
+	return func(p *ProvenanceIR) {
+		p.sourceRef = &sourceRef
+	}
+}
+
+// HasSourceRef returns true if the source ref has been set in the ProvenanceIR.
+func (p *ProvenanceIR) HasSourceRef() bool {
+	return p.sourceRef != nil
+}
+
 // WithTrustedBuilder sets the trusted builder when creating a new ProvenanceIR.
+// Byproduct is an artifact generated during the build that is not its output,
+// e.g., the build log.
+type Byproduct struct {
+	// Name distinguishes the byproduct from other byproducts, e.g., `build.log`.
+	Name string `json:"name"`
+	// URI identifies the byproduct, e.g., the location of the retained log.
+	URI string `json:"uri"`
+	// Digests of the byproduct, keyed by the canonical names of their
+	// algorithms.
+	Digests intoto.DigestSet `json:"digests"`
+}
+
+// BuildMetadata describes the invocation of the build, as recorded by the
+// builder. The start and finish times of the build are recorded separately,
+// as BuildStartedOn and BuildFinishedOn.
+type BuildMetadata struct {
+	// InvocationID identifies the build invocation, e.g., to find its logs.
+	InvocationID string `json:"invocationID"`
+	// ParametersComplete is true if the builder claims that all external
+	// parameters of the build are recorded. Only recorded in SLSA v0.2.
+	ParametersComplete bool `json:"parametersComplete"`
+	// EnvironmentComplete is true if the builder claims that the environment
+	// of the build is recorded. Only recorded in SLSA v0.2.
+	EnvironmentComplete bool `json:"environmentComplete"`
+	// MaterialsComplete is true if the builder claims that all materials of
+	// the build are recorded, i.e., that the build is hermetic. Only recorded
+	// in SLSA v0.2.
+	MaterialsComplete bool `json:"materialsComplete"`
+	// Reproducible is true if the builder claims that rerunning the build
+	// produces bit-for-bit identical output. Only recorded in SLSA v0.2.
+	Reproducible bool `json:"reproducible"`
+}
+
 func WithTrustedBuilder(trustedBuilder string) func(p *ProvenanceIR) {
 	return func(p *ProvenanceIR) {
 		p.trustedBuilder = &trustedBuilder
@@ -168,11 +371,283 @@ func (p *ProvenanceIR) HasTrustedBuilder() bool {
 	return p.trustedBuilder != nil
 }
 
+// BuildStartedOn returns the time at which the build started.
+func (p *ProvenanceIR) BuildStartedOn() (time.Time, error) {
+	if p.buildStartedOn == nil {
+		return time.Time{}, fmt.Errorf("provenance does not have a build start time")
+	}
+	return *p.buildStartedOn, nil
+}
+
+// WithBuildStartedOn sets the build start time when creating a new ProvenanceIR.
+func WithBuildStartedOn(buildStartedOn time.Time) func(p *ProvenanceIR) {
+	return func(p *ProvenanceIR) {
+		p.buildStartedOn = &buildStartedOn
+	}
+}
+
+// HasBuildStartedOn returns true if the build start time has been set in the ProvenanceIR.
+func (p *ProvenanceIR) HasBuildStartedOn() bool {
+	return p.buildStartedOn != nil
+}
+
+// BuildFinishedOn returns the time at which the build finished.
+func (p *ProvenanceIR) BuildFinishedOn() (time.Time, error) {
+	if p.buildFinishedOn == nil {
+		return time.Time{}, fmt.Errorf("provenance does not have a build finish time")
+	}
+	return *p.buildFinishedOn, nil
+}
+
+// WithBuildFinishedOn sets the build finish time when creating a new ProvenanceIR.
+func WithBuildFinishedOn(buildFinishedOn time.Time) func(p *ProvenanceIR) {
+	return func(p *ProvenanceIR) {
+		p.buildFinishedOn = &buildFinishedOn
+	}
+}
+
+// HasBuildFinishedOn returns true if the build finish time has been set in the ProvenanceIR.
+func (p *ProvenanceIR) HasBuildFinishedOn() bool {
+	return p.buildFinishedOn != nil
+}
+
+// CertificateIdentity returns the identity of the signer of the provenance.
+func (p *ProvenanceIR) CertificateIdentity() (CertificateIdentity, error) {
+	if p.certificateIdentity == nil {
+		return CertificateIdentity{}, fmt.Errorf("provenance does not have a verified certificate identity")
+	}
+	return *p.certificateIdentity, nil
+}
+
+// WithCertificateIdentity sets the identity of the signer. It must only be
+// set once the signature of the provenance, and the certificate, have been
+// verified.
+func WithCertificateIdentity(certificateIdentity CertificateIdentity) func(p *ProvenanceIR) {
+	return func(p *ProvenanceIR) {
+		p.certificateIdentity = &certificateIdentity
+	}
+}
+
+// HasCertificateIdentity returns true if the certificate identity has been set in the ProvenanceIR.
+func (p *ProvenanceIR) HasCertificateIdentity() bool {
+	return p.certificateIdentity != nil
+}
+
+// ToolchainVersions returns the versions of the toolchains in the builder
+// image, keyed by the names of the toolchains, e.g., `rustc`.
+func (p *ProvenanceIR) ToolchainVersions() (map[string]string, error) {
+	if p.toolchainVersions == nil {
+		return nil, fmt.Errorf("provenance does not have toolchain versions")
+	}
+	return *p.toolchainVersions, nil
+}
+
+// WithToolchainVersions sets the versions of the toolchains in the builder image.
+func WithToolchainVersions(toolchainVersions map[string]string) func(p *ProvenanceIR) {
+	return func(p *ProvenanceIR) {
+		p.toolchainVersions = &toolchainVersions
+	}
+}
+
+// HasToolchainVersions returns true if the toolchain versions have been set in the ProvenanceIR.
+func (p *ProvenanceIR) HasToolchainVersions() bool {
+	return p.toolchainVersions != nil
+}
+
+// Byproducts returns the artifacts generated during the build that are not
+// its output.
+func (p *ProvenanceIR) Byproducts() ([]Byproduct, error) {
+	if p.byproducts == nil {
+		return nil, fmt.Errorf("provenance does not have byproducts")
+	}
+	return *p.byproducts, nil
+}
+
+// WithByproducts sets the byproducts of the build.
+func WithByproducts(byproducts []Byproduct) func(p *ProvenanceIR) {
+	return func(p *ProvenanceIR) {
+		p.byproducts = &byproducts
+	}
+}
+
+// HasByproducts returns true if the byproducts have been set in the ProvenanceIR.
+func (p *ProvenanceIR) HasByproducts() bool {
+	return p.byproducts != nil
+}
+
+// BuildMetadata returns the metadata about the invocation of the build.
+func (p *ProvenanceIR) BuildMetadata() (BuildMetadata, error) {
+	if p.buildMetadata == nil {
+		return BuildMetadata{}, fmt.Errorf("provenance does not have build metadata")
+	}
+	return *p.buildMetadata, nil
+}
+
+// WithBuildMetadata sets the metadata about the invocation of the build.
+func WithBuildMetadata(buildMetadata BuildMetadata) func(p *ProvenanceIR) {
+	return func(p *ProvenanceIR) {
+		p.buildMetadata = &buildMetadata
+	}
+}
+
+// HasBuildMetadata returns true if the build metadata has been set in the ProvenanceIR.
+func (p *ProvenanceIR) HasBuildMetadata() bool {
+	return p.buildMetadata != nil
+}
+
+// ProvenanceFields is a flat, read-only view of all fields of a ProvenanceIR.
+// Optional fields are accompanied by a HasX field, and are set to their zero
+// value if absent. Field names are stable, so that ProvenanceFields can be
+// used as input to templates and policies.
+type ProvenanceFields struct {
+	BinarySHA256Digest          string              `json:"binarySHA256Digest"`
+	BuildType                   string              `json:"buildType"`
+	BinaryName                  string              `json:"binaryName"`
+	BinaryDigests               map[string]string   `json:"binaryDigests"`
+	HasBinaryDigests            bool                `json:"hasBinaryDigests"`
+	BuildCmd                    []string            `json:"buildCmd"`
+	HasBuildCmd                 bool                `json:"hasBuildCmd"`
+	BuilderImageSHA256Digest    string              `json:"builderImageSHA256Digest"`
+	HasBuilderImageSHA256Digest bool                `json:"hasBuilderImageSHA256Digest"`
+	BuilderImageDigests         map[string]string   `json:"builderImageDigests"`
+	HasBuilderImageDigests      bool                `json:"hasBuilderImageDigests"`
+	RepoURI                     string              `json:"repoURI"`
+	HasRepoURI                  bool                `json:"hasRepoURI"`
+	CommitSHA1Digest            string              `json:"commitSHA1Digest"`
+	HasCommitSHA1Digest         bool                `json:"hasCommitSHA1Digest"`
+	CommitDigests               map[string]string   `json:"commitDigests"`
+	HasCommitDigests            bool                `json:"hasCommitDigests"`
+	TreeDigests                 map[string]string   `json:"treeDigests"`
+	HasTreeDigests              bool                `json:"hasTreeDigests"`
+	SourceRef                   string              `json:"sourceRef"`
+	HasSourceRef                bool                `json:"hasSourceRef"`
+	TrustedBuilder              string              `json:"trustedBuilder"`
+	HasTrustedBuilder           bool                `json:"hasTrustedBuilder"`
+	BuildStartedOn              time.Time           `json:"buildStartedOn"`
+	HasBuildStartedOn           bool                `json:"hasBuildStartedOn"`
+	BuildFinishedOn             time.Time           `json:"buildFinishedOn"`
+	HasBuildFinishedOn          bool                `json:"hasBuildFinishedOn"`
+	CertificateIdentity         CertificateIdentity `json:"certificateIdentity"`
+	HasCertificateIdentity      bool                `json:"hasCertificateIdentity"`
+	ToolchainVersions           map[string]string   `json:"toolchainVersions"`
+	HasToolchainVersions        bool                `json:"hasToolchainVersions"`
+	Byproducts                  []Byproduct         `json:"byproducts"`
+	HasByproducts               bool                `json:"hasByproducts"`
+	BuildMetadata               BuildMetadata       `json:"buildMetadata"`
+	HasBuildMetadata            bool                `json:"hasBuildMetadata"`
+}
+
+// Export returns all fields of the ProvenanceIR, including whether each of
+// the optional fields has been set.
+func (p *ProvenanceIR) Export() ProvenanceFields {
+	fields := ProvenanceFields{
+		BinarySHA256Digest:          p.binarySHA256Digest,
+		BuildType:                   p.buildType,
+		BinaryName:                  p.binaryName,
+		HasBinaryDigests:            p.HasBinaryDigests(),
+		HasBuildCmd:                 p.HasBuildCmd(),
+		HasBuilderImageSHA256Digest: p.HasBuilderImageSHA256Digest(),
+		HasBuilderImageDigests:      p.HasBuilderImageDigests(),
+		HasRepoURI:                  p.HasRepoURI(),
+		HasCommitSHA1Digest:         p.HasCommitSHA1Digest(),
+		HasCommitDigests:            p.HasCommitDigests(),
+		HasTreeDigests:              p.HasTreeDigests(),
+		HasSourceRef:                p.HasSourceRef(),
+		HasTrustedBuilder:           p.HasTrustedBuilder(),
+		HasBuildStartedOn:           p.HasBuildStartedOn(),
+		HasBuildFinishedOn:          p.HasBuildFinishedOn(),
+		HasCertificateIdentity:      p.HasCertificateIdentity(),
+		HasToolchainVersions:        p.HasToolchainVersions(),
+		HasByproducts:               p.HasByproducts(),
+		HasBuildMetadata:            p.HasBuildMetadata(),
+	}
+	if p.HasBinaryDigests() {
+		fields.BinaryDigests = p.BinaryDigests()
+	}
+	if p.HasBuildCmd() {
+		fields.BuildCmd = append([]string{}, *p.buildCmd...)
+	}
+	if p.HasBuilderImageSHA256Digest() {
+		fields.BuilderImageSHA256Digest = *p.builderImageSHA256Digest
+	}
+	if p.HasBuilderImageDigests() {
+		fields.BuilderImageDigests = p.BuilderImageDigests()
+	}
+	if p.HasRepoURI() {
+		fields.RepoURI = *p.repoURI
+	}
+	if p.HasCommitSHA1Digest() {
+		fields.CommitSHA1Digest = *p.commitSHA1Digest
+	}
+	if p.HasCommitDigests() {
+		fields.CommitDigests = p.CommitDigests()
+	}
+	if p.HasTreeDigests() {
+		fields.TreeDigests = p.TreeDigests()
+	}
+	if p.HasSourceRef() {
+		fields.SourceRef = *p.sourceRef
+	}
+	if p.HasTrustedBuilder() {
+		fields.TrustedBuilder = *p.trustedBuilder
+	}
+	if p.HasBuildStartedOn() {
+		fields.BuildStartedOn = *p.buildStartedOn
+	}
+	if p.HasBuildFinishedOn() {
+		fields.BuildFinishedOn = *p.buildFinishedOn
+	}
+	if p.HasCertificateIdentity() {
+		fields.CertificateIdentity = *p.certificateIdentity
+	}
+	if p.HasToolchainVersions() {
+		fields.ToolchainVersions = make(map[string]string, len(*p.toolchainVersions))
+		for name, version := range *p.toolchainVersions {
+			fields.ToolchainVersions[name] = version
+		}
+	}
+	if p.HasByproducts() {
+		fields.Byproducts = make([]Byproduct, 0, len(*p.byproducts))
+		for _, byproduct := range *p.byproducts {
+			byproduct.Digests = intoto.DigestSet(copyStringMap(byproduct.Digests))
+			fields.Byproducts = append(fields.Byproducts, byproduct)
+		}
+	}
+	if p.HasBuildMetadata() {
+		fields.BuildMetadata = *p.buildMetadata
+	}
+	return fields
+}
+
+// SupportedFormat is a combination of a predicate type and a build type of
+// provenances that can be mapped to ProvenanceIR, and hence verified.
+type SupportedFormat struct {
+	PredicateType string `json:"predicateType"`
+	BuildType     string `json:"buildType"`
+}
+
+// SupportedFormats returns all combinations of predicate types and build types
+// that FromValidatedProvenance can map to ProvenanceIR.
+//
+// When adding a new mapping to FromValidatedProvenance, add its predicate type
+// and build type here as well.
+func SupportedFormats() []SupportedFormat {
+	return []SupportedFormat{
+		{PredicateType: intoto.SLSAV02PredicateType, BuildType: slsav02.GenericSLSABuildType},
+		{PredicateType: slsav1.PredicateSLSAProvenance, BuildType: slsav1.DockerBasedBuildType},
+		{PredicateType: slsav1.PredicateSLSAProvenanceDraft, BuildType: slsav1.DockerBasedBuildType},
+	}
+}
+
 // FromValidatedProvenance maps a validated provenance to ProvenanceIR by checking the provenance's
-// predicate and build type.
+// predicate and build type. The provenance must have a single subject; see
+// ValidatedProvenance.SelectSubject.
 //
 // To add a new mapping from a provenance P write `fromP`, which sets every required field `X` from `ProvenanceIR` using `WithX`.
 func FromValidatedProvenance(prov *ValidatedProvenance) (*ProvenanceIR, error) {
+	if prov.SubjectCount() != 1 {
+		return nil, fmt.Errorf("the provenance has %d subjects, select one of them to map it to ProvenanceIR", prov.SubjectCount())
+	}
 	predType := prov.PredicateType()
 	switch predType {
 	case intoto.SLSAV02PredicateType:
@@ -198,7 +673,8 @@ func FromValidatedProvenance(prov *ValidatedProvenance) (*ProvenanceIR, error) {
 // be mapped to a field in `ProvenanceIR`, `fromSLSAv02` sets a non-nil value
 // `v` for `X` by using `WithX(v)`.
 func fromSLSAv02(provenance *ValidatedProvenance) (*ProvenanceIR, error) {
-	// A ValidatedProvenance contains a SHA256 hash of a single subject.
+	// The ValidatedProvenance contains the digests of a single subject, which
+	// may not include a SHA256 digest.
 	binarySHA256Digest := provenance.GetBinarySHA256Digest()
 	buildType := slsav02.GenericSLSABuildType
 
@@ -208,17 +684,45 @@ func fromSLSAv02(provenance *ValidatedProvenance) (*ProvenanceIR, error) {
 	}
 
 	repoURI, commitHash := predicate.RepoURIAndDigest()
+	commitDigests, err := NormalizeDigestSet(predicate.CommitDigests())
+	if err != nil {
+		return nil, fmt.Errorf("invalid commit digest in SLSA v0.2 provenance: %v", err)
+	}
 
 	// A ValidatedProvenance has a binary name.
 	binaryName := provenance.GetBinaryName()
 
 	builder := predicate.Builder.ID
 
-	provenanceIR := NewProvenanceIR(binarySHA256Digest, buildType, binaryName,
+	options := []func(p *ProvenanceIR){
+		WithBinaryDigests(provenance.GetBinaryDigests()),
 		WithRepoURI(*repoURI),
 		WithCommitSHA1Digest(*commitHash),
+		WithCommitDigests(commitDigests),
 		WithTrustedBuilder(builder),
-	)
+	}
+	// The source ref is only known if the URI of the config source has one.
+	if sourceRef := predicate.SourceRef(); sourceRef != "" {
+		options = append(options, WithSourceRef(sourceRef))
+	}
+	// The build start and finish times are optional in SLSA v0.2.
+	if predicate.Metadata != nil && predicate.Metadata.BuildStartedOn != nil {
+		options = append(options, WithBuildStartedOn(*predicate.Metadata.BuildStartedOn))
+	}
+	if predicate.Metadata != nil && predicate.Metadata.BuildFinishedOn != nil {
+		options = append(options, WithBuildFinishedOn(*predicate.Metadata.BuildFinishedOn))
+	}
+	if predicate.Metadata != nil {
+		options = append(options, WithBuildMetadata(BuildMetadata{
+			InvocationID:        predicate.Metadata.BuildInvocationID,
+			ParametersComplete:  predicate.Metadata.Completeness.Parameters,
+			EnvironmentComplete: predicate.Metadata.Completeness.Environment,
+			MaterialsComplete:   predicate.Metadata.Completeness.Materials,
+			Reproducible:        predicate.Metadata.Reproducible,
+		}))
+	}
+
+	provenanceIR := NewProvenanceIR(binarySHA256Digest, buildType, binaryName, options...)
 	return provenanceIR, nil
 }
 
@@ -227,7 +731,8 @@ func fromSLSAv02(provenance *ValidatedProvenance) (*ProvenanceIR, error) {
 // mapped to a field in `ProvenanceIR`, `fromSLSAv1` sets a non-nil value `v`
 // for `X` by using `WithX(v)`.
 func fromSLSAv1(provenance *ValidatedProvenance) (*ProvenanceIR, error) {
-	// A ValidatedProvenance contains a SHA256 hash of a single subject.
+	// The ValidatedProvenance contains the digests of a single subject, which
+	// may not include a SHA256 digest.
 	binarySHA256Digest := provenance.GetBinarySHA256Digest()
 	buildType := slsav1.DockerBasedBuildType
 	binaryName := provenance.GetBinaryName()
@@ -238,20 +743,76 @@ func fromSLSAv1(provenance *ValidatedProvenance) (*ProvenanceIR, error) {
 	}
 
 	repoURI, commitDigest := predicate.RepoURIAndDigest()
+	// The commit may only have a SHA256 digest, for repos in the SHA-256 Git
+	// object format.
+	commitDigests, err := NormalizeDigestSet(predicate.CommitDigests())
+	if err != nil {
+		return nil, fmt.Errorf("invalid commit digest in SLSA v1 provenance: %v", err)
+	}
 	builder := predicate.BuilderID()
 	buildCmd := predicate.BuildCmd()
-	builderImageDigest, err := predicate.BuilderImageDigest()
+	// The builder image may only have digests with algorithms other than
+	// SHA256, but must have at least one supported digest.
+	builderImageDigests, err := NormalizeSupportedDigestSet(predicate.BuilderImageDigests())
 	if err != nil {
-		return nil, fmt.Errorf("getting builder image digest from SLSA v1 provenance: %v", err)
+		return nil, fmt.Errorf("invalid builder image digest in SLSA v1 provenance: %v", err)
 	}
 
-	provenanceIR := NewProvenanceIR(binarySHA256Digest, buildType, binaryName,
+	options := []func(p *ProvenanceIR){
+		WithBinaryDigests(provenance.GetBinaryDigests()),
 		WithRepoURI(*repoURI),
 		WithCommitSHA1Digest(*commitDigest),
+		WithCommitDigests(commitDigests),
 		WithTrustedBuilder(builder),
 		WithBuildCmd(buildCmd),
-		WithBuilderImageSHA256Digest(builderImageDigest),
-	)
+		WithBuilderImageDigests(builderImageDigests),
+	}
+	if digest, ok := builderImageDigests["sha2-256"]; ok {
+		options = append(options, WithBuilderImageSHA256Digest(digest))
+	}
+	// The source ref is only known if the source URI has one.
+	if sourceRef := predicate.SourceRef(); sourceRef != "" {
+		options = append(options, WithSourceRef(sourceRef))
+	}
+	// The tree of the source commit is only recorded by some builders.
+	if treeDigests := predicate.TreeDigests(); treeDigests != nil {
+		normalized, err := NormalizeSupportedDigestSet(treeDigests)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tree digest in SLSA v1 provenance: %v", err)
+		}
+		options = append(options, WithTreeDigests(normalized))
+	}
+	// The build start and finish times are optional in SLSA v1.
+	if predicate.RunDetails.BuildMetadata.StartedOn != nil {
+		options = append(options, WithBuildStartedOn(*predicate.RunDetails.BuildMetadata.StartedOn))
+	}
+	if predicate.RunDetails.BuildMetadata.FinishedOn != nil {
+		options = append(options, WithBuildFinishedOn(*predicate.RunDetails.BuildMetadata.FinishedOn))
+	}
+	// SLSA v1 has no completeness or reproducibility claims, so the build
+	// metadata is only set if the invocation ID is known.
+	if invocationID := predicate.RunDetails.BuildMetadata.InvocationID; invocationID != "" {
+		options = append(options, WithBuildMetadata(BuildMetadata{InvocationID: invocationID}))
+	}
+	// Toolchain versions are only recorded by builders that query them in the
+	// builder image.
+	if toolchainVersions := predicate.ToolchainVersions(); len(toolchainVersions) > 0 {
+		options = append(options, WithToolchainVersions(toolchainVersions))
+	}
+	// Byproducts, e.g., the build log, are optional in SLSA v1.
+	if len(predicate.RunDetails.Byproducts) > 0 {
+		byproducts := make([]Byproduct, 0, len(predicate.RunDetails.Byproducts))
+		for _, byproduct := range predicate.RunDetails.Byproducts {
+			digests, err := NormalizeDigestSet(byproduct.Digest)
+			if err != nil {
+				return nil, fmt.Errorf("invalid digest of byproduct %q in SLSA v1 provenance: %v", byproduct.Name, err)
+			}
+			byproducts = append(byproducts, Byproduct{Name: byproduct.Name, URI: byproduct.URI, Digests: digests})
+		}
+		options = append(options, WithByproducts(byproducts))
+	}
+
+	provenanceIR := NewProvenanceIR(binarySHA256Digest, buildType, binaryName, options...)
 
 	return provenanceIR, nil
 }
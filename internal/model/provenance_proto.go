@@ -0,0 +1,224 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/project-oak/transparent-release/pkg/intoto"
+	pb "github.com/project-oak/transparent-release/pkg/proto/oak/release"
+)
+
+// ToProto converts the ProvenanceIR to its protobuf representation. Only the
+// optional fields that are set in the ProvenanceIR are set in the result.
+func (p *ProvenanceIR) ToProto() *pb.ProvenanceIR {
+	message := &pb.ProvenanceIR{
+		BinarySha256Digest: proto.String(p.binarySHA256Digest),
+		BuildType:          proto.String(p.buildType),
+		BinaryName:         proto.String(p.binaryName),
+	}
+	if p.HasBinaryDigests() {
+		message.BinaryDigests = &pb.StringMap{Entries: copyStringMap(*p.binaryDigests)}
+	}
+	if p.HasBuildCmd() {
+		message.BuildCmd = &pb.StringList{Values: append([]string{}, *p.buildCmd...)}
+	}
+	if p.HasBuilderImageSHA256Digest() {
+		message.BuilderImageSha256Digest = proto.String(*p.builderImageSHA256Digest)
+	}
+	if p.HasBuilderImageDigests() {
+		message.BuilderImageDigests = &pb.StringMap{Entries: copyStringMap(*p.builderImageDigests)}
+	}
+	if p.HasRepoURI() {
+		message.RepoUri = proto.String(*p.repoURI)
+	}
+	if p.HasCommitSHA1Digest() {
+		message.CommitSha1Digest = proto.String(*p.commitSHA1Digest)
+	}
+	if p.HasCommitDigests() {
+		message.CommitDigests = &pb.StringMap{Entries: copyStringMap(*p.commitDigests)}
+	}
+	if p.HasTreeDigests() {
+		message.TreeDigests = &pb.StringMap{Entries: copyStringMap(*p.treeDigests)}
+	}
+	if p.HasSourceRef() {
+		message.SourceRef = proto.String(*p.sourceRef)
+	}
+	if p.HasTrustedBuilder() {
+		message.TrustedBuilder = proto.String(*p.trustedBuilder)
+	}
+	if p.HasBuildStartedOn() {
+		message.BuildStartedOn = timestamppb.New(*p.buildStartedOn)
+	}
+	if p.HasBuildFinishedOn() {
+		message.BuildFinishedOn = timestamppb.New(*p.buildFinishedOn)
+	}
+	if p.HasCertificateIdentity() {
+		identity := p.certificateIdentity
+		message.CertificateIdentity = &pb.CertificateIdentity{
+			SubjectAlternativeName:   identity.SubjectAlternativeName,
+			Issuer:                   identity.Issuer,
+			BuildConfigUri:           identity.BuildConfigURI,
+			SourceRepositoryRef:      identity.SourceRepositoryRef,
+			SourceRepositoryOwnerUri: identity.SourceRepositoryOwnerURI,
+		}
+	}
+	if p.HasToolchainVersions() {
+		message.ToolchainVersions = &pb.StringMap{Entries: copyStringMap(*p.toolchainVersions)}
+	}
+	if p.HasByproducts() {
+		message.Byproducts = &pb.ByproductList{Values: make([]*pb.Byproduct, 0, len(*p.byproducts))}
+		for _, byproduct := range *p.byproducts {
+			message.Byproducts.Values = append(message.Byproducts.Values, &pb.Byproduct{
+				Name:    byproduct.Name,
+				Uri:     byproduct.URI,
+				Digests: &pb.StringMap{Entries: copyStringMap(byproduct.Digests)},
+			})
+		}
+	}
+	if p.HasBuildMetadata() {
+		metadata := p.buildMetadata
+		message.BuildMetadata = &pb.BuildMetadata{
+			InvocationId:        metadata.InvocationID,
+			ParametersComplete:  metadata.ParametersComplete,
+			EnvironmentComplete: metadata.EnvironmentComplete,
+			MaterialsComplete:   metadata.MaterialsComplete,
+			Reproducible:        metadata.Reproducible,
+		}
+	}
+	return message
+}
+
+// FromProto converts the given protobuf representation to a ProvenanceIR.
+// Optional fields are set in the ProvenanceIR if and only if they are set in
+// the message. Returns an error if the binary SHA2-256 digest is not set, or
+// if the build start or finish time is invalid.
+func FromProto(message *pb.ProvenanceIR) (*ProvenanceIR, error) {
+	if message.BinarySha256Digest == nil {
+		return nil, fmt.Errorf("the binary SHA2-256 digest is required")
+	}
+
+	var options []func(p *ProvenanceIR)
+	if message.BinaryDigests != nil {
+		options = append(options, WithBinaryDigests(intoto.DigestSet(copyStringMap(message.BinaryDigests.Entries))))
+	}
+	if message.BuildCmd != nil {
+		options = append(options, WithBuildCmd(append([]string{}, message.BuildCmd.Values...)))
+	}
+	if message.BuilderImageSha256Digest != nil {
+		options = append(options, WithBuilderImageSHA256Digest(*message.BuilderImageSha256Digest))
+	}
+	if message.BuilderImageDigests != nil {
+		options = append(options, WithBuilderImageDigests(intoto.DigestSet(copyStringMap(message.BuilderImageDigests.Entries))))
+	}
+	if message.RepoUri != nil {
+		options = append(options, WithRepoURI(*message.RepoUri))
+	}
+	if message.CommitSha1Digest != nil {
+		options = append(options, WithCommitSHA1Digest(*message.CommitSha1Digest))
+	}
+	if message.CommitDigests != nil {
+		options = append(options, WithCommitDigests(intoto.DigestSet(copyStringMap(message.CommitDigests.Entries))))
+	}
+	if message.TreeDigests != nil {
+		options = append(options, WithTreeDigests(intoto.DigestSet(copyStringMap(message.TreeDigests.Entries))))
+	}
+	if message.SourceRef != nil {
+		options = append(options, WithSourceRef(*message.SourceRef))
+	}
+	if message.TrustedBuilder != nil {
+		options = append(options, WithTrustedBuilder(*message.TrustedBuilder))
+	}
+	if message.BuildStartedOn != nil {
+		if err := message.BuildStartedOn.CheckValid(); err != nil {
+			return nil, fmt.Errorf("invalid build start time: %v", err)
+		}
+		options = append(options, WithBuildStartedOn(message.BuildStartedOn.AsTime()))
+	}
+	if message.BuildFinishedOn != nil {
+		if err := message.BuildFinishedOn.CheckValid(); err != nil {
+			return nil, fmt.Errorf("invalid build finish time: %v", err)
+		}
+		options = append(options, WithBuildFinishedOn(message.BuildFinishedOn.AsTime()))
+	}
+	if identity := message.CertificateIdentity; identity != nil {
+		options = append(options, WithCertificateIdentity(CertificateIdentity{
+			SubjectAlternativeName:   identity.SubjectAlternativeName,
+			Issuer:                   identity.Issuer,
+			BuildConfigURI:           identity.BuildConfigUri,
+			SourceRepositoryRef:      identity.SourceRepositoryRef,
+			SourceRepositoryOwnerURI: identity.SourceRepositoryOwnerUri,
+		}))
+	}
+	if message.ToolchainVersions != nil {
+		options = append(options, WithToolchainVersions(copyStringMap(message.ToolchainVersions.Entries)))
+	}
+	if message.Byproducts != nil {
+		byproducts := make([]Byproduct, 0, len(message.Byproducts.Values))
+		for _, byproduct := range message.Byproducts.Values {
+			byproducts = append(byproducts, Byproduct{
+				Name:    byproduct.Name,
+				URI:     byproduct.Uri,
+				Digests: intoto.DigestSet(copyStringMap(byproduct.GetDigests().GetEntries())),
+			})
+		}
+		options = append(options, WithByproducts(byproducts))
+	}
+	if metadata := message.BuildMetadata; metadata != nil {
+		options = append(options, WithBuildMetadata(BuildMetadata{
+			InvocationID:        metadata.InvocationId,
+			ParametersComplete:  metadata.ParametersComplete,
+			EnvironmentComplete: metadata.EnvironmentComplete,
+			MaterialsComplete:   metadata.MaterialsComplete,
+			Reproducible:        metadata.Reproducible,
+		}))
+	}
+	return NewProvenanceIR(message.GetBinarySha256Digest(), message.GetBuildType(), message.GetBinaryName(), options...), nil
+}
+
+// MarshalBinary encodes the ProvenanceIR as its protobuf representation. It
+// implements encoding.BinaryMarshaler, so that ProvenanceIR, whose fields are
+// unexported, can be encoded with encoding/gob.
+func (p *ProvenanceIR) MarshalBinary() ([]byte, error) {
+	return proto.Marshal(p.ToProto())
+}
+
+// UnmarshalBinary decodes a ProvenanceIR encoded with MarshalBinary. It
+// implements encoding.BinaryUnmarshaler.
+func (p *ProvenanceIR) UnmarshalBinary(data []byte) error {
+	var message pb.ProvenanceIR
+	if err := proto.Unmarshal(data, &message); err != nil {
+		return fmt.Errorf("could not unmarshal the provenance: %v", err)
+	}
+	provenance, err := FromProto(&message)
+	if err != nil {
+		return err
+	}
+	*p = *provenance
+	return nil
+}
+
+// copyStringMap returns a copy of the given map, which is not nil even if the
+// given map is.
+func copyStringMap(m map[string]string) map[string]string {
+	copied := make(map[string]string, len(m))
+	for key, value := range m {
+		copied[key] = value
+	}
+	return copied
+}
@@ -0,0 +1,111 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"bytes"
+	"encoding/gob"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/project-oak/transparent-release/pkg/intoto"
+	slsav02 "github.com/project-oak/transparent-release/pkg/intoto/slsa_provenance/v0.2"
+	slsav1 "github.com/project-oak/transparent-release/pkg/intoto/slsa_provenance/v1"
+	pb "github.com/project-oak/transparent-release/pkg/proto/oak/release"
+)
+
+func TestProto_RoundTrip(t *testing.T) {
+	provenances := map[string]*ProvenanceIR{
+		"all fields set": NewProvenanceIR("d059c38cea82047ad316a1c6c6fbd13ecf7a0abdcc375463920bd25bf5c142cc",
+			slsav1.DockerBasedBuildType, "oak_functions_freestanding_bin",
+			WithBinaryDigests(intoto.DigestSet{"sha2-256": "d059c38cea82047ad316a1c6c6fbd13ecf7a0abdcc375463920bd25bf5c142cc"}),
+			WithBuildCmd([]string{"cargo", "build"}),
+			WithBuilderImageSHA256Digest("51532c757d1008bbff696d053a1d05226f6387cf232aa80b6f9c13b0759ccea0"),
+			WithBuilderImageDigests(intoto.DigestSet{"sha2-256": "51532c757d1008bbff696d053a1d05226f6387cf232aa80b6f9c13b0759ccea0"}),
+			WithRepoURI("git+https://github.com/project-oak/oak"),
+			WithCommitSHA1Digest("1b128fb2556e4bdcc4f92552654bfbca9d2fb8c6"),
+			WithCommitDigests(intoto.DigestSet{"sha1": "1b128fb2556e4bdcc4f92552654bfbca9d2fb8c6"}),
+			WithTreeDigests(intoto.DigestSet{"sha1": "4b825dc642cb6eb9a060e54bf8d69288fbee4904"}),
+			WithSourceRef("refs/tags/v1.2.3"),
+			WithTrustedBuilder("https://github.com/slsa-framework/slsa-github-generator/.github/workflows/builder_docker-based_slsa3.yml@refs/tags/v1.6.0"),
+			WithBuildStartedOn(time.Date(2023, 6, 1, 11, 30, 0, 0, time.UTC)),
+			WithBuildFinishedOn(time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)),
+			WithCertificateIdentity(CertificateIdentity{
+				SubjectAlternativeName: "https://github.com/project-oak/oak/.github/workflows/build.yml@refs/heads/main",
+				Issuer:                 "https://token.actions.githubusercontent.com",
+			}),
+			WithToolchainVersions(map[string]string{"rustc": "1.69.0"}),
+			WithByproducts([]Byproduct{{Name: "build.log", URI: "https://example.com/build.log", Digests: intoto.DigestSet{"sha2-256": "9e5c8e7f2a7d1a4f3b6c0d8e9f1a2b3c4d5e6f708192a3b4c5d6e7f8091a2b3c"}}}),
+			WithBuildMetadata(BuildMetadata{InvocationID: "3230206088-1", ParametersComplete: true, EnvironmentComplete: true, MaterialsComplete: true, Reproducible: true}),
+		),
+		"optional fields unset": NewProvenanceIR("d059c38cea82047ad316a1c6c6fbd13ecf7a0abdcc375463920bd25bf5c142cc",
+			slsav02.GenericSLSABuildType, "oak_functions_freestanding_bin"),
+		// Empty values of optional fields are distinct from absent ones.
+		"empty optional fields": NewProvenanceIR("d059c38cea82047ad316a1c6c6fbd13ecf7a0abdcc375463920bd25bf5c142cc",
+			slsav02.GenericSLSABuildType, "",
+			WithBuildCmd([]string{}),
+			WithRepoURI(""),
+			WithToolchainVersions(map[string]string{}),
+			WithByproducts([]Byproduct{}),
+			WithBuildMetadata(BuildMetadata{}),
+		),
+	}
+
+	for name, provenance := range provenances {
+		data, err := proto.Marshal(provenance.ToProto())
+		if err != nil {
+			t.Fatalf("%s: could not marshal the provenance: %v", name, err)
+		}
+		var message pb.ProvenanceIR
+		if err := proto.Unmarshal(data, &message); err != nil {
+			t.Fatalf("%s: could not unmarshal the provenance: %v", name, err)
+		}
+		got, err := FromProto(&message)
+		if err != nil {
+			t.Fatalf("%s: could not convert the provenance: %v", name, err)
+		}
+		if diff := cmp.Diff(got.Export(), provenance.Export()); diff != "" {
+			t.Errorf("%s: unexpected fields after the round trip: %s", name, diff)
+		}
+	}
+}
+
+func TestProto_Gob(t *testing.T) {
+	provenance := NewProvenanceIR("d059c38cea82047ad316a1c6c6fbd13ecf7a0abdcc375463920bd25bf5c142cc",
+		slsav1.DockerBasedBuildType, "oak_functions_freestanding_bin",
+		WithBuildCmd([]string{"cargo", "build"}),
+	)
+
+	var buffer bytes.Buffer
+	if err := gob.NewEncoder(&buffer).Encode(provenance); err != nil {
+		t.Fatalf("could not encode the provenance: %v", err)
+	}
+	var got ProvenanceIR
+	if err := gob.NewDecoder(&buffer).Decode(&got); err != nil {
+		t.Fatalf("could not decode the provenance: %v", err)
+	}
+	if diff := cmp.Diff(got.Export(), provenance.Export()); diff != "" {
+		t.Errorf("unexpected fields after decoding: %s", diff)
+	}
+}
+
+func TestFromProto_MissingBinaryDigest(t *testing.T) {
+	if _, err := FromProto(&pb.ProvenanceIR{BinaryName: proto.String("binary")}); err == nil {
+		t.Errorf("expected an error for a missing binary digest")
+	}
+}
@@ -17,9 +17,13 @@ package model
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/project-oak/transparent-release/internal/testutil"
+	"github.com/project-oak/transparent-release/pkg/intoto"
 	slsav02 "github.com/project-oak/transparent-release/pkg/intoto/slsa_provenance/v0.2"
 	slsav1 "github.com/project-oak/transparent-release/pkg/intoto/slsa_provenance/v1"
 )
@@ -28,6 +32,7 @@ const (
 	testdataPath          = "../../testdata/"
 	slsav02ProvenancePath = "slsa_v02_provenance.json"
 	slsav1ProvenancePath  = "slsa_v1_provenance.json"
+	toolchainsProvenance  = "slsa_v1_provenance_with_toolchains.json"
 	wantTOMLDigest        = "322527c0260e25f0e9a2595bd0d71a52294fe2397a7af76165190fd98de8920d"
 )
 
@@ -55,9 +60,13 @@ func TestFromProvenance_Slsav02(t *testing.T) {
 
 	want := NewProvenanceIR("d059c38cea82047ad316a1c6c6fbd13ecf7a0abdcc375463920bd25bf5c142cc",
 		slsav02.GenericSLSABuildType, "oak_functions_freestanding_bin",
+		WithBinaryDigests(intoto.DigestSet{"sha2-256": "d059c38cea82047ad316a1c6c6fbd13ecf7a0abdcc375463920bd25bf5c142cc"}),
 		WithRepoURI("git+https://github.com/project-oak/oak@refs/heads/main"),
 		WithCommitSHA1Digest("1b128fb2556e4bdcc4f92552654bfbca9d2fb8c6"),
+		WithCommitDigests(intoto.DigestSet{"sha1": "1b128fb2556e4bdcc4f92552654bfbca9d2fb8c6"}),
+		WithSourceRef("refs/heads/main"),
 		WithTrustedBuilder("https://github.com/slsa-framework/slsa-github-generator/.github/workflows/generator_generic_slsa3.yml@refs/tags/v1.2.0"),
+		WithBuildMetadata(BuildMetadata{InvocationID: "3230206088-1", ParametersComplete: true}),
 	)
 
 	got, err := FromValidatedProvenance(provenance)
@@ -83,6 +92,7 @@ func TestFromProvenance_Slsav1(t *testing.T) {
 
 	want := NewProvenanceIR("813841dda3818d616aa3e706e49d0286dc825c5dbad4a75cfb37b91ba412238b",
 		slsav1.DockerBasedBuildType, "oak_functions_enclave_app",
+		WithBinaryDigests(intoto.DigestSet{"sha2-256": "813841dda3818d616aa3e706e49d0286dc825c5dbad4a75cfb37b91ba412238b"}),
 		WithBuildCmd([]string{
 			"env",
 			"--chdir=oak_functions_enclave_app",
@@ -91,9 +101,12 @@ func TestFromProvenance_Slsav1(t *testing.T) {
 			"--release",
 		}),
 		WithBuilderImageSHA256Digest("51532c757d1008bbff696d053a1d05226f6387cf232aa80b6f9c13b0759ccea0"),
+		WithBuilderImageDigests(intoto.DigestSet{"sha2-256": "51532c757d1008bbff696d053a1d05226f6387cf232aa80b6f9c13b0759ccea0"}),
 		WithRepoURI("git+https://github.com/project-oak/oak"),
 		WithCommitSHA1Digest("6bac02b6b0442ed944f57b7cba9a5f1119863ca4"),
+		WithCommitDigests(intoto.DigestSet{"sha1": "6bac02b6b0442ed944f57b7cba9a5f1119863ca4"}),
 		WithTrustedBuilder("https://github.com/slsa-framework/slsa-github-generator/.github/workflows/builder_docker-based_slsa3.yml@refs/tags/v1.6.0-rc.0"),
+		WithBuildMetadata(BuildMetadata{InvocationID: "https://github.com/project-oak/oak/actions/runs/4755980100/attempts/1"}),
 	)
 
 	got, err := FromValidatedProvenance(provenance)
@@ -105,3 +118,336 @@ func TestFromProvenance_Slsav1(t *testing.T) {
 		t.Errorf("unexpected provenanceIR: %s", diff)
 	}
 }
+
+func TestFromProvenance_Slsav1WithToolchainVersions(t *testing.T) {
+	statementBytes, err := os.ReadFile(filepath.Join(testdataPath, toolchainsProvenance))
+	if err != nil {
+		t.Fatalf("could not read the provenance file: %v", err)
+	}
+	provenance, err := ParseStatementData(statementBytes)
+	if err != nil {
+		t.Fatalf("couldn't parse the provenance file: %v", err)
+	}
+
+	got, err := FromValidatedProvenance(provenance)
+	if err != nil {
+		t.Fatalf("couldn't map provenance to ProvenanceIR: %v", err)
+	}
+	versions, err := got.ToolchainVersions()
+	if err != nil {
+		t.Fatalf("couldn't get toolchain versions: %v", err)
+	}
+	// The resolved dependency without a toolchain version is ignored.
+	if diff := cmp.Diff(versions, map[string]string{"cargo": "1.69.0", "rustc": "1.69.0"}); diff != "" {
+		t.Errorf("unexpected toolchain versions: %s", diff)
+	}
+}
+
+func TestSupportedFormats_ContainsTestdataProvenances(t *testing.T) {
+	supported := make(map[SupportedFormat]bool)
+	for _, format := range SupportedFormats() {
+		supported[format] = true
+	}
+
+	for _, name := range []string{slsav02ProvenancePath, slsav1ProvenancePath} {
+		statementBytes, err := os.ReadFile(filepath.Join(testdataPath, name))
+		if err != nil {
+			t.Fatalf("could not read the provenance file: %v", err)
+		}
+		provenance, err := ParseStatementData(statementBytes)
+		if err != nil {
+			t.Fatalf("couldn't parse the provenance file: %v", err)
+		}
+		provenanceIR, err := FromValidatedProvenance(provenance)
+		if err != nil {
+			t.Fatalf("couldn't map provenance to ProvenanceIR: %v", err)
+		}
+
+		format := SupportedFormat{PredicateType: provenance.PredicateType(), BuildType: provenanceIR.BuildType()}
+		if !supported[format] {
+			t.Errorf("format of %s (%v) is missing from SupportedFormats", name, format)
+		}
+	}
+}
+
+func TestFromProvenance_Slsav1BuilderImageDigests(t *testing.T) {
+	statementBytes, err := os.ReadFile(filepath.Join(testdataPath, slsav1ProvenancePath))
+	if err != nil {
+		t.Fatalf("could not read the provenance file: %v", err)
+	}
+	sha256Digest := `"sha256": "51532c757d1008bbff696d053a1d05226f6387cf232aa80b6f9c13b0759ccea0"`
+	if !strings.Contains(string(statementBytes), sha256Digest) {
+		t.Fatalf("the provenance file does not contain the builder image digest")
+	}
+
+	// A builder image with only a SHA512 digest is mapped without a SHA256
+	// digest.
+	sha512Digest := strings.Repeat("ab", 64)
+	provenance, err := ParseStatementData([]byte(strings.Replace(string(statementBytes), sha256Digest, `"sha512": "`+sha512Digest+`"`, 1)))
+	if err != nil {
+		t.Fatalf("couldn't parse the provenance: %v", err)
+	}
+	got, err := FromValidatedProvenance(provenance)
+	if err != nil {
+		t.Fatalf("couldn't map provenance to ProvenanceIR: %v", err)
+	}
+	testutil.AssertEq(t, "has builder image SHA256 digest", got.HasBuilderImageSHA256Digest(), false)
+	if diff := cmp.Diff(got.BuilderImageDigests(), intoto.DigestSet{"sha2-512": sha512Digest}); diff != "" {
+		t.Errorf("unexpected builder image digests: %s", diff)
+	}
+
+	// A builder image without supported digests is rejected.
+	provenance, err = ParseStatementData([]byte(strings.Replace(string(statementBytes), sha256Digest, `"md5": "0123"`, 1)))
+	if err != nil {
+		t.Fatalf("couldn't parse the provenance: %v", err)
+	}
+	if _, err := FromValidatedProvenance(provenance); err == nil || !strings.Contains(err.Error(), "[md5]") {
+		t.Errorf("expected an error naming the algorithms of the builder image digest, got: %v", err)
+	}
+}
+
+func TestFromProvenance_Slsav1CommitDigests(t *testing.T) {
+	statementBytes, err := os.ReadFile(filepath.Join(testdataPath, slsav1ProvenancePath))
+	if err != nil {
+		t.Fatalf("could not read the provenance file: %v", err)
+	}
+	sha1Digest := `"sha1": "6bac02b6b0442ed944f57b7cba9a5f1119863ca4"`
+	if !strings.Contains(string(statementBytes), sha1Digest) {
+		t.Fatalf("the provenance file does not contain the commit digest")
+	}
+
+	// A commit in a repo with the SHA-256 Git object format only has a SHA256
+	// digest.
+	sha256Digest := strings.Repeat("cd", 32)
+	provenance, err := ParseStatementData([]byte(strings.Replace(string(statementBytes), sha1Digest, `"sha256": "`+sha256Digest+`"`, 1)))
+	if err != nil {
+		t.Fatalf("couldn't parse the provenance: %v", err)
+	}
+	got, err := FromValidatedProvenance(provenance)
+	if err != nil {
+		t.Fatalf("couldn't map provenance to ProvenanceIR: %v", err)
+	}
+	testutil.AssertEq(t, "commit SHA1 digest", got.CommitSHA1Digest(), "")
+	if diff := cmp.Diff(got.CommitDigests(), intoto.DigestSet{"sha2-256": sha256Digest}); diff != "" {
+		t.Errorf("unexpected commit digests: %s", diff)
+	}
+}
+
+func TestFromProvenance_Slsav1TreeDigests(t *testing.T) {
+	statementBytes, err := os.ReadFile(filepath.Join(testdataPath, slsav1ProvenancePath))
+	if err != nil {
+		t.Fatalf("could not read the provenance file: %v", err)
+	}
+	sha1Digest := `"sha1": "6bac02b6b0442ed944f57b7cba9a5f1119863ca4"`
+	if !strings.Contains(string(statementBytes), sha1Digest) {
+		t.Fatalf("the provenance file does not contain the commit digest")
+	}
+
+	provenance, err := ParseStatementData(statementBytes)
+	if err != nil {
+		t.Fatalf("couldn't parse the provenance: %v", err)
+	}
+	got, err := FromValidatedProvenance(provenance)
+	if err != nil {
+		t.Fatalf("couldn't map provenance to ProvenanceIR: %v", err)
+	}
+	testutil.AssertEq(t, "has tree digests", got.HasTreeDigests(), false)
+
+	// The tree digest is recorded next to the commit digest, but is not one of
+	// its digests.
+	treeDigest := "4b825dc642cb6eb9a060e54bf8d69288fbee4904"
+	provenance, err = ParseStatementData([]byte(strings.Replace(string(statementBytes), sha1Digest, sha1Digest+`, "gitTree": "`+treeDigest+`"`, 1)))
+	if err != nil {
+		t.Fatalf("couldn't parse the provenance: %v", err)
+	}
+	got, err = FromValidatedProvenance(provenance)
+	if err != nil {
+		t.Fatalf("couldn't map provenance to ProvenanceIR: %v", err)
+	}
+	if diff := cmp.Diff(got.TreeDigests(), intoto.DigestSet{"sha1": treeDigest}); diff != "" {
+		t.Errorf("unexpected tree digests: %s", diff)
+	}
+	if diff := cmp.Diff(got.CommitDigests(), intoto.DigestSet{"sha1": "6bac02b6b0442ed944f57b7cba9a5f1119863ca4"}); diff != "" {
+		t.Errorf("unexpected commit digests: %s", diff)
+	}
+
+	// A tree digest that is neither a SHA1 nor a SHA256 digest is rejected.
+	provenance, err = ParseStatementData([]byte(strings.Replace(string(statementBytes), sha1Digest, sha1Digest+`, "gitTree": "abcd"`, 1)))
+	if err != nil {
+		t.Fatalf("couldn't parse the provenance: %v", err)
+	}
+	if _, err := FromValidatedProvenance(provenance); err == nil || !strings.Contains(err.Error(), "invalid tree digest") {
+		t.Errorf("expected an invalid tree digest, got: %v", err)
+	}
+}
+
+func TestFromProvenance_Slsav1SourceRef(t *testing.T) {
+	statementBytes, err := os.ReadFile(filepath.Join(testdataPath, slsav1ProvenancePath))
+	if err != nil {
+		t.Fatalf("could not read the provenance file: %v", err)
+	}
+	sourceURI := `"uri": "git+https://github.com/project-oak/oak"`
+	if !strings.Contains(string(statementBytes), sourceURI) {
+		t.Fatalf("the provenance file does not contain the source URI")
+	}
+
+	provenance, err := ParseStatementData([]byte(strings.Replace(string(statementBytes), sourceURI, `"uri": "git+https://github.com/project-oak/oak@refs/tags/v1.2.3"`, 1)))
+	if err != nil {
+		t.Fatalf("couldn't parse the provenance: %v", err)
+	}
+	got, err := FromValidatedProvenance(provenance)
+	if err != nil {
+		t.Fatalf("couldn't map provenance to ProvenanceIR: %v", err)
+	}
+	sourceRef, err := got.SourceRef()
+	if err != nil {
+		t.Fatalf("no source ref: %v", err)
+	}
+	testutil.AssertEq(t, "source ref", sourceRef, "refs/tags/v1.2.3")
+}
+
+func TestFromProvenance_Slsav1BuildTimes(t *testing.T) {
+	statementBytes, err := os.ReadFile(filepath.Join(testdataPath, slsav1ProvenancePath))
+	if err != nil {
+		t.Fatalf("could not read the provenance file: %v", err)
+	}
+	invocationID := `"invocationId": "https://github.com/project-oak/oak/actions/runs/4755980100/attempts/1"`
+	if !strings.Contains(string(statementBytes), invocationID) {
+		t.Fatalf("the provenance file does not contain the invocation ID")
+	}
+
+	buildTimes := invocationID + `, "startedOn": "2023-06-01T11:30:00Z", "finishedOn": "2023-06-01T12:00:00Z"`
+	provenance, err := ParseStatementData([]byte(strings.Replace(string(statementBytes), invocationID, buildTimes, 1)))
+	if err != nil {
+		t.Fatalf("couldn't parse the provenance: %v", err)
+	}
+	got, err := FromValidatedProvenance(provenance)
+	if err != nil {
+		t.Fatalf("couldn't map provenance to ProvenanceIR: %v", err)
+	}
+	startedOn, err := got.BuildStartedOn()
+	if err != nil {
+		t.Fatalf("no build start time: %v", err)
+	}
+	testutil.AssertEq(t, "build start time", startedOn, time.Date(2023, 6, 1, 11, 30, 0, 0, time.UTC))
+	finishedOn, err := got.BuildFinishedOn()
+	if err != nil {
+		t.Fatalf("no build finish time: %v", err)
+	}
+	testutil.AssertEq(t, "build finish time", finishedOn, time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC))
+}
+
+func TestFromProvenance_Slsav1Byproducts(t *testing.T) {
+	statementBytes, err := os.ReadFile(filepath.Join(testdataPath, slsav1ProvenancePath))
+	if err != nil {
+		t.Fatalf("could not read the provenance file: %v", err)
+	}
+	invocationID := `"invocationId": "https://github.com/project-oak/oak/actions/runs/4755980100/attempts/1"
+            }`
+	if !strings.Contains(string(statementBytes), invocationID) {
+		t.Fatalf("the provenance file does not contain the invocation ID")
+	}
+
+	byproducts := invocationID + `, "byproducts": [{"name": "build.log", "uri": "https://example.com/build.log", "digest": {"sha256": "9e5c8e7f2a7d1a4f3b6c0d8e9f1a2b3c4d5e6f708192a3b4c5d6e7f8091a2b3c"}}]`
+	provenance, err := ParseStatementData([]byte(strings.Replace(string(statementBytes), invocationID, byproducts, 1)))
+	if err != nil {
+		t.Fatalf("couldn't parse the provenance: %v", err)
+	}
+	got, err := FromValidatedProvenance(provenance)
+	if err != nil {
+		t.Fatalf("couldn't map provenance to ProvenanceIR: %v", err)
+	}
+	gotByproducts, err := got.Byproducts()
+	if err != nil {
+		t.Fatalf("no byproducts: %v", err)
+	}
+	want := []Byproduct{{
+		Name:    "build.log",
+		URI:     "https://example.com/build.log",
+		Digests: intoto.DigestSet{"sha2-256": "9e5c8e7f2a7d1a4f3b6c0d8e9f1a2b3c4d5e6f708192a3b4c5d6e7f8091a2b3c"},
+	}}
+	if diff := cmp.Diff(gotByproducts, want); diff != "" {
+		t.Errorf("unexpected byproducts: %s", diff)
+	}
+}
+
+func TestCommitDigests_FallsBackToSHA1Digest(t *testing.T) {
+	provenance := NewProvenanceIR("d059c38cea82047ad316a1c6c6fbd13ecf7a0abdcc375463920bd25bf5c142cc",
+		slsav02.GenericSLSABuildType, "oak_functions_freestanding_bin",
+		WithCommitSHA1Digest("1b128fb2556e4bdcc4f92552654bfbca9d2fb8c6"),
+	)
+	if diff := cmp.Diff(provenance.CommitDigests(), intoto.DigestSet{"sha1": "1b128fb2556e4bdcc4f92552654bfbca9d2fb8c6"}); diff != "" {
+		t.Errorf("unexpected commit digests: %s", diff)
+	}
+}
+
+func TestExport_AllFieldsSet(t *testing.T) {
+	provenance := NewProvenanceIR("d059c38cea82047ad316a1c6c6fbd13ecf7a0abdcc375463920bd25bf5c142cc",
+		slsav1.DockerBasedBuildType, "oak_functions_freestanding_bin",
+		WithBinaryDigests(intoto.DigestSet{"sha2-256": "d059c38cea82047ad316a1c6c6fbd13ecf7a0abdcc375463920bd25bf5c142cc"}),
+		WithBuildCmd([]string{"cargo", "build"}),
+		WithBuilderImageSHA256Digest("51532c757d1008bbff696d053a1d05226f6387cf232aa80b6f9c13b0759ccea0"),
+		WithBuilderImageDigests(intoto.DigestSet{"sha2-256": "51532c757d1008bbff696d053a1d05226f6387cf232aa80b6f9c13b0759ccea0"}),
+		WithRepoURI("git+https://github.com/project-oak/oak"),
+		WithCommitSHA1Digest("1b128fb2556e4bdcc4f92552654bfbca9d2fb8c6"),
+		WithCommitDigests(intoto.DigestSet{"sha1": "1b128fb2556e4bdcc4f92552654bfbca9d2fb8c6"}),
+		WithSourceRef("refs/tags/v1.2.3"),
+		WithTrustedBuilder("https://github.com/slsa-framework/slsa-github-generator/.github/workflows/builder_docker-based_slsa3.yml@refs/tags/v1.6.0"),
+		WithBuildStartedOn(time.Date(2023, 6, 1, 11, 30, 0, 0, time.UTC)),
+		WithBuildFinishedOn(time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)),
+		WithToolchainVersions(map[string]string{"rustc": "1.69.0"}),
+		WithByproducts([]Byproduct{{Name: "build.log", URI: "https://example.com/build.log", Digests: intoto.DigestSet{"sha2-256": "9e5c8e7f2a7d1a4f3b6c0d8e9f1a2b3c4d5e6f708192a3b4c5d6e7f8091a2b3c"}}}),
+		WithBuildMetadata(BuildMetadata{InvocationID: "3230206088-1", MaterialsComplete: true, Reproducible: true}),
+	)
+
+	want := ProvenanceFields{
+		BinarySHA256Digest:          "d059c38cea82047ad316a1c6c6fbd13ecf7a0abdcc375463920bd25bf5c142cc",
+		BuildType:                   slsav1.DockerBasedBuildType,
+		BinaryName:                  "oak_functions_freestanding_bin",
+		BinaryDigests:               map[string]string{"sha2-256": "d059c38cea82047ad316a1c6c6fbd13ecf7a0abdcc375463920bd25bf5c142cc"},
+		HasBinaryDigests:            true,
+		BuildCmd:                    []string{"cargo", "build"},
+		HasBuildCmd:                 true,
+		BuilderImageSHA256Digest:    "51532c757d1008bbff696d053a1d05226f6387cf232aa80b6f9c13b0759ccea0",
+		HasBuilderImageSHA256Digest: true,
+		BuilderImageDigests:         map[string]string{"sha2-256": "51532c757d1008bbff696d053a1d05226f6387cf232aa80b6f9c13b0759ccea0"},
+		HasBuilderImageDigests:      true,
+		RepoURI:                     "git+https://github.com/project-oak/oak",
+		HasRepoURI:                  true,
+		CommitSHA1Digest:            "1b128fb2556e4bdcc4f92552654bfbca9d2fb8c6",
+		HasCommitSHA1Digest:         true,
+		CommitDigests:               map[string]string{"sha1": "1b128fb2556e4bdcc4f92552654bfbca9d2fb8c6"},
+		HasCommitDigests:            true,
+		SourceRef:                   "refs/tags/v1.2.3",
+		HasSourceRef:                true,
+		TrustedBuilder:              "https://github.com/slsa-framework/slsa-github-generator/.github/workflows/builder_docker-based_slsa3.yml@refs/tags/v1.6.0",
+		HasTrustedBuilder:           true,
+		BuildStartedOn:              time.Date(2023, 6, 1, 11, 30, 0, 0, time.UTC),
+		HasBuildStartedOn:           true,
+		BuildFinishedOn:             time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC),
+		HasBuildFinishedOn:          true,
+		ToolchainVersions:           map[string]string{"rustc": "1.69.0"},
+		HasToolchainVersions:        true,
+		Byproducts:                  []Byproduct{{Name: "build.log", URI: "https://example.com/build.log", Digests: intoto.DigestSet{"sha2-256": "9e5c8e7f2a7d1a4f3b6c0d8e9f1a2b3c4d5e6f708192a3b4c5d6e7f8091a2b3c"}}},
+		HasByproducts:               true,
+		BuildMetadata:               BuildMetadata{InvocationID: "3230206088-1", MaterialsComplete: true, Reproducible: true},
+		HasBuildMetadata:            true,
+	}
+	if diff := cmp.Diff(provenance.Export(), want); diff != "" {
+		t.Errorf("unexpected exported fields: %s", diff)
+	}
+}
+
+func TestExport_OptionalFieldsUnset(t *testing.T) {
+	provenance := NewProvenanceIR("d059c38cea82047ad316a1c6c6fbd13ecf7a0abdcc375463920bd25bf5c142cc",
+		slsav02.GenericSLSABuildType, "oak_functions_freestanding_bin")
+
+	want := ProvenanceFields{
+		BinarySHA256Digest: "d059c38cea82047ad316a1c6c6fbd13ecf7a0abdcc375463920bd25bf5c142cc",
+		BuildType:          slsav02.GenericSLSABuildType,
+		BinaryName:         "oak_functions_freestanding_bin",
+	}
+	if diff := cmp.Diff(provenance.Export(), want); diff != "" {
+		t.Errorf("unexpected exported fields: %s", diff)
+	}
+}
@@ -17,6 +17,7 @@ package model
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
 
 	"github.com/project-oak/transparent-release/pkg/intoto"
 	"github.com/secure-systems-lab/go-securesystemslib/dsse"
@@ -30,12 +31,83 @@ type sigstoreBundle struct {
 	DSSEEnvelope *dsse.Envelope `json:"dsseEnvelope"`
 }
 
+// supportedDigestAlgorithms contains the canonical keys of the digests
+// supported in ValidatedProvenance and ProvenanceIR.
+//
+//nolint:gochecknoglobals
+var supportedDigestAlgorithms = map[string]bool{
+	"sha1":     true,
+	"sha2-256": true,
+	"sha2-384": true,
+	"sha2-512": true,
+	"sha3-224": true,
+	"sha3-256": true,
+	"sha3-384": true,
+	"sha3-512": true,
+}
+
+// NormalizeDigestSet returns a copy of the given DigestSet, containing only
+// the digests with supported algorithms, keyed by their canonical names
+// ("sha1", "sha2-256", "sha2-384", "sha2-512", and "sha3-224" to
+// "sha3-512"). Both the names used by in-toto (e.g., "sha512" or "sha3_256")
+// and the canonical names are accepted. Returns an
+// error if the digest set contains two different digests for the same
+// algorithm.
+func NormalizeDigestSet(digestSet intoto.DigestSet) (intoto.DigestSet, error) {
+	converted, err := intoto.ConvertDigestSet(digestSet, intoto.CanonicalDigestKeys)
+	if err != nil {
+		return nil, err
+	}
+	normalized := make(intoto.DigestSet)
+	for algorithm, value := range converted {
+		if supportedDigestAlgorithms[algorithm] {
+			normalized[algorithm] = value
+		}
+	}
+	return normalized, nil
+}
+
+// NormalizeSupportedDigestSet is like NormalizeDigestSet, but fails closed:
+// it returns an error listing the algorithms in the given digest set if none
+// of them is supported, instead of an empty digest set.
+func NormalizeSupportedDigestSet(digestSet intoto.DigestSet) (intoto.DigestSet, error) {
+	normalized, err := NormalizeDigestSet(digestSet)
+	if err != nil {
+		return nil, err
+	}
+	if len(normalized) == 0 {
+		supported := make([]string, 0, len(supportedDigestAlgorithms))
+		for algorithm := range supportedDigestAlgorithms {
+			supported = append(supported, algorithm)
+		}
+		sort.Strings(supported)
+		return nil, fmt.Errorf("no digest with a supported algorithm: got %v, want one of %v", DigestAlgorithms(digestSet), supported)
+	}
+	return normalized, nil
+}
+
+// DigestAlgorithms returns the sorted algorithms of the given digest set.
+func DigestAlgorithms(digestSet intoto.DigestSet) []string {
+	algorithms := make([]string, 0, len(digestSet))
+	for algorithm := range digestSet {
+		algorithms = append(algorithms, algorithm)
+	}
+	sort.Strings(algorithms)
+	return algorithms
+}
+
 // ValidatedProvenance wraps an intoto.Statement representing a valid SLSA
-// provenance statement. A provenance statement is valid if it contains a
-// single subject, with a SHA2-256 hash.
+// provenance statement. A provenance statement is valid if it contains one or
+// more subjects, each with at least one digest with a supported algorithm.
+//
+// A provenance with several subjects attests to several binaries. Only
+// provenances with a single subject can be mapped to ProvenanceIR; use
+// SelectSubject to select the relevant subject from the others.
 type ValidatedProvenance struct {
-	// The field is private so that invalid instances cannot be created.
+	// The fields are private so that invalid instances cannot be created.
 	provenance intoto.Statement
+	// subjectDigests contains the normalized digests of each subject.
+	subjectDigests []intoto.DigestSet
 }
 
 // FindBinarySHA256Digest looks for a "sha256" or "sha2-256" entry in the input
@@ -59,15 +131,89 @@ func FindBinarySHA256Digest(digestSet intoto.DigestSet) string {
 // instance of ValidatedProvenance wrapping it if it is valid, or an error
 // otherwise.
 func NewValidatedProvenance(provenance intoto.Statement) (*ValidatedProvenance, error) {
-	return &ValidatedProvenance{provenance: provenance}, nil
+	if len(provenance.Subject) == 0 {
+		return nil, fmt.Errorf("the provenance must have at least one subject")
+	}
+	subjectDigests := make([]intoto.DigestSet, 0, len(provenance.Subject))
+	for i, subject := range provenance.Subject {
+		digests, err := NormalizeSupportedDigestSet(subject.Digest)
+		if err != nil {
+			return nil, fmt.Errorf("invalid digest of subject #%d: %v", i, err)
+		}
+		subjectDigests = append(subjectDigests, digests)
+	}
+	return &ValidatedProvenance{provenance: provenance, subjectDigests: subjectDigests}, nil
 }
 
-// GetBinarySHA256Digest returns the SHA256 digest of the subject.
+// SubjectCount returns the number of subjects in the provenance.
+func (p *ValidatedProvenance) SubjectCount() int {
+	return len(p.provenance.Subject)
+}
+
+// SelectSubject returns a copy of this provenance, with the given subject as
+// its only subject. The subject is selected by its name, by its digests, or
+// both; an empty name or digest set matches any subject. A subject matches the
+// given digests if it agrees with them on at least one algorithm, and on all
+// algorithms they share. Returns an error unless exactly one subject matches.
+func (p *ValidatedProvenance) SelectSubject(name string, digests intoto.DigestSet) (*ValidatedProvenance, error) {
+	wantDigests, err := NormalizeDigestSet(digests)
+	if err != nil {
+		return nil, fmt.Errorf("invalid digests: %v", err)
+	}
+
+	selected := -1
+	for i, subject := range p.provenance.Subject {
+		if name != "" && subject.Name != name {
+			continue
+		}
+		if len(wantDigests) != 0 && !SameDigests(p.subjectDigests[i], wantDigests) {
+			continue
+		}
+		if selected >= 0 {
+			return nil, fmt.Errorf("more than one subject matches name %q and digests %v", name, digests)
+		}
+		selected = i
+	}
+	if selected < 0 {
+		return nil, fmt.Errorf("no subject matches name %q and digests %v", name, digests)
+	}
+
+	statement := p.provenance
+	statement.Subject = []intoto.Subject{p.provenance.Subject[selected]}
+	return &ValidatedProvenance{
+		provenance:     statement,
+		subjectDigests: []intoto.DigestSet{p.subjectDigests[selected]},
+	}, nil
+}
+
+// SameDigests returns true if the given digest sets share at least one
+// algorithm, and agree on the digests of all shared algorithms.
+func SameDigests(a, b intoto.DigestSet) bool {
+	shared := false
+	for algorithm, digest := range a {
+		if other, ok := b[algorithm]; ok {
+			if other != digest {
+				return false
+			}
+			shared = true
+		}
+	}
+	return shared
+}
+
+// GetBinarySHA256Digest returns the SHA256 digest of the first subject, or an
+// empty string if the subject does not have a SHA256 digest.
 func (p *ValidatedProvenance) GetBinarySHA256Digest() string {
-	return FindBinarySHA256Digest(p.provenance.Subject[0].Digest)
+	return p.subjectDigests[0]["sha2-256"]
 }
 
-// GetBinaryName returns the name of the subject.
+// GetBinaryDigests returns a copy of all digests of the first subject, keyed
+// by their canonical names, as returned by NormalizeDigestSet.
+func (p *ValidatedProvenance) GetBinaryDigests() intoto.DigestSet {
+	return copyDigestSet(p.subjectDigests[0])
+}
+
+// GetBinaryName returns the name of the first subject.
 func (p *ValidatedProvenance) GetBinaryName() string {
 	return p.provenance.Subject[0].Name
 }
@@ -80,15 +226,19 @@ func (p *ValidatedProvenance) PredicateType() string {
 // GetProvenance returns a partial copy of the provenance statement wrapped in this instance.
 // The partial copy guarantees that the validity condition will not be violated.
 func (p *ValidatedProvenance) GetProvenance() intoto.Statement {
-	subject := intoto.Subject{
-		Name:   p.provenance.Subject[0].Name,
-		Digest: intoto.DigestSet{"sha256": p.provenance.Subject[0].Digest["sha256"]},
+	subjects := make([]intoto.Subject, 0, len(p.provenance.Subject))
+	for i, subject := range p.provenance.Subject {
+		subjects = append(subjects, intoto.Subject{
+			Name:      subject.Name,
+			Digest:    copyDigestSet(p.subjectDigests[i]),
+			MediaType: subject.MediaType,
+		})
 	}
 
 	statementHeader := intoto.StatementHeader{
 		Type:          p.provenance.Type,
 		PredicateType: p.provenance.PredicateType,
-		Subject:       []intoto.Subject{subject},
+		Subject:       subjects,
 	}
 
 	return intoto.Statement{
@@ -97,20 +247,28 @@ func (p *ValidatedProvenance) GetProvenance() intoto.Statement {
 	}
 }
 
+func copyDigestSet(digestSet intoto.DigestSet) intoto.DigestSet {
+	digests := make(intoto.DigestSet, len(digestSet))
+	for key, value := range digestSet {
+		digests[key] = value
+	}
+	return digests
+}
+
 // ParseStatementData validates that the given bytes represent a valid intoto
-// Statement containing a single subject and its SHA256 digest. Returns an
-// instance of ValidatedProvenance, or an error if the above checks fail.
+// Statement containing one or more subjects, each with at least one digest
+// with a supported algorithm. Returns an instance of ValidatedProvenance, or an error
+// if the above checks fail.
 func ParseStatementData(statementBytes []byte) (*ValidatedProvenance, error) {
 	var statement intoto.Statement
 	if err := json.Unmarshal(statementBytes, &statement); err != nil {
 		return nil, fmt.Errorf("could not unmarshal the provenance file:\n%v", err)
 	}
-
-	if len(statement.Subject) != 1 || statement.Subject[0].Digest["sha256"] == "" {
-		return nil, fmt.Errorf("the provenance must have exactly one subject with a sha256 digest")
+	if err := intoto.NormalizeStatementHeader(&statement.StatementHeader); err != nil {
+		return nil, fmt.Errorf("invalid provenance statement: %v", err)
 	}
 
-	return &ValidatedProvenance{provenance: statement}, nil
+	return NewValidatedProvenance(statement)
 }
 
 // ParseEnvelope (1) parses the given bytes as a DSSE envelope; (2) if that is
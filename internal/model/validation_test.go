@@ -16,6 +16,7 @@ package model
 
 import (
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/project-oak/transparent-release/internal/testutil"
@@ -51,3 +52,107 @@ func TestParseStatementData(t *testing.T) {
 	testutil.AssertEq(t, "subjectName", validatedProvenance.GetBinaryName(), "oak_functions_freestanding_bin")
 	testutil.AssertNonEmpty(t, "builderId", predicate.Builder.ID)
 }
+
+func TestParseStatementData_SHA512Only(t *testing.T) {
+	statementBytes := []byte(`{
+		"_type": "https://in-toto.io/Statement/v0.1",
+		"predicateType": "https://slsa.dev/provenance/v0.2",
+		"subject": [{"name": "pkg.tgz", "digest": {"sha512": "ABCDEF", "md5": "0123"}}],
+		"predicate": {}
+	}`)
+
+	validatedProvenance, err := ParseStatementData(statementBytes)
+	if err != nil {
+		t.Fatalf("Failed to parse provenance: %v", err)
+	}
+
+	testutil.AssertEq(t, "sha256 digest", validatedProvenance.GetBinarySHA256Digest(), "")
+	testutil.AssertEq(t, "digests", len(validatedProvenance.GetBinaryDigests()), 1)
+	testutil.AssertEq(t, "sha512 digest", validatedProvenance.GetBinaryDigests()["sha2-512"], "abcdef")
+}
+
+func TestParseStatementData_NoSupportedDigest(t *testing.T) {
+	statementBytes := []byte(`{
+		"_type": "https://in-toto.io/Statement/v0.1",
+		"predicateType": "https://slsa.dev/provenance/v0.2",
+		"subject": [{"name": "pkg.tgz", "digest": {"md5": "0123"}}],
+		"predicate": {}
+	}`)
+
+	_, err := ParseStatementData(statementBytes)
+	if err == nil {
+		t.Fatalf("expected an error for a subject without a supported digest")
+	}
+	// The error names the algorithms that are present.
+	if !strings.Contains(err.Error(), "[md5]") {
+		t.Errorf("expected the error to name the algorithms of the subject, got: %v", err)
+	}
+}
+
+func TestNormalizeDigestSet_Conflict(t *testing.T) {
+	if _, err := NormalizeDigestSet(map[string]string{"sha256": "aa", "sha2-256": "bb"}); err == nil {
+		t.Errorf("expected an error for conflicting digests")
+	}
+}
+
+const multiSubjectStatement = `{
+	"_type": "https://in-toto.io/Statement/v0.1",
+	"predicateType": "https://slsa.dev/provenance/v0.2",
+	"subject": [
+		{"name": "app_a", "digest": {"sha256": "aaaa"}},
+		{"name": "app_b", "digest": {"sha256": "bbbb", "sha512": "cccc"}},
+		{"name": "app_b", "digest": {"sha256": "dddd"}}
+	],
+	"predicate": {}
+}`
+
+func TestSelectSubject(t *testing.T) {
+	validatedProvenance, err := ParseStatementData([]byte(multiSubjectStatement))
+	if err != nil {
+		t.Fatalf("Failed to parse provenance: %v", err)
+	}
+	testutil.AssertEq(t, "subject count", validatedProvenance.SubjectCount(), 3)
+
+	byName, err := validatedProvenance.SelectSubject("app_a", nil)
+	if err != nil {
+		t.Fatalf("Failed to select subject by name: %v", err)
+	}
+	testutil.AssertEq(t, "subject count", byName.SubjectCount(), 1)
+	testutil.AssertEq(t, "subject name", byName.GetBinaryName(), "app_a")
+	testutil.AssertEq(t, "subject digest", byName.GetBinarySHA256Digest(), "aaaa")
+
+	byDigest, err := validatedProvenance.SelectSubject("", map[string]string{"sha2-512": "cccc"})
+	if err != nil {
+		t.Fatalf("Failed to select subject by digest: %v", err)
+	}
+	testutil.AssertEq(t, "subject digest", byDigest.GetBinarySHA256Digest(), "bbbb")
+
+	byBoth, err := validatedProvenance.SelectSubject("app_b", map[string]string{"sha256": "dddd"})
+	if err != nil {
+		t.Fatalf("Failed to select subject by name and digest: %v", err)
+	}
+	testutil.AssertEq(t, "subject digest", byBoth.GetBinarySHA256Digest(), "dddd")
+
+	// The original provenance is unchanged.
+	testutil.AssertEq(t, "subject count", validatedProvenance.SubjectCount(), 3)
+}
+
+func TestSelectSubject_Errors(t *testing.T) {
+	validatedProvenance, err := ParseStatementData([]byte(multiSubjectStatement))
+	if err != nil {
+		t.Fatalf("Failed to parse provenance: %v", err)
+	}
+
+	if _, err := validatedProvenance.SelectSubject("app_b", nil); err == nil {
+		t.Errorf("expected an error for an ambiguous name")
+	}
+	if _, err := validatedProvenance.SelectSubject("app_c", nil); err == nil {
+		t.Errorf("expected an error for an unknown name")
+	}
+	if _, err := validatedProvenance.SelectSubject("app_a", map[string]string{"sha256": "bbbb"}); err == nil {
+		t.Errorf("expected an error for a mismatching digest")
+	}
+	if _, err := FromValidatedProvenance(validatedProvenance); err == nil {
+		t.Errorf("expected an error for mapping a provenance with several subjects")
+	}
+}
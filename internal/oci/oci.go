@@ -0,0 +1,490 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package oci provides a client for discovering the attestations, e.g.,
+// provenances, attached to container images in OCI registries, and for
+// fetching them. Attestations are discovered with the OCI referrers API, and
+// with the tag convention of cosign, `sha256-<hex digest>.att`. Attestations
+// are referenced by `oci://<registry>/<repository>@sha256:<hex digest>` URIs
+// of the blobs that contain them.
+//
+// Only anonymous access, with the token flow of the Docker registry API, is
+// supported, i.e., the images and attestations must be public.
+package oci
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/project-oak/transparent-release/pkg/intoto"
+	slsav1 "github.com/project-oak/transparent-release/pkg/intoto/slsa_provenance/v1"
+)
+
+// Scheme is the scheme of URIs of blobs in OCI registries.
+const Scheme = "oci"
+
+// DockerHubRegistry is the registry of references without a registry host,
+// e.g., `ubuntu:22.04`.
+const DockerHubRegistry = "registry-1.docker.io"
+
+// Media types of manifests, and of the layers that contain attestations.
+const (
+	ImageManifestMediaType        = "application/vnd.oci.image.manifest.v1+json"
+	ImageIndexMediaType           = "application/vnd.oci.image.index.v1+json"
+	DockerManifestMediaType       = "application/vnd.docker.distribution.manifest.v2+json"
+	DockerManifestListMediaType   = "application/vnd.docker.distribution.manifest.list.v2+json"
+	DSSEEnvelopeMediaType         = "application/vnd.dsse.envelope.v1+json"
+	sigstoreBundleMediaTypePrefix = "application/vnd.dev.sigstore.bundle"
+)
+
+// Annotations recording the predicate type of an attestation: on the layers of
+// cosign attestation manifests, and on Sigstore bundle referrer manifests.
+const (
+	cosignPredicateTypeAnnotation         = "predicateType"
+	sigstoreBundlePredicateTypeAnnotation = "dev.sigstore.bundle.predicateType"
+)
+
+// provenancePredicateTypes are the predicate types of the SLSA provenances
+// that can be parsed. Attestations with other predicate types are ignored.
+//
+//nolint:gochecknoglobals
+var provenancePredicateTypes = map[string]bool{
+	intoto.SLSAV02PredicateType:         true,
+	slsav1.PredicateSLSAProvenance:      true,
+	slsav1.PredicateSLSAProvenanceDraft: true,
+}
+
+// manifestMediaTypes are accepted when fetching manifests.
+//
+//nolint:gochecknoglobals
+var manifestMediaTypes = []string{ImageManifestMediaType, ImageIndexMediaType, DockerManifestMediaType, DockerManifestListMediaType}
+
+// Reference identifies an image in a registry, by tag or by digest.
+type Reference struct {
+	// Registry is the host, and optionally the port, of the registry.
+	Registry string
+	// Repository is the path of the repository in the registry.
+	Repository string
+	// Tag is the tag of the image. Empty if the reference has a digest.
+	Tag string
+	// Digest is the digest of the manifest of the image, of the form
+	// `sha256:<hex digest>`. Empty if the reference has a tag.
+	Digest string
+}
+
+// ParseReference parses an image reference of the form
+// `[registry/]repository[:tag|@sha256:<hex digest>]`. The tag defaults to
+// `latest`. References without a registry refer to Docker Hub.
+func ParseReference(ref string) (*Reference, error) {
+	name, digest, hasDigest := strings.Cut(ref, "@")
+	reference := &Reference{}
+	if hasDigest {
+		if err := ValidateDigest(digest); err != nil {
+			return nil, fmt.Errorf("invalid digest in %q: %v", ref, err)
+		}
+		reference.Digest = strings.ToLower(digest)
+	}
+	// A colon after the last slash separates the tag; a colon before it is
+	// part of the registry host.
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		if hasDigest {
+			return nil, fmt.Errorf("invalid reference %q: both a tag and a digest", ref)
+		}
+		reference.Tag = name[i+1:]
+		name = name[:i]
+	} else if !hasDigest {
+		reference.Tag = "latest"
+	}
+
+	registry, repository, found := strings.Cut(name, "/")
+	if !found || !(strings.ContainsAny(registry, ".:") || registry == "localhost") {
+		registry, repository = DockerHubRegistry, name
+		if !strings.Contains(repository, "/") {
+			repository = "library/" + repository
+		}
+	}
+	if repository == "" || reference.Tag == "" && !hasDigest {
+		return nil, fmt.Errorf("invalid reference %q", ref)
+	}
+	reference.Registry = registry
+	reference.Repository = repository
+	return reference, nil
+}
+
+// Name returns the reference without the tag and digest, e.g.,
+// `ghcr.io/project-oak/oak`.
+func (r *Reference) Name() string {
+	return r.Registry + "/" + r.Repository
+}
+
+// String returns the reference in the format parsed by ParseReference.
+func (r *Reference) String() string {
+	if r.Digest != "" {
+		return r.Name() + "@" + r.Digest
+	}
+	return r.Name() + ":" + r.Tag
+}
+
+// BlobURI returns the URI of the blob with the given digest, of the form
+// `sha256:<hex digest>`, in the repository of the given reference.
+func BlobURI(ref *Reference, digest string) string {
+	return fmt.Sprintf("%s://%s@%s", Scheme, ref.Name(), digest)
+}
+
+// Descriptor identifies the manifest of an image by its media type and
+// digest.
+type Descriptor struct {
+	// MediaType is the media type of the manifest, e.g.,
+	// ImageManifestMediaType.
+	MediaType string
+	// Digest is the digest of the manifest, of the form `sha256:<hex digest>`.
+	Digest string
+}
+
+// descriptor describes content in a registry, as in OCI manifests and
+// indexes.
+type descriptor struct {
+	MediaType    string            `json:"mediaType"`
+	Digest       string            `json:"digest"`
+	Size         int64             `json:"size"`
+	ArtifactType string            `json:"artifactType,omitempty"`
+	Annotations  map[string]string `json:"annotations,omitempty"`
+}
+
+// manifest is an image manifest or an index, of which only the descriptors
+// are used.
+type manifest struct {
+	MediaType   string            `json:"mediaType"`
+	Layers      []descriptor      `json:"layers"`
+	Manifests   []descriptor      `json:"manifests"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// Client discovers and fetches attestations in OCI registries.
+type Client struct {
+	httpClient *http.Client
+	mu         sync.Mutex
+	// tokens caches the bearer tokens for anonymous access, keyed by
+	// registry and repository.
+	tokens map[string]string
+}
+
+// NewClient creates a new Client.
+func NewClient() *Client {
+	return &Client{httpClient: &http.Client{}, tokens: make(map[string]string)}
+}
+
+// Resolve fetches the manifest of the given image, and returns its media type
+// and digest. For references by digest, the manifest must match the digest.
+func (c *Client) Resolve(ctx context.Context, ref *Reference) (*Descriptor, error) {
+	tagOrDigest := ref.Tag
+	if ref.Digest != "" {
+		tagOrDigest = ref.Digest
+	}
+	content, found, err := c.get(ctx, ref, "manifests/"+tagOrDigest, manifestMediaTypes)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("image %s not found", ref)
+	}
+	sum256 := sha256.Sum256(content)
+	digest := "sha256:" + hex.EncodeToString(sum256[:])
+	if ref.Digest != "" && digest != ref.Digest {
+		return nil, fmt.Errorf("unexpected digest of the manifest of %s: got %s", ref, digest)
+	}
+	var m manifest
+	if err := json.Unmarshal(content, &m); err != nil {
+		return nil, fmt.Errorf("could not parse the manifest of %s: %v", ref, err)
+	}
+	if m.MediaType == "" {
+		return nil, fmt.Errorf("no media type in the manifest of %s", ref)
+	}
+	return &Descriptor{MediaType: m.MediaType, Digest: digest}, nil
+}
+
+// Attestations returns the descriptor of the manifest of the given image, and
+// the URIs of the blobs of the attestations attached to it, either as
+// referrers, or with the cosign tag convention. Only blobs containing DSSE
+// envelopes or Sigstore bundles, annotated with the predicate type of a SLSA
+// provenance, are returned.
+func (c *Client) Attestations(ctx context.Context, ref *Reference) (*Descriptor, []string, error) {
+	image, err := c.Resolve(ctx, ref)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not resolve %s: %v", ref, err)
+	}
+	digest := image.Digest
+
+	var layers []descriptor
+	index, found, err := c.getManifest(ctx, ref, "referrers/"+digest)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not list the referrers of %s: %v", ref, err)
+	}
+	if found {
+		for _, referrer := range index.Manifests {
+			artifact, found, err := c.getManifest(ctx, ref, "manifests/"+referrer.Digest)
+			if err != nil {
+				return nil, nil, fmt.Errorf("could not fetch the referrer %s: %v", referrer.Digest, err)
+			}
+			if !found {
+				continue
+			}
+			for _, layer := range artifact.Layers {
+				// Sigstore bundle referrers record the predicate type on the
+				// manifest rather than on the layer.
+				if predicateType := artifact.Annotations[sigstoreBundlePredicateTypeAnnotation]; predicateType != "" && layer.Annotations[cosignPredicateTypeAnnotation] == "" {
+					layer.Annotations = map[string]string{cosignPredicateTypeAnnotation: predicateType}
+				}
+				layers = append(layers, layer)
+			}
+		}
+	}
+
+	attestationTag := strings.Replace(digest, ":", "-", 1) + ".att"
+	attestations, found, err := c.getManifest(ctx, ref, "manifests/"+attestationTag)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not fetch the attestations of %s: %v", ref, err)
+	}
+	if found {
+		layers = append(layers, attestations.Layers...)
+	}
+
+	var uris []string
+	seen := make(map[string]bool)
+	for _, layer := range layers {
+		if !isProvenance(&layer) || seen[layer.Digest] {
+			continue
+		}
+		if err := ValidateDigest(layer.Digest); err != nil {
+			return nil, nil, fmt.Errorf("invalid digest of attestation layer: %v", err)
+		}
+		seen[layer.Digest] = true
+		uris = append(uris, BlobURI(ref, strings.ToLower(layer.Digest)))
+	}
+	return image, uris, nil
+}
+
+// Fetch fetches the blob at the given `oci://<registry>/<repository>@sha256:<hex>`
+// URI, and checks that its content matches the digest. It implements
+// fetch.Fetcher.
+func (c *Client) Fetch(ctx context.Context, uri *url.URL) (io.ReadCloser, error) {
+	if uri.Scheme != Scheme {
+		return nil, fmt.Errorf("unexpected scheme of %q, want %q", uri, Scheme)
+	}
+	ref, err := ParseReference(uri.Host + uri.Path)
+	if err != nil {
+		return nil, err
+	}
+	if ref.Digest == "" {
+		return nil, fmt.Errorf("invalid blob URI %q, want %s://<registry>/<repository>@sha256:<hex digest>", uri, Scheme)
+	}
+	content, found, err := c.get(ctx, ref, "blobs/"+ref.Digest, nil)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("blob %s not found", ref)
+	}
+	sum256 := sha256.Sum256(content)
+	if got := "sha256:" + hex.EncodeToString(sum256[:]); got != ref.Digest {
+		return nil, fmt.Errorf("unexpected digest of the blob %s: got %s", ref, got)
+	}
+	return io.NopCloser(bytes.NewReader(content)), nil
+}
+
+// getManifest fetches and parses the manifest or index at the given path in
+// the repository of the given reference.
+func (c *Client) getManifest(ctx context.Context, ref *Reference, path string) (*manifest, bool, error) {
+	content, found, err := c.get(ctx, ref, path, manifestMediaTypes)
+	if err != nil || !found {
+		return nil, found, err
+	}
+	var m manifest
+	if err := json.Unmarshal(content, &m); err != nil {
+		return nil, false, fmt.Errorf("could not parse the manifest: %v", err)
+	}
+	return &m, true, nil
+}
+
+// get fetches the given path under `/v2/<repository>/` in the registry of the
+// given reference, authenticating anonymously if the registry requires it.
+// Returns false if the registry responds with 404 Not Found.
+func (c *Client) get(ctx context.Context, ref *Reference, path string, accept []string) ([]byte, bool, error) {
+	endpoint := fmt.Sprintf("https://%s/v2/%s/%s", ref.Registry, ref.Repository, path)
+	resp, err := c.do(ctx, ref, endpoint, accept)
+	if err != nil {
+		return nil, false, err
+	}
+	defer resp.Body.Close()
+
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, false, fmt.Errorf("could not read the response from %s: %v", endpoint, err)
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return content, true, nil
+	case http.StatusNotFound:
+		return nil, false, nil
+	default:
+		return nil, false, fmt.Errorf("unexpected response from %s: %s", endpoint, resp.Status)
+	}
+}
+
+// do sends a GET request to the given endpoint, and retries it with a bearer
+// token if the registry asks for one.
+func (c *Client) do(ctx context.Context, ref *Reference, endpoint string, accept []string) (*http.Response, error) {
+	key := ref.Name()
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+		if err != nil {
+			return nil, fmt.Errorf("could not create HTTP request: %v", err)
+		}
+		if len(accept) != 0 {
+			req.Header.Set("Accept", strings.Join(accept, ", "))
+		}
+		c.mu.Lock()
+		token := c.tokens[key]
+		c.mu.Unlock()
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("could not receive response from %s: %v", endpoint, err)
+		}
+		if resp.StatusCode != http.StatusUnauthorized || attempt > 0 {
+			return resp, nil
+		}
+		challenge := resp.Header.Get("WWW-Authenticate")
+		resp.Body.Close()
+		token, err = c.fetchToken(ctx, challenge)
+		if err != nil {
+			return nil, fmt.Errorf("could not authenticate to %s: %v", ref.Registry, err)
+		}
+		c.mu.Lock()
+		c.tokens[key] = token
+		c.mu.Unlock()
+	}
+}
+
+// fetchToken obtains an anonymous bearer token for the given
+// `WWW-Authenticate` challenge, as in the token flow of the Docker registry
+// API.
+func (c *Client) fetchToken(ctx context.Context, challenge string) (string, error) {
+	params, ok := parseBearerChallenge(challenge)
+	if !ok || params["realm"] == "" {
+		return "", fmt.Errorf("unsupported authentication challenge %q", challenge)
+	}
+	tokenURL, err := url.Parse(params["realm"])
+	if err != nil {
+		return "", fmt.Errorf("invalid realm %q: %v", params["realm"], err)
+	}
+	query := tokenURL.Query()
+	for _, name := range []string{"service", "scope"} {
+		if value := params[name]; value != "" {
+			query.Set(name, value)
+		}
+	}
+	tokenURL.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, tokenURL.String(), nil)
+	if err != nil {
+		return "", fmt.Errorf("could not create HTTP request: %v", err)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("could not receive response from %s: %v", tokenURL.Host, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected response from %s: %s", tokenURL.Host, resp.Status)
+	}
+	var tokens struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tokens); err != nil {
+		return "", fmt.Errorf("could not parse the token response: %v", err)
+	}
+	if tokens.Token != "" {
+		return tokens.Token, nil
+	}
+	if tokens.AccessToken != "" {
+		return tokens.AccessToken, nil
+	}
+	return "", fmt.Errorf("no token in the response from %s", tokenURL.Host)
+}
+
+// parseBearerChallenge parses the parameters of a `Bearer` challenge, e.g.,
+// `Bearer realm="https://ghcr.io/token",service="ghcr.io",scope="..."`.
+func parseBearerChallenge(challenge string) (map[string]string, bool) {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(challenge), " ")
+	if !strings.EqualFold(scheme, "Bearer") {
+		return nil, false
+	}
+	params := make(map[string]string)
+	for rest = strings.TrimSpace(rest); rest != ""; rest = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(rest), ",")) {
+		name, value, found := strings.Cut(rest, "=")
+		if !found {
+			return nil, false
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		if strings.HasPrefix(value, `"`) {
+			end := strings.Index(value[1:], `"`)
+			if end < 0 {
+				return nil, false
+			}
+			params[name] = value[1 : end+1]
+			rest = value[end+2:]
+		} else {
+			value, rest, _ = strings.Cut(value, ",")
+			params[name] = strings.TrimSpace(value)
+		}
+	}
+	return params, true
+}
+
+// isProvenance reports whether the given layer contains an attestation, i.e.,
+// a DSSE envelope or a Sigstore bundle, of a SLSA provenance. Layers without a
+// predicate type annotation are not provenances.
+func isProvenance(layer *descriptor) bool {
+	if layer.MediaType != DSSEEnvelopeMediaType && !strings.HasPrefix(layer.MediaType, sigstoreBundleMediaTypePrefix) {
+		return false
+	}
+	return provenancePredicateTypes[layer.Annotations[cosignPredicateTypeAnnotation]]
+}
+
+// ValidateDigest checks that the given digest has the form
+// `sha256:<hex digest>`.
+func ValidateDigest(digest string) error {
+	algorithm, value, found := strings.Cut(digest, ":")
+	if !found || !strings.EqualFold(algorithm, "sha256") {
+		return fmt.Errorf("unsupported digest %q, want sha256:<hex digest>", digest)
+	}
+	if decoded, err := hex.DecodeString(value); err != nil || len(decoded) != sha256.Size {
+		return fmt.Errorf("invalid SHA2-256 digest %q", digest)
+	}
+	return nil
+}
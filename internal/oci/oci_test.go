@@ -0,0 +1,269 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oci
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/project-oak/transparent-release/internal/testutil"
+)
+
+const testToken = "anonymous-token"
+
+func digestOf(content []byte) string {
+	sum256 := sha256.Sum256(content)
+	return "sha256:" + hex.EncodeToString(sum256[:])
+}
+
+func mustMarshal(t *testing.T, v interface{}) []byte {
+	bytes, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("could not marshal: %v", err)
+	}
+	return bytes
+}
+
+// fakeRegistry is an OCI registry serving a single image in the repository
+// `project-oak/oak`, with one attestation attached as a referrer, and one with
+// the cosign tag convention. It requires an anonymous bearer token.
+type fakeRegistry struct {
+	server        *httptest.Server
+	imageDigest   string
+	referrerBlob  []byte
+	cosignBlob    []byte
+	tokenRequests int
+}
+
+func newFakeRegistry(t *testing.T, servesReferrers bool) *fakeRegistry {
+	registry := &fakeRegistry{
+		referrerBlob: []byte(`{"payloadType":"application/vnd.in-toto+json","payload":"e30=","signatures":[]}`),
+		cosignBlob:   []byte(`{"payloadType":"application/vnd.in-toto+json","payload":"e30K","signatures":[]}`),
+	}
+	image := mustMarshal(t, manifest{MediaType: ImageManifestMediaType, Layers: []descriptor{{MediaType: "application/vnd.oci.image.layer.v1.tar+gzip", Digest: digestOf([]byte("layer"))}}})
+	registry.imageDigest = digestOf(image)
+	// The referrer records the predicate type on the manifest, as Sigstore
+	// bundle referrers do.
+	referrer := mustMarshal(t, manifest{
+		MediaType:   ImageManifestMediaType,
+		Layers:      []descriptor{{MediaType: DSSEEnvelopeMediaType, Digest: digestOf(registry.referrerBlob)}},
+		Annotations: map[string]string{sigstoreBundlePredicateTypeAnnotation: "https://slsa.dev/provenance/v1"},
+	})
+	index := mustMarshal(t, manifest{MediaType: ImageIndexMediaType, Manifests: []descriptor{{MediaType: ImageManifestMediaType, Digest: digestOf(referrer)}}})
+	// The cosign manifest also lists a non-attestation layer, attestations
+	// that are not provenances, and the referrer blob again, which must be
+	// deduplicated.
+	provenance := map[string]string{cosignPredicateTypeAnnotation: "https://slsa.dev/provenance/v0.2"}
+	cosign := mustMarshal(t, manifest{MediaType: ImageManifestMediaType, Layers: []descriptor{
+		{MediaType: DSSEEnvelopeMediaType, Digest: digestOf(registry.cosignBlob), Annotations: provenance},
+		{MediaType: DSSEEnvelopeMediaType, Digest: digestOf([]byte("vulnerabilities")), Annotations: map[string]string{cosignPredicateTypeAnnotation: "https://cosign.sigstore.dev/attestation/vuln/v1"}},
+		{MediaType: DSSEEnvelopeMediaType, Digest: digestOf([]byte("unannotated"))},
+		{MediaType: DSSEEnvelopeMediaType, Digest: digestOf(registry.referrerBlob), Annotations: provenance},
+		{MediaType: "application/vnd.oci.image.config.v1+json", Digest: digestOf([]byte("config")), Annotations: provenance},
+	}})
+
+	content := map[string][]byte{
+		"/v2/project-oak/oak/manifests/latest":                                                         image,
+		"/v2/project-oak/oak/manifests/" + registry.imageDigest:                                        image,
+		"/v2/project-oak/oak/manifests/" + digestOf(referrer):                                          referrer,
+		"/v2/project-oak/oak/manifests/" + strings.Replace(registry.imageDigest, ":", "-", 1) + ".att": cosign,
+		"/v2/project-oak/oak/blobs/" + digestOf(registry.referrerBlob):                                 registry.referrerBlob,
+		"/v2/project-oak/oak/blobs/" + digestOf(registry.cosignBlob):                                   registry.cosignBlob,
+	}
+	if servesReferrers {
+		content["/v2/project-oak/oak/referrers/"+registry.imageDigest] = index
+	}
+
+	registry.server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			registry.tokenRequests++
+			if got := r.URL.Query().Get("scope"); got != "repository:project-oak/oak:pull" {
+				t.Errorf("unexpected scope: %q", got)
+			}
+			_, _ = w.Write([]byte(`{"token":"` + testToken + `"}`))
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer "+testToken {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="https://`+r.Host+`/token",service="registry",scope="repository:project-oak/oak:pull"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body, ok := content[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(body)
+	}))
+	t.Cleanup(registry.server.Close)
+	return registry
+}
+
+func (r *fakeRegistry) client() *Client {
+	client := NewClient()
+	client.httpClient = r.server.Client()
+	return client
+}
+
+func (r *fakeRegistry) reference(t *testing.T, tag string) *Reference {
+	ref, err := ParseReference(strings.TrimPrefix(r.server.URL, "https://") + "/project-oak/oak:" + tag)
+	if err != nil {
+		t.Fatalf("could not parse the reference: %v", err)
+	}
+	return ref
+}
+
+func TestParseReference(t *testing.T) {
+	digest := "sha256:" + strings.Repeat("ab", 32)
+	tests := map[string]Reference{
+		"ghcr.io/project-oak/oak:v1":   {Registry: "ghcr.io", Repository: "project-oak/oak", Tag: "v1"},
+		"ghcr.io/project-oak/oak":      {Registry: "ghcr.io", Repository: "project-oak/oak", Tag: "latest"},
+		"localhost:5000/oak@" + digest: {Registry: "localhost:5000", Repository: "oak", Digest: digest},
+		"ubuntu:22.04":                 {Registry: DockerHubRegistry, Repository: "library/ubuntu", Tag: "22.04"},
+		"project-oak/oak":              {Registry: DockerHubRegistry, Repository: "project-oak/oak", Tag: "latest"},
+	}
+	for ref, want := range tests {
+		got, err := ParseReference(ref)
+		if err != nil {
+			t.Errorf("could not parse %q: %v", ref, err)
+			continue
+		}
+		if diff := cmp.Diff(*got, want); diff != "" {
+			t.Errorf("unexpected reference for %q: %s", ref, diff)
+		}
+	}
+
+	for _, ref := range []string{"ghcr.io/project-oak/oak:v1@" + digest, "ghcr.io/project-oak/oak@sha256:abc", "ghcr.io/project-oak/oak@md5:" + strings.Repeat("ab", 16)} {
+		if _, err := ParseReference(ref); err == nil {
+			t.Errorf("expected an error for %q", ref)
+		}
+	}
+}
+
+func TestAttestations(t *testing.T) {
+	registry := newFakeRegistry(t, true)
+	client := registry.client()
+	ref := registry.reference(t, "latest")
+
+	image, uris, err := client.Attestations(context.Background(), ref)
+	if err != nil {
+		t.Fatalf("could not discover the attestations: %v", err)
+	}
+	testutil.AssertEq(t, "image digest", image.Digest, registry.imageDigest)
+	testutil.AssertEq(t, "image media type", image.MediaType, ImageManifestMediaType)
+	want := []string{BlobURI(ref, digestOf(registry.referrerBlob)), BlobURI(ref, digestOf(registry.cosignBlob))}
+	if diff := cmp.Diff(uris, want); diff != "" {
+		t.Errorf("unexpected attestation URIs: %s", diff)
+	}
+	// The token is cached for the repository.
+	testutil.AssertEq(t, "token requests", registry.tokenRequests, 1)
+
+	for i, blob := range [][]byte{registry.referrerBlob, registry.cosignBlob} {
+		uri, err := url.Parse(uris[i])
+		if err != nil {
+			t.Fatalf("could not parse %q: %v", uris[i], err)
+		}
+		reader, err := client.Fetch(context.Background(), uri)
+		if err != nil {
+			t.Fatalf("could not fetch %q: %v", uris[i], err)
+		}
+		got, err := io.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			t.Fatalf("could not read %q: %v", uris[i], err)
+		}
+		testutil.AssertEq(t, "blob", string(got), string(blob))
+	}
+}
+
+func TestAttestations_WithoutReferrersAPI(t *testing.T) {
+	registry := newFakeRegistry(t, false)
+	ref := registry.reference(t, "latest")
+
+	_, uris, err := registry.client().Attestations(context.Background(), ref)
+	if err != nil {
+		t.Fatalf("could not discover the attestations: %v", err)
+	}
+	want := []string{BlobURI(ref, digestOf(registry.cosignBlob)), BlobURI(ref, digestOf(registry.referrerBlob))}
+	if diff := cmp.Diff(uris, want); diff != "" {
+		t.Errorf("unexpected attestation URIs: %s", diff)
+	}
+}
+
+func TestAttestations_UnknownImage(t *testing.T) {
+	registry := newFakeRegistry(t, true)
+	if _, _, err := registry.client().Attestations(context.Background(), registry.reference(t, "unknown")); err == nil {
+		t.Errorf("expected an error for an unknown image")
+	}
+}
+
+func TestResolve_ByDigest(t *testing.T) {
+	registry := newFakeRegistry(t, true)
+	ref := registry.reference(t, "latest")
+	ref.Tag = ""
+	ref.Digest = registry.imageDigest
+
+	image, err := registry.client().Resolve(context.Background(), ref)
+	if err != nil {
+		t.Fatalf("could not resolve the image: %v", err)
+	}
+	if diff := cmp.Diff(*image, Descriptor{MediaType: ImageManifestMediaType, Digest: registry.imageDigest}); diff != "" {
+		t.Errorf("unexpected descriptor: %s", diff)
+	}
+
+	ref.Digest = digestOf([]byte("unknown"))
+	if _, err := registry.client().Resolve(context.Background(), ref); err == nil {
+		t.Errorf("expected an error for an unknown digest")
+	}
+}
+
+func TestFetch_DigestMismatch(t *testing.T) {
+	registry := newFakeRegistry(t, true)
+	ref := registry.reference(t, "latest")
+	uri, err := url.Parse(BlobURI(ref, digestOf(registry.referrerBlob)))
+	if err != nil {
+		t.Fatalf("could not parse the URI: %v", err)
+	}
+	// Change the served content in place, so that it no longer matches the
+	// digest in the URI.
+	registry.referrerBlob[0] = ' '
+	if _, err := registry.client().Fetch(context.Background(), uri); err == nil || !strings.Contains(err.Error(), "unexpected digest") {
+		t.Errorf("expected a digest mismatch, got: %v", err)
+	}
+}
+
+func TestParseBearerChallenge(t *testing.T) {
+	params, ok := parseBearerChallenge(`Bearer realm="https://ghcr.io/token",service="ghcr.io",scope="repository:project-oak/oak:pull"`)
+	if !ok {
+		t.Fatalf("could not parse the challenge")
+	}
+	want := map[string]string{"realm": "https://ghcr.io/token", "service": "ghcr.io", "scope": "repository:project-oak/oak:pull"}
+	if diff := cmp.Diff(params, want); diff != "" {
+		t.Errorf("unexpected parameters: %s", diff)
+	}
+	if _, ok := parseBearerChallenge(`Basic realm="registry"`); ok {
+		t.Errorf("expected basic challenges to be unsupported")
+	}
+}
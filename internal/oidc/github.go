@@ -0,0 +1,304 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package oidc provides utilities for verifying OIDC identity tokens issued
+// by GitHub Actions to workflows calling the endorsement service.
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// GitHubActionsIssuer is the issuer of GitHub Actions OIDC tokens.
+const GitHubActionsIssuer = "https://token.actions.githubusercontent.com"
+
+const (
+	// minKeyRefreshInterval is the minimum interval between two fetches of the
+	// signing keys, so that tokens with unknown key IDs cannot make the
+	// Verifier flood the issuer with requests.
+	minKeyRefreshInterval = time.Minute
+	// fetchTimeout bounds the time it takes to fetch the signing keys.
+	fetchTimeout = 30 * time.Second
+	// maxJWKSBytes bounds the size of the signing keys document.
+	maxJWKSBytes = 1 << 20
+)
+
+// GitHubClaims contains the claims in a GitHub Actions OIDC token that are
+// relevant for identifying the caller. See
+// https://docs.github.com/en/actions/deployment/security-hardening-your-deployments/about-security-hardening-with-openid-connect#understanding-the-oidc-token.
+type GitHubClaims struct {
+	Issuer          string   `json:"iss"`
+	Subject         string   `json:"sub"`
+	Audience        audience `json:"aud"`
+	ExpiresAt       int64    `json:"exp"`
+	NotBefore       int64    `json:"nbf"`
+	IssuedAt        int64    `json:"iat"`
+	Repository      string   `json:"repository"`
+	RepositoryOwner string   `json:"repository_owner"`
+	Workflow        string   `json:"workflow"`
+	WorkflowRef     string   `json:"workflow_ref"`
+	JobWorkflowRef  string   `json:"job_workflow_ref"`
+	Ref             string   `json:"ref"`
+	SHA             string   `json:"sha"`
+	RunID           string   `json:"run_id"`
+	RunAttempt      string   `json:"run_attempt"`
+}
+
+// audience is the `aud` claim, which can be either a string or an array of strings.
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+	var multiple []string
+	if err := json.Unmarshal(data, &multiple); err != nil {
+		return fmt.Errorf("aud is neither a string nor an array of strings: %v", err)
+	}
+	*a = multiple
+	return nil
+}
+
+// RunURI returns the URI of the workflow run that requested the token.
+func (c *GitHubClaims) RunURI() string {
+	return fmt.Sprintf("https://github.com/%s/actions/runs/%s/attempts/%s", c.Repository, c.RunID, c.RunAttempt)
+}
+
+// MatchesRepository returns true if the given repository URI refers to the
+// GitHub repository in the claims. The URI may have a `git+` prefix, a
+// `.git` suffix, and a `@ref` suffix, as is common in provenances.
+func (c *GitHubClaims) MatchesRepository(repoURI string) bool {
+	uri := strings.TrimPrefix(repoURI, "git+")
+	if i := strings.LastIndex(uri, "@"); i > strings.Index(uri, "://") {
+		uri = uri[:i]
+	}
+	uri = strings.TrimSuffix(uri, ".git")
+	return strings.EqualFold(uri, "https://github.com/"+c.Repository)
+}
+
+// jsonWebKey is an RSA public key in JWK format.
+type jsonWebKey struct {
+	KeyType string `json:"kty"`
+	KeyID   string `json:"kid"`
+	N       string `json:"n"`
+	E       string `json:"e"`
+}
+
+// Verifier verifies GitHub Actions OIDC tokens, using the signing keys
+// published by the issuer.
+type Verifier struct {
+	issuer   string
+	audience string
+	jwksURL  string
+	client   *http.Client
+
+	mu          sync.Mutex
+	keys        map[string]*rsa.PublicKey
+	lastRefresh time.Time
+}
+
+// NewGitHubVerifier creates a Verifier for GitHub Actions tokens with the given audience.
+func NewGitHubVerifier(audience string) *Verifier {
+	return NewVerifier(GitHubActionsIssuer, GitHubActionsIssuer+"/.well-known/jwks", audience)
+}
+
+// NewVerifier creates a Verifier for tokens from the given issuer, with the
+// given audience, signed by keys published at the given JWKS URL.
+func NewVerifier(issuer, jwksURL, audience string) *Verifier {
+	return &Verifier{
+		issuer:   issuer,
+		audience: audience,
+		jwksURL:  jwksURL,
+		client:   &http.Client{Timeout: fetchTimeout},
+		keys:     make(map[string]*rsa.PublicKey),
+	}
+}
+
+// Verify verifies the signature, issuer, audience and validity period of the
+// given token, and returns the claims in it.
+func (v *Verifier) Verify(ctx context.Context, token string) (*GitHubClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("the token is not a JWT")
+	}
+
+	var header struct {
+		Algorithm string `json:"alg"`
+		KeyID     string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("could not decode the token header: %v", err)
+	}
+	if header.Algorithm != "RS256" {
+		return nil, fmt.Errorf("unsupported signature algorithm %q", header.Algorithm)
+	}
+
+	key, err := v.getKey(ctx, header.KeyID)
+	if err != nil {
+		return nil, fmt.Errorf("could not get the signing key: %v", err)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("could not decode the token signature: %v", err)
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+		return nil, fmt.Errorf("invalid token signature: %v", err)
+	}
+
+	var claims GitHubClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("could not decode the token payload: %v", err)
+	}
+	if err := v.validateClaims(&claims, time.Now()); err != nil {
+		return nil, err
+	}
+	return &claims, nil
+}
+
+func (v *Verifier) validateClaims(claims *GitHubClaims, now time.Time) error {
+	if claims.Issuer != v.issuer {
+		return fmt.Errorf("unexpected issuer: got %q, want %q", claims.Issuer, v.issuer)
+	}
+	found := false
+	for _, aud := range claims.Audience {
+		if aud == v.audience {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("the token audience %v does not contain %q", claims.Audience, v.audience)
+	}
+	if now.Unix() >= claims.ExpiresAt {
+		return fmt.Errorf("the token expired at %v", time.Unix(claims.ExpiresAt, 0).UTC())
+	}
+	if claims.NotBefore != 0 && now.Unix() < claims.NotBefore {
+		return fmt.Errorf("the token is not valid before %v", time.Unix(claims.NotBefore, 0).UTC())
+	}
+	return nil
+}
+
+// getKey returns the key with the given ID, refreshing the cached keys from
+// the JWKS URL if the key is not known. The keys are refreshed at most once
+// per minKeyRefreshInterval; unknown key IDs in between are rejected.
+func (v *Verifier) getKey(ctx context.Context, keyID string) (*rsa.PublicKey, error) {
+	v.mu.Lock()
+	if key, ok := v.keys[keyID]; ok {
+		v.mu.Unlock()
+		return key, nil
+	}
+	now := time.Now()
+	if !v.lastRefresh.IsZero() && now.Sub(v.lastRefresh) < minKeyRefreshInterval {
+		v.mu.Unlock()
+		return nil, fmt.Errorf("no key with ID %q in %s, which was fetched less than %v ago", keyID, v.jwksURL, minKeyRefreshInterval)
+	}
+	// Claim the refresh before releasing the lock, so that concurrent callers
+	// do not fetch the keys too.
+	v.lastRefresh = now
+	v.mu.Unlock()
+
+	keys, err := v.fetchKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.keys = keys
+	if key, ok := keys[keyID]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("no key with ID %q in %s", keyID, v.jwksURL)
+}
+
+func (v *Verifier) fetchKeys(ctx context.Context) (map[string]*rsa.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.jwksURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not create HTTP request: %v", err)
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch keys from %s: %v", v.jwksURL, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxJWKSBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("could not read keys from %s: %v", v.jwksURL, err)
+	}
+	if len(body) > maxJWKSBytes {
+		return nil, fmt.Errorf("the keys at %s exceed %d bytes", v.jwksURL, maxJWKSBytes)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching keys from %s failed with status %d", v.jwksURL, resp.StatusCode)
+	}
+
+	var jwks struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(body, &jwks); err != nil {
+		return nil, fmt.Errorf("could not unmarshal keys: %v", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(jwks.Keys))
+	for _, jwk := range jwks.Keys {
+		if jwk.KeyType != "RSA" {
+			continue
+		}
+		key, err := parseRSAKey(jwk)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse key %q: %v", jwk.KeyID, err)
+		}
+		keys[jwk.KeyID] = key
+	}
+	return keys, nil
+}
+
+func parseRSAKey(jwk jsonWebKey) (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode modulus: %v", err)
+	}
+	e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode exponent: %v", err)
+	}
+	exponent := new(big.Int).SetBytes(e)
+	if !exponent.IsInt64() || exponent.Int64() > 1<<31-1 {
+		return nil, fmt.Errorf("exponent too large")
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	bytes, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(bytes, v)
+}
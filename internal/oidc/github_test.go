@@ -0,0 +1,209 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/project-oak/transparent-release/internal/testutil"
+)
+
+const testAudience = "transparent-release"
+
+type testIssuer struct {
+	key    *rsa.PrivateKey
+	server *httptest.Server
+	// fetches counts the requests for the keys.
+	fetches int32
+}
+
+func newTestIssuer(t *testing.T) *testIssuer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("could not generate key: %v", err)
+	}
+	issuer := &testIssuer{key: key}
+	issuer.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&issuer.fetches, 1)
+		jwks := map[string][]jsonWebKey{"keys": {{
+			KeyType: "RSA",
+			KeyID:   "test-kid",
+			N:       base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:       base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}}
+		if err := json.NewEncoder(w).Encode(jwks); err != nil {
+			t.Errorf("could not encode keys: %v", err)
+		}
+	}))
+	return issuer
+}
+
+func (i *testIssuer) token(t *testing.T, claims map[string]interface{}) string {
+	return i.tokenWithKeyID(t, "test-kid", claims)
+}
+
+func (i *testIssuer) tokenWithKeyID(t *testing.T, keyID string, claims map[string]interface{}) string {
+	encode := func(v interface{}) string {
+		bytes, err := json.Marshal(v)
+		if err != nil {
+			t.Fatalf("could not marshal: %v", err)
+		}
+		return base64.RawURLEncoding.EncodeToString(bytes)
+	}
+	signingInput := encode(map[string]string{"alg": "RS256", "kid": keyID}) + "." + encode(claims)
+	digest := sha256.Sum256([]byte(signingInput))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, i.key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatalf("could not sign: %v", err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func validClaims() map[string]interface{} {
+	return map[string]interface{}{
+		"iss":         GitHubActionsIssuer,
+		"aud":         testAudience,
+		"sub":         "repo:project-oak/oak:ref:refs/heads/main",
+		"exp":         time.Now().Add(5 * time.Minute).Unix(),
+		"nbf":         time.Now().Add(-time.Minute).Unix(),
+		"repository":  "project-oak/oak",
+		"workflow":    "Release",
+		"run_id":      "1234",
+		"run_attempt": "1",
+	}
+}
+
+func TestVerify_ValidToken(t *testing.T) {
+	issuer := newTestIssuer(t)
+	defer issuer.server.Close()
+	verifier := NewVerifier(GitHubActionsIssuer, issuer.server.URL, testAudience)
+
+	claims, err := verifier.Verify(context.Background(), issuer.token(t, validClaims()))
+	if err != nil {
+		t.Fatalf("could not verify token: %v", err)
+	}
+	testutil.AssertEq(t, "repository", claims.Repository, "project-oak/oak")
+	testutil.AssertEq(t, "run URI", claims.RunURI(), "https://github.com/project-oak/oak/actions/runs/1234/attempts/1")
+}
+
+func TestVerify_InvalidTokens(t *testing.T) {
+	issuer := newTestIssuer(t)
+	defer issuer.server.Close()
+	verifier := NewVerifier(GitHubActionsIssuer, issuer.server.URL, testAudience)
+
+	tests := map[string]func(claims map[string]interface{}){
+		"wrong audience": func(c map[string]interface{}) { c["aud"] = []string{"sigstore"} },
+		"wrong issuer":   func(c map[string]interface{}) { c["iss"] = "https://accounts.google.com" },
+		"expired":        func(c map[string]interface{}) { c["exp"] = time.Now().Add(-time.Minute).Unix() },
+		"not yet valid":  func(c map[string]interface{}) { c["nbf"] = time.Now().Add(time.Hour).Unix() },
+	}
+	for name, tweak := range tests {
+		claims := validClaims()
+		tweak(claims)
+		if _, err := verifier.Verify(context.Background(), issuer.token(t, claims)); err == nil {
+			t.Errorf("%s: expected failure", name)
+		}
+	}
+}
+
+func TestVerify_TamperedToken(t *testing.T) {
+	issuer := newTestIssuer(t)
+	defer issuer.server.Close()
+	verifier := NewVerifier(GitHubActionsIssuer, issuer.server.URL, testAudience)
+
+	token := issuer.token(t, validClaims())
+	other := validClaims()
+	other["repository"] = "attacker/oak"
+	otherToken := issuer.token(t, other)
+	// Combine the payload of one token with the signature of another.
+	tampered := otherToken[:len(otherToken)-len(token[len(token)-10:])] + token[len(token)-10:]
+
+	if _, err := verifier.Verify(context.Background(), tampered); err == nil {
+		t.Fatalf("expected failure for a tampered token")
+	}
+}
+
+func TestVerify_RefreshesKeysAtMostOncePerInterval(t *testing.T) {
+	issuer := newTestIssuer(t)
+	defer issuer.server.Close()
+	verifier := NewVerifier(GitHubActionsIssuer, issuer.server.URL, testAudience)
+
+	if _, err := verifier.Verify(context.Background(), issuer.token(t, validClaims())); err != nil {
+		t.Fatalf("could not verify token: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := verifier.Verify(context.Background(), issuer.tokenWithKeyID(t, "unknown-kid", validClaims())); err == nil {
+			t.Fatalf("expected failure for an unknown key ID")
+		}
+	}
+	// Known keys are still served from the cache.
+	if _, err := verifier.Verify(context.Background(), issuer.token(t, validClaims())); err != nil {
+		t.Fatalf("could not verify token: %v", err)
+	}
+	testutil.AssertEq(t, "fetches", atomic.LoadInt32(&issuer.fetches), int32(1))
+
+	// Once the interval has passed, the keys are fetched again.
+	verifier.mu.Lock()
+	verifier.lastRefresh = time.Now().Add(-minKeyRefreshInterval)
+	verifier.mu.Unlock()
+	if _, err := verifier.Verify(context.Background(), issuer.tokenWithKeyID(t, "unknown-kid", validClaims())); err == nil {
+		t.Fatalf("expected failure for an unknown key ID")
+	}
+	testutil.AssertEq(t, "fetches", atomic.LoadInt32(&issuer.fetches), int32(2))
+}
+
+func TestVerify_OversizedKeys(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := w.Write([]byte(`{"keys": [], "padding": "` + strings.Repeat("a", maxJWKSBytes) + `"}`)); err != nil {
+			t.Errorf("could not write keys: %v", err)
+		}
+	}))
+	defer server.Close()
+	issuer := newTestIssuer(t)
+	defer issuer.server.Close()
+	verifier := NewVerifier(GitHubActionsIssuer, server.URL, testAudience)
+
+	_, err := verifier.Verify(context.Background(), issuer.token(t, validClaims()))
+	if err == nil || !strings.Contains(err.Error(), "exceed") {
+		t.Fatalf("expected failure for oversized keys, got: %v", err)
+	}
+}
+
+func TestMatchesRepository(t *testing.T) {
+	claims := GitHubClaims{Repository: "project-oak/oak"}
+	tests := map[string]bool{
+		"git+https://github.com/project-oak/oak@refs/heads/main": true,
+		"https://github.com/project-oak/oak.git":                 true,
+		"https://github.com/project-oak/oak":                     true,
+		"https://github.com/project-oak/transparent-release":     false,
+		"https://gitlab.com/project-oak/oak":                     false,
+	}
+	for uri, want := range tests {
+		testutil.AssertEq(t, uri, claims.MatchesRepository(uri), want)
+	}
+}
@@ -0,0 +1,43 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package parallel runs independent tasks concurrently, with a bound on the
+// number of tasks that run at the same time.
+package parallel
+
+import "sync"
+
+// Do calls task with every index in [0, count), running at most limit tasks
+// concurrently, or one if limit is not positive, and returns the errors of the
+// tasks, indexed like the tasks. The errors of the tasks that succeeded are
+// nil. Tasks must not depend on the order in which they run.
+func Do(count, limit int, task func(i int) error) []error {
+	if limit < 1 {
+		limit = 1
+	}
+	errs := make([]error, count)
+	semaphore := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for i := 0; i < count; i++ {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			errs[i] = task(i)
+		}(i)
+	}
+	wg.Wait()
+	return errs
+}
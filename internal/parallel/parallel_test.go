@@ -0,0 +1,74 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parallel
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/project-oak/transparent-release/internal/testutil"
+)
+
+func TestDo(t *testing.T) {
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+	results := make([]int, 10)
+	errs := Do(len(results), 3, func(i int) error {
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mu.Unlock()
+		defer func() {
+			mu.Lock()
+			running--
+			mu.Unlock()
+		}()
+		if i%4 == 1 {
+			return fmt.Errorf("task %d failed", i)
+		}
+		results[i] = i * i
+		return nil
+	})
+
+	if maxRunning > 3 {
+		t.Errorf("Unexpected number of concurrent tasks: got %d, want at most 3", maxRunning)
+	}
+	testutil.AssertEq(t, "number of errors", len(errs), len(results))
+	for i, err := range errs {
+		if i%4 == 1 {
+			if err == nil {
+				t.Errorf("Expected an error for task %d", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Unexpected error for task %d: %v", i, err)
+		}
+		testutil.AssertEq(t, fmt.Sprintf("result of task %d", i), results[i], i*i)
+	}
+}
+
+func TestDo_NonPositiveLimit(t *testing.T) {
+	count := 0
+	errs := Do(3, 0, func(int) error {
+		count++
+		return nil
+	})
+	testutil.AssertEq(t, "number of errors", len(errs), 3)
+	testutil.AssertEq(t, "number of tasks", count, 3)
+}
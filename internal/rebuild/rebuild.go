@@ -0,0 +1,607 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package rebuild re-executes the container-based build described in a SLSA
+// v1 provenance, and checks that the rebuilt artifact matches the subject of
+// the provenance, as evidence that the build is reproducible.
+package rebuild
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/project-oak/transparent-release/internal/model"
+	slsav1 "github.com/project-oak/transparent-release/pkg/intoto/slsa_provenance/v1"
+)
+
+// Build is a container-based build, as described in a SLSA v1 provenance.
+type Build struct {
+	// RepoURL is the URL of the Git repository with the source, e.g.,
+	// `https://github.com/project-oak/oak`.
+	RepoURL string
+	// Commit is the hex-encoded digest of the source commit.
+	Commit string
+	// Tree is the hex-encoded digest of the Git tree of the source commit.
+	// Optional. If set, the tree of the checkout must match it.
+	Tree string
+	// Image is the builder image, pinned by its SHA256 digest.
+	Image string
+	// Command is the build command, run in the root of the repository.
+	Command []string
+	// ArtifactPath is the path of the built artifact, relative to the root of
+	// the repository. It may be a pattern, in the syntax of path.Match, that
+	// matches several artifacts, e.g., `target/release/*.so`.
+	ArtifactPath string
+	// Env holds the environment variables that are set in the container.
+	Env map[string]string
+	// Offline disables network access in the container.
+	Offline bool
+	// Platform is the platform of the builder image, e.g., `linux/amd64`.
+	// Defaults to the platform of the host.
+	Platform string
+}
+
+// Runner checks out sources, and runs builds in containers.
+type Runner interface {
+	// Checkout fetches the given commit of the Git repository with the given
+	// URL into dir, and checks it out. The returned metrics may be nil.
+	Checkout(ctx context.Context, repoURL, commit, dir string) (*CheckoutMetrics, error)
+	// Run runs the command of the given build in a new container from its
+	// builder image, with dir as its working directory, and writes the
+	// standard output and standard error of the build to output.
+	Run(ctx context.Context, build *Build, dir string, output io.Writer) error
+}
+
+// Container runtimes supported by ContainerRunner. They share the command
+// line interface of `docker run`.
+const (
+	DockerRuntime  = "docker"
+	PodmanRuntime  = "podman"
+	NerdctlRuntime = "nerdctl"
+)
+
+// ContainerRunner checks out sources with `git`, and runs builds with the
+// `run` command of a container runtime, mounting the source at /workspace.
+// Podman and nerdctl can run builds without a Docker daemon, and rootless.
+type ContainerRunner struct {
+	// Runtime is the command of the container runtime, one of DockerRuntime,
+	// PodmanRuntime, and NerdctlRuntime. Defaults to DockerRuntime.
+	Runtime string
+	// VCS checks out the sources. Defaults to GitCLI.
+	VCS VCS
+}
+
+// NewContainerRunner returns a ContainerRunner for the given container
+// runtime, or an error if the runtime is not supported.
+func NewContainerRunner(runtime string) (ContainerRunner, error) {
+	switch runtime {
+	case DockerRuntime, PodmanRuntime, NerdctlRuntime:
+		return ContainerRunner{Runtime: runtime}, nil
+	default:
+		return ContainerRunner{}, fmt.Errorf("unsupported container runtime %q, want %s, %s, or %s", runtime, DockerRuntime, PodmanRuntime, NerdctlRuntime)
+	}
+}
+
+// Checkout fetches the given commit of the repository with the given URL into
+// dir, and checks it out, with the VCS of the runner.
+func (r ContainerRunner) Checkout(ctx context.Context, repoURL, commit, dir string) (*CheckoutMetrics, error) {
+	vcs := r.VCS
+	if vcs == nil {
+		vcs = GitCLI{}
+	}
+	return vcs.Checkout(ctx, repoURL, commit, dir)
+}
+
+// Run runs the command of the given build in a new container from its builder
+// image, with dir mounted as its working directory, and writes the standard
+// output and standard error of the build to output.
+func (r ContainerRunner) Run(ctx context.Context, build *Build, dir string, output io.Writer) error {
+	runtime := r.Runtime
+	if runtime == "" {
+		runtime = DockerRuntime
+	}
+	return runWithOutput(ctx, "", output, runtime, containerRunArgs(build, dir)...)
+}
+
+// containerRunArgs returns the arguments of `docker run`, or the `run`
+// command of another container runtime, for running the command of the given
+// build, with dir mounted as its working directory.
+func containerRunArgs(build *Build, dir string) []string {
+	args := []string{"run", "--rm", "--volume", dir + ":/workspace", "--workdir", "/workspace"}
+	names := make([]string, 0, len(build.Env))
+	for name := range build.Env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		args = append(args, "--env", name+"="+build.Env[name])
+	}
+	if build.Offline {
+		args = append(args, "--network", "none")
+	}
+	if build.Platform != "" {
+		args = append(args, "--platform", build.Platform)
+	}
+	args = append(args, "--entrypoint", build.Command[0], build.Image)
+	return append(args, build.Command[1:]...)
+}
+
+func run(ctx context.Context, dir, name string, args ...string) error {
+	return runWithOutput(ctx, dir, io.Discard, name, args...)
+}
+
+// runWithOutput runs the given command, and writes its standard output and
+// standard error to output. Both are written through the same writer, so that
+// `exec` copies them from a single pipe, in order, rather than concurrently.
+// The returned error includes the output, and wraps the *exec.ExitError, if
+// any, so that the exit code can be recovered.
+func runWithOutput(ctx context.Context, dir string, output io.Writer, name string, args ...string) error {
+	var combined bytes.Buffer
+	writer := io.MultiWriter(output, &combined)
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	cmd.Stdout = writer
+	cmd.Stderr = writer
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("running %s %q: %w: %s", name, args, err, combined.String())
+	}
+	return nil
+}
+
+// RunConfig holds optional settings for running a Build.
+type RunConfig struct {
+	runner  Runner
+	vcs     VCS
+	workDir string
+	logsDir string
+}
+
+// LogsDir returns the directory in which the build log is stored, or an empty
+// string if the build log is not stored.
+func (c *RunConfig) LogsDir() string {
+	return c.logsDir
+}
+
+// WithRunner sets the Runner for checking out the source and running the
+// build. Defaults to a ContainerRunner with Docker.
+func WithRunner(runner Runner) func(c *RunConfig) {
+	return func(c *RunConfig) {
+		c.runner = runner
+	}
+}
+
+// WithVCS sets the VCS for inspecting the checked out source, e.g., for
+// comparing its tree with the tree of the build. It should match the VCS of
+// the Runner. Defaults to GitCLI.
+func WithVCS(vcs VCS) func(c *RunConfig) {
+	return func(c *RunConfig) {
+		c.vcs = vcs
+	}
+}
+
+// WithWorkDir sets the directory into which the source is checked out, and
+// which is kept after the build, e.g., for inspecting a mismatching artifact.
+// The directory must not exist or be empty. Defaults to a temporary
+// directory, which is removed after the build.
+func WithWorkDir(dir string) func(c *RunConfig) {
+	return func(c *RunConfig) {
+		c.workDir = dir
+	}
+}
+
+// WithLogsDir sets the directory in which the standard output and standard
+// error of the build are stored, as BuildLogName, together with a BuildLog
+// summary, as BuildLogSummaryName. If the build checks out its source, the
+// CheckoutMetrics are stored as CheckoutSummaryName. The directory is created
+// if it does not exist. By default, the output of the build is discarded.
+func WithLogsDir(dir string) func(c *RunConfig) {
+	return func(c *RunConfig) {
+		c.logsDir = dir
+	}
+}
+
+const (
+	// BuildLogName is the name of the file with the output of the build, in
+	// the logs directory.
+	BuildLogName = "build.log"
+	// BuildLogSummaryName is the name of the file with the JSON-encoded
+	// BuildLog, in the logs directory.
+	BuildLogSummaryName = "build-log.json"
+)
+
+// BuildLog summarizes the log of a build, stored in a logs directory.
+type BuildLog struct {
+	// SHA256Digest is the hex-encoded SHA256 digest of the log file.
+	SHA256Digest string `json:"sha256Digest"`
+	// ExitCode is the exit code of the build command, or -1 if the build
+	// command could not be run, or did not exit.
+	ExitCode int `json:"exitCode"`
+	// DurationMillis is the duration of the build in milliseconds.
+	DurationMillis int64 `json:"durationMillis"`
+}
+
+// ReadBuildLog reads the BuildLog summary from the given logs directory, as
+// written by a build with WithLogsDir.
+func ReadBuildLog(logsDir string) (*BuildLog, error) {
+	summaryBytes, err := os.ReadFile(filepath.Join(logsDir, BuildLogSummaryName))
+	if err != nil {
+		return nil, fmt.Errorf("could not read the build log summary: %v", err)
+	}
+	var buildLog BuildLog
+	if err := json.Unmarshal(summaryBytes, &buildLog); err != nil {
+		return nil, fmt.Errorf("could not unmarshal the build log summary: %v", err)
+	}
+	return &buildLog, nil
+}
+
+// FromProvenance returns the build described in the given container-based
+// SLSA v1 provenance.
+func FromProvenance(provenance *model.ValidatedProvenance) (*Build, error) {
+	predicateType := provenance.PredicateType()
+	if predicateType != slsav1.PredicateSLSAProvenance && predicateType != slsav1.PredicateSLSAProvenanceDraft {
+		return nil, fmt.Errorf("unsupported predicateType (%q) for rebuilding, want a SLSA v1 provenance", predicateType)
+	}
+	predicate, err := slsav1.ParseContainerBasedSLSAv1Provenance(provenance.GetProvenance().Predicate)
+	if err != nil {
+		return nil, fmt.Errorf("parsing SLSA v1 provenance predicate: %v", err)
+	}
+	return FromPredicate(predicate)
+}
+
+// FromPredicate returns the build described in the given container-based SLSA
+// v1 provenance predicate, as returned by
+// slsav1.ParseContainerBasedSLSAv1Provenance. Fails unless the source passes
+// ValidateSource.
+func FromPredicate(predicate *slsav1.ProvenancePredicate) (*Build, error) {
+	if predicate.BuildDefinition.BuildType != slsav1.DockerBasedBuildType {
+		return nil, fmt.Errorf("unsupported buildType (%q) for rebuilding, want %q", predicate.BuildDefinition.BuildType, slsav1.DockerBasedBuildType)
+	}
+	parameters := predicate.BuildDefinition.ExternalParameters.(slsav1.DockerBasedExternalParameters)
+
+	if !strings.HasPrefix(parameters.Source.URI, "git+") {
+		return nil, fmt.Errorf("the source %q is not a Git repository", parameters.Source.URI)
+	}
+	repoURL := strings.TrimPrefix(parameters.Source.URI, "git+")
+	// The URI may be qualified with the ref that the build was invoked on.
+	if i := strings.LastIndex(repoURL, "@"); i >= 0 && strings.HasPrefix(repoURL[i+1:], "refs/") {
+		repoURL = repoURL[:i]
+	}
+	commit := parameters.Source.Digest["sha1"]
+	if commit == "" {
+		commit = parameters.Source.Digest["sha256"]
+	}
+	if commit == "" {
+		return nil, fmt.Errorf("no sha1 or sha256 digest of the source commit: %v", parameters.Source.Digest)
+	}
+	if err := ValidateSource(repoURL, commit); err != nil {
+		return nil, fmt.Errorf("invalid source in the provenance: %v", err)
+	}
+
+	imageDigest, err := predicate.BuilderImageDigest()
+	if err != nil {
+		return nil, err
+	}
+	// The image is always pinned by the digest in the provenance, whether or
+	// not its URI has a tag or a digest.
+	image := parameters.BuilderImage.URI
+	if i := strings.Index(image, "@"); i >= 0 {
+		image = image[:i]
+	}
+	if image == "" {
+		return nil, fmt.Errorf("the builder image has no URI")
+	}
+
+	if len(parameters.Config.Command) == 0 {
+		return nil, fmt.Errorf("the provenance has no build command")
+	}
+	if parameters.Config.ArtifactPath == "" {
+		return nil, fmt.Errorf("the provenance has no artifact path")
+	}
+
+	build := &Build{
+		RepoURL:      repoURL,
+		Commit:       commit,
+		Image:        image + "@sha256:" + imageDigest,
+		Command:      parameters.Config.Command,
+		ArtifactPath: parameters.Config.ArtifactPath,
+		Env:          parameters.Config.Env,
+		Tree:         parameters.Source.Digest[slsav1.GitTreeDigestKey],
+	}
+	if options := parameters.Config.Options; options != nil {
+		build.Offline = options.Offline
+		build.Platform = options.Platform
+	}
+	return build, nil
+}
+
+// Verify re-executes the build described in the given container-based SLSA v1
+// provenance, which must have a single subject, and checks that the SHA256
+// digest of the rebuilt artifact matches the digest of the subject. If the
+// artifact path of the build is a pattern, the subject is compared to the
+// matching artifact with the same file name.
+func Verify(ctx context.Context, provenance *model.ValidatedProvenance, options ...func(c *RunConfig)) error {
+	if provenance.SubjectCount() != 1 {
+		return fmt.Errorf("the provenance has %d subjects, select one of them to rebuild it", provenance.SubjectCount())
+	}
+	want := provenance.GetBinarySHA256Digest()
+	if want == "" {
+		return fmt.Errorf("the subject of the provenance has no SHA256 digest")
+	}
+	build, err := FromProvenance(provenance)
+	if err != nil {
+		return err
+	}
+	artifacts, err := build.RunAll(ctx, options...)
+	if err != nil {
+		return err
+	}
+	artifactPath, got, err := selectArtifact(artifacts, provenance.GetBinaryName())
+	if err != nil {
+		return err
+	}
+	if got != want {
+		return fmt.Errorf("the rebuilt artifact %s has SHA256 digest %s, but the provenance subject has %s", artifactPath, got, want)
+	}
+	return nil
+}
+
+// selectArtifact returns the path and digest of the only artifact in the given
+// map, or else of the artifact with the given file name.
+func selectArtifact(artifacts map[string]string, name string) (string, string, error) {
+	if len(artifacts) == 1 {
+		for artifactPath, digest := range artifacts {
+			return artifactPath, digest, nil
+		}
+	}
+	for artifactPath, digest := range artifacts {
+		if path.Base(artifactPath) == name {
+			return artifactPath, digest, nil
+		}
+	}
+	return "", "", fmt.Errorf("none of the %d rebuilt artifacts is named %q", len(artifacts), name)
+}
+
+// Run checks out the source of the build, runs the build command in the
+// builder image, and returns the hex-encoded SHA256 digest of the artifact.
+// Fails if the artifact path is a pattern that does not match exactly one
+// artifact.
+func (b *Build) Run(ctx context.Context, options ...func(c *RunConfig)) (string, error) {
+	artifacts, err := b.RunAll(ctx, options...)
+	if err != nil {
+		return "", err
+	}
+	return b.singleArtifact(artifacts)
+}
+
+// RunAll checks out the source of the build, runs the build command in the
+// builder image, and returns the hex-encoded SHA256 digests of all artifacts
+// matching the artifact path, keyed by their slash-separated paths relative
+// to the root of the source. If the build has a tree, the build fails unless
+// the checkout has the same tree.
+func (b *Build) RunAll(ctx context.Context, options ...func(c *RunConfig)) (map[string]string, error) {
+	config := &RunConfig{runner: ContainerRunner{}, vcs: GitCLI{}}
+	for _, option := range options {
+		option(config)
+	}
+
+	dir := config.workDir
+	if dir == "" {
+		tempDir, err := os.MkdirTemp("", "rebuild-")
+		if err != nil {
+			return nil, fmt.Errorf("could not create a work directory: %v", err)
+		}
+		defer os.RemoveAll(tempDir)
+		dir = filepath.Join(tempDir, "source")
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("could not resolve the work directory: %v", err)
+	}
+	if _, err := b.artifactPathIn(dir); err != nil {
+		return nil, err
+	}
+
+	metrics, err := config.runner.Checkout(ctx, b.RepoURL, b.Commit, dir)
+	if err != nil {
+		return nil, fmt.Errorf("could not check out %s at %s: %v", b.RepoURL, b.Commit, err)
+	}
+	if metrics != nil && config.logsDir != "" {
+		if err := writeCheckoutMetrics(config.logsDir, metrics); err != nil {
+			return nil, err
+		}
+	}
+	if b.Tree != "" {
+		tree, err := config.vcs.Tree(ctx, dir, "HEAD")
+		if err != nil {
+			return nil, err
+		}
+		if tree != b.Tree {
+			return nil, fmt.Errorf("the tree of the checkout of %s is %s, want %s", b.Commit, tree, b.Tree)
+		}
+	}
+	if err := b.run(ctx, config, dir); err != nil {
+		return nil, err
+	}
+	return b.artifactsIn(dir)
+}
+
+// RunInSource runs the build command in the builder image, in the given
+// existing checkout of the source, e.g., in a CI job, and returns the
+// hex-encoded SHA256 digest of the artifact. The checkout is not compared to
+// the commit of the build. The work directory in the RunConfig is ignored.
+// Fails if the artifact path is a pattern that does not match exactly one
+// artifact.
+func (b *Build) RunInSource(ctx context.Context, dir string, options ...func(c *RunConfig)) (string, error) {
+	artifacts, err := b.RunInSourceAll(ctx, dir, options...)
+	if err != nil {
+		return "", err
+	}
+	return b.singleArtifact(artifacts)
+}
+
+// RunInSourceAll is like RunInSource, but returns the digests of all artifacts
+// matching the artifact path, as RunAll.
+func (b *Build) RunInSourceAll(ctx context.Context, dir string, options ...func(c *RunConfig)) (map[string]string, error) {
+	config := &RunConfig{runner: ContainerRunner{}, vcs: GitCLI{}}
+	for _, option := range options {
+		option(config)
+	}
+
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("could not resolve the source directory: %v", err)
+	}
+	if _, err := b.artifactPathIn(dir); err != nil {
+		return nil, err
+	}
+	if err := b.run(ctx, config, dir); err != nil {
+		return nil, err
+	}
+	return b.artifactsIn(dir)
+}
+
+// artifactPathIn returns the absolute path, or pattern, of the artifact in
+// the given absolute source directory, or an error if it is outside of the
+// directory.
+func (b *Build) artifactPathIn(dir string) (string, error) {
+	artifactPath := filepath.Join(dir, filepath.FromSlash(b.ArtifactPath))
+	if !strings.HasPrefix(artifactPath, dir+string(filepath.Separator)) {
+		return "", fmt.Errorf("the artifact path %q is outside of the source", b.ArtifactPath)
+	}
+	return artifactPath, nil
+}
+
+// artifactsIn returns the SHA256 digests of the artifacts matching the
+// artifact path in the given absolute source directory, keyed by their
+// slash-separated paths relative to the directory. Directories matching a
+// pattern are skipped.
+func (b *Build) artifactsIn(dir string) (map[string]string, error) {
+	artifactPath, err := b.artifactPathIn(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !isPattern(b.ArtifactPath) {
+		digest, err := model.ComputeSHA256Digest(artifactPath)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]string{path.Clean(b.ArtifactPath): digest}, nil
+	}
+
+	matches, err := filepath.Glob(artifactPath)
+	if err != nil {
+		return nil, fmt.Errorf("invalid artifact path %q: %v", b.ArtifactPath, err)
+	}
+	artifacts := make(map[string]string, len(matches))
+	for _, match := range matches {
+		if info, err := os.Stat(match); err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		digest, err := model.ComputeSHA256Digest(match)
+		if err != nil {
+			return nil, err
+		}
+		relative, err := filepath.Rel(dir, match)
+		if err != nil {
+			return nil, err
+		}
+		artifacts[filepath.ToSlash(relative)] = digest
+	}
+	if len(artifacts) == 0 {
+		return nil, fmt.Errorf("no artifact matches the artifact path %q", b.ArtifactPath)
+	}
+	return artifacts, nil
+}
+
+// singleArtifact returns the digest of the only artifact in the given map.
+func (b *Build) singleArtifact(artifacts map[string]string) (string, error) {
+	if len(artifacts) != 1 {
+		return "", fmt.Errorf("the artifact path %q matches %d artifacts, want one", b.ArtifactPath, len(artifacts))
+	}
+	for _, digest := range artifacts {
+		return digest, nil
+	}
+	return "", nil
+}
+
+// isPattern returns true if the given artifact path is a pattern, in the
+// syntax of path.Match.
+func isPattern(artifactPath string) bool {
+	return strings.ContainsAny(artifactPath, `*?[\`)
+}
+
+// run runs the build command in the given absolute source directory, and
+// stores the build log, if the RunConfig has a logs directory.
+func (b *Build) run(ctx context.Context, config *RunConfig, dir string) error {
+	if config.logsDir == "" {
+		if err := config.runner.Run(ctx, b, dir, io.Discard); err != nil {
+			return fmt.Errorf("could not run the build: %v", err)
+		}
+		return nil
+	}
+
+	if err := os.MkdirAll(config.logsDir, 0o755); err != nil {
+		return fmt.Errorf("could not create the logs directory: %v", err)
+	}
+	logFile, err := os.Create(filepath.Join(config.logsDir, BuildLogName))
+	if err != nil {
+		return fmt.Errorf("could not create the build log: %v", err)
+	}
+	defer logFile.Close()
+
+	hash := sha256.New()
+	startedOn := time.Now()
+	runErr := config.runner.Run(ctx, b, dir, io.MultiWriter(logFile, hash))
+	buildLog := BuildLog{
+		SHA256Digest:   hex.EncodeToString(hash.Sum(nil)),
+		DurationMillis: time.Since(startedOn).Milliseconds(),
+	}
+	var exitErr *exec.ExitError
+	if errors.As(runErr, &exitErr) {
+		buildLog.ExitCode = exitErr.ExitCode()
+	} else if runErr != nil {
+		buildLog.ExitCode = -1
+	}
+	if err := writeBuildLog(config.logsDir, &buildLog); err != nil {
+		return err
+	}
+	if runErr != nil {
+		return fmt.Errorf("could not run the build (exit code %d): %v", buildLog.ExitCode, runErr)
+	}
+	return nil
+}
+
+func writeBuildLog(logsDir string, buildLog *BuildLog) error {
+	summaryBytes, err := json.MarshalIndent(buildLog, "", "    ")
+	if err != nil {
+		return fmt.Errorf("could not marshal the build log summary: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(logsDir, BuildLogSummaryName), summaryBytes, 0o600); err != nil {
+		return fmt.Errorf("could not write the build log summary: %v", err)
+	}
+	return nil
+}
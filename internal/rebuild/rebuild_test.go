@@ -0,0 +1,381 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rebuild
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/project-oak/transparent-release/internal/model"
+)
+
+const (
+	slsav1ProvenancePath  = "../../testdata/slsa_v1_provenance.json"
+	slsav02ProvenancePath = "../../testdata/slsa_v02_provenance.json"
+	subjectDigest         = "813841dda3818d616aa3e706e49d0286dc825c5dbad4a75cfb37b91ba412238b"
+	artifactPath          = "./oak_functions_enclave_app/target/x86_64-unknown-none/release/oak_functions_enclave_app"
+)
+
+// fakeRunner writes the given artifact to the artifact path of the test
+// provenance, instead of building it.
+type fakeRunner struct {
+	artifact  string
+	checkouts []string
+	images    []string
+}
+
+func (r *fakeRunner) Checkout(_ context.Context, repoURL, commit, dir string) (*CheckoutMetrics, error) {
+	r.checkouts = append(r.checkouts, repoURL+"@"+commit)
+	return nil, os.MkdirAll(dir, 0o755)
+}
+
+func (r *fakeRunner) Run(_ context.Context, build *Build, dir string, output io.Writer) error {
+	r.images = append(r.images, build.Image)
+	fmt.Fprintf(output, "Building in %s\n", build.Image)
+	if strings.Join(build.Command, " ") != "env --chdir=oak_functions_enclave_app cargo build --release" {
+		return fmt.Errorf("unexpected command %q", build.Command)
+	}
+	path := filepath.Join(dir, artifactPath)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(r.artifact), 0o600)
+}
+
+// loadProvenance loads the test provenance, with the SHA256 digest of its
+// subject replaced by the digest of the given artifact.
+func loadProvenance(t *testing.T, path, artifact string) *model.ValidatedProvenance {
+	statementBytes, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("could not read the provenance file: %v", err)
+	}
+	sum256 := sha256.Sum256([]byte(artifact))
+	statement := strings.Replace(string(statementBytes), subjectDigest, hex.EncodeToString(sum256[:]), 1)
+	provenance, err := model.ParseStatementData([]byte(statement))
+	if err != nil {
+		t.Fatalf("couldn't parse the provenance: %v", err)
+	}
+	return provenance
+}
+
+func TestFromProvenance(t *testing.T) {
+	got, err := FromProvenance(loadProvenance(t, slsav1ProvenancePath, ""))
+	if err != nil {
+		t.Fatalf("couldn't get the build from the provenance: %v", err)
+	}
+
+	want := &Build{
+		RepoURL:      "https://github.com/project-oak/oak",
+		Commit:       "6bac02b6b0442ed944f57b7cba9a5f1119863ca4",
+		Image:        "europe-west2-docker.pkg.dev/oak-ci/oak-development/oak-development@sha256:51532c757d1008bbff696d053a1d05226f6387cf232aa80b6f9c13b0759ccea0",
+		Command:      []string{"env", "--chdir=oak_functions_enclave_app", "cargo", "build", "--release"},
+		ArtifactPath: artifactPath,
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("unexpected build: %s", diff)
+	}
+}
+
+func TestFromProvenance_InvalidSourceRejected(t *testing.T) {
+	for _, replacement := range []struct{ old, new string }{
+		{`"git+https://github.com/project-oak/oak"`, `"git+--upload-pack=touch /tmp/pwned"`},
+		{`"git+https://github.com/project-oak/oak"`, `"git+."`},
+		{`"6bac02b6b0442ed944f57b7cba9a5f1119863ca4"`, `"main"`},
+	} {
+		statementBytes, err := os.ReadFile(slsav1ProvenancePath)
+		if err != nil {
+			t.Fatalf("could not read the provenance file: %v", err)
+		}
+		statement := strings.Replace(string(statementBytes), replacement.old, replacement.new, 1)
+		provenance, err := model.ParseStatementData([]byte(statement))
+		if err != nil {
+			t.Fatalf("couldn't parse the provenance: %v", err)
+		}
+		if _, err := FromProvenance(provenance); err == nil || !strings.Contains(err.Error(), "invalid source") {
+			t.Errorf("expected the source with %s to be rejected, got %v", replacement.new, err)
+		}
+	}
+}
+
+func TestFromProvenance_Slsav02Unsupported(t *testing.T) {
+	if _, err := FromProvenance(loadProvenance(t, slsav02ProvenancePath, "")); err == nil || !strings.Contains(err.Error(), "unsupported predicateType") {
+		t.Fatalf("expected an unsupported predicate type, got %v", err)
+	}
+}
+
+func TestVerify_MatchingArtifactSucceeds(t *testing.T) {
+	runner := &fakeRunner{artifact: "reproducible"}
+	provenance := loadProvenance(t, slsav1ProvenancePath, "reproducible")
+
+	if err := Verify(context.Background(), provenance, WithRunner(runner)); err != nil {
+		t.Fatalf("couldn't verify the rebuild: %v", err)
+	}
+	if diff := cmp.Diff(runner.checkouts, []string{"https://github.com/project-oak/oak@6bac02b6b0442ed944f57b7cba9a5f1119863ca4"}); diff != "" {
+		t.Errorf("unexpected checkouts: %s", diff)
+	}
+}
+
+func TestVerify_MismatchingArtifactDetected(t *testing.T) {
+	runner := &fakeRunner{artifact: "not reproducible"}
+	provenance := loadProvenance(t, slsav1ProvenancePath, "reproducible")
+
+	if err := Verify(context.Background(), provenance, WithRunner(runner)); err == nil || !strings.Contains(err.Error(), "the provenance subject has") {
+		t.Fatalf("expected a digest mismatch, got %v", err)
+	}
+}
+
+func TestBuild_RunKeepsWorkDir(t *testing.T) {
+	runner := &fakeRunner{artifact: "reproducible"}
+	build, err := FromProvenance(loadProvenance(t, slsav1ProvenancePath, "reproducible"))
+	if err != nil {
+		t.Fatalf("couldn't get the build from the provenance: %v", err)
+	}
+	workDir := filepath.Join(t.TempDir(), "source")
+
+	if _, err := build.Run(context.Background(), WithRunner(runner), WithWorkDir(workDir)); err != nil {
+		t.Fatalf("couldn't run the build: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(workDir, artifactPath)); err != nil {
+		t.Errorf("the artifact has not been kept: %v", err)
+	}
+}
+
+func TestBuild_RunArtifactOutsideSourceFails(t *testing.T) {
+	runner := &fakeRunner{}
+	build := &Build{Command: []string{"true"}, ArtifactPath: "../artifact"}
+
+	if _, err := build.Run(context.Background(), WithRunner(runner)); err == nil || !strings.Contains(err.Error(), "outside of the source") {
+		t.Fatalf("expected an artifact outside of the source, got %v", err)
+	}
+	if len(runner.images) != 0 {
+		t.Errorf("the build has run: %v", runner.images)
+	}
+}
+
+func TestBuild_RunInSourceWritesBuildLog(t *testing.T) {
+	runner := &fakeRunner{artifact: "reproducible"}
+	build, err := FromProvenance(loadProvenance(t, slsav1ProvenancePath, "reproducible"))
+	if err != nil {
+		t.Fatalf("couldn't get the build from the provenance: %v", err)
+	}
+	logsDir := filepath.Join(t.TempDir(), "logs")
+
+	if _, err := build.RunInSource(context.Background(), t.TempDir(), WithRunner(runner), WithLogsDir(logsDir)); err != nil {
+		t.Fatalf("couldn't run the build: %v", err)
+	}
+
+	logBytes, err := os.ReadFile(filepath.Join(logsDir, BuildLogName))
+	if err != nil {
+		t.Fatalf("couldn't read the build log: %v", err)
+	}
+	if want := "Building in " + build.Image + "\n"; string(logBytes) != want {
+		t.Errorf("unexpected build log: got %q, want %q", logBytes, want)
+	}
+	buildLog, err := ReadBuildLog(logsDir)
+	if err != nil {
+		t.Fatalf("couldn't read the build log summary: %v", err)
+	}
+	sum256 := sha256.Sum256(logBytes)
+	if buildLog.SHA256Digest != hex.EncodeToString(sum256[:]) {
+		t.Errorf("unexpected build log digest: got %s, want %x", buildLog.SHA256Digest, sum256)
+	}
+	if buildLog.ExitCode != 0 {
+		t.Errorf("unexpected exit code: got %d, want 0", buildLog.ExitCode)
+	}
+}
+
+// shellRunner runs the command of the build with `sh -c`, on the host.
+type shellRunner struct{}
+
+func (shellRunner) Checkout(_ context.Context, _, _, _ string) (*CheckoutMetrics, error) {
+	return nil, nil
+}
+
+func (shellRunner) Run(ctx context.Context, build *Build, dir string, output io.Writer) error {
+	return runWithOutput(ctx, dir, output, "sh", "-c", strings.Join(build.Command, " "))
+}
+
+func TestBuild_RunInSourceRecordsFailedBuild(t *testing.T) {
+	build := &Build{Command: []string{"echo out; echo err >&2; exit 3"}, ArtifactPath: "artifact"}
+	logsDir := t.TempDir()
+
+	if _, err := build.RunInSource(context.Background(), t.TempDir(), WithRunner(shellRunner{}), WithLogsDir(logsDir)); err == nil || !strings.Contains(err.Error(), "exit code 3") {
+		t.Fatalf("expected a build failing with exit code 3, got %v", err)
+	}
+
+	logBytes, err := os.ReadFile(filepath.Join(logsDir, BuildLogName))
+	if err != nil {
+		t.Fatalf("couldn't read the build log: %v", err)
+	}
+	if string(logBytes) != "out\nerr\n" {
+		t.Errorf("unexpected build log: got %q, want both the standard output and error", logBytes)
+	}
+	summaryBytes, err := os.ReadFile(filepath.Join(logsDir, BuildLogSummaryName))
+	if err != nil {
+		t.Fatalf("couldn't read the build log summary: %v", err)
+	}
+	var buildLog BuildLog
+	if err := json.Unmarshal(summaryBytes, &buildLog); err != nil {
+		t.Fatalf("couldn't unmarshal the build log summary: %v", err)
+	}
+	if buildLog.ExitCode != 3 {
+		t.Errorf("unexpected exit code: got %d, want 3", buildLog.ExitCode)
+	}
+}
+
+func TestContainerRunArgs(t *testing.T) {
+	build := &Build{
+		Image:    "builder@sha256:1234",
+		Command:  []string{"cargo", "build"},
+		Env:      map[string]string{"RUSTFLAGS": "-C lto", "CARGO_INCREMENTAL": "0"},
+		Offline:  true,
+		Platform: "linux/amd64",
+	}
+
+	got := containerRunArgs(build, "/src")
+
+	want := []string{
+		"run", "--rm", "--volume", "/src:/workspace", "--workdir", "/workspace",
+		"--env", "CARGO_INCREMENTAL=0", "--env", "RUSTFLAGS=-C lto",
+		"--network", "none", "--platform", "linux/amd64",
+		"--entrypoint", "cargo", "builder@sha256:1234", "build",
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("unexpected docker run arguments: %s", diff)
+	}
+}
+
+func TestNewContainerRunner(t *testing.T) {
+	for _, runtime := range []string{DockerRuntime, PodmanRuntime, NerdctlRuntime} {
+		runner, err := NewContainerRunner(runtime)
+		if err != nil {
+			t.Errorf("unexpected error for %s: %v", runtime, err)
+		}
+		if runner.Runtime != runtime {
+			t.Errorf("unexpected runtime: got %q, want %q", runner.Runtime, runtime)
+		}
+	}
+	if _, err := NewContainerRunner("lxc"); err == nil {
+		t.Errorf("expected an unsupported container runtime")
+	}
+}
+
+func TestContainerRunner_RunUsesRuntime(t *testing.T) {
+	// The fake runtime prints its arguments.
+	runtime := filepath.Join(t.TempDir(), "podman")
+	if err := os.WriteFile(runtime, []byte("#!/bin/sh\necho \"$@\"\n"), 0o700); err != nil {
+		t.Fatalf("could not write the fake runtime: %v", err)
+	}
+	build := &Build{Image: "builder@sha256:1234", Command: []string{"make"}}
+
+	var output strings.Builder
+	if err := (ContainerRunner{Runtime: runtime}).Run(context.Background(), build, "/src", &output); err != nil {
+		t.Fatalf("could not run the build: %v", err)
+	}
+	want := strings.Join(containerRunArgs(build, "/src"), " ") + "\n"
+	if output.String() != want {
+		t.Errorf("unexpected arguments of the runtime: got %q, want %q", output.String(), want)
+	}
+}
+
+func TestBuild_RunInSourceAllArtifactPattern(t *testing.T) {
+	build := &Build{Command: []string{"mkdir -p out/dir.so && echo a > out/a.so && echo b > out/b.so"}, ArtifactPath: "out/*.so"}
+
+	artifacts, err := build.RunInSourceAll(context.Background(), t.TempDir(), WithRunner(shellRunner{}))
+	if err != nil {
+		t.Fatalf("couldn't run the build: %v", err)
+	}
+	sumA := sha256.Sum256([]byte("a\n"))
+	sumB := sha256.Sum256([]byte("b\n"))
+	want := map[string]string{"out/a.so": hex.EncodeToString(sumA[:]), "out/b.so": hex.EncodeToString(sumB[:])}
+	if diff := cmp.Diff(want, artifacts); diff != "" {
+		t.Errorf("unexpected artifacts (-want +got):\n%s", diff)
+	}
+
+	if _, err := build.RunInSource(context.Background(), t.TempDir(), WithRunner(shellRunner{})); err == nil || !strings.Contains(err.Error(), "matches 2 artifacts") {
+		t.Errorf("expected several artifacts, got %v", err)
+	}
+	path, digest, err := selectArtifact(artifacts, "b.so")
+	if err != nil || path != "out/b.so" || digest != want["out/b.so"] {
+		t.Errorf("unexpected selected artifact %s (%s): %v", path, digest, err)
+	}
+	if _, _, err := selectArtifact(artifacts, "c.so"); err == nil {
+		t.Errorf("expected no artifact named c.so")
+	}
+}
+
+// gitRunner checks out a new Git repository with a single file and commit,
+// instead of cloning the repository of the build.
+type gitRunner struct {
+	fakeRunner
+}
+
+func (r *gitRunner) Checkout(ctx context.Context, _, _, dir string) (*CheckoutMetrics, error) {
+	if err := run(ctx, "", "git", "init", "--quiet", dir); err != nil {
+		return nil, err
+	}
+	return nil, commitFile(ctx, dir, "README.md", "Oak\n")
+}
+
+// commitFile writes the given file in the Git repository in dir, and commits
+// it.
+func commitFile(ctx context.Context, dir, name, content string) error {
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+		return err
+	}
+	if err := run(ctx, dir, "git", "add", name); err != nil {
+		return err
+	}
+	return run(ctx, dir, "git", "-c", "user.name=Oak", "-c", "user.email=oak@example.com", "commit", "--quiet", "--message", "Update "+name)
+}
+
+func TestBuild_RunVerifiesTree(t *testing.T) {
+	build, err := FromProvenance(loadProvenance(t, slsav1ProvenancePath, ""))
+	if err != nil {
+		t.Fatalf("couldn't get the build from the provenance: %v", err)
+	}
+	// The tree of a commit only depends on the content of the source.
+	workDir := filepath.Join(t.TempDir(), "source")
+	runner := &gitRunner{fakeRunner{artifact: "artifact"}}
+	if _, err := runner.Checkout(context.Background(), build.RepoURL, build.Commit, workDir); err != nil {
+		t.Fatalf("couldn't create the Git repository: %v", err)
+	}
+	tree, err := GitCLI{}.Tree(context.Background(), workDir, "HEAD")
+	if err != nil {
+		t.Fatalf("couldn't get the tree: %v", err)
+	}
+	if want := "41db45c49c06e80a7d32b4d0e7e54d9a0b46bf73"; tree != want {
+		t.Fatalf("unexpected tree: got %s, want %s", tree, want)
+	}
+
+	build.Tree = tree
+	if _, err := build.Run(context.Background(), WithRunner(runner)); err != nil {
+		t.Errorf("couldn't run the build with a matching tree: %v", err)
+	}
+	build.Tree = strings.Repeat("0", 40)
+	if _, err := build.Run(context.Background(), WithRunner(runner)); err == nil || !strings.Contains(err.Error(), "the tree of the checkout") {
+		t.Errorf("expected a tree mismatch, got %v", err)
+	}
+}
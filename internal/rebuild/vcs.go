@@ -0,0 +1,247 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rebuild
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// CheckoutSummaryName is the name of the file with the JSON-encoded
+// CheckoutMetrics, in the logs directory.
+const CheckoutSummaryName = "checkout.json"
+
+// CheckoutMetrics describes how the source of a build was checked out, and
+// how long it took.
+type CheckoutMetrics struct {
+	// Shallow is true if only the commit was fetched, without its history,
+	// and with the blobs of its tree fetched on checkout. It is false if the
+	// repository was cloned in full.
+	Shallow bool `json:"shallow"`
+	// FallbackReason is the error of the shallow fetch, if the repository was
+	// cloned in full instead.
+	FallbackReason string `json:"fallbackReason,omitempty"`
+	// FetchMillis is the duration of the fetch, or of the clone, including
+	// any failed shallow fetch, in milliseconds.
+	FetchMillis int64 `json:"fetchMillis"`
+	// CheckoutMillis is the duration of the checkout of the commit in
+	// milliseconds.
+	CheckoutMillis int64 `json:"checkoutMillis"`
+}
+
+// commitPattern matches the hex-encoded SHA1 or SHA256 digest of a Git commit.
+var commitPattern = regexp.MustCompile(`^([0-9a-f]{40}|[0-9a-f]{64})$`)
+
+// allowedRepoSchemes are the URL schemes of the repositories that sources can
+// be fetched from. Tests add `file`.
+var allowedRepoSchemes = []string{"https"}
+
+// ValidateSource returns an error unless the given commit is a hex-encoded
+// SHA1 or SHA256 digest, and the given repository URL is an `https://` URL.
+// The URL and the commit are usually taken from an untrusted provenance, so
+// they must be validated before they are passed to a VCS: a symbolic
+// revision, e.g., `main`, does not pin the source, and a URL starting with
+// `-` or naming a local repository could be interpreted as options, or run
+// commands, by `git`.
+func ValidateSource(repoURL, commit string) error {
+	if !commitPattern.MatchString(commit) {
+		return fmt.Errorf("the commit %q is not a hex-encoded SHA1 or SHA256 digest", commit)
+	}
+	parsed, err := url.Parse(repoURL)
+	if err != nil {
+		return fmt.Errorf("invalid repository URL %q: %v", repoURL, err)
+	}
+	for _, scheme := range allowedRepoSchemes {
+		if parsed.Scheme == scheme && (parsed.Host != "" || scheme == "file") {
+			return nil
+		}
+	}
+	return fmt.Errorf("the repository URL %q is not an https:// URL", repoURL)
+}
+
+// VCS fetches sources from a version control system. Builds use GitCLI by
+// default; other implementations can, e.g., fetch sources in-process, or
+// from a cache, and tests can use fakes that do not need a Git binary.
+type VCS interface {
+	// Checkout fetches the given commit of the repository with the given URL
+	// into dir, which must not exist or be empty, and checks it out.
+	// Implementations must reject a URL and a commit that fail
+	// ValidateSource, and must fail unless the commit is checked out.
+	Checkout(ctx context.Context, repoURL, commit, dir string) (*CheckoutMetrics, error)
+	// Tree returns the hex-encoded digest of the tree of the given revision,
+	// e.g., `HEAD`, in the repository in dir.
+	Tree(ctx context.Context, dir, revision string) (string, error)
+}
+
+// GitCLI is a VCS that runs the `git` binary.
+type GitCLI struct {
+	// Timeout bounds each `git` command, if positive. The commands are also
+	// canceled with their context.
+	Timeout time.Duration
+}
+
+// Checkout checks out the given commit of the Git repository with the given
+// URL into dir, which must not exist or be empty. It first fetches only the
+// commit, with `git fetch --filter=blob:none --depth=1`, so that large
+// repositories are fetched quickly. If the shallow fetch fails, e.g., because
+// the server does not allow fetching unadvertised commits, it falls back to a
+// full `git clone`. Fails unless HEAD is the commit after the checkout.
+func (g GitCLI) Checkout(ctx context.Context, repoURL, commit, dir string) (*CheckoutMetrics, error) {
+	if err := ValidateSource(repoURL, commit); err != nil {
+		return nil, err
+	}
+	created, err := prepareCheckoutDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics := &CheckoutMetrics{}
+	startedOn := time.Now()
+	err = g.fetchShallow(ctx, repoURL, commit, dir)
+	if err == nil {
+		metrics.Shallow = true
+	} else {
+		// A canceled fetch is not retried.
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		metrics.FallbackReason = err.Error()
+		if err := removeCheckout(dir, created); err != nil {
+			return nil, fmt.Errorf("could not remove the shallow checkout: %v", err)
+		}
+		if err := g.run(ctx, "", "clone", "--quiet", "--", repoURL, dir); err != nil {
+			return nil, err
+		}
+	}
+	metrics.FetchMillis = time.Since(startedOn).Milliseconds()
+
+	startedOn = time.Now()
+	revision := commit
+	if metrics.Shallow {
+		revision = "FETCH_HEAD"
+	}
+	if err := g.run(ctx, dir, "checkout", "--quiet", "--detach", revision); err != nil {
+		return nil, err
+	}
+	var head bytes.Buffer
+	if err := g.runWithOutput(ctx, dir, &head, "rev-parse", "--verify", "HEAD"); err != nil {
+		return nil, fmt.Errorf("could not resolve the checked out commit: %v", err)
+	}
+	if got := strings.TrimSpace(head.String()); got != commit {
+		return nil, fmt.Errorf("the checked out commit is %s, want %s", got, commit)
+	}
+	metrics.CheckoutMillis = time.Since(startedOn).Milliseconds()
+	return metrics, nil
+}
+
+// prepareCheckoutDir creates dir if it does not exist, and returns whether it
+// was created. Fails if dir exists and is not an empty directory, so that
+// existing files are never overwritten or removed.
+func prepareCheckoutDir(dir string) (bool, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return false, fmt.Errorf("could not create the checkout directory: %v", err)
+		}
+		return true, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("could not read the checkout directory: %v", err)
+	}
+	if len(entries) != 0 {
+		return false, fmt.Errorf("the checkout directory %s is not empty", dir)
+	}
+	return false, nil
+}
+
+// removeCheckout removes a failed checkout from dir, as prepared by
+// prepareCheckoutDir. If dir existed before, it was empty, so only its
+// entries are removed.
+func removeCheckout(dir string, created bool) error {
+	if created {
+		return os.RemoveAll(dir)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err := os.RemoveAll(filepath.Join(dir, entry.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fetchShallow initializes a Git repository in dir, and fetches the given
+// commit from the repository with the given URL, without its history and
+// blobs.
+func (g GitCLI) fetchShallow(ctx context.Context, repoURL, commit, dir string) error {
+	if err := g.run(ctx, "", "init", "--quiet", "--", dir); err != nil {
+		return err
+	}
+	if err := g.run(ctx, dir, "remote", "add", "--", "origin", repoURL); err != nil {
+		return err
+	}
+	return g.run(ctx, dir, "fetch", "--quiet", "--filter=blob:none", "--depth=1", "--", "origin", commit)
+}
+
+// Tree returns the hex-encoded digest of the Git tree of the given revision
+// in the Git repository in dir.
+func (g GitCLI) Tree(ctx context.Context, dir, revision string) (string, error) {
+	var output bytes.Buffer
+	if err := g.runWithOutput(ctx, dir, &output, "rev-parse", "--verify", "--quiet", revision+"^{tree}"); err != nil {
+		return "", fmt.Errorf("could not get the tree of %s: %v", revision, err)
+	}
+	return strings.TrimSpace(output.String()), nil
+}
+
+func (g GitCLI) run(ctx context.Context, dir string, args ...string) error {
+	return g.runWithOutput(ctx, dir, io.Discard, args...)
+}
+
+// runWithOutput runs `git` with the given arguments in dir, within the
+// timeout, if any.
+func (g GitCLI) runWithOutput(ctx context.Context, dir string, output io.Writer, args ...string) error {
+	if g.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, g.Timeout)
+		defer cancel()
+	}
+	return runWithOutput(ctx, dir, output, "git", args...)
+}
+
+func writeCheckoutMetrics(logsDir string, metrics *CheckoutMetrics) error {
+	if err := os.MkdirAll(logsDir, 0o755); err != nil {
+		return fmt.Errorf("could not create the logs directory: %v", err)
+	}
+	summaryBytes, err := json.MarshalIndent(metrics, "", "    ")
+	if err != nil {
+		return fmt.Errorf("could not marshal the checkout metrics: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(logsDir, CheckoutSummaryName), summaryBytes, 0o600); err != nil {
+		return fmt.Errorf("could not write the checkout metrics: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,268 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rebuild
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// createRepo creates a Git repository with two commits, and returns its URL
+// and the digest of the first commit. Fetching from `file://` URLs is allowed
+// until the end of the test.
+func createRepo(t *testing.T) (string, string) {
+	ctx := context.Background()
+	schemes := allowedRepoSchemes
+	allowedRepoSchemes = append([]string{"file"}, schemes...)
+	t.Cleanup(func() { allowedRepoSchemes = schemes })
+	dir := t.TempDir()
+	if err := run(ctx, "", "git", "init", "--quiet", dir); err != nil {
+		t.Fatalf("couldn't create the repository: %v", err)
+	}
+	if err := commitFile(ctx, dir, "first.txt", "first\n"); err != nil {
+		t.Fatalf("couldn't commit: %v", err)
+	}
+	var output strings.Builder
+	if err := runWithOutput(ctx, dir, &output, "git", "rev-parse", "HEAD"); err != nil {
+		t.Fatalf("couldn't resolve the commit: %v", err)
+	}
+	if err := commitFile(ctx, dir, "second.txt", "second\n"); err != nil {
+		t.Fatalf("couldn't commit: %v", err)
+	}
+	return "file://" + dir, strings.TrimSpace(output.String())
+}
+
+func TestGitCLI_CheckoutShallow(t *testing.T) {
+	repoURL, commit := createRepo(t)
+	dir := filepath.Join(t.TempDir(), "source")
+
+	metrics, err := GitCLI{}.Checkout(context.Background(), repoURL, commit, dir)
+	if err != nil {
+		t.Fatalf("couldn't fetch the source: %v", err)
+	}
+	if !metrics.Shallow || metrics.FallbackReason != "" {
+		t.Errorf("unexpected metrics of a shallow fetch: %+v", metrics)
+	}
+	assertCheckout(t, dir, commit)
+}
+
+func TestGitCLI_CheckoutFallsBackToClone(t *testing.T) {
+	repoURL, commit := createRepo(t)
+	dir := filepath.Join(t.TempDir(), "source")
+	// Servers of the original protocol do not allow fetching commits that are
+	// not advertised, e.g., that are not the tip of a branch.
+	t.Setenv("GIT_CONFIG_COUNT", "1")
+	t.Setenv("GIT_CONFIG_KEY_0", "protocol.version")
+	t.Setenv("GIT_CONFIG_VALUE_0", "0")
+
+	metrics, err := GitCLI{}.Checkout(context.Background(), repoURL, commit, dir)
+	if err != nil {
+		t.Fatalf("couldn't fetch the source: %v", err)
+	}
+	if metrics.Shallow || metrics.FallbackReason == "" {
+		t.Errorf("unexpected metrics of a full clone: %+v", metrics)
+	}
+	assertCheckout(t, dir, commit)
+}
+
+func TestGitCLI_CheckoutFallbackKeepsEmptyDir(t *testing.T) {
+	repoURL, commit := createRepo(t)
+	dir := t.TempDir()
+	t.Setenv("GIT_CONFIG_COUNT", "1")
+	t.Setenv("GIT_CONFIG_KEY_0", "protocol.version")
+	t.Setenv("GIT_CONFIG_VALUE_0", "0")
+
+	if _, err := (GitCLI{}).Checkout(context.Background(), repoURL, commit, dir); err != nil {
+		t.Fatalf("couldn't fetch the source into an empty directory: %v", err)
+	}
+	assertCheckout(t, dir, commit)
+}
+
+func TestGitCLI_CheckoutRejectsNonEmptyDir(t *testing.T) {
+	repoURL, commit := createRepo(t)
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(existing, []byte("notes"), 0o600); err != nil {
+		t.Fatalf("couldn't write the existing file: %v", err)
+	}
+
+	if _, err := (GitCLI{}).Checkout(context.Background(), repoURL, commit, dir); err == nil || !strings.Contains(err.Error(), "not empty") {
+		t.Errorf("expected the non-empty directory to be rejected, got %v", err)
+	}
+	if _, err := os.Stat(existing); err != nil {
+		t.Errorf("the existing file was removed: %v", err)
+	}
+}
+
+func TestValidateSource(t *testing.T) {
+	commit := strings.Repeat("ab", 20)
+	for _, tc := range []struct {
+		repoURL, commit string
+		valid           bool
+	}{
+		{"https://github.com/project-oak/oak", commit, true},
+		{"https://github.com/project-oak/oak", strings.Repeat("ab", 32), true},
+		{"https://github.com/project-oak/oak", "main", false},
+		{"https://github.com/project-oak/oak", "--upload-pack=touch /tmp/pwned", false},
+		{"https://github.com/project-oak/oak", strings.ToUpper(commit), false},
+		{".", commit, false},
+		{"--upload-pack=touch /tmp/pwned", commit, false},
+		{"file:///tmp/repo", commit, false},
+		{"ext::sh -c touch% /tmp/pwned", commit, false},
+		{"https:///project-oak/oak", commit, false},
+	} {
+		if err := ValidateSource(tc.repoURL, tc.commit); (err == nil) != tc.valid {
+			t.Errorf("ValidateSource(%q, %q) = %v, want valid: %v", tc.repoURL, tc.commit, err, tc.valid)
+		}
+	}
+}
+
+func TestGitCLI_CheckoutRejectsInvalidSource(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "source")
+	if _, err := (GitCLI{}).Checkout(context.Background(), ".", "--upload-pack=touch "+dir, dir); err == nil {
+		t.Errorf("expected the invalid source to be rejected")
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("the invalid source was checked out: %v", err)
+	}
+}
+
+func TestBuild_RunWritesCheckoutMetrics(t *testing.T) {
+	build, err := FromProvenance(loadProvenance(t, slsav1ProvenancePath, ""))
+	if err != nil {
+		t.Fatalf("couldn't get the build from the provenance: %v", err)
+	}
+	build.RepoURL, build.Commit = createRepo(t)
+	logsDir := t.TempDir()
+
+	runner := &vcsRunner{fakeRunner{artifact: "artifact"}, GitCLI{}}
+	if _, err := build.Run(context.Background(), WithRunner(runner), WithLogsDir(logsDir)); err != nil {
+		t.Fatalf("couldn't run the build: %v", err)
+	}
+	summary, err := os.ReadFile(filepath.Join(logsDir, CheckoutSummaryName))
+	if err != nil {
+		t.Fatalf("couldn't read the checkout metrics: %v", err)
+	}
+	for _, field := range []string{`"shallow": true`, `"fetchMillis"`, `"checkoutMillis"`} {
+		if !strings.Contains(string(summary), field) {
+			t.Errorf("the checkout metrics %s have no %s", summary, field)
+		}
+	}
+}
+
+// assertCheckout checks that the given commit is checked out in dir.
+func assertCheckout(t *testing.T, dir, commit string) {
+	var output strings.Builder
+	if err := runWithOutput(context.Background(), dir, &output, "git", "rev-parse", "HEAD"); err != nil {
+		t.Fatalf("couldn't resolve the checked out commit: %v", err)
+	}
+	if got := strings.TrimSpace(output.String()); got != commit {
+		t.Errorf("unexpected checked out commit: got %s, want %s", got, commit)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "first.txt")); err != nil {
+		t.Errorf("the file of the commit is not checked out: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "second.txt")); !os.IsNotExist(err) {
+		t.Errorf("the file of the next commit is checked out: %v", err)
+	}
+}
+
+func TestGitCLI_CheckoutCanceled(t *testing.T) {
+	repoURL, commit := createRepo(t)
+	dir := filepath.Join(t.TempDir(), "source")
+
+	if _, err := (GitCLI{Timeout: time.Nanosecond}).Checkout(context.Background(), repoURL, commit, dir); err == nil {
+		t.Errorf("expected the checkout to time out")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := (GitCLI{}).Checkout(ctx, repoURL, commit, dir); err == nil || strings.Contains(err.Error(), "clone") {
+		t.Errorf("expected the canceled checkout to fail without a clone, got %v", err)
+	}
+}
+
+// fakeVCS checks out an empty directory, and reports a fixed tree, without a
+// Git binary.
+type fakeVCS struct {
+	tree      string
+	checkouts []string
+}
+
+func (v *fakeVCS) Checkout(_ context.Context, repoURL, commit, dir string) (*CheckoutMetrics, error) {
+	if err := ValidateSource(repoURL, commit); err != nil {
+		return nil, err
+	}
+	v.checkouts = append(v.checkouts, repoURL+"@"+commit)
+	return &CheckoutMetrics{Shallow: true}, os.MkdirAll(dir, 0o755)
+}
+
+func (v *fakeVCS) Tree(_ context.Context, _, revision string) (string, error) {
+	if revision != "HEAD" {
+		return "", fmt.Errorf("unexpected revision %q", revision)
+	}
+	return v.tree, nil
+}
+
+func TestContainerRunner_CheckoutUsesVCS(t *testing.T) {
+	vcs := &fakeVCS{}
+	runner := ContainerRunner{VCS: vcs}
+	commit := strings.Repeat("ab", 20)
+	metrics, err := runner.Checkout(context.Background(), "https://github.com/project-oak/oak", commit, t.TempDir())
+	if err != nil {
+		t.Fatalf("couldn't check out the source: %v", err)
+	}
+	if !metrics.Shallow {
+		t.Errorf("unexpected metrics: %+v", metrics)
+	}
+	if diff := cmp.Diff(vcs.checkouts, []string{"https://github.com/project-oak/oak@" + commit}); diff != "" {
+		t.Errorf("unexpected checkouts: %s", diff)
+	}
+}
+
+func TestBuild_RunComparesTreeWithVCS(t *testing.T) {
+	build, err := FromProvenance(loadProvenance(t, slsav1ProvenancePath, ""))
+	if err != nil {
+		t.Fatalf("couldn't get the build from the provenance: %v", err)
+	}
+	build.Tree = strings.Repeat("ab", 20)
+	vcs := &fakeVCS{tree: build.Tree}
+	runner := &vcsRunner{fakeRunner{artifact: "artifact"}, vcs}
+
+	if _, err := build.Run(context.Background(), WithRunner(runner), WithVCS(vcs)); err != nil {
+		t.Errorf("couldn't run the build with a matching tree: %v", err)
+	}
+	vcs.tree = strings.Repeat("cd", 20)
+	if _, err := build.Run(context.Background(), WithRunner(runner), WithVCS(vcs)); err == nil || !strings.Contains(err.Error(), "the tree of the checkout") {
+		t.Errorf("expected a tree mismatch, got %v", err)
+	}
+}
+
+// vcsRunner checks out sources with the given VCS, but does not run builds
+// in containers.
+type vcsRunner struct {
+	fakeRunner
+	vcs VCS
+}
+
+func (r *vcsRunner) Checkout(ctx context.Context, repoURL, commit, dir string) (*CheckoutMetrics, error) {
+	return ContainerRunner{VCS: r.vcs}.Checkout(ctx, repoURL, commit, dir)
+}
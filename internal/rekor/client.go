@@ -0,0 +1,287 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package rekor provides a client for uploading signed statements to a Rekor
+// transparency log, and functions for verifying the returned log entries
+// offline.
+package rekor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/project-oak/transparent-release/internal/fetch"
+	"github.com/secure-systems-lab/go-securesystemslib/dsse"
+)
+
+// DefaultURL is the URL of the public-good Rekor instance.
+const DefaultURL = "https://rekor.sigstore.dev"
+
+// DSSEKind and DSSEKindVersion identify the type of the entries uploaded by
+// Client.UploadDSSE.
+const (
+	DSSEKind        = "dsse"
+	DSSEKindVersion = "0.0.1"
+)
+
+// Client uploads entries to a Rekor transparency log, using the v1 API.
+// See https://github.com/sigstore/rekor/blob/main/openapi.yaml.
+type Client struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewClient creates a new Client for the Rekor instance at the given URL.
+func NewClient(baseURL string) *Client {
+	return &Client{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		client:  &http.Client{},
+	}
+}
+
+type proposedEntry struct {
+	Kind       string   `json:"kind"`
+	APIVersion string   `json:"apiVersion"`
+	Spec       dsseSpec `json:"spec"`
+}
+
+type dsseSpec struct {
+	ProposedContent proposedContent `json:"proposedContent"`
+}
+
+type proposedContent struct {
+	Envelope  string   `json:"envelope"`
+	Verifiers [][]byte `json:"verifiers"`
+}
+
+// LogEntry is an entry in a Rekor transparency log, as returned by Rekor
+// when the entry is created.
+type LogEntry struct {
+	// UUID identifies the entry in the log.
+	UUID string `json:"uuid"`
+	// Body is the base64-encoded canonicalized body of the entry.
+	Body           string `json:"body"`
+	IntegratedTime int64  `json:"integratedTime"`
+	// LogID is the SHA256 digest of the public key of the log, hex-encoded.
+	LogID        string                `json:"logID"`
+	LogIndex     int64                 `json:"logIndex"`
+	Verification *LogEntryVerification `json:"verification,omitempty"`
+}
+
+// IntegratedAt returns the time at which the entry was integrated into the
+// log. The integrated time is covered by the SignedEntryTimestamp, so it can
+// only be trusted once the entry has been verified; see VerifyLogEntry.
+func (e *LogEntry) IntegratedAt() time.Time {
+	return time.Unix(e.IntegratedTime, 0).UTC()
+}
+
+// LogEntryVerification contains the material for verifying that an entry is
+// included in the log.
+type LogEntryVerification struct {
+	InclusionProof *InclusionProof `json:"inclusionProof,omitempty"`
+	// SignedEntryTimestamp is the signature of the log over the entry, the
+	// integrated time, and the log index.
+	SignedEntryTimestamp []byte `json:"signedEntryTimestamp,omitempty"`
+}
+
+// InclusionProof is a Merkle tree inclusion proof for a log entry.
+type InclusionProof struct {
+	Checkpoint string `json:"checkpoint"`
+	// Hashes are the hex-encoded hashes of the audit path.
+	Hashes   []string `json:"hashes"`
+	LogIndex int64    `json:"logIndex"`
+	// RootHash is the hex-encoded root hash of the tree.
+	RootHash string `json:"rootHash"`
+	TreeSize int64  `json:"treeSize"`
+}
+
+// UploadDSSE uploads the given DSSE envelope as an entry of kind `dsse` to the
+// log. verifierPEM is the PEM-encoded public key or certificate for verifying
+// the signature on the envelope. If the log already contains the entry, the
+// existing entry is returned. Failures that may succeed when retried are
+// reported by fetch.IsTransient.
+func (c *Client) UploadDSSE(ctx context.Context, envelope *dsse.Envelope, verifierPEM []byte) (*LogEntry, error) {
+	envelopeBytes, err := json.Marshal(envelope)
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal the envelope: %v", err)
+	}
+	reqBody, err := json.Marshal(proposedEntry{
+		Kind:       DSSEKind,
+		APIVersion: DSSEKindVersion,
+		Spec: dsseSpec{ProposedContent: proposedContent{
+			Envelope:  string(envelopeBytes),
+			Verifiers: [][]byte{verifierPEM},
+		}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal the proposed entry: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/api/v1/log/entries", bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("could not create HTTP request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, &fetch.TransientError{Err: fmt.Errorf("could not receive response from Rekor: %v", err)}
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, &fetch.TransientError{Err: fmt.Errorf("could not read the Rekor response: %v", err)}
+	}
+	switch resp.StatusCode {
+	case http.StatusCreated:
+		return parseLogEntry(body)
+	case http.StatusConflict:
+		// The entry already exists; its location is given in the Location header.
+		return c.getLogEntry(ctx, resp.Header.Get("Location"))
+	default:
+		// Wraps a fetch.StatusError, so that fetch.IsTransient tells whether
+		// the upload may be retried.
+		statusErr := &fetch.StatusError{URI: req.URL.String(), StatusCode: resp.StatusCode, Status: resp.Status}
+		return nil, fmt.Errorf("creating the log entry failed: %w: %s", statusErr, body)
+	}
+}
+
+// SearchByHash returns the UUIDs of the entries in the log whose index
+// contains the given hex-encoded SHA2-256 digest. For entries of kind `dsse`,
+// Rekor indexes the digests of the payload, and of the subjects of in-toto
+// statements.
+func (c *Client) SearchByHash(ctx context.Context, sha256Digest string) ([]string, error) {
+	reqBody, err := json.Marshal(map[string]string{"hash": "sha256:" + strings.ToLower(sha256Digest)})
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal the search query: %v", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/api/v1/index/retrieve", bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("could not create HTTP request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("could not receive response from Rekor: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("could not read the Rekor response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("searching the log failed with status %d: %s", resp.StatusCode, body)
+	}
+	var uuids []string
+	if err := json.Unmarshal(body, &uuids); err != nil {
+		return nil, fmt.Errorf("could not unmarshal the Rekor response: %v", err)
+	}
+	return uuids, nil
+}
+
+// GetConsistencyProof fetches a proof that the tree of the second size is an
+// append-only extension of the tree of the first size.
+func (c *Client) GetConsistencyProof(ctx context.Context, firstSize, lastSize int64) (*ConsistencyProof, error) {
+	location := fmt.Sprintf("%s/api/v1/log/proof?firstSize=%d&lastSize=%d", c.baseURL, firstSize, lastSize)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not create HTTP request: %v", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("could not receive response from Rekor: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("could not read the Rekor response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching the consistency proof failed with status %d: %s", resp.StatusCode, body)
+	}
+	var proof ConsistencyProof
+	if err := json.Unmarshal(body, &proof); err != nil {
+		return nil, fmt.Errorf("could not unmarshal the Rekor response: %v", err)
+	}
+	return &proof, nil
+}
+
+// GetLogEntry fetches the log entry with the given UUID.
+func (c *Client) GetLogEntry(ctx context.Context, uuid string) (*LogEntry, error) {
+	return c.getLogEntry(ctx, "/api/v1/log/entries/"+uuid)
+}
+
+// getLogEntry fetches the log entry at the given location, which is either a
+// full URL or a path relative to the base URL of the log.
+func (c *Client) getLogEntry(ctx context.Context, location string) (*LogEntry, error) {
+	if location == "" {
+		return nil, fmt.Errorf("the entry already exists, but Rekor did not return its location")
+	}
+	if strings.HasPrefix(location, "/") {
+		location = c.baseURL + location
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not create HTTP request: %v", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("could not receive response from Rekor: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("could not read the Rekor response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching the log entry failed with status %d: %s", resp.StatusCode, body)
+	}
+	return parseLogEntry(body)
+}
+
+// parseLogEntry parses a Rekor response, which maps the UUID of a single
+// entry to the entry itself.
+func parseLogEntry(body []byte) (*LogEntry, error) {
+	var entries map[string]LogEntry
+	if err := json.Unmarshal(body, &entries); err != nil {
+		return nil, fmt.Errorf("could not unmarshal the Rekor response: %v", err)
+	}
+	if len(entries) != 1 {
+		return nil, fmt.Errorf("expected exactly one log entry in the Rekor response, got %d", len(entries))
+	}
+	var logEntry LogEntry
+	for uuid, entry := range entries {
+		logEntry = entry
+		logEntry.UUID = uuid
+	}
+	return &logEntry, nil
+}
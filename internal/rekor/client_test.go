@@ -0,0 +1,195 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rekor
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"testing"
+
+	"github.com/project-oak/transparent-release/internal/testutil"
+	"github.com/secure-systems-lab/go-securesystemslib/dsse"
+)
+
+const (
+	testUUID     = "24296fb24b8ad77a2c0e4bfab0e33c8e8b0e3e8ba1d2c1ae8a3f3c5d64e8e5a9e8f7c3a5b1d2e4f6a"
+	testLogID    = "c0d23d6ad406973f9559f3ba2d1ca01f84147d8ffc5b8445c224f98b9591801d"
+	testRootHash = "7d5e3c2f4a1b8e9d0c6f3a2b1e4d7c8f9a0b3c6d5e2f1a4b7c8d9e0f1a2b3c4d"
+)
+
+// newFakeRekor starts a test server that accepts a single DSSE entry. Further
+// uploads of an entry return a conflict pointing to the existing entry.
+func newFakeRekor(t *testing.T) *httptest.Server {
+	var created []byte
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v1/log/entries":
+			var entry proposedEntry
+			if err := json.NewDecoder(r.Body).Decode(&entry); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if entry.Kind != DSSEKind || len(entry.Spec.ProposedContent.Verifiers) != 1 {
+				http.Error(w, "invalid entry", http.StatusBadRequest)
+				return
+			}
+			if created != nil {
+				w.Header().Set("Location", "/api/v1/log/entries/"+testUUID)
+				w.WriteHeader(http.StatusConflict)
+				return
+			}
+			body, err := json.Marshal(map[string]LogEntry{testUUID: {
+				Body:           base64.StdEncoding.EncodeToString([]byte(entry.Spec.ProposedContent.Envelope)),
+				IntegratedTime: 1690000000,
+				LogID:          testLogID,
+				LogIndex:       42,
+				Verification: &LogEntryVerification{
+					InclusionProof: &InclusionProof{
+						Checkpoint: "rekor.sigstore.dev - 2605736670972794746\n43\nfV48L0objp0Mbzor7Ux8+aCzxtXi8aS3yNng8aK8PE0=\n",
+						Hashes:     []string{testRootHash},
+						LogIndex:   42,
+						RootHash:   testRootHash,
+						TreeSize:   43,
+					},
+					SignedEntryTimestamp: []byte("set"),
+				},
+			}})
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			created = body
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write(body)
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v1/log/entries/"+testUUID && created != nil:
+			_, _ = w.Write(created)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func testEnvelope() *dsse.Envelope {
+	return &dsse.Envelope{
+		PayloadType: "text/plain",
+		Payload:     base64.StdEncoding.EncodeToString([]byte("hello")),
+		Signatures:  []dsse.Signature{{Sig: base64.StdEncoding.EncodeToString([]byte("sig"))}},
+	}
+}
+
+func TestClient_UploadDSSE(t *testing.T) {
+	server := newFakeRekor(t)
+	defer server.Close()
+
+	ctx := context.Background()
+	client := NewClient(server.URL)
+	entry, err := client.UploadDSSE(ctx, testEnvelope(), []byte("-----BEGIN PUBLIC KEY-----"))
+	if err != nil {
+		t.Fatalf("could not upload envelope: %v", err)
+	}
+	testutil.AssertEq(t, "uuid", entry.UUID, testUUID)
+	testutil.AssertEq(t, "log index", entry.LogIndex, int64(42))
+	testutil.AssertEq(t, "tree size", entry.Verification.InclusionProof.TreeSize, int64(43))
+
+	// Uploading the same envelope again returns the existing entry.
+	again, err := client.UploadDSSE(ctx, testEnvelope(), []byte("-----BEGIN PUBLIC KEY-----"))
+	if err != nil {
+		t.Fatalf("could not upload envelope again: %v", err)
+	}
+	testutil.AssertEq(t, "uuid", again.UUID, testUUID)
+	testutil.AssertEq(t, "body", again.Body, entry.Body)
+}
+
+func TestClient_UploadDSSEFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid entry", http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	if _, err := NewClient(server.URL).UploadDSSE(context.Background(), testEnvelope(), nil); err == nil {
+		t.Fatalf("expected an error for a rejected entry")
+	}
+}
+
+func TestClient_SearchByHash(t *testing.T) {
+	server := newFakeRekor(t)
+	defer server.Close()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/index/retrieve", func(w http.ResponseWriter, r *http.Request) {
+		var query map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&query); err != nil || query["hash"] != "sha256:abcd" {
+			http.Error(w, "invalid query", http.StatusBadRequest)
+			return
+		}
+		_ = json.NewEncoder(w).Encode([]string{testUUID})
+	})
+	target, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("could not parse the server URL: %v", err)
+	}
+	mux.Handle("/", httputil.NewSingleHostReverseProxy(target))
+	search := httptest.NewServer(mux)
+	defer search.Close()
+
+	ctx := context.Background()
+	client := NewClient(search.URL)
+	if _, err := client.UploadDSSE(ctx, testEnvelope(), []byte("-----BEGIN PUBLIC KEY-----")); err != nil {
+		t.Fatalf("could not upload envelope: %v", err)
+	}
+	uuids, err := client.SearchByHash(ctx, "ABCD")
+	if err != nil {
+		t.Fatalf("could not search the log: %v", err)
+	}
+	testutil.AssertEq(t, "number of uuids", len(uuids), 1)
+	testutil.AssertEq(t, "uuid", uuids[0], testUUID)
+
+	entry, err := client.GetLogEntry(ctx, uuids[0])
+	if err != nil {
+		t.Fatalf("could not fetch the log entry: %v", err)
+	}
+	testutil.AssertEq(t, "log index", entry.LogIndex, int64(42))
+
+	if _, err := client.GetLogEntry(ctx, "unknown"); err == nil {
+		t.Errorf("expected an error for an unknown entry")
+	}
+}
+
+func TestClient_GetConsistencyProof(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/log/proof" || r.URL.Query().Get("firstSize") != "11" || r.URL.Query().Get("lastSize") != "20" {
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(ConsistencyProof{Hashes: []string{testRootHash}, RootHash: testRootHash})
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	proof, err := client.GetConsistencyProof(context.Background(), 11, 20)
+	if err != nil {
+		t.Fatalf("could not fetch the consistency proof: %v", err)
+	}
+	testutil.AssertEq(t, "root hash", proof.RootHash, testRootHash)
+	testutil.AssertEq(t, "number of hashes", len(proof.Hashes), 1)
+
+	if _, err := client.GetConsistencyProof(context.Background(), 20, 11); err == nil {
+		t.Errorf("expected an error for an unknown proof")
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rekor
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/hex"
+	"fmt"
+)
+
+// ConsistencyProof is a Merkle tree consistency proof between two tree sizes,
+// as returned by the `/api/v1/log/proof` endpoint of Rekor.
+type ConsistencyProof struct {
+	// Hashes are the hex-encoded hashes of the proof.
+	Hashes []string `json:"hashes"`
+	// RootHash is the hex-encoded root hash of the larger tree.
+	RootHash string `json:"rootHash"`
+}
+
+// VerifyConsistency verifies that the tree committed to by the new checkpoint
+// is an append-only extension of the tree committed to by the old checkpoint,
+// using the given consistency proof. Two checkpoints for the same tree size
+// are consistent only if they have the same root hash; a log presenting
+// inconsistent checkpoints to different verifiers is detected this way.
+func VerifyConsistency(oldCheckpoint, newCheckpoint *Checkpoint, proof *ConsistencyProof) error {
+	if oldCheckpoint.TreeSize <= 0 || oldCheckpoint.TreeSize > newCheckpoint.TreeSize {
+		return fmt.Errorf("invalid tree sizes: old %d, new %d", oldCheckpoint.TreeSize, newCheckpoint.TreeSize)
+	}
+	if proof.RootHash != "" && proof.RootHash != hex.EncodeToString(newCheckpoint.RootHash) {
+		return fmt.Errorf("the consistency proof is for root hash %s, but the new checkpoint has root hash %x", proof.RootHash, newCheckpoint.RootHash)
+	}
+	hashes := make([][]byte, 0, len(proof.Hashes))
+	for _, h := range proof.Hashes {
+		hash, err := hex.DecodeString(h)
+		if err != nil {
+			return fmt.Errorf("could not decode hash %q: %v", h, err)
+		}
+		hashes = append(hashes, hash)
+	}
+	return verifyConsistencyProof(uint64(oldCheckpoint.TreeSize), uint64(newCheckpoint.TreeSize), oldCheckpoint.RootHash, newCheckpoint.RootHash, hashes)
+}
+
+// VerifyEntryConsistency verifies that the given entry is included in the log
+// with the given public key, and remains included in the tree committed to by
+// the new checkpoint. It verifies the inclusion proof of the entry, the
+// signatures on both checkpoints, and the consistency proof between them.
+//
+// The old checkpoint must commit to the same tree as the inclusion proof. If
+// it is empty, the checkpoint in the inclusion proof is used.
+func VerifyEntryConsistency(entry *LogEntry, oldNote, newNote string, proof *ConsistencyProof, logPublicKey *ecdsa.PublicKey) error {
+	if err := VerifyInclusionProof(entry, logPublicKey); err != nil {
+		return fmt.Errorf("could not verify the inclusion proof: %v", err)
+	}
+	inclusionProof := entry.Verification.InclusionProof
+	if oldNote == "" {
+		oldNote = inclusionProof.Checkpoint
+	}
+	oldCheckpoint, err := VerifyCheckpoint(oldNote, logPublicKey)
+	if err != nil {
+		return fmt.Errorf("could not verify the old checkpoint: %v", err)
+	}
+	if oldCheckpoint.TreeSize != inclusionProof.TreeSize || hex.EncodeToString(oldCheckpoint.RootHash) != inclusionProof.RootHash {
+		return fmt.Errorf("the old checkpoint for tree size %d does not commit to the tree of size %d in the inclusion proof", oldCheckpoint.TreeSize, inclusionProof.TreeSize)
+	}
+	newCheckpoint, err := VerifyCheckpoint(newNote, logPublicKey)
+	if err != nil {
+		return fmt.Errorf("could not verify the new checkpoint: %v", err)
+	}
+	if err := VerifyConsistency(oldCheckpoint, newCheckpoint, proof); err != nil {
+		return fmt.Errorf("could not verify the consistency between tree sizes %d and %d: %v", oldCheckpoint.TreeSize, newCheckpoint.TreeSize, err)
+	}
+	return nil
+}
+
+// verifyConsistencyProof verifies an RFC 6962 consistency proof between the
+// trees of the given sizes and root hashes, following the algorithm in
+// https://www.rfc-editor.org/rfc/rfc9162#section-2.1.4.2.
+func verifyConsistencyProof(size1, size2 uint64, root1, root2 []byte, proof [][]byte) error {
+	if size1 == size2 {
+		if len(proof) != 0 {
+			return fmt.Errorf("wrong proof size %d for equal tree sizes, want 0", len(proof))
+		}
+		if !bytes.Equal(root1, root2) {
+			return fmt.Errorf("different root hashes %x and %x for the same tree size %d", root1, root2, size1)
+		}
+		return nil
+	}
+	if len(proof) == 0 {
+		return fmt.Errorf("empty proof for different tree sizes")
+	}
+	// If the old tree is a perfect subtree of the new one, its root hash is
+	// the first node of the proof.
+	if size1&(size1-1) == 0 {
+		proof = append([][]byte{root1}, proof...)
+	}
+
+	fn, sn := size1-1, size2-1
+	for fn&1 == 1 {
+		fn >>= 1
+		sn >>= 1
+	}
+	fr, sr := proof[0], proof[0]
+	for _, c := range proof[1:] {
+		if sn == 0 {
+			return fmt.Errorf("wrong proof size %d", len(proof))
+		}
+		if fn&1 == 1 || fn == sn {
+			fr = hashChildren(c, fr)
+			sr = hashChildren(c, sr)
+			for fn&1 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			sr = hashChildren(sr, c)
+		}
+		fn >>= 1
+		sn >>= 1
+	}
+	if sn != 0 {
+		return fmt.Errorf("wrong proof size %d", len(proof))
+	}
+	if !bytes.Equal(fr, root1) {
+		return fmt.Errorf("the computed old root hash %x does not match %x", fr, root1)
+	}
+	if !bytes.Equal(sr, root2) {
+		return fmt.Errorf("the computed new root hash %x does not match %x", sr, root2)
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rekor
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+// consistencyPath computes the RFC 6962 consistency proof between the first
+// size leaves and all the given leaves.
+func consistencyPath(size int, leaves [][]byte) [][]byte {
+	return subproof(size, leaves, true)
+}
+
+func subproof(size int, leaves [][]byte, complete bool) [][]byte {
+	if size == len(leaves) {
+		if complete {
+			return nil
+		}
+		return [][]byte{merkleRoot(leaves)}
+	}
+	k := largestPowerOfTwoBelow(len(leaves))
+	if size <= k {
+		return append(subproof(size, leaves[:k], complete), merkleRoot(leaves[k:]))
+	}
+	return append(subproof(size-k, leaves[k:], false), merkleRoot(leaves[:k]))
+}
+
+// hexHashes hex-encodes the given hashes.
+func hexHashes(hashes [][]byte) []string {
+	encoded := []string{}
+	for _, h := range hashes {
+		encoded = append(encoded, hex.EncodeToString(h))
+	}
+	return encoded
+}
+
+func TestVerifyConsistencyProof(t *testing.T) {
+	all := make([][]byte, 17)
+	for i := range all {
+		all[i] = hashLeaf([]byte{byte(i)})
+	}
+	for size2 := 1; size2 <= len(all); size2++ {
+		for size1 := 1; size1 <= size2; size1++ {
+			root1, root2 := merkleRoot(all[:size1]), merkleRoot(all[:size2])
+			proof := consistencyPath(size1, all[:size2])
+			if err := verifyConsistencyProof(uint64(size1), uint64(size2), root1, root2, proof); err != nil {
+				t.Fatalf("sizes %d and %d: %v", size1, size2, err)
+			}
+			if size1 == size2 {
+				continue
+			}
+			if err := verifyConsistencyProof(uint64(size1), uint64(size2), root2, root2, proof); err == nil {
+				t.Errorf("sizes %d and %d: expected failure for a wrong old root", size1, size2)
+			}
+			if err := verifyConsistencyProof(uint64(size1), uint64(size2), root1, root2, proof[1:]); err == nil {
+				t.Errorf("sizes %d and %d: expected failure for a truncated proof", size1, size2)
+			}
+		}
+	}
+}
+
+func TestVerifyEntryConsistency(t *testing.T) {
+	log := newFakeLog(t)
+	entry := log.entry(t, []byte("body"), 5, 11)
+	grown := leaves([]byte("body"), 5, 20)
+	newNote := log.checkpoint(t, 20, merkleRoot(grown))
+	proof := &ConsistencyProof{Hashes: hexHashes(consistencyPath(11, grown)), RootHash: hex.EncodeToString(merkleRoot(grown))}
+
+	if err := VerifyEntryConsistency(entry, "", newNote, proof, &log.key.PublicKey); err != nil {
+		t.Fatalf("could not verify the consistency: %v", err)
+	}
+	// The old checkpoint may also be given explicitly.
+	oldNote := entry.Verification.InclusionProof.Checkpoint
+	if err := VerifyEntryConsistency(entry, oldNote, newNote, proof, &log.key.PublicKey); err != nil {
+		t.Fatalf("could not verify the consistency with an explicit old checkpoint: %v", err)
+	}
+	// The same checkpoint is consistent with itself.
+	if err := VerifyEntryConsistency(entry, "", oldNote, &ConsistencyProof{}, &log.key.PublicKey); err != nil {
+		t.Fatalf("could not verify the consistency of a checkpoint with itself: %v", err)
+	}
+}
+
+func TestVerifyEntryConsistency_Failures(t *testing.T) {
+	log := newFakeLog(t)
+	entry := log.entry(t, []byte("body"), 5, 11)
+	grown := leaves([]byte("body"), 5, 20)
+	proof := &ConsistencyProof{Hashes: hexHashes(consistencyPath(11, grown))}
+
+	// The log presents a tree in which the entry has been replaced.
+	forked := leaves([]byte("other body"), 5, 20)
+	forkedNote := log.checkpoint(t, 20, merkleRoot(forked))
+	forkedProof := &ConsistencyProof{Hashes: hexHashes(consistencyPath(11, forked))}
+	if err := VerifyEntryConsistency(entry, "", forkedNote, forkedProof, &log.key.PublicKey); err == nil {
+		t.Errorf("expected failure for a forked log")
+	}
+	if err := VerifyEntryConsistency(entry, "", forkedNote, proof, &log.key.PublicKey); err == nil {
+		t.Errorf("expected failure for a proof for another tree")
+	}
+
+	// The log presents two different trees of the same size.
+	splitNote := log.checkpoint(t, 11, merkleRoot(leaves([]byte("other body"), 5, 11)))
+	if err := VerifyEntryConsistency(entry, "", splitNote, &ConsistencyProof{}, &log.key.PublicKey); err == nil {
+		t.Errorf("expected failure for a split view")
+	}
+
+	// The new checkpoint is not signed by the log.
+	newNote := newFakeLog(t).checkpoint(t, 20, merkleRoot(grown))
+	if err := VerifyEntryConsistency(entry, "", newNote, proof, &log.key.PublicKey); err == nil {
+		t.Errorf("expected failure for a checkpoint signed by another key")
+	}
+
+	// The old checkpoint is not the one of the inclusion proof.
+	oldNote := log.checkpoint(t, 12, merkleRoot(grown[:12]))
+	newNote = log.checkpoint(t, 20, merkleRoot(grown))
+	if err := VerifyEntryConsistency(entry, oldNote, newNote, &ConsistencyProof{Hashes: hexHashes(consistencyPath(12, grown))}, &log.key.PublicKey); err == nil {
+		t.Errorf("expected failure for an old checkpoint of another tree")
+	}
+
+	// The new tree is smaller than the old one.
+	smallerNote := log.checkpoint(t, 8, merkleRoot(grown[:8]))
+	if err := VerifyEntryConsistency(entry, "", smallerNote, &ConsistencyProof{}, &log.key.PublicKey); err == nil {
+		t.Errorf("expected failure for a smaller new tree")
+	}
+}
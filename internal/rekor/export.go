@@ -0,0 +1,148 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rekor
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/secure-systems-lab/go-securesystemslib/dsse"
+
+	"github.com/project-oak/transparent-release/pkg/intoto"
+)
+
+// indexAlgorithms maps the algorithms of in-toto digest sets to the prefixes
+// of the hashes in the search index of Rekor, as used in
+// `rekor-cli search --sha` and `POST /api/v1/index/retrieve`.
+//
+//nolint:gochecknoglobals
+var indexAlgorithms = map[string]string{
+	"sha1":     "sha1",
+	"sha256":   "sha256",
+	"sha2-256": "sha256",
+	"sha512":   "sha512",
+	"sha2-512": "sha512",
+}
+
+// TransparencyList maps the digests of artifacts to the Rekor log entries of
+// the signed statements, e.g., endorsements, about them. The digests are in
+// the format of the search index of Rekor, so that Sigstore tooling can be
+// used to look up the entries by artifact hash.
+type TransparencyList struct {
+	// RekorURL is the URL of the Rekor instance containing the entries.
+	RekorURL  string             `json:"rekorURL"`
+	Artifacts []ArtifactLogEntry `json:"artifacts"`
+}
+
+// ArtifactLogEntry lists the log entries about a single artifact.
+type ArtifactLogEntry struct {
+	// Digest of the artifact as `<algorithm>:<hex>`, e.g., `sha256:abc...`.
+	Digest string `json:"digest"`
+	// Names of the artifact in the statements.
+	Names   []string            `json:"names"`
+	Entries []TransparencyEntry `json:"entries"`
+}
+
+// TransparencyEntry references a single log entry.
+type TransparencyEntry struct {
+	UUID           string `json:"uuid"`
+	LogIndex       int64  `json:"logIndex"`
+	IntegratedTime int64  `json:"integratedTime"`
+	// URL from which the entry can be fetched.
+	URL string `json:"url"`
+}
+
+// SignedStatement is a DSSE envelope containing an in-toto statement, and
+// the Rekor log entry of the envelope.
+type SignedStatement struct {
+	Envelope *dsse.Envelope
+	LogEntry *LogEntry
+}
+
+// NewTransparencyList returns a TransparencyList for the given signed
+// statements in the Rekor instance at rekorURL. Each log entry must record
+// the envelope it is given with. Only the subject digests with algorithms
+// supported by the search index of Rekor are included. Neither the signatures
+// nor the inclusion of the entries are verified; see VerifyLogEntry and
+// VerifyEnvelopeLogEntry.
+func NewTransparencyList(rekorURL string, statements []SignedStatement) (*TransparencyList, error) {
+	rekorURL = strings.TrimSuffix(rekorURL, "/")
+	artifacts := make(map[string]*ArtifactLogEntry)
+	for i, signed := range statements {
+		if err := matchEnvelope(signed.Envelope, signed.LogEntry); err != nil {
+			return nil, fmt.Errorf("signed statement #%d: %v", i, err)
+		}
+		payload, err := signed.Envelope.DecodeB64Payload()
+		if err != nil {
+			return nil, fmt.Errorf("signed statement #%d: could not decode the payload: %v", i, err)
+		}
+		var header intoto.StatementHeader
+		if err := json.Unmarshal(payload, &header); err != nil {
+			return nil, fmt.Errorf("signed statement #%d: could not parse the statement: %v", i, err)
+		}
+
+		entry := TransparencyEntry{
+			UUID:           signed.LogEntry.UUID,
+			LogIndex:       signed.LogEntry.LogIndex,
+			IntegratedTime: signed.LogEntry.IntegratedTime,
+			URL:            fmt.Sprintf("%s/api/v1/log/entries/%s", rekorURL, signed.LogEntry.UUID),
+		}
+		for _, subject := range header.Subject {
+			for algorithm, value := range subject.Digest {
+				prefix, ok := indexAlgorithms[algorithm]
+				if !ok {
+					continue
+				}
+				digest := prefix + ":" + strings.ToLower(value)
+				artifact, ok := artifacts[digest]
+				if !ok {
+					artifact = &ArtifactLogEntry{Digest: digest}
+					artifacts[digest] = artifact
+				}
+				artifact.addName(subject.Name)
+				artifact.addEntry(entry)
+			}
+		}
+	}
+
+	list := &TransparencyList{RekorURL: rekorURL, Artifacts: make([]ArtifactLogEntry, 0, len(artifacts))}
+	for _, artifact := range artifacts {
+		list.Artifacts = append(list.Artifacts, *artifact)
+	}
+	sort.Slice(list.Artifacts, func(i, j int) bool {
+		return list.Artifacts[i].Digest < list.Artifacts[j].Digest
+	})
+	return list, nil
+}
+
+func (a *ArtifactLogEntry) addName(name string) {
+	for _, existing := range a.Names {
+		if existing == name {
+			return
+		}
+	}
+	a.Names = append(a.Names, name)
+}
+
+func (a *ArtifactLogEntry) addEntry(entry TransparencyEntry) {
+	for _, existing := range a.Entries {
+		if existing.UUID == entry.UUID {
+			return
+		}
+	}
+	a.Entries = append(a.Entries, entry)
+}
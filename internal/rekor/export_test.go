@@ -0,0 +1,65 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rekor
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/secure-systems-lab/go-securesystemslib/dsse"
+)
+
+func TestNewTransparencyList_Fixtures(t *testing.T) {
+	var envelope dsse.Envelope
+	readFixture(t, "endorsement.dsse.json", &envelope)
+	var entry LogEntry
+	readFixture(t, "endorsement.rekor.json", &entry)
+
+	// The same statement twice results in a single entry.
+	statements := []SignedStatement{{&envelope, &entry}, {&envelope, &entry}}
+	got, err := NewTransparencyList(DefaultURL+"/", statements)
+	if err != nil {
+		t.Fatalf("could not create the transparency list: %v", err)
+	}
+
+	want := &TransparencyList{
+		RekorURL: DefaultURL,
+		Artifacts: []ArtifactLogEntry{{
+			Digest: "sha256:01b792106ef1f61eece3a666ac6069875fc90b942fefc3fe931f016395bb6c88",
+			Names:  []string{"oak_functions-012a5206e5ab35d2778832638519441dd27664da"},
+			Entries: []TransparencyEntry{{
+				UUID:           entry.UUID,
+				LogIndex:       6,
+				IntegratedTime: 1690000000,
+				URL:            DefaultURL + "/api/v1/log/entries/" + entry.UUID,
+			}},
+		}},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected transparency list (-want +got):\n%s", diff)
+	}
+}
+
+func TestNewTransparencyList_MismatchedEntry(t *testing.T) {
+	var envelope dsse.Envelope
+	readFixture(t, "endorsement.dsse.json", &envelope)
+	var entry LogEntry
+	readFixture(t, "endorsement.rekor.json", &entry)
+	envelope.Signatures = []dsse.Signature{{Sig: "bm90IGEgc2lnbmF0dXJl"}}
+
+	if _, err := NewTransparencyList(DefaultURL, []SignedStatement{{&envelope, &entry}}); err == nil {
+		t.Errorf("expected an error for a log entry that does not record the envelope")
+	}
+}
@@ -0,0 +1,418 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rekor
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+
+	"github.com/secure-systems-lab/go-securesystemslib/cjson"
+	"github.com/secure-systems-lab/go-securesystemslib/dsse"
+)
+
+// RFC 6962 domain separation prefixes for leaf and interior node hashes.
+const (
+	leafHashPrefix = 0
+	nodeHashPrefix = 1
+)
+
+// setPayload is the content signed by the log in a SignedEntryTimestamp.
+type setPayload struct {
+	Body           string `json:"body"`
+	IntegratedTime int64  `json:"integratedTime"`
+	LogIndex       int64  `json:"logIndex"`
+	LogID          string `json:"logID"`
+}
+
+// dsseEntryBody is the canonicalized body of a Rekor entry of kind `dsse`.
+type dsseEntryBody struct {
+	Kind       string `json:"kind"`
+	APIVersion string `json:"apiVersion"`
+	Spec       struct {
+		PayloadHash struct {
+			Algorithm string `json:"algorithm"`
+			Value     string `json:"value"`
+		} `json:"payloadHash"`
+		Signatures []struct {
+			Signature string `json:"signature"`
+			Verifier  []byte `json:"verifier"`
+		} `json:"signatures"`
+	} `json:"spec"`
+}
+
+// VerifyLogEntry verifies that the given entry has been included in the log
+// with the given public key. It checks the SignedEntryTimestamp, the inclusion
+// proof, and the signature on the checkpoint that the inclusion proof refers to.
+func VerifyLogEntry(entry *LogEntry, logPublicKey *ecdsa.PublicKey) error {
+	if entry.Verification == nil {
+		return fmt.Errorf("the log entry does not contain verification material")
+	}
+	if err := VerifySET(entry, logPublicKey); err != nil {
+		return fmt.Errorf("could not verify the SignedEntryTimestamp: %v", err)
+	}
+	if err := VerifyInclusionProof(entry, logPublicKey); err != nil {
+		return fmt.Errorf("could not verify the inclusion proof: %v", err)
+	}
+	return nil
+}
+
+// VerifyEnvelopeLogEntry verifies that the given entry records the given
+// envelope, and that the envelope is signed by the given verifier, e.g., the
+// public key of the product team. The entry must record the very signature
+// of the envelope that verifies under the verifier, together with the public
+// key, or a certificate for the public key, of the verifier. It does not
+// verify the inclusion of the entry in the log; see VerifyLogEntry.
+func VerifyEnvelopeLogEntry(ctx context.Context, envelope *dsse.Envelope, entry *LogEntry, verifier dsse.Verifier) error {
+	body, err := matchEnvelopePayload(envelope, entry)
+	if err != nil {
+		return err
+	}
+
+	signature, err := verifiedSignature(ctx, envelope, verifier)
+	if err != nil {
+		return err
+	}
+	for _, logged := range body.Spec.Signatures {
+		if logged.Signature != signature {
+			continue
+		}
+		publicKey, err := parseLoggedVerifier(logged.Verifier)
+		if err != nil {
+			return fmt.Errorf("could not parse the logged verifier: %v", err)
+		}
+		if !samePublicKey(publicKey, verifier.Public()) {
+			return fmt.Errorf("the log entry records the signature with a different verifier")
+		}
+		return nil
+	}
+	return fmt.Errorf("the log entry does not record the signature of the envelope by the verifier")
+}
+
+// verifiedSignature returns the signature of the given envelope that
+// verifies under the given verifier.
+func verifiedSignature(ctx context.Context, envelope *dsse.Envelope, verifier dsse.Verifier) (string, error) {
+	payload, err := envelope.DecodeB64Payload()
+	if err != nil {
+		return "", fmt.Errorf("could not decode the envelope payload: %v", err)
+	}
+	pae := dsse.PAE(envelope.PayloadType, payload)
+	for _, signature := range envelope.Signatures {
+		sig, err := base64.StdEncoding.DecodeString(signature.Sig)
+		if err != nil {
+			continue
+		}
+		if err := verifier.Verify(ctx, pae, sig); err == nil {
+			return signature.Sig, nil
+		}
+	}
+	return "", fmt.Errorf("could not verify the envelope signature: no signature verifies under the verifier")
+}
+
+// parseLoggedVerifier parses the given PEM-encoded public key or certificate
+// recorded in a `dsse` entry, and returns the public key.
+func parseLoggedVerifier(verifierPEM []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(verifierPEM)
+	if block == nil {
+		return nil, fmt.Errorf("the verifier is not PEM-encoded")
+	}
+	switch block.Type {
+	case "PUBLIC KEY":
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return cert.PublicKey, nil
+	default:
+		return nil, fmt.Errorf("unexpected PEM block type %q", block.Type)
+	}
+}
+
+// samePublicKey returns true if the given public keys are equal.
+func samePublicKey(a, b crypto.PublicKey) bool {
+	key, ok := a.(interface{ Equal(crypto.PublicKey) bool })
+	return ok && key.Equal(b)
+}
+
+// matchEnvelope checks that the given entry is of kind `dsse`, and records
+// the payload and at least one of the signatures of the given envelope.
+func matchEnvelope(envelope *dsse.Envelope, entry *LogEntry) error {
+	body, err := matchEnvelopePayload(envelope, entry)
+	if err != nil {
+		return err
+	}
+	if !hasCommonSignature(envelope, *body) {
+		return fmt.Errorf("the log entry does not record any of the signatures of the envelope")
+	}
+	return nil
+}
+
+// matchEnvelopePayload checks that the given entry is of kind `dsse`, and
+// records the payload of the given envelope, and returns the entry body.
+func matchEnvelopePayload(envelope *dsse.Envelope, entry *LogEntry) (*dsseEntryBody, error) {
+	payload, err := envelope.DecodeB64Payload()
+	if err != nil {
+		return nil, fmt.Errorf("could not decode the envelope payload: %v", err)
+	}
+	return matchPayload(payload, entry)
+}
+
+// VerifyPayloadLogEntry verifies that the given entry is of kind `dsse`, and
+// records the given payload, e.g., a provenance statement. It does not verify
+// any signatures, nor the inclusion of the entry in the log; see
+// VerifyLogEntry.
+func VerifyPayloadLogEntry(payload []byte, entry *LogEntry) error {
+	_, err := matchPayload(payload, entry)
+	return err
+}
+
+func matchPayload(payload []byte, entry *LogEntry) (*dsseEntryBody, error) {
+	bodyBytes, err := base64.StdEncoding.DecodeString(entry.Body)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode the entry body: %v", err)
+	}
+	var body dsseEntryBody
+	if err := json.Unmarshal(bodyBytes, &body); err != nil {
+		return nil, fmt.Errorf("could not unmarshal the entry body: %v", err)
+	}
+	if body.Kind != DSSEKind {
+		return nil, fmt.Errorf("unexpected entry kind: got %q, want %q", body.Kind, DSSEKind)
+	}
+	payloadHash := sha256.Sum256(payload)
+	if body.Spec.PayloadHash.Algorithm != "sha256" || body.Spec.PayloadHash.Value != hex.EncodeToString(payloadHash[:]) {
+		return nil, fmt.Errorf("the log entry does not record the payload")
+	}
+	return &body, nil
+}
+
+func hasCommonSignature(envelope *dsse.Envelope, body dsseEntryBody) bool {
+	for _, logged := range body.Spec.Signatures {
+		for _, signature := range envelope.Signatures {
+			if logged.Signature == signature.Sig {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// VerifySET verifies the SignedEntryTimestamp of the given entry, i.e., the
+// promise of the log with the given public key to include the entry.
+func VerifySET(entry *LogEntry, logPublicKey *ecdsa.PublicKey) error {
+	if entry.Verification == nil || len(entry.Verification.SignedEntryTimestamp) == 0 {
+		return fmt.Errorf("the log entry does not contain a SignedEntryTimestamp")
+	}
+	payload, err := cjson.EncodeCanonical(setPayload{
+		Body:           entry.Body,
+		IntegratedTime: entry.IntegratedTime,
+		LogIndex:       entry.LogIndex,
+		LogID:          entry.LogID,
+	})
+	if err != nil {
+		return fmt.Errorf("could not canonicalize the entry: %v", err)
+	}
+	digest := sha256.Sum256(payload)
+	if !ecdsa.VerifyASN1(logPublicKey, digest[:], entry.Verification.SignedEntryTimestamp) {
+		return fmt.Errorf("invalid signature")
+	}
+	return nil
+}
+
+// VerifyInclusionProof verifies the Merkle tree inclusion proof of the given
+// entry, and that the checkpoint it refers to is signed by the log with the
+// given public key.
+func VerifyInclusionProof(entry *LogEntry, logPublicKey *ecdsa.PublicKey) error {
+	if entry.Verification == nil || entry.Verification.InclusionProof == nil {
+		return fmt.Errorf("the log entry does not contain an inclusion proof")
+	}
+	proof := entry.Verification.InclusionProof
+	if proof.LogIndex < 0 || proof.TreeSize <= 0 {
+		return fmt.Errorf("invalid log index %d or tree size %d", proof.LogIndex, proof.TreeSize)
+	}
+
+	body, err := base64.StdEncoding.DecodeString(entry.Body)
+	if err != nil {
+		return fmt.Errorf("could not decode the entry body: %v", err)
+	}
+	hashes := make([][]byte, 0, len(proof.Hashes))
+	for _, h := range proof.Hashes {
+		hash, err := hex.DecodeString(h)
+		if err != nil {
+			return fmt.Errorf("could not decode hash %q: %v", h, err)
+		}
+		hashes = append(hashes, hash)
+	}
+	wantRoot, err := hex.DecodeString(proof.RootHash)
+	if err != nil {
+		return fmt.Errorf("could not decode the root hash: %v", err)
+	}
+
+	gotRoot, err := rootFromInclusionProof(uint64(proof.LogIndex), uint64(proof.TreeSize), hashLeaf(body), hashes)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(gotRoot, wantRoot) {
+		return fmt.Errorf("the computed root hash %x does not match the root hash %x in the proof", gotRoot, wantRoot)
+	}
+
+	checkpoint, err := VerifyCheckpoint(proof.Checkpoint, logPublicKey)
+	if err != nil {
+		return fmt.Errorf("could not verify the checkpoint: %v", err)
+	}
+	if checkpoint.TreeSize != proof.TreeSize || !bytes.Equal(checkpoint.RootHash, wantRoot) {
+		return fmt.Errorf("the checkpoint does not commit to the tree in the inclusion proof")
+	}
+	return nil
+}
+
+// Checkpoint is the content of a signed tree head, in the checkpoint format
+// of https://github.com/transparency-dev/formats/tree/main/log.
+type Checkpoint struct {
+	// Origin identifies the log, e.g., `rekor.sigstore.dev - <tree ID>`.
+	Origin   string
+	TreeSize int64
+	RootHash []byte
+}
+
+// noteSignature is a signature line of a signed note.
+type noteSignature struct {
+	// name is the name of the signer.
+	name string
+	// signature is the decoded signature, starting with the 4-byte hash of
+	// the key of the signer.
+	signature []byte
+}
+
+// VerifyCheckpoint verifies that the given signed note carries a valid
+// signature from the given key, and parses the checkpoint in it.
+func VerifyCheckpoint(note string, logPublicKey *ecdsa.PublicKey) (*Checkpoint, error) {
+	text, signatures, err := splitNote(note)
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256([]byte(text))
+
+	verified := false
+	for _, s := range signatures {
+		if len(s.signature) < 5 {
+			continue
+		}
+		if ecdsa.VerifyASN1(logPublicKey, digest[:], s.signature[4:]) {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return nil, fmt.Errorf("the checkpoint has no valid signature from the log")
+	}
+	return parseCheckpoint(text)
+}
+
+// splitNote splits the given signed note into its text, including the final
+// newline, and its signatures. Malformed signature lines are skipped.
+func splitNote(note string) (string, []noteSignature, error) {
+	text, lines, found := strings.Cut(note, "\n\n")
+	if !found {
+		return "", nil, fmt.Errorf("the checkpoint is not a signed note")
+	}
+	var signatures []noteSignature
+	for _, line := range strings.Split(strings.TrimSuffix(lines, "\n"), "\n") {
+		// Signature lines have the form "— <name> <base64(key hash || signature)>".
+		fields := strings.Fields(strings.TrimPrefix(line, "— "))
+		if len(fields) != 2 {
+			continue
+		}
+		signature, err := base64.StdEncoding.DecodeString(fields[1])
+		if err != nil || len(signature) < 4 {
+			continue
+		}
+		signatures = append(signatures, noteSignature{name: fields[0], signature: signature})
+	}
+	return text + "\n", signatures, nil
+}
+
+// parseCheckpoint parses the given text of a checkpoint note.
+func parseCheckpoint(text string) (*Checkpoint, error) {
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	if len(lines) < 3 {
+		return nil, fmt.Errorf("the checkpoint has %d lines, want at least 3", len(lines))
+	}
+	treeSize, err := strconv.ParseInt(lines[1], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse the tree size: %v", err)
+	}
+	rootHash, err := base64.StdEncoding.DecodeString(lines[2])
+	if err != nil {
+		return nil, fmt.Errorf("could not decode the root hash: %v", err)
+	}
+	return &Checkpoint{Origin: lines[0], TreeSize: treeSize, RootHash: rootHash}, nil
+}
+
+// rootFromInclusionProof computes the root hash of a tree of the given size
+// from the hash of the leaf at the given index and its RFC 6962 audit path.
+func rootFromInclusionProof(index, size uint64, leafHash []byte, proof [][]byte) ([]byte, error) {
+	if index >= size {
+		return nil, fmt.Errorf("index %d is beyond the tree size %d", index, size)
+	}
+	// The audit path consists of the hashes of the inner part of the tree,
+	// where the paths to the leaf and to the last leaf diverge, followed by
+	// the hashes of the perfect subtrees on the right border of the tree.
+	inner := bits.Len64(index ^ (size - 1))
+	border := bits.OnesCount64(index >> uint(inner))
+	if len(proof) != inner+border {
+		return nil, fmt.Errorf("wrong proof size %d, want %d", len(proof), inner+border)
+	}
+
+	hash := leafHash
+	for i, sibling := range proof[:inner] {
+		if (index>>uint(i))&1 == 0 {
+			hash = hashChildren(hash, sibling)
+		} else {
+			hash = hashChildren(sibling, hash)
+		}
+	}
+	for _, sibling := range proof[inner:] {
+		hash = hashChildren(sibling, hash)
+	}
+	return hash, nil
+}
+
+func hashLeaf(leaf []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{leafHashPrefix})
+	h.Write(leaf)
+	return h.Sum(nil)
+}
+
+func hashChildren(left, right []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{nodeHashPrefix})
+	h.Write(left)
+	h.Write(right)
+	return h.Sum(nil)
+}
@@ -0,0 +1,413 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rekor
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/secure-systems-lab/go-securesystemslib/cjson"
+	"github.com/secure-systems-lab/go-securesystemslib/dsse"
+)
+
+const fixturesPath = "../../testdata/rekor"
+
+// merkleRoot computes the RFC 6962 root hash of the given leaf hashes.
+func merkleRoot(leaves [][]byte) []byte {
+	if len(leaves) == 1 {
+		return leaves[0]
+	}
+	k := largestPowerOfTwoBelow(len(leaves))
+	return hashChildren(merkleRoot(leaves[:k]), merkleRoot(leaves[k:]))
+}
+
+// auditPath computes the RFC 6962 audit path for the leaf at the given index.
+func auditPath(index int, leaves [][]byte) [][]byte {
+	if len(leaves) == 1 {
+		return nil
+	}
+	k := largestPowerOfTwoBelow(len(leaves))
+	if index < k {
+		return append(auditPath(index, leaves[:k]), merkleRoot(leaves[k:]))
+	}
+	return append(auditPath(index-k, leaves[k:]), merkleRoot(leaves[:k]))
+}
+
+func largestPowerOfTwoBelow(n int) int {
+	k := 1
+	for k*2 < n {
+		k *= 2
+	}
+	return k
+}
+
+// fakeLog creates log entries with valid verification material, signed by a
+// freshly generated log key.
+type fakeLog struct {
+	key *ecdsa.PrivateKey
+}
+
+func newFakeLog(t *testing.T) *fakeLog {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("could not generate log key: %v", err)
+	}
+	return &fakeLog{key: key}
+}
+
+func (l *fakeLog) sign(t *testing.T, data []byte) []byte {
+	digest := sha256.Sum256(data)
+	sig, err := l.key.Sign(rand.Reader, digest[:], crypto.SHA256)
+	if err != nil {
+		t.Fatalf("could not sign: %v", err)
+	}
+	return sig
+}
+
+// leaves returns the leaf hashes of a tree with the given size, with the
+// given body at the given index.
+func leaves(body []byte, index, size int) [][]byte {
+	leaves := make([][]byte, size)
+	for i := range leaves {
+		leaves[i] = hashLeaf([]byte(fmt.Sprintf("leaf %d", i)))
+	}
+	leaves[index] = hashLeaf(body)
+	return leaves
+}
+
+// checkpoint creates a checkpoint for the tree with the given size and root
+// hash, signed by the log.
+func (l *fakeLog) checkpoint(t *testing.T, size int, root []byte) string {
+	text := fmt.Sprintf("rekor.example.com - 1234\n%d\n%s\n", size, base64.StdEncoding.EncodeToString(root))
+	noteSig := append([]byte{0, 0, 0, 0}, l.sign(t, []byte(text))...)
+	return text + "\n— rekor.example.com " + base64.StdEncoding.EncodeToString(noteSig) + "\n"
+}
+
+// entry creates an entry with the given body at the given index of a tree
+// with the given size.
+func (l *fakeLog) entry(t *testing.T, body []byte, index, size int) *LogEntry {
+	leaves := leaves(body, index, size)
+	root := merkleRoot(leaves)
+	hashes := []string{}
+	for _, h := range auditPath(index, leaves) {
+		hashes = append(hashes, hex.EncodeToString(h))
+	}
+	note := l.checkpoint(t, size, root)
+
+	entry := &LogEntry{
+		UUID:           "uuid",
+		Body:           base64.StdEncoding.EncodeToString(body),
+		IntegratedTime: 1690000000,
+		LogID:          testLogID,
+		LogIndex:       int64(index),
+		Verification: &LogEntryVerification{
+			InclusionProof: &InclusionProof{
+				Checkpoint: note,
+				Hashes:     hashes,
+				LogIndex:   int64(index),
+				RootHash:   hex.EncodeToString(root),
+				TreeSize:   int64(size),
+			},
+		},
+	}
+	payload, err := cjson.EncodeCanonical(setPayload{
+		Body: entry.Body, IntegratedTime: entry.IntegratedTime, LogIndex: entry.LogIndex, LogID: entry.LogID,
+	})
+	if err != nil {
+		t.Fatalf("could not canonicalize entry: %v", err)
+	}
+	entry.Verification.SignedEntryTimestamp = l.sign(t, payload)
+	return entry
+}
+
+func TestRootFromInclusionProof(t *testing.T) {
+	for size := 1; size <= 17; size++ {
+		leaves := make([][]byte, size)
+		for i := range leaves {
+			leaves[i] = hashLeaf([]byte{byte(i)})
+		}
+		want := merkleRoot(leaves)
+		for index := 0; index < size; index++ {
+			got, err := rootFromInclusionProof(uint64(index), uint64(size), leaves[index], auditPath(index, leaves))
+			if err != nil {
+				t.Fatalf("size %d, index %d: %v", size, index, err)
+			}
+			if hex.EncodeToString(got) != hex.EncodeToString(want) {
+				t.Errorf("size %d, index %d: got root %x, want %x", size, index, got, want)
+			}
+		}
+	}
+}
+
+func TestVerifyLogEntry(t *testing.T) {
+	log := newFakeLog(t)
+	entry := log.entry(t, []byte("body"), 5, 11)
+	if err := VerifyLogEntry(entry, &log.key.PublicKey); err != nil {
+		t.Fatalf("could not verify log entry: %v", err)
+	}
+}
+
+func TestVerifyLogEntry_TamperedBodyFails(t *testing.T) {
+	log := newFakeLog(t)
+	entry := log.entry(t, []byte("body"), 5, 11)
+	entry.Body = base64.StdEncoding.EncodeToString([]byte("other body"))
+	if err := VerifyLogEntry(entry, &log.key.PublicKey); err == nil {
+		t.Fatalf("expected verification of a tampered entry to fail")
+	}
+}
+
+func TestVerifyLogEntry_WrongLogKeyFails(t *testing.T) {
+	entry := newFakeLog(t).entry(t, []byte("body"), 0, 1)
+	other := newFakeLog(t)
+	if err := VerifyLogEntry(entry, &other.key.PublicKey); err == nil {
+		t.Fatalf("expected verification with the wrong log key to fail")
+	}
+}
+
+func TestVerifyLogEntry_WrongProofFails(t *testing.T) {
+	log := newFakeLog(t)
+	entry := log.entry(t, []byte("body"), 2, 8)
+	entry.Verification.InclusionProof.Hashes = entry.Verification.InclusionProof.Hashes[1:]
+	if err := VerifyLogEntry(entry, &log.key.PublicKey); err == nil {
+		t.Fatalf("expected verification with a truncated proof to fail")
+	}
+}
+
+// ecdsaSigner is a dsse.SignerVerifier backed by a local ECDSA key.
+type ecdsaSigner struct {
+	key *ecdsa.PrivateKey
+}
+
+func (s *ecdsaSigner) Sign(_ context.Context, data []byte) ([]byte, error) {
+	digest := sha256.Sum256(data)
+	return s.key.Sign(rand.Reader, digest[:], crypto.SHA256)
+}
+
+func (s *ecdsaSigner) Verify(_ context.Context, data, sig []byte) error {
+	digest := sha256.Sum256(data)
+	if !ecdsa.VerifyASN1(&s.key.PublicKey, digest[:], sig) {
+		return fmt.Errorf("invalid signature")
+	}
+	return nil
+}
+
+func (s *ecdsaSigner) KeyID() (string, error) {
+	return "", nil
+}
+
+func (s *ecdsaSigner) Public() crypto.PublicKey {
+	return s.key.Public()
+}
+
+// dsseBody returns the body of a `dsse` entry for the given envelope, with
+// the given PEM-encoded verifier for each of its signatures.
+func dsseBody(t *testing.T, envelope *dsse.Envelope, verifierPEM []byte) []byte {
+	payload, err := envelope.DecodeB64Payload()
+	if err != nil {
+		t.Fatalf("could not decode payload: %v", err)
+	}
+	payloadHash := sha256.Sum256(payload)
+	var body dsseEntryBody
+	body.Kind = DSSEKind
+	body.APIVersion = DSSEKindVersion
+	body.Spec.PayloadHash.Algorithm = "sha256"
+	body.Spec.PayloadHash.Value = hex.EncodeToString(payloadHash[:])
+	for _, sig := range envelope.Signatures {
+		body.Spec.Signatures = append(body.Spec.Signatures, struct {
+			Signature string `json:"signature"`
+			Verifier  []byte `json:"verifier"`
+		}{Signature: sig.Sig, Verifier: verifierPEM})
+	}
+	bytes, err := json.Marshal(body)
+	if err != nil {
+		t.Fatalf("could not marshal body: %v", err)
+	}
+	return bytes
+}
+
+func TestVerifyEnvelopeLogEntry(t *testing.T) {
+	ctx := context.Background()
+	signer, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("could not generate key: %v", err)
+	}
+	keySigner := &ecdsaSigner{key: signer}
+	envelopeSigner, err := dsse.NewEnvelopeSigner(keySigner)
+	if err != nil {
+		t.Fatalf("could not create envelope signer: %v", err)
+	}
+	envelope, err := envelopeSigner.SignPayload(ctx, "text/plain", []byte("hello"))
+	if err != nil {
+		t.Fatalf("could not sign payload: %v", err)
+	}
+
+	log := newFakeLog(t)
+	entry := log.entry(t, dsseBody(t, envelope, publicKeyPEM(t, &signer.PublicKey)), 3, 4)
+	if err := VerifyLogEntry(entry, &log.key.PublicKey); err != nil {
+		t.Fatalf("could not verify log entry: %v", err)
+	}
+	if err := VerifyEnvelopeLogEntry(ctx, envelope, entry, keySigner); err != nil {
+		t.Fatalf("could not verify envelope log entry: %v", err)
+	}
+
+	other, err := envelopeSigner.SignPayload(ctx, "text/plain", []byte("goodbye"))
+	if err != nil {
+		t.Fatalf("could not sign payload: %v", err)
+	}
+	if err := VerifyEnvelopeLogEntry(ctx, other, entry, keySigner); err == nil {
+		t.Fatalf("expected verification of a different envelope to fail")
+	}
+}
+
+func TestVerifyEnvelopeLogEntry_TwoSignatures(t *testing.T) {
+	ctx := context.Background()
+	var signers []*ecdsaSigner
+	var keyPEMs [][]byte
+	for i := 0; i < 2; i++ {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatalf("could not generate key: %v", err)
+		}
+		signers = append(signers, &ecdsaSigner{key: key})
+		keyPEMs = append(keyPEMs, publicKeyPEM(t, &key.PublicKey))
+	}
+	envelopeSigner, err := dsse.NewEnvelopeSigner(signers[0], signers[1])
+	if err != nil {
+		t.Fatalf("could not create envelope signer: %v", err)
+	}
+	envelope, err := envelopeSigner.SignPayload(ctx, "text/plain", []byte("hello"))
+	if err != nil {
+		t.Fatalf("could not sign payload: %v", err)
+	}
+	// withSignature returns the envelope with only the signature at the given
+	// index, for building entries that record a single signature.
+	withSignature := func(i int) *dsse.Envelope {
+		single := *envelope
+		single.Signatures = []dsse.Signature{envelope.Signatures[i]}
+		return &single
+	}
+
+	log := newFakeLog(t)
+	// The entry records the first signature with the first key.
+	entry := log.entry(t, dsseBody(t, withSignature(0), keyPEMs[0]), 3, 4)
+	if err := VerifyEnvelopeLogEntry(ctx, envelope, entry, signers[0]); err != nil {
+		t.Fatalf("could not verify envelope log entry: %v", err)
+	}
+	// The second signature of the envelope verifies, but is not logged.
+	if err := VerifyEnvelopeLogEntry(ctx, envelope, entry, signers[1]); err == nil {
+		t.Fatalf("expected verification with the unlogged signature to fail")
+	}
+
+	// The entry records the second signature with the first key.
+	entry = log.entry(t, dsseBody(t, withSignature(1), keyPEMs[0]), 3, 4)
+	if err := VerifyEnvelopeLogEntry(ctx, envelope, entry, signers[1]); err == nil {
+		t.Fatalf("expected verification with a different logged verifier to fail")
+	}
+}
+
+// publicKeyPEM returns the PEM encoding of the given public key.
+func publicKeyPEM(t *testing.T, key crypto.PublicKey) []byte {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatalf("could not marshal public key: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
+
+func readFixture(t *testing.T, name string, object interface{}) {
+	bytes, err := os.ReadFile(filepath.Join(fixturesPath, name))
+	if err != nil {
+		t.Fatalf("could not read %s: %v", name, err)
+	}
+	if err := json.Unmarshal(bytes, object); err != nil {
+		t.Fatalf("could not unmarshal %s: %v", name, err)
+	}
+}
+
+func readPublicKeyFixture(t *testing.T, name string) *ecdsa.PublicKey {
+	bytes, err := os.ReadFile(filepath.Join(fixturesPath, name))
+	if err != nil {
+		t.Fatalf("could not read %s: %v", name, err)
+	}
+	block, _ := pem.Decode(bytes)
+	if block == nil {
+		t.Fatalf("could not decode %s", name)
+	}
+	publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		t.Fatalf("could not parse %s: %v", name, err)
+	}
+	return publicKey.(*ecdsa.PublicKey)
+}
+
+func TestVerify_Fixtures(t *testing.T) {
+	var envelope dsse.Envelope
+	readFixture(t, "endorsement.dsse.json", &envelope)
+	var entry LogEntry
+	readFixture(t, "endorsement.rekor.json", &entry)
+	logKey := readPublicKeyFixture(t, "rekor.pub")
+	endorserKey := readPublicKeyFixture(t, "endorser.pub")
+
+	if err := VerifySET(&entry, logKey); err != nil {
+		t.Errorf("could not verify the SET: %v", err)
+	}
+	if err := VerifyInclusionProof(&entry, logKey); err != nil {
+		t.Errorf("could not verify the inclusion proof: %v", err)
+	}
+	verifier := &ecdsaSigner{key: &ecdsa.PrivateKey{PublicKey: *endorserKey}}
+	if err := VerifyEnvelopeLogEntry(context.Background(), &envelope, &entry, verifier); err != nil {
+		t.Errorf("could not verify the envelope: %v", err)
+	}
+
+	// The endorser key is not the log key.
+	if err := VerifySET(&entry, endorserKey); err == nil {
+		t.Errorf("expected verification of the SET with the wrong key to fail")
+	}
+}
+
+func TestVerifyPayloadLogEntry_Fixtures(t *testing.T) {
+	var envelope dsse.Envelope
+	readFixture(t, "endorsement.dsse.json", &envelope)
+	var entry LogEntry
+	readFixture(t, "endorsement.rekor.json", &entry)
+	payload, err := envelope.DecodeB64Payload()
+	if err != nil {
+		t.Fatalf("could not decode the payload: %v", err)
+	}
+
+	if err := VerifyPayloadLogEntry(payload, &entry); err != nil {
+		t.Errorf("could not verify the payload: %v", err)
+	}
+	if err := VerifyPayloadLogEntry(append(payload, ' '), &entry); err == nil {
+		t.Errorf("expected verification of a different payload to fail")
+	}
+	if got, want := entry.IntegratedAt(), time.Unix(1690000000, 0).UTC(); !got.Equal(want) {
+		t.Errorf("unexpected integrated time: got %v, want %v", got, want)
+	}
+}
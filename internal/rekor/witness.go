@@ -0,0 +1,189 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rekor
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Signature algorithms of note verifier keys, as in
+// https://github.com/C2SP/C2SP/blob/main/signed-note.md.
+const (
+	// ed25519Algorithm identifies Ed25519 signatures over the note text, as
+	// produced by older witnesses.
+	ed25519Algorithm = 0x01
+	// cosignatureAlgorithm identifies timestamped Ed25519 cosignatures, as
+	// in https://github.com/C2SP/C2SP/blob/main/tlog-cosignature.md.
+	cosignatureAlgorithm = 0x04
+)
+
+// cosignatureHeader is the first line of the message signed in cosignatures.
+const cosignatureHeader = "cosignature/v1\n"
+
+// Witness is an independent party that cosigns checkpoints of the log after
+// checking that they are consistent with the checkpoints it has seen before.
+type Witness struct {
+	// Name is the name of the witness in signature lines.
+	Name      string
+	PublicKey ed25519.PublicKey
+	algorithm byte
+	keyHash   []byte
+}
+
+// ParseWitnessKey parses the note verifier key of a witness, of the form
+// `<name>+<hex key hash>+<base64 key>`, where the key is an Ed25519 public key
+// prefixed with its signature algorithm.
+func ParseWitnessKey(vkey string) (*Witness, error) {
+	parts := strings.SplitN(vkey, "+", 3)
+	if len(parts) != 3 || parts[0] == "" || strings.ContainsAny(parts[0], " \t\n") {
+		return nil, fmt.Errorf("malformed verifier key %q, want <name>+<hex key hash>+<base64 key>", vkey)
+	}
+	name := parts[0]
+	keyHash, err := hex.DecodeString(parts[1])
+	if err != nil || len(keyHash) != 4 {
+		return nil, fmt.Errorf("invalid key hash %q of witness %s", parts[1], name)
+	}
+	key, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil || len(key) != 1+ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid key of witness %s", name)
+	}
+	if key[0] != ed25519Algorithm && key[0] != cosignatureAlgorithm {
+		return nil, fmt.Errorf("unsupported signature algorithm %#x of witness %s", key[0], name)
+	}
+	if !bytes.Equal(noteKeyHash(name, key), keyHash) {
+		return nil, fmt.Errorf("the key hash of witness %s does not match its key", name)
+	}
+	return &Witness{Name: name, PublicKey: ed25519.PublicKey(key[1:]), algorithm: key[0], keyHash: keyHash}, nil
+}
+
+// noteKeyHash returns the hash identifying the given key, including its
+// algorithm, of the signer with the given name in signature lines.
+func noteKeyHash(name string, key []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte(name + "\n"))
+	h.Write(key)
+	return h.Sum(nil)[:4]
+}
+
+// verify checks whether the given signature, from a signature line with the
+// name of the witness, is a valid signature of the witness over the given
+// checkpoint text.
+func (w *Witness) verify(text string, signature []byte) bool {
+	if !bytes.HasPrefix(signature, w.keyHash) {
+		return false
+	}
+	signature = signature[len(w.keyHash):]
+	switch w.algorithm {
+	case cosignatureAlgorithm:
+		// The signature is preceded by the big-endian time of the cosignature,
+		// in seconds since the UNIX epoch, which is part of the signed message.
+		if len(signature) != 8+ed25519.SignatureSize {
+			return false
+		}
+		message := fmt.Sprintf("%stime %d\n%s", cosignatureHeader, binary.BigEndian.Uint64(signature[:8]), text)
+		return ed25519.Verify(w.PublicKey, []byte(message), signature[8:])
+	case ed25519Algorithm:
+		return len(signature) == ed25519.SignatureSize && ed25519.Verify(w.PublicKey, []byte(text), signature)
+	}
+	return false
+}
+
+// WitnessPolicy requires checkpoints to be cosigned by a minimum number of
+// trusted witnesses.
+type WitnessPolicy struct {
+	witnesses []*Witness
+	threshold int
+}
+
+// NewWitnessPolicy returns a policy requiring cosignatures from at least
+// threshold of the given witnesses, which must be distinct. The threshold must
+// be between 1 and the number of witnesses.
+func NewWitnessPolicy(witnesses []*Witness, threshold int) (*WitnessPolicy, error) {
+	if threshold < 1 || threshold > len(witnesses) {
+		return nil, fmt.Errorf("invalid threshold %d for %d witnesses", threshold, len(witnesses))
+	}
+	seen := make(map[string]bool)
+	for _, witness := range witnesses {
+		id := witness.Name + "+" + hex.EncodeToString(witness.keyHash)
+		if seen[id] {
+			return nil, fmt.Errorf("duplicate witness %s", witness.Name)
+		}
+		seen[id] = true
+	}
+	return &WitnessPolicy{witnesses: witnesses, threshold: threshold}, nil
+}
+
+// LoadWitnessPolicy reads the note verifier keys of the trusted witnesses from
+// the file at the given path, one per line, and returns a policy requiring
+// cosignatures from at least threshold of them. Empty lines and lines starting
+// with `#` are ignored.
+func LoadWitnessPolicy(path string, threshold int) (*WitnessPolicy, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read the witness keys: %v", err)
+	}
+	var witnesses []*Witness
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		witness, err := ParseWitnessKey(line)
+		if err != nil {
+			return nil, err
+		}
+		witnesses = append(witnesses, witness)
+	}
+	return NewWitnessPolicy(witnesses, threshold)
+}
+
+// VerifyWitnessedCheckpoint verifies that the given signed note carries a
+// valid signature from the log with the given key, and cosignatures from at
+// least as many witnesses as required by the given policy, and parses the
+// checkpoint in it. A witnessed checkpoint, e.g., one mirrored from a witness,
+// does not depend on the log alone being honest: the witnesses have checked
+// that it is consistent with all the checkpoints of the log they have seen.
+func VerifyWitnessedCheckpoint(note string, logPublicKey *ecdsa.PublicKey, policy *WitnessPolicy) (*Checkpoint, error) {
+	checkpoint, err := VerifyCheckpoint(note, logPublicKey)
+	if err != nil {
+		return nil, err
+	}
+	text, signatures, err := splitNote(note)
+	if err != nil {
+		return nil, err
+	}
+	cosigned := 0
+	for _, witness := range policy.witnesses {
+		for _, s := range signatures {
+			if s.name == witness.Name && witness.verify(text, s.signature) {
+				cosigned++
+				break
+			}
+		}
+	}
+	if cosigned < policy.threshold {
+		return nil, fmt.Errorf("the checkpoint is cosigned by %d trusted witnesses, want at least %d", cosigned, policy.threshold)
+	}
+	return checkpoint, nil
+}
@@ -0,0 +1,168 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rekor
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeWitness cosigns checkpoints with a freshly generated Ed25519 key.
+type fakeWitness struct {
+	name      string
+	algorithm byte
+	key       ed25519.PrivateKey
+}
+
+func newFakeWitness(t *testing.T, name string, algorithm byte) *fakeWitness {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("could not generate witness key: %v", err)
+	}
+	return &fakeWitness{name: name, algorithm: algorithm, key: key}
+}
+
+// vkey returns the note verifier key of the witness.
+func (w *fakeWitness) vkey() string {
+	key := append([]byte{w.algorithm}, w.key.Public().(ed25519.PublicKey)...)
+	return w.name + "+" + hex.EncodeToString(noteKeyHash(w.name, key)) + "+" + base64.StdEncoding.EncodeToString(key)
+}
+
+// cosign adds a signature line of the witness to the given signed note.
+func (w *fakeWitness) cosign(t *testing.T, note string) string {
+	text, _, found := strings.Cut(note, "\n\n")
+	if !found {
+		t.Fatalf("not a signed note: %q", note)
+	}
+	text += "\n"
+	key := append([]byte{w.algorithm}, w.key.Public().(ed25519.PublicKey)...)
+	signature := noteKeyHash(w.name, key)
+	if w.algorithm == cosignatureAlgorithm {
+		timestamp := uint64(1690000000)
+		var encoded [8]byte
+		binary.BigEndian.PutUint64(encoded[:], timestamp)
+		signature = append(signature, encoded[:]...)
+		signature = append(signature, ed25519.Sign(w.key, []byte(fmt.Sprintf("%stime %d\n%s", cosignatureHeader, timestamp, text)))...)
+	} else {
+		signature = append(signature, ed25519.Sign(w.key, []byte(text))...)
+	}
+	return note + "— " + w.name + " " + base64.StdEncoding.EncodeToString(signature) + "\n"
+}
+
+func witnessPolicy(t *testing.T, threshold int, witnesses ...*fakeWitness) *WitnessPolicy {
+	var parsed []*Witness
+	for _, w := range witnesses {
+		witness, err := ParseWitnessKey(w.vkey())
+		if err != nil {
+			t.Fatalf("could not parse the key of witness %s: %v", w.name, err)
+		}
+		parsed = append(parsed, witness)
+	}
+	policy, err := NewWitnessPolicy(parsed, threshold)
+	if err != nil {
+		t.Fatalf("could not create the witness policy: %v", err)
+	}
+	return policy
+}
+
+func TestVerifyWitnessedCheckpoint(t *testing.T) {
+	log := newFakeLog(t)
+	first := newFakeWitness(t, "witness.example.com", cosignatureAlgorithm)
+	second := newFakeWitness(t, "legacy.example.com", ed25519Algorithm)
+	third := newFakeWitness(t, "offline.example.com", cosignatureAlgorithm)
+	root := merkleRoot(leaves([]byte("body"), 5, 11))
+	note := second.cosign(t, first.cosign(t, log.checkpoint(t, 11, root)))
+
+	checkpoint, err := VerifyWitnessedCheckpoint(note, &log.key.PublicKey, witnessPolicy(t, 2, first, second, third))
+	if err != nil {
+		t.Fatalf("could not verify the witnessed checkpoint: %v", err)
+	}
+	if checkpoint.Origin != "rekor.example.com - 1234" || checkpoint.TreeSize != 11 || hex.EncodeToString(checkpoint.RootHash) != hex.EncodeToString(root) {
+		t.Errorf("unexpected checkpoint: %+v", checkpoint)
+	}
+
+	if _, err := VerifyWitnessedCheckpoint(note, &log.key.PublicKey, witnessPolicy(t, 3, first, second, third)); err == nil {
+		t.Errorf("expected failure for too few cosignatures")
+	}
+	// Cosignatures count once per witness.
+	repeated := first.cosign(t, first.cosign(t, log.checkpoint(t, 11, root)))
+	if _, err := VerifyWitnessedCheckpoint(repeated, &log.key.PublicKey, witnessPolicy(t, 2, first, second)); err == nil {
+		t.Errorf("expected failure for repeated cosignatures of a single witness")
+	}
+	// Witnesses cannot stand in for the log.
+	unsigned := second.cosign(t, first.cosign(t, newFakeLog(t).checkpoint(t, 11, root)))
+	if _, err := VerifyWitnessedCheckpoint(unsigned, &log.key.PublicKey, witnessPolicy(t, 1, first)); err == nil {
+		t.Errorf("expected failure for a checkpoint signed by another log")
+	}
+	// A cosignature of another tree does not carry over.
+	other := log.checkpoint(t, 12, root)
+	text, _, _ := strings.Cut(other, "\n\n")
+	_, signatures, _ := strings.Cut(first.cosign(t, log.checkpoint(t, 11, root)), "\n\n")
+	if _, err := VerifyWitnessedCheckpoint(text+"\n\n"+signatures, &log.key.PublicKey, witnessPolicy(t, 1, first)); err == nil {
+		t.Errorf("expected failure for a cosignature of another checkpoint")
+	}
+}
+
+func TestParseWitnessKey_Invalid(t *testing.T) {
+	witness := newFakeWitness(t, "witness.example.com", cosignatureAlgorithm)
+	name, rest, _ := strings.Cut(witness.vkey(), "+")
+	hash, key, _ := strings.Cut(rest, "+")
+	for _, vkey := range []string{
+		"",
+		name + "+" + hash,
+		"other.example.com+" + hash + "+" + key,
+		name + "+00000000+" + key,
+		name + "+" + hash + "+" + base64.StdEncoding.EncodeToString([]byte("short")),
+		newFakeWitness(t, name, 0x02).vkey(),
+	} {
+		if _, err := ParseWitnessKey(vkey); err == nil {
+			t.Errorf("expected an error for %q", vkey)
+		}
+	}
+}
+
+func TestLoadWitnessPolicy(t *testing.T) {
+	first := newFakeWitness(t, "witness.example.com", cosignatureAlgorithm)
+	second := newFakeWitness(t, "legacy.example.com", ed25519Algorithm)
+	path := filepath.Join(t.TempDir(), "witnesses.txt")
+	content := "# Trusted witnesses.\n" + first.vkey() + "\n\n" + second.vkey() + "\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("could not write the witness keys: %v", err)
+	}
+
+	if _, err := LoadWitnessPolicy(path, 2); err != nil {
+		t.Errorf("could not load the witness policy: %v", err)
+	}
+	if _, err := LoadWitnessPolicy(path, 3); err == nil {
+		t.Errorf("expected an error for a threshold above the number of witnesses")
+	}
+	if _, err := LoadWitnessPolicy(path, 0); err == nil {
+		t.Errorf("expected an error for a zero threshold")
+	}
+	if err := os.WriteFile(path, []byte(first.vkey()+"\n"+first.vkey()+"\n"), 0o600); err != nil {
+		t.Fatalf("could not write the witness keys: %v", err)
+	}
+	if _, err := LoadWitnessPolicy(path, 2); err == nil {
+		t.Errorf("expected an error for duplicate witnesses")
+	}
+}
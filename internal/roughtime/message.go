@@ -0,0 +1,156 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package roughtime
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+)
+
+// Tags of the Roughtime messages used by this package.
+//
+//nolint:gochecknoglobals
+var (
+	tagSIG  = tag("SIG\x00")
+	tagNONC = tag("NONC")
+	tagPAD  = tag("PAD\xff")
+	tagPATH = tag("PATH")
+	tagSREP = tag("SREP")
+	tagCERT = tag("CERT")
+	tagINDX = tag("INDX")
+	tagRADI = tag("RADI")
+	tagMIDP = tag("MIDP")
+	tagROOT = tag("ROOT")
+	tagDELE = tag("DELE")
+	tagMINT = tag("MINT")
+	tagMAXT = tag("MAXT")
+	tagPUBK = tag("PUBK")
+)
+
+// tag returns the numeric value of the given four-character tag.
+func tag(name string) uint32 {
+	return binary.LittleEndian.Uint32([]byte(name))
+}
+
+// tagName returns the four-character name of the given tag.
+func tagName(t uint32) string {
+	return string(appendUint32(nil, t))
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	var buf [4]byte
+	binary.LittleEndian.PutUint32(buf[:], v)
+	return append(b, buf[:]...)
+}
+
+// message is a Roughtime message, mapping tags to values.
+type message map[uint32][]byte
+
+// encode encodes the message in the Roughtime wire format: the number of
+// tags, the offsets of all values but the first, the tags in increasing
+// order, and the values. All integers are 32-bit little-endian. The lengths
+// of all values must be multiples of four.
+func (m message) encode() ([]byte, error) {
+	tags := make([]uint32, 0, len(m))
+	for t, value := range m {
+		if len(value)%4 != 0 {
+			return nil, fmt.Errorf("the length of the value of tag %q is not a multiple of four", tagName(t))
+		}
+		tags = append(tags, t)
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i] < tags[j] })
+
+	out := appendUint32(nil, uint32(len(tags)))
+	offset := 0
+	for i, t := range tags {
+		if i > 0 {
+			out = appendUint32(out, uint32(offset))
+		}
+		offset += len(m[t])
+	}
+	for _, t := range tags {
+		out = appendUint32(out, t)
+	}
+	for _, t := range tags {
+		out = append(out, m[t]...)
+	}
+	return out, nil
+}
+
+// decodeMessage decodes a message in the Roughtime wire format.
+func decodeMessage(data []byte) (message, error) {
+	if len(data) < 4 || len(data)%4 != 0 {
+		return nil, fmt.Errorf("invalid message length %d", len(data))
+	}
+	numTags := int(binary.LittleEndian.Uint32(data))
+	if numTags == 0 {
+		return message{}, nil
+	}
+	headerLen := 4 * (2 * numTags)
+	if numTags > len(data) || headerLen > len(data) {
+		return nil, fmt.Errorf("the message is too short for %d tags", numTags)
+	}
+	values := data[headerLen:]
+
+	m := make(message, numTags)
+	var previousTag uint32
+	for i := 0; i < numTags; i++ {
+		start := 0
+		if i > 0 {
+			start = int(binary.LittleEndian.Uint32(data[4*i:]))
+		}
+		end := len(values)
+		if i < numTags-1 {
+			end = int(binary.LittleEndian.Uint32(data[4*(i+1):]))
+		}
+		if start > end || end > len(values) || start%4 != 0 {
+			return nil, fmt.Errorf("invalid offsets of value #%d", i)
+		}
+		t := binary.LittleEndian.Uint32(data[4*(numTags+i):])
+		if i > 0 && t <= previousTag {
+			return nil, fmt.Errorf("the tags are not in strictly increasing order")
+		}
+		previousTag = t
+		m[t] = values[start:end]
+	}
+	return m, nil
+}
+
+// get returns the value of the given tag, and checks its length, unless
+// length is negative.
+func (m message) get(t uint32, length int) ([]byte, error) {
+	value, ok := m[t]
+	if !ok {
+		return nil, fmt.Errorf("missing tag %q", tagName(t))
+	}
+	if length >= 0 && len(value) != length {
+		return nil, fmt.Errorf("tag %q has length %d, want %d", tagName(t), len(value), length)
+	}
+	return value, nil
+}
+
+// getMessage returns the value of the given tag, decoded as a message.
+func (m message) getMessage(t uint32) (message, []byte, error) {
+	value, err := m.get(t, -1)
+	if err != nil {
+		return nil, nil, err
+	}
+	nested, err := decodeMessage(value)
+	if err != nil {
+		return nil, nil, fmt.Errorf("decoding tag %q: %v", tagName(t), err)
+	}
+	return nested, value, nil
+}
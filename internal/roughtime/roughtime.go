@@ -0,0 +1,294 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package roughtime provides authenticated time from Roughtime servers, for
+// checking validity windows on machines whose local clock is not trusted,
+// e.g., air-gapped verifiers. A Token is a signed Roughtime response, which
+// can be obtained with Query on a machine with network access, and verified
+// offline with the long-term public key of the server.
+//
+// This package implements the original (Google) Roughtime protocol, see
+// https://roughtime.googlesource.com/roughtime/+/HEAD/PROTOCOL.md.
+package roughtime
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"time"
+)
+
+const (
+	// NonceSize is the size of the nonce in requests.
+	NonceSize = 64
+	// minRequestSize is the size to which requests are padded, so that
+	// servers cannot be used for amplification attacks.
+	minRequestSize = 1024
+	// maxResponseSize limits the size of responses.
+	maxResponseSize = 4096
+
+	responseContext   = "RoughTime v1 response signature\x00"
+	delegationContext = "RoughTime v1 delegation signature--\x00"
+)
+
+// Token is a Roughtime response, together with the request nonce, that can be
+// stored and verified later.
+type Token struct {
+	// Server is the address of the server that issued the response.
+	Server string `json:"server"`
+	// Nonce is the nonce of the request.
+	Nonce []byte `json:"nonce"`
+	// Response is the response of the server, in the Roughtime wire format.
+	Response []byte `json:"response"`
+}
+
+// Time is a time, with its uncertainty, authenticated by a Roughtime server.
+type Time struct {
+	// Midpoint is the time reported by the server.
+	Midpoint time.Time
+	// Radius is the uncertainty of Midpoint; the true time at which the
+	// server signed the response was within Radius of Midpoint.
+	Radius time.Duration
+}
+
+// Latest returns the latest time at which the server signed the response.
+func (t *Time) Latest() time.Time {
+	return t.Midpoint.Add(t.Radius)
+}
+
+// ParsePublicKey parses the base64-encoded Ed25519 public key of a Roughtime
+// server, in the format in which servers publish their keys.
+func ParsePublicKey(encoded string) (ed25519.PublicKey, error) {
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode the public key: %v", err)
+	}
+	if len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("the public key has %d bytes, want %d", len(key), ed25519.PublicKeySize)
+	}
+	return ed25519.PublicKey(key), nil
+}
+
+// LoadToken loads a JSON-encoded Token from the given path.
+func LoadToken(path string) (*Token, error) {
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read the token: %v", err)
+	}
+	var token Token
+	if err := json.Unmarshal(bytes, &token); err != nil {
+		return nil, fmt.Errorf("could not parse the token: %v", err)
+	}
+	return &token, nil
+}
+
+// Query requests the time from the Roughtime server at the given UDP address,
+// e.g., `roughtime.sandbox.google.com:2002`, and verifies the response with
+// the given public key of the server.
+func Query(ctx context.Context, address string, publicKey ed25519.PublicKey) (*Token, *Time, error) {
+	nonce := make([]byte, NonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, nil, fmt.Errorf("could not generate a nonce: %v", err)
+	}
+	request, err := newRequest(nonce)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "udp", address)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not connect to %s: %v", address, err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return nil, nil, fmt.Errorf("could not set the deadline: %v", err)
+		}
+	}
+	if _, err := conn.Write(request); err != nil {
+		return nil, nil, fmt.Errorf("could not send the request to %s: %v", address, err)
+	}
+	response := make([]byte, maxResponseSize)
+	n, err := conn.Read(response)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not receive the response from %s: %v", address, err)
+	}
+
+	token := &Token{Server: address, Nonce: nonce, Response: response[:n]}
+	t, err := token.Verify(publicKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return token, t, nil
+}
+
+// newRequest creates a request with the given nonce.
+func newRequest(nonce []byte) ([]byte, error) {
+	// The header of a message with two tags has 16 bytes.
+	padding := minRequestSize - 16 - len(nonce)
+	return message{tagNONC: nonce, tagPAD: bytes.Repeat([]byte{0xff}, padding)}.encode()
+}
+
+// Verify verifies that the response in the token has been signed by a key
+// delegated by the given long-term public key of the server, and that it
+// answers a request with the nonce in the token. Returns the time in the
+// response.
+func (t *Token) Verify(publicKey ed25519.PublicKey) (*Time, error) {
+	if len(t.Nonce) != NonceSize {
+		return nil, fmt.Errorf("the nonce has %d bytes, want %d", len(t.Nonce), NonceSize)
+	}
+	response, err := decodeMessage(t.Response)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode the response: %v", err)
+	}
+
+	delegatedKey, minTime, maxTime, err := verifyCertificate(response, publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	signedResponse, signedResponseBytes, err := response.getMessage(tagSREP)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := response.get(tagSIG, ed25519.SignatureSize)
+	if err != nil {
+		return nil, err
+	}
+	if !ed25519.Verify(delegatedKey, append([]byte(responseContext), signedResponseBytes...), signature) {
+		return nil, fmt.Errorf("invalid signature on the response")
+	}
+
+	if err := verifyNonce(response, signedResponse, t.Nonce); err != nil {
+		return nil, err
+	}
+
+	midpoint, err := signedResponse.get(tagMIDP, 8)
+	if err != nil {
+		return nil, err
+	}
+	radius, err := signedResponse.get(tagRADI, 4)
+	if err != nil {
+		return nil, err
+	}
+	midpointMicros := binary.LittleEndian.Uint64(midpoint)
+	if midpointMicros < minTime || midpointMicros > maxTime {
+		return nil, fmt.Errorf("the time in the response is outside of the validity of the delegated key")
+	}
+	return &Time{
+		Midpoint: microsToTime(midpointMicros),
+		Radius:   time.Duration(binary.LittleEndian.Uint32(radius)) * time.Microsecond,
+	}, nil
+}
+
+// verifyCertificate verifies that the delegated key in the certificate in the
+// given response is signed by the given long-term key. Returns the delegated
+// key and the bounds of its validity, in microseconds since the epoch.
+func verifyCertificate(response message, publicKey ed25519.PublicKey) (ed25519.PublicKey, uint64, uint64, error) {
+	cert, _, err := response.getMessage(tagCERT)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	delegation, delegationBytes, err := cert.getMessage(tagDELE)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	signature, err := cert.get(tagSIG, ed25519.SignatureSize)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	if !ed25519.Verify(publicKey, append([]byte(delegationContext), delegationBytes...), signature) {
+		return nil, 0, 0, fmt.Errorf("invalid signature on the delegated key")
+	}
+
+	delegatedKey, err := delegation.get(tagPUBK, ed25519.PublicKeySize)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	minTime, err := delegation.get(tagMINT, 8)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	maxTime, err := delegation.get(tagMAXT, 8)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	return ed25519.PublicKey(delegatedKey), binary.LittleEndian.Uint64(minTime), binary.LittleEndian.Uint64(maxTime), nil
+}
+
+// verifyNonce verifies that the root of the Merkle tree in the signed
+// response is computed from the given nonce, and the path in the response.
+func verifyNonce(response, signedResponse message, nonce []byte) error {
+	root, err := signedResponse.get(tagROOT, sha512.Size)
+	if err != nil {
+		return err
+	}
+	path, err := response.get(tagPATH, -1)
+	if err != nil {
+		return err
+	}
+	if len(path)%sha512.Size != 0 {
+		return fmt.Errorf("the length of the path is not a multiple of %d", sha512.Size)
+	}
+	index, err := response.get(tagINDX, 4)
+	if err != nil {
+		return err
+	}
+
+	hash := hashLeaf(nonce)
+	i := binary.LittleEndian.Uint32(index)
+	for ; len(path) > 0; path = path[sha512.Size:] {
+		if i&1 == 0 {
+			hash = hashNode(hash, path[:sha512.Size])
+		} else {
+			hash = hashNode(path[:sha512.Size], hash)
+		}
+		i >>= 1
+	}
+	if i != 0 {
+		return fmt.Errorf("the index does not match the length of the path")
+	}
+	if !bytes.Equal(hash, root) {
+		return fmt.Errorf("the response does not answer a request with the nonce of the token")
+	}
+	return nil
+}
+
+func hashLeaf(leaf []byte) []byte {
+	h := sha512.New()
+	h.Write([]byte{0})
+	h.Write(leaf)
+	return h.Sum(nil)
+}
+
+func hashNode(left, right []byte) []byte {
+	h := sha512.New()
+	h.Write([]byte{1})
+	h.Write(left)
+	h.Write(right)
+	return h.Sum(nil)
+}
+
+func microsToTime(micros uint64) time.Time {
+	return time.Unix(int64(micros/1e6), int64(micros%1e6)*1e3).UTC()
+}
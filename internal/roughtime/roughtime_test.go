@@ -0,0 +1,218 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package roughtime
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/project-oak/transparent-release/internal/testutil"
+)
+
+// fakeServer signs Roughtime responses with a delegated key.
+type fakeServer struct {
+	publicKey ed25519.PublicKey
+	cert      []byte
+	key       ed25519.PrivateKey
+	midpoint  time.Time
+}
+
+func newFakeServer(t *testing.T, midpoint time.Time) *fakeServer {
+	publicKey, rootKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("could not generate the long-term key: %v", err)
+	}
+	delegatedPublicKey, delegatedKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("could not generate the delegated key: %v", err)
+	}
+	delegation := mustEncode(t, message{
+		tagPUBK: delegatedPublicKey,
+		tagMINT: uint64Bytes(uint64(midpoint.Add(-time.Hour).UnixMicro())),
+		tagMAXT: uint64Bytes(uint64(midpoint.Add(time.Hour).UnixMicro())),
+	})
+	cert := mustEncode(t, message{
+		tagDELE: delegation,
+		tagSIG:  ed25519.Sign(rootKey, append([]byte(delegationContext), delegation...)),
+	})
+	return &fakeServer{publicKey: publicKey, cert: cert, key: delegatedKey, midpoint: midpoint}
+}
+
+// respond answers a batch of requests with the given nonces, and returns the
+// response to the request with the given index. The batch must have two
+// requests, so that the path has a single hash.
+func (s *fakeServer) respond(t *testing.T, nonces [][]byte, index int) []byte {
+	left, right := hashLeaf(nonces[0]), hashLeaf(nonces[1])
+	siblings := [][]byte{right, left}
+	signedResponse := mustEncode(t, message{
+		tagRADI: uint32Bytes(1000000),
+		tagMIDP: uint64Bytes(uint64(s.midpoint.UnixMicro())),
+		tagROOT: hashNode(left, right),
+	})
+	return mustEncode(t, message{
+		tagSIG:  ed25519.Sign(s.key, append([]byte(responseContext), signedResponse...)),
+		tagPATH: siblings[index],
+		tagSREP: signedResponse,
+		tagCERT: s.cert,
+		tagINDX: uint32Bytes(uint32(index)),
+	})
+}
+
+func mustEncode(t *testing.T, m message) []byte {
+	encoded, err := m.encode()
+	if err != nil {
+		t.Fatalf("could not encode message: %v", err)
+	}
+	return encoded
+}
+
+func uint32Bytes(v uint32) []byte {
+	return appendUint32(nil, v)
+}
+
+func uint64Bytes(v uint64) []byte {
+	b := make([]byte, 8)
+	binary.LittleEndian.PutUint64(b, v)
+	return b
+}
+
+func testNonces() [][]byte {
+	return [][]byte{bytes.Repeat([]byte{1}, NonceSize), bytes.Repeat([]byte{2}, NonceSize)}
+}
+
+func TestMessage_EncodeDecode(t *testing.T) {
+	m := message{tagNONC: bytes.Repeat([]byte{7}, NonceSize), tagPAD: {0xff, 0xff, 0xff, 0xff}, tagINDX: {}}
+	decoded, err := decodeMessage(mustEncode(t, m))
+	if err != nil {
+		t.Fatalf("could not decode message: %v", err)
+	}
+	testutil.AssertEq(t, "number of tags", len(decoded), 3)
+	for tag, value := range m {
+		if !bytes.Equal(decoded[tag], value) {
+			t.Errorf("tag %q: got %x, want %x", tagName(tag), decoded[tag], value)
+		}
+	}
+}
+
+func TestDecodeMessage_Invalid(t *testing.T) {
+	for name, data := range map[string][]byte{
+		"empty":          {},
+		"unaligned":      {1, 0, 0, 0, 0},
+		"too many tags":  {9, 0, 0, 0},
+		"offset too big": append(append(uint32Bytes(2), uint32Bytes(8)...), append(uint32Bytes(1), uint32Bytes(2)...)...),
+	} {
+		if _, err := decodeMessage(data); err == nil {
+			t.Errorf("%s: expected failure", name)
+		}
+	}
+}
+
+func TestToken_Verify(t *testing.T) {
+	midpoint := time.Date(2023, 7, 20, 12, 0, 0, 0, time.UTC)
+	server := newFakeServer(t, midpoint)
+	nonces := testNonces()
+
+	for index := range nonces {
+		token := &Token{Nonce: nonces[index], Response: server.respond(t, nonces, index)}
+		got, err := token.Verify(server.publicKey)
+		if err != nil {
+			t.Fatalf("could not verify the token #%d: %v", index, err)
+		}
+		testutil.AssertEq(t, "midpoint", got.Midpoint, midpoint)
+		testutil.AssertEq(t, "radius", got.Radius, time.Second)
+		testutil.AssertEq(t, "latest", got.Latest(), midpoint.Add(time.Second))
+	}
+}
+
+func TestToken_VerifyWrongKeyFails(t *testing.T) {
+	server := newFakeServer(t, time.Now())
+	other := newFakeServer(t, time.Now())
+	nonces := testNonces()
+	token := &Token{Nonce: nonces[0], Response: server.respond(t, nonces, 0)}
+
+	if _, err := token.Verify(other.publicKey); err == nil {
+		t.Fatalf("expected failure with the key of another server")
+	}
+}
+
+func TestToken_VerifyWrongNonceFails(t *testing.T) {
+	server := newFakeServer(t, time.Now())
+	nonces := testNonces()
+	// The response to the first request does not answer the second one.
+	token := &Token{Nonce: nonces[1], Response: server.respond(t, nonces, 0)}
+
+	if _, err := token.Verify(server.publicKey); err == nil {
+		t.Fatalf("expected failure for the wrong nonce")
+	}
+}
+
+func TestParsePublicKey(t *testing.T) {
+	server := newFakeServer(t, time.Now())
+	key, err := ParsePublicKey(base64.StdEncoding.EncodeToString(server.publicKey))
+	if err != nil {
+		t.Fatalf("could not parse the public key: %v", err)
+	}
+	if !key.Equal(server.publicKey) {
+		t.Errorf("got %x, want %x", key, server.publicKey)
+	}
+
+	if _, err := ParsePublicKey(base64.StdEncoding.EncodeToString([]byte("short"))); err == nil {
+		t.Errorf("expected failure for a short key")
+	}
+}
+
+func TestQuery(t *testing.T) {
+	midpoint := time.Date(2023, 7, 20, 12, 0, 0, 0, time.UTC)
+	server := newFakeServer(t, midpoint)
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("could not listen: %v", err)
+	}
+	defer conn.Close()
+
+	go func() {
+		buf := make([]byte, 2*minRequestSize)
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		request, err := decodeMessage(buf[:n])
+		if err != nil || n < minRequestSize {
+			return
+		}
+		nonces := [][]byte{request[tagNONC], bytes.Repeat([]byte{3}, NonceSize)}
+		_, _ = conn.WriteTo(server.respond(t, nonces, 0), addr)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	token, got, err := Query(ctx, conn.LocalAddr().String(), server.publicKey)
+	if err != nil {
+		t.Fatalf("could not query the server: %v", err)
+	}
+	testutil.AssertEq(t, "midpoint", got.Midpoint, midpoint)
+	testutil.AssertEq(t, "server", token.Server, conn.LocalAddr().String())
+
+	if _, err := token.Verify(server.publicKey); err != nil {
+		t.Fatalf("could not verify the returned token: %v", err)
+	}
+}
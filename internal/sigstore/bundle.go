@@ -0,0 +1,170 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sigstore
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+
+	"github.com/project-oak/transparent-release/internal/rekor"
+	"github.com/secure-systems-lab/go-securesystemslib/dsse"
+)
+
+// BundleMediaType is the media type of the Sigstore bundles generated by this package.
+const BundleMediaType = "application/vnd.dev.sigstore.bundle+json;version=0.1"
+
+// Bundle is a partial representation of a Sigstore Bundle, containing a DSSE
+// envelope and the certificate chain for verifying its signature.
+// See https://github.com/sigstore/protobuf-specs/blob/main/protos/sigstore_bundle.proto
+type Bundle struct {
+	MediaType            string               `json:"mediaType"`
+	VerificationMaterial VerificationMaterial `json:"verificationMaterial"`
+	DSSEEnvelope         *dsse.Envelope       `json:"dsseEnvelope"`
+}
+
+// VerificationMaterial contains the material needed for verifying the
+// signature in a Sigstore bundle.
+type VerificationMaterial struct {
+	X509CertificateChain *X509CertificateChain  `json:"x509CertificateChain,omitempty"`
+	TlogEntries          []TransparencyLogEntry `json:"tlogEntries,omitempty"`
+}
+
+// X509CertificateChain is a chain of X.509 certificates, starting with the leaf certificate.
+type X509CertificateChain struct {
+	Certificates []X509Certificate `json:"certificates"`
+}
+
+// X509Certificate is a DER-encoded X.509 certificate.
+type X509Certificate struct {
+	RawBytes []byte `json:"rawBytes"`
+}
+
+// NewBundle creates a Sigstore bundle from the given envelope and certificate chain.
+func NewBundle(envelope *dsse.Envelope, certChain []*x509.Certificate) *Bundle {
+	certs := make([]X509Certificate, 0, len(certChain))
+	for _, cert := range certChain {
+		certs = append(certs, X509Certificate{RawBytes: cert.Raw})
+	}
+	return &Bundle{
+		MediaType: BundleMediaType,
+		VerificationMaterial: VerificationMaterial{
+			X509CertificateChain: &X509CertificateChain{Certificates: certs},
+		},
+		DSSEEnvelope: envelope,
+	}
+}
+
+// TransparencyLogEntry is the representation of a Rekor log entry in a
+// Sigstore bundle. Integers are encoded as strings, and byte arrays as base64.
+type TransparencyLogEntry struct {
+	LogIndex          string            `json:"logIndex"`
+	LogID             LogID             `json:"logId"`
+	KindVersion       KindVersion       `json:"kindVersion"`
+	IntegratedTime    string            `json:"integratedTime"`
+	InclusionPromise  *InclusionPromise `json:"inclusionPromise,omitempty"`
+	InclusionProof    *BundleProof      `json:"inclusionProof,omitempty"`
+	CanonicalizedBody string            `json:"canonicalizedBody"`
+}
+
+// LogID identifies a transparency log by the digest of its public key.
+type LogID struct {
+	KeyID []byte `json:"keyId"`
+}
+
+// KindVersion is the kind and version of a log entry.
+type KindVersion struct {
+	Kind    string `json:"kind"`
+	Version string `json:"version"`
+}
+
+// InclusionPromise is the promise of the log to include an entry.
+type InclusionPromise struct {
+	SignedEntryTimestamp []byte `json:"signedEntryTimestamp"`
+}
+
+// BundleProof is the representation of an InclusionProof in a Sigstore bundle.
+type BundleProof struct {
+	LogIndex   string     `json:"logIndex"`
+	RootHash   []byte     `json:"rootHash"`
+	TreeSize   string     `json:"treeSize"`
+	Hashes     [][]byte   `json:"hashes"`
+	Checkpoint Checkpoint `json:"checkpoint"`
+}
+
+// Checkpoint is a signed note committing to the state of the log.
+type Checkpoint struct {
+	Envelope string `json:"envelope"`
+}
+
+// AddLogEntry adds the given DSSE log entry, as returned by rekor.Client, to
+// the bundle.
+func (b *Bundle) AddLogEntry(entry *rekor.LogEntry) error {
+	logID, err := hex.DecodeString(entry.LogID)
+	if err != nil {
+		return fmt.Errorf("could not decode the log ID: %v", err)
+	}
+	// Validate the body; it is stored base64-encoded in both representations.
+	if _, err := base64.StdEncoding.DecodeString(entry.Body); err != nil {
+		return fmt.Errorf("could not decode the entry body: %v", err)
+	}
+
+	tlogEntry := TransparencyLogEntry{
+		LogIndex:          strconv.FormatInt(entry.LogIndex, 10),
+		LogID:             LogID{KeyID: logID},
+		KindVersion:       KindVersion{Kind: rekor.DSSEKind, Version: rekor.DSSEKindVersion},
+		IntegratedTime:    strconv.FormatInt(entry.IntegratedTime, 10),
+		CanonicalizedBody: entry.Body,
+	}
+	if entry.Verification != nil {
+		if entry.Verification.SignedEntryTimestamp != nil {
+			tlogEntry.InclusionPromise = &InclusionPromise{SignedEntryTimestamp: entry.Verification.SignedEntryTimestamp}
+		}
+		if proof := entry.Verification.InclusionProof; proof != nil {
+			bundleProof, err := newBundleProof(proof)
+			if err != nil {
+				return err
+			}
+			tlogEntry.InclusionProof = bundleProof
+		}
+	}
+
+	b.VerificationMaterial.TlogEntries = append(b.VerificationMaterial.TlogEntries, tlogEntry)
+	return nil
+}
+
+func newBundleProof(proof *rekor.InclusionProof) (*BundleProof, error) {
+	rootHash, err := hex.DecodeString(proof.RootHash)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode the root hash: %v", err)
+	}
+	hashes := make([][]byte, 0, len(proof.Hashes))
+	for _, h := range proof.Hashes {
+		hash, err := hex.DecodeString(h)
+		if err != nil {
+			return nil, fmt.Errorf("could not decode the inclusion proof hash %q: %v", h, err)
+		}
+		hashes = append(hashes, hash)
+	}
+	return &BundleProof{
+		LogIndex:   strconv.FormatInt(proof.LogIndex, 10),
+		RootHash:   rootHash,
+		TreeSize:   strconv.FormatInt(proof.TreeSize, 10),
+		Hashes:     hashes,
+		Checkpoint: Checkpoint{Envelope: proof.Checkpoint},
+	}, nil
+}
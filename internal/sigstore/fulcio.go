@@ -0,0 +1,165 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sigstore
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// DefaultFulcioURL is the URL of the public-good Fulcio instance.
+const DefaultFulcioURL = "https://fulcio.sigstore.dev"
+
+// FulcioClient requests short-lived signing certificates from a Fulcio
+// instance, using the v2 API. See
+// https://github.com/sigstore/fulcio/blob/main/fulcio.proto.
+type FulcioClient struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewFulcioClient creates a new FulcioClient for the Fulcio instance at the given URL.
+func NewFulcioClient(baseURL string) *FulcioClient {
+	return &FulcioClient{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		client:  &http.Client{},
+	}
+}
+
+type signingCertRequest struct {
+	Credentials      credentials      `json:"credentials"`
+	PublicKeyRequest publicKeyRequest `json:"publicKeyRequest"`
+}
+
+type credentials struct {
+	OIDCIdentityToken string `json:"oidcIdentityToken"`
+}
+
+type publicKeyRequest struct {
+	PublicKey         publicKey `json:"publicKey"`
+	ProofOfPossession []byte    `json:"proofOfPossession"`
+}
+
+type publicKey struct {
+	Algorithm string `json:"algorithm"`
+	Content   string `json:"content"`
+}
+
+type signingCertResponse struct {
+	SignedCertificateEmbeddedSct *signedCertificate `json:"signedCertificateEmbeddedSct,omitempty"`
+	SignedCertificateDetachedSct *signedCertificate `json:"signedCertificateDetachedSct,omitempty"`
+}
+
+type signedCertificate struct {
+	Chain struct {
+		Certificates []string `json:"certificates"`
+	} `json:"chain"`
+}
+
+// RequestCertificate requests a signing certificate for the public part of
+// the given ECDSA key, binding it to the identity in the given OIDC token.
+// Returns the certificate chain, starting with the leaf certificate.
+func (c *FulcioClient) RequestCertificate(ctx context.Context, idToken string, key *ecdsa.PrivateKey) ([]*x509.Certificate, error) {
+	subject, err := tokenSubject(idToken)
+	if err != nil {
+		return nil, fmt.Errorf("could not get the subject of the identity token: %v", err)
+	}
+	// The proof of possession is a signature over the token subject.
+	digest := sha256.Sum256([]byte(subject))
+	proof, err := key.Sign(rand.Reader, digest[:], crypto.SHA256)
+	if err != nil {
+		return nil, fmt.Errorf("could not create the proof of possession: %v", err)
+	}
+
+	pubBytes, err := x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal the public key: %v", err)
+	}
+	pubPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubBytes})
+
+	reqBody, err := json.Marshal(signingCertRequest{
+		Credentials: credentials{OIDCIdentityToken: idToken},
+		PublicKeyRequest: publicKeyRequest{
+			PublicKey:         publicKey{Algorithm: "ECDSA", Content: string(pubPEM)},
+			ProofOfPossession: proof,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal the signing certificate request: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/api/v2/signingCert", bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("could not create HTTP request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("could not receive response from Fulcio: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("could not read the Fulcio response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return nil, fmt.Errorf("signing certificate request failed with status %d: %s", resp.StatusCode, body)
+	}
+
+	var certResponse signingCertResponse
+	if err := json.Unmarshal(body, &certResponse); err != nil {
+		return nil, fmt.Errorf("could not unmarshal the Fulcio response: %v", err)
+	}
+	signedCert := certResponse.SignedCertificateEmbeddedSct
+	if signedCert == nil {
+		signedCert = certResponse.SignedCertificateDetachedSct
+	}
+	if signedCert == nil || len(signedCert.Chain.Certificates) == 0 {
+		return nil, fmt.Errorf("the Fulcio response does not contain a certificate chain")
+	}
+
+	return parseCertificateChain(signedCert.Chain.Certificates)
+}
+
+// parseCertificateChain parses the given PEM-encoded certificates.
+func parseCertificateChain(pemCerts []string) ([]*x509.Certificate, error) {
+	chain := make([]*x509.Certificate, 0, len(pemCerts))
+	for _, pemCert := range pemCerts {
+		block, _ := pem.Decode([]byte(pemCert))
+		if block == nil {
+			return nil, fmt.Errorf("could not decode PEM certificate")
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse certificate: %v", err)
+		}
+		chain = append(chain, cert)
+	}
+	return chain, nil
+}
@@ -0,0 +1,125 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package sigstore provides utilities for signing statements using Sigstore
+// keyless signing, in which short-lived certificates are issued by Fulcio
+// against an OIDC identity token.
+package sigstore
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// SigstoreAudience is the audience that OIDC tokens presented to Fulcio must have.
+const SigstoreAudience = "sigstore"
+
+// GetAmbientIDToken returns an OIDC identity token from the environment. If
+// the `SIGSTORE_ID_TOKEN` environment variable is set, its value is returned.
+// Otherwise, if running in GitHub Actions with the `id-token: write`
+// permission, a token with the given audience is requested from the GitHub
+// Actions OIDC provider. Returns an error if no token is available.
+func GetAmbientIDToken(ctx context.Context, audience string) (string, error) {
+	if token := os.Getenv("SIGSTORE_ID_TOKEN"); token != "" {
+		return token, nil
+	}
+
+	requestURL := os.Getenv("ACTIONS_ID_TOKEN_REQUEST_URL")
+	requestToken := os.Getenv("ACTIONS_ID_TOKEN_REQUEST_TOKEN")
+	if requestURL == "" || requestToken == "" {
+		return "", fmt.Errorf("no ambient OIDC credentials found: set SIGSTORE_ID_TOKEN, or run in GitHub Actions with `id-token: write`")
+	}
+
+	return getGitHubActionsIDToken(ctx, requestURL, requestToken, audience)
+}
+
+// getGitHubActionsIDToken requests an OIDC token from the GitHub Actions
+// token endpoint. See
+// https://docs.github.com/en/actions/deployment/security-hardening-your-deployments/about-security-hardening-with-openid-connect.
+func getGitHubActionsIDToken(ctx context.Context, requestURL, requestToken, audience string) (string, error) {
+	uri, err := url.Parse(requestURL)
+	if err != nil {
+		return "", fmt.Errorf("could not parse the token request URL (%q): %v", requestURL, err)
+	}
+	query := uri.Query()
+	query.Set("audience", audience)
+	uri.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri.String(), nil)
+	if err != nil {
+		return "", fmt.Errorf("could not create HTTP request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+requestToken)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("could not receive response from the token endpoint: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("could not read the token response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token request failed with status %d: %s", resp.StatusCode, body)
+	}
+
+	var tokenResponse struct {
+		Value string `json:"value"`
+	}
+	if err := json.Unmarshal(body, &tokenResponse); err != nil {
+		return "", fmt.Errorf("could not unmarshal the token response: %v", err)
+	}
+	if tokenResponse.Value == "" {
+		return "", fmt.Errorf("the token response does not contain a token")
+	}
+	return tokenResponse.Value, nil
+}
+
+// tokenSubject returns the identity that Fulcio binds into the issued
+// certificate, which is the `email` claim if present, and the `sub` claim
+// otherwise. The token signature is not verified; Fulcio does that.
+func tokenSubject(token string) (string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", fmt.Errorf("the identity token is not a JWT")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", fmt.Errorf("could not decode the identity token payload: %v", err)
+	}
+
+	var claims struct {
+		Subject string `json:"sub"`
+		Email   string `json:"email"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return "", fmt.Errorf("could not unmarshal the identity token payload: %v", err)
+	}
+	if claims.Email != "" {
+		return claims.Email, nil
+	}
+	if claims.Subject != "" {
+		return claims.Subject, nil
+	}
+	return "", fmt.Errorf("the identity token has neither an email nor a sub claim")
+}
@@ -0,0 +1,82 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sigstore
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"fmt"
+)
+
+// KeylessSigner signs data with an ephemeral ECDSA P-256 key, for which a
+// short-lived certificate has been issued by Fulcio. KeylessSigner implements
+// dsse.SignerVerifier.
+type KeylessSigner struct {
+	privateKey *ecdsa.PrivateKey
+	certChain  []*x509.Certificate
+}
+
+// NewKeylessSigner generates an ephemeral key, and requests a certificate for
+// it from the given Fulcio client using the given OIDC identity token.
+func NewKeylessSigner(ctx context.Context, fulcio *FulcioClient, idToken string) (*KeylessSigner, error) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("could not generate an ephemeral key: %v", err)
+	}
+
+	certChain, err := fulcio.RequestCertificate(ctx, idToken, privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("could not get a signing certificate from Fulcio: %v", err)
+	}
+
+	return &KeylessSigner{privateKey: privateKey, certChain: certChain}, nil
+}
+
+// Sign signs the SHA256 digest of the given data.
+func (s *KeylessSigner) Sign(_ context.Context, data []byte) ([]byte, error) {
+	digest := sha256.Sum256(data)
+	return s.privateKey.Sign(rand.Reader, digest[:], crypto.SHA256)
+}
+
+// Verify verifies the given signature over the given data.
+func (s *KeylessSigner) Verify(_ context.Context, data, sig []byte) error {
+	digest := sha256.Sum256(data)
+	if !ecdsa.VerifyASN1(&s.privateKey.PublicKey, digest[:], sig) {
+		return fmt.Errorf("invalid signature")
+	}
+	return nil
+}
+
+// KeyID returns an empty key ID, since keyless signatures are identified by
+// the certificate rather than by a key ID.
+func (s *KeylessSigner) KeyID() (string, error) {
+	return "", nil
+}
+
+// Public returns the public part of the ephemeral key.
+func (s *KeylessSigner) Public() crypto.PublicKey {
+	return s.privateKey.Public()
+}
+
+// CertificateChain returns the certificate chain issued by Fulcio, starting
+// with the leaf certificate.
+func (s *KeylessSigner) CertificateChain() []*x509.Certificate {
+	return s.certChain
+}
@@ -0,0 +1,239 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sigstore
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/project-oak/transparent-release/internal/rekor"
+	"github.com/project-oak/transparent-release/internal/testutil"
+	"github.com/secure-systems-lab/go-securesystemslib/dsse"
+)
+
+const testSubject = "releaser@example.com"
+
+// fakeToken returns an unsigned JWT with the given payload.
+func fakeToken(payload string) string {
+	encode := base64.RawURLEncoding.EncodeToString
+	return encode([]byte(`{"alg":"none"}`)) + "." + encode([]byte(payload)) + "." + encode([]byte("sig"))
+}
+
+// newFakeFulcio starts a test server that issues certificates signed by a
+// freshly generated CA, after checking the proof of possession.
+func newFakeFulcio(t *testing.T) *httptest.Server {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("could not generate CA key: %v", err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "fake-fulcio"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, caKey.Public(), caKey)
+	if err != nil {
+		t.Fatalf("could not create CA certificate: %v", err)
+	}
+	caCert, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		t.Fatalf("could not parse CA certificate: %v", err)
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v2/signingCert" {
+			http.NotFound(w, r)
+			return
+		}
+		var req signingCertRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		block, _ := pem.Decode([]byte(req.PublicKeyRequest.PublicKey.Content))
+		pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		digest := sha256.Sum256([]byte(testSubject))
+		if !ecdsa.VerifyASN1(pub.(*ecdsa.PublicKey), digest[:], req.PublicKeyRequest.ProofOfPossession) {
+			http.Error(w, "invalid proof of possession", http.StatusBadRequest)
+			return
+		}
+		leafTemplate := &x509.Certificate{
+			SerialNumber:   big.NewInt(2),
+			NotBefore:      time.Now(),
+			NotAfter:       time.Now().Add(10 * time.Minute),
+			EmailAddresses: []string{testSubject},
+			KeyUsage:       x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:    []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+		}
+		leafDER, err := x509.CreateCertificate(rand.Reader, leafTemplate, caCert, pub, caKey)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		var resp signingCertResponse
+		resp.SignedCertificateEmbeddedSct = &signedCertificate{}
+		resp.SignedCertificateEmbeddedSct.Chain.Certificates = []string{
+			string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: leafDER})),
+			string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER})),
+		}
+		w.WriteHeader(http.StatusCreated)
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			t.Errorf("could not encode response: %v", err)
+		}
+	}))
+}
+
+func TestKeylessSigner_SignAndVerify(t *testing.T) {
+	server := newFakeFulcio(t)
+	defer server.Close()
+
+	ctx := context.Background()
+	token := fakeToken(fmt.Sprintf(`{"sub":"some-id","email":%q}`, testSubject))
+	signer, err := NewKeylessSigner(ctx, NewFulcioClient(server.URL), token)
+	if err != nil {
+		t.Fatalf("could not create keyless signer: %v", err)
+	}
+
+	chain := signer.CertificateChain()
+	testutil.AssertEq(t, "chain length", len(chain), 2)
+	testutil.AssertEq(t, "certificate email", chain[0].EmailAddresses[0], testSubject)
+
+	envelopeSigner, err := dsse.NewEnvelopeSigner(signer)
+	if err != nil {
+		t.Fatalf("could not create envelope signer: %v", err)
+	}
+	envelope, err := envelopeSigner.SignPayload(ctx, "text/plain", []byte("hello"))
+	if err != nil {
+		t.Fatalf("could not sign payload: %v", err)
+	}
+
+	verifier, err := dsse.NewEnvelopeVerifier(signer)
+	if err != nil {
+		t.Fatalf("could not create envelope verifier: %v", err)
+	}
+	if _, err := verifier.Verify(ctx, envelope); err != nil {
+		t.Fatalf("could not verify envelope: %v", err)
+	}
+
+	bundle := NewBundle(envelope, chain)
+	testutil.AssertEq(t, "bundle media type", bundle.MediaType, BundleMediaType)
+	testutil.AssertEq(t, "bundle certificates", len(bundle.VerificationMaterial.X509CertificateChain.Certificates), 2)
+}
+
+func TestKeylessSigner_WrongSubjectFails(t *testing.T) {
+	server := newFakeFulcio(t)
+	defer server.Close()
+
+	token := fakeToken(`{"sub":"someone-else"}`)
+	if _, err := NewKeylessSigner(context.Background(), NewFulcioClient(server.URL), token); err == nil {
+		t.Fatalf("expected failure for a token with a different subject")
+	}
+}
+
+func TestTokenSubject(t *testing.T) {
+	got, err := tokenSubject(fakeToken(`{"sub":"repo:project-oak/oak:ref:refs/heads/main"}`))
+	if err != nil {
+		t.Fatalf("could not get token subject: %v", err)
+	}
+	testutil.AssertEq(t, "subject", got, "repo:project-oak/oak:ref:refs/heads/main")
+
+	if _, err := tokenSubject("not-a-jwt"); err == nil {
+		t.Fatalf("expected failure for a malformed token")
+	}
+}
+
+func TestGetAmbientIDToken_GitHubActions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer request-token" || r.URL.Query().Get("audience") != SigstoreAudience {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"value":"the-id-token"}`)
+	}))
+	defer server.Close()
+
+	t.Setenv("SIGSTORE_ID_TOKEN", "")
+	t.Setenv("ACTIONS_ID_TOKEN_REQUEST_URL", server.URL+"/token?api-version=2.0")
+	t.Setenv("ACTIONS_ID_TOKEN_REQUEST_TOKEN", "request-token")
+
+	token, err := GetAmbientIDToken(context.Background(), SigstoreAudience)
+	if err != nil {
+		t.Fatalf("could not get ambient token: %v", err)
+	}
+	testutil.AssertEq(t, "token", token, "the-id-token")
+}
+
+func TestGetAmbientIDToken_NoCredentials(t *testing.T) {
+	t.Setenv("SIGSTORE_ID_TOKEN", "")
+	t.Setenv("ACTIONS_ID_TOKEN_REQUEST_URL", "")
+	t.Setenv("ACTIONS_ID_TOKEN_REQUEST_TOKEN", "")
+
+	if _, err := GetAmbientIDToken(context.Background(), SigstoreAudience); err == nil {
+		t.Fatalf("expected failure without ambient credentials")
+	}
+}
+
+func TestBundle_AddLogEntry(t *testing.T) {
+	entry := &rekor.LogEntry{
+		UUID:           "24296fb24b8ad77a",
+		Body:           base64.StdEncoding.EncodeToString([]byte("body")),
+		IntegratedTime: 1690000000,
+		LogID:          "c0d23d6ad406973f9559f3ba2d1ca01f84147d8ffc5b8445c224f98b9591801d",
+		LogIndex:       42,
+		Verification: &rekor.LogEntryVerification{
+			InclusionProof: &rekor.InclusionProof{
+				Checkpoint: "rekor.sigstore.dev - 2605736670972794746\n43\nfV48L0objp0Mbzor7Ux8+aCzxtXi8aS3yNng8aK8PE0=\n",
+				Hashes:     []string{"7d5e3c2f4a1b8e9d0c6f3a2b1e4d7c8f9a0b3c6d5e2f1a4b7c8d9e0f1a2b3c4d"},
+				LogIndex:   42,
+				RootHash:   "7d5e3c2f4a1b8e9d0c6f3a2b1e4d7c8f9a0b3c6d5e2f1a4b7c8d9e0f1a2b3c4d",
+				TreeSize:   43,
+			},
+			SignedEntryTimestamp: []byte("set"),
+		},
+	}
+
+	bundle := NewBundle(&dsse.Envelope{}, nil)
+	if err := bundle.AddLogEntry(entry); err != nil {
+		t.Fatalf("could not add log entry: %v", err)
+	}
+	testutil.AssertEq(t, "tlog entries", len(bundle.VerificationMaterial.TlogEntries), 1)
+	tlogEntry := bundle.VerificationMaterial.TlogEntries[0]
+	testutil.AssertEq(t, "log index", tlogEntry.LogIndex, "42")
+	testutil.AssertEq(t, "kind", tlogEntry.KindVersion.Kind, "dsse")
+	testutil.AssertEq(t, "tree size", tlogEntry.InclusionProof.TreeSize, "43")
+	testutil.AssertEq(t, "log ID length", len(tlogEntry.LogID.KeyID), 32)
+}
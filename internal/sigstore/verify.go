@@ -0,0 +1,284 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sigstore
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/project-oak/transparent-release/internal/model"
+	"github.com/project-oak/transparent-release/internal/rekor"
+	"github.com/project-oak/transparent-release/pkg/sign"
+	"go.uber.org/multierr"
+)
+
+// Fulcio certificate extensions holding the identity of the signer. The
+// deprecated V1 extensions hold raw strings, while the others hold
+// DER-encoded UTF8Strings. See
+// https://github.com/sigstore/fulcio/blob/main/docs/oid-info.md.
+//
+//nolint:gochecknoglobals
+var (
+	oidIssuerV1                 = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 1}
+	oidGitHubWorkflowRefV1      = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 6}
+	oidIssuerV2                 = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 8}
+	oidSourceRepositoryRef      = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 14}
+	oidSourceRepositoryOwnerURI = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 16}
+	oidBuildConfigURI           = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 18}
+)
+
+// TrustedRoot holds the keys for verifying Sigstore bundles.
+type TrustedRoot struct {
+	// FulcioRoots are the root certificates of the Fulcio instance that
+	// issued the signing certificates.
+	FulcioRoots *x509.CertPool
+	// RekorPublicKey is the public key of the Rekor instance that logged the
+	// signatures.
+	RekorPublicKey *ecdsa.PublicKey
+}
+
+// LoadTrustedRoot loads the PEM-encoded Fulcio root certificates, and the
+// PEM-encoded Rekor public key, at the given paths.
+func LoadTrustedRoot(fulcioRootsPath, rekorPublicKeyPath string) (*TrustedRoot, error) {
+	rootsPEM, err := os.ReadFile(fulcioRootsPath)
+	if err != nil {
+		return nil, fmt.Errorf("could not read the Fulcio roots: %v", err)
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(rootsPEM) {
+		return nil, fmt.Errorf("no PEM-encoded certificates found in %q", fulcioRootsPath)
+	}
+
+	keyPEM, err := os.ReadFile(rekorPublicKeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("could not read the Rekor public key: %v", err)
+	}
+	publicKey, err := sign.ParsePublicKeyPEM(keyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse the Rekor public key: %v", err)
+	}
+	rekorPublicKey, ok := publicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("got a %T Rekor public key, want an ECDSA key", publicKey)
+	}
+	return &TrustedRoot{FulcioRoots: roots, RekorPublicKey: rekorPublicKey}, nil
+}
+
+// ParseBundle parses the given bytes as a Sigstore bundle. Returns an error if
+// the bytes are not a Sigstore bundle with a DSSE envelope.
+func ParseBundle(bytes []byte) (*Bundle, error) {
+	var bundle Bundle
+	if err := json.Unmarshal(bytes, &bundle); err != nil {
+		return nil, fmt.Errorf("could not unmarshal the bundle: %v", err)
+	}
+	if !strings.HasPrefix(bundle.MediaType, "application/vnd.dev.sigstore.bundle") {
+		return nil, fmt.Errorf("unexpected media type %q", bundle.MediaType)
+	}
+	if bundle.DSSEEnvelope == nil {
+		return nil, fmt.Errorf("the bundle does not contain a DSSE envelope")
+	}
+	return &bundle, nil
+}
+
+// Verify verifies the bundle against the given trusted root, and returns the
+// identity of the signer. It checks that
+//   - the envelope is signed with the key of the leaf certificate,
+//   - the envelope has been logged in a Rekor entry of kind `dsse`, signed by
+//     the Rekor public key,
+//   - the certificate chain leads to one of the Fulcio roots, and was valid
+//     when the entry was integrated into the log.
+func (b *Bundle) Verify(ctx context.Context, root *TrustedRoot) (*model.CertificateIdentity, error) {
+	certs, err := b.certificates()
+	if err != nil {
+		return nil, err
+	}
+	leaf := certs[0]
+	leafVerifier, err := sign.NewPublicKeyVerifier(leaf.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("could not create a verifier for the leaf certificate: %v", err)
+	}
+
+	if len(b.VerificationMaterial.TlogEntries) == 0 {
+		return nil, fmt.Errorf("the bundle does not contain a transparency log entry")
+	}
+	var entry *rekor.LogEntry
+	var errs error
+	for i := range b.VerificationMaterial.TlogEntries {
+		candidate, err := b.VerificationMaterial.TlogEntries[i].LogEntry()
+		if err == nil {
+			err = verifyTlogEntry(ctx, b, candidate, leafVerifier, root.RekorPublicKey)
+		}
+		if err != nil {
+			errs = multierr.Append(errs, fmt.Errorf("tlog entry #%d: %v", i, err))
+			continue
+		}
+		entry = candidate
+		break
+	}
+	if entry == nil {
+		return nil, fmt.Errorf("could not verify any of the transparency log entries: %v", errs)
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	if _, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         root.FulcioRoots,
+		Intermediates: intermediates,
+		CurrentTime:   entry.IntegratedAt(),
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+	}); err != nil {
+		return nil, fmt.Errorf("could not verify the certificate chain: %v", err)
+	}
+
+	return certificateIdentity(leaf)
+}
+
+// certificates parses the certificate chain in the bundle.
+func (b *Bundle) certificates() ([]*x509.Certificate, error) {
+	chain := b.VerificationMaterial.X509CertificateChain
+	if chain == nil || len(chain.Certificates) == 0 {
+		return nil, fmt.Errorf("the bundle does not contain a certificate chain")
+	}
+	certs := make([]*x509.Certificate, 0, len(chain.Certificates))
+	for _, c := range chain.Certificates {
+		cert, err := x509.ParseCertificate(c.RawBytes)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse certificate: %v", err)
+		}
+		certs = append(certs, cert)
+	}
+	return certs, nil
+}
+
+// verifyTlogEntry verifies that the given entry records the envelope in the
+// bundle, signed by the given verifier, and that it carries a valid
+// SignedEntryTimestamp, and inclusion proof if present, from the log.
+func verifyTlogEntry(ctx context.Context, b *Bundle, entry *rekor.LogEntry, verifier *sign.PublicKeyVerifier, logPublicKey *ecdsa.PublicKey) error {
+	// The SignedEntryTimestamp covers the integrated time, which is used for
+	// checking the validity of the short-lived certificate.
+	if err := rekor.VerifySET(entry, logPublicKey); err != nil {
+		return fmt.Errorf("could not verify the SignedEntryTimestamp: %v", err)
+	}
+	if entry.Verification.InclusionProof != nil {
+		if err := rekor.VerifyInclusionProof(entry, logPublicKey); err != nil {
+			return fmt.Errorf("could not verify the inclusion proof: %v", err)
+		}
+	}
+	return rekor.VerifyEnvelopeLogEntry(ctx, b.DSSEEnvelope, entry, verifier)
+}
+
+// LogEntry converts the entry to the representation used by rekor.Client.
+// This is the inverse of Bundle.AddLogEntry.
+func (e *TransparencyLogEntry) LogEntry() (*rekor.LogEntry, error) {
+	logIndex, err := strconv.ParseInt(e.LogIndex, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse the log index: %v", err)
+	}
+	integratedTime, err := strconv.ParseInt(e.IntegratedTime, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse the integrated time: %v", err)
+	}
+	entry := &rekor.LogEntry{
+		Body:           e.CanonicalizedBody,
+		IntegratedTime: integratedTime,
+		LogID:          hex.EncodeToString(e.LogID.KeyID),
+		LogIndex:       logIndex,
+		Verification:   &rekor.LogEntryVerification{},
+	}
+	if e.InclusionPromise != nil {
+		entry.Verification.SignedEntryTimestamp = e.InclusionPromise.SignedEntryTimestamp
+	}
+	if e.InclusionProof != nil {
+		proof, err := e.InclusionProof.inclusionProof()
+		if err != nil {
+			return nil, err
+		}
+		entry.Verification.InclusionProof = proof
+	}
+	return entry, nil
+}
+
+func (p *BundleProof) inclusionProof() (*rekor.InclusionProof, error) {
+	logIndex, err := strconv.ParseInt(p.LogIndex, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse the log index of the inclusion proof: %v", err)
+	}
+	treeSize, err := strconv.ParseInt(p.TreeSize, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse the tree size of the inclusion proof: %v", err)
+	}
+	hashes := make([]string, 0, len(p.Hashes))
+	for _, h := range p.Hashes {
+		hashes = append(hashes, hex.EncodeToString(h))
+	}
+	return &rekor.InclusionProof{
+		Checkpoint: p.Checkpoint.Envelope,
+		Hashes:     hashes,
+		LogIndex:   logIndex,
+		RootHash:   hex.EncodeToString(p.RootHash),
+		TreeSize:   treeSize,
+	}, nil
+}
+
+// certificateIdentity extracts the identity from the given Fulcio certificate.
+func certificateIdentity(cert *x509.Certificate) (*model.CertificateIdentity, error) {
+	identity := &model.CertificateIdentity{}
+	switch {
+	case len(cert.URIs) > 0:
+		identity.SubjectAlternativeName = cert.URIs[0].String()
+	case len(cert.EmailAddresses) > 0:
+		identity.SubjectAlternativeName = cert.EmailAddresses[0]
+	default:
+		return nil, fmt.Errorf("the certificate has neither a URI nor an email subject alternative name")
+	}
+
+	// Values of the V2 extensions take precedence over the V1 ones.
+	v2Extensions := map[string]*string{
+		oidIssuerV2.String():                 &identity.Issuer,
+		oidSourceRepositoryRef.String():      &identity.SourceRepositoryRef,
+		oidSourceRepositoryOwnerURI.String(): &identity.SourceRepositoryOwnerURI,
+		oidBuildConfigURI.String():           &identity.BuildConfigURI,
+	}
+	v1Extensions := map[string]*string{
+		oidIssuerV1.String():            &identity.Issuer,
+		oidGitHubWorkflowRefV1.String(): &identity.SourceRepositoryRef,
+	}
+	for _, ext := range cert.Extensions {
+		if field, ok := v2Extensions[ext.Id.String()]; ok {
+			if _, err := asn1.UnmarshalWithParams(ext.Value, field, "utf8"); err != nil {
+				return nil, fmt.Errorf("could not parse the extension %v: %v", ext.Id, err)
+			}
+		}
+	}
+	for _, ext := range cert.Extensions {
+		if field, ok := v1Extensions[ext.Id.String()]; ok && *field == "" {
+			*field = string(ext.Value)
+		}
+	}
+	if identity.Issuer == "" {
+		return nil, fmt.Errorf("the certificate does not have an OIDC issuer extension")
+	}
+	return identity, nil
+}
@@ -0,0 +1,307 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sigstore
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/project-oak/transparent-release/internal/rekor"
+	"github.com/project-oak/transparent-release/internal/testutil"
+	"github.com/secure-systems-lab/go-securesystemslib/cjson"
+	"github.com/secure-systems-lab/go-securesystemslib/dsse"
+)
+
+const (
+	testWorkflow    = "https://github.com/slsa-framework/slsa-github-generator/.github/workflows/builder_docker-based_slsa3.yml@refs/tags/v1.6.0"
+	testIssuer      = "https://token.actions.githubusercontent.com"
+	testBuildConfig = "https://github.com/project-oak/oak/.github/workflows/provenance.yaml@refs/heads/main"
+	testRef         = "refs/heads/main"
+	testOwner       = "https://github.com/project-oak"
+)
+
+// testBundle is a Sigstore bundle signed with a certificate issued by a test
+// CA, and logged in a test log.
+type testBundle struct {
+	bundle *Bundle
+	root   *TrustedRoot
+}
+
+func generateKey(t *testing.T) *ecdsa.PrivateKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("could not generate key: %v", err)
+	}
+	return key
+}
+
+// newTestBundle creates a bundle for an envelope signed at the given time.
+func newTestBundle(t *testing.T, signedAt time.Time) *testBundle {
+	caKey := generateKey(t)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "fake-fulcio"},
+		NotBefore:             signedAt.Add(-time.Hour),
+		NotAfter:              signedAt.Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, caKey.Public(), caKey)
+	if err != nil {
+		t.Fatalf("could not create CA certificate: %v", err)
+	}
+	caCert, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		t.Fatalf("could not parse CA certificate: %v", err)
+	}
+
+	leafKey := generateKey(t)
+	workflow, err := url.Parse(testWorkflow)
+	if err != nil {
+		t.Fatalf("could not parse the workflow URI: %v", err)
+	}
+	extensions := []pkix.Extension{
+		utf8Extension(t, oidIssuerV2, testIssuer),
+		utf8Extension(t, oidBuildConfigURI, testBuildConfig),
+		utf8Extension(t, oidSourceRepositoryRef, testRef),
+		utf8Extension(t, oidSourceRepositoryOwnerURI, testOwner),
+	}
+	leafTemplate := &x509.Certificate{
+		SerialNumber:    big.NewInt(2),
+		NotBefore:       signedAt.Add(-time.Minute),
+		NotAfter:        signedAt.Add(10 * time.Minute),
+		URIs:            []*url.URL{workflow},
+		KeyUsage:        x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+		ExtraExtensions: extensions,
+	}
+	leafDER, err := x509.CreateCertificate(rand.Reader, leafTemplate, caCert, leafKey.Public(), caKey)
+	if err != nil {
+		t.Fatalf("could not create leaf certificate: %v", err)
+	}
+	leafCert, err := x509.ParseCertificate(leafDER)
+	if err != nil {
+		t.Fatalf("could not parse leaf certificate: %v", err)
+	}
+
+	signer := &KeylessSigner{privateKey: leafKey, certChain: []*x509.Certificate{leafCert}}
+	envelopeSigner, err := dsse.NewEnvelopeSigner(signer)
+	if err != nil {
+		t.Fatalf("could not create envelope signer: %v", err)
+	}
+	payload := []byte(`{"_type":"https://in-toto.io/Statement/v0.1"}`)
+	envelope, err := envelopeSigner.SignPayload(context.Background(), "application/vnd.in-toto+json", payload)
+	if err != nil {
+		t.Fatalf("could not sign payload: %v", err)
+	}
+
+	// As for the public-good Fulcio instance, the chain only contains the
+	// leaf certificate, and the root is distributed out of band.
+	bundle := NewBundle(envelope, []*x509.Certificate{leafCert})
+	logKey := generateKey(t)
+	if err := bundle.AddLogEntry(newTestLogEntry(t, logKey, envelope, leafDER, payload, signedAt)); err != nil {
+		t.Fatalf("could not add log entry: %v", err)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(caCert)
+	return &testBundle{
+		bundle: bundle,
+		root:   &TrustedRoot{FulcioRoots: roots, RekorPublicKey: &logKey.PublicKey},
+	}
+}
+
+// utf8Extension returns an extension with the given value as a DER-encoded
+// UTF8String.
+func utf8Extension(t *testing.T, id asn1.ObjectIdentifier, value string) pkix.Extension {
+	der, err := asn1.MarshalWithParams(value, "utf8")
+	if err != nil {
+		t.Fatalf("could not marshal %q: %v", value, err)
+	}
+	return pkix.Extension{Id: id, Value: der}
+}
+
+// newTestLogEntry creates a log entry of kind `dsse` for the given envelope,
+// signed with the key of the given DER-encoded certificate, with a
+// SignedEntryTimestamp signed by the given log key.
+func newTestLogEntry(t *testing.T, logKey *ecdsa.PrivateKey, envelope *dsse.Envelope, certDER, payload []byte, integratedAt time.Time) *rekor.LogEntry {
+	payloadHash := sha256.Sum256(payload)
+	body, err := json.Marshal(map[string]interface{}{
+		"kind":       rekor.DSSEKind,
+		"apiVersion": rekor.DSSEKindVersion,
+		"spec": map[string]interface{}{
+			"payloadHash": map[string]string{"algorithm": "sha256", "value": hex.EncodeToString(payloadHash[:])},
+			"signatures": []map[string]interface{}{{
+				"signature": envelope.Signatures[0].Sig,
+				"verifier":  pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}),
+			}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("could not marshal the entry body: %v", err)
+	}
+	logKeyDER, err := x509.MarshalPKIXPublicKey(&logKey.PublicKey)
+	if err != nil {
+		t.Fatalf("could not marshal the log key: %v", err)
+	}
+	logID := sha256.Sum256(logKeyDER)
+	entry := &rekor.LogEntry{
+		Body:           base64.StdEncoding.EncodeToString(body),
+		IntegratedTime: integratedAt.Unix(),
+		LogID:          hex.EncodeToString(logID[:]),
+		LogIndex:       7,
+	}
+
+	setPayload, err := cjson.EncodeCanonical(map[string]interface{}{
+		"body":           entry.Body,
+		"integratedTime": entry.IntegratedTime,
+		"logIndex":       entry.LogIndex,
+		"logID":          entry.LogID,
+	})
+	if err != nil {
+		t.Fatalf("could not canonicalize the entry: %v", err)
+	}
+	digest := sha256.Sum256(setPayload)
+	set, err := ecdsa.SignASN1(rand.Reader, logKey, digest[:])
+	if err != nil {
+		t.Fatalf("could not sign the entry: %v", err)
+	}
+	entry.Verification = &rekor.LogEntryVerification{SignedEntryTimestamp: set}
+	return entry
+}
+
+func TestBundle_Verify(t *testing.T) {
+	tb := newTestBundle(t, time.Now())
+
+	identity, err := tb.bundle.Verify(context.Background(), tb.root)
+	if err != nil {
+		t.Fatalf("could not verify the bundle: %v", err)
+	}
+	testutil.AssertEq(t, "subject alternative name", identity.SubjectAlternativeName, testWorkflow)
+	testutil.AssertEq(t, "issuer", identity.Issuer, testIssuer)
+	testutil.AssertEq(t, "build config URI", identity.BuildConfigURI, testBuildConfig)
+	testutil.AssertEq(t, "source repository ref", identity.SourceRepositoryRef, testRef)
+	testutil.AssertEq(t, "source repository owner URI", identity.SourceRepositoryOwnerURI, testOwner)
+}
+
+func TestCertificateIdentity_V1Extensions(t *testing.T) {
+	workflow, err := url.Parse(testWorkflow)
+	if err != nil {
+		t.Fatalf("could not parse the workflow URI: %v", err)
+	}
+	cert := &x509.Certificate{
+		URIs: []*url.URL{workflow},
+		Extensions: []pkix.Extension{
+			{Id: oidIssuerV1, Value: []byte(testIssuer)},
+			{Id: oidGitHubWorkflowRefV1, Value: []byte(testRef)},
+		},
+	}
+
+	identity, err := certificateIdentity(cert)
+	if err != nil {
+		t.Fatalf("could not get the certificate identity: %v", err)
+	}
+	testutil.AssertEq(t, "issuer", identity.Issuer, testIssuer)
+	testutil.AssertEq(t, "source repository ref", identity.SourceRepositoryRef, testRef)
+	testutil.AssertEq(t, "build config URI", identity.BuildConfigURI, "")
+}
+
+func TestBundle_VerifyRoundTrip(t *testing.T) {
+	tb := newTestBundle(t, time.Now())
+	bytes, err := json.Marshal(tb.bundle)
+	if err != nil {
+		t.Fatalf("could not marshal the bundle: %v", err)
+	}
+
+	bundle, err := ParseBundle(bytes)
+	if err != nil {
+		t.Fatalf("could not parse the bundle: %v", err)
+	}
+	if _, err := bundle.Verify(context.Background(), tb.root); err != nil {
+		t.Fatalf("could not verify the parsed bundle: %v", err)
+	}
+}
+
+func TestBundle_VerifyUntrustedRootFails(t *testing.T) {
+	tb := newTestBundle(t, time.Now())
+	other := newTestBundle(t, time.Now())
+
+	_, err := tb.bundle.Verify(context.Background(), &TrustedRoot{FulcioRoots: other.root.FulcioRoots, RekorPublicKey: tb.root.RekorPublicKey})
+	if err == nil || !strings.Contains(err.Error(), "certificate chain") {
+		t.Fatalf("got %v, want an error about the certificate chain", err)
+	}
+}
+
+func TestBundle_VerifyWrongLogKeyFails(t *testing.T) {
+	tb := newTestBundle(t, time.Now())
+	other := newTestBundle(t, time.Now())
+
+	_, err := tb.bundle.Verify(context.Background(), &TrustedRoot{FulcioRoots: tb.root.FulcioRoots, RekorPublicKey: other.root.RekorPublicKey})
+	if err == nil || !strings.Contains(err.Error(), "SignedEntryTimestamp") {
+		t.Fatalf("got %v, want an error about the SignedEntryTimestamp", err)
+	}
+}
+
+func TestBundle_VerifyExpiredCertificateFails(t *testing.T) {
+	// The entry is integrated an hour after the certificate was issued.
+	tb := newTestBundle(t, time.Now())
+	logKey := generateKey(t)
+	tb.root.RekorPublicKey = &logKey.PublicKey
+	payload, err := tb.bundle.DSSEEnvelope.DecodeB64Payload()
+	if err != nil {
+		t.Fatalf("could not decode the payload: %v", err)
+	}
+	tb.bundle.VerificationMaterial.TlogEntries = nil
+	leafDER := tb.bundle.VerificationMaterial.X509CertificateChain.Certificates[0].RawBytes
+	if err := tb.bundle.AddLogEntry(newTestLogEntry(t, logKey, tb.bundle.DSSEEnvelope, leafDER, payload, time.Now().Add(time.Hour))); err != nil {
+		t.Fatalf("could not add log entry: %v", err)
+	}
+
+	_, err = tb.bundle.Verify(context.Background(), tb.root)
+	if err == nil || !strings.Contains(err.Error(), "certificate chain") {
+		t.Fatalf("got %v, want an error about the certificate chain", err)
+	}
+}
+
+func TestBundle_VerifyTamperedPayloadFails(t *testing.T) {
+	tb := newTestBundle(t, time.Now())
+	tb.bundle.DSSEEnvelope.Payload = base64.StdEncoding.EncodeToString([]byte(`{"_type":"tampered"}`))
+
+	if _, err := tb.bundle.Verify(context.Background(), tb.root); err == nil {
+		t.Fatalf("expected failure for a tampered payload")
+	}
+}
+
+func TestParseBundle_NotABundle(t *testing.T) {
+	if _, err := ParseBundle([]byte(`{"payloadType":"application/vnd.in-toto+json","payload":"e30="}`)); err == nil {
+		t.Fatalf("expected failure for a DSSE envelope")
+	}
+}
@@ -0,0 +1,43 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verifier
+
+import (
+	"github.com/project-oak/transparent-release/internal/model"
+	pb "github.com/project-oak/transparent-release/pkg/proto/oak/release"
+)
+
+// BootstrapVerificationOptions returns starter VerificationOptions, which
+// pin the build type, and the builder, repository, and builder image digest
+// of the given known-good provenance, to the extent that they are recorded in
+// it. The provenance itself passes the returned options. The options are a
+// starting point for writing a policy, and should be reviewed before use,
+// e.g., to allow further builder images.
+func BootstrapVerificationOptions(provenance *model.ProvenanceIR) *pb.VerificationOptions {
+	verOpts := &pb.VerificationOptions{
+		ProvenanceCountAtLeast: &pb.VerifyProvenanceCountAtLeast{Count: 1},
+		AllWithBuildTypes:      &pb.VerifyAllWithBuildTypes{BuildTypes: []string{provenance.BuildType()}},
+	}
+	if builder, err := provenance.TrustedBuilder(); err == nil && builder != "" {
+		verOpts.AllWithBuilderNames = &pb.VerifyAllWithBuilderNames{BuilderNames: []string{builder}}
+	}
+	if provenance.HasRepoURI() && provenance.RepoURI() != "" {
+		verOpts.AllWithRepository = &pb.VerifyAllWithRepository{RepositoryUri: provenance.RepoURI()}
+	}
+	if digest := DigestFromDigestSet(provenance.BuilderImageDigests()); len(digest.Hexadecimal) != 0 {
+		verOpts.AllWithBuilderDigests = &pb.VerifyAllWithBuilderDigests{Digests: []*pb.Digest{digest}}
+	}
+	return verOpts
+}
@@ -0,0 +1,88 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verifier
+
+import (
+	"os"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/project-oak/transparent-release/internal/model"
+	slsav1 "github.com/project-oak/transparent-release/pkg/intoto/slsa_provenance/v1"
+	pb "github.com/project-oak/transparent-release/pkg/proto/oak/release"
+)
+
+func TestBootstrapVerificationOptions(t *testing.T) {
+	statementBytes, err := os.ReadFile("../../testdata/slsa_v1_provenance.json")
+	if err != nil {
+		t.Fatalf("couldn't read the provenance file: %v", err)
+	}
+	validatedProvenance, err := model.ParseStatementData(statementBytes)
+	if err != nil {
+		t.Fatalf("couldn't parse the provenance: %v", err)
+	}
+	provenance, err := model.FromValidatedProvenance(validatedProvenance)
+	if err != nil {
+		t.Fatalf("couldn't map the provenance: %v", err)
+	}
+
+	got := BootstrapVerificationOptions(provenance)
+
+	want := &pb.VerificationOptions{
+		ProvenanceCountAtLeast: &pb.VerifyProvenanceCountAtLeast{Count: 1},
+		AllWithBuildTypes:      &pb.VerifyAllWithBuildTypes{BuildTypes: []string{slsav1.DockerBasedBuildType}},
+		AllWithBuilderNames:    &pb.VerifyAllWithBuilderNames{BuilderNames: []string{provenanceBuilder(t, provenance)}},
+		AllWithRepository:      &pb.VerifyAllWithRepository{RepositoryUri: provenance.RepoURI()},
+		AllWithBuilderDigests: &pb.VerifyAllWithBuilderDigests{Digests: []*pb.Digest{
+			{Hexadecimal: map[int32]string{int32(pb.Digest_SHA2_256): "51532c757d1008bbff696d053a1d05226f6387cf232aa80b6f9c13b0759ccea0"}},
+		}},
+	}
+	if !proto.Equal(got, want) {
+		t.Errorf("unexpected verification options: got %v, want %v", got, want)
+	}
+	if err := Verify([]model.ProvenanceIR{*provenance}, got); err != nil {
+		t.Errorf("the provenance does not pass its bootstrapped options: %v", err)
+	}
+}
+
+func TestBootstrapVerificationOptions_MissingFieldsOmitted(t *testing.T) {
+	provenance := model.NewProvenanceIR(binaryDigest, slsav1.DockerBasedBuildType, binaryName)
+
+	got := BootstrapVerificationOptions(provenance)
+
+	if got.AllWithBuilderNames != nil || got.AllWithRepository != nil || got.AllWithBuilderDigests != nil {
+		t.Errorf("expected only the build type to be pinned, got %v", got)
+	}
+}
+
+func TestVerify_BuildTypeMismatchDetected(t *testing.T) {
+	provenance := model.NewProvenanceIR(binaryDigest, slsav1.DockerBasedBuildType, binaryName)
+	verOpts := &pb.VerificationOptions{
+		AllWithBuildTypes: &pb.VerifyAllWithBuildTypes{BuildTypes: []string{"https://example.com/other-build-type"}},
+	}
+
+	if err := Verify([]model.ProvenanceIR{*provenance}, verOpts); err == nil {
+		t.Fatalf("expected failure")
+	}
+}
+
+func provenanceBuilder(t *testing.T, provenance *model.ProvenanceIR) string {
+	builder, err := provenance.TrustedBuilder()
+	if err != nil {
+		t.Fatalf("the provenance has no builder: %v", err)
+	}
+	return builder
+}
@@ -0,0 +1,105 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verifier
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Categories of verification failures. Errors returned by Verify, and
+// recorded by VerifyWithReport, wrap one of them, so that callers can branch
+// on the category with errors.Is, and get the details with errors.As and a
+// *VerificationError.
+//
+//nolint:gochecknoglobals
+var (
+	ErrTooFewProvenances           = errors.New("too few provenances")
+	ErrTooManyProvenances          = errors.New("too many provenances")
+	ErrDenied                      = errors.New("denied")
+	ErrBinaryNameMismatch          = errors.New("binary name mismatch")
+	ErrBinaryDigestMismatch        = errors.New("binary digest mismatch")
+	ErrMissingBuildCommand         = errors.New("missing build command")
+	ErrRepositoryMismatch          = errors.New("repository mismatch")
+	ErrUntrustedBuilder            = errors.New("untrusted builder")
+	ErrBuilderDigestMismatch       = errors.New("builder digest mismatch")
+	ErrCommitDigestMismatch        = errors.New("commit digest mismatch")
+	ErrTreeDigestMismatch          = errors.New("tree digest mismatch")
+	ErrSourceRefMismatch           = errors.New("source ref mismatch")
+	ErrBuildTimeOutOfRange         = errors.New("build time out of range")
+	ErrByproductMismatch           = errors.New("byproduct mismatch")
+	ErrBuildMetadataMismatch       = errors.New("build metadata mismatch")
+	ErrBuildTypeMismatch           = errors.New("build type mismatch")
+	ErrCertificateIdentityMismatch = errors.New("certificate identity mismatch")
+	ErrToolchainVersionTooOld      = errors.New("toolchain version too old")
+)
+
+// AllProvenances is the Index of a VerificationError about the provenances as
+// a whole, rather than a single provenance.
+const AllProvenances = -1
+
+// VerificationError is a failure of a single check on a provenance.
+type VerificationError struct {
+	// Kind is the category of the failure, one of the Err* errors.
+	Kind error
+	// Index is the index of the failing provenance, or AllProvenances.
+	Index int
+	// Expected is the value required by the VerificationOptions, if any.
+	Expected interface{}
+	// Actual is the value found in the provenance, or nil if the provenance
+	// does not have the value.
+	Actual interface{}
+
+	message string
+}
+
+func (e *VerificationError) Error() string {
+	return e.message
+}
+
+func (e *VerificationError) Unwrap() error {
+	return e.Kind
+}
+
+// newVerificationError returns a VerificationError with the given details,
+// and the given formatted message.
+func newVerificationError(kind error, index int, expected, actual interface{}, format string, a ...interface{}) error {
+	return &VerificationError{
+		Kind:     kind,
+		Index:    index,
+		Expected: expected,
+		Actual:   actual,
+		message:  fmt.Sprintf(format, a...),
+	}
+}
+
+// errorCategories returns the distinct categories of the VerificationErrors
+// among the given errors, in the order in which they first occur.
+func errorCategories(errs []error) []string {
+	var categories []string
+	seen := make(map[string]bool)
+	for _, err := range errs {
+		var verificationErr *VerificationError
+		if !errors.As(err, &verificationErr) {
+			continue
+		}
+		category := verificationErr.Kind.Error()
+		if !seen[category] {
+			seen[category] = true
+			categories = append(categories, category)
+		}
+	}
+	return categories
+}
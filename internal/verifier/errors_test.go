@@ -0,0 +1,94 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verifier
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/project-oak/transparent-release/internal/model"
+	"github.com/project-oak/transparent-release/internal/testutil"
+	slsav02 "github.com/project-oak/transparent-release/pkg/intoto/slsa_provenance/v0.2"
+	pb "github.com/project-oak/transparent-release/pkg/proto/oak/release"
+)
+
+func TestVerify_UntrustedBuilderError(t *testing.T) {
+	provenances := []model.ProvenanceIR{
+		*model.NewProvenanceIR(binaryDigest, slsav02.GenericSLSABuildType, binaryName, model.WithTrustedBuilder(builderName)),
+		*model.NewProvenanceIR(binaryDigest, slsav02.GenericSLSABuildType, binaryName, model.WithTrustedBuilder("other_"+builderName)),
+	}
+	verOpts := pb.VerificationOptions{
+		AllWithBuilderNames: &pb.VerifyAllWithBuilderNames{BuilderNames: []string{builderName}},
+	}
+
+	err := Verify(provenances, &verOpts)
+	if !errors.Is(err, ErrUntrustedBuilder) {
+		t.Fatalf("got %v, want an error wrapping ErrUntrustedBuilder", err)
+	}
+	if errors.Is(err, ErrBinaryDigestMismatch) {
+		t.Errorf("unexpected ErrBinaryDigestMismatch in %v", err)
+	}
+	var verificationErr *VerificationError
+	if !errors.As(err, &verificationErr) {
+		t.Fatalf("got %v, want a *VerificationError", err)
+	}
+	testutil.AssertEq(t, "index", verificationErr.Index, 1)
+	if diff := cmp.Diff(interface{}("other_"+builderName), verificationErr.Actual); diff != "" {
+		t.Errorf("unexpected actual value (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(interface{}([]string{builderName}), verificationErr.Expected); diff != "" {
+		t.Errorf("unexpected expected value (-want +got):\n%s", diff)
+	}
+}
+
+func TestVerify_ProvenanceCountError(t *testing.T) {
+	verOpts := pb.VerificationOptions{
+		ProvenanceCountAtLeast: &pb.VerifyProvenanceCountAtLeast{Count: 1},
+	}
+
+	err := Verify([]model.ProvenanceIR{}, &verOpts)
+	var verificationErr *VerificationError
+	if !errors.As(err, &verificationErr) || !errors.Is(err, ErrTooFewProvenances) {
+		t.Fatalf("got %v, want a *VerificationError wrapping ErrTooFewProvenances", err)
+	}
+	testutil.AssertEq(t, "index", verificationErr.Index, AllProvenances)
+	testutil.AssertEq(t, "message", verificationErr.Error(), "too few provenances: have 0 but want at least 1")
+}
+
+func TestVerifyWithReport_Categories(t *testing.T) {
+	provenance := model.NewProvenanceIR(binaryDigest, slsav02.GenericSLSABuildType, binaryName,
+		model.WithTrustedBuilder(builderName), model.WithRepoURI(repoURI))
+	verOpts := pb.VerificationOptions{
+		AllWithBinaryName: &pb.VerifyAllWithBinaryName{BinaryName: binaryName},
+		AllWithRepository: &pb.VerifyAllWithRepository{RepositoryUri: "other_" + repoURI},
+		AllWithSourceRef:  &pb.VerifyAllWithSourceRef{Patterns: []string{"refs/tags/*"}},
+	}
+
+	report := VerifyWithReport([]model.ProvenanceIR{*provenance}, &verOpts)
+	got := make(map[string][]string)
+	for _, check := range report.Checks {
+		got[check.Name] = check.Categories
+	}
+	want := map[string][]string{
+		"all_with_binary_name": nil,
+		"all_with_repository":  {"repository mismatch"},
+		"all_with_source_ref":  {"source ref mismatch"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected categories (-want +got):\n%s", diff)
+	}
+}
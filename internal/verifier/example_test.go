@@ -0,0 +1,55 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verifier_test
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/project-oak/transparent-release/internal/model"
+	"github.com/project-oak/transparent-release/internal/verifier"
+)
+
+func ExampleVerify() {
+	bytes, err := os.ReadFile("../../testdata/slsa_v1_provenance.json")
+	if err != nil {
+		log.Fatal(err)
+	}
+	validated, err := model.ParseStatementData(bytes)
+	if err != nil {
+		log.Fatal(err)
+	}
+	provenance, err := model.FromValidatedProvenance(validated)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	verOpts, err := verifier.ParseVerificationOptions(`
+		all_with_binary_digests {
+			digests { hexadecimal { key: 18 value: "813841dda3818d616aa3e706e49d0286dc825c5dbad4a75cfb37b91ba412238b" } }
+		}
+		all_with_repository { repository_uri: "git+https://github.com/project-oak/oak" }`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := verifier.Verify([]model.ProvenanceIR{*provenance}, verOpts); err != nil {
+		fmt.Println("verification failed:", err)
+		return
+	}
+	fmt.Println("verification passed")
+	// Output: verification passed
+}
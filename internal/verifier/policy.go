@@ -0,0 +1,308 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verifier
+
+// This file provides YAML (de)serialization of VerificationOptions, for
+// teams that maintain their verification policies in YAML. The YAML uses the
+// same field names as textproto. Before converting YAML to
+// VerificationOptions, it is validated against a JSON schema derived from the
+// proto definition, so that errors point at the offending line and column.
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"gopkg.in/yaml.v3"
+
+	pb "github.com/project-oak/transparent-release/pkg/proto/oak/release"
+)
+
+// jsonSchemaDraft is the JSON schema dialect of VerificationOptionsJSONSchema.
+const jsonSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// jsonSchema is the subset of JSON schema needed to describe proto messages.
+type jsonSchema struct {
+	Schema        string                 `json:"$schema,omitempty"`
+	Title         string                 `json:"title,omitempty"`
+	Type          string                 `json:"type"`
+	Format        string                 `json:"format,omitempty"`
+	Enum          []string               `json:"enum,omitempty"`
+	Pattern       string                 `json:"pattern,omitempty"`
+	Properties    map[string]*jsonSchema `json:"properties,omitempty"`
+	PropertyNames *jsonSchema            `json:"propertyNames,omitempty"`
+	// AdditionalProperties is either false, or the schema of the values of
+	// a map.
+	AdditionalProperties interface{} `json:"additionalProperties,omitempty"`
+	Items                *jsonSchema `json:"items,omitempty"`
+}
+
+// VerificationOptionsJSONSchema returns the JSON schema of VerificationOptions
+// in YAML or JSON, e.g., for validating policies in editors.
+func VerificationOptionsJSONSchema() ([]byte, error) {
+	schema := messageSchema((&pb.VerificationOptions{}).ProtoReflect().Descriptor())
+	schema.Schema = jsonSchemaDraft
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+func messageSchema(md protoreflect.MessageDescriptor) *jsonSchema {
+	properties := make(map[string]*jsonSchema, md.Fields().Len())
+	for i := 0; i < md.Fields().Len(); i++ {
+		fd := md.Fields().Get(i)
+		properties[string(fd.Name())] = fieldSchema(fd)
+	}
+	return &jsonSchema{
+		Title:                string(md.FullName()),
+		Type:                 "object",
+		Properties:           properties,
+		AdditionalProperties: false,
+	}
+}
+
+func fieldSchema(fd protoreflect.FieldDescriptor) *jsonSchema {
+	switch {
+	case fd.IsMap():
+		keys := kindSchema(fd.MapKey())
+		if keys.Type == "integer" {
+			// Keys are always strings in JSON.
+			keys = &jsonSchema{Type: "string", Pattern: "^-?[0-9]+$"}
+		}
+		return &jsonSchema{
+			Type:                 "object",
+			PropertyNames:        keys,
+			AdditionalProperties: kindSchema(fd.MapValue()),
+		}
+	case fd.IsList():
+		return &jsonSchema{Type: "array", Items: kindSchema(fd)}
+	default:
+		return kindSchema(fd)
+	}
+}
+
+// kindSchema returns the schema of a single value of the given field.
+func kindSchema(fd protoreflect.FieldDescriptor) *jsonSchema {
+	//nolint:exhaustive
+	switch fd.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return messageSchema(fd.Message())
+	case protoreflect.EnumKind:
+		values := fd.Enum().Values()
+		names := make([]string, 0, values.Len())
+		for i := 0; i < values.Len(); i++ {
+			names = append(names, string(values.Get(i).Name()))
+		}
+		return &jsonSchema{Type: "string", Enum: names}
+	case protoreflect.BoolKind:
+		return &jsonSchema{Type: "boolean"}
+	case protoreflect.StringKind:
+		return &jsonSchema{Type: "string"}
+	case protoreflect.BytesKind:
+		return &jsonSchema{Type: "string", Format: "byte"}
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		return &jsonSchema{Type: "number"}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return &jsonSchema{Type: "integer", Format: "int32"}
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return &jsonSchema{Type: "integer", Format: "uint32"}
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return &jsonSchema{Type: "integer", Format: "uint64"}
+	default:
+		return &jsonSchema{Type: "integer", Format: "int64"}
+	}
+}
+
+// ParseVerificationOptionsYAML parses VerificationOptions from YAML, using the
+// same field names as textproto. An empty document results in empty options.
+func ParseVerificationOptionsYAML(data []byte) (*pb.VerificationOptions, error) {
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("parse VerificationOptions: %v", err)
+	}
+	var opts pb.VerificationOptions
+	if len(document.Content) == 0 {
+		return &opts, nil
+	}
+
+	schema := messageSchema(opts.ProtoReflect().Descriptor())
+	value, err := decodeNode(document.Content[0], schema, "")
+	if err != nil {
+		return nil, fmt.Errorf("parse VerificationOptions: %v", err)
+	}
+	bytes, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("parse VerificationOptions: %v", err)
+	}
+	if err := protojson.Unmarshal(bytes, &opts); err != nil {
+		return nil, fmt.Errorf("parse VerificationOptions: %v", err)
+	}
+	return &opts, nil
+}
+
+// MarshalVerificationOptionsYAML serializes the given VerificationOptions as
+// YAML, which can be parsed with ParseVerificationOptionsYAML.
+func MarshalVerificationOptionsYAML(opts *pb.VerificationOptions) ([]byte, error) {
+	bytes, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(opts)
+	if err != nil {
+		return nil, fmt.Errorf("marshal VerificationOptions: %v", err)
+	}
+	var value interface{}
+	if err := json.Unmarshal(bytes, &value); err != nil {
+		return nil, fmt.Errorf("marshal VerificationOptions: %v", err)
+	}
+	return yaml.Marshal(value)
+}
+
+// schemaError is a validation error at the position of the given node.
+func schemaError(node *yaml.Node, path string, format string, a ...interface{}) error {
+	if path == "" {
+		path = "."
+	}
+	return fmt.Errorf("line %d, column %d: %s: %s", node.Line, node.Column, path, fmt.Sprintf(format, a...))
+}
+
+// decodeNode validates the given YAML node against the given schema, and
+// returns it as a value that can be marshalled as JSON. Duplicate keys are
+// rejected.
+//
+//nolint:cyclop
+func decodeNode(node *yaml.Node, schema *jsonSchema, path string) (interface{}, error) {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	isNull := node.Kind == yaml.ScalarNode && node.Tag == "!!null"
+
+	switch schema.Type {
+	case "object":
+		if isNull {
+			// Allows, e.g., `all_same_binary_name:` without a value.
+			return map[string]interface{}{}, nil
+		}
+		if node.Kind != yaml.MappingNode {
+			return nil, schemaError(node, path, "got %s, want a mapping", describeNode(node))
+		}
+		object := make(map[string]interface{}, len(node.Content)/2)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			keyNode, valueNode := node.Content[i], node.Content[i+1]
+			key := keyNode.Value
+			keyPath := path + "." + key
+			// Later values of duplicate keys would silently override, e.g.,
+			// a reviewed setting.
+			if _, ok := object[key]; ok {
+				return nil, schemaError(keyNode, path, "duplicate field %q", key)
+			}
+			valueSchema, ok := schema.Properties[key]
+			if !ok {
+				values, isMap := schema.AdditionalProperties.(*jsonSchema)
+				if !isMap {
+					return nil, schemaError(keyNode, path, "unknown field %q in %s", key, schema.Title)
+				}
+				if _, err := decodeNode(keyNode, schema.PropertyNames, keyPath); err != nil {
+					return nil, err
+				}
+				valueSchema = values
+			}
+			value, err := decodeNode(valueNode, valueSchema, keyPath)
+			if err != nil {
+				return nil, err
+			}
+			object[key] = value
+		}
+		return object, nil
+	case "array":
+		if node.Kind != yaml.SequenceNode {
+			return nil, schemaError(node, path, "got %s, want a sequence", describeNode(node))
+		}
+		array := make([]interface{}, 0, len(node.Content))
+		for i, itemNode := range node.Content {
+			item, err := decodeNode(itemNode, schema.Items, fmt.Sprintf("%s[%d]", path, i))
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, item)
+		}
+		return array, nil
+	}
+
+	if node.Kind != yaml.ScalarNode || isNull {
+		return nil, schemaError(node, path, "got %s, want a %s", describeNode(node), schema.Type)
+	}
+	switch schema.Type {
+	case "string":
+		if schema.Pattern != "" {
+			if _, err := strconv.ParseInt(node.Value, 10, 64); err != nil {
+				return nil, schemaError(node, path, "got %q, want an integer", node.Value)
+			}
+		}
+		if len(schema.Enum) > 0 && !contains(schema.Enum, node.Value) {
+			return nil, schemaError(node, path, "got %q, want one of %v", node.Value, schema.Enum)
+		}
+		return node.Value, nil
+	case "boolean":
+		value, err := strconv.ParseBool(node.Value)
+		if err != nil {
+			return nil, schemaError(node, path, "got %q, want a boolean", node.Value)
+		}
+		return value, nil
+	case "number":
+		if _, err := strconv.ParseFloat(node.Value, 64); err != nil {
+			return nil, schemaError(node, path, "got %q, want a number", node.Value)
+		}
+		return json.Number(node.Value), nil
+	default:
+		var err error
+		switch schema.Format {
+		case "int32":
+			_, err = strconv.ParseInt(node.Value, 10, 32)
+		case "uint32":
+			_, err = strconv.ParseUint(node.Value, 10, 32)
+		case "uint64":
+			_, err = strconv.ParseUint(node.Value, 10, 64)
+		default:
+			_, err = strconv.ParseInt(node.Value, 10, 64)
+		}
+		if err != nil {
+			return nil, schemaError(node, path, "got %q, want an %s", node.Value, schema.Format)
+		}
+		return json.Number(node.Value), nil
+	}
+}
+
+// describeNode describes the kind of the given node for error messages.
+func describeNode(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return "a mapping"
+	case yaml.SequenceNode:
+		return "a sequence"
+	case yaml.ScalarNode:
+		if node.Tag == "!!null" {
+			return "null"
+		}
+		return fmt.Sprintf("%q", node.Value)
+	default:
+		return "an unsupported node"
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,177 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verifier
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+)
+
+const policyYAML = `
+provenance_count_at_least:
+  count: 1
+all_same_binary_name:
+all_with_binary_name:
+  binary_name: oak_functions_freestanding_bin
+all_with_binary_digests:
+  digests:
+    - hexadecimal:
+        18: 322527c0260e25f0e9a2595bd0d71a52294fe2397a7af76165190fd98de8920d
+all_with_builder_names:
+  builder_names: ["", "https://github.com/slsa-framework/slsa-github-generator"]
+`
+
+const policyTextproto = `
+provenance_count_at_least { count: 1 }
+all_same_binary_name {}
+all_with_binary_name { binary_name: "oak_functions_freestanding_bin" }
+all_with_binary_digests {
+  digests { hexadecimal { key: 18 value: "322527c0260e25f0e9a2595bd0d71a52294fe2397a7af76165190fd98de8920d" } }
+}
+all_with_builder_names { builder_names: ["", "https://github.com/slsa-framework/slsa-github-generator"] }
+`
+
+func TestParseVerificationOptionsYAML(t *testing.T) {
+	got, err := ParseVerificationOptionsYAML([]byte(policyYAML))
+	if err != nil {
+		t.Fatalf("Failed to parse YAML: %v", err)
+	}
+	want, err := ParseVerificationOptions(policyTextproto)
+	if err != nil {
+		t.Fatalf("Failed to parse textproto: %v", err)
+	}
+	if !proto.Equal(got, want) {
+		t.Errorf("Unexpected options: got %v, want %v", got, want)
+	}
+}
+
+func TestParseVerificationOptionsYAML_Empty(t *testing.T) {
+	got, err := ParseVerificationOptionsYAML(nil)
+	if err != nil {
+		t.Fatalf("Failed to parse empty YAML: %v", err)
+	}
+	if got.ProvenanceCountAtLeast != nil || got.AllWithBinaryName != nil {
+		t.Errorf("Expected empty options, got %v", got)
+	}
+}
+
+func TestParseVerificationOptionsYAML_SchemaErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want string
+	}{
+		{
+			name: "unknown field",
+			yaml: "all_with_binary_name:\n  binary: foo\n",
+			want: `line 2, column 3: .all_with_binary_name: unknown field "binary" in oak.release.VerifyAllWithBinaryName`,
+		},
+		{
+			name: "wrong type",
+			yaml: "provenance_count_at_least:\n  count: many\n",
+			want: `line 2, column 10: .provenance_count_at_least.count: got "many", want an int32`,
+		},
+		{
+			name: "not a sequence",
+			yaml: "all_with_builder_names:\n  builder_names: foo\n",
+			want: `line 2, column 18: .all_with_builder_names.builder_names: got "foo", want a sequence`,
+		},
+		{
+			name: "invalid map key",
+			yaml: "all_with_binary_digests:\n  digests:\n    - hexadecimal:\n        sha256: abc\n",
+			want: `line 4, column 9: .all_with_binary_digests.digests[0].hexadecimal.sha256: got "sha256", want an integer`,
+		},
+		{
+			name: "duplicate field",
+			yaml: "provenance_count_at_least:\n  count: 2\nprovenance_count_at_least:\n  count: 1\n",
+			want: `line 3, column 1: .: duplicate field "provenance_count_at_least"`,
+		},
+		{
+			name: "duplicate map key",
+			yaml: "all_with_minimum_toolchain_versions:\n  minimum_versions:\n    rustc: 1.70.0\n    rustc: 1.60.0\n",
+			want: `line 4, column 5: .all_with_minimum_toolchain_versions.minimum_versions: duplicate field "rustc"`,
+		},
+		{
+			name: "not a mapping",
+			yaml: "- provenance_count_at_least\n",
+			want: `line 1, column 1: .: got a sequence, want a mapping`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseVerificationOptionsYAML([]byte(tt.yaml))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Unexpected error: got %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarshalVerificationOptionsYAML(t *testing.T) {
+	want, err := ParseVerificationOptions(policyTextproto)
+	if err != nil {
+		t.Fatalf("Failed to parse textproto: %v", err)
+	}
+	bytes, err := MarshalVerificationOptionsYAML(want)
+	if err != nil {
+		t.Fatalf("Failed to marshal YAML: %v", err)
+	}
+	got, err := ParseVerificationOptionsYAML(bytes)
+	if err != nil {
+		t.Fatalf("Failed to parse marshalled YAML %s: %v", bytes, err)
+	}
+	if !proto.Equal(got, want) {
+		t.Errorf("Unexpected options after a round trip: got %v, want %v", got, want)
+	}
+}
+
+func TestLoadVerificationOptions_YAML(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	if err := os.WriteFile(path, []byte(policyYAML), 0o600); err != nil {
+		t.Fatalf("Failed to write policy: %v", err)
+	}
+	got, err := LoadVerificationOptions(path)
+	if err != nil {
+		t.Fatalf("Failed to load policy: %v", err)
+	}
+	if got.GetAllWithBinaryName().GetBinaryName() != "oak_functions_freestanding_bin" {
+		t.Errorf("Unexpected options: %v", got)
+	}
+}
+
+func TestVerificationOptionsJSONSchema(t *testing.T) {
+	bytes, err := VerificationOptionsJSONSchema()
+	if err != nil {
+		t.Fatalf("Failed to generate schema: %v", err)
+	}
+	var schema struct {
+		Schema     string                     `json:"$schema"`
+		Properties map[string]json.RawMessage `json:"properties"`
+	}
+	if err := json.Unmarshal(bytes, &schema); err != nil {
+		t.Fatalf("Failed to parse schema: %v", err)
+	}
+	if schema.Schema != jsonSchemaDraft {
+		t.Errorf("Unexpected $schema: %q", schema.Schema)
+	}
+	if _, ok := schema.Properties["all_with_binary_digests"]; !ok {
+		t.Errorf("Expected a property for all_with_binary_digests in %s", bytes)
+	}
+}
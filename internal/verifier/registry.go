@@ -0,0 +1,115 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verifier
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/BurntSushi/toml"
+	"go.uber.org/multierr"
+
+	pb "github.com/project-oak/transparent-release/pkg/proto/oak/release"
+)
+
+// Registry holds the VerificationOptions of many binaries, keyed by binary
+// name, so that a team can maintain a single policy file for all its release
+// binaries. Registries are written in TOML, with one `[binary."<name>"]` table
+// per binary, e.g.:
+//
+//	[binary."oak_functions_bin"]
+//	verification_options = """
+//	all_with_builder_names { builder_names: "https://github.com/..." }
+//	"""
+type Registry struct {
+	Binaries map[string]RegistryEntry `toml:"binary"`
+
+	verOpts map[string]*pb.VerificationOptions
+}
+
+// RegistryEntry holds the VerificationOptions of a single binary.
+type RegistryEntry struct {
+	// VerificationOptions as textproto. Must be set, unless SkipVerification is set.
+	VerificationOptions string `toml:"verification_options"`
+	// Confirms that empty VerificationOptions are intended.
+	SkipVerification bool `toml:"skip_verification"`
+}
+
+// LoadRegistry reads and validates the registry at the given path.
+func LoadRegistry(path string) (*Registry, error) {
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading the registry: %v", err)
+	}
+	return ParseRegistry(bytes)
+}
+
+// ParseRegistry parses and validates the given TOML registry, including the
+// VerificationOptions of all binaries.
+func ParseRegistry(bytes []byte) (*Registry, error) {
+	var registry Registry
+	metadata, err := toml.Decode(string(bytes), &registry)
+	if err != nil {
+		return nil, fmt.Errorf("parsing the registry: %v", err)
+	}
+	if undecoded := metadata.Undecoded(); len(undecoded) != 0 {
+		return nil, fmt.Errorf("unknown keys in the registry: %v", undecoded)
+	}
+	if len(registry.Binaries) == 0 {
+		return nil, fmt.Errorf("no binaries in the registry")
+	}
+
+	var errs error
+	registry.verOpts = make(map[string]*pb.VerificationOptions, len(registry.Binaries))
+	for _, name := range registry.Names() {
+		entry := registry.Binaries[name]
+		if entry.VerificationOptions == "" && !entry.SkipVerification {
+			errs = multierr.Append(errs, fmt.Errorf("binary %q has empty verification_options, set skip_verification to overrule", name))
+			continue
+		}
+		verOpts, err := ParseVerificationOptions(entry.VerificationOptions)
+		if err != nil {
+			errs = multierr.Append(errs, fmt.Errorf("binary %q: %v", name, err))
+			continue
+		}
+		registry.verOpts[name] = verOpts
+	}
+	if errs != nil {
+		return nil, fmt.Errorf("invalid registry: %v", errs)
+	}
+	return &registry, nil
+}
+
+// Names returns the names of all binaries in the registry, in lexicographic
+// order.
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.Binaries))
+	for name := range r.Binaries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// VerificationOptions returns the VerificationOptions of the binary with the
+// given name, or an error if the registry has no entry for it.
+func (r *Registry) VerificationOptions(name string) (*pb.VerificationOptions, error) {
+	verOpts, ok := r.verOpts[name]
+	if !ok {
+		return nil, fmt.Errorf("no verification options for binary %q in the registry", name)
+	}
+	return verOpts, nil
+}
@@ -0,0 +1,86 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verifier
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/project-oak/transparent-release/pkg/proto/oak/release"
+)
+
+func TestParseRegistry(t *testing.T) {
+	registryBytes := `
+[binary."stage0_bin"]
+verification_options = """
+provenance_count_at_least { count: 1 }
+all_with_binary_name { binary_name: "stage0_bin" }
+"""
+
+[binary."kernel_bin"]
+skip_verification = true
+`
+	registry, err := ParseRegistry([]byte(registryBytes))
+	if err != nil {
+		t.Fatalf("Failed to parse the registry: %v", err)
+	}
+	if diff := cmp.Diff([]string{"kernel_bin", "stage0_bin"}, registry.Names()); diff != "" {
+		t.Errorf("Unexpected names (-want +got):\n%s", diff)
+	}
+
+	tests := map[string]*pb.VerificationOptions{
+		"stage0_bin": {
+			ProvenanceCountAtLeast: &pb.VerifyProvenanceCountAtLeast{Count: 1},
+			AllWithBinaryName:      &pb.VerifyAllWithBinaryName{BinaryName: "stage0_bin"},
+		},
+		"kernel_bin": {},
+	}
+	for name, want := range tests {
+		got, err := registry.VerificationOptions(name)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !proto.Equal(got, want) {
+			t.Errorf("%s: got %v, want %v", name, got, want)
+		}
+	}
+
+	if _, err := registry.VerificationOptions("unknown_bin"); err == nil {
+		t.Errorf("Expected an error for a binary that is not in the registry")
+	}
+}
+
+func TestParseRegistry_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"no binaries": ``,
+		"unknown key": `
+[binary."a"]
+skip_verification = true
+policy = "a.yaml"`,
+		"empty verification options": `
+[binary."a"]
+verification_options = ""`,
+		"invalid verification options": `
+[binary."a"]
+verification_options = "unknown_option {}"`,
+	}
+	for name, registry := range tests {
+		if _, err := ParseRegistry([]byte(registry)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
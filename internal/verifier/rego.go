@@ -0,0 +1,152 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verifier
+
+// This file provides verification policies in Rego, the policy language of
+// Open Policy Agent (https://www.openpolicyagent.org/docs/latest/policy-language/),
+// for conditions that VerificationOptions cannot express, e.g., that
+// provenances built by a given builder must come from a given repository and
+// ref. A Rego policy is evaluated against the model.ProvenanceFields of each
+// provenance, and must define a `deny` set of messages, as in:
+//
+//	package transparent_release
+//
+//	deny[msg] {
+//		input.trustedBuilder == "https://github.com/slsa-framework/slsa-github-generator/.github/workflows/builder_go_slsa3.yml@refs/tags/v1.5.0"
+//		not startswith(input.sourceRef, "refs/tags/v")
+//		msg := sprintf("release builds must be tagged, got ref %q", [input.sourceRef])
+//	}
+//
+// The provenance passes if the set is empty.
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/rego"
+	"go.uber.org/multierr"
+
+	"github.com/project-oak/transparent-release/internal/model"
+)
+
+// regoDenyRule is the name of the rule that a Rego policy must define.
+const regoDenyRule = "deny"
+
+// RegoPolicy is a compiled Rego policy.
+type RegoPolicy struct {
+	// Name is the name of the policy, e.g., the path it was loaded from.
+	Name  string
+	query rego.PreparedEvalQuery
+}
+
+// LoadRegoPolicy loads and compiles the Rego policy at the given path.
+func LoadRegoPolicy(ctx context.Context, path string) (*RegoPolicy, error) {
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read the Rego policy: %v", err)
+	}
+	return ParseRegoPolicy(ctx, path, string(bytes))
+}
+
+// ParseRegoPolicy compiles the given Rego module, with the given name, as a
+// policy. The module must define a `deny` rule.
+func ParseRegoPolicy(ctx context.Context, name, module string) (*RegoPolicy, error) {
+	parsed, err := ast.ParseModule(name, module)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse the Rego policy: %v", err)
+	}
+	if !definesRule(parsed, regoDenyRule) {
+		return nil, fmt.Errorf("the Rego policy %s does not define a %q rule", name, regoDenyRule)
+	}
+	query, err := rego.New(
+		rego.Query(parsed.Package.Path.String()+"."+regoDenyRule),
+		rego.ParsedModule(parsed),
+	).PrepareForEval(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not compile the Rego policy: %v", err)
+	}
+	return &RegoPolicy{Name: name, query: query}, nil
+}
+
+// Evaluate evaluates the policy against the given provenance, and returns an
+// error for each message in the `deny` set, in lexicographic order.
+func (p *RegoPolicy) Evaluate(ctx context.Context, provenance *model.ProvenanceIR) error {
+	input, err := regoInput(provenance)
+	if err != nil {
+		return err
+	}
+	results, err := p.query.Eval(ctx, rego.EvalInput(input))
+	if err != nil {
+		return fmt.Errorf("could not evaluate the Rego policy: %v", err)
+	}
+	if len(results) == 0 || len(results[0].Expressions) == 0 {
+		// An undefined `deny` set denies nothing.
+		return nil
+	}
+	denied, ok := results[0].Expressions[0].Value.([]interface{})
+	if !ok {
+		return fmt.Errorf("the %q rule of the Rego policy must be a set, got %T", regoDenyRule, results[0].Expressions[0].Value)
+	}
+	messages := make([]string, 0, len(denied))
+	for _, message := range denied {
+		messages = append(messages, fmt.Sprint(message))
+	}
+	sort.Strings(messages)
+	var errs error
+	for _, message := range messages {
+		errs = multierr.Append(errs, fmt.Errorf("denied by %s: %s", p.Name, message))
+	}
+	return errs
+}
+
+// CheckRegoPolicy evaluates the given Rego policy against each of the given
+// provenances, and records the outcome in the report.
+func (r *Report) CheckRegoPolicy(ctx context.Context, policy *RegoPolicy, provenances []model.ProvenanceIR) {
+	var errs error
+	for index := range provenances {
+		if err := policy.Evaluate(ctx, &provenances[index]); err != nil {
+			for _, err := range multierr.Errors(err) {
+				errs = multierr.Append(errs, fmt.Errorf("#%d: %v", index, err))
+			}
+		}
+	}
+	r.addCheck("rego_policy", fmt.Sprintf("path:%q", policy.Name), errs)
+}
+
+// regoInput returns the fields of the given provenance as a JSON document.
+func regoInput(provenance *model.ProvenanceIR) (interface{}, error) {
+	bytes, err := json.Marshal(provenance.Export())
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal the provenance: %v", err)
+	}
+	var input interface{}
+	if err := json.Unmarshal(bytes, &input); err != nil {
+		return nil, fmt.Errorf("could not unmarshal the provenance: %v", err)
+	}
+	return input, nil
+}
+
+func definesRule(module *ast.Module, name string) bool {
+	for _, rule := range module.Rules {
+		if rule.Head.Name.String() == name {
+			return true
+		}
+	}
+	return false
+}
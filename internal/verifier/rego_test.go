@@ -0,0 +1,124 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verifier
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"go.uber.org/multierr"
+
+	"github.com/project-oak/transparent-release/internal/model"
+	"github.com/project-oak/transparent-release/internal/testutil"
+	slsav02 "github.com/project-oak/transparent-release/pkg/intoto/slsa_provenance/v0.2"
+)
+
+const taggedReleasesPolicy = `package transparent_release
+
+deny[msg] {
+	input.trustedBuilder == "` + builderName + `"
+	input.repoURI != "` + repoURI + `"
+	msg := sprintf("unexpected repository %q", [input.repoURI])
+}
+
+deny[msg] {
+	input.trustedBuilder == "` + builderName + `"
+	not startswith(input.sourceRef, "refs/tags/v")
+	msg := sprintf("untagged ref %q", [input.sourceRef])
+}
+`
+
+func TestRegoPolicy(t *testing.T) {
+	ctx := context.Background()
+	policy, err := ParseRegoPolicy(ctx, "tagged_releases.rego", taggedReleasesPolicy)
+	if err != nil {
+		t.Fatalf("could not parse the policy: %v", err)
+	}
+
+	tests := map[string]struct {
+		options []func(p *model.ProvenanceIR)
+		want    []string
+	}{
+		"tagged release": {
+			options: []func(p *model.ProvenanceIR){model.WithTrustedBuilder(builderName), model.WithRepoURI(repoURI), model.WithSourceRef("refs/tags/v1.0.0")},
+		},
+		"other builder": {
+			options: []func(p *model.ProvenanceIR){model.WithTrustedBuilder("other"), model.WithSourceRef("refs/heads/main")},
+		},
+		"untagged release from another repository": {
+			options: []func(p *model.ProvenanceIR){model.WithTrustedBuilder(builderName), model.WithRepoURI("https://github.com/other/transparent-release"), model.WithSourceRef("refs/heads/main")},
+			want: []string{
+				`denied by tagged_releases.rego: unexpected repository "https://github.com/other/transparent-release"`,
+				`denied by tagged_releases.rego: untagged ref "refs/heads/main"`,
+			},
+		},
+	}
+	for name, test := range tests {
+		provenance := model.NewProvenanceIR(binaryDigest, slsav02.GenericSLSABuildType, binaryName, test.options...)
+		var got []string
+		for _, err := range multierr.Errors(policy.Evaluate(ctx, provenance)) {
+			got = append(got, err.Error())
+		}
+		if diff := cmp.Diff(test.want, got); diff != "" {
+			t.Errorf("%s: unexpected errors (-want +got):\n%s", name, diff)
+		}
+	}
+}
+
+func TestCheckRegoPolicy(t *testing.T) {
+	ctx := context.Background()
+	policy, err := ParseRegoPolicy(ctx, "tagged_releases.rego", taggedReleasesPolicy)
+	if err != nil {
+		t.Fatalf("could not parse the policy: %v", err)
+	}
+	provenances := []model.ProvenanceIR{
+		*model.NewProvenanceIR(binaryDigest, slsav02.GenericSLSABuildType, binaryName,
+			model.WithTrustedBuilder(builderName), model.WithRepoURI(repoURI), model.WithSourceRef("refs/tags/v1.0.0")),
+		*model.NewProvenanceIR(binaryDigest, slsav02.GenericSLSABuildType, binaryName,
+			model.WithTrustedBuilder(builderName), model.WithRepoURI(repoURI), model.WithSourceRef("refs/heads/main")),
+	}
+
+	report := &Report{Passed: true, Checks: []CheckResult{}}
+	report.CheckRegoPolicy(ctx, policy, provenances)
+
+	testutil.AssertEq(t, "passed", report.Passed, false)
+	want := []CheckResult{{
+		Name:    "rego_policy",
+		Options: `path:"tagged_releases.rego"`,
+		Errors:  []string{`#1: denied by tagged_releases.rego: untagged ref "refs/heads/main"`},
+	}}
+	if diff := cmp.Diff(want, report.Checks); diff != "" {
+		t.Errorf("unexpected checks (-want +got):\n%s", diff)
+	}
+}
+
+func TestParseRegoPolicy_Invalid(t *testing.T) {
+	tests := map[string]struct {
+		module string
+		want   string
+	}{
+		"syntax error":  {"package p\n\ndeny[msg] {", "could not parse the Rego policy"},
+		"no deny rule":  {"package p\n\nallow { true }", `does not define a "deny" rule`},
+		"compile error": {"package p\n\ndeny[msg] { msg := unknown_function(1) }", "could not compile the Rego policy"},
+	}
+	for name, test := range tests {
+		_, err := ParseRegoPolicy(context.Background(), "p.rego", test.module)
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%s: got error %v, want an error containing %q", name, err, test.want)
+		}
+	}
+}
@@ -18,101 +18,329 @@ import (
 	"encoding/hex"
 	"fmt"
 	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/project-oak/transparent-release/internal/model"
+	"github.com/project-oak/transparent-release/internal/roughtime"
+	"github.com/project-oak/transparent-release/pkg/claims"
+	"github.com/project-oak/transparent-release/pkg/intoto"
 	pb "github.com/project-oak/transparent-release/pkg/proto/oak/release"
 	"go.uber.org/multierr"
 	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
 )
 
 // Verify checks that the provenance conforms to expectations, returning a
-// list of errors whenever the verification failed.
+// list of errors whenever the verification failed. Failed checks are reported
+// as VerificationErrors; see ErrUntrustedBuilder and the other categories.
+func Verify(provenances []model.ProvenanceIR, verOpts *pb.VerificationOptions) error {
+	return VerifyWithReport(provenances, verOpts).Err()
+}
+
+// CheckResult is the outcome of a single check in a Report.
+type CheckResult struct {
+	// Name of the check, as the name of the field in VerificationOptions.
+	Name string `json:"name"`
+	// Options of the check, as textproto.
+	Options string `json:"options"`
+	Passed  bool   `json:"passed"`
+	// Errors describes the failures, if the check did not pass.
+	Errors []string `json:"errors,omitempty"`
+	// Categories lists the distinct categories of the failures, e.g.,
+	// "untrusted builder", as the messages of the Err* errors.
+	Categories []string `json:"categories,omitempty"`
+}
+
+// Report lists the checks performed by VerifyWithReport.
+type Report struct {
+	ProvenanceCount int           `json:"provenanceCount"`
+	Passed          bool          `json:"passed"`
+	Checks          []CheckResult `json:"checks"`
+	// IntegratedTime is the time at which the provenance was integrated into
+	// a Rekor log, if checked with CheckIntegratedTime.
+	IntegratedTime *time.Time `json:"integratedTime,omitempty"`
+	// TimeSource is the source of the time at which validity windows have
+	// been checked, if checked with CheckClaimValidity.
+	TimeSource *TimeSource `json:"timeSource,omitempty"`
+
+	errs error
+}
+
+// Kinds of time sources.
+const (
+	// SystemTimeSource is the local clock of the verifier.
+	SystemTimeSource = "system"
+	// RoughtimeTimeSource is a Roughtime server, which authenticated the time.
+	RoughtimeTimeSource = "roughtime"
+)
+
+// TimeSource describes the time at which validity windows are checked, and
+// where it came from.
+type TimeSource struct {
+	// Kind is SystemTimeSource or RoughtimeTimeSource.
+	Kind string `json:"kind"`
+	// Time is the time at which validity windows are checked.
+	Time time.Time `json:"time"`
+	// Server is the address of the Roughtime server.
+	Server string `json:"server,omitempty"`
+	// Radius is the uncertainty of the time reported by the Roughtime
+	// server, in nanoseconds. Time is the latest time within the radius.
+	Radius time.Duration `json:"radius,omitempty"`
+}
+
+// SystemTime returns a TimeSource for the current time of the local clock.
+func SystemTime() *TimeSource {
+	return &TimeSource{Kind: SystemTimeSource, Time: time.Now().UTC()}
+}
+
+// LoadRoughtimeToken loads the Roughtime token at the given path, and verifies
+// it with the given base64-encoded public key of the Roughtime server. The
+// returned TimeSource reports the latest time at which the server could have
+// signed the token, i.e., the midpoint plus the radius. This is not a lower
+// bound for the current time: as long as the token has been obtained with a
+// fresh nonce, the lower bound is the midpoint minus the radius.
+func LoadRoughtimeToken(path, publicKey string) (*TimeSource, error) {
+	key, err := roughtime.ParsePublicKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	token, err := roughtime.LoadToken(path)
+	if err != nil {
+		return nil, err
+	}
+	t, err := token.Verify(key)
+	if err != nil {
+		return nil, fmt.Errorf("could not verify the Roughtime token: %v", err)
+	}
+	return &TimeSource{Kind: RoughtimeTimeSource, Time: t.Latest(), Server: token.Server, Radius: t.Radius}, nil
+}
+
+// Now returns the time of the source, so that a TimeSource can be used as a
+// claims.Clock.
+func (s *TimeSource) Now() time.Time {
+	return s.Time
+}
+
+// Err returns the errors of all failed checks, or nil if all checks passed.
+func (r *Report) Err() error {
+	return r.errs
+}
+
+// AddCheck records the outcome of a check with the given name and options. A
+// check passes if errs is nil. It can be used for recording checks performed
+// outside of VerifyWithReport, e.g., against reference values.
+func (r *Report) AddCheck(name string, options proto.Message, errs error) {
+	textproto := ""
+	if options != nil {
+		if bytes, err := prototext.Marshal(options); err == nil {
+			textproto = string(bytes)
+		}
+	}
+	r.addCheck(name, textproto, errs)
+}
+
+// CheckIntegratedTime checks that the given provenance has been integrated
+// into a Rekor log at the given time, within maxSkew after the build
+// finished, and records the outcome in the report. A larger difference, in
+// either direction, indicates a backdated or replayed provenance.
+func (r *Report) CheckIntegratedTime(provenance model.ProvenanceIR, integratedTime time.Time, maxSkew time.Duration) {
+	r.IntegratedTime = &integratedTime
+	r.addCheck("build_finished_before_integrated_time", fmt.Sprintf("max_skew:%q", maxSkew), VerifyIntegratedTime(provenance, integratedTime, maxSkew))
+}
+
+// CheckClaimValidity checks that the given claim, e.g., an endorsement, is
+// valid at the time of the given source, and records the outcome and the time
+// source in the report.
+func (r *Report) CheckClaimValidity(statement *intoto.Statement, source *TimeSource) {
+	r.TimeSource = source
+	var errs error
+	status, err := claims.ClaimStatus(statement, claims.WithStatusClock(source))
+	switch {
+	case err != nil:
+		errs = err
+	case status != claims.StatusActive && status != claims.StatusExpiringSoon:
+		errs = fmt.Errorf("the claim is %s at %v (%s time)", status, source.Time, source.Kind)
+	}
+	r.addCheck("claim_validity", fmt.Sprintf("time:%q", source.Time.Format(time.RFC3339)), errs)
+}
+
+func (r *Report) addCheck(name, options string, errs error) {
+	result := CheckResult{Name: name, Options: options, Passed: errs == nil}
+	for _, err := range multierr.Errors(errs) {
+		result.Errors = append(result.Errors, err.Error())
+	}
+	result.Categories = errorCategories(multierr.Errors(errs))
+	r.Checks = append(r.Checks, result)
+	r.errs = multierr.Append(r.errs, errs)
+	r.Passed = r.errs == nil
+}
+
+// VerifyIntegratedTime checks that the build of the given provenance finished
+// at most maxSkew before, and at most maxSkew after, the given time at which
+// the provenance was integrated into a Rekor log.
+func VerifyIntegratedTime(provenance model.ProvenanceIR, integratedTime time.Time, maxSkew time.Duration) error {
+	finishedOn, err := provenance.BuildFinishedOn()
+	if err != nil {
+		return err
+	}
+	delay := integratedTime.Sub(finishedOn)
+	if delay < -maxSkew {
+		return fmt.Errorf("the build finished at %v, %v after the provenance was logged at %v", finishedOn, -delay, integratedTime)
+	}
+	if delay > maxSkew {
+		return fmt.Errorf("the provenance was logged at %v, %v after the build finished at %v, more than the maximum skew of %v", integratedTime, delay, finishedOn, maxSkew)
+	}
+	return nil
+}
+
+// VerifyWithReport checks that the provenance conforms to expectations, as
+// Verify, and returns a report listing every check performed and its outcome.
 //
 //nolint:cyclop,gocognit,gocyclo,maintidx
-func Verify(provenances []model.ProvenanceIR, verOpts *pb.VerificationOptions) error {
+func VerifyWithReport(provenances []model.ProvenanceIR, verOpts *pb.VerificationOptions) *Report {
 	if provenances == nil {
 		panic(fmt.Errorf("provenances must not be nil"))
 	}
 
-	var errs error
+	report := &Report{ProvenanceCount: len(provenances), Passed: true, Checks: []CheckResult{}}
+
+	// Deny-lists are checked first, so that they are reported before the
+	// allow-lists that the denied provenances may well pass.
+	if verOpts.NoneWithBuilderNames != nil {
+		var errs error
+		for index, provenance := range provenances {
+			builderName, err := provenance.TrustedBuilder()
+			if err != nil {
+				continue
+			}
+			for _, name := range verOpts.NoneWithBuilderNames.BuilderNames {
+				if builderName == name {
+					errs = multierr.Append(errs, newVerificationError(ErrDenied, index, verOpts.NoneWithBuilderNames.BuilderNames, builderName, "denied builder name in #%d: %q", index, builderName))
+					break
+				}
+			}
+		}
+		report.AddCheck("none_with_builder_names", verOpts.NoneWithBuilderNames, errs)
+	}
+
+	if verOpts.NoneWithRepositories != nil {
+		var errs error
+		for index, provenance := range provenances {
+			if !provenance.HasRepoURI() {
+				continue
+			}
+			for _, uri := range verOpts.NoneWithRepositories.RepositoryUris {
+				if provenance.RepoURI() == uri {
+					errs = multierr.Append(errs, newVerificationError(ErrDenied, index, verOpts.NoneWithRepositories.RepositoryUris, uri, "denied repository in #%d: %q", index, uri))
+					break
+				}
+			}
+		}
+		report.AddCheck("none_with_repositories", verOpts.NoneWithRepositories, errs)
+	}
+
+	if verOpts.NoneWithBinaryDigests != nil {
+		var errs error
+		for index, provenance := range provenances {
+			if digests := provenance.BinaryDigests(); matchesAnyOfDigests(digests, verOpts.NoneWithBinaryDigests.Digests) {
+				errs = multierr.Append(errs, newVerificationError(ErrDenied, index, verOpts.NoneWithBinaryDigests.Digests, digests, "denied binary digest in #%d: %v", index, digests))
+			}
+		}
+		report.AddCheck("none_with_binary_digests", verOpts.NoneWithBinaryDigests, errs)
+	}
+
+	if verOpts.NoneWithBuilderDigests != nil {
+		var errs error
+		for index, provenance := range provenances {
+			if digests := provenance.BuilderImageDigests(); matchesAnyOfDigests(digests, verOpts.NoneWithBuilderDigests.Digests) {
+				errs = multierr.Append(errs, newVerificationError(ErrDenied, index, verOpts.NoneWithBuilderDigests.Digests, digests, "denied builder digest in #%d: %v", index, digests))
+			}
+		}
+		report.AddCheck("none_with_builder_digests", verOpts.NoneWithBuilderDigests, errs)
+	}
 
-	if verOpts.ProvenanceCountAtLeast != nil && len(provenances) < int(verOpts.ProvenanceCountAtLeast.Count) {
-		errs = multierr.Append(errs, fmt.Errorf("too few provenances: have %d but want at least %d", len(provenances), verOpts.ProvenanceCountAtLeast.Count))
+	if verOpts.ProvenanceCountAtLeast != nil {
+		var errs error
+		if len(provenances) < int(verOpts.ProvenanceCountAtLeast.Count) {
+			errs = newVerificationError(ErrTooFewProvenances, AllProvenances, verOpts.ProvenanceCountAtLeast.Count, len(provenances), "too few provenances: have %d but want at least %d", len(provenances), verOpts.ProvenanceCountAtLeast.Count)
+		}
+		report.AddCheck("provenance_count_at_least", verOpts.ProvenanceCountAtLeast, errs)
 	}
 
-	if verOpts.ProvenanceCountAtMost != nil && len(provenances) > int(verOpts.ProvenanceCountAtMost.Count) {
-		errs = multierr.Append(errs, fmt.Errorf("too many provenances: have %d but want at most %d", len(provenances), verOpts.ProvenanceCountAtMost.Count))
+	if verOpts.ProvenanceCountAtMost != nil {
+		var errs error
+		if len(provenances) > int(verOpts.ProvenanceCountAtMost.Count) {
+			errs = newVerificationError(ErrTooManyProvenances, AllProvenances, verOpts.ProvenanceCountAtMost.Count, len(provenances), "too many provenances: have %d but want at most %d", len(provenances), verOpts.ProvenanceCountAtMost.Count)
+		}
+		report.AddCheck("provenance_count_at_most", verOpts.ProvenanceCountAtMost, errs)
 	}
 
-	if verOpts.AllSameBinaryName != nil && len(provenances) > 1 {
-		expectedBinaryName := provenances[0].BinaryName()
-		for _, p := range provenances {
-			if p.BinaryName() != expectedBinaryName {
-				errs = multierr.Append(errs, fmt.Errorf("not all have same binary name"))
+	if verOpts.AllSameBinaryName != nil {
+		var errs error
+		if len(provenances) > 1 {
+			expectedBinaryName := provenances[0].BinaryName()
+			for i, p := range provenances {
+				if p.BinaryName() != expectedBinaryName {
+					errs = multierr.Append(errs, newVerificationError(ErrBinaryNameMismatch, i, expectedBinaryName, p.BinaryName(), "not all have same binary name"))
+				}
 			}
 		}
+		report.AddCheck("all_same_binary_name", verOpts.AllSameBinaryName, errs)
 	}
 
-	if verOpts.AllSameBinaryDigest != nil && len(provenances) > 1 {
-		expectedDigest := provenances[0].BinarySHA256Digest()
-		for _, p := range provenances {
-			if p.BinarySHA256Digest() != expectedDigest {
-				errs = multierr.Append(errs, fmt.Errorf("not all have same SHA2-256 binary digest"))
+	if verOpts.AllSameBinaryDigest != nil {
+		var errs error
+		if len(provenances) > 1 {
+			expectedDigests := provenances[0].BinaryDigests()
+			for i, p := range provenances {
+				digests := p.BinaryDigests()
+				if !sharesAlgorithm(digests, expectedDigests) {
+					errs = multierr.Append(errs, newVerificationError(ErrBinaryDigestMismatch, i, expectedDigests, digests, "not all have same binary digest: #%d has digests with algorithms %v, and #0 with %v", i, model.DigestAlgorithms(digests), model.DigestAlgorithms(expectedDigests)))
+				} else if !model.SameDigests(digests, expectedDigests) {
+					errs = multierr.Append(errs, newVerificationError(ErrBinaryDigestMismatch, i, expectedDigests, digests, "not all have same binary digest: #%d differs from #0", i))
+				}
 			}
 		}
+		report.AddCheck("all_same_binary_digest", verOpts.AllSameBinaryDigest, errs)
 	}
 
 	if verOpts.AllWithBuildCommand != nil {
+		var errs error
 		for i, p := range provenances {
 			if buildCmd, err := p.BuildCmd(); err != nil || len(buildCmd) == 0 {
-				errs = multierr.Append(errs, fmt.Errorf("no build command found in #%d", i))
+				errs = multierr.Append(errs, newVerificationError(ErrMissingBuildCommand, i, nil, nil, "no build command found in #%d", i))
 			}
 		}
+		report.AddCheck("all_with_build_command", verOpts.AllWithBuildCommand, errs)
 	}
 
 	if verOpts.AllWithBinaryName != nil {
+		var errs error
 		for i, p := range provenances {
 			if p.BinaryName() != verOpts.AllWithBinaryName.BinaryName {
-				errs = multierr.Append(errs, fmt.Errorf("unexpected binary name in #%d: got %q but want %q", i, p.BinaryName(), verOpts.AllWithBinaryName.BinaryName))
+				errs = multierr.Append(errs, newVerificationError(ErrBinaryNameMismatch, i, verOpts.AllWithBinaryName.BinaryName, p.BinaryName(), "unexpected binary name in #%d: got %q but want %q", i, p.BinaryName(), verOpts.AllWithBinaryName.BinaryName))
 			}
 		}
+		report.AddCheck("all_with_binary_name", verOpts.AllWithBinaryName, errs)
 	}
 
-	//nolint:nestif
 	if verOpts.AllWithBinaryDigests != nil {
+		var errs error
 		for index, provenance := range provenances {
-			digest := provenance.BinarySHA256Digest()
-			found := false
-			for _, digests := range verOpts.AllWithBinaryDigests.Digests {
-				for f, d := range digests.Binary {
-					if f != int32(pb.Digest_SHA2_256) {
-						continue
-					}
-					if digest == hex.EncodeToString(d) {
-						found = true
-						break
-					}
-				}
-				if found {
-					break
-				}
-				for f, d := range digests.Hexadecimal {
-					if f != int32(pb.Digest_SHA2_256) {
-						continue
-					}
-					if digest == d {
-						found = true
-						break
-					}
-				}
-			}
-			if !found {
-				errs = multierr.Append(errs, fmt.Errorf("could not match binary digest in #%d: %q", index, digest))
+			if err := matchDigests(provenance.BinaryDigests(), verOpts.AllWithBinaryDigests.Digests, artifactDigestAlgorithms); err != nil {
+				errs = multierr.Append(errs, newVerificationError(ErrBinaryDigestMismatch, index, verOpts.AllWithBinaryDigests.Digests, provenance.BinaryDigests(), "could not match binary digest in #%d: %v", index, err))
 			}
 		}
+		report.AddCheck("all_with_binary_digests", verOpts.AllWithBinaryDigests, errs)
 	}
 
 	if verOpts.AllWithRepository != nil {
+		var errs error
 		expected := verOpts.AllWithRepository.RepositoryUri
 		for index, provenance := range provenances {
 			repoURI := ""
@@ -120,12 +348,14 @@ func Verify(provenances []model.ProvenanceIR, verOpts *pb.VerificationOptions) e
 				repoURI = provenance.RepoURI()
 			}
 			if repoURI != expected {
-				errs = multierr.Append(errs, fmt.Errorf("repository mismatch in #%d: got %q but want %q", index, repoURI, expected))
+				errs = multierr.Append(errs, newVerificationError(ErrRepositoryMismatch, index, expected, repoURI, "repository mismatch in #%d: got %q but want %q", index, repoURI, expected))
 			}
 		}
+		report.AddCheck("all_with_repository", verOpts.AllWithRepository, errs)
 	}
 
 	if verOpts.AllWithBuilderNames != nil {
+		var errs error
 		for index, provenance := range provenances {
 			buiilderName, err := provenance.TrustedBuilder()
 			if err != nil {
@@ -139,58 +369,491 @@ func Verify(provenances []model.ProvenanceIR, verOpts *pb.VerificationOptions) e
 				}
 			}
 			if !found {
-				errs = multierr.Append(errs, fmt.Errorf("could not match builder name in #%d: %q", index, buiilderName))
+				errs = multierr.Append(errs, newVerificationError(ErrUntrustedBuilder, index, verOpts.AllWithBuilderNames.BuilderNames, buiilderName, "could not match builder name in #%d: %q", index, buiilderName))
 			}
 		}
+		report.AddCheck("all_with_builder_names", verOpts.AllWithBuilderNames, errs)
 	}
 
-	//nolint:nestif
 	if verOpts.AllWithBuilderDigests != nil {
+		var errs error
 		for index, provenance := range provenances {
-			digest, err := provenance.BuilderImageSHA256Digest()
+			if err := matchDigests(provenance.BuilderImageDigests(), verOpts.AllWithBuilderDigests.Digests, artifactDigestAlgorithms); err != nil {
+				errs = multierr.Append(errs, newVerificationError(ErrBuilderDigestMismatch, index, verOpts.AllWithBuilderDigests.Digests, provenance.BuilderImageDigests(), "could not match builder digest in #%d: %v", index, err))
+			}
+		}
+		report.AddCheck("all_with_builder_digests", verOpts.AllWithBuilderDigests, errs)
+	}
+
+	if verOpts.AllWithCommitDigests != nil {
+		var errs error
+		for index, provenance := range provenances {
+			if err := matchDigests(provenance.CommitDigests(), verOpts.AllWithCommitDigests.Digests, gitDigestAlgorithms); err != nil {
+				errs = multierr.Append(errs, newVerificationError(ErrCommitDigestMismatch, index, verOpts.AllWithCommitDigests.Digests, provenance.CommitDigests(), "could not match commit digest in #%d: %v", index, err))
+			}
+		}
+		report.AddCheck("all_with_commit_digests", verOpts.AllWithCommitDigests, errs)
+	}
+
+	if verOpts.AllWithTreeDigests != nil {
+		var errs error
+		for index, provenance := range provenances {
+			if !provenance.HasTreeDigests() {
+				errs = multierr.Append(errs, newVerificationError(ErrTreeDigestMismatch, index, verOpts.AllWithTreeDigests.Digests, nil, "no tree digest in #%d", index))
+				continue
+			}
+			if err := matchDigests(provenance.TreeDigests(), verOpts.AllWithTreeDigests.Digests, gitDigestAlgorithms); err != nil {
+				errs = multierr.Append(errs, newVerificationError(ErrTreeDigestMismatch, index, verOpts.AllWithTreeDigests.Digests, provenance.TreeDigests(), "could not match tree digest in #%d: %v", index, err))
+			}
+		}
+		report.AddCheck("all_with_tree_digests", verOpts.AllWithTreeDigests, errs)
+	}
+
+	if verOpts.AllWithSourceRef != nil {
+		var errs error
+		for index, provenance := range provenances {
+			sourceRef, err := provenance.SourceRef()
 			if err != nil {
-				digest = ""
+				errs = multierr.Append(errs, newVerificationError(ErrSourceRefMismatch, index, verOpts.AllWithSourceRef.Patterns, nil, "no source ref in #%d", index))
+				continue
 			}
-			found := false
-			for _, digests := range verOpts.AllWithBuilderDigests.Digests {
-				for f, d := range digests.Binary {
-					if f != int32(pb.Digest_SHA2_256) {
-						continue
-					}
-					if digest == hex.EncodeToString(d) {
-						found = true
-						break
-					}
+			matched, err := matchesAnyPattern(sourceRef, verOpts.AllWithSourceRef.Patterns)
+			if err != nil {
+				errs = multierr.Append(errs, err)
+			} else if !matched {
+				errs = multierr.Append(errs, newVerificationError(ErrSourceRefMismatch, index, verOpts.AllWithSourceRef.Patterns, sourceRef, "could not match source ref in #%d: got %q but want one of %q", index, sourceRef, verOpts.AllWithSourceRef.Patterns))
+			}
+		}
+		report.AddCheck("all_with_source_ref", verOpts.AllWithSourceRef, errs)
+	}
+
+	if verOpts.AllWithBuildTime != nil {
+		after, afterErr := parseOptionalTime(verOpts.AllWithBuildTime.FinishedAfter)
+		before, beforeErr := parseOptionalTime(verOpts.AllWithBuildTime.FinishedBefore)
+		errs := multierr.Combine(afterErr, beforeErr)
+		// The provenances are only checked against a valid window.
+		if errs == nil {
+			for index, provenance := range provenances {
+				finishedOn, err := provenance.BuildFinishedOn()
+				if err != nil {
+					errs = multierr.Append(errs, newVerificationError(ErrBuildTimeOutOfRange, index, verOpts.AllWithBuildTime, nil, "no build finish time in #%d", index))
+					continue
 				}
-				if found {
-					break
+				if after != nil && finishedOn.Before(*after) {
+					errs = multierr.Append(errs, newVerificationError(ErrBuildTimeOutOfRange, index, verOpts.AllWithBuildTime, finishedOn, "the build in #%d finished at %v, before %v", index, finishedOn, *after))
 				}
-				for f, d := range digests.Hexadecimal {
-					if f != int32(pb.Digest_SHA2_256) {
-						continue
-					}
-					if digest == d {
-						found = true
-						break
-					}
+				if before != nil && finishedOn.After(*before) {
+					errs = multierr.Append(errs, newVerificationError(ErrBuildTimeOutOfRange, index, verOpts.AllWithBuildTime, finishedOn, "the build in #%d finished at %v, after %v", index, finishedOn, *before))
+				}
+			}
+		}
+		report.AddCheck("all_with_build_time", verOpts.AllWithBuildTime, errs)
+	}
+
+	if verOpts.AllWithByproducts != nil {
+		var errs error
+		for index, provenance := range provenances {
+			byproducts, err := provenance.Byproducts()
+			if err != nil {
+				errs = multierr.Append(errs, newVerificationError(ErrByproductMismatch, index, verOpts.AllWithByproducts.Byproducts, nil, "no byproducts in #%d", index))
+				continue
+			}
+			for _, required := range verOpts.AllWithByproducts.Byproducts {
+				if err := matchByproduct(byproducts, required); err != nil {
+					errs = multierr.Append(errs, newVerificationError(ErrByproductMismatch, index, required, byproducts, "could not match byproduct %q in #%d: %v", required.Name, index, err))
+				}
+			}
+		}
+		report.AddCheck("all_with_byproducts", verOpts.AllWithByproducts, errs)
+	}
+
+	if verOpts.AllWithBuildMetadata != nil {
+		var errs error
+		expected := verOpts.AllWithBuildMetadata
+		// Provenances without build metadata only fail if a claim is required.
+		requiresClaims := expected.ParametersComplete || expected.EnvironmentComplete || expected.MaterialsComplete || expected.Reproducible
+		for index, provenance := range provenances {
+			metadata, err := provenance.BuildMetadata()
+			if err != nil {
+				if requiresClaims {
+					errs = multierr.Append(errs, newVerificationError(ErrBuildMetadataMismatch, index, expected, nil, "no build metadata in #%d", index))
+				}
+				continue
+			}
+			if expected.ParametersComplete && !metadata.ParametersComplete {
+				errs = multierr.Append(errs, newVerificationError(ErrBuildMetadataMismatch, index, expected, metadata, "the builder does not claim complete parameters in #%d", index))
+			}
+			if expected.EnvironmentComplete && !metadata.EnvironmentComplete {
+				errs = multierr.Append(errs, newVerificationError(ErrBuildMetadataMismatch, index, expected, metadata, "the builder does not claim a complete environment in #%d", index))
+			}
+			if expected.MaterialsComplete && !metadata.MaterialsComplete {
+				errs = multierr.Append(errs, newVerificationError(ErrBuildMetadataMismatch, index, expected, metadata, "the builder does not claim complete materials in #%d", index))
+			}
+			if expected.Reproducible && !metadata.Reproducible {
+				errs = multierr.Append(errs, newVerificationError(ErrBuildMetadataMismatch, index, expected, metadata, "the builder does not claim a reproducible build in #%d", index))
+			}
+		}
+		report.AddCheck("all_with_build_metadata", verOpts.AllWithBuildMetadata, errs)
+	}
+
+	if verOpts.AllWithBuildTypes != nil {
+		var errs error
+		for index, provenance := range provenances {
+			buildType := provenance.BuildType()
+			found := false
+			for _, expected := range verOpts.AllWithBuildTypes.BuildTypes {
+				if buildType == expected {
+					found = true
+					break
 				}
 			}
 			if !found {
-				errs = multierr.Append(errs, fmt.Errorf("could not match builder digest in #%d: %q", index, digest))
+				errs = multierr.Append(errs, newVerificationError(ErrBuildTypeMismatch, index, verOpts.AllWithBuildTypes.BuildTypes, buildType, "could not match build type in #%d: %q", index, buildType))
 			}
 		}
+		report.AddCheck("all_with_build_types", verOpts.AllWithBuildTypes, errs)
 	}
 
+	if verOpts.AllWithCertificateIdentity != nil {
+		var errs error
+		expected := verOpts.AllWithCertificateIdentity
+		for index, provenance := range provenances {
+			identity, err := provenance.CertificateIdentity()
+			if err != nil {
+				errs = multierr.Append(errs, newVerificationError(ErrCertificateIdentityMismatch, index, expected, nil, "no verified certificate identity in #%d", index))
+				continue
+			}
+			if identity.SubjectAlternativeName != expected.SubjectAlternativeName {
+				errs = multierr.Append(errs, newVerificationError(ErrCertificateIdentityMismatch, index, expected.SubjectAlternativeName, identity.SubjectAlternativeName, "subject alternative name mismatch in #%d: got %q but want %q", index, identity.SubjectAlternativeName, expected.SubjectAlternativeName))
+			}
+			if identity.Issuer != expected.Issuer {
+				errs = multierr.Append(errs, newVerificationError(ErrCertificateIdentityMismatch, index, expected.Issuer, identity.Issuer, "issuer mismatch in #%d: got %q but want %q", index, identity.Issuer, expected.Issuer))
+			}
+			if expected.BuildConfigUri != "" && identity.BuildConfigURI != expected.BuildConfigUri {
+				errs = multierr.Append(errs, newVerificationError(ErrCertificateIdentityMismatch, index, expected.BuildConfigUri, identity.BuildConfigURI, "build config URI mismatch in #%d: got %q but want %q", index, identity.BuildConfigURI, expected.BuildConfigUri))
+			}
+			if expected.SourceRepositoryRef != "" && identity.SourceRepositoryRef != expected.SourceRepositoryRef {
+				errs = multierr.Append(errs, newVerificationError(ErrCertificateIdentityMismatch, index, expected.SourceRepositoryRef, identity.SourceRepositoryRef, "source repository ref mismatch in #%d: got %q but want %q", index, identity.SourceRepositoryRef, expected.SourceRepositoryRef))
+			}
+			if expected.SourceRepositoryOwnerUri != "" && identity.SourceRepositoryOwnerURI != expected.SourceRepositoryOwnerUri {
+				errs = multierr.Append(errs, newVerificationError(ErrCertificateIdentityMismatch, index, expected.SourceRepositoryOwnerUri, identity.SourceRepositoryOwnerURI, "source repository owner mismatch in #%d: got %q but want %q", index, identity.SourceRepositoryOwnerURI, expected.SourceRepositoryOwnerUri))
+			}
+		}
+		report.AddCheck("all_with_certificate_identity", verOpts.AllWithCertificateIdentity, errs)
+	}
+
+	if verOpts.AllWithMinimumToolchainVersions != nil {
+		var errs error
+		minimumVersions := verOpts.AllWithMinimumToolchainVersions.MinimumVersions
+		// Sort the toolchains, so that errors are reported in a stable order.
+		names := make([]string, 0, len(minimumVersions))
+		for name := range minimumVersions {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for index, provenance := range provenances {
+			versions, err := provenance.ToolchainVersions()
+			if err != nil {
+				errs = multierr.Append(errs, newVerificationError(ErrToolchainVersionTooOld, index, minimumVersions, nil, "no toolchain versions in #%d", index))
+				continue
+			}
+			for _, name := range names {
+				version, ok := versions[name]
+				if !ok {
+					errs = multierr.Append(errs, newVerificationError(ErrToolchainVersionTooOld, index, minimumVersions[name], nil, "no version of %q in #%d", name, index))
+					continue
+				}
+				order, err := compareVersions(version, minimumVersions[name])
+				if err != nil {
+					errs = multierr.Append(errs, fmt.Errorf("could not compare versions of %q in #%d: %v", name, index, err))
+				} else if order < 0 {
+					errs = multierr.Append(errs, newVerificationError(ErrToolchainVersionTooOld, index, minimumVersions[name], version, "version of %q in #%d is too old: got %q but want at least %q", name, index, version, minimumVersions[name]))
+				}
+			}
+		}
+		report.AddCheck("all_with_minimum_toolchain_versions", verOpts.AllWithMinimumToolchainVersions, errs)
+	}
+
+	return report
+}
+
+// digestAlgorithms maps the digest types in VerificationOptions to the
+// canonical keys of digest sets in ProvenanceIR.
+//
+//nolint:gochecknoglobals
+var digestAlgorithms = map[int32]string{
+	int32(pb.Digest_SHA1):     "sha1",
+	int32(pb.Digest_SHA2_256): "sha2-256",
+	int32(pb.Digest_SHA2_384): "sha2-384",
+	int32(pb.Digest_SHA2_512): "sha2-512",
+	int32(pb.Digest_SHA3_224): "sha3-224",
+	int32(pb.Digest_SHA3_256): "sha3-256",
+	int32(pb.Digest_SHA3_384): "sha3-384",
+	int32(pb.Digest_SHA3_512): "sha3-512",
+}
+
+// DigestFromDigestSet converts the digests with supported algorithms in the
+// given digest set to a Digest with hexadecimal values, for use in
+// VerificationOptions.
+func DigestFromDigestSet(digestSet map[string]string) *pb.Digest {
+	digest := &pb.Digest{Hexadecimal: make(map[int32]string)}
+	for f, algorithm := range digestAlgorithms {
+		if d, ok := digestSet[algorithm]; ok {
+			digest.Hexadecimal[f] = d
+		}
+	}
+	return digest
+}
+
+// artifactDigestAlgorithms are the algorithms of digests that pin an artifact,
+// i.e., SHA-2 and SHA-3. SHA1 digests are only compared if present in both
+// digests, since they are not collision resistant.
+//
+//nolint:gochecknoglobals
+var artifactDigestAlgorithms = map[string]bool{
+	"sha2-256": true,
+	"sha2-384": true,
+	"sha2-512": true,
+	"sha3-224": true,
+	"sha3-256": true,
+	"sha3-384": true,
+	"sha3-512": true,
+}
+
+// gitDigestAlgorithms are the algorithms of digests that pin a Git object,
+// which are SHA1 digests in most repositories.
+//
+//nolint:gochecknoglobals
+var gitDigestAlgorithms = map[string]bool{
+	"sha1":     true,
+	"sha2-256": true,
+}
+
+// matchDigests returns an error unless the given digest set matches any of the
+// given Digests, as in matchesDigest, with the given pinning algorithms. It
+// fails closed, with an error naming the algorithms involved, if the digest
+// set is empty, or if it shares no supported algorithm with any of the
+// Digests, so that such cases are not mistaken for comparisons of empty
+// digests.
+func matchDigests(digestSet map[string]string, digests []*pb.Digest, pinning map[string]bool) error {
+	if len(digestSet) == 0 {
+		return fmt.Errorf("the provenance has no digest with a supported algorithm")
+	}
+	expected := make(map[string]string)
+	for _, d := range digests {
+		if matchesDigest(digestSet, d, pinning) {
+			return nil
+		}
+		for algorithm := range expectedDigests(d) {
+			expected[algorithm] = ""
+		}
+	}
+	if !sharesAlgorithm(digestSet, expected) {
+		return fmt.Errorf("the provenance has digests with algorithms %v, but the expected digests have algorithms %v", model.DigestAlgorithms(digestSet), model.DigestAlgorithms(expected))
+	}
+	return fmt.Errorf("no expected digest matches %v", digestSet)
+}
+
+// matchesAnyOfDigests returns true if the given digest set has the same SHA-2
+// or SHA-3 digest as any of the given Digests. Unlike matchDigests, it does
+// not fail closed, and ignores conflicting digests, since it is used for
+// deny-lists.
+func matchesAnyOfDigests(digestSet map[string]string, digests []*pb.Digest) bool {
+	for _, d := range digests {
+		for algorithm, value := range expectedDigests(d) {
+			if artifactDigestAlgorithms[algorithm] && strings.ToLower(digestSet[algorithm]) == value {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sharesAlgorithm returns true if the given digest sets have a digest with the
+// same algorithm.
+func sharesAlgorithm(a, b map[string]string) bool {
+	for algorithm := range a {
+		if _, ok := b[algorithm]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesDigest returns true if the given digest set and the given Digest
+// have the same digest for at least one of the given pinning algorithms, and
+// no different digests for any algorithm supported in both.
+func matchesDigest(digestSet map[string]string, digest *pb.Digest, pinning map[string]bool) bool {
+	matched := false
+	for algorithm, value := range expectedDigests(digest) {
+		got, ok := digestSet[algorithm]
+		if !ok {
+			continue
+		}
+		if strings.ToLower(got) != value {
+			return false
+		}
+		matched = matched || pinning[algorithm]
+	}
+	return matched
+}
+
+// expectedDigests returns the digests with supported algorithms in the given
+// Digest, as lowercase hex-encoded values keyed by their canonical algorithm.
+// If the Digest has different binary and hexadecimal values for an algorithm,
+// the value is empty, so that it matches no digest.
+func expectedDigests(digest *pb.Digest) map[string]string {
+	digests := make(map[string]string)
+	add := func(f int32, value string) {
+		algorithm, ok := digestAlgorithms[f]
+		if !ok {
+			return
+		}
+		if previous, ok := digests[algorithm]; ok && previous != value {
+			value = ""
+		}
+		digests[algorithm] = value
+	}
+	for f, d := range digest.Binary {
+		add(f, hex.EncodeToString(d))
+	}
+	for f, d := range digest.Hexadecimal {
+		add(f, strings.ToLower(d))
+	}
+	return digests
+}
+
+// matchesAnyPattern returns true if the given value matches any of the given
+// patterns, as in path.Match, or an error if a pattern is malformed.
+func matchesAnyPattern(value string, patterns []string) (bool, error) {
+	for _, pattern := range patterns {
+		matched, err := path.Match(pattern, value)
+		if err != nil {
+			return false, fmt.Errorf("invalid pattern %q: %v", pattern, err)
+		}
+		if matched {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// matchByproduct returns an error unless one of the given byproducts has the
+// name of the required byproduct and a digest, which matches one of the
+// required digests, if any.
+func matchByproduct(byproducts []model.Byproduct, required *pb.RequiredByproduct) error {
+	var errs error
+	for _, byproduct := range byproducts {
+		if byproduct.Name != required.Name {
+			continue
+		}
+		if len(byproduct.Digests) == 0 {
+			errs = multierr.Append(errs, fmt.Errorf("the byproduct has no digest with a supported algorithm"))
+			continue
+		}
+		if len(required.Digests) == 0 {
+			return nil
+		}
+		err := matchDigests(byproduct.Digests, required.Digests, artifactDigestAlgorithms)
+		if err == nil {
+			return nil
+		}
+		errs = multierr.Append(errs, err)
+	}
+	if errs == nil {
+		return fmt.Errorf("no byproduct with this name")
+	}
 	return errs
 }
 
-// LoadVerificationOptions loads VerificationOptions from a textproto file.
+// parseOptionalTime parses the given time in RFC 3339 format, or returns nil
+// if it is empty.
+func parseOptionalTime(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time %q: %v", value, err)
+	}
+	return &parsed, nil
+}
+
+// compareVersions compares two versions, such as `1.70.0`, by their numeric
+// components, where missing components are zero. Build metadata after a `+`
+// is ignored, and a version with a pre-release suffix after a `-`, such as
+// `1.70.0-nightly`, is lower than the release itself. Returns a negative
+// number, zero, or a positive number if a is lower than, equal to, or higher
+// than b.
+func compareVersions(a, b string) (int, error) {
+	aParts, aPreRelease, err := parseVersion(a)
+	if err != nil {
+		return 0, err
+	}
+	bParts, bPreRelease, err := parseVersion(b)
+	if err != nil {
+		return 0, err
+	}
+	for i := 0; i < len(aParts) || i < len(bParts); i++ {
+		var x, y int
+		if i < len(aParts) {
+			x = aParts[i]
+		}
+		if i < len(bParts) {
+			y = bParts[i]
+		}
+		if x != y {
+			return x - y, nil
+		}
+	}
+	switch {
+	case aPreRelease && !bPreRelease:
+		return -1, nil
+	case !aPreRelease && bPreRelease:
+		return 1, nil
+	default:
+		return 0, nil
+	}
+}
+
+// parseVersion returns the numeric components of the given version, and
+// whether it has a pre-release suffix.
+func parseVersion(version string) ([]int, bool, error) {
+	release := version
+	if i := strings.Index(release, "+"); i >= 0 {
+		release = release[:i]
+	}
+	preRelease := false
+	if i := strings.Index(release, "-"); i >= 0 {
+		release = release[:i]
+		preRelease = true
+	}
+	parts := strings.Split(release, ".")
+	numbers := make([]int, 0, len(parts))
+	for _, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return nil, false, fmt.Errorf("invalid version %q", version)
+		}
+		numbers = append(numbers, n)
+	}
+	return numbers, preRelease, nil
+}
+
+// LoadVerificationOptions loads VerificationOptions from a file, which is
+// parsed as YAML if it has a `.yaml` or `.yml` extension, and as textproto
+// otherwise.
 func LoadVerificationOptions(path string) (*pb.VerificationOptions, error) {
 	bytes, err := os.ReadFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("reading file from %q: %v", path, err)
 	}
-	return ParseVerificationOptions(string(bytes))
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return ParseVerificationOptionsYAML(bytes)
+	default:
+		return ParseVerificationOptions(string(bytes))
+	}
 }
 
 // LoadVerificationOptions parses VerificationOptions from textproto.
@@ -15,20 +15,29 @@
 package verifier
 
 import (
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/project-oak/transparent-release/internal/model"
+	"github.com/project-oak/transparent-release/internal/testutil"
+	"github.com/project-oak/transparent-release/pkg/claims"
+	"github.com/project-oak/transparent-release/pkg/intoto"
 	slsav02 "github.com/project-oak/transparent-release/pkg/intoto/slsa_provenance/v0.2"
 	pb "github.com/project-oak/transparent-release/pkg/proto/oak/release"
 )
 
 const (
-	binaryName    = "test.txt-9b5f98310dbbad675834474fa68c37d880687cb9"
-	binaryDigest  = "322527c0260e25f0e9a2595bd0d71a52294fe2397a7af76165190fd98de8920d"
-	builderName   = "https://github.com/slsa-framework/slsa-github-generator/.github/workflows/generator_generic_slsa3.yml@refs/tags/v1.2.0"
-	builderDigest = "9e2ba52487d945504d250de186cb4fe2e3ba023ed2921dd6ac8b97ed43e76af9"
-	repoURI       = "https://github.com/project-oak/transparent-release"
-	otherRepoURI  = "git+https://github.com/project-oak/oak@refs/heads/main"
+	binaryName       = "test.txt-9b5f98310dbbad675834474fa68c37d880687cb9"
+	binaryDigest     = "322527c0260e25f0e9a2595bd0d71a52294fe2397a7af76165190fd98de8920d"
+	builderName      = "https://github.com/slsa-framework/slsa-github-generator/.github/workflows/generator_generic_slsa3.yml@refs/tags/v1.2.0"
+	builderDigest    = "9e2ba52487d945504d250de186cb4fe2e3ba023ed2921dd6ac8b97ed43e76af9"
+	repoURI          = "https://github.com/project-oak/transparent-release"
+	otherRepoURI     = "git+https://github.com/project-oak/oak@refs/heads/main"
+	workflowURI      = "https://github.com/project-oak/transparent-release/.github/workflows/build.yml@refs/heads/main"
+	githubIssuer     = "https://token.actions.githubusercontent.com"
+	buildConfigURI   = "https://github.com/project-oak/transparent-release/.github/workflows/release.yml@refs/heads/main"
+	commitSHA1Digest = "1b128fb2556e4bdcc4f92552654bfbca9d2fb8c6"
 )
 
 func TestVerify_ProvenancesNilPanics(t *testing.T) {
@@ -262,6 +271,89 @@ func TestVerify_BinaryDigestMismatchDetected(t *testing.T) {
 	}
 }
 
+func TestVerify_BinaryDigestOtherAlgorithmMatchSucceeds(t *testing.T) {
+	provenance := model.NewProvenanceIR("", slsav02.GenericSLSABuildType, binaryName,
+		model.WithBinaryDigests(intoto.DigestSet{"sha2-512": "abcdef"}))
+	provenances := []model.ProvenanceIR{*provenance}
+	verOpts := pb.VerificationOptions{
+		AllWithBinaryDigests: &pb.VerifyAllWithBinaryDigests{
+			Digests: []*pb.Digest{
+				{Hexadecimal: map[int32]string{int32(pb.Digest_SHA2_256): binaryDigest, int32(pb.Digest_SHA2_512): "ABCDEF"}},
+			},
+		},
+	}
+
+	if err := Verify(provenances, &verOpts); err != nil {
+		t.Fatalf("verify failed: %v", err)
+	}
+}
+
+func TestVerify_BinaryDigestConflictingAlgorithmDetected(t *testing.T) {
+	provenance := model.NewProvenanceIR("", slsav02.GenericSLSABuildType, binaryName,
+		model.WithBinaryDigests(intoto.DigestSet{"sha2-256": binaryDigest, "sha2-512": "abcdef"}))
+	provenances := []model.ProvenanceIR{*provenance}
+	verOpts := pb.VerificationOptions{
+		AllWithBinaryDigests: &pb.VerifyAllWithBinaryDigests{
+			Digests: []*pb.Digest{
+				{Hexadecimal: map[int32]string{int32(pb.Digest_SHA2_256): binaryDigest, int32(pb.Digest_SHA2_512): "012345"}},
+			},
+		},
+	}
+
+	if err := Verify(provenances, &verOpts); err == nil {
+		t.Fatalf("expected failure")
+	}
+}
+
+func TestVerify_BinaryDigestSHA1OnlyDetected(t *testing.T) {
+	provenance := model.NewProvenanceIR("", slsav02.GenericSLSABuildType, binaryName,
+		model.WithBinaryDigests(intoto.DigestSet{"sha1": commitSHA1Digest, "sha2-256": binaryDigest}))
+	provenances := []model.ProvenanceIR{*provenance}
+	verOpts := pb.VerificationOptions{
+		AllWithBinaryDigests: &pb.VerifyAllWithBinaryDigests{
+			Digests: []*pb.Digest{
+				{Hexadecimal: map[int32]string{int32(pb.Digest_SHA1): commitSHA1Digest}},
+			},
+		},
+	}
+
+	if err := Verify(provenances, &verOpts); err == nil {
+		t.Fatalf("expected failure")
+	}
+}
+
+func TestVerify_BinaryDigestNoSharedAlgorithmDetected(t *testing.T) {
+	provenance := model.NewProvenanceIR("", slsav02.GenericSLSABuildType, binaryName,
+		model.WithBinaryDigests(intoto.DigestSet{"sha2-512": "abcdef"}))
+	provenances := []model.ProvenanceIR{*provenance}
+	verOpts := pb.VerificationOptions{
+		AllWithBinaryDigests: &pb.VerifyAllWithBinaryDigests{
+			Digests: []*pb.Digest{
+				{Hexadecimal: map[int32]string{int32(pb.Digest_SHA2_256): "abcdef"}},
+			},
+		},
+	}
+
+	if err := Verify(provenances, &verOpts); err == nil {
+		t.Fatalf("expected failure")
+	}
+}
+
+func TestVerify_SameBinaryDigestOtherAlgorithmFails(t *testing.T) {
+	provenance1 := model.NewProvenanceIR(binaryDigest, slsav02.GenericSLSABuildType, binaryName,
+		model.WithBinaryDigests(intoto.DigestSet{"sha2-256": binaryDigest, "sha2-512": "abcdef"}))
+	provenance2 := model.NewProvenanceIR("", slsav02.GenericSLSABuildType, binaryName,
+		model.WithBinaryDigests(intoto.DigestSet{"sha2-512": "012345"}))
+	provenances := []model.ProvenanceIR{*provenance1, *provenance2}
+	verOpts := pb.VerificationOptions{
+		AllSameBinaryDigest: &pb.VerifyAllSameBinaryDigest{},
+	}
+
+	if err := Verify(provenances, &verOpts); err == nil {
+		t.Fatalf("expected failure")
+	}
+}
+
 func TestVerify_BuilderNameMatchSucceeds(t *testing.T) {
 	provenance := model.NewProvenanceIR(binaryDigest, slsav02.GenericSLSABuildType, binaryName, model.WithTrustedBuilder(builderName))
 	provenances := []model.ProvenanceIR{*provenance}
@@ -347,6 +439,101 @@ func TestVerify_BuilderDigestMismatchDetected(t *testing.T) {
 	}
 }
 
+func TestVerify_BuilderDigestOtherAlgorithmsMatchSucceeds(t *testing.T) {
+	sha512Digest := strings.Repeat("ab", 64)
+	sha3Digest := strings.Repeat("cd", 32)
+	provenance := model.NewProvenanceIR(binaryDigest, slsav02.GenericSLSABuildType, binaryName,
+		model.WithBuilderImageDigests(intoto.DigestSet{"sha2-512": sha512Digest, "sha3-256": sha3Digest}))
+	provenances := []model.ProvenanceIR{*provenance}
+
+	for _, digest := range []*pb.Digest{
+		{Hexadecimal: map[int32]string{int32(pb.Digest_SHA2_512): sha512Digest}},
+		{Hexadecimal: map[int32]string{int32(pb.Digest_SHA3_256): sha3Digest}},
+	} {
+		verOpts := pb.VerificationOptions{
+			AllWithBuilderDigests: &pb.VerifyAllWithBuilderDigests{Digests: []*pb.Digest{digest}},
+		}
+		if err := Verify(provenances, &verOpts); err != nil {
+			t.Errorf("expected success for %v: %v", digest, err)
+		}
+	}
+
+	// Digests with other algorithms do not match.
+	verOpts := pb.VerificationOptions{
+		AllWithBuilderDigests: &pb.VerifyAllWithBuilderDigests{
+			Digests: []*pb.Digest{{Hexadecimal: map[int32]string{int32(pb.Digest_SHA3_512): sha512Digest}}},
+		},
+	}
+	if err := Verify(provenances, &verOpts); err == nil {
+		t.Errorf("expected error")
+	}
+}
+
+func TestVerify_BinaryDigestSHA3MatchSucceeds(t *testing.T) {
+	sha3Digest := strings.Repeat("ef", 48)
+	provenance := model.NewProvenanceIR("", slsav02.GenericSLSABuildType, binaryName,
+		model.WithBinaryDigests(intoto.DigestSet{"sha3-384": sha3Digest}))
+	provenances := []model.ProvenanceIR{*provenance}
+	verOpts := pb.VerificationOptions{
+		AllWithBinaryDigests: &pb.VerifyAllWithBinaryDigests{
+			Digests: []*pb.Digest{{Hexadecimal: map[int32]string{int32(pb.Digest_SHA3_384): strings.ToUpper(sha3Digest)}}},
+		},
+	}
+
+	if err := Verify(provenances, &verOpts); err != nil {
+		t.Fatalf("verify failed: %v", err)
+	}
+}
+
+func TestVerify_DigestWithoutSharedAlgorithmFails(t *testing.T) {
+	// The provenance only has a SHA512 digest, and must not be compared with
+	// the SHA256 digests in the options as if its SHA256 digest were empty.
+	provenance := model.NewProvenanceIR("", slsav02.GenericSLSABuildType, binaryName,
+		model.WithBinaryDigests(intoto.DigestSet{"sha2-512": strings.Repeat("ab", 64)}),
+		model.WithBuilderImageDigests(intoto.DigestSet{"sha2-512": strings.Repeat("cd", 64)}))
+	provenances := []model.ProvenanceIR{*provenance}
+	verOpts := pb.VerificationOptions{
+		AllWithBinaryDigests: &pb.VerifyAllWithBinaryDigests{
+			Digests: []*pb.Digest{{Hexadecimal: map[int32]string{int32(pb.Digest_SHA2_256): ""}}},
+		},
+		AllWithBuilderDigests: &pb.VerifyAllWithBuilderDigests{
+			Digests: []*pb.Digest{{Hexadecimal: map[int32]string{int32(pb.Digest_SHA2_256): builderDigest}}},
+		},
+	}
+
+	report := VerifyWithReport(provenances, &verOpts)
+	if report.Passed {
+		t.Fatalf("expected failure")
+	}
+	for _, check := range report.Checks {
+		if !strings.Contains(strings.Join(check.Errors, "; "), "algorithms [sha2-512]") {
+			t.Errorf("expected %s to name the algorithms of the provenance, got: %v", check.Name, check.Errors)
+		}
+	}
+}
+
+func TestVerify_NoSupportedDigestFails(t *testing.T) {
+	provenance := model.NewProvenanceIR("", slsav02.GenericSLSABuildType, binaryName,
+		model.WithBinaryDigests(intoto.DigestSet{}))
+	provenances := []model.ProvenanceIR{*provenance}
+	verOpts := pb.VerificationOptions{
+		AllWithBinaryDigests: &pb.VerifyAllWithBinaryDigests{
+			Digests: []*pb.Digest{{Hexadecimal: map[int32]string{int32(pb.Digest_SHA2_256): binaryDigest}}},
+		},
+		AllWithBuilderDigests: &pb.VerifyAllWithBuilderDigests{
+			Digests: []*pb.Digest{{Hexadecimal: map[int32]string{int32(pb.Digest_SHA2_256): builderDigest}}},
+		},
+	}
+
+	report := VerifyWithReport(provenances, &verOpts)
+	testutil.AssertEq(t, "checks", len(report.Checks), 2)
+	for _, check := range report.Checks {
+		if !strings.Contains(strings.Join(check.Errors, "; "), "no digest with a supported algorithm") {
+			t.Errorf("expected %s to fail closed, got: %v", check.Name, check.Errors)
+		}
+	}
+}
+
 func TestVerify_BuilderDigestEmptyOk(t *testing.T) {
 	provenance := model.NewProvenanceIR(binaryDigest, slsav02.GenericSLSABuildType, binaryName, model.WithBuilderImageSHA256Digest(""))
 	provenances := []model.ProvenanceIR{*provenance}
@@ -393,3 +580,677 @@ func TestVerify_RepoURIEmptyMismatchDetected(t *testing.T) {
 		t.Fatalf("expected failure")
 	}
 }
+
+func TestVerify_CommitDigestMatchSucceeds(t *testing.T) {
+	provenance := model.NewProvenanceIR(binaryDigest, slsav02.GenericSLSABuildType, binaryName,
+		model.WithCommitSHA1Digest(commitSHA1Digest))
+	provenances := []model.ProvenanceIR{*provenance}
+	verOpts := pb.VerificationOptions{
+		AllWithCommitDigests: &pb.VerifyAllWithCommitDigests{
+			Digests: []*pb.Digest{
+				{Hexadecimal: map[int32]string{int32(pb.Digest_SHA1): "some_digest"}},
+				{Hexadecimal: map[int32]string{int32(pb.Digest_SHA1): commitSHA1Digest}},
+			},
+		},
+	}
+
+	if err := Verify(provenances, &verOpts); err != nil {
+		t.Fatalf("verify failed, got %v", err)
+	}
+}
+
+func TestVerify_CommitDigestMismatchDetected(t *testing.T) {
+	provenance := model.NewProvenanceIR(binaryDigest, slsav02.GenericSLSABuildType, binaryName,
+		model.WithCommitSHA1Digest(commitSHA1Digest))
+	provenances := []model.ProvenanceIR{*provenance}
+	verOpts := pb.VerificationOptions{
+		AllWithCommitDigests: &pb.VerifyAllWithCommitDigests{
+			Digests: []*pb.Digest{{Hexadecimal: map[int32]string{int32(pb.Digest_SHA1): strings.Repeat("0", 40)}}},
+		},
+	}
+
+	if err := Verify(provenances, &verOpts); err == nil {
+		t.Fatalf("expected failure")
+	}
+}
+
+func TestVerify_CommitDigestSHA256ObjectFormatSucceeds(t *testing.T) {
+	sha256Digest := strings.Repeat("cd", 32)
+	provenance := model.NewProvenanceIR(binaryDigest, slsav02.GenericSLSABuildType, binaryName,
+		model.WithCommitSHA1Digest(""),
+		model.WithCommitDigests(intoto.DigestSet{"sha2-256": sha256Digest}))
+	provenances := []model.ProvenanceIR{*provenance}
+	verOpts := pb.VerificationOptions{
+		AllWithCommitDigests: &pb.VerifyAllWithCommitDigests{
+			Digests: []*pb.Digest{{Hexadecimal: map[int32]string{int32(pb.Digest_SHA2_256): sha256Digest}}},
+		},
+	}
+
+	if err := Verify(provenances, &verOpts); err != nil {
+		t.Fatalf("verify failed, got %v", err)
+	}
+
+	// A SHA1 digest cannot be compared with the SHA256 commit digest.
+	verOpts.AllWithCommitDigests.Digests = []*pb.Digest{{Hexadecimal: map[int32]string{int32(pb.Digest_SHA1): commitSHA1Digest}}}
+	if err := Verify(provenances, &verOpts); err == nil || !strings.Contains(err.Error(), "algorithms") {
+		t.Fatalf("expected a failure naming the algorithms, got %v", err)
+	}
+}
+
+func TestVerify_CommitDigestMissingDetected(t *testing.T) {
+	provenance := model.NewProvenanceIR(binaryDigest, slsav02.GenericSLSABuildType, binaryName)
+	provenances := []model.ProvenanceIR{*provenance}
+	verOpts := pb.VerificationOptions{
+		AllWithCommitDigests: &pb.VerifyAllWithCommitDigests{
+			Digests: []*pb.Digest{{Hexadecimal: map[int32]string{int32(pb.Digest_SHA1): commitSHA1Digest}}},
+		},
+	}
+
+	if err := Verify(provenances, &verOpts); err == nil {
+		t.Fatalf("expected failure")
+	}
+}
+
+func TestVerify_TreeDigests(t *testing.T) {
+	treeDigest := "4b825dc642cb6eb9a060e54bf8d69288fbee4904"
+	withTree := model.NewProvenanceIR(binaryDigest, slsav02.GenericSLSABuildType, binaryName,
+		model.WithCommitSHA1Digest(commitSHA1Digest),
+		model.WithTreeDigests(intoto.DigestSet{"sha1": treeDigest}))
+	withoutTree := model.NewProvenanceIR(binaryDigest, slsav02.GenericSLSABuildType, binaryName,
+		model.WithCommitSHA1Digest(commitSHA1Digest))
+	verOpts := pb.VerificationOptions{
+		AllWithTreeDigests: &pb.VerifyAllWithTreeDigests{
+			Digests: []*pb.Digest{
+				{Hexadecimal: map[int32]string{int32(pb.Digest_SHA1): strings.Repeat("0", 40)}},
+				{Hexadecimal: map[int32]string{int32(pb.Digest_SHA1): treeDigest}},
+			},
+		},
+	}
+
+	if err := Verify([]model.ProvenanceIR{*withTree}, &verOpts); err != nil {
+		t.Fatalf("verify failed, got %v", err)
+	}
+	// The commit digest is not a tree digest.
+	if err := Verify([]model.ProvenanceIR{*withTree, *withoutTree}, &verOpts); err == nil || !strings.Contains(err.Error(), "no tree digest in #1") {
+		t.Fatalf("expected a missing tree digest, got %v", err)
+	}
+	verOpts.AllWithTreeDigests.Digests = []*pb.Digest{{Hexadecimal: map[int32]string{int32(pb.Digest_SHA1): commitSHA1Digest}}}
+	if err := Verify([]model.ProvenanceIR{*withTree}, &verOpts); err == nil || !strings.Contains(err.Error(), "could not match tree digest in #0") {
+		t.Fatalf("expected a tree digest mismatch, got %v", err)
+	}
+}
+
+func TestVerify_SourceRefMatchSucceeds(t *testing.T) {
+	provenance := model.NewProvenanceIR(binaryDigest, slsav02.GenericSLSABuildType, binaryName,
+		model.WithSourceRef("refs/tags/v1.2.3"))
+	provenances := []model.ProvenanceIR{*provenance}
+
+	for _, patterns := range [][]string{
+		{"refs/tags/v1.2.3"},
+		{"refs/heads/main", "refs/tags/v*"},
+	} {
+		verOpts := pb.VerificationOptions{
+			AllWithSourceRef: &pb.VerifyAllWithSourceRef{Patterns: patterns},
+		}
+		if err := Verify(provenances, &verOpts); err != nil {
+			t.Errorf("verify failed for %q, got %v", patterns, err)
+		}
+	}
+}
+
+func TestVerify_SourceRefMismatchDetected(t *testing.T) {
+	provenance := model.NewProvenanceIR(binaryDigest, slsav02.GenericSLSABuildType, binaryName,
+		model.WithSourceRef("refs/heads/feature"))
+	provenances := []model.ProvenanceIR{*provenance}
+
+	for _, patterns := range [][]string{
+		{"refs/heads/main", "refs/tags/v*"},
+		// `*` does not match `/`.
+		{"refs/*"},
+		{},
+	} {
+		verOpts := pb.VerificationOptions{
+			AllWithSourceRef: &pb.VerifyAllWithSourceRef{Patterns: patterns},
+		}
+		if err := Verify(provenances, &verOpts); err == nil {
+			t.Errorf("expected failure for %q", patterns)
+		}
+	}
+}
+
+func TestVerify_SourceRefMissingDetected(t *testing.T) {
+	provenance := model.NewProvenanceIR(binaryDigest, slsav02.GenericSLSABuildType, binaryName)
+	provenances := []model.ProvenanceIR{*provenance}
+	verOpts := pb.VerificationOptions{
+		AllWithSourceRef: &pb.VerifyAllWithSourceRef{Patterns: []string{"*"}},
+	}
+
+	if err := Verify(provenances, &verOpts); err == nil {
+		t.Fatalf("expected failure")
+	}
+}
+
+func TestVerify_SourceRefInvalidPatternDetected(t *testing.T) {
+	provenance := model.NewProvenanceIR(binaryDigest, slsav02.GenericSLSABuildType, binaryName,
+		model.WithSourceRef("refs/tags/v1.2.3"))
+	provenances := []model.ProvenanceIR{*provenance}
+	verOpts := pb.VerificationOptions{
+		AllWithSourceRef: &pb.VerifyAllWithSourceRef{Patterns: []string{"refs/tags/[v"}},
+	}
+
+	if err := Verify(provenances, &verOpts); err == nil || !strings.Contains(err.Error(), "invalid pattern") {
+		t.Fatalf("expected an invalid pattern, got %v", err)
+	}
+}
+
+func TestVerify_BuildTimeWithinWindowSucceeds(t *testing.T) {
+	finishedOn := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	provenance := model.NewProvenanceIR(binaryDigest, slsav02.GenericSLSABuildType, binaryName,
+		model.WithBuildFinishedOn(finishedOn))
+	provenances := []model.ProvenanceIR{*provenance}
+
+	for _, window := range []*pb.VerifyAllWithBuildTime{
+		{FinishedAfter: "2023-06-01T00:00:00Z", FinishedBefore: "2023-06-02T00:00:00Z"},
+		{FinishedAfter: "2023-06-01T12:00:00Z"},
+		{FinishedBefore: "2023-06-01T14:00:00+02:00"},
+		{},
+	} {
+		verOpts := pb.VerificationOptions{AllWithBuildTime: window}
+		if err := Verify(provenances, &verOpts); err != nil {
+			t.Errorf("verify failed for %v, got %v", window, err)
+		}
+	}
+}
+
+func TestVerify_BuildTimeOutsideWindowDetected(t *testing.T) {
+	finishedOn := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	provenance := model.NewProvenanceIR(binaryDigest, slsav02.GenericSLSABuildType, binaryName,
+		model.WithBuildFinishedOn(finishedOn))
+	provenances := []model.ProvenanceIR{*provenance}
+
+	for _, window := range []*pb.VerifyAllWithBuildTime{
+		{FinishedAfter: "2023-06-02T00:00:00Z"},
+		{FinishedBefore: "2023-06-01T00:00:00Z"},
+		{FinishedAfter: "2023-06-01T12:00:01Z", FinishedBefore: "2023-06-02T00:00:00Z"},
+	} {
+		verOpts := pb.VerificationOptions{AllWithBuildTime: window}
+		if err := Verify(provenances, &verOpts); err == nil {
+			t.Errorf("expected failure for %v", window)
+		}
+	}
+}
+
+func TestVerify_BuildTimeMissingDetected(t *testing.T) {
+	provenance := model.NewProvenanceIR(binaryDigest, slsav02.GenericSLSABuildType, binaryName)
+	provenances := []model.ProvenanceIR{*provenance}
+	verOpts := pb.VerificationOptions{
+		AllWithBuildTime: &pb.VerifyAllWithBuildTime{FinishedAfter: "2023-06-01T00:00:00Z"},
+	}
+
+	if err := Verify(provenances, &verOpts); err == nil {
+		t.Fatalf("expected failure")
+	}
+}
+
+func TestVerify_BuildTimeInvalidWindowDetected(t *testing.T) {
+	provenance := model.NewProvenanceIR(binaryDigest, slsav02.GenericSLSABuildType, binaryName,
+		model.WithBuildFinishedOn(time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)))
+	provenances := []model.ProvenanceIR{*provenance}
+	verOpts := pb.VerificationOptions{
+		AllWithBuildTime: &pb.VerifyAllWithBuildTime{FinishedAfter: "2023-06-01"},
+	}
+
+	if err := Verify(provenances, &verOpts); err == nil || !strings.Contains(err.Error(), "invalid time") {
+		t.Fatalf("expected an invalid time, got %v", err)
+	}
+}
+
+func TestVerify_ByproductsMatchSucceeds(t *testing.T) {
+	buildLog := model.Byproduct{Name: "build.log", Digests: intoto.DigestSet{"sha2-256": binaryDigest}}
+	provenance := model.NewProvenanceIR(binaryDigest, slsav02.GenericSLSABuildType, binaryName,
+		model.WithByproducts([]model.Byproduct{buildLog}))
+	provenances := []model.ProvenanceIR{*provenance}
+
+	for _, required := range []*pb.RequiredByproduct{
+		{Name: "build.log"},
+		{Name: "build.log", Digests: []*pb.Digest{{Hexadecimal: map[int32]string{int32(pb.Digest_SHA2_256): binaryDigest}}}},
+	} {
+		verOpts := pb.VerificationOptions{
+			AllWithByproducts: &pb.VerifyAllWithByproducts{Byproducts: []*pb.RequiredByproduct{required}},
+		}
+		if err := Verify(provenances, &verOpts); err != nil {
+			t.Errorf("verify failed for %v, got %v", required, err)
+		}
+	}
+}
+
+func TestVerify_ByproductsMismatchDetected(t *testing.T) {
+	buildLog := model.Byproduct{Name: "build.log", Digests: intoto.DigestSet{"sha2-256": binaryDigest}}
+	unhashed := model.Byproduct{Name: "test.log", URI: "https://example.com/test.log", Digests: intoto.DigestSet{}}
+	provenance := model.NewProvenanceIR(binaryDigest, slsav02.GenericSLSABuildType, binaryName,
+		model.WithByproducts([]model.Byproduct{buildLog, unhashed}))
+	provenances := []model.ProvenanceIR{*provenance}
+
+	for _, required := range []*pb.RequiredByproduct{
+		{Name: "sbom.json"},
+		// A byproduct without a digest does not show what has been retained.
+		{Name: "test.log"},
+		{Name: "build.log", Digests: []*pb.Digest{{Hexadecimal: map[int32]string{int32(pb.Digest_SHA2_256): builderDigest}}}},
+	} {
+		verOpts := pb.VerificationOptions{
+			AllWithByproducts: &pb.VerifyAllWithByproducts{Byproducts: []*pb.RequiredByproduct{required}},
+		}
+		if err := Verify(provenances, &verOpts); err == nil {
+			t.Errorf("expected failure for %v", required)
+		}
+	}
+}
+
+func TestVerify_ByproductsMissingDetected(t *testing.T) {
+	provenance := model.NewProvenanceIR(binaryDigest, slsav02.GenericSLSABuildType, binaryName)
+	provenances := []model.ProvenanceIR{*provenance}
+	verOpts := pb.VerificationOptions{
+		AllWithByproducts: &pb.VerifyAllWithByproducts{Byproducts: []*pb.RequiredByproduct{{Name: "build.log"}}},
+	}
+
+	if err := Verify(provenances, &verOpts); err == nil {
+		t.Fatalf("expected failure")
+	}
+}
+
+func TestVerify_BuildMetadataClaimsSucceed(t *testing.T) {
+	provenance := model.NewProvenanceIR(binaryDigest, slsav02.GenericSLSABuildType, binaryName,
+		model.WithBuildMetadata(model.BuildMetadata{InvocationID: "3230206088-1", ParametersComplete: true, Reproducible: true}))
+	provenances := []model.ProvenanceIR{*provenance}
+	verOpts := pb.VerificationOptions{
+		AllWithBuildMetadata: &pb.VerifyAllWithBuildMetadata{ParametersComplete: true, Reproducible: true},
+	}
+
+	if err := Verify(provenances, &verOpts); err != nil {
+		t.Fatalf("verify failed, got %v", err)
+	}
+}
+
+func TestVerify_BuildMetadataClaimsMissingDetected(t *testing.T) {
+	provenance := model.NewProvenanceIR(binaryDigest, slsav02.GenericSLSABuildType, binaryName,
+		model.WithBuildMetadata(model.BuildMetadata{InvocationID: "3230206088-1", ParametersComplete: true}))
+	withoutMetadata := model.NewProvenanceIR(binaryDigest, slsav02.GenericSLSABuildType, binaryName)
+
+	for _, provenance := range []*model.ProvenanceIR{provenance, withoutMetadata} {
+		verOpts := pb.VerificationOptions{
+			AllWithBuildMetadata: &pb.VerifyAllWithBuildMetadata{ParametersComplete: true, MaterialsComplete: true},
+		}
+		if err := Verify([]model.ProvenanceIR{*provenance}, &verOpts); err == nil {
+			t.Errorf("expected failure for %v", provenance.Export())
+		}
+	}
+}
+
+func TestVerify_BuildMetadataNoClaimsSucceeds(t *testing.T) {
+	withoutMetadata := model.NewProvenanceIR(binaryDigest, slsav02.GenericSLSABuildType, binaryName)
+	verOpts := pb.VerificationOptions{
+		AllWithBuildMetadata: &pb.VerifyAllWithBuildMetadata{},
+	}
+
+	if err := Verify([]model.ProvenanceIR{*withoutMetadata}, &verOpts); err != nil {
+		t.Fatalf("verify failed, got %v", err)
+	}
+}
+
+func TestVerify_CertificateIdentityMatchSucceeds(t *testing.T) {
+	identity := model.CertificateIdentity{SubjectAlternativeName: workflowURI, Issuer: githubIssuer}
+	provenance := model.NewProvenanceIR(binaryDigest, slsav02.GenericSLSABuildType, binaryName,
+		model.WithCertificateIdentity(identity))
+	provenances := []model.ProvenanceIR{*provenance}
+	verOpts := pb.VerificationOptions{
+		AllWithCertificateIdentity: &pb.VerifyAllWithCertificateIdentity{SubjectAlternativeName: workflowURI, Issuer: githubIssuer},
+	}
+
+	if err := Verify(provenances, &verOpts); err != nil {
+		t.Fatalf("verify failed, got %v", err)
+	}
+}
+
+func TestVerify_CertificateIdentityMismatchDetected(t *testing.T) {
+	identity := model.CertificateIdentity{SubjectAlternativeName: workflowURI + "-fork", Issuer: githubIssuer}
+	provenance := model.NewProvenanceIR(binaryDigest, slsav02.GenericSLSABuildType, binaryName,
+		model.WithCertificateIdentity(identity))
+	provenances := []model.ProvenanceIR{*provenance}
+	verOpts := pb.VerificationOptions{
+		AllWithCertificateIdentity: &pb.VerifyAllWithCertificateIdentity{SubjectAlternativeName: workflowURI, Issuer: githubIssuer},
+	}
+
+	if err := Verify(provenances, &verOpts); err == nil {
+		t.Fatalf("expected failure")
+	}
+}
+
+func TestVerify_CertificateIdentityConstraints(t *testing.T) {
+	identity := model.CertificateIdentity{
+		SubjectAlternativeName:   workflowURI,
+		Issuer:                   githubIssuer,
+		BuildConfigURI:           buildConfigURI,
+		SourceRepositoryRef:      "refs/heads/main",
+		SourceRepositoryOwnerURI: "https://github.com/project-oak",
+	}
+	provenance := model.NewProvenanceIR(binaryDigest, slsav02.GenericSLSABuildType, binaryName,
+		model.WithCertificateIdentity(identity))
+	provenances := []model.ProvenanceIR{*provenance}
+
+	for name, tc := range map[string]struct {
+		expected *pb.VerifyAllWithCertificateIdentity
+		wantErr  string
+	}{
+		"all set": {
+			expected: &pb.VerifyAllWithCertificateIdentity{
+				SubjectAlternativeName:   workflowURI,
+				Issuer:                   githubIssuer,
+				BuildConfigUri:           buildConfigURI,
+				SourceRepositoryRef:      "refs/heads/main",
+				SourceRepositoryOwnerUri: "https://github.com/project-oak",
+			},
+		},
+		"unset constraints are not checked": {
+			expected: &pb.VerifyAllWithCertificateIdentity{SubjectAlternativeName: workflowURI, Issuer: githubIssuer},
+		},
+		"other build config": {
+			expected: &pb.VerifyAllWithCertificateIdentity{SubjectAlternativeName: workflowURI, Issuer: githubIssuer, BuildConfigUri: buildConfigURI + "-fork"},
+			wantErr:  "build config URI mismatch",
+		},
+		"other ref": {
+			expected: &pb.VerifyAllWithCertificateIdentity{SubjectAlternativeName: workflowURI, Issuer: githubIssuer, SourceRepositoryRef: "refs/heads/release"},
+			wantErr:  "source repository ref mismatch",
+		},
+		"other owner": {
+			expected: &pb.VerifyAllWithCertificateIdentity{SubjectAlternativeName: workflowURI, Issuer: githubIssuer, SourceRepositoryOwnerUri: "https://github.com/attacker"},
+			wantErr:  "source repository owner mismatch",
+		},
+	} {
+		err := Verify(provenances, &pb.VerificationOptions{AllWithCertificateIdentity: tc.expected})
+		switch {
+		case tc.wantErr == "" && err != nil:
+			t.Errorf("%s: verify failed, got %v", name, err)
+		case tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)):
+			t.Errorf("%s: got %v, want an error containing %q", name, err, tc.wantErr)
+		}
+	}
+}
+
+func TestVerify_CertificateIdentityMissingDetected(t *testing.T) {
+	// NB: Unverified provenances have no certificate identity.
+	provenance := model.NewProvenanceIR(binaryDigest, slsav02.GenericSLSABuildType, binaryName)
+	provenances := []model.ProvenanceIR{*provenance}
+	verOpts := pb.VerificationOptions{
+		AllWithCertificateIdentity: &pb.VerifyAllWithCertificateIdentity{SubjectAlternativeName: workflowURI, Issuer: githubIssuer},
+	}
+
+	if err := Verify(provenances, &verOpts); err == nil {
+		t.Fatalf("expected failure")
+	}
+}
+
+func TestVerify_DeniedBuilderNameDetected(t *testing.T) {
+	provenance := model.NewProvenanceIR(binaryDigest, slsav02.GenericSLSABuildType, binaryName, model.WithTrustedBuilder(builderName))
+	provenances := []model.ProvenanceIR{*provenance}
+	verOpts := pb.VerificationOptions{
+		NoneWithBuilderNames: &pb.VerifyNoneWithBuilderNames{
+			BuilderNames: []string{"other_" + builderName, builderName},
+		},
+	}
+
+	if err := Verify(provenances, &verOpts); err == nil {
+		t.Fatalf("expected failure")
+	}
+}
+
+func TestVerify_DeniedRepositoryDetected(t *testing.T) {
+	provenance := model.NewProvenanceIR(binaryDigest, slsav02.GenericSLSABuildType, binaryName, model.WithRepoURI(repoURI))
+	provenances := []model.ProvenanceIR{*provenance}
+	verOpts := pb.VerificationOptions{
+		NoneWithRepositories: &pb.VerifyNoneWithRepositories{RepositoryUris: []string{repoURI}},
+	}
+
+	if err := Verify(provenances, &verOpts); err == nil {
+		t.Fatalf("expected failure")
+	}
+}
+
+func TestVerify_DeniedBinaryDigestDetected(t *testing.T) {
+	provenance := model.NewProvenanceIR(binaryDigest, slsav02.GenericSLSABuildType, binaryName)
+	provenances := []model.ProvenanceIR{*provenance}
+	verOpts := pb.VerificationOptions{
+		NoneWithBinaryDigests: &pb.VerifyNoneWithBinaryDigests{
+			Digests: []*pb.Digest{{Hexadecimal: map[int32]string{int32(pb.Digest_SHA2_256): strings.ToUpper(binaryDigest)}}},
+		},
+	}
+
+	if err := Verify(provenances, &verOpts); err == nil {
+		t.Fatalf("expected failure")
+	}
+}
+
+func TestVerify_DeniedBuilderDigestDetected(t *testing.T) {
+	provenance := model.NewProvenanceIR(binaryDigest, slsav02.GenericSLSABuildType, binaryName, model.WithBuilderImageSHA256Digest(builderDigest))
+	provenances := []model.ProvenanceIR{*provenance}
+	verOpts := pb.VerificationOptions{
+		NoneWithBuilderDigests: &pb.VerifyNoneWithBuilderDigests{
+			Digests: []*pb.Digest{{Hexadecimal: map[int32]string{int32(pb.Digest_SHA2_256): builderDigest}}},
+		},
+	}
+
+	if err := Verify(provenances, &verOpts); err == nil {
+		t.Fatalf("expected failure")
+	}
+}
+
+func TestVerify_DenyListsWithoutMatchSucceed(t *testing.T) {
+	provenance := model.NewProvenanceIR(binaryDigest, slsav02.GenericSLSABuildType, binaryName,
+		model.WithTrustedBuilder(builderName), model.WithRepoURI(repoURI), model.WithBuilderImageSHA256Digest(builderDigest))
+	// Provenances without a builder, repository, or builder digest are not
+	// denied either.
+	provenances := []model.ProvenanceIR{*provenance, *model.NewProvenanceIR(binaryDigest, slsav02.GenericSLSABuildType, binaryName)}
+	otherDigest := []*pb.Digest{{Hexadecimal: map[int32]string{int32(pb.Digest_SHA2_256): strings.Repeat("ab", 32)}}}
+	verOpts := pb.VerificationOptions{
+		NoneWithBuilderNames:   &pb.VerifyNoneWithBuilderNames{BuilderNames: []string{"other_" + builderName}},
+		NoneWithRepositories:   &pb.VerifyNoneWithRepositories{RepositoryUris: []string{otherRepoURI}},
+		NoneWithBinaryDigests:  &pb.VerifyNoneWithBinaryDigests{Digests: otherDigest},
+		NoneWithBuilderDigests: &pb.VerifyNoneWithBuilderDigests{Digests: otherDigest},
+	}
+
+	if err := Verify(provenances, &verOpts); err != nil {
+		t.Fatalf("verify failed, got %v", err)
+	}
+}
+
+func TestVerifyWithReport_DenyListsCheckedFirst(t *testing.T) {
+	provenance := model.NewProvenanceIR(binaryDigest, slsav02.GenericSLSABuildType, binaryName, model.WithTrustedBuilder(builderName))
+	provenances := []model.ProvenanceIR{*provenance}
+	verOpts := pb.VerificationOptions{
+		ProvenanceCountAtLeast: &pb.VerifyProvenanceCountAtLeast{Count: 1},
+		AllWithBuilderNames:    &pb.VerifyAllWithBuilderNames{BuilderNames: []string{builderName}},
+		NoneWithBuilderNames:   &pb.VerifyNoneWithBuilderNames{BuilderNames: []string{builderName}},
+	}
+
+	report := VerifyWithReport(provenances, &verOpts)
+	if report.Passed {
+		t.Fatalf("expected failure")
+	}
+	var names []string
+	for _, check := range report.Checks {
+		names = append(names, check.Name)
+	}
+	testutil.AssertEq(t, "checks", strings.Join(names, ","), "none_with_builder_names,provenance_count_at_least,all_with_builder_names")
+}
+
+func TestVerifyWithReport_ListsEveryCheck(t *testing.T) {
+	provenance := model.NewProvenanceIR(binaryDigest, slsav02.GenericSLSABuildType, binaryName)
+	provenances := []model.ProvenanceIR{*provenance}
+	verOpts := pb.VerificationOptions{
+		ProvenanceCountAtLeast: &pb.VerifyProvenanceCountAtLeast{Count: 1},
+		AllWithBinaryName: &pb.VerifyAllWithBinaryName{
+			BinaryName: builderName, /* sic */
+		},
+	}
+
+	report := VerifyWithReport(provenances, &verOpts)
+	if report.Passed || report.Err() == nil {
+		t.Fatalf("expected failure")
+	}
+	if report.ProvenanceCount != 1 {
+		t.Errorf("unexpected provenance count: got %d, want 1", report.ProvenanceCount)
+	}
+	if len(report.Checks) != 2 {
+		t.Fatalf("unexpected number of checks: got %d, want 2", len(report.Checks))
+	}
+	if check := report.Checks[0]; check.Name != "provenance_count_at_least" || !check.Passed || len(check.Errors) != 0 {
+		t.Errorf("unexpected result of the first check: %+v", check)
+	}
+	if check := report.Checks[1]; check.Name != "all_with_binary_name" || check.Passed || len(check.Errors) != 1 || check.Options == "" {
+		t.Errorf("unexpected result of the second check: %+v", check)
+	}
+}
+
+func TestVerifyWithReport_EmptyVerificationPasses(t *testing.T) {
+	report := VerifyWithReport([]model.ProvenanceIR{}, &pb.VerificationOptions{})
+	if !report.Passed || report.Err() != nil || len(report.Checks) != 0 {
+		t.Errorf("unexpected report: %+v", report)
+	}
+}
+
+func TestVerifyIntegratedTime(t *testing.T) {
+	finishedOn := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	provenance := model.NewProvenanceIR(binaryDigest, slsav02.GenericSLSABuildType, binaryName, model.WithBuildFinishedOn(finishedOn))
+	tests := map[time.Duration]bool{
+		// Logged shortly after the build finished.
+		5 * time.Minute: true,
+		// The clock of the builder is slightly ahead of the clock of the log.
+		-time.Minute: true,
+		// Backdated or replayed.
+		2 * time.Hour:  false,
+		-2 * time.Hour: false,
+	}
+	for delay, wantPassed := range tests {
+		err := VerifyIntegratedTime(*provenance, finishedOn.Add(delay), time.Hour)
+		if (err == nil) != wantPassed {
+			t.Errorf("unexpected outcome for a delay of %v: got %v, want passed=%v", delay, err, wantPassed)
+		}
+	}
+}
+
+func TestReport_CheckIntegratedTimeWithoutFinishTimeFails(t *testing.T) {
+	provenance := model.NewProvenanceIR(binaryDigest, slsav02.GenericSLSABuildType, binaryName)
+	report := VerifyWithReport([]model.ProvenanceIR{*provenance}, &pb.VerificationOptions{})
+	integratedTime := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	report.CheckIntegratedTime(*provenance, integratedTime, time.Hour)
+	if report.Passed || len(report.Checks) != 1 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if report.IntegratedTime == nil || !report.IntegratedTime.Equal(integratedTime) {
+		t.Errorf("unexpected integrated time in the report: %v", report.IntegratedTime)
+	}
+}
+
+func TestReport_CheckClaimValidity(t *testing.T) {
+	notBefore := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+	notAfter := notBefore.Add(30 * 24 * time.Hour)
+	endorsement := claims.GenerateEndorsementStatement(
+		claims.ClaimValidity{NotBefore: &notBefore, NotAfter: &notAfter},
+		claims.VerifiedProvenanceSet{BinaryName: binaryName, Digests: intoto.DigestSet{"sha256": binaryDigest}})
+	tests := map[time.Time]bool{
+		notBefore.Add(time.Hour):   true,
+		notBefore.Add(-time.Hour):  false,
+		notAfter.Add(time.Hour):    false,
+		notAfter.Add(-time.Minute): true,
+	}
+	for now, wantPassed := range tests {
+		source := &TimeSource{Kind: RoughtimeTimeSource, Time: now, Server: "roughtime.example.com:2002", Radius: time.Second}
+		report := VerifyWithReport([]model.ProvenanceIR{}, &pb.VerificationOptions{})
+
+		report.CheckClaimValidity(endorsement, source)
+		if report.Passed != wantPassed || len(report.Checks) != 1 || report.Checks[0].Name != "claim_validity" {
+			t.Errorf("unexpected report at %v: %+v", now, report)
+		}
+		if report.TimeSource != source {
+			t.Errorf("unexpected time source in the report: %+v", report.TimeSource)
+		}
+	}
+}
+
+func TestVerify_MinimumToolchainVersionsSucceeds(t *testing.T) {
+	provenance := model.NewProvenanceIR(binaryDigest, slsav02.GenericSLSABuildType, binaryName,
+		model.WithToolchainVersions(map[string]string{"rustc": "1.70.1", "cargo": "1.70.0"}))
+	provenances := []model.ProvenanceIR{*provenance}
+	verOpts := pb.VerificationOptions{
+		AllWithMinimumToolchainVersions: &pb.VerifyAllWithMinimumToolchainVersions{
+			MinimumVersions: map[string]string{"rustc": "1.70", "cargo": "1.70.0"},
+		},
+	}
+
+	if err := Verify(provenances, &verOpts); err != nil {
+		t.Fatalf("verify failed, got %v", err)
+	}
+}
+
+func TestVerify_MinimumToolchainVersionsDetectsOldVersion(t *testing.T) {
+	provenance := model.NewProvenanceIR(binaryDigest, slsav02.GenericSLSABuildType, binaryName,
+		model.WithToolchainVersions(map[string]string{"rustc": "1.69.0"}))
+	provenances := []model.ProvenanceIR{*provenance}
+	verOpts := pb.VerificationOptions{
+		AllWithMinimumToolchainVersions: &pb.VerifyAllWithMinimumToolchainVersions{
+			MinimumVersions: map[string]string{"rustc": "1.70.0"},
+		},
+	}
+
+	report := VerifyWithReport(provenances, &verOpts)
+	if report.Passed {
+		t.Fatalf("expected failure")
+	}
+	want := `version of "rustc" in #0 is too old: got "1.69.0" but want at least "1.70.0"`
+	testutil.AssertEq(t, "errors", strings.Join(report.Checks[0].Errors, "\n"), want)
+}
+
+func TestVerify_MinimumToolchainVersionsMissingDetected(t *testing.T) {
+	withoutVersions := model.NewProvenanceIR(binaryDigest, slsav02.GenericSLSABuildType, binaryName)
+	withOtherToolchain := model.NewProvenanceIR(binaryDigest, slsav02.GenericSLSABuildType, binaryName,
+		model.WithToolchainVersions(map[string]string{"go": "1.20.5"}))
+	provenances := []model.ProvenanceIR{*withoutVersions, *withOtherToolchain}
+	verOpts := pb.VerificationOptions{
+		AllWithMinimumToolchainVersions: &pb.VerifyAllWithMinimumToolchainVersions{
+			MinimumVersions: map[string]string{"rustc": "1.70.0"},
+		},
+	}
+
+	report := VerifyWithReport(provenances, &verOpts)
+	if report.Passed {
+		t.Fatalf("expected failure")
+	}
+	testutil.AssertEq(t, "number of errors", len(report.Checks[0].Errors), 2)
+}
+
+func TestCompareVersions(t *testing.T) {
+	for _, tc := range []struct {
+		a, b string
+		want int
+	}{
+		{"1.70.0", "1.70.0", 0},
+		{"1.70", "1.70.0", 0},
+		{"1.70.0+build.1", "1.70.0", 0},
+		{"1.69.9", "1.70.0", -1},
+		{"1.100.0", "1.99.0", 1},
+		{"1.70.0-nightly", "1.70.0", -1},
+		{"1.70.1-nightly", "1.70.0", 1},
+	} {
+		got, err := compareVersions(tc.a, tc.b)
+		if err != nil {
+			t.Fatalf("could not compare %q and %q: %v", tc.a, tc.b, err)
+		}
+		if (got < 0) != (tc.want < 0) || (got > 0) != (tc.want > 0) {
+			t.Errorf("compareVersions(%q, %q) = %d, want sign of %d", tc.a, tc.b, got, tc.want)
+		}
+	}
+
+	if _, err := compareVersions("stable", "1.70.0"); err == nil {
+		t.Errorf("expected failure for a non-numeric version")
+	}
+}
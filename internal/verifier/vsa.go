@@ -0,0 +1,196 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verifier
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/project-oak/transparent-release/internal/model"
+	"github.com/project-oak/transparent-release/pkg/claims"
+	"github.com/project-oak/transparent-release/pkg/intoto"
+	pb "github.com/project-oak/transparent-release/pkg/proto/oak/release"
+)
+
+// VerificationSummaryV1 is the predicate type of SLSA Verification Summary
+// Attestations (VSAs); see https://slsa.dev/verification_summary/v1.
+const VerificationSummaryV1 = "https://slsa.dev/verification_summary/v1"
+
+// DefaultVerifierID is the default ID of the verifier in generated VSAs.
+const DefaultVerifierID = "https://github.com/project-oak/transparent-release/cmd/verifier"
+
+// Results of a verification, as recorded in a VSA.
+const (
+	VerificationPassed = "PASSED"
+	VerificationFailed = "FAILED"
+)
+
+// SLSA Build levels, as recorded in a VSA.
+const (
+	SLSABuildLevel1 = "SLSA_BUILD_LEVEL_1"
+	SLSABuildLevel2 = "SLSA_BUILD_LEVEL_2"
+	SLSABuildLevel3 = "SLSA_BUILD_LEVEL_3"
+)
+
+// VerificationSummary is the predicate of a VSA.
+type VerificationSummary struct {
+	Verifier           VSAVerifier             `json:"verifier"`
+	TimeVerified       time.Time               `json:"timeVerified"`
+	ResourceURI        string                  `json:"resourceUri"`
+	Policy             VSAResourceDescriptor   `json:"policy"`
+	InputAttestations  []VSAResourceDescriptor `json:"inputAttestations,omitempty"`
+	VerificationResult string                  `json:"verificationResult"`
+	// VerifiedLevels lists the highest SLSA Build level verified, if any.
+	VerifiedLevels []string `json:"verifiedLevels"`
+	SLSAVersion    string   `json:"slsaVersion"`
+}
+
+// VSAVerifier identifies the verifier that generated a VSA.
+type VSAVerifier struct {
+	ID      string            `json:"id"`
+	Version map[string]string `json:"version,omitempty"`
+}
+
+// VSAResourceDescriptor identifies a policy or an attestation in a VSA.
+type VSAResourceDescriptor struct {
+	URI    string           `json:"uri,omitempty"`
+	Digest intoto.DigestSet `json:"digest"`
+}
+
+// VSAConfig holds optional settings for generating a VSA.
+type VSAConfig struct {
+	verifierID        string
+	verifierVersion   map[string]string
+	resourceURI       string
+	policy            *VSAResourceDescriptor
+	inputAttestations []VSAResourceDescriptor
+	clock             claims.Clock
+}
+
+// WithVerifierID overrides DefaultVerifierID.
+func WithVerifierID(id string, version map[string]string) func(c *VSAConfig) {
+	return func(c *VSAConfig) {
+		c.verifierID = id
+		c.verifierVersion = version
+	}
+}
+
+// WithResourceURI sets the URI of the verified artifact, e.g., the URI from
+// which it is distributed. Defaults to the name of the subject of the
+// provenance.
+func WithResourceURI(uri string) func(c *VSAConfig) {
+	return func(c *VSAConfig) {
+		c.resourceURI = uri
+	}
+}
+
+// WithPolicy identifies the policy by the given URI and content, e.g., the
+// path and the bytes of a policy file. By default, the policy is identified by
+// the digest of the deterministic binary encoding of the VerificationOptions.
+func WithPolicy(uri string, content []byte) func(c *VSAConfig) {
+	return func(c *VSAConfig) {
+		c.policy = &VSAResourceDescriptor{URI: uri, Digest: sha256DigestSet(content)}
+	}
+}
+
+// WithInputAttestation records the provenance with the given URI and content
+// as an input attestation of the VSA.
+func WithInputAttestation(uri string, content []byte) func(c *VSAConfig) {
+	return func(c *VSAConfig) {
+		c.inputAttestations = append(c.inputAttestations, VSAResourceDescriptor{URI: uri, Digest: sha256DigestSet(content)})
+	}
+}
+
+// WithVSAClock sets the clock providing the verification time. Defaults to
+// the system clock.
+func WithVSAClock(clock claims.Clock) func(c *VSAConfig) {
+	return func(c *VSAConfig) {
+		c.clock = clock
+	}
+}
+
+// GenerateVerificationSummary generates a VSA about the subject of the given
+// provenance, summarizing the given report of its verification against the
+// given VerificationOptions. The verified SLSA Build level is:
+//   - level 1 if the verification passed,
+//   - level 2 if, in addition, the signature of the provenance has been
+//     verified against Fulcio, so that it has a certificate identity,
+//   - level 3 if, in addition, the VerificationOptions pin the builder names
+//     and the certificate identity, so that the provenance must have been
+//     generated by a trusted build platform.
+//
+// No level is verified if the verification failed.
+func GenerateVerificationSummary(report *Report, provenance *model.ProvenanceIR, verOpts *pb.VerificationOptions, options ...func(c *VSAConfig)) (*intoto.Statement, error) {
+	config := &VSAConfig{verifierID: DefaultVerifierID, clock: claims.SystemClock()}
+	for _, addOption := range options {
+		addOption(config)
+	}
+	if config.policy == nil {
+		policyBytes, err := proto.MarshalOptions{Deterministic: true}.Marshal(verOpts)
+		if err != nil {
+			return nil, fmt.Errorf("could not marshal the verification options: %v", err)
+		}
+		config.policy = &VSAResourceDescriptor{Digest: sha256DigestSet(policyBytes)}
+	}
+	resourceURI := config.resourceURI
+	if resourceURI == "" {
+		resourceURI = provenance.BinaryName()
+	}
+
+	summary := VerificationSummary{
+		Verifier:           VSAVerifier{ID: config.verifierID, Version: config.verifierVersion},
+		TimeVerified:       config.clock.Now().UTC(),
+		ResourceURI:        resourceURI,
+		Policy:             *config.policy,
+		InputAttestations:  config.inputAttestations,
+		VerificationResult: VerificationFailed,
+		VerifiedLevels:     []string{},
+		SLSAVersion:        "1.0",
+	}
+	if report.Passed {
+		summary.VerificationResult = VerificationPassed
+		summary.VerifiedLevels = []string{slsaBuildLevel(provenance, verOpts)}
+	}
+
+	return &intoto.Statement{
+		StatementHeader: intoto.StatementHeader{
+			Type:          intoto.StatementInTotoV1,
+			PredicateType: VerificationSummaryV1,
+			Subject:       []intoto.Subject{{Name: provenance.BinaryName(), Digest: provenance.BinaryDigests()}},
+		},
+		Predicate: summary,
+	}, nil
+}
+
+// slsaBuildLevel returns the SLSA Build level of a provenance that passed
+// the verification against the given VerificationOptions.
+func slsaBuildLevel(provenance *model.ProvenanceIR, verOpts *pb.VerificationOptions) string {
+	if !provenance.HasCertificateIdentity() {
+		return SLSABuildLevel1
+	}
+	if verOpts.GetAllWithBuilderNames() == nil || verOpts.GetAllWithCertificateIdentity() == nil {
+		return SLSABuildLevel2
+	}
+	return SLSABuildLevel3
+}
+
+func sha256DigestSet(content []byte) intoto.DigestSet {
+	sum256 := sha256.Sum256(content)
+	return intoto.DigestSet{"sha256": hex.EncodeToString(sum256[:])}
+}
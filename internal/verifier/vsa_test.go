@@ -0,0 +1,95 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verifier
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/project-oak/transparent-release/internal/model"
+	"github.com/project-oak/transparent-release/internal/testutil"
+	"github.com/project-oak/transparent-release/pkg/claims"
+	slsav02 "github.com/project-oak/transparent-release/pkg/intoto/slsa_provenance/v0.2"
+	pb "github.com/project-oak/transparent-release/pkg/proto/oak/release"
+)
+
+func TestGenerateVerificationSummary_Levels(t *testing.T) {
+	identity := model.CertificateIdentity{SubjectAlternativeName: workflowURI, Issuer: githubIssuer}
+	unsigned := model.NewProvenanceIR(binaryDigest, slsav02.GenericSLSABuildType, binaryName, model.WithTrustedBuilder(builderName))
+	signed := model.NewProvenanceIR(binaryDigest, slsav02.GenericSLSABuildType, binaryName,
+		model.WithTrustedBuilder(builderName), model.WithCertificateIdentity(identity))
+	builderOnly := &pb.VerificationOptions{
+		AllWithBuilderNames: &pb.VerifyAllWithBuilderNames{BuilderNames: []string{builderName}},
+	}
+	pinned := &pb.VerificationOptions{
+		AllWithBuilderNames:        &pb.VerifyAllWithBuilderNames{BuilderNames: []string{builderName}},
+		AllWithCertificateIdentity: &pb.VerifyAllWithCertificateIdentity{SubjectAlternativeName: workflowURI, Issuer: githubIssuer},
+	}
+
+	tests := map[string]struct {
+		provenance *model.ProvenanceIR
+		verOpts    *pb.VerificationOptions
+		want       string
+	}{
+		"unsigned":              {unsigned, builderOnly, SLSABuildLevel1},
+		"signed":                {signed, builderOnly, SLSABuildLevel2},
+		"signed, pinned policy": {signed, pinned, SLSABuildLevel3},
+	}
+	for name, test := range tests {
+		report := VerifyWithReport([]model.ProvenanceIR{*test.provenance}, test.verOpts)
+		statement, err := GenerateVerificationSummary(report, test.provenance, test.verOpts)
+		if err != nil {
+			t.Fatalf("%s: could not generate the VSA: %v", name, err)
+		}
+		summary := statement.Predicate.(VerificationSummary)
+		testutil.AssertEq(t, name+": result", summary.VerificationResult, VerificationPassed)
+		if diff := cmp.Diff([]string{test.want}, summary.VerifiedLevels); diff != "" {
+			t.Errorf("%s: unexpected levels (-want +got):\n%s", name, diff)
+		}
+	}
+}
+
+func TestGenerateVerificationSummary_Failed(t *testing.T) {
+	provenance := model.NewProvenanceIR(binaryDigest, slsav02.GenericSLSABuildType, binaryName, model.WithTrustedBuilder(builderName))
+	verOpts := &pb.VerificationOptions{
+		AllWithBuilderNames: &pb.VerifyAllWithBuilderNames{BuilderNames: []string{"other"}},
+	}
+	report := VerifyWithReport([]model.ProvenanceIR{*provenance}, verOpts)
+	verifiedOn := time.Date(2023, 7, 1, 12, 0, 0, 0, time.UTC)
+
+	statement, err := GenerateVerificationSummary(report, provenance, verOpts,
+		WithPolicy("policy.textproto", []byte("policy")),
+		WithInputAttestation("provenance.json", []byte("provenance")),
+		WithResourceURI("https://example.com/binary"),
+		WithVSAClock(claims.FixedClock(verifiedOn)))
+	if err != nil {
+		t.Fatalf("could not generate the VSA: %v", err)
+	}
+
+	testutil.AssertEq(t, "predicate type", statement.PredicateType, VerificationSummaryV1)
+	testutil.AssertEq(t, "subject digest", statement.Subject[0].Digest["sha2-256"], binaryDigest)
+	summary := statement.Predicate.(VerificationSummary)
+	testutil.AssertEq(t, "result", summary.VerificationResult, VerificationFailed)
+	testutil.AssertEq(t, "levels", len(summary.VerifiedLevels), 0)
+	testutil.AssertEq(t, "verifier", summary.Verifier.ID, DefaultVerifierID)
+	testutil.AssertEq(t, "time verified", summary.TimeVerified, verifiedOn)
+	testutil.AssertEq(t, "resource URI", summary.ResourceURI, "https://example.com/binary")
+	testutil.AssertEq(t, "policy URI", summary.Policy.URI, "policy.textproto")
+	// The SHA256 digest of "policy".
+	testutil.AssertEq(t, "policy digest", summary.Policy.Digest["sha256"], "823412d1eacb67956220e532959f0104603057c88704863ca38e7cd188fda812")
+	testutil.AssertEq(t, "input attestations", len(summary.InputAttestations), 1)
+}
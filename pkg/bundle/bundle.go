@@ -0,0 +1,248 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bundle provides release bundles: single JSON documents combining a
+// signed endorsement with its Rekor log entry, the provenances it references,
+// their Rekor log entries, if any, and the public keys of Rekor and of the
+// endorser. A release bundle can be verified without network access.
+//
+// Unlike release archives, which are meant for long-term archival, release
+// bundles are meant to be distributed alongside a binary, e.g., as a release
+// asset.
+package bundle
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/secure-systems-lab/go-securesystemslib/dsse"
+	"go.uber.org/multierr"
+
+	"github.com/project-oak/transparent-release/internal/rekor"
+	"github.com/project-oak/transparent-release/pkg/claims"
+	"github.com/project-oak/transparent-release/pkg/intoto"
+	"github.com/project-oak/transparent-release/pkg/sign"
+)
+
+// ReleaseBundleV1 is the type of release bundles.
+const ReleaseBundleV1 = "https://github.com/project-oak/transparent-release/release_bundle/v1"
+
+// LogEntry is a Rekor log entry.
+type LogEntry = rekor.LogEntry
+
+// Bundle is a release bundle.
+type Bundle struct {
+	// Type must be ReleaseBundleV1.
+	Type string `json:"type"`
+	// Endorsement is the signed endorsement, as a DSSE envelope.
+	Endorsement *dsse.Envelope `json:"endorsement"`
+	// EndorsementLogEntry is the Rekor log entry of Endorsement.
+	EndorsementLogEntry *LogEntry `json:"endorsementLogEntry"`
+	// Provenances are the provenances referenced by the endorsement.
+	Provenances []Provenance `json:"provenances"`
+	// RekorPublicKey is the PEM-encoded public key of the Rekor instance.
+	RekorPublicKey string `json:"rekorPublicKey"`
+	// EndorserPublicKey is the PEM-encoded public key that signed the
+	// endorsement.
+	EndorserPublicKey string `json:"endorserPublicKey"`
+}
+
+// Provenance is a provenance referenced by the endorsement in a bundle.
+type Provenance struct {
+	// URI is the URI by which the endorsement references the provenance.
+	URI string `json:"uri"`
+	// Content is the provenance, either as a bare in-toto statement, a DSSE
+	// envelope, or a Sigstore bundle. It is base64-encoded in JSON.
+	Content []byte `json:"content"`
+	// LogEntry is the Rekor log entry of the provenance, if it is a DSSE
+	// envelope that has been uploaded to Rekor.
+	LogEntry *LogEntry `json:"logEntry,omitempty"`
+}
+
+// Parse parses the given JSON bytes into a Bundle, and checks its type. The
+// bundle is not verified; see Verify.
+func Parse(bundleBytes []byte) (*Bundle, error) {
+	var bundle Bundle
+	if err := json.Unmarshal(bundleBytes, &bundle); err != nil {
+		return nil, fmt.Errorf("could not unmarshal the release bundle: %v", err)
+	}
+	if bundle.Type != ReleaseBundleV1 {
+		return nil, fmt.Errorf("unexpected type of the release bundle; got: %q, want: %q", bundle.Type, ReleaseBundleV1)
+	}
+	if bundle.Endorsement == nil || bundle.EndorsementLogEntry == nil {
+		return nil, fmt.Errorf("the release bundle must contain an endorsement and its log entry")
+	}
+	return &bundle, nil
+}
+
+// VerifyConfig holds optional settings for verifying a bundle.
+type VerifyConfig struct {
+	clock claims.Clock
+}
+
+// WithClock additionally checks that the endorsement is valid, i.e., active
+// or expiring soon, at the time of the given clock.
+func WithClock(clock claims.Clock) func(c *VerifyConfig) {
+	return func(c *VerifyConfig) {
+		c.clock = clock
+	}
+}
+
+// Verify verifies, without network access, that the endorsement in the
+// bundle is signed by the endorser public key, and recorded in its log entry,
+// whose inclusion is verified with the Rekor public key. It checks that the
+// bundle contains exactly the provenances referenced by the endorsement, with
+// the digests recorded in the endorsement, and that the log entries of the
+// provenances, if any, are included in the log and record the provenances.
+// The keys in the bundle are never trusted by themselves: they must be equal
+// to the given trusted Rekor and endorser public keys, which are required.
+// Returns the endorsement statement.
+func (b *Bundle) Verify(ctx context.Context, trustedRekorPublicKey, trustedEndorserPublicKey crypto.PublicKey, options ...func(c *VerifyConfig)) (*intoto.Statement, error) {
+	config := &VerifyConfig{}
+	for _, addOption := range options {
+		addOption(config)
+	}
+
+	rekorPublicKey, err := parseRekorPublicKey(b.RekorPublicKey, trustedRekorPublicKey)
+	if err != nil {
+		return nil, err
+	}
+	endorserPublicKey, err := parsePublicKey("endorser", b.EndorserPublicKey, trustedEndorserPublicKey)
+	if err != nil {
+		return nil, err
+	}
+	endorserVerifier, err := sign.NewPublicKeyVerifier(endorserPublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("could not create a verifier for the endorser public key: %v", err)
+	}
+
+	if err := rekor.VerifyLogEntry(b.EndorsementLogEntry, rekorPublicKey); err != nil {
+		return nil, fmt.Errorf("could not verify the inclusion of the log entry of the endorsement: %v", err)
+	}
+	if err := rekor.VerifyEnvelopeLogEntry(ctx, b.Endorsement, b.EndorsementLogEntry, endorserVerifier); err != nil {
+		return nil, fmt.Errorf("could not verify the signed endorsement: %v", err)
+	}
+	payload, err := b.Endorsement.DecodeB64Payload()
+	if err != nil {
+		return nil, fmt.Errorf("could not decode the endorsement: %v", err)
+	}
+	endorsement, err := claims.ParseEndorsementV2Bytes(payload)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse the endorsement: %v", err)
+	}
+
+	if config.clock != nil {
+		status, err := claims.ClaimStatus(endorsement, claims.WithStatusClock(config.clock))
+		if err != nil {
+			return nil, err
+		}
+		if status != claims.StatusActive && status != claims.StatusExpiringSoon {
+			return nil, fmt.Errorf("the endorsement is %s at %v", status, config.clock.Now())
+		}
+	}
+
+	if err := b.verifyProvenances(endorsement.Predicate.(claims.ClaimPredicate), rekorPublicKey); err != nil {
+		return nil, err
+	}
+	return endorsement, nil
+}
+
+// verifyProvenances checks that the bundle contains exactly the provenances
+// referenced by the given predicate, and verifies their log entries.
+func (b *Bundle) verifyProvenances(predicate claims.ClaimPredicate, rekorPublicKey *ecdsa.PublicKey) error {
+	provenances := make(map[string]*Provenance, len(b.Provenances))
+	for i := range b.Provenances {
+		provenance := &b.Provenances[i]
+		if _, ok := provenances[provenance.URI]; ok {
+			return fmt.Errorf("duplicate provenance %q", provenance.URI)
+		}
+		provenances[provenance.URI] = provenance
+	}
+
+	var errs error
+	for _, evidence := range predicate.Evidence {
+		if evidence.Role != claims.ProvenanceRole {
+			continue
+		}
+		provenance, ok := provenances[evidence.URI]
+		if !ok {
+			errs = multierr.Append(errs, fmt.Errorf("missing provenance %q", evidence.URI))
+			continue
+		}
+		delete(provenances, evidence.URI)
+		sum256 := sha256.Sum256(provenance.Content)
+		if got := hex.EncodeToString(sum256[:]); got != evidence.Digest["sha256"] {
+			errs = multierr.Append(errs, fmt.Errorf("the digest of provenance %q does not match the endorsement; got: %s, want: %s", evidence.URI, got, evidence.Digest["sha256"]))
+			continue
+		}
+		if provenance.LogEntry != nil {
+			if err := verifyProvenanceLogEntry(provenance, rekorPublicKey); err != nil {
+				errs = multierr.Append(errs, fmt.Errorf("provenance %q: %v", evidence.URI, err))
+			}
+		}
+	}
+	for uri := range provenances {
+		errs = multierr.Append(errs, fmt.Errorf("the provenance %q is not referenced by the endorsement", uri))
+	}
+	return errs
+}
+
+func verifyProvenanceLogEntry(provenance *Provenance, rekorPublicKey *ecdsa.PublicKey) error {
+	var envelope dsse.Envelope
+	if err := json.Unmarshal(provenance.Content, &envelope); err != nil || envelope.PayloadType == "" {
+		return fmt.Errorf("only provenances in DSSE envelopes can have a log entry")
+	}
+	payload, err := envelope.DecodeB64Payload()
+	if err != nil {
+		return fmt.Errorf("could not decode the provenance: %v", err)
+	}
+	if err := rekor.VerifyLogEntry(provenance.LogEntry, rekorPublicKey); err != nil {
+		return fmt.Errorf("could not verify the inclusion of the log entry: %v", err)
+	}
+	return rekor.VerifyPayloadLogEntry(payload, provenance.LogEntry)
+}
+
+func parseRekorPublicKey(publicKeyPEM string, trusted crypto.PublicKey) (*ecdsa.PublicKey, error) {
+	publicKey, err := parsePublicKey("Rekor", publicKeyPEM, trusted)
+	if err != nil {
+		return nil, err
+	}
+	ecdsaKey, ok := publicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("got a %T Rekor public key, want an ECDSA key", publicKey)
+	}
+	return ecdsaKey, nil
+}
+
+// parsePublicKey parses the given PEM-encoded public key, and checks that it
+// is equal to the trusted key, which is required.
+func parsePublicKey(name, publicKeyPEM string, trusted crypto.PublicKey) (crypto.PublicKey, error) {
+	if trusted == nil {
+		return nil, fmt.Errorf("no trusted %s public key", name)
+	}
+	publicKey, err := sign.ParsePublicKeyPEM([]byte(publicKeyPEM))
+	if err != nil {
+		return nil, fmt.Errorf("could not parse the %s public key: %v", name, err)
+	}
+	key, ok := publicKey.(interface{ Equal(crypto.PublicKey) bool })
+	if !ok || !key.Equal(trusted) {
+		return nil, fmt.Errorf("the %s public key in the release bundle is not the trusted key", name)
+	}
+	return publicKey, nil
+}
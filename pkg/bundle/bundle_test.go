@@ -0,0 +1,170 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bundle
+
+import (
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/secure-systems-lab/go-securesystemslib/dsse"
+
+	"github.com/project-oak/transparent-release/pkg/claims"
+	"github.com/project-oak/transparent-release/pkg/intoto"
+	"github.com/project-oak/transparent-release/pkg/sign"
+)
+
+const fixturesPath = "../../testdata/rekor"
+
+// provenanceURI is the URI of the provenance referenced by the fixture
+// endorsement, whose content is not part of the fixtures.
+const provenanceURI = "https://github.com/project-oak/oak/blob/provenance/01b792106ef1f61eece3a666ac6069875fc90b942fefc3fe931f016395bb6c88/012a5206e5ab35d2778832638519441dd27664da.json"
+
+func readFixture(t *testing.T, name string) []byte {
+	bytes, err := os.ReadFile(filepath.Join(fixturesPath, name))
+	if err != nil {
+		t.Fatalf("could not read %s: %v", name, err)
+	}
+	return bytes
+}
+
+func newBundle(t *testing.T) *Bundle {
+	var envelope dsse.Envelope
+	if err := json.Unmarshal(readFixture(t, "endorsement.dsse.json"), &envelope); err != nil {
+		t.Fatalf("could not parse the endorsement: %v", err)
+	}
+	var entry LogEntry
+	if err := json.Unmarshal(readFixture(t, "endorsement.rekor.json"), &entry); err != nil {
+		t.Fatalf("could not parse the log entry: %v", err)
+	}
+	return &Bundle{
+		Type:                ReleaseBundleV1,
+		Endorsement:         &envelope,
+		EndorsementLogEntry: &entry,
+		RekorPublicKey:      string(readFixture(t, "rekor.pub")),
+		EndorserPublicKey:   string(readFixture(t, "endorser.pub")),
+	}
+}
+
+func TestParse_RoundTrip(t *testing.T) {
+	bundle := newBundle(t)
+	bundle.Provenances = []Provenance{{URI: provenanceURI, Content: []byte("{}")}}
+	bundleBytes, err := json.Marshal(bundle)
+	if err != nil {
+		t.Fatalf("could not marshal the bundle: %v", err)
+	}
+	parsed, err := Parse(bundleBytes)
+	if err != nil {
+		t.Fatalf("could not parse the bundle: %v", err)
+	}
+	if string(parsed.Provenances[0].Content) != "{}" {
+		t.Errorf("unexpected provenance content: %q", parsed.Provenances[0].Content)
+	}
+
+	bundle.Type = "https://example.com/other"
+	otherBytes, err := json.Marshal(bundle)
+	if err != nil {
+		t.Fatalf("could not marshal the bundle: %v", err)
+	}
+	if _, err := Parse(otherBytes); err == nil {
+		t.Errorf("expected an error for a bundle of another type")
+	}
+}
+
+// trustedKeys returns the public keys of Rekor and of the endorser of the
+// fixtures.
+func trustedKeys(t *testing.T) (crypto.PublicKey, crypto.PublicKey) {
+	rekorPublicKey, err := sign.ParsePublicKeyPEM(readFixture(t, "rekor.pub"))
+	if err != nil {
+		t.Fatalf("could not parse the Rekor public key: %v", err)
+	}
+	endorserPublicKey, err := sign.ParsePublicKeyPEM(readFixture(t, "endorser.pub"))
+	if err != nil {
+		t.Fatalf("could not parse the endorser public key: %v", err)
+	}
+	return rekorPublicKey, endorserPublicKey
+}
+
+func TestVerify_Provenances(t *testing.T) {
+	rekorPublicKey, endorserPublicKey := trustedKeys(t)
+	tests := map[string]struct {
+		provenances []Provenance
+		want        string
+	}{
+		"missing provenance": {nil, "missing provenance"},
+		"wrong digest":       {[]Provenance{{URI: provenanceURI, Content: []byte("{}")}}, "does not match the endorsement"},
+		"unreferenced provenance": {
+			[]Provenance{{URI: "https://example.com/other.json", Content: []byte("{}")}},
+			"not referenced by the endorsement",
+		},
+	}
+	for name, test := range tests {
+		bundle := newBundle(t)
+		bundle.Provenances = test.provenances
+		// The signature and the log entry of the endorsement are verified
+		// before the provenances.
+		_, err := bundle.Verify(context.Background(), rekorPublicKey, endorserPublicKey)
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%s: got %v, want an error containing %q", name, err, test.want)
+		}
+	}
+}
+
+func TestVerify_PinnedKeys(t *testing.T) {
+	rekorPublicKey, endorserPublicKey := trustedKeys(t)
+
+	// With the trusted keys, verification only fails on the missing provenance.
+	_, err := newBundle(t).Verify(context.Background(), rekorPublicKey, endorserPublicKey)
+	if err == nil || !strings.Contains(err.Error(), "missing provenance") {
+		t.Errorf("got %v, want an error about the missing provenance", err)
+	}
+	_, err = newBundle(t).Verify(context.Background(), rekorPublicKey, rekorPublicKey)
+	if err == nil || !strings.Contains(err.Error(), "not the trusted key") {
+		t.Errorf("got %v, want an error about the untrusted endorser key", err)
+	}
+	// The keys in the bundle are never trusted by themselves.
+	for _, keys := range [][2]crypto.PublicKey{{nil, endorserPublicKey}, {rekorPublicKey, nil}} {
+		_, err = newBundle(t).Verify(context.Background(), keys[0], keys[1])
+		if err == nil || !strings.Contains(err.Error(), "no trusted") {
+			t.Errorf("got %v, want an error about the missing trusted key", err)
+		}
+	}
+}
+
+func TestVerifyProvenances(t *testing.T) {
+	content := []byte(`{"_type": "https://in-toto.io/Statement/v1"}`)
+	sum256 := sha256.Sum256(content)
+	predicate := claims.ClaimPredicate{Evidence: []claims.ClaimEvidence{
+		{Role: claims.ProvenanceRole, URI: "https://example.com/provenance.json", Digest: intoto.DigestSet{"sha256": hex.EncodeToString(sum256[:])}},
+		{Role: "Verification report", URI: "https://example.com/report.json", Digest: intoto.DigestSet{"sha256": "00"}},
+	}}
+
+	bundle := &Bundle{Provenances: []Provenance{{URI: "https://example.com/provenance.json", Content: content}}}
+	if err := bundle.verifyProvenances(predicate, nil); err != nil {
+		t.Errorf("could not verify the provenances: %v", err)
+	}
+
+	// Log entries are only supported for provenances in DSSE envelopes.
+	bundle.Provenances[0].LogEntry = &LogEntry{}
+	if err := bundle.verifyProvenances(predicate, nil); err == nil || !strings.Contains(err.Error(), "DSSE envelopes") {
+		t.Errorf("got %v, want an error about the log entry of a bare statement", err)
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package claims
+
+import (
+	"fmt"
+	"time"
+)
+
+// Clock provides the current time, used as the issuance time of claims and as
+// the reference for computing their validity. Replacing the system clock with
+// a fixed one makes the generated statements deterministic.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+type fixedClock struct {
+	now time.Time
+}
+
+func (c fixedClock) Now() time.Time {
+	return c.now
+}
+
+// SystemClock returns a Clock that reports the current system time.
+func SystemClock() Clock {
+	return systemClock{}
+}
+
+// FixedClock returns a Clock that always reports the given time.
+func FixedClock(now time.Time) Clock {
+	return fixedClock{now: now}
+}
+
+// ParseClock returns a FixedClock for the given RFC3339 timestamp, or the
+// SystemClock if the timestamp is empty.
+func ParseClock(timestamp string) (Clock, error) {
+	if timestamp == "" {
+		return SystemClock(), nil
+	}
+	now, err := time.Parse(time.RFC3339, timestamp)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %q as an RFC3339 timestamp: %v", timestamp, err)
+	}
+	return FixedClock(now), nil
+}
@@ -0,0 +1,43 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package claims
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseClock(t *testing.T) {
+	clock, err := ParseClock("2023-07-01T12:00:00Z")
+	if err != nil {
+		t.Fatalf("Failed to parse clock: %v", err)
+	}
+	want := time.Date(2023, 7, 1, 12, 0, 0, 0, time.UTC)
+	if got := clock.Now(); !got.Equal(want) {
+		t.Errorf("Unexpected time: got %v, want %v", got, want)
+	}
+
+	clock, err = ParseClock("")
+	if err != nil {
+		t.Fatalf("Failed to parse empty clock: %v", err)
+	}
+	if _, ok := clock.(systemClock); !ok {
+		t.Errorf("Expected the system clock for an empty timestamp, got %T", clock)
+	}
+
+	if _, err := ParseClock("2023-07-01"); err == nil {
+		t.Errorf("Expected an error for a timestamp without time")
+	}
+}
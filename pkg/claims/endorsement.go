@@ -17,8 +17,8 @@ package claims
 import (
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"os"
-	"time"
 
 	"github.com/project-oak/transparent-release/pkg/intoto"
 )
@@ -27,6 +27,10 @@ import (
 // together with `ClaimV1` as the predicate type in an in-toto statement.
 const EndorsementV2 = "https://github.com/project-oak/transparent-release/endorsement/v2"
 
+// ProvenanceRole is the role of the evidence referencing the provenances from
+// which an endorsement was issued.
+const ProvenanceRole = "Provenance"
+
 // VerifiedProvenanceSet encapsulates metadata about a non-empty list of
 // verified provenances.
 type VerifiedProvenanceSet struct {
@@ -69,6 +73,9 @@ func ParseEndorsementV2Bytes(statementBytes []byte) (*intoto.Statement, error) {
 	if err := json.Unmarshal(statementBytes, &statement); err != nil {
 		return nil, fmt.Errorf("could not unmarshal the endorsement file:\n%v", err)
 	}
+	if err := intoto.NormalizeStatementHeader(&statement.StatementHeader); err != nil {
+		return nil, fmt.Errorf("invalid endorsement statement: %v", err)
+	}
 
 	// statement.Predicate is now just a map, we have to parse it into an instance of ClaimPredicate.
 	predicateBytes, err := json.Marshal(statement.Predicate)
@@ -107,34 +114,186 @@ func validateClaim(statement intoto.Statement) error {
 	return nil
 }
 
-// GenerateEndorsementStatement generates an endorsement object with the given subject, and
-// validity duration.
+// EndorsementConfig holds optional settings for generating an endorsement
+// statement. By default, generated endorsements use `ClaimV1` as the predicate
+// type and `EndorsementV2` as the claim type, in in-toto v0.1 statements.
+// Newer formats can be selected using WithPredicateType, WithClaimType, and
+// WithStatementType.
+type EndorsementConfig struct {
+	statementType string
+	predicateType string
+	claimType     string
+	evidence      []ClaimEvidence
+	claimSpec     interface{}
+	mediaType     string
+	clock         Clock
+}
+
+// WithStatementType sets the statement type of the generated endorsement
+// statement, either intoto.StatementInTotoV01 or intoto.StatementInTotoV1.
+// The digests of the subjects of in-toto v1 statements are keyed by the
+// algorithm names of the in-toto specification, e.g., "sha256".
+func WithStatementType(statementType string) func(c *EndorsementConfig) {
+	return func(c *EndorsementConfig) {
+		c.statementType = statementType
+	}
+}
+
+// WithPredicateType sets the predicate type of the generated endorsement statement.
+func WithPredicateType(predicateType string) func(c *EndorsementConfig) {
+	return func(c *EndorsementConfig) {
+		c.predicateType = predicateType
+	}
+}
+
+// WithClaimType sets the claim type of the generated endorsement statement.
+func WithClaimType(claimType string) func(c *EndorsementConfig) {
+	return func(c *EndorsementConfig) {
+		c.claimType = claimType
+	}
+}
+
+// WithEvidence adds the given evidence to the generated endorsement
+// statement, in addition to the evidence for the verified provenances.
+func WithEvidence(evidence ...ClaimEvidence) func(c *EndorsementConfig) {
+	return func(c *EndorsementConfig) {
+		c.evidence = append(c.evidence, evidence...)
+	}
+}
+
+// WithClaimSpec sets the claim spec of the generated endorsement statement,
+// describing the endorsed subject in more detail.
+func WithClaimSpec(claimSpec interface{}) func(c *EndorsementConfig) {
+	return func(c *EndorsementConfig) {
+		c.claimSpec = claimSpec
+	}
+}
+
+// WithSubjectMediaType sets the media type of the subject of the generated
+// endorsement statement, e.g., to the media type of the manifest of an
+// endorsed container image, so that its endorsements are distinguishable from
+// endorsements of binaries.
+func WithSubjectMediaType(mediaType string) func(c *EndorsementConfig) {
+	return func(c *EndorsementConfig) {
+		c.mediaType = mediaType
+	}
+}
+
+// WithClock sets the clock providing the issuance time of the generated
+// endorsement statement. Defaults to the system clock.
+func WithClock(clock Clock) func(c *EndorsementConfig) {
+	return func(c *EndorsementConfig) {
+		c.clock = clock
+	}
+}
+
+// NewEndorsementConfig creates a new EndorsementConfig with the default
+// statement, predicate, and claim types, and applies the given options to it.
+// Returns an error if the statement type is not supported, or if any of the
+// other types is not an absolute URI.
+func NewEndorsementConfig(options ...func(c *EndorsementConfig)) (*EndorsementConfig, error) {
+	config := &EndorsementConfig{statementType: intoto.StatementInTotoV01, predicateType: ClaimV1, claimType: EndorsementV2, clock: SystemClock()}
+	for _, addOption := range options {
+		addOption(config)
+	}
+	if config.statementType != intoto.StatementInTotoV01 && config.statementType != intoto.StatementInTotoV1 {
+		return nil, fmt.Errorf("unsupported statement type %q, want %q or %q", config.statementType, intoto.StatementInTotoV01, intoto.StatementInTotoV1)
+	}
+	if err := validateTypeURI(config.predicateType); err != nil {
+		return nil, fmt.Errorf("invalid predicate type: %v", err)
+	}
+	if err := validateTypeURI(config.claimType); err != nil {
+		return nil, fmt.Errorf("invalid claim type: %v", err)
+	}
+	return config, nil
+}
+
+// StatementType returns the statement type of the generated endorsement statement.
+func (c *EndorsementConfig) StatementType() string {
+	return c.statementType
+}
+
+// PredicateType returns the predicate type of the generated endorsement statement.
+func (c *EndorsementConfig) PredicateType() string {
+	return c.predicateType
+}
+
+// ClaimType returns the claim type of the generated endorsement statement.
+func (c *EndorsementConfig) ClaimType() string {
+	return c.claimType
+}
+
+// Clock returns the clock providing the issuance time of the generated
+// endorsement statement.
+func (c *EndorsementConfig) Clock() Clock {
+	if c.clock == nil {
+		return SystemClock()
+	}
+	return c.clock
+}
+
+func validateTypeURI(typeURI string) error {
+	parsedURI, err := url.Parse(typeURI)
+	if err != nil || !parsedURI.IsAbs() {
+		return fmt.Errorf("%q is not an absolute URI", typeURI)
+	}
+	return nil
+}
+
+// GenerateEndorsementStatement generates an endorsement object with the given
+// subject, and validity duration, using the default endorsement format.
 func GenerateEndorsementStatement(validity ClaimValidity, provenances VerifiedProvenanceSet) *intoto.Statement {
-	evidence := make([]ClaimEvidence, 0, len(provenances.Provenances))
+	config := &EndorsementConfig{statementType: intoto.StatementInTotoV01, predicateType: ClaimV1, claimType: EndorsementV2, clock: SystemClock()}
+	return newEndorsementStatement(config, validity, provenances, provenances.Digests)
+}
+
+// GenerateEndorsementStatementWithConfig generates an endorsement object with
+// the given subject, and validity duration, in the format specified by the
+// given config. Returns an error if the subject digests cannot be converted
+// to the digest keys of the statement type.
+func GenerateEndorsementStatementWithConfig(config *EndorsementConfig, validity ClaimValidity, provenances VerifiedProvenanceSet) (*intoto.Statement, error) {
+	digests := provenances.Digests
+	if config.statementType == intoto.StatementInTotoV1 {
+		converted, err := intoto.ConvertDigestSet(digests, intoto.InTotoDigestKeys)
+		if err != nil {
+			return nil, fmt.Errorf("could not convert the subject digests: %v", err)
+		}
+		digests = converted
+	}
+	return newEndorsementStatement(config, validity, provenances, digests), nil
+}
+
+// newEndorsementStatement generates an endorsement object for the subject
+// with the given digests, in the format specified by the given config.
+func newEndorsementStatement(config *EndorsementConfig, validity ClaimValidity, provenances VerifiedProvenanceSet, digests intoto.DigestSet) *intoto.Statement {
+	evidence := make([]ClaimEvidence, 0, len(provenances.Provenances)+len(config.evidence))
 	for _, provenance := range provenances.Provenances {
 		evidence = append(evidence, ClaimEvidence{
-			Role:   "Provenance",
+			Role:   ProvenanceRole,
 			URI:    provenance.URI,
 			Digest: intoto.DigestSet{"sha256": provenance.SHA256Digest},
 		})
 	}
+	evidence = append(evidence, config.evidence...)
 
-	currentTime := time.Now()
+	currentTime := config.Clock().Now()
 	predicate := ClaimPredicate{
-		ClaimType: EndorsementV2,
+		ClaimType: config.claimType,
+		ClaimSpec: config.claimSpec,
 		IssuedOn:  &currentTime,
 		Validity:  &validity,
 		Evidence:  evidence,
 	}
 
 	subject := intoto.Subject{
-		Name:   provenances.BinaryName,
-		Digest: provenances.Digests,
+		Name:      provenances.BinaryName,
+		Digest:    digests,
+		MediaType: config.mediaType,
 	}
 
 	statementHeader := intoto.StatementHeader{
-		Type:          intoto.StatementInTotoV01,
-		PredicateType: ClaimV1,
+		Type:          config.statementType,
+		PredicateType: config.predicateType,
 		Subject:       []intoto.Subject{subject},
 	}
 
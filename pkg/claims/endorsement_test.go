@@ -48,6 +48,12 @@ func TestExampleEndorsement(t *testing.T) {
 	if len(claimPredicate.Evidence) != 1 {
 		t.Errorf("Exactly one evidence is expected: got %d", len(claimPredicate.Evidence))
 	}
+
+	// The "sha256" key of the example is normalized when loading.
+	wantDigest := "01b792106ef1f61eece3a666ac6069875fc90b942fefc3fe931f016395bb6c88"
+	if got := endorsement.Subject[0].Digest["sha2-256"]; got != wantDigest {
+		t.Errorf("Unexpected subject digest: got %q, want %q", got, wantDigest)
+	}
 }
 
 func TestIssuedAfterNotBeforeEndorsement(t *testing.T) {
@@ -89,6 +95,171 @@ func TestGenerateProvenanceLessEndorsement(t *testing.T) {
 	}
 }
 
+func TestGenerateEndorsementWithConfig(t *testing.T) {
+	newNotBefore := time.Now().AddDate(0, 0, 1)
+	newNotAfter := time.Now().AddDate(0, 0, 3)
+	validity := ClaimValidity{
+		NotBefore: &newNotBefore,
+		NotAfter:  &newNotAfter,
+	}
+	provenances := VerifiedProvenanceSet{
+		BinaryName: "SomeBinary",
+		Digests:    intoto.DigestSet{"sha2-256": "813841dda3818d616aa3e706e49d0286dc825c5dbad4a75cfb37b91ba412238b"},
+	}
+
+	claimV2 := "https://github.com/project-oak/transparent-release/claim/v2"
+	endorsementV3 := "https://github.com/project-oak/transparent-release/endorsement/v3"
+	config, err := NewEndorsementConfig(WithPredicateType(claimV2), WithClaimType(endorsementV3), WithClaimSpec("spec"))
+	if err != nil {
+		t.Fatalf("Failed to create endorsement config: %v", err)
+	}
+
+	endorsement, err := GenerateEndorsementStatementWithConfig(config, validity, provenances)
+	if err != nil {
+		t.Fatalf("Failed to generate endorsement: %v", err)
+	}
+	if endorsement.PredicateType != claimV2 {
+		t.Errorf("Unexpected PredicateType: got %s, want %s", endorsement.PredicateType, claimV2)
+	}
+	claimPredicate := endorsement.Predicate.(ClaimPredicate)
+	if claimPredicate.ClaimType != endorsementV3 {
+		t.Errorf("Unexpected ClaimType: got %s, want %s", claimPredicate.ClaimType, endorsementV3)
+	}
+	if claimPredicate.ClaimSpec != "spec" {
+		t.Errorf("Unexpected ClaimSpec: got %v, want %q", claimPredicate.ClaimSpec, "spec")
+	}
+}
+
+func TestGenerateEndorsementWithSubjectMediaType(t *testing.T) {
+	newNotBefore := time.Now().AddDate(0, 0, 1)
+	newNotAfter := time.Now().AddDate(0, 0, 3)
+	validity := ClaimValidity{
+		NotBefore: &newNotBefore,
+		NotAfter:  &newNotAfter,
+	}
+	provenances := VerifiedProvenanceSet{
+		BinaryName: "ghcr.io/project-oak/oak",
+		Digests:    intoto.DigestSet{"sha2-256": "813841dda3818d616aa3e706e49d0286dc825c5dbad4a75cfb37b91ba412238b"},
+	}
+
+	mediaType := "application/vnd.oci.image.manifest.v1+json"
+	config, err := NewEndorsementConfig(WithSubjectMediaType(mediaType))
+	if err != nil {
+		t.Fatalf("Failed to create endorsement config: %v", err)
+	}
+	statement, err := GenerateEndorsementStatementWithConfig(config, validity, provenances)
+	if err != nil {
+		t.Fatalf("Failed to generate endorsement: %v", err)
+	}
+	bytes, err := json.Marshal(statement)
+	if err != nil {
+		t.Fatalf("Failed to marshal endorsement: %v", err)
+	}
+	endorsement, err := ParseEndorsementV2Bytes(bytes)
+	if err != nil {
+		t.Fatalf("Failed to parse endorsement: %v", err)
+	}
+	if endorsement.Subject[0].MediaType != mediaType {
+		t.Errorf("Unexpected subject MediaType: got %q, want %q", endorsement.Subject[0].MediaType, mediaType)
+	}
+}
+
+func TestGenerateEndorsementWithStatementTypeV1(t *testing.T) {
+	newNotBefore := time.Now().AddDate(0, 0, 1)
+	newNotAfter := time.Now().AddDate(0, 0, 3)
+	validity := ClaimValidity{
+		NotBefore: &newNotBefore,
+		NotAfter:  &newNotAfter,
+	}
+	digest := "813841dda3818d616aa3e706e49d0286dc825c5dbad4a75cfb37b91ba412238b"
+	provenances := VerifiedProvenanceSet{
+		BinaryName: "SomeBinary",
+		Digests:    intoto.DigestSet{"sha2-256": digest},
+	}
+
+	config, err := NewEndorsementConfig(WithStatementType(intoto.StatementInTotoV1))
+	if err != nil {
+		t.Fatalf("Failed to create endorsement config: %v", err)
+	}
+	statement, err := GenerateEndorsementStatementWithConfig(config, validity, provenances)
+	if err != nil {
+		t.Fatalf("Failed to generate endorsement: %v", err)
+	}
+	if statement.Type != intoto.StatementInTotoV1 {
+		t.Errorf("Unexpected statement Type: got %s, want %s", statement.Type, intoto.StatementInTotoV1)
+	}
+	if got := statement.Subject[0].Digest["sha256"]; got != digest {
+		t.Errorf("Unexpected subject sha256 digest: got %q, want %q", got, digest)
+	}
+
+	bytes, err := json.Marshal(statement)
+	if err != nil {
+		t.Fatalf("Failed to marshal endorsement: %v", err)
+	}
+	endorsement, err := ParseEndorsementV2Bytes(bytes)
+	if err != nil {
+		t.Fatalf("Failed to parse endorsement: %v", err)
+	}
+	if endorsement.Type != intoto.StatementInTotoV1 {
+		t.Errorf("Unexpected parsed statement Type: got %s, want %s", endorsement.Type, intoto.StatementInTotoV1)
+	}
+	if got := endorsement.Subject[0].Digest["sha2-256"]; got != digest {
+		t.Errorf("Unexpected parsed subject sha2-256 digest: got %q, want %q", got, digest)
+	}
+}
+
+func TestGenerateEndorsementWithStatementTypeV1_ConflictingDigests(t *testing.T) {
+	newNotBefore := time.Now().AddDate(0, 0, 1)
+	newNotAfter := time.Now().AddDate(0, 0, 3)
+	validity := ClaimValidity{
+		NotBefore: &newNotBefore,
+		NotAfter:  &newNotAfter,
+	}
+	provenances := VerifiedProvenanceSet{
+		BinaryName: "SomeBinary",
+		Digests: intoto.DigestSet{
+			"sha2-256": "813841dda3818d616aa3e706e49d0286dc825c5dbad4a75cfb37b91ba412238b",
+			"sha256":   "d059c38cea82047ad316a1c6c6fbd13ecf7a0abdcc375463920bd25bf5c142cc",
+		},
+	}
+
+	config, err := NewEndorsementConfig(WithStatementType(intoto.StatementInTotoV1))
+	if err != nil {
+		t.Fatalf("Failed to create endorsement config: %v", err)
+	}
+	if _, err := GenerateEndorsementStatementWithConfig(config, validity, provenances); err == nil {
+		t.Errorf("Expected an error for conflicting subject digests")
+	}
+}
+
+func TestNewEndorsementConfig_Defaults(t *testing.T) {
+	config, err := NewEndorsementConfig()
+	if err != nil {
+		t.Fatalf("Failed to create endorsement config: %v", err)
+	}
+	if config.StatementType() != intoto.StatementInTotoV01 {
+		t.Errorf("Unexpected StatementType: got %s, want %s", config.StatementType(), intoto.StatementInTotoV01)
+	}
+	if config.PredicateType() != ClaimV1 {
+		t.Errorf("Unexpected PredicateType: got %s, want %s", config.PredicateType(), ClaimV1)
+	}
+	if config.ClaimType() != EndorsementV2 {
+		t.Errorf("Unexpected ClaimType: got %s, want %s", config.ClaimType(), EndorsementV2)
+	}
+}
+
+func TestNewEndorsementConfig_InvalidClaimType(t *testing.T) {
+	if _, err := NewEndorsementConfig(WithClaimType("endorsement-v3")); err == nil {
+		t.Fatalf("Expected an error about invalid claim type")
+	}
+}
+
+func TestNewEndorsementConfig_InvalidStatementType(t *testing.T) {
+	if _, err := NewEndorsementConfig(WithStatementType("https://in-toto.io/Statement/v2")); err == nil {
+		t.Fatalf("Expected an error about unsupported statement type")
+	}
+}
+
 // Helper function for creating new test cases from the hard-coded one.
 func tweakValidity(t *testing.T, daysAddedToNotBefore, daysAddedToNotAfter int) []byte {
 	examplePath := "../../schema/claim/v1/example.json"
@@ -115,3 +286,48 @@ func tweakValidity(t *testing.T, daysAddedToNotBefore, daysAddedToNotAfter int)
 
 	return bytes
 }
+
+func TestGenerateEndorsementWithClock_Reproducible(t *testing.T) {
+	now := time.Date(2023, 7, 1, 12, 0, 0, 0, time.UTC)
+	notBefore := now.AddDate(0, 0, 1)
+	notAfter := now.AddDate(0, 0, 90)
+	validity := ClaimValidity{NotBefore: &notBefore, NotAfter: &notAfter}
+	provenances := VerifiedProvenanceSet{
+		BinaryName: "SomeBinary",
+		Digests:    intoto.DigestSet{"sha2-256": "813841dda3818d616aa3e706e49d0286dc825c5dbad4a75cfb37b91ba412238b"},
+	}
+
+	config, err := NewEndorsementConfig(WithClock(FixedClock(now)))
+	if err != nil {
+		t.Fatalf("Failed to create endorsement config: %v", err)
+	}
+	statement, err := GenerateEndorsementStatementWithConfig(config, validity, provenances)
+	if err != nil {
+		t.Fatalf("Failed to generate endorsement: %v", err)
+	}
+	first, err := json.Marshal(statement)
+	if err != nil {
+		t.Fatalf("Failed to marshal endorsement: %v", err)
+	}
+	statement, err = GenerateEndorsementStatementWithConfig(config, validity, provenances)
+	if err != nil {
+		t.Fatalf("Failed to generate endorsement: %v", err)
+	}
+	second, err := json.Marshal(statement)
+	if err != nil {
+		t.Fatalf("Failed to marshal endorsement: %v", err)
+	}
+	if string(first) != string(second) {
+		t.Errorf("Endorsements generated with a fixed clock differ:\n%s\n%s", first, second)
+	}
+
+	var parsed struct {
+		Predicate ClaimPredicate `json:"predicate"`
+	}
+	if err := json.Unmarshal(first, &parsed); err != nil {
+		t.Fatalf("Failed to unmarshal endorsement: %v", err)
+	}
+	if !parsed.Predicate.IssuedOn.Equal(now) {
+		t.Errorf("Unexpected IssuedOn: got %v, want %v", parsed.Predicate.IssuedOn, now)
+	}
+}
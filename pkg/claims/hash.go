@@ -0,0 +1,61 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package claims
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/project-oak/transparent-release/pkg/intoto"
+)
+
+// StatementHashDomain is the domain separation tag of statement hashes. It
+// keeps statement hashes apart from SHA2-256 digests of other data, including
+// the statement bytes themselves.
+const StatementHashDomain = "https://github.com/project-oak/transparent-release/statement-hash/v1"
+
+// StatementHash returns the hex-encoded statement hash of the given
+// statement, which identifies it when anchoring it externally. The hash is
+// the SHA2-256 digest of StatementHashDomain, a zero byte, and the canonical
+// JSON encoding of the statement, as in intoto.MarshalCanonical (RFC 8785),
+// so it does not depend on whitespace, the order of keys, or the formatting of
+// numbers in the serialized statement.
+func StatementHash(statement *intoto.Statement) (string, error) {
+	return statementHash(statement)
+}
+
+// StatementHashFromBytes returns the statement hash, as in StatementHash, of
+// the statement in the given JSON bytes, e.g., the payload of a DSSE
+// envelope. The statement is not parsed, so the hash covers all its fields.
+func StatementHashFromBytes(statementBytes []byte) (string, error) {
+	if !json.Valid(statementBytes) {
+		return "", fmt.Errorf("the statement is not valid JSON")
+	}
+	return statementHash(json.RawMessage(statementBytes))
+}
+
+func statementHash(statement interface{}) (string, error) {
+	canonical, err := intoto.MarshalCanonical(statement)
+	if err != nil {
+		return "", fmt.Errorf("could not canonicalize the statement: %v", err)
+	}
+	hash := sha256.New()
+	hash.Write([]byte(StatementHashDomain))
+	hash.Write([]byte{0})
+	hash.Write(canonical)
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
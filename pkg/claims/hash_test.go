@@ -0,0 +1,87 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package claims
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+
+	"github.com/project-oak/transparent-release/internal/testutil"
+	"github.com/project-oak/transparent-release/pkg/intoto"
+)
+
+func TestStatementHash(t *testing.T) {
+	statement := newEndorsement(statusSubjectDigest, 0)
+	hash, err := StatementHash(statement)
+	if err != nil {
+		t.Fatalf("Failed to hash the statement: %v", err)
+	}
+
+	// The hash does not depend on the serialization of the statement.
+	statementBytes, err := json.Marshal(statement)
+	if err != nil {
+		t.Fatalf("Failed to marshal the statement: %v", err)
+	}
+	indentedBytes, err := json.MarshalIndent(statement, "", "    ")
+	if err != nil {
+		t.Fatalf("Failed to marshal the statement: %v", err)
+	}
+	for _, b := range [][]byte{statementBytes, indentedBytes} {
+		got, err := StatementHashFromBytes(b)
+		if err != nil {
+			t.Fatalf("Failed to hash the statement bytes: %v", err)
+		}
+		testutil.AssertEq(t, "statement hash", got, hash)
+	}
+
+	// The hash is domain-separated from the plain digest of the statement.
+	digest := sha256.Sum256(statementBytes)
+	if hash == hex.EncodeToString(digest[:]) {
+		t.Errorf("The statement hash is the plain SHA2-256 digest of the statement")
+	}
+
+	other, err := StatementHash(newEndorsement(otherStatusSubjectDigest, 0))
+	if err != nil {
+		t.Fatalf("Failed to hash the statement: %v", err)
+	}
+	if other == hash {
+		t.Errorf("Different statements have the same hash %s", hash)
+	}
+}
+
+func TestStatementHash_Numbers(t *testing.T) {
+	statement := &intoto.Statement{
+		StatementHeader: intoto.StatementHeader{Type: intoto.StatementInTotoV01},
+		Predicate:       map[string]interface{}{"score": 0.5},
+	}
+	hash, err := StatementHash(statement)
+	if err != nil {
+		t.Fatalf("Failed to hash a statement with a floating-point number: %v", err)
+	}
+	// Numbers are hashed in their shortest form, as in RFC 8785.
+	got, err := StatementHashFromBytes([]byte(`{"predicate": {"score": 5e-1}, "_type": "` + intoto.StatementInTotoV01 + `", "predicateType": "", "subject": null}`))
+	if err != nil {
+		t.Fatalf("Failed to hash the statement bytes: %v", err)
+	}
+	testutil.AssertEq(t, "statement hash", got, hash)
+}
+
+func TestStatementHash_Invalid(t *testing.T) {
+	if _, err := StatementHashFromBytes([]byte(`{"_type":`)); err == nil {
+		t.Errorf("Expected an error for invalid JSON")
+	}
+}
@@ -0,0 +1,209 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package claims
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/project-oak/transparent-release/pkg/intoto"
+)
+
+// RevocationV1 is the claim type of revocations, which revoke all claims
+// about their subject. Revocations are meant to be signed by a designated
+// revocation authority, with a different key than endorsements.
+const RevocationV1 = "https://github.com/project-oak/transparent-release/revocation/v1"
+
+// RevocationSpec is the claim spec of a revocation.
+type RevocationSpec struct {
+	// Reason is a human-readable explanation of the revocation, e.g., a CVE.
+	Reason string `json:"reason,omitempty"`
+}
+
+// GenerateRevocationStatement generates a revocation of all claims about the
+// given subject, issued at the time of the given clock. Revocations do not
+// expire.
+func GenerateRevocationStatement(subject intoto.Subject, reason string, clock Clock) *intoto.Statement {
+	issuedOn := clock.Now()
+	return &intoto.Statement{
+		StatementHeader: intoto.StatementHeader{
+			Type:          intoto.StatementInTotoV01,
+			PredicateType: ClaimV1,
+			Subject:       []intoto.Subject{subject},
+		},
+		Predicate: ClaimPredicate{
+			ClaimType: RevocationV1,
+			ClaimSpec: RevocationSpec{Reason: reason},
+			IssuedOn:  &issuedOn,
+			Validity:  &ClaimValidity{NotBefore: &issuedOn},
+		},
+	}
+}
+
+// ParseRevocationBytes parses the given JSON bytes into an instance of
+// intoto.Statement, with a ClaimPredicate as the predicate, and a
+// RevocationSpec as its claim spec. Returns an error
+// if the statement is not a revocation with a SHA2-256 digest of its subject.
+func ParseRevocationBytes(statementBytes []byte) (*intoto.Statement, error) {
+	var claimSpec RevocationSpec
+	return parseRevocationBytes(statementBytes, RevocationV1, &claimSpec, func() interface{} { return claimSpec })
+}
+
+// parseRevocationBytes parses the given JSON bytes into a statement with a
+// ClaimPredicate of the given claim type, and unmarshals its claim spec into
+// the given pointer. The claim spec of the returned statement is set to the
+// value returned by deref, so that it is not a pointer.
+func parseRevocationBytes(statementBytes []byte, claimType string, claimSpec interface{}, deref func() interface{}) (*intoto.Statement, error) {
+	var statement intoto.Statement
+	if err := json.Unmarshal(statementBytes, &statement); err != nil {
+		return nil, fmt.Errorf("could not unmarshal the revocation: %v", err)
+	}
+	if err := intoto.NormalizeStatementHeader(&statement.StatementHeader); err != nil {
+		return nil, fmt.Errorf("invalid revocation statement: %v", err)
+	}
+
+	predicateBytes, err := json.Marshal(statement.Predicate)
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal Predicate map into JSON bytes: %v", err)
+	}
+	var predicate ClaimPredicate
+	if err = json.Unmarshal(predicateBytes, &predicate); err != nil {
+		return nil, fmt.Errorf("could not unmarshal JSON bytes into a ClaimPredicate: %v", err)
+	}
+	// The claim spec is now just a map too, parse it into the given spec.
+	claimSpecBytes, err := json.Marshal(predicate.ClaimSpec)
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal ClaimSpec map into JSON bytes: %v", err)
+	}
+	if err = json.Unmarshal(claimSpecBytes, claimSpec); err != nil {
+		return nil, fmt.Errorf("could not unmarshal JSON bytes into a %T: %v", deref(), err)
+	}
+	predicate.ClaimSpec = deref()
+	statement.Predicate = predicate
+
+	if statement.PredicateType != ClaimV1 {
+		return nil, fmt.Errorf("the revocation does not have the expected predicate type; got: %s, want: %s", statement.PredicateType, ClaimV1)
+	}
+	if predicate.ClaimType != claimType {
+		return nil, fmt.Errorf("the revocation does not have the expected claim type; got: %s, want: %s", predicate.ClaimType, claimType)
+	}
+	if len(statement.Subject) != 1 || subjectSHA256Digest(&statement) == "" {
+		return nil, fmt.Errorf("the revocation must have a single subject with a SHA2-256 digest")
+	}
+	return &statement, nil
+}
+
+// WithRevocations marks all claims about the subjects of the given
+// revocations, as returned by ParseRevocationBytes, as revoked. The
+// signatures on the revocations must have been verified by the caller.
+func WithRevocations(revocations ...*intoto.Statement) func(c *StatusConfig) {
+	return func(c *StatusConfig) {
+		for _, revocation := range revocations {
+			if digest := subjectSHA256Digest(revocation); digest != "" {
+				c.revokedSubjects[digest] = true
+			}
+		}
+	}
+}
+
+// EndorsementRevocationV1 is the claim type of endorsement revocations, which
+// revoke a single endorsement, identified by its statement hash, rather than
+// all claims about a subject. This allows withdrawing an endorsement that was
+// issued by mistake, e.g., with a wrong validity, without revoking the binary
+// it endorses. Like revocations, endorsement revocations are meant to be
+// signed by the revocation authority.
+const EndorsementRevocationV1 = "https://github.com/project-oak/transparent-release/endorsement/revocation/v1"
+
+// EndorsementRevocationSpec is the claim spec of an endorsement revocation.
+type EndorsementRevocationSpec struct {
+	// StatementHash is the statement hash of the revoked endorsement, as
+	// returned by StatementHashFromBytes.
+	StatementHash string `json:"statementHash"`
+	// Reason is a human-readable explanation of the revocation.
+	Reason string `json:"reason,omitempty"`
+}
+
+// GenerateEndorsementRevocationStatement generates a revocation of the
+// endorsement in the given JSON bytes, e.g., the payload of its DSSE
+// envelope, issued at the time of the given clock. The subject of the
+// revocation is the subject of the endorsement, and the endorsement is
+// referenced by its statement hash. Endorsement revocations do not expire.
+func GenerateEndorsementRevocationStatement(endorsementBytes []byte, reason string, clock Clock) (*intoto.Statement, error) {
+	endorsement, err := ParseEndorsementV2Bytes(endorsementBytes)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse the endorsement: %v", err)
+	}
+	hash, err := StatementHashFromBytes(endorsementBytes)
+	if err != nil {
+		return nil, fmt.Errorf("could not hash the endorsement: %v", err)
+	}
+	issuedOn := clock.Now()
+	return &intoto.Statement{
+		StatementHeader: intoto.StatementHeader{
+			Type:          intoto.StatementInTotoV01,
+			PredicateType: ClaimV1,
+			Subject:       []intoto.Subject{endorsement.Subject[0]},
+		},
+		Predicate: ClaimPredicate{
+			ClaimType: EndorsementRevocationV1,
+			ClaimSpec: EndorsementRevocationSpec{StatementHash: hash, Reason: reason},
+			IssuedOn:  &issuedOn,
+			Validity:  &ClaimValidity{NotBefore: &issuedOn},
+		},
+	}, nil
+}
+
+// ParseEndorsementRevocationBytes parses the given JSON bytes into an
+// instance of intoto.Statement, with a ClaimPredicate as the predicate, and
+// an EndorsementRevocationSpec as its claim spec. Returns an error if the
+// statement is not an endorsement revocation with a statement hash.
+func ParseEndorsementRevocationBytes(statementBytes []byte) (*intoto.Statement, error) {
+	var claimSpec EndorsementRevocationSpec
+	statement, err := parseRevocationBytes(statementBytes, EndorsementRevocationV1, &claimSpec, func() interface{} { return claimSpec })
+	if err != nil {
+		return nil, err
+	}
+	if claimSpec.StatementHash == "" || statement.Predicate.(ClaimPredicate).IssuedOn == nil {
+		return nil, fmt.Errorf("the endorsement revocation must have a statement hash and an issuance time")
+	}
+	return statement, nil
+}
+
+// CheckEndorsementNotRevoked returns an error if any of the given endorsement
+// revocations, as returned by ParseEndorsementRevocationBytes, revokes the
+// endorsement with the given statement hash. The signatures on the
+// revocations must have been verified by the caller.
+func CheckEndorsementNotRevoked(statementHash string, revocations ...*intoto.Statement) error {
+	for _, revocation := range revocations {
+		predicate, ok := revocation.Predicate.(ClaimPredicate)
+		if !ok {
+			return fmt.Errorf("the predicate of the revocation does not have the expected type; got: %T, want: ClaimPredicate", revocation.Predicate)
+		}
+		spec, ok := predicate.ClaimSpec.(EndorsementRevocationSpec)
+		if !ok {
+			return fmt.Errorf("the claim spec of the revocation does not have the expected type; got: %T, want: EndorsementRevocationSpec", predicate.ClaimSpec)
+		}
+		if !strings.EqualFold(spec.StatementHash, statementHash) {
+			continue
+		}
+		if spec.Reason != "" {
+			return fmt.Errorf("the endorsement with statement hash %s was revoked on %s: %s", statementHash, predicate.IssuedOn.Format(time.RFC3339), spec.Reason)
+		}
+		return fmt.Errorf("the endorsement with statement hash %s was revoked on %s", statementHash, predicate.IssuedOn.Format(time.RFC3339))
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package claims
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/project-oak/transparent-release/pkg/intoto"
+)
+
+func TestParseRevocationBytes(t *testing.T) {
+	subject := intoto.Subject{Name: "binary", Digest: intoto.DigestSet{"sha2-256": statusSubjectDigest}}
+	revocationBytes, err := json.Marshal(GenerateRevocationStatement(subject, "CVE-2023-0001", FixedClock(issuedOn)))
+	if err != nil {
+		t.Fatalf("Failed to marshal the revocation: %v", err)
+	}
+
+	revocation, err := ParseRevocationBytes(revocationBytes)
+	if err != nil {
+		t.Fatalf("Failed to parse the revocation: %v", err)
+	}
+	predicate := revocation.Predicate.(ClaimPredicate)
+	if !predicate.IssuedOn.Equal(issuedOn) {
+		t.Errorf("Unexpected issuance time: got %v, want %v", predicate.IssuedOn, issuedOn)
+	}
+	endorsementBytes, err := json.Marshal(newEndorsement(statusSubjectDigest, 0))
+	if err != nil {
+		t.Fatalf("Failed to marshal the endorsement: %v", err)
+	}
+	if _, err := ParseRevocationBytes(endorsementBytes); err == nil {
+		t.Errorf("Expected an error for an endorsement")
+	}
+}
+
+func TestClaimStatuses_WithRevocations(t *testing.T) {
+	statements := []*intoto.Statement{
+		newEndorsement(statusSubjectDigest, 0),
+		newEndorsement(otherStatusSubjectDigest, 0),
+	}
+	subject := intoto.Subject{Name: "binary", Digest: intoto.DigestSet{"sha256": otherStatusSubjectDigest}}
+	revocation := GenerateRevocationStatement(subject, "", FixedClock(issuedOn))
+
+	got, err := ClaimStatuses(statements, WithStatusClock(FixedClock(issuedOn.AddDate(0, 0, 1))), WithRevocations(revocation))
+	if err != nil {
+		t.Fatalf("Failed to compute the statuses: %v", err)
+	}
+	want := []Status{StatusActive, StatusRevoked}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Unexpected status of #%d: got %s, want %s", i, got[i], want[i])
+		}
+	}
+}
+
+func TestEndorsementRevocation(t *testing.T) {
+	endorsementBytes, err := json.Marshal(newEndorsement(statusSubjectDigest, 0))
+	if err != nil {
+		t.Fatalf("Failed to marshal the endorsement: %v", err)
+	}
+	hash, err := StatementHashFromBytes(endorsementBytes)
+	if err != nil {
+		t.Fatalf("Failed to hash the endorsement: %v", err)
+	}
+	otherBytes, err := json.Marshal(newEndorsement(statusSubjectDigest, 1))
+	if err != nil {
+		t.Fatalf("Failed to marshal the endorsement: %v", err)
+	}
+	otherHash, err := StatementHashFromBytes(otherBytes)
+	if err != nil {
+		t.Fatalf("Failed to hash the endorsement: %v", err)
+	}
+
+	statement, err := GenerateEndorsementRevocationStatement(endorsementBytes, "wrong validity", FixedClock(issuedOn))
+	if err != nil {
+		t.Fatalf("Failed to generate the endorsement revocation: %v", err)
+	}
+	revocationBytes, err := json.Marshal(statement)
+	if err != nil {
+		t.Fatalf("Failed to marshal the endorsement revocation: %v", err)
+	}
+	revocation, err := ParseEndorsementRevocationBytes(revocationBytes)
+	if err != nil {
+		t.Fatalf("Failed to parse the endorsement revocation: %v", err)
+	}
+	if got := subjectSHA256Digest(revocation); got != statusSubjectDigest {
+		t.Errorf("Unexpected subject digest: got %s, want %s", got, statusSubjectDigest)
+	}
+
+	if err := CheckEndorsementNotRevoked(hash, revocation); err == nil || !strings.Contains(err.Error(), "wrong validity") {
+		t.Errorf("Expected an error with the reason of the revocation, got %v", err)
+	}
+	if err := CheckEndorsementNotRevoked(otherHash, revocation); err != nil {
+		t.Errorf("Unexpected error for another endorsement of the same subject: %v", err)
+	}
+
+	// Endorsement revocations and revocations of subjects are not interchangeable.
+	if _, err := ParseRevocationBytes(revocationBytes); err == nil {
+		t.Errorf("Expected an error when parsing an endorsement revocation as a revocation")
+	}
+	subject := intoto.Subject{Name: "binary", Digest: intoto.DigestSet{"sha2-256": statusSubjectDigest}}
+	subjectRevocationBytes, err := json.Marshal(GenerateRevocationStatement(subject, "", FixedClock(issuedOn)))
+	if err != nil {
+		t.Fatalf("Failed to marshal the revocation: %v", err)
+	}
+	if _, err := ParseEndorsementRevocationBytes(subjectRevocationBytes); err == nil {
+		t.Errorf("Expected an error when parsing a revocation as an endorsement revocation")
+	}
+}
@@ -0,0 +1,188 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package claims
+
+// Property tests checking that the statements generated in this package are
+// accepted by the parsers in this package, and survive the round trip
+// unchanged, up to the normalization of the digests of their subjects.
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+	"testing/quick"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/project-oak/transparent-release/pkg/intoto"
+)
+
+// endorsementInput holds random inputs for generating an endorsement.
+type endorsementInput struct {
+	Subject     intoto.Subject
+	IssuedOn    time.Time
+	NotBefore   time.Time
+	NotAfter    time.Time
+	Provenances []ProvenanceData
+	Evidence    []ClaimEvidence
+	ClaimSpec   map[string]interface{}
+}
+
+// Generate implements quick.Generator.
+func (endorsementInput) Generate(r *rand.Rand, size int) reflect.Value {
+	issuedOn := randomTime(r)
+	notBefore := issuedOn.Add(time.Duration(r.Int63n(int64(30 * 24 * time.Hour))))
+	input := endorsementInput{
+		Subject:   randomSubject(r, size),
+		IssuedOn:  issuedOn,
+		NotBefore: notBefore,
+		NotAfter:  notBefore.Add(time.Duration(1 + r.Int63n(int64(365*24*time.Hour)))),
+	}
+	for i := r.Intn(size + 1); i > 0; i-- {
+		input.Provenances = append(input.Provenances, ProvenanceData{URI: randomURI(r), SHA256Digest: randomHex(r, 32)})
+	}
+	for i := r.Intn(size + 1); i > 0; i-- {
+		input.Evidence = append(input.Evidence, ClaimEvidence{
+			Role:   randomString(r, size),
+			URI:    randomURI(r),
+			Digest: intoto.DigestSet{"sha256": randomHex(r, 32)},
+		})
+	}
+	if r.Intn(2) == 0 {
+		input.ClaimSpec = map[string]interface{}{randomString(r, size): randomString(r, size)}
+	}
+	return reflect.ValueOf(input)
+}
+
+func TestEndorsementRoundTrip(t *testing.T) {
+	property := func(input endorsementInput) bool {
+		options := []func(c *EndorsementConfig){
+			WithClock(FixedClock(input.IssuedOn)),
+			WithEvidence(input.Evidence...),
+			WithSubjectMediaType(input.Subject.MediaType),
+		}
+		if input.ClaimSpec != nil {
+			options = append(options, WithClaimSpec(input.ClaimSpec))
+		}
+		config, err := NewEndorsementConfig(options...)
+		if err != nil {
+			t.Logf("Invalid endorsement config: %v", err)
+			return false
+		}
+		validity := ClaimValidity{NotBefore: &input.NotBefore, NotAfter: &input.NotAfter}
+		statement, err := GenerateEndorsementStatementWithConfig(config, validity, VerifiedProvenanceSet{
+			BinaryName:  input.Subject.Name,
+			Digests:     input.Subject.Digest,
+			Provenances: input.Provenances,
+		})
+		if err != nil {
+			t.Logf("Invalid subject digests: %v", err)
+			return false
+		}
+
+		return checkRoundTrip(t, statement, ParseEndorsementV2Bytes)
+	}
+	if err := quick.Check(property, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRevocationRoundTrip(t *testing.T) {
+	property := func(seed int64, reason string) bool {
+		r := rand.New(rand.NewSource(seed)) //nolint:gosec
+		statement := GenerateRevocationStatement(randomSubject(r, 10), reason, FixedClock(randomTime(r)))
+
+		return checkRoundTrip(t, statement, ParseRevocationBytes)
+	}
+	if err := quick.Check(property, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+// checkRoundTrip marshals the given statement, parses it with the given
+// parser, and checks that the parsed statement equals the given one, with
+// normalized digests of its subjects.
+func checkRoundTrip(t *testing.T, statement *intoto.Statement, parse func([]byte) (*intoto.Statement, error)) bool {
+	t.Helper()
+	statementBytes, err := json.Marshal(statement)
+	if err != nil {
+		t.Logf("Failed to marshal the statement: %v", err)
+		return false
+	}
+	parsed, err := parse(statementBytes)
+	if err != nil {
+		t.Logf("Failed to parse the generated statement %s: %v", statementBytes, err)
+		return false
+	}
+
+	want := *statement
+	if err := intoto.NormalizeStatementHeader(&want.StatementHeader); err != nil {
+		t.Logf("Failed to normalize the generated statement: %v", err)
+		return false
+	}
+	if diff := cmp.Diff(want, *parsed, cmpopts.EquateEmpty()); diff != "" {
+		t.Logf("The parsed statement differs from the generated one (-want +got):\n%s", diff)
+		return false
+	}
+	return true
+}
+
+// randomSubject returns a subject with a SHA256 digest, keyed either as in
+// in-toto or as in Transparent Release, and possibly other digests.
+func randomSubject(r *rand.Rand, size int) intoto.Subject {
+	sha256Keys := []string{"sha256", "sha2-256"}
+	subject := intoto.Subject{
+		Name:   randomString(r, size),
+		Digest: intoto.DigestSet{sha256Keys[r.Intn(len(sha256Keys))]: randomHex(r, 32)},
+	}
+	if r.Intn(2) == 0 {
+		subject.Digest["sha512"] = randomHex(r, 64)
+	}
+	if r.Intn(2) == 0 {
+		subject.MediaType = "application/octet-stream"
+	}
+	return subject
+}
+
+// randomTime returns a time in UTC, with nanosecond precision, between 2000
+// and 2100.
+func randomTime(r *rand.Rand) time.Time {
+	start := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	return start.Add(time.Duration(r.Int63n(int64(100 * 365 * 24 * time.Hour))))
+}
+
+func randomString(r *rand.Rand, size int) string {
+	value, _ := quick.Value(reflect.TypeOf(""), r)
+	runes := []rune(value.String())
+	if len(runes) > size {
+		runes = runes[:size]
+	}
+	return string(runes)
+}
+
+func randomHex(r *rand.Rand, length int) string {
+	bytes := make([]byte, length)
+	_, _ = r.Read(bytes)
+	return hex.EncodeToString(bytes)
+}
+
+func randomURI(r *rand.Rand) string {
+	return fmt.Sprintf("https://example.com/%s", randomHex(r, 8))
+}
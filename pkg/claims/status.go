@@ -0,0 +1,170 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package claims
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/project-oak/transparent-release/pkg/intoto"
+)
+
+// Status is the lifecycle state of a claim at a given time.
+type Status string
+
+const (
+	// StatusPending is the status of claims whose validity has not started yet.
+	StatusPending Status = "pending"
+	// StatusActive is the status of claims within their validity window.
+	StatusActive Status = "active"
+	// StatusExpiringSoon is the status of active claims that expire within
+	// the configured expiry window.
+	StatusExpiringSoon Status = "expiring-soon"
+	// StatusExpired is the status of claims whose validity has ended.
+	StatusExpired Status = "expired"
+	// StatusRevoked is the status of claims about a revoked subject.
+	StatusRevoked Status = "revoked"
+	// StatusSuperseded is the status of claims for which a claim of the same
+	// type about the same subject has been issued later, e.g., a renewal.
+	StatusSuperseded Status = "superseded"
+)
+
+// DefaultExpiringSoonWindow is the default window before the end of the
+// validity of a claim, in which the claim is reported as expiring soon.
+const DefaultExpiringSoonWindow = 14 * 24 * time.Hour
+
+// StatusConfig holds optional settings for computing the status of claims.
+type StatusConfig struct {
+	clock              Clock
+	expiringSoonWindow time.Duration
+	revokedSubjects    map[string]bool
+}
+
+// WithStatusClock sets the clock providing the time at which the status is
+// computed. Defaults to the system clock.
+func WithStatusClock(clock Clock) func(c *StatusConfig) {
+	return func(c *StatusConfig) {
+		c.clock = clock
+	}
+}
+
+// WithExpiringSoonWindow overrides DefaultExpiringSoonWindow.
+func WithExpiringSoonWindow(window time.Duration) func(c *StatusConfig) {
+	return func(c *StatusConfig) {
+		c.expiringSoonWindow = window
+	}
+}
+
+// WithRevokedSubjects marks all claims about subjects with any of the given
+// hex-encoded SHA2-256 digests as revoked.
+func WithRevokedSubjects(sha256Digests ...string) func(c *StatusConfig) {
+	return func(c *StatusConfig) {
+		for _, digest := range sha256Digests {
+			c.revokedSubjects[strings.ToLower(digest)] = true
+		}
+	}
+}
+
+func newStatusConfig(options ...func(c *StatusConfig)) *StatusConfig {
+	config := &StatusConfig{
+		clock:              SystemClock(),
+		expiringSoonWindow: DefaultExpiringSoonWindow,
+		revokedSubjects:    make(map[string]bool),
+	}
+	for _, addOption := range options {
+		addOption(config)
+	}
+	return config
+}
+
+// ClaimStatus returns the status of the given claim, without taking other
+// claims into account. The predicate of the statement must be a
+// ClaimPredicate, as returned by ParseEndorsementV2Bytes.
+func ClaimStatus(statement *intoto.Statement, options ...func(c *StatusConfig)) (Status, error) {
+	statuses, err := ClaimStatuses([]*intoto.Statement{statement}, options...)
+	if err != nil {
+		return "", err
+	}
+	return statuses[0], nil
+}
+
+// ClaimStatuses returns the status of each of the given claims. A claim is
+// superseded if another of the given claims has the same claim type and
+// subject, and has been issued later. Revocation takes precedence over
+// supersession, which takes precedence over the validity window.
+func ClaimStatuses(statements []*intoto.Statement, options ...func(c *StatusConfig)) ([]Status, error) {
+	config := newStatusConfig(options...)
+
+	predicates := make([]*ClaimPredicate, 0, len(statements))
+	for i, statement := range statements {
+		predicate, ok := statement.Predicate.(ClaimPredicate)
+		if !ok {
+			return nil, fmt.Errorf("the predicate of claim #%d does not have the expected type; got: %T, want: ClaimPredicate", i, statement.Predicate)
+		}
+		if len(statement.Subject) != 1 || predicate.IssuedOn == nil || predicate.Validity == nil ||
+			predicate.Validity.NotBefore == nil || predicate.Validity.NotAfter == nil {
+			return nil, fmt.Errorf("claim #%d must have a single subject, an issuance time, and a validity", i)
+		}
+		predicates = append(predicates, &predicate)
+	}
+
+	now := config.clock.Now()
+	statuses := make([]Status, 0, len(statements))
+	for i, statement := range statements {
+		predicate := predicates[i]
+		switch {
+		case config.revokedSubjects[subjectSHA256Digest(statement)]:
+			statuses = append(statuses, StatusRevoked)
+		case isSuperseded(i, statements, predicates):
+			statuses = append(statuses, StatusSuperseded)
+		case now.Before(*predicate.Validity.NotBefore):
+			statuses = append(statuses, StatusPending)
+		case !now.Before(*predicate.Validity.NotAfter):
+			statuses = append(statuses, StatusExpired)
+		case predicate.Validity.NotAfter.Sub(now) <= config.expiringSoonWindow:
+			statuses = append(statuses, StatusExpiringSoon)
+		default:
+			statuses = append(statuses, StatusActive)
+		}
+	}
+	return statuses, nil
+}
+
+// isSuperseded returns true if any other claim has the same claim type and
+// subject as the i-th claim, and has been issued after it.
+func isSuperseded(i int, statements []*intoto.Statement, predicates []*ClaimPredicate) bool {
+	digest := subjectSHA256Digest(statements[i])
+	if digest == "" {
+		return false
+	}
+	for j, other := range statements {
+		if j == i || predicates[j].ClaimType != predicates[i].ClaimType {
+			continue
+		}
+		if subjectSHA256Digest(other) == digest && predicates[j].IssuedOn.After(*predicates[i].IssuedOn) {
+			return true
+		}
+	}
+	return false
+}
+
+func subjectSHA256Digest(statement *intoto.Statement) string {
+	digests := statement.Subject[0].Digest
+	if digest, ok := digests["sha2-256"]; ok {
+		return strings.ToLower(digest)
+	}
+	return strings.ToLower(digests["sha256"])
+}
@@ -0,0 +1,112 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package claims
+
+import (
+	"testing"
+	"time"
+
+	"github.com/project-oak/transparent-release/pkg/intoto"
+)
+
+const (
+	statusSubjectDigest      = "d059c38cea82047ad316a1c6c6fbd13ecf7a0abdcc375463920bd25bf5c142cc"
+	otherStatusSubjectDigest = "813841dda3818d616aa3e706e49d0286dc825c5dbad4a75cfb37b91ba412238b"
+)
+
+// issuedOn is the issuance time of the endorsements created by newEndorsement.
+//
+//nolint:gochecknoglobals
+var issuedOn = time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+
+// newEndorsement returns an endorsement for the given subject, issued at
+// issuedOn plus the given offset in days, and valid for 30 days from then.
+func newEndorsement(digest string, offsetDays int) *intoto.Statement {
+	clock := FixedClock(issuedOn.AddDate(0, 0, offsetDays))
+	notBefore := clock.Now()
+	notAfter := notBefore.AddDate(0, 0, 30)
+	config, _ := NewEndorsementConfig(WithClock(clock))
+	statement, _ := GenerateEndorsementStatementWithConfig(config, ClaimValidity{NotBefore: &notBefore, NotAfter: &notAfter},
+		VerifiedProvenanceSet{BinaryName: "binary", Digests: intoto.DigestSet{"sha2-256": digest}})
+	return statement
+}
+
+func TestClaimStatus_ValidityWindow(t *testing.T) {
+	endorsement := newEndorsement(statusSubjectDigest, 0)
+	tests := map[Status]time.Time{
+		StatusPending:      issuedOn.Add(-time.Hour),
+		StatusActive:       issuedOn.AddDate(0, 0, 1),
+		StatusExpiringSoon: issuedOn.AddDate(0, 0, 20),
+		StatusExpired:      issuedOn.AddDate(0, 0, 30),
+	}
+	for want, now := range tests {
+		got, err := ClaimStatus(endorsement, WithStatusClock(FixedClock(now)))
+		if err != nil {
+			t.Fatalf("Failed to compute the status: %v", err)
+		}
+		if got != want {
+			t.Errorf("Unexpected status at %v: got %s, want %s", now, got, want)
+		}
+	}
+}
+
+func TestClaimStatus_ExpiringSoonWindow(t *testing.T) {
+	endorsement := newEndorsement(statusSubjectDigest, 0)
+	now := FixedClock(issuedOn.AddDate(0, 0, 20))
+
+	got, err := ClaimStatus(endorsement, WithStatusClock(now), WithExpiringSoonWindow(24*time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to compute the status: %v", err)
+	}
+	if got != StatusActive {
+		t.Errorf("Unexpected status: got %s, want %s", got, StatusActive)
+	}
+}
+
+func TestClaimStatuses_RevokedAndSuperseded(t *testing.T) {
+	statements := []*intoto.Statement{
+		newEndorsement(statusSubjectDigest, 0),
+		// A renewal of the first endorsement.
+		newEndorsement(statusSubjectDigest, 5),
+		newEndorsement(otherStatusSubjectDigest, 0),
+	}
+	now := FixedClock(issuedOn.AddDate(0, 0, 6))
+
+	got, err := ClaimStatuses(statements, WithStatusClock(now))
+	if err != nil {
+		t.Fatalf("Failed to compute the statuses: %v", err)
+	}
+	want := []Status{StatusSuperseded, StatusActive, StatusActive}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Unexpected status of #%d: got %s, want %s", i, got[i], want[i])
+		}
+	}
+
+	got, err = ClaimStatuses(statements, WithStatusClock(now), WithRevokedSubjects(otherStatusSubjectDigest))
+	if err != nil {
+		t.Fatalf("Failed to compute the statuses: %v", err)
+	}
+	if got[2] != StatusRevoked {
+		t.Errorf("Unexpected status of the revoked claim: got %s, want %s", got[2], StatusRevoked)
+	}
+}
+
+func TestClaimStatus_InvalidPredicate(t *testing.T) {
+	statement := &intoto.Statement{Predicate: map[string]interface{}{}}
+	if _, err := ClaimStatus(statement); err == nil {
+		t.Errorf("Expected an error for a predicate that is not a ClaimPredicate")
+	}
+}
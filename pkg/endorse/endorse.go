@@ -0,0 +1,137 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package endorse is the public entry point for generating endorsements, for
+// release tooling that generates endorsements as a library instead of running
+// cmd/endorser. VerificationOptions can be parsed with the verify package.
+//
+// The API of this package is stable: it follows semantic versioning, so that
+// existing identifiers are only removed or changed incompatibly in a new
+// major version of the module.
+package endorse
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/project-oak/transparent-release/internal/endorser"
+	"github.com/project-oak/transparent-release/pkg/claims"
+	"github.com/project-oak/transparent-release/pkg/intoto"
+	pb "github.com/project-oak/transparent-release/pkg/proto/oak/release"
+)
+
+// DefaultParallelism is the default maximum number of provenances that
+// LoadProvenances loads concurrently.
+const DefaultParallelism = endorser.DefaultLoadParallelism
+
+// ParsedProvenance is a provenance loaded by LoadProvenances. Its
+// format-independent representation is not part of the API; only the URI and
+// digest of the document it was loaded from are exposed.
+type ParsedProvenance struct {
+	// URI of the document that the provenance was loaded from.
+	URI string
+	// SHA256Digest is the hex-encoded SHA2-256 digest of the document.
+	SHA256Digest string
+
+	parsed *endorser.ParsedProvenance
+}
+
+// LoadConfig holds optional settings for loading provenances.
+type LoadConfig struct {
+	options []func(c *endorser.LoadConfig)
+}
+
+// WithRequireEnvelope makes loading fail for provenances given as bare in-toto
+// statements, so that only provenances wrapped in a DSSE envelope are
+// accepted. The signatures on the envelopes are not verified.
+func WithRequireEnvelope() func(c *LoadConfig) {
+	return func(c *LoadConfig) {
+		c.options = append(c.options, endorser.WithRequireEnvelope())
+	}
+}
+
+// WithSubjectName selects the subject with the given name from provenances
+// with several subjects.
+func WithSubjectName(name string) func(c *LoadConfig) {
+	return func(c *LoadConfig) {
+		c.options = append(c.options, endorser.WithSubjectName(name))
+	}
+}
+
+// WithSubjectDigests selects the subject with the given digests, e.g., of the
+// binary to endorse, from provenances with several subjects. Can be combined
+// with WithSubjectName.
+func WithSubjectDigests(digests intoto.DigestSet) func(c *LoadConfig) {
+	return func(c *LoadConfig) {
+		c.options = append(c.options, endorser.WithSubjectDigests(digests))
+	}
+}
+
+// WithParallelism sets the maximum number of provenances that LoadProvenances
+// loads concurrently, instead of DefaultParallelism.
+func WithParallelism(parallelism int) func(c *LoadConfig) {
+	return func(c *LoadConfig) {
+		c.options = append(c.options, endorser.WithLoadParallelism(parallelism))
+	}
+}
+
+// LoadProvenances fetches and parses the provenances at the given URIs, with
+// the `file`, `http(s)`, `gs`, or `s3` schemes, among others. Returns the
+// provenances in the order of the URIs, or the errors of all provenances for
+// which loading fails.
+func LoadProvenances(ctx context.Context, provenanceURIs []string, options ...func(c *LoadConfig)) ([]ParsedProvenance, error) {
+	config := &LoadConfig{}
+	for _, addOption := range options {
+		addOption(config)
+	}
+	parsed, err := endorser.LoadProvenances(ctx, provenanceURIs, config.options...)
+	if err != nil {
+		return nil, err
+	}
+	provenances := make([]ParsedProvenance, 0, len(parsed))
+	for i := range parsed {
+		provenances = append(provenances, ParsedProvenance{
+			URI:          parsed[i].SourceMetadata.URI,
+			SHA256Digest: parsed[i].SourceMetadata.SHA256Digest,
+			parsed:       &parsed[i],
+		})
+	}
+	return provenances, nil
+}
+
+// GenerateEndorsement verifies that the given provenances are for the binary
+// with the given name and digests, and satisfy the given VerificationOptions,
+// and generates an endorsement statement for the binary with the given
+// validity, referencing the provenances as evidence. At least one provenance,
+// as returned by LoadProvenances, is required. The optional EndorsementConfig
+// options select the format of the statement.
+func GenerateEndorsement(binaryName string, digests intoto.DigestSet, options *pb.VerificationOptions, validity claims.ClaimValidity, provenances []ParsedProvenance, endorsementOptions ...func(c *claims.EndorsementConfig)) (*intoto.Statement, error) {
+	if options == nil {
+		return nil, fmt.Errorf("options must not be nil")
+	}
+	if validity.NotBefore == nil || validity.NotAfter == nil {
+		return nil, fmt.Errorf("the validity must have a start and an end")
+	}
+	if len(provenances) == 0 {
+		return nil, fmt.Errorf("at least one provenance is required")
+	}
+	parsed := make([]endorser.ParsedProvenance, 0, len(provenances))
+	for i, provenance := range provenances {
+		if provenance.parsed == nil {
+			return nil, fmt.Errorf("provenance #%d was not loaded with LoadProvenances", i)
+		}
+		parsed = append(parsed, *provenance.parsed)
+	}
+	return endorser.GenerateEndorsement(binaryName, digests, options, validity, parsed, endorsementOptions...)
+}
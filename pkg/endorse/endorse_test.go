@@ -0,0 +1,114 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package endorse
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/project-oak/transparent-release/pkg/claims"
+	"github.com/project-oak/transparent-release/pkg/intoto"
+	"github.com/project-oak/transparent-release/pkg/verify"
+)
+
+const (
+	binaryName   = "oak_functions_freestanding_bin"
+	binaryDigest = "d059c38cea82047ad316a1c6c6fbd13ecf7a0abdcc375463920bd25bf5c142cc"
+)
+
+func provenanceURI(t *testing.T) string {
+	path, err := filepath.Abs("../../testdata/slsa_v02_provenance.json")
+	if err != nil {
+		t.Fatalf("could not resolve the provenance path: %v", err)
+	}
+	return "file://" + path
+}
+
+func validity() claims.ClaimValidity {
+	notBefore := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	notAfter := notBefore.AddDate(0, 0, 90)
+	return claims.ClaimValidity{NotBefore: &notBefore, NotAfter: &notAfter}
+}
+
+func TestGenerateEndorsement(t *testing.T) {
+	digests := intoto.DigestSet{"sha2-256": binaryDigest}
+	provenances, err := LoadProvenances(context.Background(), []string{provenanceURI(t)}, WithSubjectDigests(digests))
+	if err != nil {
+		t.Fatalf("could not load the provenances: %v", err)
+	}
+	options, err := verify.ParseOptions(`all_with_binary_name { binary_name: "` + binaryName + `" }`)
+	if err != nil {
+		t.Fatalf("could not parse the options: %v", err)
+	}
+
+	endorsement, err := GenerateEndorsement(binaryName, digests, options, validity(), provenances)
+	if err != nil {
+		t.Fatalf("could not generate the endorsement: %v", err)
+	}
+	if got := endorsement.Subject[0].Name; got != binaryName {
+		t.Errorf("unexpected subject name: %q", got)
+	}
+	predicate := endorsement.Predicate.(claims.ClaimPredicate)
+	if provenances[0].URI != provenanceURI(t) {
+		t.Errorf("unexpected provenance URI: %q", provenances[0].URI)
+	}
+	if len(predicate.Evidence) != 1 || predicate.Evidence[0].URI != provenanceURI(t) {
+		t.Errorf("unexpected evidence: %+v", predicate.Evidence)
+	}
+}
+
+func TestGenerateEndorsement_FailedVerification(t *testing.T) {
+	digests := intoto.DigestSet{"sha2-256": binaryDigest}
+	provenances, err := LoadProvenances(context.Background(), []string{provenanceURI(t)})
+	if err != nil {
+		t.Fatalf("could not load the provenances: %v", err)
+	}
+	options, err := verify.ParseOptions(`all_with_binary_name { binary_name: "other_bin" }`)
+	if err != nil {
+		t.Fatalf("could not parse the options: %v", err)
+	}
+	if _, err := GenerateEndorsement(binaryName, digests, options, validity(), provenances); err == nil {
+		t.Errorf("expected an error for provenances that fail the verification")
+	}
+}
+
+func TestGenerateEndorsement_InvalidArguments(t *testing.T) {
+	digests := intoto.DigestSet{"sha2-256": binaryDigest}
+	if _, err := GenerateEndorsement(binaryName, digests, nil, validity(), nil); err == nil {
+		t.Errorf("expected an error for nil options")
+	}
+	options, err := verify.ParseOptions("")
+	if err != nil {
+		t.Fatalf("could not parse the options: %v", err)
+	}
+	if _, err := GenerateEndorsement(binaryName, digests, options, claims.ClaimValidity{}, nil); err == nil {
+		t.Errorf("expected an error for an empty validity")
+	}
+	if _, err := GenerateEndorsement(binaryName, digests, options, validity(), nil); err == nil {
+		t.Errorf("expected an error for no provenances")
+	}
+	provenances := []ParsedProvenance{{URI: provenanceURI(t), SHA256Digest: binaryDigest}}
+	if _, err := GenerateEndorsement(binaryName, digests, options, validity(), provenances); err == nil {
+		t.Errorf("expected an error for a provenance that was not loaded")
+	}
+}
+
+func TestLoadProvenances_InvalidParallelism(t *testing.T) {
+	if _, err := LoadProvenances(context.Background(), []string{provenanceURI(t)}, WithParallelism(0)); err == nil {
+		t.Errorf("expected an error for a parallelism of 0")
+	}
+}
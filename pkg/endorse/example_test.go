@@ -0,0 +1,54 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package endorse_test
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"time"
+
+	"github.com/project-oak/transparent-release/pkg/claims"
+	"github.com/project-oak/transparent-release/pkg/endorse"
+	"github.com/project-oak/transparent-release/pkg/intoto"
+	"github.com/project-oak/transparent-release/pkg/verify"
+)
+
+func ExampleGenerateEndorsement() {
+	path, err := filepath.Abs("../../testdata/slsa_v02_provenance.json")
+	if err != nil {
+		log.Fatal(err)
+	}
+	digests := intoto.DigestSet{"sha2-256": "d059c38cea82047ad316a1c6c6fbd13ecf7a0abdcc375463920bd25bf5c142cc"}
+	provenances, err := endorse.LoadProvenances(context.Background(), []string{"file://" + path}, endorse.WithSubjectDigests(digests))
+	if err != nil {
+		log.Fatal(err)
+	}
+	options, err := verify.ParseOptions(`all_with_binary_name { binary_name: "oak_functions_freestanding_bin" }`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	notBefore := time.Now()
+	notAfter := notBefore.AddDate(0, 0, 90)
+	validity := claims.ClaimValidity{NotBefore: &notBefore, NotAfter: &notAfter}
+	endorsement, err := endorse.GenerateEndorsement("oak_functions_freestanding_bin", digests, options, validity, provenances)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(endorsement.Subject[0].Name)
+	// Output: oak_functions_freestanding_bin
+}
@@ -0,0 +1,198 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gate
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// maxAdmissionReviewBytes limits the size of admission reviews.
+const maxAdmissionReviewBytes = 4 << 20
+
+// AdmissionReview is the subset of a Kubernetes admission.k8s.io/v1
+// AdmissionReview used by AdmissionHandler.
+type AdmissionReview struct {
+	APIVersion string             `json:"apiVersion"`
+	Kind       string             `json:"kind"`
+	Request    *AdmissionRequest  `json:"request,omitempty"`
+	Response   *AdmissionResponse `json:"response,omitempty"`
+}
+
+// AdmissionRequest is the subset of an admission request used by
+// AdmissionHandler.
+type AdmissionRequest struct {
+	UID string `json:"uid"`
+	// Object is the object to admit, e.g., a Pod or a Deployment.
+	Object json.RawMessage `json:"object"`
+}
+
+// AdmissionResponse is the response to an AdmissionRequest.
+type AdmissionResponse struct {
+	UID     string           `json:"uid"`
+	Allowed bool             `json:"allowed"`
+	Status  *AdmissionStatus `json:"status,omitempty"`
+}
+
+// AdmissionStatus explains a denied AdmissionRequest.
+type AdmissionStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// podSpec is the subset of a Kubernetes PodSpec listing the images.
+type podSpec struct {
+	Containers          []container `json:"containers"`
+	InitContainers      []container `json:"initContainers"`
+	EphemeralContainers []container `json:"ephemeralContainers"`
+}
+
+type container struct {
+	Image string `json:"image"`
+}
+
+type podTemplate struct {
+	Spec podSpec `json:"spec"`
+}
+
+// admissionObject is the subset of a Pod, or of a workload with a pod
+// template, e.g., a Deployment, a Job, or a CronJob, listing the images.
+type admissionObject struct {
+	Kind string `json:"kind"`
+	Spec struct {
+		podSpec
+		Template    *podTemplate `json:"template"`
+		JobTemplate *struct {
+			Spec struct {
+				Template *podTemplate `json:"template"`
+			} `json:"spec"`
+		} `json:"jobTemplate"`
+	} `json:"spec"`
+}
+
+// podTemplateKinds are the kinds of workloads with a pod template in
+// `spec.template`.
+var podTemplateKinds = map[string]bool{
+	"DaemonSet":             true,
+	"Deployment":            true,
+	"Job":                   true,
+	"ReplicaSet":            true,
+	"ReplicationController": true,
+	"StatefulSet":           true,
+}
+
+// images returns the images of all containers in the given object. Fails if
+// the kind of the object is not known, so that pods of unknown workloads are
+// never admitted without checking their images, or if the object has no
+// images.
+func (o *admissionObject) images() ([]string, error) {
+	var spec *podSpec
+	switch {
+	case o.Kind == "Pod":
+		spec = &o.Spec.podSpec
+	case o.Kind == "CronJob":
+		if o.Spec.JobTemplate != nil && o.Spec.JobTemplate.Spec.Template != nil {
+			spec = &o.Spec.JobTemplate.Spec.Template.Spec
+		}
+	case podTemplateKinds[o.Kind]:
+		if o.Spec.Template != nil {
+			spec = &o.Spec.Template.Spec
+		}
+	default:
+		return nil, fmt.Errorf("unsupported kind %q", o.Kind)
+	}
+	if spec == nil {
+		return nil, fmt.Errorf("the %s has no pod template", o.Kind)
+	}
+
+	var images []string
+	for _, containers := range [][]container{spec.Containers, spec.InitContainers, spec.EphemeralContainers} {
+		for _, c := range containers {
+			images = append(images, c.Image)
+		}
+	}
+	if len(images) == 0 {
+		return nil, fmt.Errorf("the %s has no container images", o.Kind)
+	}
+	return images, nil
+}
+
+// AdmissionHandler returns an HTTP handler for a Kubernetes validating
+// admission webhook, which admits Pods, and workloads with pod templates, only
+// if the given gate allows all their images. Objects of other kinds, and
+// objects without images, are denied.
+func AdmissionHandler(gate *Gate) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "only POST is supported", http.StatusMethodNotAllowed)
+			return
+		}
+		var review AdmissionReview
+		if err := json.NewDecoder(io.LimitReader(r.Body, maxAdmissionReviewBytes)).Decode(&review); err != nil {
+			http.Error(w, fmt.Sprintf("parsing the admission review: %v", err), http.StatusBadRequest)
+			return
+		}
+		if review.Request == nil {
+			http.Error(w, "the admission review has no request", http.StatusBadRequest)
+			return
+		}
+
+		review.Response = admit(r, gate, review.Request)
+		review.Request = nil
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(review); err != nil {
+			log.Printf("Failed to write the admission response: %v", err)
+		}
+	})
+}
+
+// admit decides the given admission request.
+func admit(r *http.Request, gate *Gate, request *AdmissionRequest) *AdmissionResponse {
+	response := &AdmissionResponse{UID: request.UID}
+	var object admissionObject
+	if err := json.Unmarshal(request.Object, &object); err != nil {
+		response.Status = &AdmissionStatus{Code: http.StatusBadRequest, Message: fmt.Sprintf("parsing the object: %v", err)}
+		return response
+	}
+
+	images, err := object.images()
+	if err != nil {
+		response.Status = &AdmissionStatus{Code: http.StatusForbidden, Message: err.Error()}
+		return response
+	}
+
+	var denials []string
+	for _, image := range images {
+		decision := gate.Check(r.Context(), image)
+		log.Printf("Admission of %s: allowed=%t, reasons=%q", image, decision.Allowed, decision.Reasons)
+		if !decision.Allowed {
+			denials = append(denials, fmt.Sprintf("%s: %s", image, strings.Join(decision.Reasons, "; ")))
+		}
+	}
+	if len(denials) > 0 {
+		response.Status = &AdmissionStatus{
+			Code:    http.StatusForbidden,
+			Message: "images without a valid endorsement: " + strings.Join(denials, "; "),
+		}
+		return response
+	}
+	response.Allowed = true
+	return response
+}
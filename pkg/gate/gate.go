@@ -0,0 +1,291 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package gate decides whether container images may be deployed, based on
+// signed endorsements of their digests. It is meant for enforcing
+// transparent-release endorsements at deploy time, e.g., in a Kubernetes
+// admission webhook; see AdmissionHandler.
+package gate
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/secure-systems-lab/go-securesystemslib/dsse"
+
+	"github.com/project-oak/transparent-release/internal/rekor"
+	"github.com/project-oak/transparent-release/pkg/bundle"
+	"github.com/project-oak/transparent-release/pkg/claims"
+	"github.com/project-oak/transparent-release/pkg/intoto"
+)
+
+// LogEntry is a Rekor log entry of a signed endorsement.
+type LogEntry = rekor.LogEntry
+
+// SignedEndorsement is an endorsement, as a signed DSSE envelope, found by a
+// Source.
+type SignedEndorsement struct {
+	Envelope *dsse.Envelope
+	// LogEntry is the Rekor log entry of the envelope, if known to the source.
+	LogEntry *LogEntry
+	// Origin describes where the endorsement was found, e.g., a file path.
+	Origin string
+	// Bundle is the release bundle with the envelope, if it was found in one.
+	// The provenances in the bundle are verified along with the envelope.
+	Bundle *bundle.Bundle
+}
+
+// Source finds candidate endorsements of an image. Sources need not verify
+// the endorsements; this is done by Gate.
+type Source interface {
+	Endorsements(ctx context.Context, image *Image) ([]SignedEndorsement, error)
+}
+
+// Config holds optional settings for a Gate.
+type Config struct {
+	sources        []Source
+	clock          claims.Clock
+	statusOptions  []func(c *claims.StatusConfig)
+	rekorPublicKey *ecdsa.PublicKey
+	rekorClient    *rekor.Client
+}
+
+// WithSource adds a source of endorsements. At least one source is required.
+func WithSource(source Source) func(c *Config) {
+	return func(c *Config) {
+		c.sources = append(c.sources, source)
+	}
+}
+
+// WithClock sets the clock for checking the validity of endorsements.
+// Defaults to the system clock.
+func WithClock(clock claims.Clock) func(c *Config) {
+	return func(c *Config) {
+		c.clock = clock
+	}
+}
+
+// WithRevokedDigests denies images with any of the given hex-encoded SHA2-256
+// digests, regardless of their endorsements.
+func WithRevokedDigests(sha256Digests ...string) func(c *Config) {
+	return func(c *Config) {
+		c.statusOptions = append(c.statusOptions, claims.WithRevokedSubjects(sha256Digests...))
+	}
+}
+
+// WithRekor requires endorsements to be included in the Rekor log with the
+// given public key. Log entries not provided by the source are looked up in
+// the Rekor instance at rekorURL, unless rekorURL is empty.
+func WithRekor(publicKey *ecdsa.PublicKey, rekorURL string) func(c *Config) {
+	return func(c *Config) {
+		c.rekorPublicKey = publicKey
+		if rekorURL != "" {
+			c.rekorClient = rekor.NewClient(rekorURL)
+		}
+	}
+}
+
+// Decision is the outcome of checking an image.
+type Decision struct {
+	Image   string `json:"image"`
+	Allowed bool   `json:"allowed"`
+	// Reasons explain the decision. If the image is denied, there is a
+	// reason for every rejected endorsement.
+	Reasons []string `json:"reasons"`
+	// Endorsement is the endorsement that allowed the image, if any.
+	Endorsement *intoto.Statement `json:"endorsement,omitempty"`
+}
+
+func deny(image string, reasons ...string) *Decision {
+	return &Decision{Image: image, Allowed: false, Reasons: reasons}
+}
+
+// Gate allows images with a valid endorsement, signed by the endorser, from
+// any of its sources.
+type Gate struct {
+	endorser dsse.Verifier
+	config   *Config
+}
+
+// New creates a Gate that accepts endorsements signed by the given verifier,
+// e.g., the public key of the product team.
+func New(endorser dsse.Verifier, options ...func(c *Config)) (*Gate, error) {
+	if endorser == nil {
+		return nil, fmt.Errorf("the endorser verifier must be set")
+	}
+	config := &Config{clock: claims.SystemClock()}
+	for _, addOption := range options {
+		addOption(config)
+	}
+	if len(config.sources) == 0 {
+		return nil, fmt.Errorf("at least one source of endorsements is required")
+	}
+	return &Gate{endorser: endorser, config: config}, nil
+}
+
+// Check decides whether the given image may be deployed. The image must be
+// pinned by its SHA2-256 digest, e.g., `gcr.io/project/app@sha256:...`.
+func (g *Gate) Check(ctx context.Context, imageRef string) *Decision {
+	image, err := ParseImage(imageRef)
+	if err != nil {
+		return deny(imageRef, err.Error())
+	}
+
+	var reasons []string
+	for _, source := range g.config.sources {
+		endorsements, err := source.Endorsements(ctx, image)
+		if err != nil {
+			reasons = append(reasons, fmt.Sprintf("querying endorsements: %v", err))
+			continue
+		}
+		for i := range endorsements {
+			statement, err := g.verify(ctx, image, &endorsements[i])
+			if err != nil {
+				reasons = append(reasons, fmt.Sprintf("%s: %v", endorsements[i].Origin, err))
+				continue
+			}
+			return &Decision{
+				Image:       imageRef,
+				Allowed:     true,
+				Reasons:     []string{fmt.Sprintf("endorsed by %s", endorsements[i].Origin)},
+				Endorsement: statement,
+			}
+		}
+	}
+	if len(reasons) == 0 {
+		reasons = append(reasons, fmt.Sprintf("no endorsements found for sha256:%s", image.Digest))
+	}
+	return deny(imageRef, reasons...)
+}
+
+// verify verifies the given endorsement of the given image, and returns the
+// endorsement statement.
+func (g *Gate) verify(ctx context.Context, image *Image, endorsement *SignedEndorsement) (*intoto.Statement, error) {
+	envelope := endorsement.Envelope
+	if envelope == nil {
+		return nil, fmt.Errorf("missing envelope")
+	}
+	if envelope.PayloadType != intoto.PayloadType {
+		return nil, fmt.Errorf("unexpected payload type %q", envelope.PayloadType)
+	}
+	if endorsement.Bundle != nil {
+		if err := g.verifyBundle(ctx, endorsement.Bundle); err != nil {
+			return nil, err
+		}
+	}
+	envelopeVerifier, err := dsse.NewEnvelopeVerifier(g.endorser)
+	if err != nil {
+		return nil, fmt.Errorf("creating an envelope verifier: %v", err)
+	}
+	if _, err := envelopeVerifier.Verify(ctx, envelope); err != nil {
+		return nil, fmt.Errorf("verifying the signature: %v", err)
+	}
+
+	payload, err := envelope.DecodeB64Payload()
+	if err != nil {
+		return nil, fmt.Errorf("decoding the payload: %v", err)
+	}
+	statement, err := claims.ParseEndorsementV2Bytes(payload)
+	if err != nil {
+		return nil, fmt.Errorf("parsing the endorsement: %v", err)
+	}
+	if !endorses(statement, image.Digest) {
+		return nil, fmt.Errorf("the endorsement is not about sha256:%s", image.Digest)
+	}
+
+	statusOptions := append([]func(c *claims.StatusConfig){claims.WithStatusClock(g.config.clock)}, g.config.statusOptions...)
+	status, err := claims.ClaimStatus(statement, statusOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("checking the validity: %v", err)
+	}
+	if status != claims.StatusActive && status != claims.StatusExpiringSoon {
+		return nil, fmt.Errorf("the endorsement is %s", status)
+	}
+
+	if g.config.rekorPublicKey != nil {
+		if err := g.verifyLogEntry(ctx, envelope, payload, endorsement.LogEntry); err != nil {
+			return nil, err
+		}
+	}
+	return statement, nil
+}
+
+// verifyBundle verifies the given release bundle, including the provenances
+// referenced by its endorsement, with the Rekor public key and the public key
+// of the endorser.
+func (g *Gate) verifyBundle(ctx context.Context, releaseBundle *bundle.Bundle) error {
+	if g.config.rekorPublicKey == nil {
+		return fmt.Errorf("verifying a release bundle requires a Rekor public key")
+	}
+	if _, err := releaseBundle.Verify(ctx, g.config.rekorPublicKey, g.endorser.Public()); err != nil {
+		return fmt.Errorf("verifying the release bundle: %v", err)
+	}
+	return nil
+}
+
+// verifyLogEntry verifies that the given envelope is included in the Rekor
+// log, using the given entry, or the entries found in Rekor if it is nil.
+func (g *Gate) verifyLogEntry(ctx context.Context, envelope *dsse.Envelope, payload []byte, entry *LogEntry) error {
+	entries := []*LogEntry{}
+	if entry != nil {
+		entries = append(entries, entry)
+	} else if g.config.rekorClient != nil {
+		sum256 := sha256.Sum256(payload)
+		uuids, err := g.config.rekorClient.SearchByHash(ctx, hex.EncodeToString(sum256[:]))
+		if err != nil {
+			return fmt.Errorf("searching Rekor: %v", err)
+		}
+		for _, uuid := range uuids {
+			found, err := g.config.rekorClient.GetLogEntry(ctx, uuid)
+			if err != nil {
+				return fmt.Errorf("fetching the Rekor log entry: %v", err)
+			}
+			entries = append(entries, found)
+		}
+	}
+	if len(entries) == 0 {
+		return fmt.Errorf("no Rekor log entry found")
+	}
+
+	var errs []string
+	for _, entry := range entries {
+		if err := rekor.VerifyEnvelopeLogEntry(ctx, envelope, entry, g.endorser); err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		if err := rekor.VerifyLogEntry(entry, g.config.rekorPublicKey); err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		return nil
+	}
+	return fmt.Errorf("verifying the Rekor log entry: %s", strings.Join(errs, "; "))
+}
+
+// endorses returns true if a subject of the given statement has the given
+// hex-encoded SHA2-256 digest.
+func endorses(statement *intoto.Statement, sha256Digest string) bool {
+	for _, subject := range statement.Subject {
+		for _, key := range []string{"sha2-256", "sha256"} {
+			if strings.EqualFold(subject.Digest[key], sha256Digest) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,369 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gate
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/project-oak/transparent-release/internal/testutil"
+	"github.com/project-oak/transparent-release/pkg/bundle"
+	"github.com/project-oak/transparent-release/pkg/claims"
+	"github.com/project-oak/transparent-release/pkg/sign"
+)
+
+const (
+	fixturesPath = "../../testdata/rekor"
+	// endorsedDigest is the subject digest of the endorsement in the fixtures,
+	// which is valid from 2022-07-08 to 2022-08-08.
+	endorsedDigest = "01b792106ef1f61eece3a666ac6069875fc90b942fefc3fe931f016395bb6c88"
+	otherDigest    = "322527c0260e25f0e9a2595bd0d71a52294fe2397a7af76165190fd98de8920d"
+	testUUID       = "24296fb24b8ad77a"
+)
+
+var (
+	endorsedImage = "gcr.io/oak/functions:v1@sha256:" + endorsedDigest
+	otherImage    = "gcr.io/oak/functions@sha256:" + otherDigest
+)
+
+func validClock() claims.Clock {
+	return claims.FixedClock(time.Date(2022, 7, 20, 0, 0, 0, 0, time.UTC))
+}
+
+func readFixture(t *testing.T, name string) []byte {
+	bytes, err := os.ReadFile(filepath.Join(fixturesPath, name))
+	if err != nil {
+		t.Fatalf("could not read fixture %s: %v", name, err)
+	}
+	return bytes
+}
+
+func endorserVerifier(t *testing.T) *sign.PublicKeyVerifier {
+	verifier, err := sign.LoadPublicKeyVerifier(filepath.Join(fixturesPath, "endorser.pub"))
+	if err != nil {
+		t.Fatalf("could not load the endorser key: %v", err)
+	}
+	return verifier
+}
+
+func rekorPublicKey(t *testing.T) *ecdsa.PublicKey {
+	publicKey, err := sign.ParsePublicKeyPEM(readFixture(t, "rekor.pub"))
+	if err != nil {
+		t.Fatalf("could not parse the Rekor key: %v", err)
+	}
+	return publicKey.(*ecdsa.PublicKey)
+}
+
+// endorsementsDir returns a directory with the endorsement in the fixtures,
+// and its log entry if withLogEntry is set.
+func endorsementsDir(t *testing.T, withLogEntry bool) string {
+	dir := t.TempDir()
+	files := map[string]string{endorsedDigest + ".dsse.json": "endorsement.dsse.json"}
+	if withLogEntry {
+		files[endorsedDigest+".rekor.json"] = "endorsement.rekor.json"
+	}
+	for name, fixture := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), readFixture(t, fixture), 0o600); err != nil {
+			t.Fatalf("could not write %s: %v", name, err)
+		}
+	}
+	return dir
+}
+
+func newGate(t *testing.T, options ...func(c *Config)) *Gate {
+	gate, err := New(endorserVerifier(t), options...)
+	if err != nil {
+		t.Fatalf("could not create gate: %v", err)
+	}
+	return gate
+}
+
+func assertDenied(t *testing.T, decision *Decision, reason string) {
+	t.Helper()
+	if decision.Allowed {
+		t.Errorf("expected %s to be denied", decision.Image)
+	}
+	if !strings.Contains(strings.Join(decision.Reasons, "\n"), reason) {
+		t.Errorf("expected a reason containing %q, got %q", reason, decision.Reasons)
+	}
+}
+
+func TestParseImage(t *testing.T) {
+	tests := []struct {
+		ref  string
+		want Image
+	}{
+		{"gcr.io/oak/functions:v1@sha256:" + endorsedDigest, Image{"gcr.io", "oak/functions", endorsedDigest}},
+		{"localhost:5000/app@sha256:" + strings.ToUpper(endorsedDigest), Image{"localhost:5000", "app", endorsedDigest}},
+		{"busybox@sha256:" + endorsedDigest, Image{DefaultRegistry, "library/busybox", endorsedDigest}},
+		{"oak/functions@sha256:" + endorsedDigest, Image{DefaultRegistry, "oak/functions", endorsedDigest}},
+	}
+	for _, tt := range tests {
+		got, err := ParseImage(tt.ref)
+		if err != nil {
+			t.Errorf("could not parse %q: %v", tt.ref, err)
+			continue
+		}
+		testutil.AssertEq(t, tt.ref, *got, tt.want)
+	}
+
+	for _, ref := range []string{"gcr.io/oak/functions:v1", "gcr.io/oak/functions@sha512:" + endorsedDigest, "gcr.io/oak/functions@sha256:abc"} {
+		if _, err := ParseImage(ref); err == nil {
+			t.Errorf("expected an error for %q", ref)
+		}
+	}
+}
+
+func TestNew_RequiresSource(t *testing.T) {
+	if _, err := New(endorserVerifier(t)); err == nil {
+		t.Errorf("expected an error without sources")
+	}
+}
+
+func TestGate_DirectorySource(t *testing.T) {
+	ctx := context.Background()
+	dir := endorsementsDir(t, true)
+	gate := newGate(t, WithSource(NewDirectorySource(dir)), WithClock(validClock()), WithRekor(rekorPublicKey(t), ""))
+
+	decision := gate.Check(ctx, endorsedImage)
+	if !decision.Allowed {
+		t.Fatalf("expected %s to be allowed, got reasons %q", endorsedImage, decision.Reasons)
+	}
+	testutil.AssertEq(t, "subject name", decision.Endorsement.Subject[0].Name, "oak_functions-012a5206e5ab35d2778832638519441dd27664da")
+
+	assertDenied(t, gate.Check(ctx, otherImage), "no endorsements found")
+	assertDenied(t, gate.Check(ctx, "gcr.io/oak/functions:latest"), "not pinned by digest")
+
+	expired := newGate(t, WithSource(NewDirectorySource(dir)), WithClock(claims.FixedClock(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC))))
+	assertDenied(t, expired.Check(ctx, endorsedImage), "the endorsement is expired")
+
+	revoked := newGate(t, WithSource(NewDirectorySource(dir)), WithClock(validClock()), WithRevokedDigests(endorsedDigest))
+	assertDenied(t, revoked.Check(ctx, endorsedImage), "the endorsement is revoked")
+}
+
+func TestGate_BundleSource(t *testing.T) {
+	releaseBundle := &bundle.Bundle{
+		Type:              bundle.ReleaseBundleV1,
+		RekorPublicKey:    string(readFixture(t, "rekor.pub")),
+		EndorserPublicKey: string(readFixture(t, "endorser.pub")),
+	}
+	if err := json.Unmarshal(readFixture(t, "endorsement.dsse.json"), &releaseBundle.Endorsement); err != nil {
+		t.Fatalf("could not parse the endorsement: %v", err)
+	}
+	if err := json.Unmarshal(readFixture(t, "endorsement.rekor.json"), &releaseBundle.EndorsementLogEntry); err != nil {
+		t.Fatalf("could not parse the log entry: %v", err)
+	}
+	bundleBytes, err := json.Marshal(releaseBundle)
+	if err != nil {
+		t.Fatalf("could not marshal the bundle: %v", err)
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, endorsedDigest+".bundle.json"), bundleBytes, 0o600); err != nil {
+		t.Fatalf("could not write the bundle: %v", err)
+	}
+
+	// The provenance referenced by the endorsement is not in the fixtures, so
+	// the bundle must be rejected even though the endorsement is valid.
+	gate := newGate(t, WithSource(NewBundleSource(dir)), WithRekor(rekorPublicKey(t), ""), WithClock(validClock()))
+	assertDenied(t, gate.Check(context.Background(), endorsedImage), "missing provenance")
+	assertDenied(t, gate.Check(context.Background(), otherImage), "no endorsements found")
+
+	gate = newGate(t, WithSource(NewBundleSource(dir)), WithClock(validClock()))
+	assertDenied(t, gate.Check(context.Background(), endorsedImage), "requires a Rekor public key")
+}
+
+func TestGate_WrongEndorser(t *testing.T) {
+	// The Rekor key did not sign the endorsement.
+	verifier, err := sign.NewPublicKeyVerifier(rekorPublicKey(t))
+	if err != nil {
+		t.Fatalf("could not create verifier: %v", err)
+	}
+	gate, err := New(verifier, WithSource(NewDirectorySource(endorsementsDir(t, true))), WithClock(validClock()))
+	if err != nil {
+		t.Fatalf("could not create gate: %v", err)
+	}
+	assertDenied(t, gate.Check(context.Background(), endorsedImage), "verifying the signature")
+}
+
+func TestGate_RekorLookup(t *testing.T) {
+	var entry LogEntry
+	if err := json.Unmarshal(readFixture(t, "endorsement.rekor.json"), &entry); err != nil {
+		t.Fatalf("could not parse the log entry: %v", err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/index/retrieve":
+			_ = json.NewEncoder(w).Encode([]string{testUUID})
+		case "/api/v1/log/entries/" + testUUID:
+			_ = json.NewEncoder(w).Encode(map[string]LogEntry{testUUID: entry})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	dir := endorsementsDir(t, false)
+	gate := newGate(t, WithSource(NewDirectorySource(dir)), WithClock(validClock()), WithRekor(rekorPublicKey(t), server.URL))
+	if decision := gate.Check(ctx, endorsedImage); !decision.Allowed {
+		t.Errorf("expected %s to be allowed, got reasons %q", endorsedImage, decision.Reasons)
+	}
+
+	offline := newGate(t, WithSource(NewDirectorySource(dir)), WithClock(validClock()), WithRekor(rekorPublicKey(t), ""))
+	assertDenied(t, offline.Check(ctx, endorsedImage), "no Rekor log entry found")
+}
+
+// newFakeRegistry starts a registry serving the endorsement in the fixtures
+// as an OCI artifact referring to the image with endorsedDigest.
+func newFakeRegistry(t *testing.T) *httptest.Server {
+	envelope := readFixture(t, "endorsement.dsse.json")
+	blobDigest := sha256Digest(envelope)
+	manifest, err := json.Marshal(map[string]interface{}{
+		"schemaVersion": 2,
+		"mediaType":     ociManifestMediaType,
+		"artifactType":  DSSEMediaType,
+		"layers":        []ociDescriptor{{MediaType: DSSEMediaType, Digest: blobDigest}},
+	})
+	if err != nil {
+		t.Fatalf("could not marshal manifest: %v", err)
+	}
+	manifestDigest := sha256Digest(manifest)
+	index, err := json.Marshal(map[string]interface{}{
+		"schemaVersion": 2,
+		"mediaType":     ociIndexMediaType,
+		"manifests": []ociDescriptor{
+			{MediaType: ociManifestMediaType, ArtifactType: "application/vnd.example.sbom", Digest: "sha256:ignored"},
+			{MediaType: ociManifestMediaType, ArtifactType: DSSEMediaType, Digest: manifestDigest},
+		},
+	})
+	if err != nil {
+		t.Fatalf("could not marshal index: %v", err)
+	}
+
+	responses := map[string][]byte{
+		"/v2/oak/functions/referrers/sha256:" + endorsedDigest: index,
+		"/v2/oak/functions/referrers/sha256:" + otherDigest:    []byte(`{"schemaVersion": 2, "manifests": []}`),
+		"/v2/oak/functions/manifests/" + manifestDigest:        manifest,
+		"/v2/oak/functions/blobs/" + blobDigest:                envelope,
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := responses[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(body)
+	}))
+}
+
+func sha256Digest(b []byte) string {
+	sum256 := sha256.Sum256(b)
+	return "sha256:" + hex.EncodeToString(sum256[:])
+}
+
+func TestGate_OCISource(t *testing.T) {
+	server := newFakeRegistry(t)
+	defer server.Close()
+	registry := strings.TrimPrefix(server.URL, "http://")
+
+	ctx := context.Background()
+	gate := newGate(t, WithSource(NewOCISource(WithPlainHTTP())), WithClock(validClock()))
+	image := fmt.Sprintf("%s/oak/functions@sha256:%s", registry, endorsedDigest)
+	decision := gate.Check(ctx, image)
+	if !decision.Allowed {
+		t.Fatalf("expected %s to be allowed, got reasons %q", image, decision.Reasons)
+	}
+	if !strings.Contains(decision.Reasons[0], registry+"/oak/functions@sha256:") {
+		t.Errorf("expected the origin in the reasons, got %q", decision.Reasons)
+	}
+
+	other := fmt.Sprintf("%s/oak/functions@sha256:%s", registry, otherDigest)
+	assertDenied(t, gate.Check(ctx, other), "no endorsements found")
+	unknown := fmt.Sprintf("%s/oak/unknown@sha256:%s", registry, endorsedDigest)
+	assertDenied(t, gate.Check(ctx, unknown), "listing the referrers")
+}
+
+func TestAdmissionHandler(t *testing.T) {
+	gate := newGate(t, WithSource(NewDirectorySource(endorsementsDir(t, true))), WithClock(validClock()))
+	server := httptest.NewServer(AdmissionHandler(gate))
+	defer server.Close()
+
+	review := func(object string) *AdmissionResponse {
+		request, err := json.Marshal(AdmissionReview{
+			APIVersion: "admission.k8s.io/v1",
+			Kind:       "AdmissionReview",
+			Request:    &AdmissionRequest{UID: "uid-1", Object: json.RawMessage(object)},
+		})
+		if err != nil {
+			t.Fatalf("could not marshal the review: %v", err)
+		}
+		resp, err := http.Post(server.URL, "application/json", bytes.NewReader(request))
+		if err != nil {
+			t.Fatalf("could not send the review: %v", err)
+		}
+		defer resp.Body.Close()
+		var response AdmissionReview
+		if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+			t.Fatalf("could not parse the response: %v", err)
+		}
+		testutil.AssertEq(t, "kind", response.Kind, "AdmissionReview")
+		testutil.AssertEq(t, "uid", response.Response.UID, "uid-1")
+		return response.Response
+	}
+
+	pod := fmt.Sprintf(`{"kind": "Pod", "spec": {"containers": [{"image": %q}]}}`, endorsedImage)
+	if response := review(pod); !response.Allowed {
+		t.Errorf("expected the pod to be allowed, got %+v", response.Status)
+	}
+
+	cronJob := fmt.Sprintf(`{"kind": "CronJob", "spec": {"jobTemplate": {"spec": {"template": {"spec": {"containers": [{"image": %q}]}}}}}}`, otherImage)
+	if response := review(cronJob); response.Allowed || !strings.Contains(response.Status.Message, otherImage) {
+		t.Errorf("expected the cron job to be denied for %s, got %+v", otherImage, response.Status)
+	}
+
+	denied := map[string]string{
+		"unknown kind":    fmt.Sprintf(`{"kind": "PodTemplate", "template": {"spec": {"containers": [{"image": %q}]}}}`, otherImage),
+		"no images":       `{"kind": "Pod", "spec": {"containers": []}}`,
+		"missing kind":    fmt.Sprintf(`{"spec": {"containers": [{"image": %q}]}}`, endorsedImage),
+		"no pod template": `{"kind": "CronJob", "spec": {"schedule": "@daily"}}`,
+	}
+	for name, object := range denied {
+		if response := review(object); response.Allowed {
+			t.Errorf("%s: expected the object to be denied", name)
+		}
+	}
+
+	deployment := fmt.Sprintf(`{"kind": "Deployment", "spec": {"template": {"spec": {"containers": [{"image": %q}], "initContainers": [{"image": %q}]}}}}`, endorsedImage, otherImage)
+	response := review(deployment)
+	if response.Allowed {
+		t.Fatalf("expected the deployment to be denied")
+	}
+	testutil.AssertEq(t, "code", response.Status.Code, http.StatusForbidden)
+	if !strings.Contains(response.Status.Message, otherImage) || strings.Contains(response.Status.Message, endorsedImage) {
+		t.Errorf("expected only the unendorsed image in the message, got %q", response.Status.Message)
+	}
+}
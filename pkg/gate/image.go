@@ -0,0 +1,75 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gate
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// DefaultRegistry is the registry of image references without a registry,
+// e.g., `busybox@sha256:...`.
+const DefaultRegistry = "docker.io"
+
+// Image is a container image reference that is pinned by digest.
+type Image struct {
+	// Registry is the host, and optionally port, of the registry.
+	Registry string
+	// Repository is the path of the repository in the registry.
+	Repository string
+	// Digest is the hex-encoded SHA2-256 digest of the image manifest.
+	Digest string
+}
+
+// String returns the image reference in the canonical form
+// `registry/repository@sha256:digest`.
+func (i *Image) String() string {
+	return fmt.Sprintf("%s/%s@sha256:%s", i.Registry, i.Repository, i.Digest)
+}
+
+// ParseImage parses an image reference of the form
+// `[registry/]repository[:tag]@sha256:digest`. References without a digest
+// are rejected, since tags are mutable.
+func ParseImage(ref string) (*Image, error) {
+	name, digest, ok := strings.Cut(ref, "@")
+	if !ok {
+		return nil, fmt.Errorf("image %q is not pinned by digest", ref)
+	}
+	hexDigest := strings.TrimPrefix(digest, "sha256:")
+	if hexDigest == digest {
+		return nil, fmt.Errorf("image %q is not pinned by a SHA2-256 digest", ref)
+	}
+	if decoded, err := hex.DecodeString(hexDigest); err != nil || len(decoded) != 32 {
+		return nil, fmt.Errorf("image %q has an invalid SHA2-256 digest", ref)
+	}
+
+	// Drop the tag, which follows the last colon after the last slash.
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name = name[:i]
+	}
+	registry := DefaultRegistry
+	repository := name
+	if first, rest, ok := strings.Cut(name, "/"); ok && (strings.ContainsAny(first, ".:") || first == "localhost") {
+		registry, repository = first, rest
+	}
+	if repository == "" {
+		return nil, fmt.Errorf("image %q has no repository", ref)
+	}
+	if registry == DefaultRegistry && !strings.Contains(repository, "/") {
+		repository = "library/" + repository
+	}
+	return &Image{Registry: registry, Repository: repository, Digest: strings.ToLower(hexDigest)}, nil
+}
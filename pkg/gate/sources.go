@@ -0,0 +1,244 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gate
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/secure-systems-lab/go-securesystemslib/dsse"
+
+	"github.com/project-oak/transparent-release/pkg/bundle"
+)
+
+// directorySource finds endorsements in a local directory.
+type directorySource struct {
+	dir string
+}
+
+// NewDirectorySource creates a Source for endorsements in a local directory,
+// e.g., a mounted volume. The endorsement of an image with digest
+// `sha256:<hex>` is expected in `<hex>.dsse.json`, and optionally its Rekor
+// log entry in `<hex>.rekor.json`. These are the files written by the
+// endorser with `--output_path=<dir>/<hex>.json` and `--kms_key_uri`.
+func NewDirectorySource(dir string) Source {
+	return &directorySource{dir: dir}
+}
+
+func (s *directorySource) Endorsements(_ context.Context, image *Image) ([]SignedEndorsement, error) {
+	envelopePath := filepath.Join(s.dir, image.Digest+".dsse.json")
+	var envelope dsse.Envelope
+	if err := readJSON(envelopePath, &envelope); errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	endorsement := SignedEndorsement{Envelope: &envelope, Origin: envelopePath}
+	var entry LogEntry
+	if err := readJSON(filepath.Join(s.dir, image.Digest+".rekor.json"), &entry); err == nil {
+		endorsement.LogEntry = &entry
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	return []SignedEndorsement{endorsement}, nil
+}
+
+// bundleSource finds release bundles in a local directory.
+type bundleSource struct {
+	dir string
+}
+
+// NewBundleSource creates a Source for release bundles in a local directory,
+// e.g., a mounted volume. The release bundle of an image with digest
+// `sha256:<hex>` is expected in `<hex>.bundle.json`. Besides the endorsement,
+// the gate verifies the provenances in the bundle, and their log entries,
+// which requires WithRekor.
+func NewBundleSource(dir string) Source {
+	return &bundleSource{dir: dir}
+}
+
+func (s *bundleSource) Endorsements(_ context.Context, image *Image) ([]SignedEndorsement, error) {
+	bundlePath := filepath.Join(s.dir, image.Digest+".bundle.json")
+	bundleBytes, err := os.ReadFile(bundlePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	releaseBundle, err := bundle.Parse(bundleBytes)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %q: %v", bundlePath, err)
+	}
+	return []SignedEndorsement{{
+		Envelope: releaseBundle.Endorsement,
+		LogEntry: releaseBundle.EndorsementLogEntry,
+		Origin:   bundlePath,
+		Bundle:   releaseBundle,
+	}}, nil
+}
+
+func readJSON(path string, v interface{}) error {
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(bytes, v); err != nil {
+		return fmt.Errorf("parsing %q: %v", path, err)
+	}
+	return nil
+}
+
+// Media types used by OCISource.
+const (
+	// DSSEMediaType is the artifact type of OCI artifacts, and the media
+	// type of their layers, holding endorsements as DSSE envelopes.
+	DSSEMediaType        = "application/vnd.dsse.envelope.v1+json"
+	ociIndexMediaType    = "application/vnd.oci.image.index.v1+json"
+	ociManifestMediaType = "application/vnd.oci.image.manifest.v1+json"
+)
+
+// maxOCIResponseBytes limits the size of manifests and envelopes fetched from
+// registries.
+const maxOCIResponseBytes = 4 << 20
+
+// ociDescriptor is the subset of an OCI content descriptor used by OCISource.
+type ociDescriptor struct {
+	MediaType    string `json:"mediaType"`
+	ArtifactType string `json:"artifactType,omitempty"`
+	Digest       string `json:"digest"`
+}
+
+// OCIConfig holds optional settings for an OCI source.
+type OCIConfig struct {
+	client    *http.Client
+	plainHTTP bool
+}
+
+// WithHTTPClient sets the HTTP client for accessing registries, e.g., one that
+// adds credentials. Defaults to an anonymous client.
+func WithHTTPClient(client *http.Client) func(c *OCIConfig) {
+	return func(c *OCIConfig) {
+		c.client = client
+	}
+}
+
+// WithPlainHTTP accesses registries over HTTP instead of HTTPS, e.g., for
+// local test registries.
+func WithPlainHTTP() func(c *OCIConfig) {
+	return func(c *OCIConfig) {
+		c.plainHTTP = true
+	}
+}
+
+// ociSource finds endorsements attached to images in their OCI registry.
+type ociSource struct {
+	config *OCIConfig
+}
+
+// NewOCISource creates a Source for endorsements attached to images in their
+// registry, using the OCI referrers API. Endorsements are OCI artifacts with
+// artifact type DSSEMediaType, whose subject is the image, and whose layers
+// are DSSE envelopes.
+func NewOCISource(options ...func(c *OCIConfig)) Source {
+	config := &OCIConfig{client: &http.Client{}}
+	for _, addOption := range options {
+		addOption(config)
+	}
+	return &ociSource{config: config}
+}
+
+func (s *ociSource) Endorsements(ctx context.Context, image *Image) ([]SignedEndorsement, error) {
+	scheme := "https"
+	if s.config.plainHTTP {
+		scheme = "http"
+	}
+	baseURL := fmt.Sprintf("%s://%s/v2/%s", scheme, image.Registry, image.Repository)
+
+	var index struct {
+		Manifests []ociDescriptor `json:"manifests"`
+	}
+	referrersURL := fmt.Sprintf("%s/referrers/sha256:%s?artifactType=%s", baseURL, image.Digest, DSSEMediaType)
+	if err := s.getJSON(ctx, referrersURL, ociIndexMediaType, "", &index); err != nil {
+		return nil, fmt.Errorf("listing the referrers of %s: %v", image, err)
+	}
+
+	var endorsements []SignedEndorsement
+	for _, descriptor := range index.Manifests {
+		// Registries may ignore the artifactType filter.
+		if descriptor.ArtifactType != DSSEMediaType {
+			continue
+		}
+		var manifest struct {
+			Layers []ociDescriptor `json:"layers"`
+		}
+		if err := s.getJSON(ctx, baseURL+"/manifests/"+descriptor.Digest, ociManifestMediaType, descriptor.Digest, &manifest); err != nil {
+			return nil, fmt.Errorf("fetching the manifest %s: %v", descriptor.Digest, err)
+		}
+		for _, layer := range manifest.Layers {
+			if layer.MediaType != DSSEMediaType {
+				continue
+			}
+			var envelope dsse.Envelope
+			if err := s.getJSON(ctx, baseURL+"/blobs/"+layer.Digest, layer.MediaType, layer.Digest, &envelope); err != nil {
+				return nil, fmt.Errorf("fetching the envelope %s: %v", layer.Digest, err)
+			}
+			endorsements = append(endorsements, SignedEndorsement{
+				Envelope: &envelope,
+				Origin:   fmt.Sprintf("%s/%s@%s", image.Registry, image.Repository, descriptor.Digest),
+			})
+		}
+	}
+	return endorsements, nil
+}
+
+// getJSON fetches the JSON document at the given URL, checks that it has the
+// given `sha256:<hex>` digest, unless the digest is empty, and unmarshals it
+// into v.
+func (s *ociSource) getJSON(ctx context.Context, url, accept, digest string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("could not create HTTP request: %v", err)
+	}
+	req.Header.Set("Accept", accept)
+	resp, err := s.config.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("could not receive response from registry: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected response from registry: %s", resp.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxOCIResponseBytes))
+	if err != nil {
+		return fmt.Errorf("could not read the response: %v", err)
+	}
+	if digest != "" {
+		sum256 := sha256.Sum256(body)
+		if got := "sha256:" + hex.EncodeToString(sum256[:]); got != digest {
+			return fmt.Errorf("unexpected digest: got %s, want %s", got, digest)
+		}
+	}
+	return json.Unmarshal(body, v)
+}
@@ -0,0 +1,88 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package intoto
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMarshalCanonical(t *testing.T) {
+	tests := map[string]struct {
+		input string
+		want  string
+	}{
+		"whitespace and order": {`{ "b": [1, 2], "a": {"d": null, "c": true} }`, `{"a":{"c":true,"d":null},"b":[1,2]}`},
+		"utf-16 order":         {`{"\u20ac": 1, "\r": 2, "\ud83d\ude00": 3, "\u00f6": 4, "1": 5}`, "{\"\\r\":2,\"1\":5,\"\u00f6\":4,\"\u20ac\":1,\"\U0001f600\":3}"},
+		"escapes":              {`"<&>\u0001\u001f\/\"\\\b\f\n\r\t"`, `"<&>\u0001\u001f/\"\\\b\f\n\r\t"`},
+		"integers":             {`[0, -0, 1, -1, 1e2, 100.0, 9007199254740992]`, `[0,0,1,-1,100,100,9007199254740992]`},
+		"fractions":            {`[0.5, 1.25, -0.000001, 0.0000001, 1e21, 1e20, 123e-20]`, `[0.5,1.25,-0.000001,1e-7,1e+21,100000000000000000000,1.23e-18]`},
+		"rfc 8785 numbers":     {`[333333333.33333329, 1E30, 4.50, 2e-3, 0.000000000000000000000000001]`, `[333333333.3333333,1e+30,4.5,0.002,1e-27]`},
+	}
+	for name, test := range tests {
+		got, err := MarshalCanonical(json.RawMessage(test.input))
+		if err != nil {
+			t.Errorf("%s: could not marshal: %v", name, err)
+			continue
+		}
+		if string(got) != test.want {
+			t.Errorf("%s: got %s, want %s", name, got, test.want)
+		}
+	}
+}
+
+func TestMarshalCanonical_Statement(t *testing.T) {
+	statement := &Statement{
+		StatementHeader: StatementHeader{
+			Type:          StatementInTotoV01,
+			PredicateType: SLSAV02PredicateType,
+			Subject:       []Subject{{Name: "binary", Digest: DigestSet{"sha256": testDigest, "sha1": "abc"}}},
+		},
+		Predicate: map[string]interface{}{"z": 1, "a": "<b>"},
+	}
+	got, err := MarshalCanonical(statement)
+	if err != nil {
+		t.Fatalf("could not marshal the statement: %v", err)
+	}
+	want := `{"_type":"https://in-toto.io/Statement/v0.1","predicate":{"a":"<b>","z":1},"predicateType":"https://slsa.dev/provenance/v0.2",` +
+		`"subject":[{"digest":{"sha1":"abc","sha256":"` + testDigest + `"},"name":"binary"}]}`
+	if string(got) != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestWriteStatement(t *testing.T) {
+	statement := &Statement{
+		StatementHeader: StatementHeader{Type: StatementInTotoV01, PredicateType: SLSAV02PredicateType},
+		Predicate:       map[string]interface{}{"z": 1, "a": 0.5},
+	}
+	path := filepath.Join(t.TempDir(), "statement.json")
+	if err := WriteStatement(path, statement); err != nil {
+		t.Fatalf("could not write the statement: %v", err)
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("could not read the statement: %v", err)
+	}
+	want, err := MarshalCanonical(statement)
+	if err != nil {
+		t.Fatalf("could not marshal the statement: %v", err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package intoto
+
+import (
+	"fmt"
+	"strings"
+)
+
+// StatementInTotoV1 is the statement type of in-toto v1 statements.
+const StatementInTotoV1 = "https://in-toto.io/Statement/v1"
+
+// DigestKeyStyle specifies how the algorithms in a DigestSet are named.
+type DigestKeyStyle int
+
+const (
+	// CanonicalDigestKeys names the SHA2 algorithms with their family, e.g.,
+	// "sha2-256". These are the keys used throughout Transparent Release.
+	CanonicalDigestKeys DigestKeyStyle = iota
+	// InTotoDigestKeys names the algorithms as in the in-toto digest set
+	// specification, e.g., "sha256".
+	InTotoDigestKeys
+)
+
+// digestKeys maps the lowercase names of known algorithms to their canonical
+// and in-toto names. Keys of other algorithms are kept as they are.
+//
+//nolint:gochecknoglobals
+var digestKeys = map[string][2]string{
+	"sha1":     {"sha1", "sha1"},
+	"sha256":   {"sha2-256", "sha256"},
+	"sha2-256": {"sha2-256", "sha256"},
+	"sha384":   {"sha2-384", "sha384"},
+	"sha2-384": {"sha2-384", "sha384"},
+	"sha512":   {"sha2-512", "sha512"},
+	"sha2-512": {"sha2-512", "sha512"},
+	"sha3-224": {"sha3-224", "sha3_224"},
+	"sha3_224": {"sha3-224", "sha3_224"},
+	"sha3-256": {"sha3-256", "sha3_256"},
+	"sha3_256": {"sha3-256", "sha3_256"},
+	"sha3-384": {"sha3-384", "sha3_384"},
+	"sha3_384": {"sha3-384", "sha3_384"},
+	"sha3-512": {"sha3-512", "sha3_512"},
+	"sha3_512": {"sha3-512", "sha3_512"},
+}
+
+// ConvertDigestSet returns a copy of the given DigestSet with the keys of
+// known algorithms renamed in the given style, and all keys and values in
+// lowercase. Returns an error if the digest set contains two different
+// digests for the same algorithm, e.g., under "sha256" and "sha2-256".
+func ConvertDigestSet(digestSet DigestSet, style DigestKeyStyle) (DigestSet, error) {
+	converted := make(DigestSet, len(digestSet))
+	for key, value := range digestSet {
+		key = strings.ToLower(key)
+		if names, ok := digestKeys[key]; ok {
+			key = names[style]
+		}
+		value = strings.ToLower(value)
+		if existing, ok := converted[key]; ok && existing != value {
+			return nil, fmt.Errorf("conflicting %s digests: %q and %q", key, existing, value)
+		}
+		converted[key] = value
+	}
+	return converted, nil
+}
+
+// ConvertStatement returns a copy of the given statement with the given
+// statement type, and the digests of its subjects converted to the given
+// style. The predicate is shared with the given statement. The statement type
+// must be StatementInTotoV01 or StatementInTotoV1.
+func ConvertStatement(statement *Statement, statementType string, style DigestKeyStyle) (*Statement, error) {
+	header, err := convertHeader(statement.StatementHeader, statementType, style)
+	if err != nil {
+		return nil, err
+	}
+	return &Statement{StatementHeader: *header, Predicate: statement.Predicate}, nil
+}
+
+// NormalizeStatementHeader checks that the given header has a supported
+// statement type, and converts the digests of its subjects to canonical keys
+// in place. Loaders of statements call it so that statements of different
+// versions, or with digests keyed by in-toto names, can be verified alike.
+func NormalizeStatementHeader(header *StatementHeader) error {
+	normalized, err := convertHeader(*header, header.Type, CanonicalDigestKeys)
+	if err != nil {
+		return err
+	}
+	*header = *normalized
+	return nil
+}
+
+func convertHeader(header StatementHeader, statementType string, style DigestKeyStyle) (*StatementHeader, error) {
+	if statementType != StatementInTotoV01 && statementType != StatementInTotoV1 {
+		return nil, fmt.Errorf("unsupported statement type %q, want %q or %q", statementType, StatementInTotoV01, StatementInTotoV1)
+	}
+	subjects := make([]Subject, 0, len(header.Subject))
+	for i, subject := range header.Subject {
+		digests, err := ConvertDigestSet(subject.Digest, style)
+		if err != nil {
+			return nil, fmt.Errorf("invalid digest of subject #%d: %v", i, err)
+		}
+		subject.Digest = digests
+		subjects = append(subjects, subject)
+	}
+	return &StatementHeader{
+		Type:          statementType,
+		PredicateType: header.PredicateType,
+		Subject:       subjects,
+	}, nil
+}
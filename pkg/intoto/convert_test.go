@@ -0,0 +1,134 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package intoto
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const testDigest = "813841dda3818d616aa3e706e49d0286dc825c5dbad4a75cfb37b91ba412238b"
+
+func TestConvertDigestSet(t *testing.T) {
+	digestSet := DigestSet{"SHA256": "813841DDA3818D616AA3E706E49D0286DC825C5DBAD4A75CFB37B91BA412238B", "sha1": "abc", "sha3_256": "123", "blake2b": "def"}
+
+	canonical, err := ConvertDigestSet(digestSet, CanonicalDigestKeys)
+	if err != nil {
+		t.Fatalf("could not convert the digest set: %v", err)
+	}
+	if diff := cmp.Diff(canonical, DigestSet{"sha2-256": testDigest, "sha1": "abc", "sha3-256": "123", "blake2b": "def"}); diff != "" {
+		t.Errorf("unexpected canonical digests: %s", diff)
+	}
+
+	inToto, err := ConvertDigestSet(canonical, InTotoDigestKeys)
+	if err != nil {
+		t.Fatalf("could not convert the digest set: %v", err)
+	}
+	if diff := cmp.Diff(inToto, DigestSet{"sha256": testDigest, "sha1": "abc", "sha3_256": "123", "blake2b": "def"}); diff != "" {
+		t.Errorf("unexpected in-toto digests: %s", diff)
+	}
+
+	// Equal digests under both keys are merged, and different ones rejected.
+	if _, err := ConvertDigestSet(DigestSet{"sha256": testDigest, "sha2-256": testDigest}, InTotoDigestKeys); err != nil {
+		t.Errorf("could not convert equal digests: %v", err)
+	}
+	if _, err := ConvertDigestSet(DigestSet{"sha256": testDigest, "sha2-256": "abc"}, InTotoDigestKeys); err == nil {
+		t.Errorf("expected failure for conflicting digests")
+	}
+}
+
+func TestConvertStatement(t *testing.T) {
+	statement := &Statement{
+		StatementHeader: StatementHeader{
+			Type:          StatementInTotoV01,
+			PredicateType: SLSAV02PredicateType,
+			Subject:       []Subject{{Name: "binary", Digest: DigestSet{"sha2-256": testDigest}}},
+		},
+		Predicate: "predicate",
+	}
+
+	converted, err := ConvertStatement(statement, StatementInTotoV1, InTotoDigestKeys)
+	if err != nil {
+		t.Fatalf("could not convert the statement: %v", err)
+	}
+	want := &Statement{
+		StatementHeader: StatementHeader{
+			Type:          StatementInTotoV1,
+			PredicateType: SLSAV02PredicateType,
+			Subject:       []Subject{{Name: "binary", Digest: DigestSet{"sha256": testDigest}}},
+		},
+		Predicate: "predicate",
+	}
+	if diff := cmp.Diff(converted, want); diff != "" {
+		t.Errorf("unexpected statement: %s", diff)
+	}
+	// The given statement is unchanged.
+	if got := statement.Subject[0].Digest["sha2-256"]; got != testDigest {
+		t.Errorf("the given statement was modified")
+	}
+
+	if _, err := ConvertStatement(statement, "https://in-toto.io/Statement/v2", InTotoDigestKeys); err == nil {
+		t.Errorf("expected failure for an unsupported statement type")
+	}
+}
+
+func TestNormalizeStatementHeader(t *testing.T) {
+	header := StatementHeader{
+		Type: StatementInTotoV1,
+		Subject: []Subject{
+			{Name: "binary", Digest: DigestSet{"sha256": testDigest}},
+			{Name: "image", Digest: DigestSet{"sha256": testDigest}, MediaType: "application/vnd.oci.image.manifest.v1+json"},
+		},
+	}
+	if err := NormalizeStatementHeader(&header); err != nil {
+		t.Fatalf("could not normalize the header: %v", err)
+	}
+	want := []Subject{
+		{Name: "binary", Digest: DigestSet{"sha2-256": testDigest}},
+		{Name: "image", Digest: DigestSet{"sha2-256": testDigest}, MediaType: "application/vnd.oci.image.manifest.v1+json"},
+	}
+	if diff := cmp.Diff(header.Subject, want); diff != "" {
+		t.Errorf("unexpected subjects: %s", diff)
+	}
+
+	if err := NormalizeStatementHeader(&StatementHeader{Type: "tampered"}); err == nil {
+		t.Errorf("expected failure for an unsupported statement type")
+	}
+}
+
+func TestConvertStatement_ResourceDescriptor(t *testing.T) {
+	subject := ResourceDescriptor{
+		Name:             "binary",
+		URI:              "https://example.com/binary",
+		Digest:           DigestSet{"sha256": testDigest},
+		DownloadLocation: "https://example.com/download/binary",
+		MediaType:        "application/octet-stream",
+		Annotations:      map[string]interface{}{"key": "value"},
+	}
+	statement := &Statement{
+		StatementHeader: StatementHeader{Type: StatementInTotoV1, Subject: []Subject{subject}},
+	}
+
+	converted, err := ConvertStatement(statement, StatementInTotoV01, CanonicalDigestKeys)
+	if err != nil {
+		t.Fatalf("could not convert the statement: %v", err)
+	}
+	want := subject
+	want.Digest = DigestSet{"sha2-256": testDigest}
+	if diff := cmp.Diff(converted.Subject, []Subject{want}); diff != "" {
+		t.Errorf("unexpected subjects: %s", diff)
+	}
+}
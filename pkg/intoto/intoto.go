@@ -22,6 +22,9 @@ package intoto
 // containing statements. This is constant for all predicate types.
 const StatementInTotoV01 = "https://in-toto.io/Statement/v0.1"
 
+// PayloadType is the DSSE payload type for in-toto statements.
+const PayloadType = "application/vnd.in-toto+json"
+
 // SLSAV02PredicateType is the predicate type for all SLSA v02 provenances.
 const SLSAV02PredicateType = "https://slsa.dev/provenance/v0.2"
 
@@ -29,12 +32,28 @@ const SLSAV02PredicateType = "https://slsa.dev/provenance/v0.2"
 // algorithm name to lowercase hex-encoded value.
 type DigestSet map[string]string
 
-// Subject describes the set of software artifacts the statement applies to.
-type Subject struct {
-	Name   string    `json:"name"`
+// ResourceDescriptor describes a software artifact, as in the in-toto
+// attestation framework v1. Statements of type StatementInTotoV01 only use
+// Name and Digest.
+type ResourceDescriptor struct {
+	Name string `json:"name"`
+	// URI is the optional URI identifying the artifact.
+	URI    string    `json:"uri,omitempty"`
 	Digest DigestSet `json:"digest"`
+	// DownloadLocation is the optional location from which the artifact can
+	// be downloaded, if different from URI.
+	DownloadLocation string `json:"downloadLocation,omitempty"`
+	// MediaType is the optional media type of the artifact, e.g., the media
+	// type of the manifest of a container image. It is not set for binaries.
+	MediaType string `json:"mediaType,omitempty"`
+	// Annotations are optional additional information about the artifact.
+	Annotations map[string]interface{} `json:"annotations,omitempty"`
 }
 
+// Subject describes the set of software artifacts the statement applies to.
+// In-toto v1 statements describe their subjects with resource descriptors.
+type Subject = ResourceDescriptor
+
 // StatementHeader defines the common fields for all statements
 type StatementHeader struct {
 	Type          string    `json:"_type"`
@@ -199,3 +199,32 @@ func (p *ProvenancePredicate) RepoURIAndDigest() (*string, *string) {
 	}
 	return nil, nil
 }
+
+// CommitDigests returns a copy of all digests of the commit extracted from
+// materials, e.g., a "sha1" digest for the SHA-1 Git object format, or a
+// "sha256" digest for the SHA-256 one. Returns nil if there is no Git repo in
+// the materials.
+func (p *ProvenancePredicate) CommitDigests() intoto.DigestSet {
+	for _, material := range p.Materials {
+		if strings.Contains(material.URI, "git") {
+			digests := make(intoto.DigestSet)
+			for algorithm, digest := range material.Digest {
+				digests[algorithm] = digest
+			}
+			return digests
+		}
+	}
+	return nil
+}
+
+// SourceRef returns the Git ref, e.g., `refs/heads/main`, that the URI of the
+// config source of the invocation is qualified with, as in
+// `git+https://github.com/project-oak/oak@refs/heads/main`. Returns an empty
+// string if the URI has no ref.
+func (p *ProvenancePredicate) SourceRef() string {
+	uri := p.Invocation.ConfigSource.URI
+	if i := strings.LastIndex(uri, "@"); i >= 0 && strings.HasPrefix(uri[i+1:], "refs/") {
+		return uri[i+1:]
+	}
+	return ""
+}
@@ -40,6 +40,17 @@ const (
 	// The `draft` in the URI signals that the format might need to change.
 	// See https://github.com/slsa-framework/github-actions-buildtypes/issues/4.
 	DockerBasedBuildType = "https://slsa.dev/container-based-build/v0.1?draft"
+
+	// ToolchainVersionAnnotation is the annotation of resolved dependencies
+	// that records the version of a toolchain, e.g., `rustc`, installed in the
+	// builder image of a container-based build.
+	ToolchainVersionAnnotation = "toolchainVersion"
+
+	// GitTreeDigestKey is the key of the digest of the Git tree of the source
+	// commit in the digests of the source, as in the in-toto digest set
+	// specification. Unlike the commit hash, the tree hash only depends on the
+	// content of the source, and not on its history or the commit metadata.
+	GitTreeDigestKey = "gitTree"
 )
 
 // ProvenancePredicate defines the structure of a SLSA v1 provenance predicate.
@@ -167,6 +178,24 @@ type BuildConfig struct {
 
 	// Build command that is passed to `docker run`.
 	Command []string `toml:"command"`
+
+	// Environment variables that are set in the container. Optional.
+	Env map[string]string `toml:"env" json:",omitempty"`
+
+	// Options for running the container. Optional.
+	Options *BuildOptions `toml:"options" json:",omitempty"`
+}
+
+// BuildOptions are options for running the container of a container-based
+// build.
+type BuildOptions struct {
+	// Runs the container without network access, e.g., to check that the
+	// build is hermetic.
+	Offline bool `toml:"offline" json:",omitempty"`
+
+	// The platform of the builder image, e.g., `linux/amd64`, for images that
+	// support several platforms.
+	Platform string `toml:"platform" json:",omitempty"`
 }
 
 // ParseContainerBasedSLSAv1Provenance parses the given object as a
@@ -214,6 +243,15 @@ func (p *ProvenancePredicate) BuilderImageDigest() (string, error) {
 	return digest, nil
 }
 
+// BuilderImageDigests returns a copy of all digests of the builder image.
+func (p *ProvenancePredicate) BuilderImageDigests() intoto.DigestSet {
+	digests := make(intoto.DigestSet)
+	for algorithm, digest := range p.BuildDefinition.ExternalParameters.(DockerBasedExternalParameters).BuilderImage.Digest {
+		digests[algorithm] = digest
+	}
+	return digests
+}
+
 // RepoURIAndDigest returns the URI of the Git repo and the SHA1 commit hash.
 func (p *ProvenancePredicate) RepoURIAndDigest() (*string, *string) {
 	src := p.BuildDefinition.ExternalParameters.(DockerBasedExternalParameters).Source
@@ -224,7 +262,78 @@ func (p *ProvenancePredicate) RepoURIAndDigest() (*string, *string) {
 	return nil, nil
 }
 
+// CommitDigests returns a copy of all digests of the source commit, e.g., a
+// "sha1" digest for the SHA-1 Git object format, or a "sha256" digest for the
+// SHA-256 one. Returns nil if the source is not a Git repo.
+func (p *ProvenancePredicate) CommitDigests() intoto.DigestSet {
+	src := p.BuildDefinition.ExternalParameters.(DockerBasedExternalParameters).Source
+	if !strings.Contains(src.URI, "git") {
+		return nil
+	}
+	digests := make(intoto.DigestSet)
+	for algorithm, digest := range src.Digest {
+		if algorithm != GitTreeDigestKey {
+			digests[algorithm] = digest
+		}
+	}
+	return digests
+}
+
+// TreeDigests returns the digest of the Git tree of the source commit,
+// recorded with the GitTreeDigestKey key in the digests of the source, keyed
+// by the hash algorithm of the Git object format: "sha1" for hex-encoded
+// digests of 40 characters, and "sha256" for digests of 64 characters.
+// Returns nil if the source is not a Git repo or has no tree digest.
+func (p *ProvenancePredicate) TreeDigests() intoto.DigestSet {
+	src := p.BuildDefinition.ExternalParameters.(DockerBasedExternalParameters).Source
+	tree, ok := src.Digest[GitTreeDigestKey]
+	if !strings.Contains(src.URI, "git") || !ok {
+		return nil
+	}
+	switch len(tree) {
+	case 40:
+		return intoto.DigestSet{"sha1": tree}
+	case 64:
+		return intoto.DigestSet{"sha256": tree}
+	default:
+		return intoto.DigestSet{}
+	}
+}
+
+// SourceRef returns the Git ref, e.g., `refs/tags/v1.2.3`, that the source URI
+// is qualified with, as in `git+https://github.com/project-oak/oak@refs/tags/v1.2.3`.
+// Returns an empty string if the source URI has no ref.
+func (p *ProvenancePredicate) SourceRef() string {
+	uri := p.BuildDefinition.ExternalParameters.(DockerBasedExternalParameters).Source.URI
+	if i := strings.LastIndex(uri, "@"); i >= 0 && strings.HasPrefix(uri[i+1:], "refs/") {
+		return uri[i+1:]
+	}
+	return ""
+}
+
 // BuilderID extracts and returns the builder ID from the given ProvenancePredicate.
 func (p *ProvenancePredicate) BuilderID() string {
 	return p.RunDetails.Builder.ID
 }
+
+// NewToolchainDependency returns a resolved dependency that records the version
+// of the toolchain with the given name, e.g., `rustc`, in the builder image.
+func NewToolchainDependency(name, version string) ResourceDescriptor {
+	return ResourceDescriptor{
+		Name:        name,
+		Annotations: map[string]interface{}{ToolchainVersionAnnotation: version},
+	}
+}
+
+// ToolchainVersions returns the versions of the toolchains recorded in the
+// resolved dependencies of the given ProvenancePredicate, keyed by the names
+// of the toolchains. Dependencies without a toolchain version are ignored.
+func (p *ProvenancePredicate) ToolchainVersions() map[string]string {
+	versions := make(map[string]string)
+	for _, dependency := range p.BuildDefinition.ResolvedDependencies {
+		if version, ok := dependency.Annotations[ToolchainVersionAnnotation].(string); ok && dependency.Name != "" {
+			versions[dependency.Name] = version
+		}
+	}
+	return versions
+}
@@ -47,16 +47,30 @@ type VerificationOptions struct {
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	ProvenanceCountAtLeast *VerifyProvenanceCountAtLeast `protobuf:"bytes,1,opt,name=provenance_count_at_least,json=provenanceCountAtLeast,proto3,oneof" json:"provenance_count_at_least,omitempty"`
-	ProvenanceCountAtMost  *VerifyProvenanceCountAtMost  `protobuf:"bytes,2,opt,name=provenance_count_at_most,json=provenanceCountAtMost,proto3,oneof" json:"provenance_count_at_most,omitempty"`
-	AllSameBinaryName      *VerifyAllSameBinaryName      `protobuf:"bytes,3,opt,name=all_same_binary_name,json=allSameBinaryName,proto3,oneof" json:"all_same_binary_name,omitempty"`
-	AllSameBinaryDigest    *VerifyAllSameBinaryDigest    `protobuf:"bytes,4,opt,name=all_same_binary_digest,json=allSameBinaryDigest,proto3,oneof" json:"all_same_binary_digest,omitempty"`
-	AllWithBuildCommand    *VerifyAllWithBuildCommand    `protobuf:"bytes,5,opt,name=all_with_build_command,json=allWithBuildCommand,proto3,oneof" json:"all_with_build_command,omitempty"`
-	AllWithBinaryName      *VerifyAllWithBinaryName      `protobuf:"bytes,6,opt,name=all_with_binary_name,json=allWithBinaryName,proto3,oneof" json:"all_with_binary_name,omitempty"`
-	AllWithBinaryDigests   *VerifyAllWithBinaryDigests   `protobuf:"bytes,7,opt,name=all_with_binary_digests,json=allWithBinaryDigests,proto3,oneof" json:"all_with_binary_digests,omitempty"`
-	AllWithBuilderNames    *VerifyAllWithBuilderNames    `protobuf:"bytes,8,opt,name=all_with_builder_names,json=allWithBuilderNames,proto3,oneof" json:"all_with_builder_names,omitempty"`
-	AllWithBuilderDigests  *VerifyAllWithBuilderDigests  `protobuf:"bytes,9,opt,name=all_with_builder_digests,json=allWithBuilderDigests,proto3,oneof" json:"all_with_builder_digests,omitempty"`
-	AllWithRepository      *VerifyAllWithRepository      `protobuf:"bytes,10,opt,name=all_with_repository,json=allWithRepository,proto3,oneof" json:"all_with_repository,omitempty"`
+	ProvenanceCountAtLeast          *VerifyProvenanceCountAtLeast          `protobuf:"bytes,1,opt,name=provenance_count_at_least,json=provenanceCountAtLeast,proto3,oneof" json:"provenance_count_at_least,omitempty"`
+	ProvenanceCountAtMost           *VerifyProvenanceCountAtMost           `protobuf:"bytes,2,opt,name=provenance_count_at_most,json=provenanceCountAtMost,proto3,oneof" json:"provenance_count_at_most,omitempty"`
+	AllSameBinaryName               *VerifyAllSameBinaryName               `protobuf:"bytes,3,opt,name=all_same_binary_name,json=allSameBinaryName,proto3,oneof" json:"all_same_binary_name,omitempty"`
+	AllSameBinaryDigest             *VerifyAllSameBinaryDigest             `protobuf:"bytes,4,opt,name=all_same_binary_digest,json=allSameBinaryDigest,proto3,oneof" json:"all_same_binary_digest,omitempty"`
+	AllWithBuildCommand             *VerifyAllWithBuildCommand             `protobuf:"bytes,5,opt,name=all_with_build_command,json=allWithBuildCommand,proto3,oneof" json:"all_with_build_command,omitempty"`
+	AllWithBinaryName               *VerifyAllWithBinaryName               `protobuf:"bytes,6,opt,name=all_with_binary_name,json=allWithBinaryName,proto3,oneof" json:"all_with_binary_name,omitempty"`
+	AllWithBinaryDigests            *VerifyAllWithBinaryDigests            `protobuf:"bytes,7,opt,name=all_with_binary_digests,json=allWithBinaryDigests,proto3,oneof" json:"all_with_binary_digests,omitempty"`
+	AllWithBuilderNames             *VerifyAllWithBuilderNames             `protobuf:"bytes,8,opt,name=all_with_builder_names,json=allWithBuilderNames,proto3,oneof" json:"all_with_builder_names,omitempty"`
+	AllWithBuilderDigests           *VerifyAllWithBuilderDigests           `protobuf:"bytes,9,opt,name=all_with_builder_digests,json=allWithBuilderDigests,proto3,oneof" json:"all_with_builder_digests,omitempty"`
+	AllWithRepository               *VerifyAllWithRepository               `protobuf:"bytes,10,opt,name=all_with_repository,json=allWithRepository,proto3,oneof" json:"all_with_repository,omitempty"`
+	AllWithCertificateIdentity      *VerifyAllWithCertificateIdentity      `protobuf:"bytes,11,opt,name=all_with_certificate_identity,json=allWithCertificateIdentity,proto3,oneof" json:"all_with_certificate_identity,omitempty"`
+	AllWithMinimumToolchainVersions *VerifyAllWithMinimumToolchainVersions `protobuf:"bytes,12,opt,name=all_with_minimum_toolchain_versions,json=allWithMinimumToolchainVersions,proto3,oneof" json:"all_with_minimum_toolchain_versions,omitempty"`
+	AllWithCommitDigests            *VerifyAllWithCommitDigests            `protobuf:"bytes,13,opt,name=all_with_commit_digests,json=allWithCommitDigests,proto3,oneof" json:"all_with_commit_digests,omitempty"`
+	AllWithSourceRef                *VerifyAllWithSourceRef                `protobuf:"bytes,14,opt,name=all_with_source_ref,json=allWithSourceRef,proto3,oneof" json:"all_with_source_ref,omitempty"`
+	AllWithBuildTime                *VerifyAllWithBuildTime                `protobuf:"bytes,15,opt,name=all_with_build_time,json=allWithBuildTime,proto3,oneof" json:"all_with_build_time,omitempty"`
+	AllWithByproducts               *VerifyAllWithByproducts               `protobuf:"bytes,16,opt,name=all_with_byproducts,json=allWithByproducts,proto3,oneof" json:"all_with_byproducts,omitempty"`
+	AllWithBuildMetadata            *VerifyAllWithBuildMetadata            `protobuf:"bytes,17,opt,name=all_with_build_metadata,json=allWithBuildMetadata,proto3,oneof" json:"all_with_build_metadata,omitempty"`
+	AllWithBuildTypes               *VerifyAllWithBuildTypes               `protobuf:"bytes,18,opt,name=all_with_build_types,json=allWithBuildTypes,proto3,oneof" json:"all_with_build_types,omitempty"`
+	// Deny-lists, which are checked before the other options.
+	NoneWithBuilderNames   *VerifyNoneWithBuilderNames   `protobuf:"bytes,19,opt,name=none_with_builder_names,json=noneWithBuilderNames,proto3,oneof" json:"none_with_builder_names,omitempty"`
+	NoneWithRepositories   *VerifyNoneWithRepositories   `protobuf:"bytes,20,opt,name=none_with_repositories,json=noneWithRepositories,proto3,oneof" json:"none_with_repositories,omitempty"`
+	NoneWithBinaryDigests  *VerifyNoneWithBinaryDigests  `protobuf:"bytes,21,opt,name=none_with_binary_digests,json=noneWithBinaryDigests,proto3,oneof" json:"none_with_binary_digests,omitempty"`
+	NoneWithBuilderDigests *VerifyNoneWithBuilderDigests `protobuf:"bytes,22,opt,name=none_with_builder_digests,json=noneWithBuilderDigests,proto3,oneof" json:"none_with_builder_digests,omitempty"`
+	AllWithTreeDigests     *VerifyAllWithTreeDigests     `protobuf:"bytes,23,opt,name=all_with_tree_digests,json=allWithTreeDigests,proto3,oneof" json:"all_with_tree_digests,omitempty"`
 }
 
 func (x *VerificationOptions) Reset() {
@@ -161,6 +175,97 @@ func (x *VerificationOptions) GetAllWithRepository() *VerifyAllWithRepository {
 	return nil
 }
 
+func (x *VerificationOptions) GetAllWithCertificateIdentity() *VerifyAllWithCertificateIdentity {
+	if x != nil {
+		return x.AllWithCertificateIdentity
+	}
+	return nil
+}
+
+func (x *VerificationOptions) GetAllWithMinimumToolchainVersions() *VerifyAllWithMinimumToolchainVersions {
+	if x != nil {
+		return x.AllWithMinimumToolchainVersions
+	}
+	return nil
+}
+
+func (x *VerificationOptions) GetAllWithCommitDigests() *VerifyAllWithCommitDigests {
+	if x != nil {
+		return x.AllWithCommitDigests
+	}
+	return nil
+}
+
+func (x *VerificationOptions) GetAllWithSourceRef() *VerifyAllWithSourceRef {
+	if x != nil {
+		return x.AllWithSourceRef
+	}
+	return nil
+}
+
+func (x *VerificationOptions) GetAllWithBuildTime() *VerifyAllWithBuildTime {
+	if x != nil {
+		return x.AllWithBuildTime
+	}
+	return nil
+}
+
+func (x *VerificationOptions) GetAllWithByproducts() *VerifyAllWithByproducts {
+	if x != nil {
+		return x.AllWithByproducts
+	}
+	return nil
+}
+
+func (x *VerificationOptions) GetAllWithBuildMetadata() *VerifyAllWithBuildMetadata {
+	if x != nil {
+		return x.AllWithBuildMetadata
+	}
+	return nil
+}
+
+func (x *VerificationOptions) GetAllWithBuildTypes() *VerifyAllWithBuildTypes {
+	if x != nil {
+		return x.AllWithBuildTypes
+	}
+	return nil
+}
+
+func (x *VerificationOptions) GetNoneWithBuilderNames() *VerifyNoneWithBuilderNames {
+	if x != nil {
+		return x.NoneWithBuilderNames
+	}
+	return nil
+}
+
+func (x *VerificationOptions) GetNoneWithRepositories() *VerifyNoneWithRepositories {
+	if x != nil {
+		return x.NoneWithRepositories
+	}
+	return nil
+}
+
+func (x *VerificationOptions) GetNoneWithBinaryDigests() *VerifyNoneWithBinaryDigests {
+	if x != nil {
+		return x.NoneWithBinaryDigests
+	}
+	return nil
+}
+
+func (x *VerificationOptions) GetNoneWithBuilderDigests() *VerifyNoneWithBuilderDigests {
+	if x != nil {
+		return x.NoneWithBuilderDigests
+	}
+	return nil
+}
+
+func (x *VerificationOptions) GetAllWithTreeDigests() *VerifyAllWithTreeDigests {
+	if x != nil {
+		return x.AllWithTreeDigests
+	}
+	return nil
+}
+
 // Verifies that the number of provenances is at least the specified count.
 type VerifyProvenanceCountAtLeast struct {
 	state         protoimpl.MessageState
@@ -625,231 +730,1278 @@ func (x *VerifyAllWithBuilderDigests) GetDigests() []*Digest {
 	return nil
 }
 
-var File_proto_verification_options_proto protoreflect.FileDescriptor
+// Verifies that the source commit is among the specified ones, for all
+// available provenances, to pin the exact commit that the binaries must be
+// built from. Commits in the SHA-1 Git object format are specified as SHA1
+// digests, and commits in the SHA-256 object format as SHA2_256 digests.
+type VerifyAllWithCommitDigests struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
 
-var file_proto_verification_options_proto_rawDesc = []byte{
-	0x0a, 0x20, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x76, 0x65, 0x72, 0x69, 0x66, 0x69, 0x63, 0x61,
-	0x74, 0x69, 0x6f, 0x6e, 0x5f, 0x6f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x2e, 0x70, 0x72, 0x6f,
-	0x74, 0x6f, 0x12, 0x0b, 0x6f, 0x61, 0x6b, 0x2e, 0x72, 0x65, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x1a,
-	0x12, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x64, 0x69, 0x67, 0x65, 0x73, 0x74, 0x2e, 0x70, 0x72,
-	0x6f, 0x74, 0x6f, 0x22, 0xfd, 0x09, 0x0a, 0x13, 0x56, 0x65, 0x72, 0x69, 0x66, 0x69, 0x63, 0x61,
-	0x74, 0x69, 0x6f, 0x6e, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x12, 0x69, 0x0a, 0x19, 0x70,
-	0x72, 0x6f, 0x76, 0x65, 0x6e, 0x61, 0x6e, 0x63, 0x65, 0x5f, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x5f,
-	0x61, 0x74, 0x5f, 0x6c, 0x65, 0x61, 0x73, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x29,
-	0x2e, 0x6f, 0x61, 0x6b, 0x2e, 0x72, 0x65, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x2e, 0x56, 0x65, 0x72,
-	0x69, 0x66, 0x79, 0x50, 0x72, 0x6f, 0x76, 0x65, 0x6e, 0x61, 0x6e, 0x63, 0x65, 0x43, 0x6f, 0x75,
-	0x6e, 0x74, 0x41, 0x74, 0x4c, 0x65, 0x61, 0x73, 0x74, 0x48, 0x00, 0x52, 0x16, 0x70, 0x72, 0x6f,
-	0x76, 0x65, 0x6e, 0x61, 0x6e, 0x63, 0x65, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x41, 0x74, 0x4c, 0x65,
-	0x61, 0x73, 0x74, 0x88, 0x01, 0x01, 0x12, 0x66, 0x0a, 0x18, 0x70, 0x72, 0x6f, 0x76, 0x65, 0x6e,
-	0x61, 0x6e, 0x63, 0x65, 0x5f, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x5f, 0x61, 0x74, 0x5f, 0x6d, 0x6f,
-	0x73, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x28, 0x2e, 0x6f, 0x61, 0x6b, 0x2e, 0x72,
-	0x65, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x2e, 0x56, 0x65, 0x72, 0x69, 0x66, 0x79, 0x50, 0x72, 0x6f,
-	0x76, 0x65, 0x6e, 0x61, 0x6e, 0x63, 0x65, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x41, 0x74, 0x4d, 0x6f,
-	0x73, 0x74, 0x48, 0x01, 0x52, 0x15, 0x70, 0x72, 0x6f, 0x76, 0x65, 0x6e, 0x61, 0x6e, 0x63, 0x65,
-	0x43, 0x6f, 0x75, 0x6e, 0x74, 0x41, 0x74, 0x4d, 0x6f, 0x73, 0x74, 0x88, 0x01, 0x01, 0x12, 0x5a,
-	0x0a, 0x14, 0x61, 0x6c, 0x6c, 0x5f, 0x73, 0x61, 0x6d, 0x65, 0x5f, 0x62, 0x69, 0x6e, 0x61, 0x72,
-	0x79, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x24, 0x2e, 0x6f,
-	0x61, 0x6b, 0x2e, 0x72, 0x65, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x2e, 0x56, 0x65, 0x72, 0x69, 0x66,
-	0x79, 0x41, 0x6c, 0x6c, 0x53, 0x61, 0x6d, 0x65, 0x42, 0x69, 0x6e, 0x61, 0x72, 0x79, 0x4e, 0x61,
-	0x6d, 0x65, 0x48, 0x02, 0x52, 0x11, 0x61, 0x6c, 0x6c, 0x53, 0x61, 0x6d, 0x65, 0x42, 0x69, 0x6e,
-	0x61, 0x72, 0x79, 0x4e, 0x61, 0x6d, 0x65, 0x88, 0x01, 0x01, 0x12, 0x60, 0x0a, 0x16, 0x61, 0x6c,
-	0x6c, 0x5f, 0x73, 0x61, 0x6d, 0x65, 0x5f, 0x62, 0x69, 0x6e, 0x61, 0x72, 0x79, 0x5f, 0x64, 0x69,
-	0x67, 0x65, 0x73, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x26, 0x2e, 0x6f, 0x61, 0x6b,
-	0x2e, 0x72, 0x65, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x2e, 0x56, 0x65, 0x72, 0x69, 0x66, 0x79, 0x41,
-	0x6c, 0x6c, 0x53, 0x61, 0x6d, 0x65, 0x42, 0x69, 0x6e, 0x61, 0x72, 0x79, 0x44, 0x69, 0x67, 0x65,
-	0x73, 0x74, 0x48, 0x03, 0x52, 0x13, 0x61, 0x6c, 0x6c, 0x53, 0x61, 0x6d, 0x65, 0x42, 0x69, 0x6e,
-	0x61, 0x72, 0x79, 0x44, 0x69, 0x67, 0x65, 0x73, 0x74, 0x88, 0x01, 0x01, 0x12, 0x60, 0x0a, 0x16,
-	0x61, 0x6c, 0x6c, 0x5f, 0x77, 0x69, 0x74, 0x68, 0x5f, 0x62, 0x75, 0x69, 0x6c, 0x64, 0x5f, 0x63,
-	0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x26, 0x2e, 0x6f,
-	0x61, 0x6b, 0x2e, 0x72, 0x65, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x2e, 0x56, 0x65, 0x72, 0x69, 0x66,
-	0x79, 0x41, 0x6c, 0x6c, 0x57, 0x69, 0x74, 0x68, 0x42, 0x75, 0x69, 0x6c, 0x64, 0x43, 0x6f, 0x6d,
-	0x6d, 0x61, 0x6e, 0x64, 0x48, 0x04, 0x52, 0x13, 0x61, 0x6c, 0x6c, 0x57, 0x69, 0x74, 0x68, 0x42,
-	0x75, 0x69, 0x6c, 0x64, 0x43, 0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64, 0x88, 0x01, 0x01, 0x12, 0x5a,
-	0x0a, 0x14, 0x61, 0x6c, 0x6c, 0x5f, 0x77, 0x69, 0x74, 0x68, 0x5f, 0x62, 0x69, 0x6e, 0x61, 0x72,
-	0x79, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x24, 0x2e, 0x6f,
-	0x61, 0x6b, 0x2e, 0x72, 0x65, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x2e, 0x56, 0x65, 0x72, 0x69, 0x66,
-	0x79, 0x41, 0x6c, 0x6c, 0x57, 0x69, 0x74, 0x68, 0x42, 0x69, 0x6e, 0x61, 0x72, 0x79, 0x4e, 0x61,
-	0x6d, 0x65, 0x48, 0x05, 0x52, 0x11, 0x61, 0x6c, 0x6c, 0x57, 0x69, 0x74, 0x68, 0x42, 0x69, 0x6e,
-	0x61, 0x72, 0x79, 0x4e, 0x61, 0x6d, 0x65, 0x88, 0x01, 0x01, 0x12, 0x63, 0x0a, 0x17, 0x61, 0x6c,
-	0x6c, 0x5f, 0x77, 0x69, 0x74, 0x68, 0x5f, 0x62, 0x69, 0x6e, 0x61, 0x72, 0x79, 0x5f, 0x64, 0x69,
-	0x67, 0x65, 0x73, 0x74, 0x73, 0x18, 0x07, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x27, 0x2e, 0x6f, 0x61,
-	0x6b, 0x2e, 0x72, 0x65, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x2e, 0x56, 0x65, 0x72, 0x69, 0x66, 0x79,
-	0x41, 0x6c, 0x6c, 0x57, 0x69, 0x74, 0x68, 0x42, 0x69, 0x6e, 0x61, 0x72, 0x79, 0x44, 0x69, 0x67,
-	0x65, 0x73, 0x74, 0x73, 0x48, 0x06, 0x52, 0x14, 0x61, 0x6c, 0x6c, 0x57, 0x69, 0x74, 0x68, 0x42,
-	0x69, 0x6e, 0x61, 0x72, 0x79, 0x44, 0x69, 0x67, 0x65, 0x73, 0x74, 0x73, 0x88, 0x01, 0x01, 0x12,
-	0x60, 0x0a, 0x16, 0x61, 0x6c, 0x6c, 0x5f, 0x77, 0x69, 0x74, 0x68, 0x5f, 0x62, 0x75, 0x69, 0x6c,
-	0x64, 0x65, 0x72, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x73, 0x18, 0x08, 0x20, 0x01, 0x28, 0x0b, 0x32,
-	0x26, 0x2e, 0x6f, 0x61, 0x6b, 0x2e, 0x72, 0x65, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x2e, 0x56, 0x65,
-	0x72, 0x69, 0x66, 0x79, 0x41, 0x6c, 0x6c, 0x57, 0x69, 0x74, 0x68, 0x42, 0x75, 0x69, 0x6c, 0x64,
-	0x65, 0x72, 0x4e, 0x61, 0x6d, 0x65, 0x73, 0x48, 0x07, 0x52, 0x13, 0x61, 0x6c, 0x6c, 0x57, 0x69,
-	0x74, 0x68, 0x42, 0x75, 0x69, 0x6c, 0x64, 0x65, 0x72, 0x4e, 0x61, 0x6d, 0x65, 0x73, 0x88, 0x01,
-	0x01, 0x12, 0x66, 0x0a, 0x18, 0x61, 0x6c, 0x6c, 0x5f, 0x77, 0x69, 0x74, 0x68, 0x5f, 0x62, 0x75,
-	0x69, 0x6c, 0x64, 0x65, 0x72, 0x5f, 0x64, 0x69, 0x67, 0x65, 0x73, 0x74, 0x73, 0x18, 0x09, 0x20,
-	0x01, 0x28, 0x0b, 0x32, 0x28, 0x2e, 0x6f, 0x61, 0x6b, 0x2e, 0x72, 0x65, 0x6c, 0x65, 0x61, 0x73,
-	0x65, 0x2e, 0x56, 0x65, 0x72, 0x69, 0x66, 0x79, 0x41, 0x6c, 0x6c, 0x57, 0x69, 0x74, 0x68, 0x42,
-	0x75, 0x69, 0x6c, 0x64, 0x65, 0x72, 0x44, 0x69, 0x67, 0x65, 0x73, 0x74, 0x73, 0x48, 0x08, 0x52,
-	0x15, 0x61, 0x6c, 0x6c, 0x57, 0x69, 0x74, 0x68, 0x42, 0x75, 0x69, 0x6c, 0x64, 0x65, 0x72, 0x44,
-	0x69, 0x67, 0x65, 0x73, 0x74, 0x73, 0x88, 0x01, 0x01, 0x12, 0x59, 0x0a, 0x13, 0x61, 0x6c, 0x6c,
-	0x5f, 0x77, 0x69, 0x74, 0x68, 0x5f, 0x72, 0x65, 0x70, 0x6f, 0x73, 0x69, 0x74, 0x6f, 0x72, 0x79,
-	0x18, 0x0a, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x24, 0x2e, 0x6f, 0x61, 0x6b, 0x2e, 0x72, 0x65, 0x6c,
-	0x65, 0x61, 0x73, 0x65, 0x2e, 0x56, 0x65, 0x72, 0x69, 0x66, 0x79, 0x41, 0x6c, 0x6c, 0x57, 0x69,
-	0x74, 0x68, 0x52, 0x65, 0x70, 0x6f, 0x73, 0x69, 0x74, 0x6f, 0x72, 0x79, 0x48, 0x09, 0x52, 0x11,
-	0x61, 0x6c, 0x6c, 0x57, 0x69, 0x74, 0x68, 0x52, 0x65, 0x70, 0x6f, 0x73, 0x69, 0x74, 0x6f, 0x72,
-	0x79, 0x88, 0x01, 0x01, 0x42, 0x1c, 0x0a, 0x1a, 0x5f, 0x70, 0x72, 0x6f, 0x76, 0x65, 0x6e, 0x61,
-	0x6e, 0x63, 0x65, 0x5f, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x5f, 0x61, 0x74, 0x5f, 0x6c, 0x65, 0x61,
-	0x73, 0x74, 0x42, 0x1b, 0x0a, 0x19, 0x5f, 0x70, 0x72, 0x6f, 0x76, 0x65, 0x6e, 0x61, 0x6e, 0x63,
-	0x65, 0x5f, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x5f, 0x61, 0x74, 0x5f, 0x6d, 0x6f, 0x73, 0x74, 0x42,
-	0x17, 0x0a, 0x15, 0x5f, 0x61, 0x6c, 0x6c, 0x5f, 0x73, 0x61, 0x6d, 0x65, 0x5f, 0x62, 0x69, 0x6e,
-	0x61, 0x72, 0x79, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x42, 0x19, 0x0a, 0x17, 0x5f, 0x61, 0x6c, 0x6c,
-	0x5f, 0x73, 0x61, 0x6d, 0x65, 0x5f, 0x62, 0x69, 0x6e, 0x61, 0x72, 0x79, 0x5f, 0x64, 0x69, 0x67,
-	0x65, 0x73, 0x74, 0x42, 0x19, 0x0a, 0x17, 0x5f, 0x61, 0x6c, 0x6c, 0x5f, 0x77, 0x69, 0x74, 0x68,
-	0x5f, 0x62, 0x75, 0x69, 0x6c, 0x64, 0x5f, 0x63, 0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64, 0x42, 0x17,
-	0x0a, 0x15, 0x5f, 0x61, 0x6c, 0x6c, 0x5f, 0x77, 0x69, 0x74, 0x68, 0x5f, 0x62, 0x69, 0x6e, 0x61,
-	0x72, 0x79, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x42, 0x1a, 0x0a, 0x18, 0x5f, 0x61, 0x6c, 0x6c, 0x5f,
-	0x77, 0x69, 0x74, 0x68, 0x5f, 0x62, 0x69, 0x6e, 0x61, 0x72, 0x79, 0x5f, 0x64, 0x69, 0x67, 0x65,
-	0x73, 0x74, 0x73, 0x42, 0x19, 0x0a, 0x17, 0x5f, 0x61, 0x6c, 0x6c, 0x5f, 0x77, 0x69, 0x74, 0x68,
-	0x5f, 0x62, 0x75, 0x69, 0x6c, 0x64, 0x65, 0x72, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x73, 0x42, 0x1b,
-	0x0a, 0x19, 0x5f, 0x61, 0x6c, 0x6c, 0x5f, 0x77, 0x69, 0x74, 0x68, 0x5f, 0x62, 0x75, 0x69, 0x6c,
-	0x64, 0x65, 0x72, 0x5f, 0x64, 0x69, 0x67, 0x65, 0x73, 0x74, 0x73, 0x42, 0x16, 0x0a, 0x14, 0x5f,
-	0x61, 0x6c, 0x6c, 0x5f, 0x77, 0x69, 0x74, 0x68, 0x5f, 0x72, 0x65, 0x70, 0x6f, 0x73, 0x69, 0x74,
-	0x6f, 0x72, 0x79, 0x22, 0x34, 0x0a, 0x1c, 0x56, 0x65, 0x72, 0x69, 0x66, 0x79, 0x50, 0x72, 0x6f,
-	0x76, 0x65, 0x6e, 0x61, 0x6e, 0x63, 0x65, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x41, 0x74, 0x4c, 0x65,
-	0x61, 0x73, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x01, 0x20, 0x01,
-	0x28, 0x05, 0x52, 0x05, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x22, 0x33, 0x0a, 0x1b, 0x56, 0x65, 0x72,
-	0x69, 0x66, 0x79, 0x50, 0x72, 0x6f, 0x76, 0x65, 0x6e, 0x61, 0x6e, 0x63, 0x65, 0x43, 0x6f, 0x75,
-	0x6e, 0x74, 0x41, 0x74, 0x4d, 0x6f, 0x73, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x63, 0x6f, 0x75, 0x6e,
-	0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x05, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x22, 0x19,
-	0x0a, 0x17, 0x56, 0x65, 0x72, 0x69, 0x66, 0x79, 0x41, 0x6c, 0x6c, 0x53, 0x61, 0x6d, 0x65, 0x42,
-	0x69, 0x6e, 0x61, 0x72, 0x79, 0x4e, 0x61, 0x6d, 0x65, 0x22, 0x1b, 0x0a, 0x19, 0x56, 0x65, 0x72,
-	0x69, 0x66, 0x79, 0x41, 0x6c, 0x6c, 0x53, 0x61, 0x6d, 0x65, 0x42, 0x69, 0x6e, 0x61, 0x72, 0x79,
-	0x44, 0x69, 0x67, 0x65, 0x73, 0x74, 0x22, 0x1b, 0x0a, 0x19, 0x56, 0x65, 0x72, 0x69, 0x66, 0x79,
-	0x41, 0x6c, 0x6c, 0x57, 0x69, 0x74, 0x68, 0x42, 0x75, 0x69, 0x6c, 0x64, 0x43, 0x6f, 0x6d, 0x6d,
-	0x61, 0x6e, 0x64, 0x22, 0x3a, 0x0a, 0x17, 0x56, 0x65, 0x72, 0x69, 0x66, 0x79, 0x41, 0x6c, 0x6c,
-	0x57, 0x69, 0x74, 0x68, 0x42, 0x69, 0x6e, 0x61, 0x72, 0x79, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x1f,
-	0x0a, 0x0b, 0x62, 0x69, 0x6e, 0x61, 0x72, 0x79, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20,
-	0x01, 0x28, 0x09, 0x52, 0x0a, 0x62, 0x69, 0x6e, 0x61, 0x72, 0x79, 0x4e, 0x61, 0x6d, 0x65, 0x22,
-	0x4b, 0x0a, 0x1a, 0x56, 0x65, 0x72, 0x69, 0x66, 0x79, 0x41, 0x6c, 0x6c, 0x57, 0x69, 0x74, 0x68,
-	0x42, 0x69, 0x6e, 0x61, 0x72, 0x79, 0x44, 0x69, 0x67, 0x65, 0x73, 0x74, 0x73, 0x12, 0x2d, 0x0a,
-	0x07, 0x64, 0x69, 0x67, 0x65, 0x73, 0x74, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x13,
-	0x2e, 0x6f, 0x61, 0x6b, 0x2e, 0x72, 0x65, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x2e, 0x44, 0x69, 0x67,
-	0x65, 0x73, 0x74, 0x52, 0x07, 0x64, 0x69, 0x67, 0x65, 0x73, 0x74, 0x73, 0x22, 0x40, 0x0a, 0x17,
-	0x56, 0x65, 0x72, 0x69, 0x66, 0x79, 0x41, 0x6c, 0x6c, 0x57, 0x69, 0x74, 0x68, 0x52, 0x65, 0x70,
-	0x6f, 0x73, 0x69, 0x74, 0x6f, 0x72, 0x79, 0x12, 0x25, 0x0a, 0x0e, 0x72, 0x65, 0x70, 0x6f, 0x73,
-	0x69, 0x74, 0x6f, 0x72, 0x79, 0x5f, 0x75, 0x72, 0x69, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
-	0x0d, 0x72, 0x65, 0x70, 0x6f, 0x73, 0x69, 0x74, 0x6f, 0x72, 0x79, 0x55, 0x72, 0x69, 0x22, 0x40,
-	0x0a, 0x19, 0x56, 0x65, 0x72, 0x69, 0x66, 0x79, 0x41, 0x6c, 0x6c, 0x57, 0x69, 0x74, 0x68, 0x42,
-	0x75, 0x69, 0x6c, 0x64, 0x65, 0x72, 0x4e, 0x61, 0x6d, 0x65, 0x73, 0x12, 0x23, 0x0a, 0x0d, 0x62,
-	0x75, 0x69, 0x6c, 0x64, 0x65, 0x72, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03,
-	0x28, 0x09, 0x52, 0x0c, 0x62, 0x75, 0x69, 0x6c, 0x64, 0x65, 0x72, 0x4e, 0x61, 0x6d, 0x65, 0x73,
-	0x22, 0x4c, 0x0a, 0x1b, 0x56, 0x65, 0x72, 0x69, 0x66, 0x79, 0x41, 0x6c, 0x6c, 0x57, 0x69, 0x74,
-	0x68, 0x42, 0x75, 0x69, 0x6c, 0x64, 0x65, 0x72, 0x44, 0x69, 0x67, 0x65, 0x73, 0x74, 0x73, 0x12,
-	0x2d, 0x0a, 0x07, 0x64, 0x69, 0x67, 0x65, 0x73, 0x74, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b,
-	0x32, 0x13, 0x2e, 0x6f, 0x61, 0x6b, 0x2e, 0x72, 0x65, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x2e, 0x44,
-	0x69, 0x67, 0x65, 0x73, 0x74, 0x52, 0x07, 0x64, 0x69, 0x67, 0x65, 0x73, 0x74, 0x73, 0x42, 0x13,
-	0x5a, 0x11, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x6f, 0x61, 0x6b, 0x2f, 0x72, 0x65, 0x6c, 0x65,
-	0x61, 0x73, 0x65, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	Digests []*Digest `protobuf:"bytes,1,rep,name=digests,proto3" json:"digests,omitempty"`
 }
 
-var (
-	file_proto_verification_options_proto_rawDescOnce sync.Once
-	file_proto_verification_options_proto_rawDescData = file_proto_verification_options_proto_rawDesc
-)
+func (x *VerifyAllWithCommitDigests) Reset() {
+	*x = VerifyAllWithCommitDigests{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_verification_options_proto_msgTypes[11]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
 
-func file_proto_verification_options_proto_rawDescGZIP() []byte {
-	file_proto_verification_options_proto_rawDescOnce.Do(func() {
-		file_proto_verification_options_proto_rawDescData = protoimpl.X.CompressGZIP(file_proto_verification_options_proto_rawDescData)
-	})
-	return file_proto_verification_options_proto_rawDescData
+func (x *VerifyAllWithCommitDigests) String() string {
+	return protoimpl.X.MessageStringOf(x)
 }
 
-var file_proto_verification_options_proto_msgTypes = make([]protoimpl.MessageInfo, 11)
-var file_proto_verification_options_proto_goTypes = []interface{}{
-	(*VerificationOptions)(nil),          // 0: oak.release.VerificationOptions
-	(*VerifyProvenanceCountAtLeast)(nil), // 1: oak.release.VerifyProvenanceCountAtLeast
-	(*VerifyProvenanceCountAtMost)(nil),  // 2: oak.release.VerifyProvenanceCountAtMost
-	(*VerifyAllSameBinaryName)(nil),      // 3: oak.release.VerifyAllSameBinaryName
-	(*VerifyAllSameBinaryDigest)(nil),    // 4: oak.release.VerifyAllSameBinaryDigest
-	(*VerifyAllWithBuildCommand)(nil),    // 5: oak.release.VerifyAllWithBuildCommand
-	(*VerifyAllWithBinaryName)(nil),      // 6: oak.release.VerifyAllWithBinaryName
-	(*VerifyAllWithBinaryDigests)(nil),   // 7: oak.release.VerifyAllWithBinaryDigests
-	(*VerifyAllWithRepository)(nil),      // 8: oak.release.VerifyAllWithRepository
-	(*VerifyAllWithBuilderNames)(nil),    // 9: oak.release.VerifyAllWithBuilderNames
-	(*VerifyAllWithBuilderDigests)(nil),  // 10: oak.release.VerifyAllWithBuilderDigests
-	(*Digest)(nil),                       // 11: oak.release.Digest
+func (*VerifyAllWithCommitDigests) ProtoMessage() {}
+
+func (x *VerifyAllWithCommitDigests) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_verification_options_proto_msgTypes[11]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
 }
-var file_proto_verification_options_proto_depIdxs = []int32{
-	1,  // 0: oak.release.VerificationOptions.provenance_count_at_least:type_name -> oak.release.VerifyProvenanceCountAtLeast
-	2,  // 1: oak.release.VerificationOptions.provenance_count_at_most:type_name -> oak.release.VerifyProvenanceCountAtMost
-	3,  // 2: oak.release.VerificationOptions.all_same_binary_name:type_name -> oak.release.VerifyAllSameBinaryName
-	4,  // 3: oak.release.VerificationOptions.all_same_binary_digest:type_name -> oak.release.VerifyAllSameBinaryDigest
-	5,  // 4: oak.release.VerificationOptions.all_with_build_command:type_name -> oak.release.VerifyAllWithBuildCommand
-	6,  // 5: oak.release.VerificationOptions.all_with_binary_name:type_name -> oak.release.VerifyAllWithBinaryName
-	7,  // 6: oak.release.VerificationOptions.all_with_binary_digests:type_name -> oak.release.VerifyAllWithBinaryDigests
-	9,  // 7: oak.release.VerificationOptions.all_with_builder_names:type_name -> oak.release.VerifyAllWithBuilderNames
-	10, // 8: oak.release.VerificationOptions.all_with_builder_digests:type_name -> oak.release.VerifyAllWithBuilderDigests
-	8,  // 9: oak.release.VerificationOptions.all_with_repository:type_name -> oak.release.VerifyAllWithRepository
-	11, // 10: oak.release.VerifyAllWithBinaryDigests.digests:type_name -> oak.release.Digest
-	11, // 11: oak.release.VerifyAllWithBuilderDigests.digests:type_name -> oak.release.Digest
-	12, // [12:12] is the sub-list for method output_type
-	12, // [12:12] is the sub-list for method input_type
-	12, // [12:12] is the sub-list for extension type_name
-	12, // [12:12] is the sub-list for extension extendee
-	0,  // [0:12] is the sub-list for field type_name
+
+// Deprecated: Use VerifyAllWithCommitDigests.ProtoReflect.Descriptor instead.
+func (*VerifyAllWithCommitDigests) Descriptor() ([]byte, []int) {
+	return file_proto_verification_options_proto_rawDescGZIP(), []int{11}
 }
 
-func init() { file_proto_verification_options_proto_init() }
-func file_proto_verification_options_proto_init() {
-	if File_proto_verification_options_proto != nil {
-		return
+func (x *VerifyAllWithCommitDigests) GetDigests() []*Digest {
+	if x != nil {
+		return x.Digests
 	}
-	file_proto_digest_proto_init()
-	if !protoimpl.UnsafeEnabled {
-		file_proto_verification_options_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*VerificationOptions); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
-		}
-		file_proto_verification_options_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*VerifyProvenanceCountAtLeast); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
-		}
-		file_proto_verification_options_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*VerifyProvenanceCountAtMost); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
+	return nil
+}
+
+// Verifies that the Git tree of the source commit is among the specified
+// ones, for all available provenances. Unlike a commit, a tree only depends
+// on the content of the source, so that a reviewed tree remains valid when it
+// is committed again, e.g., rebased or cherry-picked. Provenances that do not
+// record the tree of the source fail this check.
+type VerifyAllWithTreeDigests struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Digests []*Digest `protobuf:"bytes,1,rep,name=digests,proto3" json:"digests,omitempty"`
+}
+
+func (x *VerifyAllWithTreeDigests) Reset() {
+	*x = VerifyAllWithTreeDigests{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_verification_options_proto_msgTypes[12]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *VerifyAllWithTreeDigests) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VerifyAllWithTreeDigests) ProtoMessage() {}
+
+func (x *VerifyAllWithTreeDigests) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_verification_options_proto_msgTypes[12]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
 		}
-		file_proto_verification_options_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*VerifyAllSameBinaryName); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VerifyAllWithTreeDigests.ProtoReflect.Descriptor instead.
+func (*VerifyAllWithTreeDigests) Descriptor() ([]byte, []int) {
+	return file_proto_verification_options_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *VerifyAllWithTreeDigests) GetDigests() []*Digest {
+	if x != nil {
+		return x.Digests
+	}
+	return nil
+}
+
+// Verifies that the Git ref of the source that the build was invoked on, e.g.,
+// `refs/tags/v1.2.3` or `refs/heads/main`, matches ONE of the specified
+// patterns, for all available provenances, so that only binaries built from
+// release tags or protected branches pass. Patterns use the syntax of Go's
+// path.Match, where `*` does not match `/`, e.g., `refs/tags/v*`. Provenances
+// without a source ref fail the verification.
+type VerifyAllWithSourceRef struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Patterns []string `protobuf:"bytes,1,rep,name=patterns,proto3" json:"patterns,omitempty"`
+}
+
+func (x *VerifyAllWithSourceRef) Reset() {
+	*x = VerifyAllWithSourceRef{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_verification_options_proto_msgTypes[13]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *VerifyAllWithSourceRef) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VerifyAllWithSourceRef) ProtoMessage() {}
+
+func (x *VerifyAllWithSourceRef) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_verification_options_proto_msgTypes[13]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VerifyAllWithSourceRef.ProtoReflect.Descriptor instead.
+func (*VerifyAllWithSourceRef) Descriptor() ([]byte, []int) {
+	return file_proto_verification_options_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *VerifyAllWithSourceRef) GetPatterns() []string {
+	if x != nil {
+		return x.Patterns
+	}
+	return nil
+}
+
+// Verifies that the build finished within the specified window, for all
+// available provenances, e.g., to reject stale provenances of binaries built
+// before a vulnerable dependency was patched. Times are in RFC 3339 format,
+// e.g., `2023-06-01T00:00:00Z`. Either bound may be omitted. Provenances
+// without a build finish time fail the verification.
+type VerifyAllWithBuildTime struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// The build must have finished at or after this time.
+	FinishedAfter string `protobuf:"bytes,1,opt,name=finished_after,json=finishedAfter,proto3" json:"finished_after,omitempty"`
+	// The build must have finished at or before this time.
+	FinishedBefore string `protobuf:"bytes,2,opt,name=finished_before,json=finishedBefore,proto3" json:"finished_before,omitempty"`
+}
+
+func (x *VerifyAllWithBuildTime) Reset() {
+	*x = VerifyAllWithBuildTime{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_verification_options_proto_msgTypes[14]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *VerifyAllWithBuildTime) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VerifyAllWithBuildTime) ProtoMessage() {}
+
+func (x *VerifyAllWithBuildTime) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_verification_options_proto_msgTypes[14]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VerifyAllWithBuildTime.ProtoReflect.Descriptor instead.
+func (*VerifyAllWithBuildTime) Descriptor() ([]byte, []int) {
+	return file_proto_verification_options_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *VerifyAllWithBuildTime) GetFinishedAfter() string {
+	if x != nil {
+		return x.FinishedAfter
+	}
+	return ""
+}
+
+func (x *VerifyAllWithBuildTime) GetFinishedBefore() string {
+	if x != nil {
+		return x.FinishedBefore
+	}
+	return ""
+}
+
+// Verifies that all provenances reference the specified byproducts of the
+// build, e.g., the build log, so that audit policies can require that logs are
+// retained and referenced from the provenance. Byproducts are matched by name,
+// and must have a digest. Only SLSA v1 provenances record byproducts.
+type VerifyAllWithByproducts struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Byproducts []*RequiredByproduct `protobuf:"bytes,1,rep,name=byproducts,proto3" json:"byproducts,omitempty"`
+}
+
+func (x *VerifyAllWithByproducts) Reset() {
+	*x = VerifyAllWithByproducts{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_verification_options_proto_msgTypes[15]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *VerifyAllWithByproducts) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VerifyAllWithByproducts) ProtoMessage() {}
+
+func (x *VerifyAllWithByproducts) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_verification_options_proto_msgTypes[15]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VerifyAllWithByproducts.ProtoReflect.Descriptor instead.
+func (*VerifyAllWithByproducts) Descriptor() ([]byte, []int) {
+	return file_proto_verification_options_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *VerifyAllWithByproducts) GetByproducts() []*RequiredByproduct {
+	if x != nil {
+		return x.Byproducts
+	}
+	return nil
+}
+
+// A byproduct that must be referenced from the provenance.
+type RequiredByproduct struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// The name of the byproduct, e.g., `build.log`.
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// If not empty, the digest of the byproduct must match ONE of these.
+	Digests []*Digest `protobuf:"bytes,2,rep,name=digests,proto3" json:"digests,omitempty"`
+}
+
+func (x *RequiredByproduct) Reset() {
+	*x = RequiredByproduct{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_verification_options_proto_msgTypes[16]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *RequiredByproduct) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RequiredByproduct) ProtoMessage() {}
+
+func (x *RequiredByproduct) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_verification_options_proto_msgTypes[16]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RequiredByproduct.ProtoReflect.Descriptor instead.
+func (*RequiredByproduct) Descriptor() ([]byte, []int) {
+	return file_proto_verification_options_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *RequiredByproduct) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *RequiredByproduct) GetDigests() []*Digest {
+	if x != nil {
+		return x.Digests
+	}
+	return nil
+}
+
+// Verifies that the builder makes the specified claims about the build, for
+// all available provenances. Only the claims set to true are required. These
+// claims are only recorded in SLSA v0.2 provenances, so provenances in other
+// formats fail the verification if any claim is required. An empty message
+// requires no claims, and passes for all provenances.
+type VerifyAllWithBuildMetadata struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Requires that all external parameters of the build are recorded.
+	ParametersComplete bool `protobuf:"varint,1,opt,name=parameters_complete,json=parametersComplete,proto3" json:"parameters_complete,omitempty"`
+	// Requires that the environment of the build is recorded.
+	EnvironmentComplete bool `protobuf:"varint,2,opt,name=environment_complete,json=environmentComplete,proto3" json:"environment_complete,omitempty"`
+	// Requires that all materials of the build are recorded, i.e., that the
+	// build is hermetic.
+	MaterialsComplete bool `protobuf:"varint,3,opt,name=materials_complete,json=materialsComplete,proto3" json:"materials_complete,omitempty"`
+	// Requires that the build is reproducible.
+	Reproducible bool `protobuf:"varint,4,opt,name=reproducible,proto3" json:"reproducible,omitempty"`
+}
+
+func (x *VerifyAllWithBuildMetadata) Reset() {
+	*x = VerifyAllWithBuildMetadata{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_verification_options_proto_msgTypes[17]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *VerifyAllWithBuildMetadata) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VerifyAllWithBuildMetadata) ProtoMessage() {}
+
+func (x *VerifyAllWithBuildMetadata) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_verification_options_proto_msgTypes[17]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VerifyAllWithBuildMetadata.ProtoReflect.Descriptor instead.
+func (*VerifyAllWithBuildMetadata) Descriptor() ([]byte, []int) {
+	return file_proto_verification_options_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *VerifyAllWithBuildMetadata) GetParametersComplete() bool {
+	if x != nil {
+		return x.ParametersComplete
+	}
+	return false
+}
+
+func (x *VerifyAllWithBuildMetadata) GetEnvironmentComplete() bool {
+	if x != nil {
+		return x.EnvironmentComplete
+	}
+	return false
+}
+
+func (x *VerifyAllWithBuildMetadata) GetMaterialsComplete() bool {
+	if x != nil {
+		return x.MaterialsComplete
+	}
+	return false
+}
+
+func (x *VerifyAllWithBuildMetadata) GetReproducible() bool {
+	if x != nil {
+		return x.Reproducible
+	}
+	return false
+}
+
+// Verifies that the build type is among the specified ones, for all available
+// provenances, e.g., to pin the container-based build type of SLSA v1
+// provenances.
+type VerifyAllWithBuildTypes struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	BuildTypes []string `protobuf:"bytes,1,rep,name=build_types,json=buildTypes,proto3" json:"build_types,omitempty"`
+}
+
+func (x *VerifyAllWithBuildTypes) Reset() {
+	*x = VerifyAllWithBuildTypes{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_verification_options_proto_msgTypes[18]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *VerifyAllWithBuildTypes) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VerifyAllWithBuildTypes) ProtoMessage() {}
+
+func (x *VerifyAllWithBuildTypes) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_verification_options_proto_msgTypes[18]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VerifyAllWithBuildTypes.ProtoReflect.Descriptor instead.
+func (*VerifyAllWithBuildTypes) Descriptor() ([]byte, []int) {
+	return file_proto_verification_options_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *VerifyAllWithBuildTypes) GetBuildTypes() []string {
+	if x != nil {
+		return x.BuildTypes
+	}
+	return nil
+}
+
+// Verifies that the trusted builder is none of the specified ones, for all
+// available provenances, e.g., to block a compromised builder.
+type VerifyNoneWithBuilderNames struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	BuilderNames []string `protobuf:"bytes,1,rep,name=builder_names,json=builderNames,proto3" json:"builder_names,omitempty"`
+}
+
+func (x *VerifyNoneWithBuilderNames) Reset() {
+	*x = VerifyNoneWithBuilderNames{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_verification_options_proto_msgTypes[19]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *VerifyNoneWithBuilderNames) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VerifyNoneWithBuilderNames) ProtoMessage() {}
+
+func (x *VerifyNoneWithBuilderNames) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_verification_options_proto_msgTypes[19]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VerifyNoneWithBuilderNames.ProtoReflect.Descriptor instead.
+func (*VerifyNoneWithBuilderNames) Descriptor() ([]byte, []int) {
+	return file_proto_verification_options_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *VerifyNoneWithBuilderNames) GetBuilderNames() []string {
+	if x != nil {
+		return x.BuilderNames
+	}
+	return nil
+}
+
+// Verifies that the repository is none of the specified ones, for all
+// available provenances.
+type VerifyNoneWithRepositories struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	RepositoryUris []string `protobuf:"bytes,1,rep,name=repository_uris,json=repositoryUris,proto3" json:"repository_uris,omitempty"`
+}
+
+func (x *VerifyNoneWithRepositories) Reset() {
+	*x = VerifyNoneWithRepositories{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_verification_options_proto_msgTypes[20]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *VerifyNoneWithRepositories) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VerifyNoneWithRepositories) ProtoMessage() {}
+
+func (x *VerifyNoneWithRepositories) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_verification_options_proto_msgTypes[20]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VerifyNoneWithRepositories.ProtoReflect.Descriptor instead.
+func (*VerifyNoneWithRepositories) Descriptor() ([]byte, []int) {
+	return file_proto_verification_options_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *VerifyNoneWithRepositories) GetRepositoryUris() []string {
+	if x != nil {
+		return x.RepositoryUris
+	}
+	return nil
+}
+
+// Verifies that the binary digest matches none of the specified ones, for all
+// available provenances, e.g., to block yanked binaries.
+type VerifyNoneWithBinaryDigests struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Digests []*Digest `protobuf:"bytes,1,rep,name=digests,proto3" json:"digests,omitempty"`
+}
+
+func (x *VerifyNoneWithBinaryDigests) Reset() {
+	*x = VerifyNoneWithBinaryDigests{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_verification_options_proto_msgTypes[21]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *VerifyNoneWithBinaryDigests) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VerifyNoneWithBinaryDigests) ProtoMessage() {}
+
+func (x *VerifyNoneWithBinaryDigests) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_verification_options_proto_msgTypes[21]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VerifyNoneWithBinaryDigests.ProtoReflect.Descriptor instead.
+func (*VerifyNoneWithBinaryDigests) Descriptor() ([]byte, []int) {
+	return file_proto_verification_options_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *VerifyNoneWithBinaryDigests) GetDigests() []*Digest {
+	if x != nil {
+		return x.Digests
+	}
+	return nil
+}
+
+// Verifies that the builder image digest matches none of the specified ones,
+// for all available provenances, e.g., to block a compromised builder image.
+type VerifyNoneWithBuilderDigests struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Digests []*Digest `protobuf:"bytes,1,rep,name=digests,proto3" json:"digests,omitempty"`
+}
+
+func (x *VerifyNoneWithBuilderDigests) Reset() {
+	*x = VerifyNoneWithBuilderDigests{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_verification_options_proto_msgTypes[22]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *VerifyNoneWithBuilderDigests) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VerifyNoneWithBuilderDigests) ProtoMessage() {}
+
+func (x *VerifyNoneWithBuilderDigests) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_verification_options_proto_msgTypes[22]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VerifyNoneWithBuilderDigests.ProtoReflect.Descriptor instead.
+func (*VerifyNoneWithBuilderDigests) Descriptor() ([]byte, []int) {
+	return file_proto_verification_options_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *VerifyNoneWithBuilderDigests) GetDigests() []*Digest {
+	if x != nil {
+		return x.Digests
+	}
+	return nil
+}
+
+// Verifies that all provenances are Sigstore bundles, signed with a Fulcio
+// certificate issued to the specified identity. Provenances are only
+// considered to have a certificate identity if their bundle has been verified
+// against trusted Fulcio roots and a Rekor public key when loading them.
+//
+// The subject alternative name and the issuer must always match. The other
+// fields are only checked if they are set.
+type VerifyAllWithCertificateIdentity struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// The expected subject alternative name of the signing certificate, e.g.,
+	// the URI of the GitHub Actions workflow, including its ref. For reusable
+	// workflows, such as the SLSA3 GitHub generators, this is the URI of the
+	// reusable workflow.
+	SubjectAlternativeName string `protobuf:"bytes,1,opt,name=subject_alternative_name,json=subjectAlternativeName,proto3" json:"subject_alternative_name,omitempty"`
+	// The expected OIDC issuer of the signing certificate, e.g.,
+	// https://token.actions.githubusercontent.com.
+	Issuer string `protobuf:"bytes,2,opt,name=issuer,proto3" json:"issuer,omitempty"`
+	// The expected URI of the top-level workflow, including its ref, e.g.,
+	// https://github.com/project-oak/oak/.github/workflows/provenance.yaml@refs/heads/main,
+	// from the Build Config URI extension.
+	BuildConfigUri string `protobuf:"bytes,3,opt,name=build_config_uri,json=buildConfigUri,proto3" json:"build_config_uri,omitempty"`
+	// The expected ref of the source repository at which the workflow ran,
+	// e.g., refs/heads/main, from the Source Repository Ref extension.
+	SourceRepositoryRef string `protobuf:"bytes,4,opt,name=source_repository_ref,json=sourceRepositoryRef,proto3" json:"source_repository_ref,omitempty"`
+	// The expected URI of the owner of the source repository, e.g.,
+	// https://github.com/project-oak, from the Source Repository Owner URI
+	// extension.
+	SourceRepositoryOwnerUri string `protobuf:"bytes,5,opt,name=source_repository_owner_uri,json=sourceRepositoryOwnerUri,proto3" json:"source_repository_owner_uri,omitempty"`
+}
+
+func (x *VerifyAllWithCertificateIdentity) Reset() {
+	*x = VerifyAllWithCertificateIdentity{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_verification_options_proto_msgTypes[23]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *VerifyAllWithCertificateIdentity) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VerifyAllWithCertificateIdentity) ProtoMessage() {}
+
+func (x *VerifyAllWithCertificateIdentity) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_verification_options_proto_msgTypes[23]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VerifyAllWithCertificateIdentity.ProtoReflect.Descriptor instead.
+func (*VerifyAllWithCertificateIdentity) Descriptor() ([]byte, []int) {
+	return file_proto_verification_options_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *VerifyAllWithCertificateIdentity) GetSubjectAlternativeName() string {
+	if x != nil {
+		return x.SubjectAlternativeName
+	}
+	return ""
+}
+
+func (x *VerifyAllWithCertificateIdentity) GetIssuer() string {
+	if x != nil {
+		return x.Issuer
+	}
+	return ""
+}
+
+func (x *VerifyAllWithCertificateIdentity) GetBuildConfigUri() string {
+	if x != nil {
+		return x.BuildConfigUri
+	}
+	return ""
+}
+
+func (x *VerifyAllWithCertificateIdentity) GetSourceRepositoryRef() string {
+	if x != nil {
+		return x.SourceRepositoryRef
+	}
+	return ""
+}
+
+func (x *VerifyAllWithCertificateIdentity) GetSourceRepositoryOwnerUri() string {
+	if x != nil {
+		return x.SourceRepositoryOwnerUri
+	}
+	return ""
+}
+
+// Verifies that all provenances record the versions of the specified
+// toolchains in the builder image, and that these are at least the specified
+// minimum versions, e.g., to reject binaries built with a compiler that is
+// affected by a known vulnerability. Versions are compared by their numeric
+// components, so `1.70` is the same as `1.70.0`.
+type VerifyAllWithMinimumToolchainVersions struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Maps the names of toolchains, e.g., `rustc`, to their minimum versions,
+	// e.g., `1.70.0`.
+	MinimumVersions map[string]string `protobuf:"bytes,1,rep,name=minimum_versions,json=minimumVersions,proto3" json:"minimum_versions,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+}
+
+func (x *VerifyAllWithMinimumToolchainVersions) Reset() {
+	*x = VerifyAllWithMinimumToolchainVersions{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_verification_options_proto_msgTypes[24]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *VerifyAllWithMinimumToolchainVersions) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VerifyAllWithMinimumToolchainVersions) ProtoMessage() {}
+
+func (x *VerifyAllWithMinimumToolchainVersions) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_verification_options_proto_msgTypes[24]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VerifyAllWithMinimumToolchainVersions.ProtoReflect.Descriptor instead.
+func (*VerifyAllWithMinimumToolchainVersions) Descriptor() ([]byte, []int) {
+	return file_proto_verification_options_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *VerifyAllWithMinimumToolchainVersions) GetMinimumVersions() map[string]string {
+	if x != nil {
+		return x.MinimumVersions
+	}
+	return nil
+}
+
+var File_proto_verification_options_proto protoreflect.FileDescriptor
+
+var file_proto_verification_options_proto_rawDesc = []byte{
+	0x0a, 0x20, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x76, 0x65, 0x72, 0x69, 0x66, 0x69, 0x63, 0x61,
+	0x74, 0x69, 0x6f, 0x6e, 0x5f, 0x6f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x2e, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x12, 0x0b, 0x6f, 0x61, 0x6b, 0x2e, 0x72, 0x65, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x1a,
+	0x12, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x64, 0x69, 0x67, 0x65, 0x73, 0x74, 0x2e, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x22, 0x99, 0x17, 0x0a, 0x13, 0x56, 0x65, 0x72, 0x69, 0x66, 0x69, 0x63, 0x61,
+	0x74, 0x69, 0x6f, 0x6e, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x12, 0x69, 0x0a, 0x19, 0x70,
+	0x72, 0x6f, 0x76, 0x65, 0x6e, 0x61, 0x6e, 0x63, 0x65, 0x5f, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x5f,
+	0x61, 0x74, 0x5f, 0x6c, 0x65, 0x61, 0x73, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x29,
+	0x2e, 0x6f, 0x61, 0x6b, 0x2e, 0x72, 0x65, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x2e, 0x56, 0x65, 0x72,
+	0x69, 0x66, 0x79, 0x50, 0x72, 0x6f, 0x76, 0x65, 0x6e, 0x61, 0x6e, 0x63, 0x65, 0x43, 0x6f, 0x75,
+	0x6e, 0x74, 0x41, 0x74, 0x4c, 0x65, 0x61, 0x73, 0x74, 0x48, 0x00, 0x52, 0x16, 0x70, 0x72, 0x6f,
+	0x76, 0x65, 0x6e, 0x61, 0x6e, 0x63, 0x65, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x41, 0x74, 0x4c, 0x65,
+	0x61, 0x73, 0x74, 0x88, 0x01, 0x01, 0x12, 0x66, 0x0a, 0x18, 0x70, 0x72, 0x6f, 0x76, 0x65, 0x6e,
+	0x61, 0x6e, 0x63, 0x65, 0x5f, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x5f, 0x61, 0x74, 0x5f, 0x6d, 0x6f,
+	0x73, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x28, 0x2e, 0x6f, 0x61, 0x6b, 0x2e, 0x72,
+	0x65, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x2e, 0x56, 0x65, 0x72, 0x69, 0x66, 0x79, 0x50, 0x72, 0x6f,
+	0x76, 0x65, 0x6e, 0x61, 0x6e, 0x63, 0x65, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x41, 0x74, 0x4d, 0x6f,
+	0x73, 0x74, 0x48, 0x01, 0x52, 0x15, 0x70, 0x72, 0x6f, 0x76, 0x65, 0x6e, 0x61, 0x6e, 0x63, 0x65,
+	0x43, 0x6f, 0x75, 0x6e, 0x74, 0x41, 0x74, 0x4d, 0x6f, 0x73, 0x74, 0x88, 0x01, 0x01, 0x12, 0x5a,
+	0x0a, 0x14, 0x61, 0x6c, 0x6c, 0x5f, 0x73, 0x61, 0x6d, 0x65, 0x5f, 0x62, 0x69, 0x6e, 0x61, 0x72,
+	0x79, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x24, 0x2e, 0x6f,
+	0x61, 0x6b, 0x2e, 0x72, 0x65, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x2e, 0x56, 0x65, 0x72, 0x69, 0x66,
+	0x79, 0x41, 0x6c, 0x6c, 0x53, 0x61, 0x6d, 0x65, 0x42, 0x69, 0x6e, 0x61, 0x72, 0x79, 0x4e, 0x61,
+	0x6d, 0x65, 0x48, 0x02, 0x52, 0x11, 0x61, 0x6c, 0x6c, 0x53, 0x61, 0x6d, 0x65, 0x42, 0x69, 0x6e,
+	0x61, 0x72, 0x79, 0x4e, 0x61, 0x6d, 0x65, 0x88, 0x01, 0x01, 0x12, 0x60, 0x0a, 0x16, 0x61, 0x6c,
+	0x6c, 0x5f, 0x73, 0x61, 0x6d, 0x65, 0x5f, 0x62, 0x69, 0x6e, 0x61, 0x72, 0x79, 0x5f, 0x64, 0x69,
+	0x67, 0x65, 0x73, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x26, 0x2e, 0x6f, 0x61, 0x6b,
+	0x2e, 0x72, 0x65, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x2e, 0x56, 0x65, 0x72, 0x69, 0x66, 0x79, 0x41,
+	0x6c, 0x6c, 0x53, 0x61, 0x6d, 0x65, 0x42, 0x69, 0x6e, 0x61, 0x72, 0x79, 0x44, 0x69, 0x67, 0x65,
+	0x73, 0x74, 0x48, 0x03, 0x52, 0x13, 0x61, 0x6c, 0x6c, 0x53, 0x61, 0x6d, 0x65, 0x42, 0x69, 0x6e,
+	0x61, 0x72, 0x79, 0x44, 0x69, 0x67, 0x65, 0x73, 0x74, 0x88, 0x01, 0x01, 0x12, 0x60, 0x0a, 0x16,
+	0x61, 0x6c, 0x6c, 0x5f, 0x77, 0x69, 0x74, 0x68, 0x5f, 0x62, 0x75, 0x69, 0x6c, 0x64, 0x5f, 0x63,
+	0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x26, 0x2e, 0x6f,
+	0x61, 0x6b, 0x2e, 0x72, 0x65, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x2e, 0x56, 0x65, 0x72, 0x69, 0x66,
+	0x79, 0x41, 0x6c, 0x6c, 0x57, 0x69, 0x74, 0x68, 0x42, 0x75, 0x69, 0x6c, 0x64, 0x43, 0x6f, 0x6d,
+	0x6d, 0x61, 0x6e, 0x64, 0x48, 0x04, 0x52, 0x13, 0x61, 0x6c, 0x6c, 0x57, 0x69, 0x74, 0x68, 0x42,
+	0x75, 0x69, 0x6c, 0x64, 0x43, 0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64, 0x88, 0x01, 0x01, 0x12, 0x5a,
+	0x0a, 0x14, 0x61, 0x6c, 0x6c, 0x5f, 0x77, 0x69, 0x74, 0x68, 0x5f, 0x62, 0x69, 0x6e, 0x61, 0x72,
+	0x79, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x24, 0x2e, 0x6f,
+	0x61, 0x6b, 0x2e, 0x72, 0x65, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x2e, 0x56, 0x65, 0x72, 0x69, 0x66,
+	0x79, 0x41, 0x6c, 0x6c, 0x57, 0x69, 0x74, 0x68, 0x42, 0x69, 0x6e, 0x61, 0x72, 0x79, 0x4e, 0x61,
+	0x6d, 0x65, 0x48, 0x05, 0x52, 0x11, 0x61, 0x6c, 0x6c, 0x57, 0x69, 0x74, 0x68, 0x42, 0x69, 0x6e,
+	0x61, 0x72, 0x79, 0x4e, 0x61, 0x6d, 0x65, 0x88, 0x01, 0x01, 0x12, 0x63, 0x0a, 0x17, 0x61, 0x6c,
+	0x6c, 0x5f, 0x77, 0x69, 0x74, 0x68, 0x5f, 0x62, 0x69, 0x6e, 0x61, 0x72, 0x79, 0x5f, 0x64, 0x69,
+	0x67, 0x65, 0x73, 0x74, 0x73, 0x18, 0x07, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x27, 0x2e, 0x6f, 0x61,
+	0x6b, 0x2e, 0x72, 0x65, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x2e, 0x56, 0x65, 0x72, 0x69, 0x66, 0x79,
+	0x41, 0x6c, 0x6c, 0x57, 0x69, 0x74, 0x68, 0x42, 0x69, 0x6e, 0x61, 0x72, 0x79, 0x44, 0x69, 0x67,
+	0x65, 0x73, 0x74, 0x73, 0x48, 0x06, 0x52, 0x14, 0x61, 0x6c, 0x6c, 0x57, 0x69, 0x74, 0x68, 0x42,
+	0x69, 0x6e, 0x61, 0x72, 0x79, 0x44, 0x69, 0x67, 0x65, 0x73, 0x74, 0x73, 0x88, 0x01, 0x01, 0x12,
+	0x60, 0x0a, 0x16, 0x61, 0x6c, 0x6c, 0x5f, 0x77, 0x69, 0x74, 0x68, 0x5f, 0x62, 0x75, 0x69, 0x6c,
+	0x64, 0x65, 0x72, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x73, 0x18, 0x08, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x26, 0x2e, 0x6f, 0x61, 0x6b, 0x2e, 0x72, 0x65, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x2e, 0x56, 0x65,
+	0x72, 0x69, 0x66, 0x79, 0x41, 0x6c, 0x6c, 0x57, 0x69, 0x74, 0x68, 0x42, 0x75, 0x69, 0x6c, 0x64,
+	0x65, 0x72, 0x4e, 0x61, 0x6d, 0x65, 0x73, 0x48, 0x07, 0x52, 0x13, 0x61, 0x6c, 0x6c, 0x57, 0x69,
+	0x74, 0x68, 0x42, 0x75, 0x69, 0x6c, 0x64, 0x65, 0x72, 0x4e, 0x61, 0x6d, 0x65, 0x73, 0x88, 0x01,
+	0x01, 0x12, 0x66, 0x0a, 0x18, 0x61, 0x6c, 0x6c, 0x5f, 0x77, 0x69, 0x74, 0x68, 0x5f, 0x62, 0x75,
+	0x69, 0x6c, 0x64, 0x65, 0x72, 0x5f, 0x64, 0x69, 0x67, 0x65, 0x73, 0x74, 0x73, 0x18, 0x09, 0x20,
+	0x01, 0x28, 0x0b, 0x32, 0x28, 0x2e, 0x6f, 0x61, 0x6b, 0x2e, 0x72, 0x65, 0x6c, 0x65, 0x61, 0x73,
+	0x65, 0x2e, 0x56, 0x65, 0x72, 0x69, 0x66, 0x79, 0x41, 0x6c, 0x6c, 0x57, 0x69, 0x74, 0x68, 0x42,
+	0x75, 0x69, 0x6c, 0x64, 0x65, 0x72, 0x44, 0x69, 0x67, 0x65, 0x73, 0x74, 0x73, 0x48, 0x08, 0x52,
+	0x15, 0x61, 0x6c, 0x6c, 0x57, 0x69, 0x74, 0x68, 0x42, 0x75, 0x69, 0x6c, 0x64, 0x65, 0x72, 0x44,
+	0x69, 0x67, 0x65, 0x73, 0x74, 0x73, 0x88, 0x01, 0x01, 0x12, 0x59, 0x0a, 0x13, 0x61, 0x6c, 0x6c,
+	0x5f, 0x77, 0x69, 0x74, 0x68, 0x5f, 0x72, 0x65, 0x70, 0x6f, 0x73, 0x69, 0x74, 0x6f, 0x72, 0x79,
+	0x18, 0x0a, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x24, 0x2e, 0x6f, 0x61, 0x6b, 0x2e, 0x72, 0x65, 0x6c,
+	0x65, 0x61, 0x73, 0x65, 0x2e, 0x56, 0x65, 0x72, 0x69, 0x66, 0x79, 0x41, 0x6c, 0x6c, 0x57, 0x69,
+	0x74, 0x68, 0x52, 0x65, 0x70, 0x6f, 0x73, 0x69, 0x74, 0x6f, 0x72, 0x79, 0x48, 0x09, 0x52, 0x11,
+	0x61, 0x6c, 0x6c, 0x57, 0x69, 0x74, 0x68, 0x52, 0x65, 0x70, 0x6f, 0x73, 0x69, 0x74, 0x6f, 0x72,
+	0x79, 0x88, 0x01, 0x01, 0x12, 0x75, 0x0a, 0x1d, 0x61, 0x6c, 0x6c, 0x5f, 0x77, 0x69, 0x74, 0x68,
+	0x5f, 0x63, 0x65, 0x72, 0x74, 0x69, 0x66, 0x69, 0x63, 0x61, 0x74, 0x65, 0x5f, 0x69, 0x64, 0x65,
+	0x6e, 0x74, 0x69, 0x74, 0x79, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x2d, 0x2e, 0x6f, 0x61,
+	0x6b, 0x2e, 0x72, 0x65, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x2e, 0x56, 0x65, 0x72, 0x69, 0x66, 0x79,
+	0x41, 0x6c, 0x6c, 0x57, 0x69, 0x74, 0x68, 0x43, 0x65, 0x72, 0x74, 0x69, 0x66, 0x69, 0x63, 0x61,
+	0x74, 0x65, 0x49, 0x64, 0x65, 0x6e, 0x74, 0x69, 0x74, 0x79, 0x48, 0x0a, 0x52, 0x1a, 0x61, 0x6c,
+	0x6c, 0x57, 0x69, 0x74, 0x68, 0x43, 0x65, 0x72, 0x74, 0x69, 0x66, 0x69, 0x63, 0x61, 0x74, 0x65,
+	0x49, 0x64, 0x65, 0x6e, 0x74, 0x69, 0x74, 0x79, 0x88, 0x01, 0x01, 0x12, 0x85, 0x01, 0x0a, 0x23,
+	0x61, 0x6c, 0x6c, 0x5f, 0x77, 0x69, 0x74, 0x68, 0x5f, 0x6d, 0x69, 0x6e, 0x69, 0x6d, 0x75, 0x6d,
+	0x5f, 0x74, 0x6f, 0x6f, 0x6c, 0x63, 0x68, 0x61, 0x69, 0x6e, 0x5f, 0x76, 0x65, 0x72, 0x73, 0x69,
+	0x6f, 0x6e, 0x73, 0x18, 0x0c, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x32, 0x2e, 0x6f, 0x61, 0x6b, 0x2e,
+	0x72, 0x65, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x2e, 0x56, 0x65, 0x72, 0x69, 0x66, 0x79, 0x41, 0x6c,
+	0x6c, 0x57, 0x69, 0x74, 0x68, 0x4d, 0x69, 0x6e, 0x69, 0x6d, 0x75, 0x6d, 0x54, 0x6f, 0x6f, 0x6c,
+	0x63, 0x68, 0x61, 0x69, 0x6e, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x73, 0x48, 0x0b, 0x52,
+	0x1f, 0x61, 0x6c, 0x6c, 0x57, 0x69, 0x74, 0x68, 0x4d, 0x69, 0x6e, 0x69, 0x6d, 0x75, 0x6d, 0x54,
+	0x6f, 0x6f, 0x6c, 0x63, 0x68, 0x61, 0x69, 0x6e, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x73,
+	0x88, 0x01, 0x01, 0x12, 0x63, 0x0a, 0x17, 0x61, 0x6c, 0x6c, 0x5f, 0x77, 0x69, 0x74, 0x68, 0x5f,
+	0x63, 0x6f, 0x6d, 0x6d, 0x69, 0x74, 0x5f, 0x64, 0x69, 0x67, 0x65, 0x73, 0x74, 0x73, 0x18, 0x0d,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x27, 0x2e, 0x6f, 0x61, 0x6b, 0x2e, 0x72, 0x65, 0x6c, 0x65, 0x61,
+	0x73, 0x65, 0x2e, 0x56, 0x65, 0x72, 0x69, 0x66, 0x79, 0x41, 0x6c, 0x6c, 0x57, 0x69, 0x74, 0x68,
+	0x43, 0x6f, 0x6d, 0x6d, 0x69, 0x74, 0x44, 0x69, 0x67, 0x65, 0x73, 0x74, 0x73, 0x48, 0x0c, 0x52,
+	0x14, 0x61, 0x6c, 0x6c, 0x57, 0x69, 0x74, 0x68, 0x43, 0x6f, 0x6d, 0x6d, 0x69, 0x74, 0x44, 0x69,
+	0x67, 0x65, 0x73, 0x74, 0x73, 0x88, 0x01, 0x01, 0x12, 0x57, 0x0a, 0x13, 0x61, 0x6c, 0x6c, 0x5f,
+	0x77, 0x69, 0x74, 0x68, 0x5f, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x5f, 0x72, 0x65, 0x66, 0x18,
+	0x0e, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x23, 0x2e, 0x6f, 0x61, 0x6b, 0x2e, 0x72, 0x65, 0x6c, 0x65,
+	0x61, 0x73, 0x65, 0x2e, 0x56, 0x65, 0x72, 0x69, 0x66, 0x79, 0x41, 0x6c, 0x6c, 0x57, 0x69, 0x74,
+	0x68, 0x53, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x52, 0x65, 0x66, 0x48, 0x0d, 0x52, 0x10, 0x61, 0x6c,
+	0x6c, 0x57, 0x69, 0x74, 0x68, 0x53, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x52, 0x65, 0x66, 0x88, 0x01,
+	0x01, 0x12, 0x57, 0x0a, 0x13, 0x61, 0x6c, 0x6c, 0x5f, 0x77, 0x69, 0x74, 0x68, 0x5f, 0x62, 0x75,
+	0x69, 0x6c, 0x64, 0x5f, 0x74, 0x69, 0x6d, 0x65, 0x18, 0x0f, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x23,
+	0x2e, 0x6f, 0x61, 0x6b, 0x2e, 0x72, 0x65, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x2e, 0x56, 0x65, 0x72,
+	0x69, 0x66, 0x79, 0x41, 0x6c, 0x6c, 0x57, 0x69, 0x74, 0x68, 0x42, 0x75, 0x69, 0x6c, 0x64, 0x54,
+	0x69, 0x6d, 0x65, 0x48, 0x0e, 0x52, 0x10, 0x61, 0x6c, 0x6c, 0x57, 0x69, 0x74, 0x68, 0x42, 0x75,
+	0x69, 0x6c, 0x64, 0x54, 0x69, 0x6d, 0x65, 0x88, 0x01, 0x01, 0x12, 0x59, 0x0a, 0x13, 0x61, 0x6c,
+	0x6c, 0x5f, 0x77, 0x69, 0x74, 0x68, 0x5f, 0x62, 0x79, 0x70, 0x72, 0x6f, 0x64, 0x75, 0x63, 0x74,
+	0x73, 0x18, 0x10, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x24, 0x2e, 0x6f, 0x61, 0x6b, 0x2e, 0x72, 0x65,
+	0x6c, 0x65, 0x61, 0x73, 0x65, 0x2e, 0x56, 0x65, 0x72, 0x69, 0x66, 0x79, 0x41, 0x6c, 0x6c, 0x57,
+	0x69, 0x74, 0x68, 0x42, 0x79, 0x70, 0x72, 0x6f, 0x64, 0x75, 0x63, 0x74, 0x73, 0x48, 0x0f, 0x52,
+	0x11, 0x61, 0x6c, 0x6c, 0x57, 0x69, 0x74, 0x68, 0x42, 0x79, 0x70, 0x72, 0x6f, 0x64, 0x75, 0x63,
+	0x74, 0x73, 0x88, 0x01, 0x01, 0x12, 0x63, 0x0a, 0x17, 0x61, 0x6c, 0x6c, 0x5f, 0x77, 0x69, 0x74,
+	0x68, 0x5f, 0x62, 0x75, 0x69, 0x6c, 0x64, 0x5f, 0x6d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61,
+	0x18, 0x11, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x27, 0x2e, 0x6f, 0x61, 0x6b, 0x2e, 0x72, 0x65, 0x6c,
+	0x65, 0x61, 0x73, 0x65, 0x2e, 0x56, 0x65, 0x72, 0x69, 0x66, 0x79, 0x41, 0x6c, 0x6c, 0x57, 0x69,
+	0x74, 0x68, 0x42, 0x75, 0x69, 0x6c, 0x64, 0x4d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61, 0x48,
+	0x10, 0x52, 0x14, 0x61, 0x6c, 0x6c, 0x57, 0x69, 0x74, 0x68, 0x42, 0x75, 0x69, 0x6c, 0x64, 0x4d,
+	0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61, 0x88, 0x01, 0x01, 0x12, 0x5a, 0x0a, 0x14, 0x61, 0x6c,
+	0x6c, 0x5f, 0x77, 0x69, 0x74, 0x68, 0x5f, 0x62, 0x75, 0x69, 0x6c, 0x64, 0x5f, 0x74, 0x79, 0x70,
+	0x65, 0x73, 0x18, 0x12, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x24, 0x2e, 0x6f, 0x61, 0x6b, 0x2e, 0x72,
+	0x65, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x2e, 0x56, 0x65, 0x72, 0x69, 0x66, 0x79, 0x41, 0x6c, 0x6c,
+	0x57, 0x69, 0x74, 0x68, 0x42, 0x75, 0x69, 0x6c, 0x64, 0x54, 0x79, 0x70, 0x65, 0x73, 0x48, 0x11,
+	0x52, 0x11, 0x61, 0x6c, 0x6c, 0x57, 0x69, 0x74, 0x68, 0x42, 0x75, 0x69, 0x6c, 0x64, 0x54, 0x79,
+	0x70, 0x65, 0x73, 0x88, 0x01, 0x01, 0x12, 0x63, 0x0a, 0x17, 0x6e, 0x6f, 0x6e, 0x65, 0x5f, 0x77,
+	0x69, 0x74, 0x68, 0x5f, 0x62, 0x75, 0x69, 0x6c, 0x64, 0x65, 0x72, 0x5f, 0x6e, 0x61, 0x6d, 0x65,
+	0x73, 0x18, 0x13, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x27, 0x2e, 0x6f, 0x61, 0x6b, 0x2e, 0x72, 0x65,
+	0x6c, 0x65, 0x61, 0x73, 0x65, 0x2e, 0x56, 0x65, 0x72, 0x69, 0x66, 0x79, 0x4e, 0x6f, 0x6e, 0x65,
+	0x57, 0x69, 0x74, 0x68, 0x42, 0x75, 0x69, 0x6c, 0x64, 0x65, 0x72, 0x4e, 0x61, 0x6d, 0x65, 0x73,
+	0x48, 0x12, 0x52, 0x14, 0x6e, 0x6f, 0x6e, 0x65, 0x57, 0x69, 0x74, 0x68, 0x42, 0x75, 0x69, 0x6c,
+	0x64, 0x65, 0x72, 0x4e, 0x61, 0x6d, 0x65, 0x73, 0x88, 0x01, 0x01, 0x12, 0x62, 0x0a, 0x16, 0x6e,
+	0x6f, 0x6e, 0x65, 0x5f, 0x77, 0x69, 0x74, 0x68, 0x5f, 0x72, 0x65, 0x70, 0x6f, 0x73, 0x69, 0x74,
+	0x6f, 0x72, 0x69, 0x65, 0x73, 0x18, 0x14, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x27, 0x2e, 0x6f, 0x61,
+	0x6b, 0x2e, 0x72, 0x65, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x2e, 0x56, 0x65, 0x72, 0x69, 0x66, 0x79,
+	0x4e, 0x6f, 0x6e, 0x65, 0x57, 0x69, 0x74, 0x68, 0x52, 0x65, 0x70, 0x6f, 0x73, 0x69, 0x74, 0x6f,
+	0x72, 0x69, 0x65, 0x73, 0x48, 0x13, 0x52, 0x14, 0x6e, 0x6f, 0x6e, 0x65, 0x57, 0x69, 0x74, 0x68,
+	0x52, 0x65, 0x70, 0x6f, 0x73, 0x69, 0x74, 0x6f, 0x72, 0x69, 0x65, 0x73, 0x88, 0x01, 0x01, 0x12,
+	0x66, 0x0a, 0x18, 0x6e, 0x6f, 0x6e, 0x65, 0x5f, 0x77, 0x69, 0x74, 0x68, 0x5f, 0x62, 0x69, 0x6e,
+	0x61, 0x72, 0x79, 0x5f, 0x64, 0x69, 0x67, 0x65, 0x73, 0x74, 0x73, 0x18, 0x15, 0x20, 0x01, 0x28,
+	0x0b, 0x32, 0x28, 0x2e, 0x6f, 0x61, 0x6b, 0x2e, 0x72, 0x65, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x2e,
+	0x56, 0x65, 0x72, 0x69, 0x66, 0x79, 0x4e, 0x6f, 0x6e, 0x65, 0x57, 0x69, 0x74, 0x68, 0x42, 0x69,
+	0x6e, 0x61, 0x72, 0x79, 0x44, 0x69, 0x67, 0x65, 0x73, 0x74, 0x73, 0x48, 0x14, 0x52, 0x15, 0x6e,
+	0x6f, 0x6e, 0x65, 0x57, 0x69, 0x74, 0x68, 0x42, 0x69, 0x6e, 0x61, 0x72, 0x79, 0x44, 0x69, 0x67,
+	0x65, 0x73, 0x74, 0x73, 0x88, 0x01, 0x01, 0x12, 0x69, 0x0a, 0x19, 0x6e, 0x6f, 0x6e, 0x65, 0x5f,
+	0x77, 0x69, 0x74, 0x68, 0x5f, 0x62, 0x75, 0x69, 0x6c, 0x64, 0x65, 0x72, 0x5f, 0x64, 0x69, 0x67,
+	0x65, 0x73, 0x74, 0x73, 0x18, 0x16, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x29, 0x2e, 0x6f, 0x61, 0x6b,
+	0x2e, 0x72, 0x65, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x2e, 0x56, 0x65, 0x72, 0x69, 0x66, 0x79, 0x4e,
+	0x6f, 0x6e, 0x65, 0x57, 0x69, 0x74, 0x68, 0x42, 0x75, 0x69, 0x6c, 0x64, 0x65, 0x72, 0x44, 0x69,
+	0x67, 0x65, 0x73, 0x74, 0x73, 0x48, 0x15, 0x52, 0x16, 0x6e, 0x6f, 0x6e, 0x65, 0x57, 0x69, 0x74,
+	0x68, 0x42, 0x75, 0x69, 0x6c, 0x64, 0x65, 0x72, 0x44, 0x69, 0x67, 0x65, 0x73, 0x74, 0x73, 0x88,
+	0x01, 0x01, 0x12, 0x5d, 0x0a, 0x15, 0x61, 0x6c, 0x6c, 0x5f, 0x77, 0x69, 0x74, 0x68, 0x5f, 0x74,
+	0x72, 0x65, 0x65, 0x5f, 0x64, 0x69, 0x67, 0x65, 0x73, 0x74, 0x73, 0x18, 0x17, 0x20, 0x01, 0x28,
+	0x0b, 0x32, 0x25, 0x2e, 0x6f, 0x61, 0x6b, 0x2e, 0x72, 0x65, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x2e,
+	0x56, 0x65, 0x72, 0x69, 0x66, 0x79, 0x41, 0x6c, 0x6c, 0x57, 0x69, 0x74, 0x68, 0x54, 0x72, 0x65,
+	0x65, 0x44, 0x69, 0x67, 0x65, 0x73, 0x74, 0x73, 0x48, 0x16, 0x52, 0x12, 0x61, 0x6c, 0x6c, 0x57,
+	0x69, 0x74, 0x68, 0x54, 0x72, 0x65, 0x65, 0x44, 0x69, 0x67, 0x65, 0x73, 0x74, 0x73, 0x88, 0x01,
+	0x01, 0x42, 0x1c, 0x0a, 0x1a, 0x5f, 0x70, 0x72, 0x6f, 0x76, 0x65, 0x6e, 0x61, 0x6e, 0x63, 0x65,
+	0x5f, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x5f, 0x61, 0x74, 0x5f, 0x6c, 0x65, 0x61, 0x73, 0x74, 0x42,
+	0x1b, 0x0a, 0x19, 0x5f, 0x70, 0x72, 0x6f, 0x76, 0x65, 0x6e, 0x61, 0x6e, 0x63, 0x65, 0x5f, 0x63,
+	0x6f, 0x75, 0x6e, 0x74, 0x5f, 0x61, 0x74, 0x5f, 0x6d, 0x6f, 0x73, 0x74, 0x42, 0x17, 0x0a, 0x15,
+	0x5f, 0x61, 0x6c, 0x6c, 0x5f, 0x73, 0x61, 0x6d, 0x65, 0x5f, 0x62, 0x69, 0x6e, 0x61, 0x72, 0x79,
+	0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x42, 0x19, 0x0a, 0x17, 0x5f, 0x61, 0x6c, 0x6c, 0x5f, 0x73, 0x61,
+	0x6d, 0x65, 0x5f, 0x62, 0x69, 0x6e, 0x61, 0x72, 0x79, 0x5f, 0x64, 0x69, 0x67, 0x65, 0x73, 0x74,
+	0x42, 0x19, 0x0a, 0x17, 0x5f, 0x61, 0x6c, 0x6c, 0x5f, 0x77, 0x69, 0x74, 0x68, 0x5f, 0x62, 0x75,
+	0x69, 0x6c, 0x64, 0x5f, 0x63, 0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64, 0x42, 0x17, 0x0a, 0x15, 0x5f,
+	0x61, 0x6c, 0x6c, 0x5f, 0x77, 0x69, 0x74, 0x68, 0x5f, 0x62, 0x69, 0x6e, 0x61, 0x72, 0x79, 0x5f,
+	0x6e, 0x61, 0x6d, 0x65, 0x42, 0x1a, 0x0a, 0x18, 0x5f, 0x61, 0x6c, 0x6c, 0x5f, 0x77, 0x69, 0x74,
+	0x68, 0x5f, 0x62, 0x69, 0x6e, 0x61, 0x72, 0x79, 0x5f, 0x64, 0x69, 0x67, 0x65, 0x73, 0x74, 0x73,
+	0x42, 0x19, 0x0a, 0x17, 0x5f, 0x61, 0x6c, 0x6c, 0x5f, 0x77, 0x69, 0x74, 0x68, 0x5f, 0x62, 0x75,
+	0x69, 0x6c, 0x64, 0x65, 0x72, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x73, 0x42, 0x1b, 0x0a, 0x19, 0x5f,
+	0x61, 0x6c, 0x6c, 0x5f, 0x77, 0x69, 0x74, 0x68, 0x5f, 0x62, 0x75, 0x69, 0x6c, 0x64, 0x65, 0x72,
+	0x5f, 0x64, 0x69, 0x67, 0x65, 0x73, 0x74, 0x73, 0x42, 0x16, 0x0a, 0x14, 0x5f, 0x61, 0x6c, 0x6c,
+	0x5f, 0x77, 0x69, 0x74, 0x68, 0x5f, 0x72, 0x65, 0x70, 0x6f, 0x73, 0x69, 0x74, 0x6f, 0x72, 0x79,
+	0x42, 0x20, 0x0a, 0x1e, 0x5f, 0x61, 0x6c, 0x6c, 0x5f, 0x77, 0x69, 0x74, 0x68, 0x5f, 0x63, 0x65,
+	0x72, 0x74, 0x69, 0x66, 0x69, 0x63, 0x61, 0x74, 0x65, 0x5f, 0x69, 0x64, 0x65, 0x6e, 0x74, 0x69,
+	0x74, 0x79, 0x42, 0x26, 0x0a, 0x24, 0x5f, 0x61, 0x6c, 0x6c, 0x5f, 0x77, 0x69, 0x74, 0x68, 0x5f,
+	0x6d, 0x69, 0x6e, 0x69, 0x6d, 0x75, 0x6d, 0x5f, 0x74, 0x6f, 0x6f, 0x6c, 0x63, 0x68, 0x61, 0x69,
+	0x6e, 0x5f, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x73, 0x42, 0x1a, 0x0a, 0x18, 0x5f, 0x61,
+	0x6c, 0x6c, 0x5f, 0x77, 0x69, 0x74, 0x68, 0x5f, 0x63, 0x6f, 0x6d, 0x6d, 0x69, 0x74, 0x5f, 0x64,
+	0x69, 0x67, 0x65, 0x73, 0x74, 0x73, 0x42, 0x16, 0x0a, 0x14, 0x5f, 0x61, 0x6c, 0x6c, 0x5f, 0x77,
+	0x69, 0x74, 0x68, 0x5f, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x5f, 0x72, 0x65, 0x66, 0x42, 0x16,
+	0x0a, 0x14, 0x5f, 0x61, 0x6c, 0x6c, 0x5f, 0x77, 0x69, 0x74, 0x68, 0x5f, 0x62, 0x75, 0x69, 0x6c,
+	0x64, 0x5f, 0x74, 0x69, 0x6d, 0x65, 0x42, 0x16, 0x0a, 0x14, 0x5f, 0x61, 0x6c, 0x6c, 0x5f, 0x77,
+	0x69, 0x74, 0x68, 0x5f, 0x62, 0x79, 0x70, 0x72, 0x6f, 0x64, 0x75, 0x63, 0x74, 0x73, 0x42, 0x1a,
+	0x0a, 0x18, 0x5f, 0x61, 0x6c, 0x6c, 0x5f, 0x77, 0x69, 0x74, 0x68, 0x5f, 0x62, 0x75, 0x69, 0x6c,
+	0x64, 0x5f, 0x6d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61, 0x42, 0x17, 0x0a, 0x15, 0x5f, 0x61,
+	0x6c, 0x6c, 0x5f, 0x77, 0x69, 0x74, 0x68, 0x5f, 0x62, 0x75, 0x69, 0x6c, 0x64, 0x5f, 0x74, 0x79,
+	0x70, 0x65, 0x73, 0x42, 0x1a, 0x0a, 0x18, 0x5f, 0x6e, 0x6f, 0x6e, 0x65, 0x5f, 0x77, 0x69, 0x74,
+	0x68, 0x5f, 0x62, 0x75, 0x69, 0x6c, 0x64, 0x65, 0x72, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x73, 0x42,
+	0x19, 0x0a, 0x17, 0x5f, 0x6e, 0x6f, 0x6e, 0x65, 0x5f, 0x77, 0x69, 0x74, 0x68, 0x5f, 0x72, 0x65,
+	0x70, 0x6f, 0x73, 0x69, 0x74, 0x6f, 0x72, 0x69, 0x65, 0x73, 0x42, 0x1b, 0x0a, 0x19, 0x5f, 0x6e,
+	0x6f, 0x6e, 0x65, 0x5f, 0x77, 0x69, 0x74, 0x68, 0x5f, 0x62, 0x69, 0x6e, 0x61, 0x72, 0x79, 0x5f,
+	0x64, 0x69, 0x67, 0x65, 0x73, 0x74, 0x73, 0x42, 0x1c, 0x0a, 0x1a, 0x5f, 0x6e, 0x6f, 0x6e, 0x65,
+	0x5f, 0x77, 0x69, 0x74, 0x68, 0x5f, 0x62, 0x75, 0x69, 0x6c, 0x64, 0x65, 0x72, 0x5f, 0x64, 0x69,
+	0x67, 0x65, 0x73, 0x74, 0x73, 0x42, 0x18, 0x0a, 0x16, 0x5f, 0x61, 0x6c, 0x6c, 0x5f, 0x77, 0x69,
+	0x74, 0x68, 0x5f, 0x74, 0x72, 0x65, 0x65, 0x5f, 0x64, 0x69, 0x67, 0x65, 0x73, 0x74, 0x73, 0x22,
+	0x34, 0x0a, 0x1c, 0x56, 0x65, 0x72, 0x69, 0x66, 0x79, 0x50, 0x72, 0x6f, 0x76, 0x65, 0x6e, 0x61,
+	0x6e, 0x63, 0x65, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x41, 0x74, 0x4c, 0x65, 0x61, 0x73, 0x74, 0x12,
+	0x14, 0x0a, 0x05, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x05,
+	0x63, 0x6f, 0x75, 0x6e, 0x74, 0x22, 0x33, 0x0a, 0x1b, 0x56, 0x65, 0x72, 0x69, 0x66, 0x79, 0x50,
+	0x72, 0x6f, 0x76, 0x65, 0x6e, 0x61, 0x6e, 0x63, 0x65, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x41, 0x74,
+	0x4d, 0x6f, 0x73, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x05, 0x52, 0x05, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x22, 0x19, 0x0a, 0x17, 0x56, 0x65,
+	0x72, 0x69, 0x66, 0x79, 0x41, 0x6c, 0x6c, 0x53, 0x61, 0x6d, 0x65, 0x42, 0x69, 0x6e, 0x61, 0x72,
+	0x79, 0x4e, 0x61, 0x6d, 0x65, 0x22, 0x1b, 0x0a, 0x19, 0x56, 0x65, 0x72, 0x69, 0x66, 0x79, 0x41,
+	0x6c, 0x6c, 0x53, 0x61, 0x6d, 0x65, 0x42, 0x69, 0x6e, 0x61, 0x72, 0x79, 0x44, 0x69, 0x67, 0x65,
+	0x73, 0x74, 0x22, 0x1b, 0x0a, 0x19, 0x56, 0x65, 0x72, 0x69, 0x66, 0x79, 0x41, 0x6c, 0x6c, 0x57,
+	0x69, 0x74, 0x68, 0x42, 0x75, 0x69, 0x6c, 0x64, 0x43, 0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64, 0x22,
+	0x3a, 0x0a, 0x17, 0x56, 0x65, 0x72, 0x69, 0x66, 0x79, 0x41, 0x6c, 0x6c, 0x57, 0x69, 0x74, 0x68,
+	0x42, 0x69, 0x6e, 0x61, 0x72, 0x79, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x1f, 0x0a, 0x0b, 0x62, 0x69,
+	0x6e, 0x61, 0x72, 0x79, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x0a, 0x62, 0x69, 0x6e, 0x61, 0x72, 0x79, 0x4e, 0x61, 0x6d, 0x65, 0x22, 0x4b, 0x0a, 0x1a, 0x56,
+	0x65, 0x72, 0x69, 0x66, 0x79, 0x41, 0x6c, 0x6c, 0x57, 0x69, 0x74, 0x68, 0x42, 0x69, 0x6e, 0x61,
+	0x72, 0x79, 0x44, 0x69, 0x67, 0x65, 0x73, 0x74, 0x73, 0x12, 0x2d, 0x0a, 0x07, 0x64, 0x69, 0x67,
+	0x65, 0x73, 0x74, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x13, 0x2e, 0x6f, 0x61, 0x6b,
+	0x2e, 0x72, 0x65, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x2e, 0x44, 0x69, 0x67, 0x65, 0x73, 0x74, 0x52,
+	0x07, 0x64, 0x69, 0x67, 0x65, 0x73, 0x74, 0x73, 0x22, 0x40, 0x0a, 0x17, 0x56, 0x65, 0x72, 0x69,
+	0x66, 0x79, 0x41, 0x6c, 0x6c, 0x57, 0x69, 0x74, 0x68, 0x52, 0x65, 0x70, 0x6f, 0x73, 0x69, 0x74,
+	0x6f, 0x72, 0x79, 0x12, 0x25, 0x0a, 0x0e, 0x72, 0x65, 0x70, 0x6f, 0x73, 0x69, 0x74, 0x6f, 0x72,
+	0x79, 0x5f, 0x75, 0x72, 0x69, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0d, 0x72, 0x65, 0x70,
+	0x6f, 0x73, 0x69, 0x74, 0x6f, 0x72, 0x79, 0x55, 0x72, 0x69, 0x22, 0x40, 0x0a, 0x19, 0x56, 0x65,
+	0x72, 0x69, 0x66, 0x79, 0x41, 0x6c, 0x6c, 0x57, 0x69, 0x74, 0x68, 0x42, 0x75, 0x69, 0x6c, 0x64,
+	0x65, 0x72, 0x4e, 0x61, 0x6d, 0x65, 0x73, 0x12, 0x23, 0x0a, 0x0d, 0x62, 0x75, 0x69, 0x6c, 0x64,
+	0x65, 0x72, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x09, 0x52, 0x0c,
+	0x62, 0x75, 0x69, 0x6c, 0x64, 0x65, 0x72, 0x4e, 0x61, 0x6d, 0x65, 0x73, 0x22, 0x4c, 0x0a, 0x1b,
+	0x56, 0x65, 0x72, 0x69, 0x66, 0x79, 0x41, 0x6c, 0x6c, 0x57, 0x69, 0x74, 0x68, 0x42, 0x75, 0x69,
+	0x6c, 0x64, 0x65, 0x72, 0x44, 0x69, 0x67, 0x65, 0x73, 0x74, 0x73, 0x12, 0x2d, 0x0a, 0x07, 0x64,
+	0x69, 0x67, 0x65, 0x73, 0x74, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x13, 0x2e, 0x6f,
+	0x61, 0x6b, 0x2e, 0x72, 0x65, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x2e, 0x44, 0x69, 0x67, 0x65, 0x73,
+	0x74, 0x52, 0x07, 0x64, 0x69, 0x67, 0x65, 0x73, 0x74, 0x73, 0x22, 0x4b, 0x0a, 0x1a, 0x56, 0x65,
+	0x72, 0x69, 0x66, 0x79, 0x41, 0x6c, 0x6c, 0x57, 0x69, 0x74, 0x68, 0x43, 0x6f, 0x6d, 0x6d, 0x69,
+	0x74, 0x44, 0x69, 0x67, 0x65, 0x73, 0x74, 0x73, 0x12, 0x2d, 0x0a, 0x07, 0x64, 0x69, 0x67, 0x65,
+	0x73, 0x74, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x13, 0x2e, 0x6f, 0x61, 0x6b, 0x2e,
+	0x72, 0x65, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x2e, 0x44, 0x69, 0x67, 0x65, 0x73, 0x74, 0x52, 0x07,
+	0x64, 0x69, 0x67, 0x65, 0x73, 0x74, 0x73, 0x22, 0x49, 0x0a, 0x18, 0x56, 0x65, 0x72, 0x69, 0x66,
+	0x79, 0x41, 0x6c, 0x6c, 0x57, 0x69, 0x74, 0x68, 0x54, 0x72, 0x65, 0x65, 0x44, 0x69, 0x67, 0x65,
+	0x73, 0x74, 0x73, 0x12, 0x2d, 0x0a, 0x07, 0x64, 0x69, 0x67, 0x65, 0x73, 0x74, 0x73, 0x18, 0x01,
+	0x20, 0x03, 0x28, 0x0b, 0x32, 0x13, 0x2e, 0x6f, 0x61, 0x6b, 0x2e, 0x72, 0x65, 0x6c, 0x65, 0x61,
+	0x73, 0x65, 0x2e, 0x44, 0x69, 0x67, 0x65, 0x73, 0x74, 0x52, 0x07, 0x64, 0x69, 0x67, 0x65, 0x73,
+	0x74, 0x73, 0x22, 0x34, 0x0a, 0x16, 0x56, 0x65, 0x72, 0x69, 0x66, 0x79, 0x41, 0x6c, 0x6c, 0x57,
+	0x69, 0x74, 0x68, 0x53, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x52, 0x65, 0x66, 0x12, 0x1a, 0x0a, 0x08,
+	0x70, 0x61, 0x74, 0x74, 0x65, 0x72, 0x6e, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x09, 0x52, 0x08,
+	0x70, 0x61, 0x74, 0x74, 0x65, 0x72, 0x6e, 0x73, 0x22, 0x68, 0x0a, 0x16, 0x56, 0x65, 0x72, 0x69,
+	0x66, 0x79, 0x41, 0x6c, 0x6c, 0x57, 0x69, 0x74, 0x68, 0x42, 0x75, 0x69, 0x6c, 0x64, 0x54, 0x69,
+	0x6d, 0x65, 0x12, 0x25, 0x0a, 0x0e, 0x66, 0x69, 0x6e, 0x69, 0x73, 0x68, 0x65, 0x64, 0x5f, 0x61,
+	0x66, 0x74, 0x65, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0d, 0x66, 0x69, 0x6e, 0x69,
+	0x73, 0x68, 0x65, 0x64, 0x41, 0x66, 0x74, 0x65, 0x72, 0x12, 0x27, 0x0a, 0x0f, 0x66, 0x69, 0x6e,
+	0x69, 0x73, 0x68, 0x65, 0x64, 0x5f, 0x62, 0x65, 0x66, 0x6f, 0x72, 0x65, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x0e, 0x66, 0x69, 0x6e, 0x69, 0x73, 0x68, 0x65, 0x64, 0x42, 0x65, 0x66, 0x6f,
+	0x72, 0x65, 0x22, 0x59, 0x0a, 0x17, 0x56, 0x65, 0x72, 0x69, 0x66, 0x79, 0x41, 0x6c, 0x6c, 0x57,
+	0x69, 0x74, 0x68, 0x42, 0x79, 0x70, 0x72, 0x6f, 0x64, 0x75, 0x63, 0x74, 0x73, 0x12, 0x3e, 0x0a,
+	0x0a, 0x62, 0x79, 0x70, 0x72, 0x6f, 0x64, 0x75, 0x63, 0x74, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28,
+	0x0b, 0x32, 0x1e, 0x2e, 0x6f, 0x61, 0x6b, 0x2e, 0x72, 0x65, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x2e,
+	0x52, 0x65, 0x71, 0x75, 0x69, 0x72, 0x65, 0x64, 0x42, 0x79, 0x70, 0x72, 0x6f, 0x64, 0x75, 0x63,
+	0x74, 0x52, 0x0a, 0x62, 0x79, 0x70, 0x72, 0x6f, 0x64, 0x75, 0x63, 0x74, 0x73, 0x22, 0x56, 0x0a,
+	0x11, 0x52, 0x65, 0x71, 0x75, 0x69, 0x72, 0x65, 0x64, 0x42, 0x79, 0x70, 0x72, 0x6f, 0x64, 0x75,
+	0x63, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x2d, 0x0a, 0x07, 0x64, 0x69, 0x67, 0x65, 0x73, 0x74,
+	0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x13, 0x2e, 0x6f, 0x61, 0x6b, 0x2e, 0x72, 0x65,
+	0x6c, 0x65, 0x61, 0x73, 0x65, 0x2e, 0x44, 0x69, 0x67, 0x65, 0x73, 0x74, 0x52, 0x07, 0x64, 0x69,
+	0x67, 0x65, 0x73, 0x74, 0x73, 0x22, 0xd3, 0x01, 0x0a, 0x1a, 0x56, 0x65, 0x72, 0x69, 0x66, 0x79,
+	0x41, 0x6c, 0x6c, 0x57, 0x69, 0x74, 0x68, 0x42, 0x75, 0x69, 0x6c, 0x64, 0x4d, 0x65, 0x74, 0x61,
+	0x64, 0x61, 0x74, 0x61, 0x12, 0x2f, 0x0a, 0x13, 0x70, 0x61, 0x72, 0x61, 0x6d, 0x65, 0x74, 0x65,
+	0x72, 0x73, 0x5f, 0x63, 0x6f, 0x6d, 0x70, 0x6c, 0x65, 0x74, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x08, 0x52, 0x12, 0x70, 0x61, 0x72, 0x61, 0x6d, 0x65, 0x74, 0x65, 0x72, 0x73, 0x43, 0x6f, 0x6d,
+	0x70, 0x6c, 0x65, 0x74, 0x65, 0x12, 0x31, 0x0a, 0x14, 0x65, 0x6e, 0x76, 0x69, 0x72, 0x6f, 0x6e,
+	0x6d, 0x65, 0x6e, 0x74, 0x5f, 0x63, 0x6f, 0x6d, 0x70, 0x6c, 0x65, 0x74, 0x65, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x08, 0x52, 0x13, 0x65, 0x6e, 0x76, 0x69, 0x72, 0x6f, 0x6e, 0x6d, 0x65, 0x6e, 0x74,
+	0x43, 0x6f, 0x6d, 0x70, 0x6c, 0x65, 0x74, 0x65, 0x12, 0x2d, 0x0a, 0x12, 0x6d, 0x61, 0x74, 0x65,
+	0x72, 0x69, 0x61, 0x6c, 0x73, 0x5f, 0x63, 0x6f, 0x6d, 0x70, 0x6c, 0x65, 0x74, 0x65, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x08, 0x52, 0x11, 0x6d, 0x61, 0x74, 0x65, 0x72, 0x69, 0x61, 0x6c, 0x73, 0x43,
+	0x6f, 0x6d, 0x70, 0x6c, 0x65, 0x74, 0x65, 0x12, 0x22, 0x0a, 0x0c, 0x72, 0x65, 0x70, 0x72, 0x6f,
+	0x64, 0x75, 0x63, 0x69, 0x62, 0x6c, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0c, 0x72,
+	0x65, 0x70, 0x72, 0x6f, 0x64, 0x75, 0x63, 0x69, 0x62, 0x6c, 0x65, 0x22, 0x3a, 0x0a, 0x17, 0x56,
+	0x65, 0x72, 0x69, 0x66, 0x79, 0x41, 0x6c, 0x6c, 0x57, 0x69, 0x74, 0x68, 0x42, 0x75, 0x69, 0x6c,
+	0x64, 0x54, 0x79, 0x70, 0x65, 0x73, 0x12, 0x1f, 0x0a, 0x0b, 0x62, 0x75, 0x69, 0x6c, 0x64, 0x5f,
+	0x74, 0x79, 0x70, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x09, 0x52, 0x0a, 0x62, 0x75, 0x69,
+	0x6c, 0x64, 0x54, 0x79, 0x70, 0x65, 0x73, 0x22, 0x41, 0x0a, 0x1a, 0x56, 0x65, 0x72, 0x69, 0x66,
+	0x79, 0x4e, 0x6f, 0x6e, 0x65, 0x57, 0x69, 0x74, 0x68, 0x42, 0x75, 0x69, 0x6c, 0x64, 0x65, 0x72,
+	0x4e, 0x61, 0x6d, 0x65, 0x73, 0x12, 0x23, 0x0a, 0x0d, 0x62, 0x75, 0x69, 0x6c, 0x64, 0x65, 0x72,
+	0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x09, 0x52, 0x0c, 0x62, 0x75,
+	0x69, 0x6c, 0x64, 0x65, 0x72, 0x4e, 0x61, 0x6d, 0x65, 0x73, 0x22, 0x45, 0x0a, 0x1a, 0x56, 0x65,
+	0x72, 0x69, 0x66, 0x79, 0x4e, 0x6f, 0x6e, 0x65, 0x57, 0x69, 0x74, 0x68, 0x52, 0x65, 0x70, 0x6f,
+	0x73, 0x69, 0x74, 0x6f, 0x72, 0x69, 0x65, 0x73, 0x12, 0x27, 0x0a, 0x0f, 0x72, 0x65, 0x70, 0x6f,
+	0x73, 0x69, 0x74, 0x6f, 0x72, 0x79, 0x5f, 0x75, 0x72, 0x69, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28,
+	0x09, 0x52, 0x0e, 0x72, 0x65, 0x70, 0x6f, 0x73, 0x69, 0x74, 0x6f, 0x72, 0x79, 0x55, 0x72, 0x69,
+	0x73, 0x22, 0x4c, 0x0a, 0x1b, 0x56, 0x65, 0x72, 0x69, 0x66, 0x79, 0x4e, 0x6f, 0x6e, 0x65, 0x57,
+	0x69, 0x74, 0x68, 0x42, 0x69, 0x6e, 0x61, 0x72, 0x79, 0x44, 0x69, 0x67, 0x65, 0x73, 0x74, 0x73,
+	0x12, 0x2d, 0x0a, 0x07, 0x64, 0x69, 0x67, 0x65, 0x73, 0x74, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28,
+	0x0b, 0x32, 0x13, 0x2e, 0x6f, 0x61, 0x6b, 0x2e, 0x72, 0x65, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x2e,
+	0x44, 0x69, 0x67, 0x65, 0x73, 0x74, 0x52, 0x07, 0x64, 0x69, 0x67, 0x65, 0x73, 0x74, 0x73, 0x22,
+	0x4d, 0x0a, 0x1c, 0x56, 0x65, 0x72, 0x69, 0x66, 0x79, 0x4e, 0x6f, 0x6e, 0x65, 0x57, 0x69, 0x74,
+	0x68, 0x42, 0x75, 0x69, 0x6c, 0x64, 0x65, 0x72, 0x44, 0x69, 0x67, 0x65, 0x73, 0x74, 0x73, 0x12,
+	0x2d, 0x0a, 0x07, 0x64, 0x69, 0x67, 0x65, 0x73, 0x74, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b,
+	0x32, 0x13, 0x2e, 0x6f, 0x61, 0x6b, 0x2e, 0x72, 0x65, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x2e, 0x44,
+	0x69, 0x67, 0x65, 0x73, 0x74, 0x52, 0x07, 0x64, 0x69, 0x67, 0x65, 0x73, 0x74, 0x73, 0x22, 0x91,
+	0x02, 0x0a, 0x20, 0x56, 0x65, 0x72, 0x69, 0x66, 0x79, 0x41, 0x6c, 0x6c, 0x57, 0x69, 0x74, 0x68,
+	0x43, 0x65, 0x72, 0x74, 0x69, 0x66, 0x69, 0x63, 0x61, 0x74, 0x65, 0x49, 0x64, 0x65, 0x6e, 0x74,
+	0x69, 0x74, 0x79, 0x12, 0x38, 0x0a, 0x18, 0x73, 0x75, 0x62, 0x6a, 0x65, 0x63, 0x74, 0x5f, 0x61,
+	0x6c, 0x74, 0x65, 0x72, 0x6e, 0x61, 0x74, 0x69, 0x76, 0x65, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x16, 0x73, 0x75, 0x62, 0x6a, 0x65, 0x63, 0x74, 0x41, 0x6c,
+	0x74, 0x65, 0x72, 0x6e, 0x61, 0x74, 0x69, 0x76, 0x65, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x16, 0x0a,
+	0x06, 0x69, 0x73, 0x73, 0x75, 0x65, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x69,
+	0x73, 0x73, 0x75, 0x65, 0x72, 0x12, 0x28, 0x0a, 0x10, 0x62, 0x75, 0x69, 0x6c, 0x64, 0x5f, 0x63,
+	0x6f, 0x6e, 0x66, 0x69, 0x67, 0x5f, 0x75, 0x72, 0x69, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x0e, 0x62, 0x75, 0x69, 0x6c, 0x64, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x55, 0x72, 0x69, 0x12,
+	0x32, 0x0a, 0x15, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x5f, 0x72, 0x65, 0x70, 0x6f, 0x73, 0x69,
+	0x74, 0x6f, 0x72, 0x79, 0x5f, 0x72, 0x65, 0x66, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x13,
+	0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x52, 0x65, 0x70, 0x6f, 0x73, 0x69, 0x74, 0x6f, 0x72, 0x79,
+	0x52, 0x65, 0x66, 0x12, 0x3d, 0x0a, 0x1b, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x5f, 0x72, 0x65,
+	0x70, 0x6f, 0x73, 0x69, 0x74, 0x6f, 0x72, 0x79, 0x5f, 0x6f, 0x77, 0x6e, 0x65, 0x72, 0x5f, 0x75,
+	0x72, 0x69, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x18, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65,
+	0x52, 0x65, 0x70, 0x6f, 0x73, 0x69, 0x74, 0x6f, 0x72, 0x79, 0x4f, 0x77, 0x6e, 0x65, 0x72, 0x55,
+	0x72, 0x69, 0x22, 0xdf, 0x01, 0x0a, 0x25, 0x56, 0x65, 0x72, 0x69, 0x66, 0x79, 0x41, 0x6c, 0x6c,
+	0x57, 0x69, 0x74, 0x68, 0x4d, 0x69, 0x6e, 0x69, 0x6d, 0x75, 0x6d, 0x54, 0x6f, 0x6f, 0x6c, 0x63,
+	0x68, 0x61, 0x69, 0x6e, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x73, 0x12, 0x72, 0x0a, 0x10,
+	0x6d, 0x69, 0x6e, 0x69, 0x6d, 0x75, 0x6d, 0x5f, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x73,
+	0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x47, 0x2e, 0x6f, 0x61, 0x6b, 0x2e, 0x72, 0x65, 0x6c,
+	0x65, 0x61, 0x73, 0x65, 0x2e, 0x56, 0x65, 0x72, 0x69, 0x66, 0x79, 0x41, 0x6c, 0x6c, 0x57, 0x69,
+	0x74, 0x68, 0x4d, 0x69, 0x6e, 0x69, 0x6d, 0x75, 0x6d, 0x54, 0x6f, 0x6f, 0x6c, 0x63, 0x68, 0x61,
+	0x69, 0x6e, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x73, 0x2e, 0x4d, 0x69, 0x6e, 0x69, 0x6d,
+	0x75, 0x6d, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52,
+	0x0f, 0x6d, 0x69, 0x6e, 0x69, 0x6d, 0x75, 0x6d, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x73,
+	0x1a, 0x42, 0x0a, 0x14, 0x4d, 0x69, 0x6e, 0x69, 0x6d, 0x75, 0x6d, 0x56, 0x65, 0x72, 0x73, 0x69,
+	0x6f, 0x6e, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61,
+	0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65,
+	0x3a, 0x02, 0x38, 0x01, 0x42, 0x13, 0x5a, 0x11, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x6f, 0x61,
+	0x6b, 0x2f, 0x72, 0x65, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x33,
+}
+
+var (
+	file_proto_verification_options_proto_rawDescOnce sync.Once
+	file_proto_verification_options_proto_rawDescData = file_proto_verification_options_proto_rawDesc
+)
+
+func file_proto_verification_options_proto_rawDescGZIP() []byte {
+	file_proto_verification_options_proto_rawDescOnce.Do(func() {
+		file_proto_verification_options_proto_rawDescData = protoimpl.X.CompressGZIP(file_proto_verification_options_proto_rawDescData)
+	})
+	return file_proto_verification_options_proto_rawDescData
+}
+
+var file_proto_verification_options_proto_msgTypes = make([]protoimpl.MessageInfo, 26)
+var file_proto_verification_options_proto_goTypes = []interface{}{
+	(*VerificationOptions)(nil),                   // 0: oak.release.VerificationOptions
+	(*VerifyProvenanceCountAtLeast)(nil),          // 1: oak.release.VerifyProvenanceCountAtLeast
+	(*VerifyProvenanceCountAtMost)(nil),           // 2: oak.release.VerifyProvenanceCountAtMost
+	(*VerifyAllSameBinaryName)(nil),               // 3: oak.release.VerifyAllSameBinaryName
+	(*VerifyAllSameBinaryDigest)(nil),             // 4: oak.release.VerifyAllSameBinaryDigest
+	(*VerifyAllWithBuildCommand)(nil),             // 5: oak.release.VerifyAllWithBuildCommand
+	(*VerifyAllWithBinaryName)(nil),               // 6: oak.release.VerifyAllWithBinaryName
+	(*VerifyAllWithBinaryDigests)(nil),            // 7: oak.release.VerifyAllWithBinaryDigests
+	(*VerifyAllWithRepository)(nil),               // 8: oak.release.VerifyAllWithRepository
+	(*VerifyAllWithBuilderNames)(nil),             // 9: oak.release.VerifyAllWithBuilderNames
+	(*VerifyAllWithBuilderDigests)(nil),           // 10: oak.release.VerifyAllWithBuilderDigests
+	(*VerifyAllWithCommitDigests)(nil),            // 11: oak.release.VerifyAllWithCommitDigests
+	(*VerifyAllWithTreeDigests)(nil),              // 12: oak.release.VerifyAllWithTreeDigests
+	(*VerifyAllWithSourceRef)(nil),                // 13: oak.release.VerifyAllWithSourceRef
+	(*VerifyAllWithBuildTime)(nil),                // 14: oak.release.VerifyAllWithBuildTime
+	(*VerifyAllWithByproducts)(nil),               // 15: oak.release.VerifyAllWithByproducts
+	(*RequiredByproduct)(nil),                     // 16: oak.release.RequiredByproduct
+	(*VerifyAllWithBuildMetadata)(nil),            // 17: oak.release.VerifyAllWithBuildMetadata
+	(*VerifyAllWithBuildTypes)(nil),               // 18: oak.release.VerifyAllWithBuildTypes
+	(*VerifyNoneWithBuilderNames)(nil),            // 19: oak.release.VerifyNoneWithBuilderNames
+	(*VerifyNoneWithRepositories)(nil),            // 20: oak.release.VerifyNoneWithRepositories
+	(*VerifyNoneWithBinaryDigests)(nil),           // 21: oak.release.VerifyNoneWithBinaryDigests
+	(*VerifyNoneWithBuilderDigests)(nil),          // 22: oak.release.VerifyNoneWithBuilderDigests
+	(*VerifyAllWithCertificateIdentity)(nil),      // 23: oak.release.VerifyAllWithCertificateIdentity
+	(*VerifyAllWithMinimumToolchainVersions)(nil), // 24: oak.release.VerifyAllWithMinimumToolchainVersions
+	nil,            // 25: oak.release.VerifyAllWithMinimumToolchainVersions.MinimumVersionsEntry
+	(*Digest)(nil), // 26: oak.release.Digest
+}
+var file_proto_verification_options_proto_depIdxs = []int32{
+	1,  // 0: oak.release.VerificationOptions.provenance_count_at_least:type_name -> oak.release.VerifyProvenanceCountAtLeast
+	2,  // 1: oak.release.VerificationOptions.provenance_count_at_most:type_name -> oak.release.VerifyProvenanceCountAtMost
+	3,  // 2: oak.release.VerificationOptions.all_same_binary_name:type_name -> oak.release.VerifyAllSameBinaryName
+	4,  // 3: oak.release.VerificationOptions.all_same_binary_digest:type_name -> oak.release.VerifyAllSameBinaryDigest
+	5,  // 4: oak.release.VerificationOptions.all_with_build_command:type_name -> oak.release.VerifyAllWithBuildCommand
+	6,  // 5: oak.release.VerificationOptions.all_with_binary_name:type_name -> oak.release.VerifyAllWithBinaryName
+	7,  // 6: oak.release.VerificationOptions.all_with_binary_digests:type_name -> oak.release.VerifyAllWithBinaryDigests
+	9,  // 7: oak.release.VerificationOptions.all_with_builder_names:type_name -> oak.release.VerifyAllWithBuilderNames
+	10, // 8: oak.release.VerificationOptions.all_with_builder_digests:type_name -> oak.release.VerifyAllWithBuilderDigests
+	8,  // 9: oak.release.VerificationOptions.all_with_repository:type_name -> oak.release.VerifyAllWithRepository
+	23, // 10: oak.release.VerificationOptions.all_with_certificate_identity:type_name -> oak.release.VerifyAllWithCertificateIdentity
+	24, // 11: oak.release.VerificationOptions.all_with_minimum_toolchain_versions:type_name -> oak.release.VerifyAllWithMinimumToolchainVersions
+	11, // 12: oak.release.VerificationOptions.all_with_commit_digests:type_name -> oak.release.VerifyAllWithCommitDigests
+	13, // 13: oak.release.VerificationOptions.all_with_source_ref:type_name -> oak.release.VerifyAllWithSourceRef
+	14, // 14: oak.release.VerificationOptions.all_with_build_time:type_name -> oak.release.VerifyAllWithBuildTime
+	15, // 15: oak.release.VerificationOptions.all_with_byproducts:type_name -> oak.release.VerifyAllWithByproducts
+	17, // 16: oak.release.VerificationOptions.all_with_build_metadata:type_name -> oak.release.VerifyAllWithBuildMetadata
+	18, // 17: oak.release.VerificationOptions.all_with_build_types:type_name -> oak.release.VerifyAllWithBuildTypes
+	19, // 18: oak.release.VerificationOptions.none_with_builder_names:type_name -> oak.release.VerifyNoneWithBuilderNames
+	20, // 19: oak.release.VerificationOptions.none_with_repositories:type_name -> oak.release.VerifyNoneWithRepositories
+	21, // 20: oak.release.VerificationOptions.none_with_binary_digests:type_name -> oak.release.VerifyNoneWithBinaryDigests
+	22, // 21: oak.release.VerificationOptions.none_with_builder_digests:type_name -> oak.release.VerifyNoneWithBuilderDigests
+	12, // 22: oak.release.VerificationOptions.all_with_tree_digests:type_name -> oak.release.VerifyAllWithTreeDigests
+	26, // 23: oak.release.VerifyAllWithBinaryDigests.digests:type_name -> oak.release.Digest
+	26, // 24: oak.release.VerifyAllWithBuilderDigests.digests:type_name -> oak.release.Digest
+	26, // 25: oak.release.VerifyAllWithCommitDigests.digests:type_name -> oak.release.Digest
+	26, // 26: oak.release.VerifyAllWithTreeDigests.digests:type_name -> oak.release.Digest
+	16, // 27: oak.release.VerifyAllWithByproducts.byproducts:type_name -> oak.release.RequiredByproduct
+	26, // 28: oak.release.RequiredByproduct.digests:type_name -> oak.release.Digest
+	26, // 29: oak.release.VerifyNoneWithBinaryDigests.digests:type_name -> oak.release.Digest
+	26, // 30: oak.release.VerifyNoneWithBuilderDigests.digests:type_name -> oak.release.Digest
+	25, // 31: oak.release.VerifyAllWithMinimumToolchainVersions.minimum_versions:type_name -> oak.release.VerifyAllWithMinimumToolchainVersions.MinimumVersionsEntry
+	32, // [32:32] is the sub-list for method output_type
+	32, // [32:32] is the sub-list for method input_type
+	32, // [32:32] is the sub-list for extension type_name
+	32, // [32:32] is the sub-list for extension extendee
+	0,  // [0:32] is the sub-list for field type_name
+}
+
+func init() { file_proto_verification_options_proto_init() }
+func file_proto_verification_options_proto_init() {
+	if File_proto_verification_options_proto != nil {
+		return
+	}
+	file_proto_digest_proto_init()
+	if !protoimpl.UnsafeEnabled {
+		file_proto_verification_options_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*VerificationOptions); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_verification_options_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*VerifyProvenanceCountAtLeast); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_verification_options_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*VerifyProvenanceCountAtMost); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_verification_options_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*VerifyAllSameBinaryName); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
 			}
 		}
 		file_proto_verification_options_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
@@ -936,6 +2088,174 @@ func file_proto_verification_options_proto_init() {
 				return nil
 			}
 		}
+		file_proto_verification_options_proto_msgTypes[11].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*VerifyAllWithCommitDigests); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_verification_options_proto_msgTypes[12].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*VerifyAllWithTreeDigests); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_verification_options_proto_msgTypes[13].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*VerifyAllWithSourceRef); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_verification_options_proto_msgTypes[14].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*VerifyAllWithBuildTime); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_verification_options_proto_msgTypes[15].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*VerifyAllWithByproducts); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_verification_options_proto_msgTypes[16].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*RequiredByproduct); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_verification_options_proto_msgTypes[17].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*VerifyAllWithBuildMetadata); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_verification_options_proto_msgTypes[18].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*VerifyAllWithBuildTypes); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_verification_options_proto_msgTypes[19].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*VerifyNoneWithBuilderNames); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_verification_options_proto_msgTypes[20].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*VerifyNoneWithRepositories); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_verification_options_proto_msgTypes[21].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*VerifyNoneWithBinaryDigests); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_verification_options_proto_msgTypes[22].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*VerifyNoneWithBuilderDigests); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_verification_options_proto_msgTypes[23].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*VerifyAllWithCertificateIdentity); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_verification_options_proto_msgTypes[24].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*VerifyAllWithMinimumToolchainVersions); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 	}
 	file_proto_verification_options_proto_msgTypes[0].OneofWrappers = []interface{}{}
 	type x struct{}
@@ -944,7 +2264,7 @@ func file_proto_verification_options_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_proto_verification_options_proto_rawDesc,
 			NumEnums:      0,
-			NumMessages:   11,
+			NumMessages:   26,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
@@ -0,0 +1,170 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sign
+
+import (
+	"context"
+	"crypto"
+	_ "crypto/sha256" // Registers SHA256 for crypto.Hash.
+	_ "crypto/sha512" // Registers SHA384 and SHA512 for crypto.Hash.
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"regexp"
+	"strings"
+
+	cloudkms "google.golang.org/api/cloudkms/v1"
+	"google.golang.org/api/option"
+)
+
+// KMSKeyURIPrefix is the scheme prefix of Google Cloud KMS key URIs.
+const KMSKeyURIPrefix = "gcpkms://"
+
+//nolint:gochecknoglobals
+var kmsKeyVersionPattern = regexp.MustCompile(
+	`^projects/[^/]+/locations/[^/]+/keyRings/[^/]+/cryptoKeys/[^/]+/cryptoKeyVersions/[^/]+$`)
+
+// kmsHashes maps the supported Cloud KMS signing algorithms to the hash
+// function they use.
+//
+//nolint:gochecknoglobals
+var kmsHashes = map[string]crypto.Hash{
+	"EC_SIGN_P256_SHA256":        crypto.SHA256,
+	"EC_SIGN_P384_SHA384":        crypto.SHA384,
+	"RSA_SIGN_PKCS1_2048_SHA256": crypto.SHA256,
+	"RSA_SIGN_PKCS1_3072_SHA256": crypto.SHA256,
+	"RSA_SIGN_PKCS1_4096_SHA256": crypto.SHA256,
+	"RSA_SIGN_PKCS1_4096_SHA512": crypto.SHA512,
+}
+
+// ParseKMSKeyURI parses a key URI of the form
+// `gcpkms://projects/P/locations/L/keyRings/R/cryptoKeys/K/cryptoKeyVersions/V`
+// and returns the resource name of the key version.
+func ParseKMSKeyURI(keyURI string) (string, error) {
+	if !strings.HasPrefix(keyURI, KMSKeyURIPrefix) {
+		return "", fmt.Errorf("KMS key URI %q does not start with %q", keyURI, KMSKeyURIPrefix)
+	}
+	name := strings.TrimPrefix(keyURI, KMSKeyURIPrefix)
+	if !kmsKeyVersionPattern.MatchString(name) {
+		return "", fmt.Errorf("KMS key URI %q does not identify a crypto key version", keyURI)
+	}
+	return name, nil
+}
+
+// KMSSigner signs data with an asymmetric signing key held in Google Cloud
+// KMS. The private key never leaves KMS. KMSSigner implements Signer.
+type KMSSigner struct {
+	service   *cloudkms.Service
+	name      string
+	hash      crypto.Hash
+	publicKey crypto.PublicKey
+}
+
+// NewKMSSigner creates a KMSSigner for the key version identified by the given
+// `gcpkms://` URI, and fetches its public key. By default, application default
+// credentials are used to authenticate to Cloud KMS.
+func NewKMSSigner(ctx context.Context, keyURI string, opts ...option.ClientOption) (*KMSSigner, error) {
+	name, err := ParseKMSKeyURI(keyURI)
+	if err != nil {
+		return nil, err
+	}
+
+	service, err := cloudkms.NewService(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("could not create a Cloud KMS client: %v", err)
+	}
+
+	versions := service.Projects.Locations.KeyRings.CryptoKeys.CryptoKeyVersions
+	response, err := versions.GetPublicKey(name).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("could not get the public key of %q: %v", name, err)
+	}
+	hash, ok := kmsHashes[response.Algorithm]
+	if !ok {
+		return nil, fmt.Errorf("unsupported signing algorithm %q for %q", response.Algorithm, name)
+	}
+	block, _ := pem.Decode([]byte(response.Pem))
+	if block == nil {
+		return nil, fmt.Errorf("could not decode the PEM-encoded public key of %q", name)
+	}
+	publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse the public key of %q: %v", name, err)
+	}
+
+	return &KMSSigner{service: service, name: name, hash: hash, publicKey: publicKey}, nil
+}
+
+// Sign asks Cloud KMS to sign the digest of the given data.
+func (s *KMSSigner) Sign(ctx context.Context, data []byte) ([]byte, error) {
+	digest, err := s.digest(data)
+	if err != nil {
+		return nil, err
+	}
+	request := &cloudkms.AsymmetricSignRequest{Digest: &cloudkms.Digest{}}
+	encoded := base64.StdEncoding.EncodeToString(digest)
+	switch s.hash {
+	case crypto.SHA256:
+		request.Digest.Sha256 = encoded
+	case crypto.SHA384:
+		request.Digest.Sha384 = encoded
+	case crypto.SHA512:
+		request.Digest.Sha512 = encoded
+	}
+
+	versions := s.service.Projects.Locations.KeyRings.CryptoKeys.CryptoKeyVersions
+	response, err := versions.AsymmetricSign(s.name, request).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("could not sign with %q: %v", s.name, err)
+	}
+	if response.Name != "" && response.Name != s.name {
+		return nil, fmt.Errorf("signature created with %q instead of %q", response.Name, s.name)
+	}
+	sig, err := base64.StdEncoding.DecodeString(response.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode the signature: %v", err)
+	}
+	return sig, nil
+}
+
+// Verify verifies the given signature over the given data locally, using the
+// public key fetched from Cloud KMS.
+func (s *KMSSigner) Verify(_ context.Context, data, sig []byte) error {
+	digest, err := s.digest(data)
+	if err != nil {
+		return err
+	}
+	return verifySignature(s.publicKey, s.hash, digest, sig)
+}
+
+// KeyID returns the KMS key URI, which identifies the signing key.
+func (s *KMSSigner) KeyID() (string, error) {
+	return KMSKeyURIPrefix + s.name, nil
+}
+
+// Public returns the public key fetched from Cloud KMS.
+func (s *KMSSigner) Public() crypto.PublicKey {
+	return s.publicKey
+}
+
+func (s *KMSSigner) digest(data []byte) ([]byte, error) {
+	if !s.hash.Available() {
+		return nil, fmt.Errorf("hash function %v is not available", s.hash)
+	}
+	h := s.hash.New()
+	h.Write(data)
+	return h.Sum(nil), nil
+}
@@ -0,0 +1,245 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sign
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/project-oak/transparent-release/internal/testutil"
+	"github.com/secure-systems-lab/go-securesystemslib/dsse"
+	cloudkms "google.golang.org/api/cloudkms/v1"
+	"google.golang.org/api/option"
+)
+
+const keyName = "projects/oak/locations/global/keyRings/release/cryptoKeys/endorser/cryptoKeyVersions/1"
+
+// newFakeKMS starts a server implementing the GetPublicKey and AsymmetricSign
+// methods of the Cloud KMS REST API for a single EC_SIGN_P256_SHA256 key.
+func newFakeKMS(t *testing.T) (*httptest.Server, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("could not generate key: %v", err)
+	}
+	return newFakeKMSWithKey(t, "EC_SIGN_P256_SHA256", key), key
+}
+
+// newFakeKMSWithKey starts a fake Cloud KMS for the given key, with the given
+// signing algorithm. Like Cloud KMS, it only signs digests computed with the
+// hash function of the algorithm.
+func newFakeKMSWithKey(t *testing.T, algorithm string, key crypto.Signer) *httptest.Server {
+	der, err := x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		t.Fatalf("could not marshal public key: %v", err)
+	}
+	publicKeyPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	hash := kmsHashes[algorithm]
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var response any
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/v1/"+keyName+"/publicKey":
+			response = cloudkms.PublicKey{Name: keyName, Algorithm: algorithm, Pem: string(publicKeyPEM)}
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/"+keyName+":asymmetricSign":
+			var request cloudkms.AsymmetricSignRequest
+			if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			encoded := map[crypto.Hash]string{
+				crypto.SHA256: request.Digest.Sha256,
+				crypto.SHA384: request.Digest.Sha384,
+				crypto.SHA512: request.Digest.Sha512,
+			}[hash]
+			digest, err := base64.StdEncoding.DecodeString(encoded)
+			if err != nil || len(digest) != hash.Size() {
+				http.Error(w, "the digest does not match the algorithm of the key", http.StatusBadRequest)
+				return
+			}
+			sig, err := key.Sign(rand.Reader, digest, hash)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			response = cloudkms.AsymmetricSignResponse{Name: keyName, Signature: base64.StdEncoding.EncodeToString(sig)}
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(response)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newTestKMSSigner(t *testing.T, server *httptest.Server) *KMSSigner {
+	signer, err := NewKMSSigner(context.Background(), KMSKeyURIPrefix+keyName,
+		option.WithEndpoint(server.URL+"/"), option.WithoutAuthentication())
+	if err != nil {
+		t.Fatalf("could not create KMS signer: %v", err)
+	}
+	return signer
+}
+
+func TestParseKMSKeyURI(t *testing.T) {
+	name, err := ParseKMSKeyURI(KMSKeyURIPrefix + keyName)
+	if err != nil {
+		t.Fatalf("could not parse key URI: %v", err)
+	}
+	testutil.AssertEq(t, "key name", name, keyName)
+
+	for _, uri := range []string{
+		keyName,
+		"awskms://" + keyName,
+		KMSKeyURIPrefix + strings.TrimSuffix(keyName, "/cryptoKeyVersions/1"),
+	} {
+		if _, err := ParseKMSKeyURI(uri); err == nil {
+			t.Errorf("expected an error for %q", uri)
+		}
+	}
+}
+
+func TestKMSSigner_SignAndVerify(t *testing.T) {
+	server, key := newFakeKMS(t)
+	signer := newTestKMSSigner(t, server)
+
+	keyID, err := signer.KeyID()
+	if err != nil {
+		t.Fatalf("could not get key ID: %v", err)
+	}
+	testutil.AssertEq(t, "key ID", keyID, KMSKeyURIPrefix+keyName)
+	if !key.PublicKey.Equal(signer.Public()) {
+		t.Fatalf("got unexpected public key")
+	}
+
+	ctx := context.Background()
+	data := []byte("endorsement")
+	sig, err := signer.Sign(ctx, data)
+	if err != nil {
+		t.Fatalf("could not sign: %v", err)
+	}
+	if err := signer.Verify(ctx, data, sig); err != nil {
+		t.Fatalf("could not verify signature: %v", err)
+	}
+	if err := signer.Verify(ctx, []byte("tampered"), sig); err == nil {
+		t.Fatalf("expected verification of tampered data to fail")
+	}
+}
+
+func TestKMSSigner_DSSEEnvelope(t *testing.T) {
+	server, _ := newFakeKMS(t)
+	signer := newTestKMSSigner(t, server)
+
+	envelopeSigner, err := dsse.NewEnvelopeSigner(signer)
+	if err != nil {
+		t.Fatalf("could not create envelope signer: %v", err)
+	}
+	ctx := context.Background()
+	envelope, err := envelopeSigner.SignPayload(ctx, "application/vnd.in-toto+json", []byte("{}"))
+	if err != nil {
+		t.Fatalf("could not sign payload: %v", err)
+	}
+	testutil.AssertEq(t, "keyid", envelope.Signatures[0].KeyID, KMSKeyURIPrefix+keyName)
+
+	envelopeVerifier, err := dsse.NewEnvelopeVerifier(signer)
+	if err != nil {
+		t.Fatalf("could not create envelope verifier: %v", err)
+	}
+	if _, err := envelopeVerifier.Verify(ctx, envelope); err != nil {
+		t.Fatalf("could not verify envelope: %v", err)
+	}
+}
+
+func TestNewKMSSigner_UnknownKeyFails(t *testing.T) {
+	server, _ := newFakeKMS(t)
+	_, err := NewKMSSigner(context.Background(),
+		KMSKeyURIPrefix+strings.Replace(keyName, "endorser", "other", 1),
+		option.WithEndpoint(server.URL+"/"), option.WithoutAuthentication())
+	if err == nil {
+		t.Fatalf("expected an error for an unknown key")
+	}
+}
+
+func TestPublicKeyVerifier(t *testing.T) {
+	server, key := newFakeKMS(t)
+	signer := newTestKMSSigner(t, server)
+
+	der, err := x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		t.Fatalf("could not marshal public key: %v", err)
+	}
+	publicKey, err := ParsePublicKeyPEM(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	if err != nil {
+		t.Fatalf("could not parse public key: %v", err)
+	}
+	verifier, err := NewPublicKeyVerifier(publicKey)
+	if err != nil {
+		t.Fatalf("could not create verifier: %v", err)
+	}
+
+	ctx := context.Background()
+	sig, err := signer.Sign(ctx, []byte("endorsement"))
+	if err != nil {
+		t.Fatalf("could not sign: %v", err)
+	}
+	if err := verifier.Verify(ctx, []byte("endorsement"), sig); err != nil {
+		t.Fatalf("could not verify signature: %v", err)
+	}
+	if err := verifier.Verify(ctx, []byte("tampered"), sig); err == nil {
+		t.Fatalf("expected verification of tampered data to fail")
+	}
+}
+
+func TestPublicKeyVerifier_RSAKMSAlgorithms(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 4096)
+	if err != nil {
+		t.Fatalf("could not generate key: %v", err)
+	}
+	verifier, err := NewPublicKeyVerifier(key.Public())
+	if err != nil {
+		t.Fatalf("could not create verifier: %v", err)
+	}
+
+	ctx := context.Background()
+	for _, algorithm := range []string{"RSA_SIGN_PKCS1_4096_SHA256", "RSA_SIGN_PKCS1_4096_SHA512"} {
+		signer := newTestKMSSigner(t, newFakeKMSWithKey(t, algorithm, key))
+		sig, err := signer.Sign(ctx, []byte("endorsement"))
+		if err != nil {
+			t.Fatalf("%s: could not sign: %v", algorithm, err)
+		}
+		if err := signer.Verify(ctx, []byte("endorsement"), sig); err != nil {
+			t.Errorf("%s: could not verify the signature with the signer: %v", algorithm, err)
+		}
+		if err := verifier.Verify(ctx, []byte("endorsement"), sig); err != nil {
+			t.Errorf("%s: could not verify the signature with the public key: %v", algorithm, err)
+		}
+		if err := verifier.Verify(ctx, []byte("tampered"), sig); err == nil {
+			t.Errorf("%s: expected verification of tampered data to fail", algorithm)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sign
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// PublicKeyVerifier verifies DSSE signatures with a public key, e.g., the
+// public key of a product team. PublicKeyVerifier implements dsse.Verifier.
+type PublicKeyVerifier struct {
+	publicKey crypto.PublicKey
+	// hashes are the hash functions of the signing algorithms of the key, or
+	// nil for Ed25519 keys, which sign the data itself.
+	hashes []crypto.Hash
+}
+
+// NewPublicKeyVerifier creates a PublicKeyVerifier for the given ECDSA, RSA,
+// or Ed25519 public key. ECDSA signatures are expected over the hash matching
+// the curve, and Ed25519 signatures over the data. RSA signatures are expected
+// over the hash of any Cloud KMS signing algorithm for the size of the key,
+// e.g., SHA256 or SHA512 for 4096-bit keys, or SHA256 for keys of other sizes.
+// Since PKCS #1 v1.5 signatures encode the hash function, a signature is only
+// valid with the hash function it was created with.
+func NewPublicKeyVerifier(publicKey crypto.PublicKey) (*PublicKeyVerifier, error) {
+	switch key := publicKey.(type) {
+	case *ecdsa.PublicKey:
+		if key.Curve == elliptic.P384() {
+			return &PublicKeyVerifier{publicKey: publicKey, hashes: []crypto.Hash{crypto.SHA384}}, nil
+		}
+		return &PublicKeyVerifier{publicKey: publicKey, hashes: []crypto.Hash{crypto.SHA256}}, nil
+	case *rsa.PublicKey:
+		return &PublicKeyVerifier{publicKey: publicKey, hashes: rsaHashes(key)}, nil
+	case ed25519.PublicKey:
+		// Ed25519 signs the data itself rather than its digest.
+		return &PublicKeyVerifier{publicKey: publicKey}, nil
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T", publicKey)
+	}
+}
+
+// rsaHashes returns the hash functions of the Cloud KMS signing algorithms for
+// RSA keys of the size of the given key, or SHA256 if there are none.
+func rsaHashes(key *rsa.PublicKey) []crypto.Hash {
+	prefix := fmt.Sprintf("RSA_SIGN_PKCS1_%d_", key.N.BitLen())
+	var hashes []crypto.Hash
+	for algorithm, hash := range kmsHashes {
+		if strings.HasPrefix(algorithm, prefix) {
+			hashes = append(hashes, hash)
+		}
+	}
+	if len(hashes) == 0 {
+		return []crypto.Hash{crypto.SHA256}
+	}
+	sort.Slice(hashes, func(i, j int) bool { return hashes[i] < hashes[j] })
+	return hashes
+}
+
+// LoadPublicKeyVerifier creates a PublicKeyVerifier for the PEM-encoded
+// public key in the given file.
+func LoadPublicKeyVerifier(path string) (*PublicKeyVerifier, error) {
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading public key from %q: %v", path, err)
+	}
+	publicKey, err := ParsePublicKeyPEM(bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parsing public key from %q: %v", path, err)
+	}
+	return NewPublicKeyVerifier(publicKey)
+}
+
+// ParsePublicKeyPEM parses a PEM-encoded PKIX public key, or returns the
+// public key of a PEM-encoded X.509 certificate.
+func ParsePublicKeyPEM(pemBytes []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, fmt.Errorf("could not decode PEM block")
+	}
+	if block.Type == "CERTIFICATE" {
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse certificate: %v", err)
+		}
+		return cert.PublicKey, nil
+	}
+	publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse public key: %v", err)
+	}
+	return publicKey, nil
+}
+
+// Verify verifies the given signature over the given data.
+func (v *PublicKeyVerifier) Verify(_ context.Context, data, sig []byte) error {
+	if len(v.hashes) == 0 {
+		return verifySignature(v.publicKey, 0, data, sig)
+	}
+	var err error
+	for _, hash := range v.hashes {
+		h := hash.New()
+		h.Write(data)
+		if err = verifySignature(v.publicKey, hash, h.Sum(nil), sig); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// KeyID returns an empty key ID, so that signatures with any key ID are
+// verified against the public key.
+func (v *PublicKeyVerifier) KeyID() (string, error) {
+	return "", nil
+}
+
+// Public returns the public key.
+func (v *PublicKeyVerifier) Public() crypto.PublicKey {
+	return v.publicKey
+}
@@ -0,0 +1,155 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package toolchain queries the versions of toolchains, such as `rustc`, in
+// the builder image of a container-based build, so that generators of
+// provenances can record them as resolved dependencies. Verifiers can then
+// require minimum toolchain versions, e.g., in reaction to a compiler CVE.
+package toolchain
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"sort"
+
+	slsav1 "github.com/project-oak/transparent-release/pkg/intoto/slsa_provenance/v1"
+)
+
+// versionCommands maps the names of supported toolchains to the commands that
+// print their versions.
+//
+//nolint:gochecknoglobals
+var versionCommands = map[string][]string{
+	"rustc": {"rustc", "--version"},
+	"cargo": {"cargo", "--version"},
+	"go":    {"go", "version"},
+}
+
+// versionPattern matches versions in the output of version commands, e.g.,
+// `1.70.0` in `rustc 1.70.0 (90c541806 2023-05-31)`, `1.71.0-nightly` in
+// `rustc 1.71.0-nightly (...)`, and `1.20.5` in `go version go1.20.5 linux/amd64`.
+//
+//nolint:gochecknoglobals
+var versionPattern = regexp.MustCompile(`\d+\.\d+(\.\d+)?(-[0-9A-Za-z.]+)?`)
+
+// Runner runs a command in a container from an image, and returns its
+// standard output.
+type Runner interface {
+	Run(ctx context.Context, image string, command []string) ([]byte, error)
+}
+
+// DockerRunner runs commands with `docker run`, without network access.
+type DockerRunner struct {
+	// Runtime is the command of a container runtime with the same `run`
+	// arguments as Docker, e.g., `podman`. Defaults to `docker`.
+	Runtime string
+}
+
+// Run runs the given command in a new container from the given image.
+func (r DockerRunner) Run(ctx context.Context, image string, command []string) ([]byte, error) {
+	runtime := r.Runtime
+	if runtime == "" {
+		runtime = "docker"
+	}
+	args := append([]string{"run", "--rm", "--network=none", "--entrypoint", command[0], image}, command[1:]...)
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, runtime, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("running %q in %s: %v: %s", command, image, err, stderr.String())
+	}
+	return stdout.Bytes(), nil
+}
+
+// QueryConfig holds optional settings for QueryVersions.
+type QueryConfig struct {
+	runner Runner
+}
+
+// WithRunner sets the Runner for the version commands. Defaults to DockerRunner.
+func WithRunner(runner Runner) func(c *QueryConfig) {
+	return func(c *QueryConfig) {
+		c.runner = runner
+	}
+}
+
+// SupportedToolchains returns the sorted names of the toolchains whose
+// versions can be queried.
+func SupportedToolchains() []string {
+	names := make([]string, 0, len(versionCommands))
+	for name := range versionCommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// QueryVersions queries the versions of the toolchains with the given names in
+// the given builder image, which should be pinned by digest. Returns the
+// versions keyed by the names of the toolchains.
+func QueryVersions(ctx context.Context, image string, names []string, options ...func(c *QueryConfig)) (map[string]string, error) {
+	config := &QueryConfig{runner: DockerRunner{}}
+	for _, option := range options {
+		option(config)
+	}
+
+	versions := make(map[string]string, len(names))
+	for _, name := range names {
+		command, ok := versionCommands[name]
+		if !ok {
+			return nil, fmt.Errorf("unsupported toolchain %q, want one of %v", name, SupportedToolchains())
+		}
+		output, err := config.runner.Run(ctx, image, command)
+		if err != nil {
+			return nil, fmt.Errorf("querying the version of %q: %v", name, err)
+		}
+		version, err := ParseVersion(output)
+		if err != nil {
+			return nil, fmt.Errorf("querying the version of %q: %v", name, err)
+		}
+		versions[name] = version
+	}
+	return versions, nil
+}
+
+// ParseVersion returns the first version in the given output of a version
+// command.
+func ParseVersion(output []byte) (string, error) {
+	version := versionPattern.Find(output)
+	if version == nil {
+		return "", fmt.Errorf("no version in %q", bytes.TrimSpace(output))
+	}
+	return string(version), nil
+}
+
+// ResolvedDependencies returns the given toolchain versions as resolved
+// dependencies for a SLSA v1 provenance, sorted by the names of the
+// toolchains.
+func ResolvedDependencies(versions map[string]string) []slsav1.ResourceDescriptor {
+	names := make([]string, 0, len(versions))
+	for name := range versions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	dependencies := make([]slsav1.ResourceDescriptor, 0, len(names))
+	for _, name := range names {
+		dependencies = append(dependencies, slsav1.NewToolchainDependency(name, versions[name]))
+	}
+	return dependencies
+}
@@ -0,0 +1,104 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package toolchain
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/project-oak/transparent-release/internal/testutil"
+	slsav1 "github.com/project-oak/transparent-release/pkg/intoto/slsa_provenance/v1"
+)
+
+const testImage = "europe-west2-docker.pkg.dev/oak-ci/oak-development/oak-development@sha256:51532c757d1008bbff696d053a1d05226f6387cf232aa80b6f9c13b0759ccea0"
+
+// fakeRunner returns canned outputs of commands.
+type fakeRunner map[string]string
+
+func (r fakeRunner) Run(_ context.Context, image string, command []string) ([]byte, error) {
+	if image != testImage {
+		return nil, fmt.Errorf("unexpected image %q", image)
+	}
+	output, ok := r[strings.Join(command, " ")]
+	if !ok {
+		return nil, fmt.Errorf("command not found: %q", command)
+	}
+	return []byte(output), nil
+}
+
+func TestParseVersion(t *testing.T) {
+	for output, want := range map[string]string{
+		"rustc 1.70.0 (90c541806 2023-05-31)\n":         "1.70.0",
+		"rustc 1.71.0-nightly (2f2c438dc 2023-05-08)\n": "1.71.0-nightly",
+		"cargo 1.70.0 (ec8a8a0ca 2023-04-25)\n":         "1.70.0",
+		"go version go1.20.5 linux/amd64\n":             "1.20.5",
+		"go version go1.21 linux/amd64\n":               "1.21",
+	} {
+		got, err := ParseVersion([]byte(output))
+		if err != nil {
+			t.Fatalf("could not parse %q: %v", output, err)
+		}
+		testutil.AssertEq(t, output, got, want)
+	}
+
+	if _, err := ParseVersion([]byte("command not found")); err == nil {
+		t.Errorf("expected failure for output without a version")
+	}
+}
+
+func TestQueryVersions(t *testing.T) {
+	runner := fakeRunner{
+		"rustc --version": "rustc 1.70.0 (90c541806 2023-05-31)\n",
+		"go version":      "go version go1.20.5 linux/amd64\n",
+	}
+	got, err := QueryVersions(context.Background(), testImage, []string{"rustc", "go"}, WithRunner(runner))
+	if err != nil {
+		t.Fatalf("could not query versions: %v", err)
+	}
+	if diff := cmp.Diff(got, map[string]string{"rustc": "1.70.0", "go": "1.20.5"}); diff != "" {
+		t.Errorf("unexpected versions: %s", diff)
+	}
+}
+
+func TestQueryVersions_Errors(t *testing.T) {
+	if _, err := QueryVersions(context.Background(), testImage, []string{"javac"}, WithRunner(fakeRunner{})); err == nil {
+		t.Errorf("expected failure for an unsupported toolchain")
+	}
+	// The image has no cargo.
+	if _, err := QueryVersions(context.Background(), testImage, []string{"cargo"}, WithRunner(fakeRunner{})); err == nil {
+		t.Errorf("expected failure for a missing toolchain")
+	}
+}
+
+func TestResolvedDependencies(t *testing.T) {
+	dependencies := ResolvedDependencies(map[string]string{"rustc": "1.70.0", "cargo": "1.70.0"})
+	want := []slsav1.ResourceDescriptor{
+		slsav1.NewToolchainDependency("cargo", "1.70.0"),
+		slsav1.NewToolchainDependency("rustc", "1.70.0"),
+	}
+	if diff := cmp.Diff(dependencies, want); diff != "" {
+		t.Errorf("unexpected dependencies: %s", diff)
+	}
+
+	// The dependencies round-trip through a provenance predicate.
+	predicate := slsav1.ProvenancePredicate{BuildDefinition: slsav1.ProvenanceBuildDefinition{ResolvedDependencies: dependencies}}
+	if diff := cmp.Diff(predicate.ToolchainVersions(), map[string]string{"rustc": "1.70.0", "cargo": "1.70.0"}); diff != "" {
+		t.Errorf("unexpected toolchain versions: %s", diff)
+	}
+}
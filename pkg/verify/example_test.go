@@ -0,0 +1,40 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify_test
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/project-oak/transparent-release/pkg/verify"
+)
+
+func ExampleVerify() {
+	provenance, err := verify.LoadProvenance("../../testdata/slsa_v1_provenance.json")
+	if err != nil {
+		log.Fatal(err)
+	}
+	options, err := verify.ParseOptions(`all_with_repository { repository_uri: "git+https://github.com/project-oak/oak" }`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := verify.Verify([]verify.Provenance{*provenance}, options); err != nil {
+		fmt.Println("verification failed:", err)
+		return
+	}
+	fmt.Println("verification passed")
+	// Output: verification passed
+}
@@ -0,0 +1,171 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package verify is the public entry point for verifying provenances against
+// VerificationOptions, for services that embed the verification of
+// transparent-release instead of running cmd/verifier.
+package verify
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/project-oak/transparent-release/internal/model"
+	"github.com/project-oak/transparent-release/internal/verifier"
+	"github.com/project-oak/transparent-release/pkg/intoto"
+	pb "github.com/project-oak/transparent-release/pkg/proto/oak/release"
+)
+
+// Provenance is the format-independent representation of a provenance, as
+// returned by LoadProvenance and ParseProvenance.
+type Provenance = model.ProvenanceIR
+
+// Report lists the checks performed by VerifyWithReport.
+type Report = verifier.Report
+
+// CheckResult is the outcome of a single check in a Report.
+type CheckResult = verifier.CheckResult
+
+// VerificationError is a failure of a single check, with the category of the
+// failure, the index of the failing provenance, and the expected and actual
+// values. Errors returned by Verify wrap VerificationErrors.
+type VerificationError = verifier.VerificationError
+
+// AllProvenances is the Index of a VerificationError about the provenances as
+// a whole.
+const AllProvenances = verifier.AllProvenances
+
+// Categories of verification failures, for use with errors.Is.
+//
+//nolint:gochecknoglobals
+var (
+	ErrTooFewProvenances           = verifier.ErrTooFewProvenances
+	ErrTooManyProvenances          = verifier.ErrTooManyProvenances
+	ErrDenied                      = verifier.ErrDenied
+	ErrBinaryNameMismatch          = verifier.ErrBinaryNameMismatch
+	ErrBinaryDigestMismatch        = verifier.ErrBinaryDigestMismatch
+	ErrMissingBuildCommand         = verifier.ErrMissingBuildCommand
+	ErrRepositoryMismatch          = verifier.ErrRepositoryMismatch
+	ErrUntrustedBuilder            = verifier.ErrUntrustedBuilder
+	ErrBuilderDigestMismatch       = verifier.ErrBuilderDigestMismatch
+	ErrCommitDigestMismatch        = verifier.ErrCommitDigestMismatch
+	ErrTreeDigestMismatch          = verifier.ErrTreeDigestMismatch
+	ErrSourceRefMismatch           = verifier.ErrSourceRefMismatch
+	ErrBuildTimeOutOfRange         = verifier.ErrBuildTimeOutOfRange
+	ErrByproductMismatch           = verifier.ErrByproductMismatch
+	ErrBuildMetadataMismatch       = verifier.ErrBuildMetadataMismatch
+	ErrBuildTypeMismatch           = verifier.ErrBuildTypeMismatch
+	ErrCertificateIdentityMismatch = verifier.ErrCertificateIdentityMismatch
+	ErrToolchainVersionTooOld      = verifier.ErrToolchainVersionTooOld
+)
+
+// SupportedFormat is a combination of predicate type and build type of
+// provenances that can be verified.
+type SupportedFormat = model.SupportedFormat
+
+// ProvenanceConfig holds optional settings for loading provenances.
+type ProvenanceConfig struct {
+	subjectName    string
+	subjectDigests intoto.DigestSet
+}
+
+// WithSubjectName selects the subject with the given name from provenances
+// with several subjects.
+func WithSubjectName(name string) func(c *ProvenanceConfig) {
+	return func(c *ProvenanceConfig) {
+		c.subjectName = name
+	}
+}
+
+// WithSubjectDigests selects the subject with the given digests from
+// provenances with several subjects. Can be combined with WithSubjectName.
+func WithSubjectDigests(digests intoto.DigestSet) func(c *ProvenanceConfig) {
+	return func(c *ProvenanceConfig) {
+		c.subjectDigests = digests
+	}
+}
+
+// LoadProvenance reads and parses the provenance at the given path.
+func LoadProvenance(path string, options ...func(c *ProvenanceConfig)) (*Provenance, error) {
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading the provenance from %q: %v", path, err)
+	}
+	return ParseProvenance(bytes, options...)
+}
+
+// ParseProvenance parses the given provenance, in any of the formats returned
+// by SupportedFormats.
+func ParseProvenance(bytes []byte, options ...func(c *ProvenanceConfig)) (*Provenance, error) {
+	config := &ProvenanceConfig{}
+	for _, addOption := range options {
+		addOption(config)
+	}
+
+	validated, err := model.ParseStatementData(bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parsing the provenance: %v", err)
+	}
+	if config.subjectName != "" || len(config.subjectDigests) != 0 {
+		validated, err = validated.SelectSubject(config.subjectName, config.subjectDigests)
+		if err != nil {
+			return nil, fmt.Errorf("selecting the subject: %v", err)
+		}
+	}
+	provenance, err := model.FromValidatedProvenance(validated)
+	if err != nil {
+		return nil, fmt.Errorf("mapping the provenance to its internal representation: %v", err)
+	}
+	return provenance, nil
+}
+
+// ParseOptions parses VerificationOptions from textproto.
+func ParseOptions(textproto string) (*pb.VerificationOptions, error) {
+	return verifier.ParseVerificationOptions(textproto)
+}
+
+// ParseOptionsYAML parses VerificationOptions from YAML, using the same field
+// names as textproto. Errors point at the line and column of invalid fields.
+func ParseOptionsYAML(data []byte) (*pb.VerificationOptions, error) {
+	return verifier.ParseVerificationOptionsYAML(data)
+}
+
+// LoadOptions loads VerificationOptions from a YAML file, if the file has a
+// `.yaml` or `.yml` extension, or from a textproto file otherwise.
+func LoadOptions(path string) (*pb.VerificationOptions, error) {
+	return verifier.LoadVerificationOptions(path)
+}
+
+// Verify checks that the given provenances conform to the given options,
+// returning the errors of all failed checks.
+func Verify(provenances []Provenance, options *pb.VerificationOptions) error {
+	if provenances == nil || options == nil {
+		return fmt.Errorf("provenances and options must not be nil")
+	}
+	return verifier.Verify(provenances, options)
+}
+
+// VerifyWithReport checks that the given provenances conform to the given
+// options, and returns a report listing every check performed.
+func VerifyWithReport(provenances []Provenance, options *pb.VerificationOptions) (*Report, error) {
+	if provenances == nil || options == nil {
+		return nil, fmt.Errorf("provenances and options must not be nil")
+	}
+	return verifier.VerifyWithReport(provenances, options), nil
+}
+
+// SupportedFormats returns the formats of provenances that can be verified.
+func SupportedFormats() []SupportedFormat {
+	return model.SupportedFormats()
+}
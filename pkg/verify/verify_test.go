@@ -0,0 +1,73 @@
+// Copyright 2023 The Project Oak Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/project-oak/transparent-release/pkg/intoto"
+)
+
+const provenancePath = "../../testdata/slsa_v02_provenance.json"
+
+func TestLoadProvenance_VerifyWithReport(t *testing.T) {
+	provenance, err := LoadProvenance(provenancePath)
+	if err != nil {
+		t.Fatalf("could not load the provenance: %v", err)
+	}
+	options, err := ParseOptions(`all_with_binary_name { binary_name: "oak_functions_freestanding_bin" }`)
+	if err != nil {
+		t.Fatalf("could not parse the options: %v", err)
+	}
+
+	report, err := VerifyWithReport([]Provenance{*provenance}, options)
+	if err != nil {
+		t.Fatalf("could not verify the provenance: %v", err)
+	}
+	if !report.Passed || len(report.Checks) != 1 {
+		t.Errorf("unexpected report: %+v", report)
+	}
+}
+
+func TestLoadProvenance_UnknownSubject(t *testing.T) {
+	if _, err := LoadProvenance(provenancePath, WithSubjectName("unknown")); err == nil {
+		t.Errorf("expected an error for an unknown subject")
+	}
+}
+
+func TestLoadProvenance_SubjectDigests(t *testing.T) {
+	provenance, err := LoadProvenance(provenancePath, WithSubjectDigests(intoto.DigestSet{
+		"sha256": "d059c38cea82047ad316a1c6c6fbd13ecf7a0abdcc375463920bd25bf5c142cc",
+	}))
+	if err != nil {
+		t.Fatalf("could not load the provenance: %v", err)
+	}
+	if got := provenance.BinaryName(); got != "oak_functions_freestanding_bin" {
+		t.Errorf("unexpected binary name: %q", got)
+	}
+	if _, err := LoadProvenance(provenancePath, WithSubjectDigests(intoto.DigestSet{"sha256": strings.Repeat("0", 64)})); err == nil {
+		t.Errorf("expected an error for an unknown subject digest")
+	}
+}
+
+func TestVerify_NilArgumentsFail(t *testing.T) {
+	if err := Verify(nil, nil); err == nil {
+		t.Errorf("expected an error for nil arguments")
+	}
+	if _, err := VerifyWithReport([]Provenance{}, nil); err == nil {
+		t.Errorf("expected an error for nil options")
+	}
+}